@@ -8,7 +8,16 @@
 //
 // File layout:
 //   - main.go: flag definitions, logger setup, command execution.
+//   - profile.go: -pprof-listen server and -cpuprofile/-memprofile files.
+//   - keychain.go: login/logout subcommands and OS credential store lookups.
+//   - reload.go: SIGHUP re-exec reload and systemd stop/reload notifications for daemon modes.
+//   - service.go: install-service/uninstall-service options and event log writer for Windows service mode.
+//   - service_windows.go: Windows service manager install, removal, and control handler.
+//   - service_other.go: service command stubs for other platforms.
 //   - main_test.go: CLI logging behavior tests.
+//   - profile_test.go: profile file and pprof endpoint tests.
+//   - keychain_test.go: stored credential save, lookup, and removal tests.
+//   - service_test.go: service argument, context, and event log writer tests.
 //   - doc.go: package contract for command wiring.
 //
 // Failure modes:
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/term"
+)
+
+// ─── OS Credential Store ───────────────────────────────────────────────────────
+
+// keychainService names the entries written by login in the macOS
+// Keychain, Windows Credential Manager, or Secret Service.
+const keychainService = "es-bulk-loader"
+
+// storedCredential is the secret saved for one cluster, either a user and
+// password or an API key.
+type storedCredential struct {
+	User   string `json:"user,omitempty"`
+	Pass   string `json:"pass,omitempty"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// keychainAccount returns the account name credentials for rawURL are kept
+// under: its scheme and host, so paths and user info do not matter.
+func keychainAccount(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return strings.ToLower(rawURL)
+	}
+	return strings.ToLower(parsed.Scheme + "://" + parsed.Host)
+}
+
+// saveCredential stores cred for rawURL, replacing any earlier entry.
+func saveCredential(rawURL string, cred storedCredential) error {
+	encoded, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	return keyring.Set(keychainService, keychainAccount(rawURL), string(encoded))
+}
+
+// loadCredential returns the credential stored for rawURL; ok is false when
+// there is none.
+func loadCredential(rawURL string) (cred storedCredential, ok bool, err error) {
+	secret, err := keyring.Get(keychainService, keychainAccount(rawURL))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return cred, false, nil
+	}
+	if err != nil {
+		return cred, false, err
+	}
+	if err := json.Unmarshal([]byte(secret), &cred); err != nil {
+		return cred, false, fmt.Errorf("parsing stored credential for %s: %w", keychainAccount(rawURL), err)
+	}
+	return cred, true, nil
+}
+
+// deleteCredential removes the credential stored for rawURL, if any.
+func deleteCredential(rawURL string) error {
+	err := keyring.Delete(keychainService, keychainAccount(rawURL))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// applyStoredCredential fills user, pass, and apiKey from the credential
+// store when none was given, or when only the user was and it matches the
+// stored one. It reports whether stored values were used.
+func applyStoredCredential(rawURL string, user, pass, apiKey *string) (bool, error) {
+	if *pass != "" || *apiKey != "" {
+		return false, nil
+	}
+	cred, ok, err := loadCredential(rawURL)
+	if err != nil || !ok {
+		return false, err
+	}
+	switch {
+	case cred.APIKey != "" && *user == "":
+		*apiKey = cred.APIKey
+	case cred.User != "" && (*user == "" || *user == cred.User):
+		*user, *pass = cred.User, cred.Pass
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// runLogin prompts for the password of user, or for an API key when user
+// is empty, and saves it for rawURL.
+func runLogin(rawURL, user string, in io.Reader, out io.Writer) error {
+	cred := storedCredential{User: user}
+	var err error
+	if user != "" {
+		cred.Pass, err = readSecret(in, out, fmt.Sprintf("Password for %s at %s: ", user, keychainAccount(rawURL)))
+	} else {
+		cred.APIKey, err = readSecret(in, out, fmt.Sprintf("API key for %s: ", keychainAccount(rawURL)))
+	}
+	if err != nil {
+		return err
+	}
+	if cred.Pass == "" && cred.APIKey == "" {
+		return errors.New("no secret was entered")
+	}
+	if err := saveCredential(rawURL, cred); err != nil {
+		return fmt.Errorf("saving to the OS credential store: %w", err)
+	}
+	return nil
+}
+
+// runLogout removes the credential saved for rawURL.
+func runLogout(rawURL string) error {
+	if err := deleteCredential(rawURL); err != nil {
+		return fmt.Errorf("removing from the OS credential store: %w", err)
+	}
+	return nil
+}
+
+// readSecret prints prompt and reads one line from in without echoing it
+// when in is a terminal.
+func readSecret(in io.Reader, out io.Writer, prompt string) (string, error) {
+	fmt.Fprint(out, prompt)
+	if file, ok := in.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		secret, err := term.ReadPassword(int(file.Fd()))
+		fmt.Fprintln(out)
+		return strings.TrimSpace(string(secret)), err
+	}
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
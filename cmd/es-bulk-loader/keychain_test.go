@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// TestLoginStoresCredentials verifies behavior for the related scenario.
+func TestLoginStoresCredentials(t *testing.T) {
+	keyring.MockInit()
+
+	var prompt bytes.Buffer
+	if err := runLogin("https://es.example.com:9200/", "loader", strings.NewReader("s3cret\n"), &prompt); err != nil {
+		t.Fatalf("runLogin returned error: %v", err)
+	}
+	if !strings.Contains(prompt.String(), "Password for loader at https://es.example.com:9200") {
+		t.Fatalf("unexpected prompt %q", prompt.String())
+	}
+	if err := runLogin("https://keys.example.com", "", strings.NewReader("aWQ6a2V5\n"), &prompt); err != nil {
+		t.Fatalf("runLogin returned error: %v", err)
+	}
+	if err := runLogin("https://keys.example.com", "", strings.NewReader("\n"), &prompt); err == nil {
+		t.Fatal("expected an empty secret to be rejected")
+	}
+
+	tests := []struct {
+		name                        string
+		url, user, pass, apiKey     string
+		wantUsed                    bool
+		wantUser, wantPass, wantKey string
+	}{
+		{name: "stored password", url: "https://ES.example.com:9200/_bulk", wantUsed: true, wantUser: "loader", wantPass: "s3cret"},
+		{name: "matching user", url: "https://es.example.com:9200", user: "loader", wantUsed: true, wantUser: "loader", wantPass: "s3cret"},
+		{name: "other user", url: "https://es.example.com:9200", user: "admin", wantUser: "admin"},
+		{name: "explicit password", url: "https://es.example.com:9200", user: "loader", pass: "typed", wantUser: "loader", wantPass: "typed"},
+		{name: "stored API key", url: "https://keys.example.com", wantUsed: true, wantKey: "aWQ6a2V5"},
+		{name: "nothing stored", url: "https://other.example.com"},
+	}
+	for _, tt := range tests {
+		user, pass, apiKey := tt.user, tt.pass, tt.apiKey
+		used, err := applyStoredCredential(tt.url, &user, &pass, &apiKey)
+		if err != nil || used != tt.wantUsed || user != tt.wantUser || pass != tt.wantPass || apiKey != tt.wantKey {
+			t.Fatalf("%s: got used=%v user=%q pass=%q key=%q err=%v", tt.name, used, user, pass, apiKey, err)
+		}
+	}
+
+	if err := runLogout("https://es.example.com:9200"); err != nil {
+		t.Fatalf("runLogout returned error: %v", err)
+	}
+	if _, ok, _ := loadCredential("https://es.example.com:9200"); ok {
+		t.Fatal("expected logout to remove the stored credential")
+	}
+	if err := runLogout("https://es.example.com:9200"); err != nil {
+		t.Fatalf("expected a second logout to succeed, got %v", err)
+	}
+}
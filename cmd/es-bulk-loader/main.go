@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
+	"unicode"
 
 	"github.com/jnovack/es-bulk-loader/pkg/loader"
 	"github.com/jnovack/flag"
@@ -102,6 +106,118 @@ func (e *enrichFlagValue) explicitPolicies() []string {
 	return policies
 }
 
+// ─── URL Flag Parsing ──────────────────────────────────────────────────────────
+
+// urlListFlagValue collects -url targets. The flag may be repeated or given a
+// comma-separated list; the first explicit value replaces the default.
+type urlListFlagValue struct {
+	urls     []string
+	explicit bool
+}
+
+// String returns the canonical textual form used by callers and logs.
+func (u *urlListFlagValue) String() string {
+	if u == nil {
+		return ""
+	}
+	return strings.Join(u.urls, ",")
+}
+
+// Set parses and stores caller-provided configuration input.
+func (u *urlListFlagValue) Set(value string) error {
+	if !u.explicit {
+		u.urls = nil
+		u.explicit = true
+	}
+	for _, part := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			u.urls = append(u.urls, trimmed)
+		}
+	}
+	return nil
+}
+
+// ─── Repeatable Flag Parsing ───────────────────────────────────────────────────
+
+// stringListFlagValue collects every occurrence of a repeatable flag in order.
+type stringListFlagValue []string
+
+// String returns the canonical textual form used by callers and logs.
+func (s *stringListFlagValue) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(*s, ",")
+}
+
+// Set parses and stores caller-provided configuration input.
+func (s *stringListFlagValue) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// first returns the first occurrence, or "" when the flag was not given.
+func (s *stringListFlagValue) first() string {
+	if len(*s) == 0 {
+		return ""
+	}
+	return (*s)[0]
+}
+
+// rest returns every occurrence after the first.
+func (s *stringListFlagValue) rest() []string {
+	if len(*s) < 2 {
+		return nil
+	}
+	return (*s)[1:]
+}
+
+// ─── Byte Size Flag Parsing ────────────────────────────────────────────────────
+
+// byteSizeUnits maps size suffixes to multipliers. Decimal and binary
+// prefixes are both accepted; a bare K, M, or G is binary, as in container
+// memory limits.
+var byteSizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1000,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1000 * 1000,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1000 * 1000 * 1000,
+	"gib": 1 << 30,
+}
+
+// byteSizeFlagValue parses sizes such as 512MiB, 2g, or 1073741824.
+type byteSizeFlagValue int64
+
+// String returns the canonical textual form used by callers and logs.
+func (b *byteSizeFlagValue) String() string {
+	if b == nil {
+		return "0"
+	}
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+// Set parses and stores caller-provided configuration input.
+func (b *byteSizeFlagValue) Set(value string) error {
+	trimmed := strings.TrimSpace(value)
+	digits := strings.TrimRightFunc(trimmed, func(r rune) bool { return unicode.IsLetter(r) || r == ' ' })
+	multiplier, ok := byteSizeUnits[strings.ToLower(strings.TrimSpace(trimmed[len(digits):]))]
+	if !ok {
+		return fmt.Errorf("unknown size unit in %q", value)
+	}
+	number, err := strconv.ParseFloat(digits, 64)
+	if err != nil || number < 0 {
+		return fmt.Errorf("expected a size such as 512MiB or 2GB, got %q", value)
+	}
+	*b = byteSizeFlagValue(number * float64(multiplier))
+	return nil
+}
+
 // ─── Runtime Helpers ───────────────────────────────────────────────────────────
 
 // populateBuildMetadataFromBuildInfo centralizes this code path so package behavior stays consistent.
@@ -152,43 +268,248 @@ func newConsoleLogger(out io.Writer) zerolog.Logger {
 	return zerolog.New(zerolog.ConsoleWriter{Out: out, TimeFormat: "15:04:05"}).With().Timestamp().Logger()
 }
 
+// splitCommand separates a leading subcommand (a first argument that is not a
+// flag) from the flag arguments that follow it.
+func splitCommand(args []string) (string, []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", args
+	}
+	return args[0], args[1:]
+}
+
+// runGenerate writes the generate command's documents to output, or, when
+// output is empty, to a temporary NDJSON file that is loaded with opts and
+// then removed.
+func runGenerate(ctx context.Context, gen loader.GenerateOptions, output string, opts loader.Options) error {
+	path := output
+	if path == "" {
+		file, err := os.CreateTemp("", "es-bulk-loader-generate-*.ndjson")
+		if err != nil {
+			return err
+		}
+		path = file.Name()
+		_ = file.Close()
+		defer os.Remove(path)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	gen.Output = file
+	count, err := loader.Generate(ctx, gen)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	log.Info().Int("documents", count).Str("file", path).Msg("Generated documents")
+	if output != "" {
+		return nil
+	}
+	opts.DataFile, opts.DataFiles, opts.Format = path, nil, "ndjson"
+	_, err = loader.Run(ctx, opts)
+	return err
+}
+
 // ─── Main Execution ────────────────────────────────────────────────────────────
 
 // main centralizes this code path so package behavior stays consistent.
 func main() {
 	populateBuildMetadataFromBuildInfo()
 
-	url := flag.String("url", "http://localhost:9200", "Elasticsearch URL")
+	urls := &urlListFlagValue{urls: []string{"http://localhost:9200"}}
+	flag.Var(urls, "url", "Elasticsearch URL, or unix:///path for a Unix domain socket; repeat or comma-separate to write the same load to several clusters")
 	insecure := flag.Bool("insecureSkipVerify", false, "Skip TLS verification")
+	dialTarget := flag.String("dial-target", "", "Open every connection to this host:port or unix:///path instead of the -url host, such as an SSH tunnel or sidecar proxy (optional)")
+	preflight := flag.Bool("preflight", true, "Check connectivity and credentials and log the cluster version, distribution, and license before any index operation")
 	index := flag.String("index", "", "Elasticsearch index name")
 	settingsFile := flag.String("settings", "", "Path to index settings JSON file (optional)")
 	mappingsFile := flag.String("mappings", "", "Path to index mappings JSON file (optional)")
 	pipelinesFile := flag.String("pipelines", "", "Path to JSON file containing one or more ingest pipeline definitions (optional)")
 	policiesFile := flag.String("policies", "", "Path to JSON file containing one or more enrich policy definitions (optional)")
 	transformsFile := flag.String("transforms", "", "Path to JSON file containing one or more transform definitions (optional)")
-	dataFile := flag.String("data", "", "Path to bulk JSON data file (array of objects)")
+	useTemplates := flag.Bool("use-templates", false, "Create the index from its installed index template instead of -settings and -mappings, after checking one matches")
+	aliasesFile := flag.String("aliases", "", "Path to JSON file of filtered aliases (filter and routing) to point at the index after the load (optional)")
+	dataFiles := &stringListFlagValue{}
+	flag.Var(dataFiles, "data", "Path to bulk JSON data file (array of objects); repeat to load several files, read in parallel")
+	readConcurrency := flag.Int("read-concurrency", 0, "With several -data files, how many are read and parsed at once (default: one per CPU)")
+	format := flag.String("format", "", "Format of -data: json (array of objects), ndjson, csv, fixed, msgpack, cbor, protobuf, geojson, elasticdump, accesslog, regex, cef, leef, evtx, or pcap (default: from the file extension)")
+	columnMap := flag.String("column-map", "", "JSON file mapping CSV column names or positions to typed fields; implies -format csv (optional)")
+	logFormat := flag.String("log-format", "", "Access log line format: combined, common, or an Apache LogFormat or nginx log_format string; implies -format accesslog (optional)")
+	grokPattern := flag.String("grok-pattern", "", "Regular expression with named groups or %{NAME:field:type} grok references parsing each text log line; implies -format regex (optional)")
+	pcapMode := flag.String("pcap-mode", "", "With -format pcap, index one document per packet (default) or per flow; flow implies -format pcap (optional)")
+	grokPatternsDir := flag.String("grok-patterns-dir", "", "Directory of NAME PATTERN files adding to the built-in grok patterns (optional)")
+	layout := flag.String("layout", "", "JSON file giving each fixed-width field's name, start, length, and type; implies -format fixed (optional)")
+	descriptor := flag.String("descriptor", "", "Protobuf FileDescriptorSet (protoc --include_imports --descriptor_set_out) for -format protobuf; implies it (optional)")
+	message := flag.String("message", "", "With -descriptor, fully qualified name of the length-delimited record message, e.g. acme.events.v1.Event")
+	archivePattern := flag.String("archive-pattern", "", "When -data is a .zip, .tar, .tar.gz, or .tgz archive, read only members whose base name matches this glob (default: every data file)")
+	preserveIndex := flag.Bool("preserve-index", false, "With -format elasticdump, write each document back to the _index it was exported from instead of -index")
+	sanitizeIndexNames := flag.Bool("sanitize-index-names", false, "With -preserve-index, rewrite _index values that break index naming rules (uppercase, forbidden characters, length) instead of failing the load")
+	geoShapeField := flag.String("geo-shape-field", "geometry", "With -format geojson, field that receives each Feature's geometry and is mapped as geo_shape")
 	batchSize := flag.Int("batch", 1000, "Batch size for bulk inserts")
 	bulkRetryAttempts := flag.Int("bulk-retry-attempts", 4, "Total bulk request attempts, including first try")
 	bulkRetryBackoffBase := flag.Duration("bulk-retry-backoff-base", 500*time.Millisecond, "Base backoff for retryable bulk failures")
 	bulkRetryBackoffMax := flag.Duration("bulk-retry-backoff-max", 5*time.Second, "Maximum backoff for retryable bulk failures")
+	errorLog := flag.String("error-log", "", "Append the raw Elasticsearch error of every failed bulk request and item to this NDJSON file (optional)")
+	shard := flag.String("shard", "", "Load only the i-th of N disjoint slices of the input, as i/N, so N machines can share one dataset (optional)")
+	shardBy := flag.String("shard-by", "", "With -shard, slice the input by record number within each data file or by a hash of -id: record or id (default: record)")
+	journal := flag.String("journal", "", "Journal sent and confirmed batches to this file, configmap://[namespace/]name, or http(s) object URL so a rerun after a crash resumes -add after the confirmed documents (optional)")
+	retryQueue := flag.String("retry-queue", "", "Append documents whose transient failures outlast every retry to this file instead of failing; the retry command replays it (optional)")
+	slowBatchThreshold := flag.Duration("slow-batch-threshold", 0, "Log a warning for any bulk request slower than this duration (0 disables)")
+	benchmark := flag.Bool("benchmark", false, "Run the load once and print a throughput and latency report for comparing -batch, -prefetch, and cluster sizes")
+	benchmarkThrowaway := flag.Bool("benchmark-throwaway", false, "With -benchmark, load into a new <index>-benchmark-<timestamp> index and delete it afterwards")
+	maxMemory := new(byteSizeFlagValue)
+	flag.Var(maxMemory, "max-memory", "Pause reading while the heap nears this size, e.g. 512MiB or 2GB, and use it as the Go memory limit (default: 0, disabled)")
+	maxIdleConns := flag.Int("max-idle-conns", 16, "Idle connections kept open per Elasticsearch host for reuse between bulk requests")
+	maxConnsPerHost := flag.Int("max-conns-per-host", 0, "Maximum open connections per Elasticsearch host (0 is unlimited)")
+	keepAlive := flag.Duration("keep-alive", 30*time.Second, "TCP keep-alive period for connections to Elasticsearch (negative disables)")
+	idleConnTimeout := flag.Duration("idle-conn-timeout", 90*time.Second, "How long an idle connection to Elasticsearch is kept before it is closed")
+	loadDurability := flag.String("load-durability", "", "Set to async to switch the write index to index.translog.durability=async for the load and restore it afterwards")
+	workers := flag.Int("workers", 1, "Most bulk requests sent at once; lowered automatically while Elasticsearch rejects writes and raised again as rejections stop")
+	shardGroup := flag.Int("shard-group", 0, "Collect this many batches and regroup them by destination shard of -routing-field or -id so each bulk request reaches fewer shards (0 disables)")
+	prefetch := flag.Int("prefetch", 1, "Number of encoded batches read ahead while the previous batch is in flight (0 sends each batch before reading on)")
 	deleteIndex := flag.Bool("delete", false, "Delete index if it exists")
 	addToIndex := flag.Bool("add", false, "Add documents to existing index")
+	syncChanged := flag.Bool("sync", false, "With -add, index only documents whose _id is new or whose content hash differs from the stored one")
+	syncHashField := flag.String("sync-hash-field", "content_hash", "With -sync, field that stores each document's content hash")
+	partitionBy := flag.String("partition-by", "", "Write each document to <index>-<value of this field>, creating each partition index with the -settings and -mappings on first use (optional)")
+	partitionMax := flag.Int("partition-max", 0, "With -partition-by, most partition indices one run may write (default 100)")
+	capacityCheck := flag.String("capacity-check", "", "Before writing, compare the data size and new shards with the cluster's free disk, high watermark, and shard limit: warn or refuse (default: not checked)")
+	minFreeDisk := new(byteSizeFlagValue)
+	flag.Var(minFreeDisk, "min-free-disk", "With -capacity-check, free disk every data node must keep after the load, e.g. 50GiB; alone, implies -capacity-check refuse")
+	dynamicTemplatesFile := flag.String("dynamic-templates", "", "Path to JSON file mapping field paths to dynamic templates of the index mapping, sent with each bulk action (Elasticsearch 7.13+; optional)")
+	concurrencyControl := flag.String("concurrency-control", "", "With -add, send if_seq_no/if_primary_term so documents a concurrent writer changed are left alone: input (from -seq-no-field and -primary-term-field) or fetch (looked up per batch)")
+	seqNoField := flag.String("seq-no-field", "_seq_no", "With -concurrency-control input, field holding each document's captured sequence number")
+	primaryTermField := flag.String("primary-term-field", "_primary_term", "With -concurrency-control input, field holding each document's captured primary term")
 	flushIndex := flag.Bool("flush", false, "Delete all documents from an existing index without deleting the index")
 	syncManaged := flag.Bool("sync-managed", false, "Create or update declared ingest pipelines, enrich policies, and transforms")
 	aliasMode := flag.Bool("alias", false, "Treat -index as an alias; create timestamped indices as <alias>-YYYYMMDDHHMMSS and repoint the alias on recreate")
+	shrinkTo := flag.Int("shrink-to", 0, "When -alias creates a new index, shrink it to N primary shards after the load, before the alias moves (0 disables)")
+	splitTo := flag.Int("split-to", 0, "When -alias creates a new index, split it into N primary shards after the load, before the alias moves (0 disables)")
+	sourceIncludes := &stringListFlagValue{}
+	flag.Var(sourceIncludes, "source-includes", "Field path or wildcard kept in the stored _source of a created index (repeatable)")
+	sourceExcludes := &stringListFlagValue{}
+	flag.Var(sourceExcludes, "source-excludes", "Field path or wildcard indexed but left out of the stored _source of a created index (repeatable)")
+	finalize := flag.String("finalize", "", "After a load without failed documents, block the loaded index: read-only or write-block")
 	keepLast := flag.Int("keep-last", 0, "When -alias is set, keep only the newest N timestamped indices matching <alias>-YYYYMMDDHHMMSS (0 disables pruning)")
 	nuke := flag.Bool("nuke", false, "Delete the current index and declared managed resources, including dependent pipelines that reference declared enrich policies")
 	idField := flag.String("id", "", "Field to use to override _id (not normal)")
+	routingField := flag.String("routing-field", "", "Field whose value is sent as each document's bulk routing (optional)")
+	docType := flag.String("doc-type", "", "Elasticsearch 7.x mapping type sent as each document's _type and used to nest created index mappings (optional)")
 	user := flag.String("user", "", "Username for basic auth (optional)")
 	pass := flag.String("pass", "", "Password for basic auth (optional)")
 	apiKey := flag.String("apiKey", "", "Elasticsearch API key (optional)")
+	opaqueID := flag.String("opaque-id", "", "X-Opaque-Id sent with every request so the load's tasks can be found; with the cancel command, prefix of the ids to cancel (default: es-bulk-loader/<index>/<UTC time>)")
+	kerberosKeytab := flag.String("kerberos-keytab", "", "Keytab file for Kerberos/SPNEGO authentication with -kerberos-principal (optional)")
+	kerberosPrincipal := flag.String("kerberos-principal", "", "Kerberos client principal as user@REALM, used with -kerberos-keytab (optional)")
+	kerberosCCache := flag.String("kerberos-ccache", "", "Kerberos credential cache written by kinit, used for SPNEGO authentication (optional)")
+	krb5Config := flag.String("krb5-config", "", "Path to krb5.conf (default: $KRB5_CONFIG, then /etc/krb5.conf)")
+	oidc := flag.Bool("oidc", false, "Sign in with the OIDC device-code flow and send the resulting token as the Elasticsearch bearer credential")
+	oidcIssuer := flag.String("oidc-issuer", "", "OIDC issuer URL used by -oidc")
+	oidcClientID := flag.String("oidc-client-id", "", "OIDC client ID used by -oidc")
+	oidcScope := flag.String("oidc-scope", "openid,offline_access", "Comma-separated scopes requested by -oidc; offline_access lets long loads refresh the token")
+	kerberosSPN := flag.String("kerberos-spn", "", "Service principal of the SPNEGO proxy (default: HTTP/<-url host>)")
 	logLevel := flag.String("level", "info", "Log level (trace, debug, info, warn, error)")
 	enrich := &enrichFlagValue{}
 	flag.Var(enrich, "enrich", "Run enrich policies after the bulk insert; provide a comma-separated policy list or omit the value to run all policies")
+	otelEnabled := flag.Bool("otel", false, "Export OpenTelemetry traces and metrics over OTLP (configured by OTEL_EXPORTER_OTLP_* environment variables)")
+	statsdAddr := flag.String("statsd-addr", "", "Send StatsD/DogStatsD metrics to this host:port over UDP (optional)")
+	notifyURL := flag.String("notify-url", "", "POST a JSON run summary to this webhook URL when the run finishes or fails (optional)")
+	notifyTemplate := flag.String("notify-template", "", "Path to a $VAR-templated webhook payload used instead of the raw JSON summary (optional)")
+	watchDir := flag.String("watch", "", "Continuously load data files dropped into this directory, moving them to done/ or failed/ (optional)")
+	watchPattern := flag.String("watch-pattern", "*.json", "File name glob matched in the -watch directory")
+	watchInterval := flag.Duration("watch-interval", 5*time.Second, "Delay between -watch directory scans")
+	source := flag.String("source", "", "Stay running and load JSON messages from this queue URL into -index: sqs://, pubsub://, nats://, amqp(s)://, redis(s)://, or mongodb(+srv):// (optional)")
+	collection := flag.String("collection", "", "With a mongodb:// -source, collection to copy and then follow through its change stream")
+	mongoQuery := flag.String("query", "", "With a mongodb:// -source, JSON filter the copied documents must match (optional)")
+	sourceFlushInterval := flag.Duration("source-flush-interval", 5*time.Second, "With -source, maximum time a partial batch of messages waits before it is sent")
+	schedule := flag.String("schedule", "", "Stay running and repeat the load on this cron schedule (5-field expression or @daily/@hourly/...; optional CRON_TZ=<zone> prefix)")
+	tail := flag.Bool("tail", false, "Follow -data as it grows (like tail -F), indexing appended NDJSON lines until interrupted")
+	tailFlushInterval := flag.Duration("tail-flush-interval", 5*time.Second, "With -tail, send a partial batch after this long without filling -batch")
+	rename := &stringListFlagValue{}
+	flag.Var(rename, "rename", "Rename a field before indexing as old=new (dot-notation paths; repeatable)")
+	ecsPreset := flag.String("ecs-preset", "", "Map common field names onto Elastic Common Schema fields and set ecs.version: weblogs, netflow (comma-separated; optional)")
+	decode := &stringListFlagValue{}
+	flag.Var(decode, "decode", "Decode an encoded field as field=base64 or field=hex; add :json to parse the result as JSON (repeatable)")
+	expandDots := flag.Bool("expand-dots", false, "Expand flat keys such as user.name into nested objects before indexing")
+	flatten := flag.Bool("flatten", false, "Collapse nested objects into flat keys before indexing")
+	flattenSeparator := flag.String("flatten-separator", ".", "With -flatten, separator placed between parent and child keys")
+	nullPolicy := flag.String("null-policy", "keep", "Handling of null and empty-string fields: keep, drop, or empty-to-null")
+	defaults := &stringListFlagValue{}
+	flag.Var(defaults, "default", "Set a field missing from a document as field=value; value is JSON or a plain string (repeatable)")
+	convert := &stringListFlagValue{}
+	flag.Var(convert, "convert", "Convert a numeric field between units as field=from:to, e.g. size=b:mb, latency=ms:s, temp=f:c (repeatable)")
+	normalize := &stringListFlagValue{}
+	flag.Var(normalize, "normalize", "Normalize a string field as field=op[,op...] with trim, lower, upper, or collapse-spaces (repeatable)")
+	geoIP := &stringListFlagValue{}
+	flag.Var(geoIP, "geoip", "Enrich documents from -geoip-db as ipField or ipField:targetField (default target geoip; repeatable)")
+	geoIPDatabase := flag.String("geoip-db", "", "With -geoip, path to a local MaxMind GeoIP2/GeoLite2 .mmdb database")
+	lookups := &stringListFlagValue{}
+	flag.Var(lookups, "lookup", "Join documents against a local .csv or JSON dictionary as lookupFile:keyField:targetField (repeatable)")
+	explode := &stringListFlagValue{}
+	flag.Var(explode, "explode", "Index one document per element of an array field as field or field:target, copying the other fields (repeatable)")
+	uuidField := flag.String("uuid-field", "", "Set this field to a new UUID in every document that lacks it (optional)")
+	uuidVersion := flag.Int("uuid-version", 4, "With -uuid-field, UUID version: 4 (random) or 7 (time-ordered)")
+	hashFields := &stringListFlagValue{}
+	flag.Var(hashFields, "hash-field", "Replace a field with its hex SHA-256 before indexing (dot-notation path; repeatable)")
+	hashSalt := flag.String("hash-salt", "", "With -hash-field, salt prepended to each value before hashing (optional)")
+	redactFields := &stringListFlagValue{}
+	flag.Var(redactFields, "redact-field", "Mask a field before indexing; field:N keeps the last N characters (repeatable)")
+	docTemplate := flag.String("doc-template", "", "Go text/template file that renders each document's final JSON from the source record (optional)")
+	scriptTransform := flag.String("script-transform", "", "Starlark file defining transform(doc), which returns a dict, a list of dicts to split, or None to drop (optional)")
+	transformExec := flag.String("transform-exec", "", "Shell command that reads each document as an NDJSON line and answers with one line: an object, an array to split, or null to drop (optional)")
+	transformExecTimeout := flag.Duration("transform-exec-timeout", 30*time.Second, "With -transform-exec, longest wait for the command's answer to one document")
+	dedupField := flag.String("dedup-field", "", "Drop documents that repeat an earlier value of this field (optional)")
+	dedupPolicy := flag.String("dedup-policy", "keep-first", "With -dedup-field, which duplicate to index: keep-first or keep-last")
+	filter := flag.String("filter", "", `Index only documents matching this expression, e.g. 'status == "active" && amount > 0' (optional)`)
+	generateSpec := flag.String("generate-spec", "", "With the generate command, JSON file describing each generated field (required)")
+	generateCount := flag.Int("generate-count", 1000, "With the generate command, number of documents to generate")
+	generateSeed := flag.Uint64("generate-seed", 0, "With the generate command, random seed for repeatable output (0 picks one)")
+	generateOutput := flag.String("generate-output", "", "With the generate command, write the documents to this NDJSON file instead of loading them (optional)")
+	convertOutput := flag.String("convert-output", "", "With the convert command, file the transformed documents are written to (required)")
+	convertFormat := flag.String("convert-format", "", "With the convert command, output format: ndjson, csv, or parquet (default: from the -convert-output extension)")
+	splitDocuments := flag.Int("split-documents", 0, "With the split command, most documents per chunk file (0: no limit)")
+	splitSize := new(byteSizeFlagValue)
+	flag.Var(splitSize, "split-size", "With the split command, largest chunk file, e.g. 100MB (default: 0, no limit)")
+	splitDir := flag.String("split-dir", "", "With the split command, directory for the chunk files (default: the -data file's directory)")
+	snapshotRepository := flag.String("snapshot-repository", "", "With the snapshot and restore commands, registered snapshot repository to use (required)")
+	snapshotName := flag.String("snapshot-name", "", "With the snapshot and restore commands, snapshot name (default for snapshot: es-bulk-loader-<UTC time>; required for restore)")
+	snapshotWait := flag.Bool("snapshot-wait", true, "With the snapshot and restore commands, wait for the operation to finish and fail on failed shards")
+	restoreRenamePattern := flag.String("restore-rename-pattern", "", "With the restore command, regular expression matched against each restored index name (optional)")
+	restoreRenameReplacement := flag.String("restore-rename-replacement", "", "With -restore-rename-pattern, replacement for the matched part, e.g. restored-$1")
+	cancelDryRun := flag.Bool("cancel-dry-run", false, "With the cancel command, list the matching tasks without cancelling them")
+	schemaDir := flag.String("schema-dir", "", "With the dump-schema command, directory settings.json and mappings.json are written to (default: the working directory)")
+	preview := flag.Int("preview", 0, "Print the first N documents as the bulk lines they would be sent as, then exit without writing (0 disables)")
+	mappingCheck := flag.Int("mapping-check", 0, "Before loading, compare the fields of the first N documents with the index mapping and warn about fields it will drop, reject, or map with a likely wrong type (0 disables)")
+	analyzeCheck := &stringListFlagValue{}
+	flag.Var(analyzeCheck, "analyze-check", "Before loading, print the tokens the index's _analyze API gives a few sample values of a field as field=analyzer, or field for its mapped analyzer (repeatable)")
+	estimate := flag.Bool("estimate", false, "Scan the input and report document count, sizes, batches, and projected index size, then exit without writing")
+	tui := flag.Bool("tui", false, "Render a live terminal dashboard during the bulk load")
+	pprofListen := flag.String("pprof-listen", "", "Serve net/http/pprof endpoints under /debug/pprof/ on this address, e.g. localhost:6060 (optional)")
+	cpuProfile := flag.String("cpuprofile", "", "Write a CPU profile of the whole run to this file (optional)")
+	memProfile := flag.String("memprofile", "", "Write a heap profile to this file when the run ends (optional)")
+
+	jobsIndex := flag.String("jobs-index", loader.DefaultJobsIndex, "In serve, -watch, and -schedule modes, record job history in this index (empty disables)")
+	listen := flag.String("listen", "localhost:8080", "With the serve command, HTTP listen address; other than loopback needs -serve-token or -serve-insecure")
+	spoolDir := flag.String("spool-dir", "", "With the serve command, directory that holds uploads until their job runs (default: system temp dir)")
+	serveToken := flag.String("serve-token", "", "With the serve command, require this bearer token on /jobs requests (optional)")
+	serveInsecure := flag.Bool("serve-insecure", false, "With the serve command, accept jobs without -serve-token on a -listen or -grpc-listen address other hosts can reach")
+	serveMaxUpload := flag.Int64("serve-max-upload", 1<<30, "With the serve command, maximum upload size in bytes")
+	grpcListen := flag.String("grpc-listen", "", "With the serve command, also serve the gRPC streaming ingestion service on this address (optional)")
+	grpcFlushInterval := flag.Duration("grpc-flush-interval", 5*time.Second, "With -grpc-listen, maximum time a partial batch waits on a stream before it is sent")
+	serviceName := flag.String("service-name", defaultServiceName, "With install-service and uninstall-service, name of the Windows service and its event log source")
+	workdir := flag.String("workdir", "", "Change to this directory before reading any other file; install-service records the current one (optional)")
 	showVersion := flag.Bool("version", false, "print version and exit")
 
 	flag.String(flag.DefaultConfigFlagname, "", "path to config file")
-	flag.Parse()
+	command, args := splitCommand(os.Args[1:])
+	if command != "" && command != "serve" && command != "validate" && command != "diff" && command != "convert" && command != "split" && command != "dump-schema" && command != "snapshot" && command != "restore" && command != "cancel" && command != "generate" && command != "retry" && command != "login" && command != "logout" && command != "install-service" && command != "uninstall-service" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		flag.Usage()
+		os.Exit(1)
+	}
+	_ = flag.CommandLine.Parse(args)
 
 	zerolog.TimeFieldFormat = time.RFC3339
 	parsedLogLevel, err := parseLogLevel(*logLevel)
@@ -200,6 +521,21 @@ func main() {
 	zerolog.SetGlobalLevel(parsedLogLevel)
 	log.Logger = newConsoleLogger(os.Stderr)
 
+	service, err := startService(*serviceName)
+	if err != nil {
+		log.Error().Err(err).Msg("Starting the Windows service failed")
+		os.Exit(1)
+	}
+	if service != nil {
+		log.Logger = zerolog.New(service.log).With().Timestamp().Logger()
+	}
+	if *workdir != "" {
+		if err := os.Chdir(*workdir); err != nil {
+			log.Error().Err(err).Msg("Changing to -workdir failed")
+			os.Exit(1)
+		}
+	}
+
 	if *showVersion {
 		log.Info().
 			Str("version", version).
@@ -209,51 +545,343 @@ func main() {
 		os.Exit(0)
 	}
 
+	switch command {
+	case "login":
+		if err := runLogin(urls.urls[0], *user, os.Stdin, os.Stderr); err != nil {
+			log.Error().Err(err).Msg("Login failed")
+			os.Exit(1)
+		}
+		log.Info().Str("url", keychainAccount(urls.urls[0])).Msg("Saved credentials to the OS credential store")
+		os.Exit(0)
+	case "logout":
+		if err := runLogout(urls.urls[0]); err != nil {
+			log.Error().Err(err).Msg("Logout failed")
+			os.Exit(1)
+		}
+		log.Info().Str("url", keychainAccount(urls.urls[0])).Msg("Removed credentials from the OS credential store")
+		os.Exit(0)
+	case "install-service":
+		err := checkServiceOptions(*watchDir, *schedule, *source, flag.Lookup(flag.DefaultConfigFlagname).Value.String())
+		if err == nil {
+			var cwd string
+			if cwd, err = os.Getwd(); err == nil {
+				err = installService(*serviceName, serviceArgs(args, cwd))
+			}
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Installing the service failed")
+			os.Exit(1)
+		}
+		log.Info().Str("service", *serviceName).Msg("Installed the Windows service; start it with sc.exe start or the Services console")
+		os.Exit(0)
+	case "uninstall-service":
+		if err := uninstallService(*serviceName); err != nil {
+			log.Error().Err(err).Msg("Uninstalling the service failed")
+			os.Exit(1)
+		}
+		log.Info().Str("service", *serviceName).Msg("Removed the Windows service")
+		os.Exit(0)
+	}
+
 	log.Info().
 		Str("version", version).
 		Str("build_rfc3339", buildRFC3339).
 		Str("revision", revision).
 		Msg("jnovack/es-bulk-loader starting...")
 
+	if len(urls.urls) == 0 {
+		urls.urls = []string{"http://localhost:9200"}
+	}
 	opts := loader.Options{
-		URL:                  *url,
+		URL:                  urls.urls[0],
 		InsecureSkipVerify:   *insecure,
+		DialTarget:           *dialTarget,
+		Preflight:            *preflight,
 		Index:                *index,
 		SettingsFile:         *settingsFile,
 		MappingsFile:         *mappingsFile,
 		PipelinesFile:        *pipelinesFile,
 		PoliciesFile:         *policiesFile,
 		TransformsFile:       *transformsFile,
-		DataFile:             *dataFile,
+		AliasesFile:          *aliasesFile,
+		UseTemplates:         *useTemplates,
+		DataFile:             dataFiles.first(),
+		DataFiles:            dataFiles.rest(),
+		ReadConcurrency:      *readConcurrency,
 		BatchSize:            *batchSize,
 		BulkRetryAttempts:    *bulkRetryAttempts,
 		BulkRetryBackoffBase: *bulkRetryBackoffBase,
 		BulkRetryBackoffMax:  *bulkRetryBackoffMax,
+		SlowBatchThreshold:   *slowBatchThreshold,
+		ErrorLog:             *errorLog,
+		RetryQueue:           *retryQueue,
+		Journal:              *journal,
+		Shard:                *shard,
+		ShardBy:              *shardBy,
+		Prefetch:             *prefetch,
+		MaxMemory:            int64(*maxMemory),
+		MaxIdleConns:         *maxIdleConns,
+		MaxConnsPerHost:      *maxConnsPerHost,
+		KeepAlive:            *keepAlive,
+		IdleConnTimeout:      *idleConnTimeout,
+		LoadDurability:       *loadDurability,
+		ShardGroup:           *shardGroup,
+		Workers:              *workers,
 		DeleteIndex:          *deleteIndex,
 		AddToIndex:           *addToIndex,
+		Sync:                 *syncChanged,
+		SyncHashField:        *syncHashField,
+		ConcurrencyControl:   *concurrencyControl,
+		DynamicTemplatesFile: *dynamicTemplatesFile,
+		PartitionBy:          *partitionBy,
+		PartitionMax:         *partitionMax,
+		CapacityCheck:        *capacityCheck,
+		MinFreeDisk:          int64(*minFreeDisk),
+		SeqNoField:           *seqNoField,
+		PrimaryTermField:     *primaryTermField,
 		FlushIndex:           *flushIndex,
 		SyncManaged:          *syncManaged,
 		AliasMode:            *aliasMode,
 		KeepLast:             *keepLast,
+		ShrinkTo:             *shrinkTo,
+		SplitTo:              *splitTo,
+		SourceIncludes:       *sourceIncludes,
+		SourceExcludes:       *sourceExcludes,
+		Finalize:             *finalize,
 		Nuke:                 *nuke,
 		IDField:              *idField,
+		RoutingField:         *routingField,
+		DocType:              *docType,
 		User:                 *user,
 		Pass:                 *pass,
 		APIKey:               *apiKey,
+		OpaqueID:             *opaqueID,
+		Kerberos: loader.KerberosOptions{
+			Keytab:    *kerberosKeytab,
+			Principal: *kerberosPrincipal,
+			CCache:    *kerberosCCache,
+			Config:    *krb5Config,
+			SPN:       *kerberosSPN,
+		},
 		Enrich: loader.EnrichOptions{
 			Enabled:  enrich.enabled,
 			All:      enrich.all,
 			Raw:      enrich.raw,
 			Policies: enrich.explicitPolicies(),
 		},
+		Preview:              *preview,
+		PreviewOutput:        os.Stdout,
+		Estimate:             *estimate,
+		MappingCheck:         *mappingCheck,
+		AnalyzeCheck:         *analyzeCheck,
+		AnalyzeOutput:        os.Stdout,
+		TUI:                  *tui,
+		TUIOutput:            os.Stderr,
+		Telemetry:            *otelEnabled,
+		StatsDAddr:           *statsdAddr,
+		NotifyURL:            *notifyURL,
+		NotifyTemplate:       *notifyTemplate,
+		Tail:                 *tail,
+		TailFlushInterval:    *tailFlushInterval,
+		Format:               *format,
+		ColumnMap:            *columnMap,
+		Layout:               *layout,
+		LogFormat:            *logFormat,
+		GrokPattern:          *grokPattern,
+		GrokPatternsDir:      *grokPatternsDir,
+		PcapMode:             *pcapMode,
+		Descriptor:           *descriptor,
+		Message:              *message,
+		GeoShapeField:        *geoShapeField,
+		ArchivePattern:       *archivePattern,
+		PreserveIndex:        *preserveIndex,
+		SanitizeIndexNames:   *sanitizeIndexNames,
+		Rename:               *rename,
+		ECSPreset:            *ecsPreset,
+		Decode:               *decode,
+		ExpandDots:           *expandDots,
+		Flatten:              *flatten,
+		FlattenSeparator:     *flattenSeparator,
+		NullPolicy:           *nullPolicy,
+		Defaults:             *defaults,
+		Convert:              *convert,
+		Normalize:            *normalize,
+		GeoIP:                *geoIP,
+		GeoIPDatabase:        *geoIPDatabase,
+		Lookups:              *lookups,
+		Explode:              *explode,
+		UUIDField:            *uuidField,
+		UUIDVersion:          *uuidVersion,
+		Filter:               *filter,
+		DedupField:           *dedupField,
+		DedupPolicy:          *dedupPolicy,
+		HashFields:           *hashFields,
+		HashSalt:             *hashSalt,
+		RedactFields:         *redactFields,
+		DocTemplate:          *docTemplate,
+		ScriptTransform:      *scriptTransform,
+		TransformExec:        *transformExec,
+		TransformExecTimeout: *transformExecTimeout,
+	}
+	if len(urls.urls) > 1 {
+		opts.Clusters = urls.urls
+	}
+	if *prefetch == 0 {
+		// Options treats 0 as the default depth.
+		opts.Prefetch = -1
 	}
 
-	_, err = loader.Run(context.Background(), opts)
+	if *watchDir != "" && *schedule != "" {
+		fmt.Fprintln(os.Stderr, "-watch and -schedule cannot be combined")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *source != "" && (command != "" || *watchDir != "" || *schedule != "") {
+		fmt.Fprintln(os.Stderr, "-source cannot be combined with a command, -watch, or -schedule")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *benchmark && (command == "serve" || *watchDir != "" || *schedule != "" || *source != "") {
+		fmt.Fprintln(os.Stderr, "-benchmark cannot be combined with serve, -watch, -schedule, or -source")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if !*oidc && *kerberosKeytab == "" && *kerberosCCache == "" {
+		used, err := applyStoredCredential(opts.URL, &opts.User, &opts.Pass, &opts.APIKey)
+		if err != nil {
+			log.Debug().Err(err).Msg("Reading the OS credential store")
+		}
+		if used {
+			log.Info().Str("url", keychainAccount(opts.URL)).Msg("Using credentials saved by login")
+		}
+	}
+	if *oidc {
+		session, err := loader.NewOIDCDeviceSession(context.Background(), loader.OIDCOptions{
+			Issuer:   *oidcIssuer,
+			ClientID: *oidcClientID,
+			Scopes:   strings.FieldsFunc(*oidcScope, func(r rune) bool { return r == ',' || r == ' ' }),
+		})
+		if err != nil {
+			if errors.Is(err, loader.ErrInvalidOptions) {
+				flag.Usage()
+			}
+			log.Error().Err(err).Msg("OIDC sign-in failed")
+			os.Exit(1)
+		}
+		opts.BearerToken = session.Token
+	}
+
+	profiles, err := startProfiling(*pprofListen, *cpuProfile, *memProfile)
+	if err != nil {
+		log.Error().Err(err).Msg("Starting profiling failed")
+		os.Exit(1)
+	}
+
+	// Long-running modes stop cleanly on SIGINT/SIGTERM; one-shot runs keep the
+	// default signal behavior. Daemon modes also restart in place on SIGHUP,
+	// and stop when the Windows service manager stops the service.
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	signalCtx = service.context(signalCtx)
+	var reloading func() bool
+	switch {
+	case command == "serve":
+		var daemonCtx context.Context
+		daemonCtx, reloading = daemonContext(signalCtx)
+		err = loader.Serve(daemonCtx, loader.ServerOptions{
+			Addr:              *listen,
+			SpoolDir:          *spoolDir,
+			Token:             *serveToken,
+			Insecure:          *serveInsecure,
+			MaxUploadBytes:    *serveMaxUpload,
+			JobsIndex:         *jobsIndex,
+			GRPCAddr:          *grpcListen,
+			GRPCFlushInterval: *grpcFlushInterval,
+			Load:              opts,
+		})
+	case command == "validate":
+		stop()
+		var report loader.ValidationReport
+		report, err = loader.Validate(context.Background(), opts)
+		for _, problem := range report.Problems {
+			log.Error().Str("check", problem.Check).Str("cluster", problem.Cluster).Err(problem.Err).Msg("Validation problem")
+		}
+		if err == nil {
+			log.Info().Int("documents", report.Documents).Int("clusters", len(report.Clusters)).Msg("Validation passed; nothing was written")
+		}
+	case command == "diff":
+		stop()
+		_, err = loader.Diff(context.Background(), loader.DiffOptions{Output: os.Stdout, Load: opts})
+	case command == "convert":
+		stop()
+		_, err = loader.Convert(context.Background(), loader.ConvertOptions{Output: *convertOutput, Format: *convertFormat, Load: opts})
+	case command == "split":
+		stop()
+		_, err = loader.Split(context.Background(), loader.SplitOptions{Documents: *splitDocuments, MaxBytes: int64(*splitSize), OutputDir: *splitDir, Load: opts})
+	case command == "dump-schema":
+		stop()
+		_, err = loader.DumpSchema(context.Background(), loader.DumpSchemaOptions{Dir: *schemaDir, Load: opts})
+	case command == "snapshot":
+		stop()
+		_, err = loader.Snapshot(context.Background(), loader.SnapshotOptions{Repository: *snapshotRepository, Name: *snapshotName, NoWait: !*snapshotWait, Load: opts})
+	case command == "restore":
+		stop()
+		_, err = loader.Restore(context.Background(), loader.RestoreOptions{
+			Repository:        *snapshotRepository,
+			Name:              *snapshotName,
+			RenamePattern:     *restoreRenamePattern,
+			RenameReplacement: *restoreRenameReplacement,
+			NoWait:            !*snapshotWait,
+			Load:              opts,
+		})
+	case command == "cancel":
+		stop()
+		_, err = loader.Cancel(context.Background(), loader.CancelOptions{OpaqueID: *opaqueID, DryRun: *cancelDryRun, Load: opts})
+	case command == "generate":
+		stop()
+		err = runGenerate(context.Background(), loader.GenerateOptions{SpecFile: *generateSpec, Count: *generateCount, Seed: *generateSeed}, *generateOutput, opts)
+	case command == "retry":
+		stop()
+		_, err = loader.Retry(context.Background(), loader.RetryOptions{Queue: *retryQueue, Load: opts})
+	case *source != "":
+		var daemonCtx context.Context
+		daemonCtx, reloading = daemonContext(signalCtx)
+		err = loader.Consume(daemonCtx, loader.ConsumeOptions{Source: *source, Collection: *collection, Query: *mongoQuery, FlushInterval: *sourceFlushInterval, Load: opts})
+	case *watchDir != "":
+		var daemonCtx context.Context
+		daemonCtx, reloading = daemonContext(signalCtx)
+		err = loader.Watch(daemonCtx, loader.WatchOptions{
+			Dir:       *watchDir,
+			Pattern:   *watchPattern,
+			Interval:  *watchInterval,
+			JobsIndex: *jobsIndex,
+			Load:      opts,
+		})
+	case *schedule != "":
+		var daemonCtx context.Context
+		daemonCtx, reloading = daemonContext(signalCtx)
+		err = loader.Schedule(daemonCtx, loader.ScheduleOptions{Expr: *schedule, JobsIndex: *jobsIndex, Load: opts})
+	case *benchmark:
+		stop()
+		_, err = loader.Benchmark(context.Background(), loader.BenchmarkOptions{Throwaway: *benchmarkThrowaway, Output: os.Stdout, Load: opts})
+	case *tail:
+		_, err = loader.Run(signalCtx, opts)
+	default:
+		stop()
+		_, err = loader.Run(context.Background(), opts)
+	}
+	profiles.stop()
+	if err == nil && reloading != nil && reloading() {
+		err = reexec()
+	}
 	if err != nil {
 		if errors.Is(err, loader.ErrInvalidOptions) {
 			flag.Usage()
 		}
 		log.Error().Err(err).Msg("Loader run failed")
+		service.finish(err)
 		os.Exit(1)
 	}
+	service.finish(nil)
 }
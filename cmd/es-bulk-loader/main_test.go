@@ -24,3 +24,81 @@ func TestNewConsoleLoggerIncludesTimestamp(t *testing.T) {
 		t.Fatalf("expected HH:MM:SS timestamp in console output, got: %s", logs)
 	}
 }
+
+// TestSplitCommand verifies behavior for the related scenario.
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		args        []string
+		wantCommand string
+		wantArgs    []string
+	}{
+		{args: nil, wantCommand: "", wantArgs: nil},
+		{args: []string{"-index", "cards"}, wantCommand: "", wantArgs: []string{"-index", "cards"}},
+		{args: []string{"serve", "-listen", ":9000"}, wantCommand: "serve", wantArgs: []string{"-listen", ":9000"}},
+	}
+	for _, tt := range tests {
+		command, args := splitCommand(tt.args)
+		if command != tt.wantCommand || strings.Join(args, " ") != strings.Join(tt.wantArgs, " ") {
+			t.Fatalf("splitCommand(%q) = %q, %q; want %q, %q", tt.args, command, args, tt.wantCommand, tt.wantArgs)
+		}
+	}
+}
+
+// TestURLListFlagValue verifies behavior for the related scenario.
+func TestURLListFlagValue(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{values: nil, want: "http://localhost:9200"},
+		{values: []string{"https://a:9200"}, want: "https://a:9200"},
+		{values: []string{"https://a:9200", "https://b:9200"}, want: "https://a:9200,https://b:9200"},
+		{values: []string{"https://a:9200, https://b:9200,"}, want: "https://a:9200,https://b:9200"},
+	}
+	for _, tt := range tests {
+		urls := &urlListFlagValue{urls: []string{"http://localhost:9200"}}
+		for _, value := range tt.values {
+			if err := urls.Set(value); err != nil {
+				t.Fatalf("Set(%q) returned error: %v", value, err)
+			}
+		}
+		if got := urls.String(); got != tt.want {
+			t.Fatalf("after %q got %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+// TestByteSizeFlagValue verifies behavior for the related scenario.
+func TestByteSizeFlagValue(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "1073741824", want: 1 << 30},
+		{value: "512MiB", want: 512 << 20},
+		{value: "512m", want: 512 << 20},
+		{value: "2GB", want: 2000000000},
+		{value: "1.5 GiB", want: 3 << 29},
+		{value: "64kb", want: 64000},
+		{value: "12 parsecs", wantErr: true},
+		{value: "-1G", wantErr: true},
+		{value: "MiB", wantErr: true},
+	}
+	for _, tt := range tests {
+		var size byteSizeFlagValue
+		err := size.Set(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Fatalf("Set(%q) expected an error, got %d", tt.value, size)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Set(%q) returned error: %v", tt.value, err)
+		}
+		if int64(size) != tt.want {
+			t.Fatalf("Set(%q) = %d, want %d", tt.value, size, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+
+	"github.com/rs/zerolog/log"
+)
+
+// profiler holds the profiles requested by -pprof-listen, -cpuprofile, and
+// -memprofile for the lifetime of one process.
+type profiler struct {
+	listener net.Listener
+	server   *http.Server
+	cpuFile  *os.File
+	memPath  string
+}
+
+// startProfiling starts the requested profiles. Every argument is optional;
+// with none set it returns a profiler whose stop does nothing.
+func startProfiling(listen, cpuPath, memPath string) (*profiler, error) {
+	p := &profiler{memPath: memPath}
+	if cpuPath != "" {
+		file, err := os.Create(cpuPath)
+		if err != nil {
+			return nil, fmt.Errorf("creating -cpuprofile: %w", err)
+		}
+		if err := runtimepprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("starting CPU profile: %w", err)
+		}
+		p.cpuFile = file
+	}
+	if listen != "" {
+		listener, err := net.Listen("tcp", listen)
+		if err != nil {
+			p.stop()
+			return nil, fmt.Errorf("listening on -pprof-listen: %w", err)
+		}
+		// A private mux keeps the profiling endpoints off any other server.
+		// cmdline is left out because os.Args can carry -pass or -apiKey.
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		p.listener = listener
+		p.server = &http.Server{Handler: mux}
+		go func() {
+			if err := p.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Warn().Err(err).Msg("pprof server stopped")
+			}
+		}()
+		log.Info().Str("addr", listener.Addr().String()).Msg("Serving pprof endpoints under /debug/pprof/")
+	}
+	return p, nil
+}
+
+// addr returns the pprof listen address, or "" without -pprof-listen.
+func (p *profiler) addr() string {
+	if p.listener == nil {
+		return ""
+	}
+	return p.listener.Addr().String()
+}
+
+// stop finishes the CPU profile, writes the heap profile, and closes the
+// pprof server. It must run before the process exits, since os.Exit skips
+// deferred calls.
+func (p *profiler) stop() {
+	if p.cpuFile != nil {
+		runtimepprof.StopCPUProfile()
+		if err := p.cpuFile.Close(); err != nil {
+			log.Warn().Err(err).Msg("Writing CPU profile")
+		}
+		p.cpuFile = nil
+	}
+	if p.memPath != "" {
+		if err := writeHeapProfile(p.memPath); err != nil {
+			log.Warn().Err(err).Msg("Writing memory profile")
+		}
+		p.memPath = ""
+	}
+	if p.server != nil {
+		_ = p.server.Close()
+		p.server = nil
+	}
+}
+
+// writeHeapProfile writes a heap profile to path after a GC, so it shows
+// live memory as well as the allocations sampled during the run.
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	runtime.GC()
+	if err := runtimepprof.WriteHeapProfile(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
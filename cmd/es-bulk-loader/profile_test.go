@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestStartProfilingWritesProfiles verifies behavior for the related scenario.
+func TestStartProfilingWritesProfiles(t *testing.T) {
+	dir := t.TempDir()
+	cpuPath := filepath.Join(dir, "cpu.pprof")
+	memPath := filepath.Join(dir, "mem.pprof")
+
+	profiles, err := startProfiling("127.0.0.1:0", cpuPath, memPath)
+	if err != nil {
+		t.Fatalf("startProfiling returned error: %v", err)
+	}
+	res, err := http.Get("http://" + profiles.addr() + "/debug/pprof/goroutine?debug=1")
+	if err != nil {
+		profiles.stop()
+		t.Fatalf("GET /debug/pprof/goroutine returned error: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	cmdline, err := http.Get("http://" + profiles.addr() + "/debug/pprof/cmdline")
+	if err != nil {
+		profiles.stop()
+		t.Fatalf("GET /debug/pprof/cmdline returned error: %v", err)
+	}
+	cmdline.Body.Close()
+	profiles.stop()
+	profiles.stop()
+
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(body), "goroutine profile") {
+		t.Fatalf("unexpected pprof response %d: %q", res.StatusCode, body)
+	}
+	if cmdline.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected /debug/pprof/cmdline to be withheld, got %d", cmdline.StatusCode)
+	}
+	for _, path := range []string{cpuPath, memPath} {
+		info, err := os.Stat(path)
+		if err != nil || info.Size() == 0 {
+			t.Fatalf("expected a non-empty profile at %s, got %v", path, err)
+		}
+	}
+	if _, err := http.Get("http://" + profiles.listener.Addr().String() + "/debug/pprof/"); err == nil {
+		t.Fatal("expected the pprof server to be closed after stop")
+	}
+}
+
+// TestStartProfilingRejectsBadPaths verifies behavior for the related scenario.
+func TestStartProfilingRejectsBadPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		listen string
+		cpu    string
+		want   string
+	}{
+		{name: "cpu profile directory missing", cpu: filepath.Join(t.TempDir(), "missing", "cpu.pprof"), want: "-cpuprofile"},
+		{name: "bad listen address", listen: "256.0.0.1:bad", want: "-pprof-listen"},
+	}
+	for _, tt := range tests {
+		_, err := startProfiling(tt.listen, tt.cpu, "")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error mentioning %s, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	profiles, err := startProfiling("", "", "")
+	if err != nil || profiles.addr() != "" {
+		t.Fatalf("expected an idle profiler, got %+v, %v", profiles, err)
+	}
+	profiles.stop()
+}
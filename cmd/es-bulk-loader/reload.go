@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/jnovack/es-bulk-loader/pkg/loader"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Daemon Reload ─────────────────────────────────────────────────────────────
+
+// daemonContext returns the context a daemon mode runs under: it ends with
+// ctx or on SIGHUP. The returned function reports whether SIGHUP ended it,
+// so the caller reloads instead of exiting. systemd hears STOPPING=1 or
+// RELOADING=1 as soon as either signal arrives.
+func daemonContext(ctx context.Context) (context.Context, func() bool) {
+	var reloading atomic.Bool
+	daemonCtx, cancel := context.WithCancel(ctx)
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hangup)
+		select {
+		case <-hangup:
+			reloading.Store(true)
+			log.Info().Msg("Received SIGHUP; reloading configuration")
+			notify("RELOADING=1")
+		case <-daemonCtx.Done():
+			notify("STOPPING=1")
+		}
+		cancel()
+	}()
+	return daemonCtx, reloading.Load
+}
+
+// notify sends state to systemd, logging rather than failing on errors.
+func notify(state string) {
+	if err := loader.NotifySystemd(state); err != nil {
+		log.Warn().Err(err).Msg("Notifying systemd failed")
+	}
+}
+
+// reexec replaces the process with a fresh start of the same binary and
+// arguments, which re-reads the config file, environment, and credentials.
+// The process ID stays the same, so systemd keeps supervising it and any
+// sockets it passed are inherited again.
+func reexec() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("reloading: %w", err)
+	}
+	if err := syscall.Exec(executable, os.Args, os.Environ()); err != nil {
+		return fmt.Errorf("reloading: re-executing %s: %w", executable, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// ─── Windows Service ───────────────────────────────────────────────────────────
+
+// defaultServiceName is the service and event log source install-service
+// registers when -service-name is not given.
+const defaultServiceName = "es-bulk-loader"
+
+// Event IDs written to the Windows event log, one per severity.
+const (
+	eventIDInfo    = 1
+	eventIDWarning = 2
+	eventIDError   = 3
+)
+
+// checkServiceOptions reports why the flags cannot run as a service: it
+// must stay running with -watch, -schedule, or -source, and the service
+// starts in the system directory, so -watch and -config must be absolute
+// paths.
+func checkServiceOptions(watchDir, schedule, source, configFile string) error {
+	if watchDir == "" && schedule == "" && source == "" {
+		return errors.New("install-service needs -watch, -schedule, or -source so the service stays running")
+	}
+	if watchDir != "" && !filepath.IsAbs(watchDir) {
+		return errors.New("install-service needs an absolute -watch path")
+	}
+	if configFile != "" && !filepath.IsAbs(configFile) {
+		return errors.New("install-service needs an absolute -config path")
+	}
+	return nil
+}
+
+// serviceArgs returns the arguments the installed service starts with: args
+// as given to install-service, led by -workdir so relative paths resolve
+// where they did at install time.
+func serviceArgs(args []string, workdir string) []string {
+	for _, arg := range args {
+		if arg == "-workdir" || arg == "--workdir" || strings.HasPrefix(arg, "-workdir=") || strings.HasPrefix(arg, "--workdir=") {
+			return args
+		}
+	}
+	return append([]string{"-workdir=" + workdir}, args...)
+}
+
+// serviceHandle links a process run by the Windows service control manager
+// to it. A nil handle means the process is not a service.
+type serviceHandle struct {
+	// log receives the process log in place of stderr.
+	log zerolog.LevelWriter
+	// stop is closed when the service manager asks the service to stop.
+	stop <-chan struct{}
+	// done receives the result of the daemon mode once it returns.
+	done chan<- error
+	// exited is closed once the service manager has been told the service
+	// stopped.
+	exited <-chan struct{}
+}
+
+// context returns ctx, ended early when the service manager stops the
+// service.
+func (s *serviceHandle) context(ctx context.Context) context.Context {
+	if s == nil {
+		return ctx
+	}
+	serviceCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-s.stop:
+		case <-serviceCtx.Done():
+		}
+		cancel()
+	}()
+	return serviceCtx
+}
+
+// finish reports err as the service's result and waits until the service
+// manager has been told it stopped.
+func (s *serviceHandle) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.done <- err
+	<-s.exited
+}
+
+// eventSink is the part of a Windows event log the logger writes to.
+type eventSink interface {
+	Info(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+}
+
+// eventLogWriter writes each zerolog entry to an event log as one event of
+// the matching severity, in the console format without the timestamp the
+// event log already records.
+type eventLogWriter struct {
+	sink eventSink
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	out  zerolog.ConsoleWriter
+}
+
+// newEventLogWriter returns a zerolog writer that logs to sink.
+func newEventLogWriter(sink eventSink) *eventLogWriter {
+	w := &eventLogWriter{sink: sink}
+	w.out = zerolog.ConsoleWriter{Out: &w.buf, NoColor: true, PartsExclude: []string{zerolog.TimestampFieldName}}
+	return w
+}
+
+// Write logs p, an entry without a level, as information.
+func (w *eventLogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel logs p as an event of level's severity.
+func (w *eventLogWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Reset()
+	if _, err := w.out.Write(p); err != nil {
+		return 0, err
+	}
+	msg := strings.TrimSpace(w.buf.String())
+	var err error
+	switch {
+	case level >= zerolog.ErrorLevel && level <= zerolog.PanicLevel:
+		err = w.sink.Error(eventIDError, msg)
+	case level == zerolog.WarnLevel:
+		err = w.sink.Warning(eventIDWarning, msg)
+	default:
+		err = w.sink.Info(eventIDInfo, msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// errServiceUnsupported is returned by the service commands off Windows.
+var errServiceUnsupported = errors.New("Windows services are only available on Windows; use a systemd unit elsewhere")
+
+// installService is only implemented on Windows.
+func installService(string, []string) error {
+	return errServiceUnsupported
+}
+
+// uninstallService is only implemented on Windows.
+func uninstallService(string) error {
+	return errServiceUnsupported
+}
+
+// startService returns nil: only Windows runs the process as a service.
+func startService(string) (*serviceHandle, error) {
+	return nil, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// recordedEvent is one event written to a fakeEventSink.
+type recordedEvent struct {
+	severity string
+	eid      uint32
+	msg      string
+}
+
+// fakeEventSink records the events written to it.
+type fakeEventSink struct {
+	events []recordedEvent
+}
+
+func (f *fakeEventSink) Info(eid uint32, msg string) error {
+	f.events = append(f.events, recordedEvent{"info", eid, msg})
+	return nil
+}
+
+func (f *fakeEventSink) Warning(eid uint32, msg string) error {
+	f.events = append(f.events, recordedEvent{"warning", eid, msg})
+	return nil
+}
+
+func (f *fakeEventSink) Error(eid uint32, msg string) error {
+	f.events = append(f.events, recordedEvent{"error", eid, msg})
+	return nil
+}
+
+// TestCheckServiceOptions verifies behavior for the related scenario.
+func TestCheckServiceOptions(t *testing.T) {
+	t.Parallel()
+
+	abs := filepath.Join(t.TempDir(), "drop")
+	tests := []struct {
+		name     string
+		watch    string
+		schedule string
+		source   string
+		config   string
+		wantErr  string
+	}{
+		{name: "watch", watch: abs},
+		{name: "schedule", schedule: "@hourly", config: filepath.Join(abs, "loader.conf")},
+		{name: "source", source: "sqs://sqs.us-east-1.amazonaws.com/123456789012/events"},
+		{name: "one-shot", wantErr: "-watch, -schedule, or -source"},
+		{name: "relative watch", watch: "drop", wantErr: "absolute -watch"},
+		{name: "relative config", schedule: "@daily", config: "loader.conf", wantErr: "absolute -config"},
+	}
+	for _, tt := range tests {
+		err := checkServiceOptions(tt.watch, tt.schedule, tt.source, tt.config)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// TestServiceArgs verifies behavior for the related scenario.
+func TestServiceArgs(t *testing.T) {
+	t.Parallel()
+
+	got := serviceArgs([]string{"-watch", `C:\drop`, "-index", "logs"}, `C:\loader`)
+	if want := []string{`-workdir=C:\loader`, "-watch", `C:\drop`, "-index", "logs"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	given := []string{"-workdir", `D:\jobs`, "-watch", `C:\drop`}
+	if got := serviceArgs(given, `C:\loader`); !slices.Equal(got, given) {
+		t.Fatalf("expected an explicit -workdir to be kept, got %v", got)
+	}
+}
+
+// TestEventLogWriter verifies behavior for the related scenario.
+func TestEventLogWriter(t *testing.T) {
+	t.Parallel()
+
+	sink := &fakeEventSink{}
+	logger := zerolog.New(newEventLogWriter(sink)).With().Timestamp().Logger()
+	logger.Info().Str("file", "a.json").Msg("Loaded file")
+	logger.Warn().Msg("Slow batch")
+	logger.Error().Err(errors.New("boom")).Msg("Load failed")
+
+	want := []struct{ severity, msg string }{
+		{"info", "INF Loaded file file=a.json"},
+		{"warning", "WRN Slow batch"},
+		{"error", "ERR Load failed error=boom"},
+	}
+	if len(sink.events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), sink.events)
+	}
+	for i, event := range sink.events {
+		if event.severity != want[i].severity || event.msg != want[i].msg {
+			t.Fatalf("event %d: expected %s %q, got %s %q", i, want[i].severity, want[i].msg, event.severity, event.msg)
+		}
+	}
+	if sink.events[1].eid != eventIDWarning || sink.events[2].eid != eventIDError {
+		t.Fatalf("unexpected event IDs %+v", sink.events)
+	}
+}
+
+// TestServiceHandle verifies behavior for the related scenario.
+func TestServiceHandle(t *testing.T) {
+	t.Parallel()
+
+	var none *serviceHandle
+	ctx := context.Background()
+	if none.context(ctx) != ctx {
+		t.Fatal("expected no service to leave the context alone")
+	}
+	none.finish(nil)
+
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	exited := make(chan struct{})
+	service := &serviceHandle{stop: stop, done: done, exited: exited}
+	serviceCtx := service.context(ctx)
+	close(stop)
+	select {
+	case <-serviceCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a service stop to end the context")
+	}
+
+	boom := errors.New("boom")
+	go func() {
+		if err := <-done; err != boom {
+			t.Errorf("expected the run result, got %v", err)
+		}
+		close(exited)
+	}()
+	service.finish(boom)
+}
@@ -0,0 +1,141 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// installService registers the running executable as an automatically
+// started service called name that runs with args, restarts after a failure,
+// and logs to the Windows event log under name.
+func installService(name string, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("installing service %s: %w", name, err)
+	}
+	manager, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to the service manager: %w", err)
+	}
+	defer manager.Disconnect()
+	if existing, err := manager.OpenService(name); err == nil {
+		existing.Close()
+		return fmt.Errorf("service %s is already installed; run uninstall-service first", name)
+	}
+	service, err := manager.CreateService(name, executable, mgr.Config{
+		DisplayName: name,
+		Description: "Loads data files into Elasticsearch with es-bulk-loader.",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return fmt.Errorf("installing service %s: %w", name, err)
+	}
+	defer service.Close()
+	if err := service.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 10 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+	}, uint32((24 * time.Hour).Seconds())); err != nil {
+		_ = service.Delete()
+		return fmt.Errorf("setting recovery actions of service %s: %w", name, err)
+	}
+	if err := eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		_ = service.Delete()
+		return fmt.Errorf("registering event log source %s: %w", name, err)
+	}
+	return nil
+}
+
+// uninstallService stops and removes the service called name and its event
+// log source.
+func uninstallService(name string) error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to the service manager: %w", err)
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %w", name, err)
+	}
+	defer service.Close()
+	if status, err := service.Control(svc.Stop); err == nil {
+		for deadline := time.Now().Add(30 * time.Second); status.State != svc.Stopped && time.Now().Before(deadline); {
+			time.Sleep(300 * time.Millisecond)
+			if status, err = service.Query(); err != nil {
+				break
+			}
+		}
+	}
+	if err := service.Delete(); err != nil {
+		return fmt.Errorf("removing service %s: %w", name, err)
+	}
+	if err := eventlog.Remove(name); err != nil {
+		return fmt.Errorf("removing event log source %s: %w", name, err)
+	}
+	return nil
+}
+
+// startService connects to the service manager when the process was started
+// as the service called name, and returns nil otherwise.
+func startService(name string) (*serviceHandle, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return nil, err
+	}
+	events, err := eventlog.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("opening event log source %s: %w", name, err)
+	}
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	exited := make(chan struct{})
+	handler := &serviceHandler{stop: stop, done: done}
+	go func() {
+		defer close(exited)
+		defer events.Close()
+		if err := svc.Run(name, handler); err != nil {
+			_ = events.Error(eventIDError, fmt.Sprintf("Running service %s failed: %v", name, err))
+		}
+	}()
+	return &serviceHandle{log: newEventLogWriter(events), stop: stop, done: done, exited: exited}, nil
+}
+
+// serviceHandler answers the service manager for a running daemon mode.
+type serviceHandler struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     <-chan error
+}
+
+// Execute reports the service running, turns stop and shutdown requests into
+// a stop of the daemon mode, and reports the service stopped once it ends.
+func (h *serviceHandler) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case err := <-h.done:
+			status <- svc.Status{State: svc.StopPending}
+			if err != nil {
+				return true, 1
+			}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				h.stopOnce.Do(func() { close(h.stop) })
+				status <- svc.Status{State: svc.StopPending}
+			}
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package loader
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Access Log Input ──────────────────────────────────────────────────────────
+
+const (
+	// accessLogCommon is Apache's common log format.
+	accessLogCommon = `%h %l %u %t "%r" %>s %b`
+	// accessLogCombined adds the referrer and user agent, as Apache's
+	// combined format and nginx's default do.
+	accessLogCombined = accessLogCommon + ` "%{Referer}i" "%{User-Agent}i"`
+)
+
+// Conversions of captured access log values; a field without one is kept
+// as a string.
+const (
+	logValueInteger = "integer"
+	logValueFloat   = "float"
+	// logValueBytes is a byte count that logs as "-" for none.
+	logValueBytes = "bytes"
+	// logValueCLFTime is a 02/Jan/2006:15:04:05 -0700 time.
+	logValueCLFTime = "clf_time"
+	logValueISOTime = "iso_time"
+	// logValueEpoch is seconds since the epoch with a fraction.
+	logValueEpoch = "epoch"
+	// logValueRequest is a request line, split into method, path, and
+	// protocol.
+	logValueRequest = "request"
+)
+
+// accessLogFormat is a compiled -log-format: a pattern matching one line,
+// with a field for each capture group.
+type accessLogFormat struct {
+	pattern *regexp.Regexp
+	fields  []accessLogField
+}
+
+// accessLogField is where one captured value goes and how it converts.
+type accessLogField struct {
+	name string
+	kind string
+	// pattern, when set, is what the value may match, for fields logged
+	// next to each other.
+	pattern string
+	quoted  bool
+}
+
+// apacheLogFields maps the Apache LogFormat directives that take no
+// parameter to fields.
+var apacheLogFields = map[string]accessLogField{
+	"h": {name: "ip"}, "a": {name: "ip"}, "A": {name: "server_ip"},
+	"l": {name: "ident"}, "u": {name: "user"},
+	"t": {name: "@timestamp", kind: logValueCLFTime},
+	"r": {name: "request", kind: logValueRequest},
+	"s": {name: "status", kind: logValueInteger}, ">s": {name: "status", kind: logValueInteger},
+	"b": {name: "bytes", kind: logValueBytes}, "B": {name: "bytes", kind: logValueBytes},
+	"I": {name: "bytes_in", kind: logValueBytes}, "O": {name: "bytes_out", kind: logValueBytes},
+	"D": {name: "duration_us", kind: logValueInteger}, "T": {name: "duration_s", kind: logValueInteger},
+	"m": {name: "method"}, "U": {name: "path", pattern: `[^\s?]*`}, "q": {name: "query", pattern: `(?:\?\S*)?`}, "H": {name: "protocol"},
+	"v": {name: "vhost"}, "V": {name: "vhost"}, "p": {name: "port", kind: logValueInteger},
+	"P": {name: "pid", kind: logValueInteger}, "X": {name: "connection_status"},
+	"k": {name: "keepalive_requests", kind: logValueInteger}, "L": {name: "log_id"},
+	"f": {name: "filename"}, "R": {name: "handler"},
+}
+
+// nginxLogFields maps nginx log_format variables to fields; other
+// variables keep their name, less an http_ prefix.
+var nginxLogFields = map[string]accessLogField{
+	"remote_addr": {name: "ip"}, "realip_remote_addr": {name: "ip"}, "remote_user": {name: "user"},
+	"time_local":      {name: "@timestamp", kind: logValueCLFTime},
+	"time_iso8601":    {name: "@timestamp", kind: logValueISOTime},
+	"msec":            {name: "@timestamp", kind: logValueEpoch},
+	"request":         {name: "request", kind: logValueRequest},
+	"status":          {name: "status", kind: logValueInteger},
+	"body_bytes_sent": {name: "bytes", kind: logValueBytes},
+	"bytes_sent":      {name: "bytes_sent", kind: logValueBytes},
+	"request_length":  {name: "request_length", kind: logValueInteger},
+	"request_time":    {name: "request_time", kind: logValueFloat},
+	"http_referer":    {name: "referrer"}, "http_user_agent": {name: "user_agent"},
+	"request_method": {name: "method"}, "request_uri": {name: "path"}, "server_protocol": {name: "protocol"},
+	"server_port": {name: "port", kind: logValueInteger}, "pid": {name: "pid", kind: logValueInteger},
+}
+
+// nginxVariable matches an nginx $name or ${name}.
+var nginxVariable = regexp.MustCompile(`^\$(?:\{(\w+)\}|(\w+))`)
+
+// apacheDirective matches an Apache %directive with its optional < or >
+// and {parameter}.
+var apacheDirective = regexp.MustCompile(`^%([<>]?)(?:\{([^}]*)\})?([a-zA-Z%])`)
+
+// compileAccessLogFormat compiles combined, common, or a custom Apache
+// LogFormat or nginx log_format string. Text after the last field of a
+// line, following a space, is ignored.
+func compileAccessLogFormat(spec string) (*accessLogFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(spec)) {
+	case "", "combined":
+		spec = accessLogCombined
+	case "common":
+		spec = accessLogCommon
+	}
+	// Formats copied from httpd.conf escape their quotes.
+	spec = strings.ReplaceAll(spec, `\"`, `"`)
+	type token struct {
+		literal string
+		field   *accessLogField
+		// bracketed values are written inside [ and ], as Apache's %t is.
+		bracketed bool
+	}
+	var tokens []token
+	literal := ""
+	for rest := spec; rest != ""; {
+		var field accessLogField
+		var size int
+		bracketed := false
+		if match := apacheDirective.FindStringSubmatch(rest); match != nil {
+			size = len(match[0])
+			if match[3] == "%" {
+				literal += "%"
+				rest = rest[size:]
+				continue
+			}
+			var err error
+			if field, err = apacheLogField(match[1], match[2], match[3]); err != nil {
+				return nil, err
+			}
+			bracketed = match[3] == "t"
+		} else if match := nginxVariable.FindStringSubmatch(rest); match != nil {
+			size = len(match[0])
+			field = nginxLogField(match[1] + match[2])
+		} else {
+			literal += rest[:1]
+			rest = rest[1:]
+			continue
+		}
+		tokens = append(tokens, token{literal: literal}, token{field: &field, bracketed: bracketed})
+		literal = ""
+		rest = rest[size:]
+	}
+	tokens = append(tokens, token{literal: literal})
+
+	format := &accessLogFormat{}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for i, tok := range tokens {
+		if tok.field == nil {
+			pattern.WriteString(regexp.QuoteMeta(tok.literal))
+			continue
+		}
+		before, after := tokens[i-1].literal, tokens[i+1].literal
+		field := *tok.field
+		switch {
+		case tok.bracketed:
+			pattern.WriteString(`\[([^\]]*)\]`)
+		case field.pattern != "":
+			pattern.WriteString("(" + field.pattern + ")")
+		case strings.HasSuffix(before, `"`) && strings.HasPrefix(after, `"`):
+			field.quoted = true
+			pattern.WriteString(`((?:[^"\\]|\\.)*)`)
+		case (after == "" && i+2 == len(tokens)) || strings.HasPrefix(after, " "):
+			pattern.WriteString(`(\S*)`)
+		default:
+			pattern.WriteString(`(.*?)`)
+		}
+		format.fields = append(format.fields, field)
+	}
+	if len(format.fields) == 0 {
+		return nil, fmt.Errorf("-log-format %q has no %%directives or $variables", spec)
+	}
+	pattern.WriteString(`(?:\s.*)?$`)
+	compiled, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("-log-format %q: %w", spec, err)
+	}
+	format.pattern = compiled
+	return format, nil
+}
+
+// apacheLogField returns the field of an Apache directive: %{Name}i request
+// headers, %{Name}o response headers, %{Name}e environment variables,
+// %{Name}n notes, and %{Name}C cookies take their parameter for a name.
+func apacheLogField(modifier, param, letter string) (accessLogField, error) {
+	name := strings.ToLower(strings.ReplaceAll(param, "-", "_"))
+	switch {
+	case letter == "i" && strings.EqualFold(param, "Referer"):
+		return accessLogField{name: "referrer"}, nil
+	case letter == "i" && strings.EqualFold(param, "User-Agent"):
+		return accessLogField{name: "user_agent"}, nil
+	case param != "" && letter == "i":
+		return accessLogField{name: name}, nil
+	case param != "" && letter == "o":
+		return accessLogField{name: "response_" + name}, nil
+	case param != "" && (letter == "e" || letter == "n"):
+		return accessLogField{name: name}, nil
+	case param != "" && letter == "C":
+		return accessLogField{name: "cookie_" + name}, nil
+	case letter == "a" && param == "c":
+		return accessLogField{name: "ip"}, nil
+	case letter == "T" && (param == "ms" || param == "us"):
+		return accessLogField{name: "duration_" + param, kind: logValueInteger}, nil
+	case letter == "T" && param == "s":
+		return apacheLogFields["T"], nil
+	}
+	if field, ok := apacheLogFields[modifier+letter]; ok && param == "" {
+		return field, nil
+	}
+	if field, ok := apacheLogFields[letter]; ok && param == "" {
+		return field, nil
+	}
+	return accessLogField{}, fmt.Errorf("-log-format directive %%%s{%s}%s is not supported", modifier, param, letter)
+}
+
+// nginxLogField returns the field of an nginx variable.
+func nginxLogField(variable string) accessLogField {
+	if field, ok := nginxLogFields[variable]; ok {
+		return field
+	}
+	return accessLogField{name: strings.TrimPrefix(variable, "http_")}
+}
+
+// parse returns the document of one line, or an error if the line does not
+// match or a value does not convert. Values logged as "-" are left out,
+// except byte counts, which are 0.
+func (f *accessLogFormat) parse(line string) (map[string]interface{}, error) {
+	match := f.pattern.FindStringSubmatch(line)
+	if match == nil {
+		return nil, errors.New("line does not match -log-format")
+	}
+	doc := make(map[string]interface{}, len(f.fields)+2)
+	for i, field := range f.fields {
+		value := match[i+1]
+		if field.quoted {
+			value = unescapeLogValue(value)
+		}
+		if value == "-" || value == "" {
+			if field.kind == logValueBytes {
+				doc[field.name] = int64(0)
+			}
+			continue
+		}
+		if err := setLogValue(doc, field, value); err != nil {
+			return nil, fmt.Errorf("%s %q: %w", field.name, value, err)
+		}
+	}
+	return doc, nil
+}
+
+// setLogValue converts value as field says and sets it in doc.
+func setLogValue(doc map[string]interface{}, field accessLogField, value string) error {
+	switch field.kind {
+	case logValueInteger, logValueBytes:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+		setField(doc, field.name, n)
+	case logValueFloat:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("not a number")
+		}
+		setField(doc, field.name, n)
+	case logValueCLFTime:
+		t, err := time.Parse("02/Jan/2006:15:04:05 -0700", value)
+		if err != nil {
+			return errors.New("not a dd/Mon/yyyy:hh:mm:ss +zzzz time")
+		}
+		setField(doc, field.name, t.Format(time.RFC3339))
+	case logValueISOTime:
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return errors.New("not an ISO 8601 time")
+		}
+		setField(doc, field.name, value)
+	case logValueEpoch:
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("not seconds since the epoch")
+		}
+		setField(doc, field.name, time.UnixMilli(int64(seconds*1000)).UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+	case logValueRequest:
+		parts := strings.Split(value, " ")
+		switch {
+		case len(parts) == 3 && strings.HasPrefix(parts[2], "HTTP/"):
+			doc["method"], doc["path"], doc["protocol"] = parts[0], parts[1], parts[2]
+		case len(parts) == 2:
+			doc["method"], doc["path"] = parts[0], parts[1]
+		default:
+			// Scanners and TLS handshakes on a plain port log garbage here.
+			doc[field.name] = value
+		}
+	default:
+		setField(doc, field.name, value)
+	}
+	return nil
+}
+
+// unescapeLogValue undoes the \" \\ and \xHH escaping Apache and nginx
+// apply inside quoted values.
+func unescapeLogValue(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			out.WriteByte(value[i])
+			continue
+		}
+		switch next := value[i+1]; next {
+		case 'x':
+			if i+4 <= len(value) {
+				if b, err := strconv.ParseUint(value[i+2:i+4], 16, 8); err == nil {
+					out.WriteByte(byte(b))
+					i += 3
+					continue
+				}
+			}
+			out.WriteByte('\\')
+		case 'n':
+			out.WriteByte('\n')
+			i++
+		case 't':
+			out.WriteByte('\t')
+			i++
+		default:
+			out.WriteByte(next)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// lineParser turns one line of a text log into a document.
+type lineParser interface {
+	parse(line string) (map[string]interface{}, error)
+}
+
+// logLineReader parses one text log line per document, for -format
+// accesslog and regex.
+type logLineReader struct {
+	file    io.ReadCloser
+	reader  *bufio.Reader
+	parser  lineParser
+	line    int
+	matched bool
+}
+
+// newLogLineReader returns a reader of file parsed by parser.
+func newLogLineReader(file io.ReadCloser, parser lineParser) *logLineReader {
+	return &logLineReader{file: file, reader: bufio.NewReader(file), parser: parser}
+}
+
+// next parses the following non-blank line. A first line that does not
+// parse fails the load, as the format is likely wrong; later ones are
+// logged and skipped.
+func (r *logLineReader) next() (map[string]interface{}, error) {
+	for {
+		text, err := r.reader.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || text == "") {
+			return nil, err
+		}
+		r.line++
+		text = strings.TrimRight(text, "\r\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		doc, err := r.parser.parse(text)
+		if err == nil {
+			r.matched = true
+			return doc, nil
+		}
+		if !r.matched {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		log.Warn().Err(err).Int("line", r.line).Msg("Skipping log line")
+	}
+}
+
+// close releases the underlying file.
+func (r *logLineReader) close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,119 @@
+package loader
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestAccessLogReaderCombined verifies behavior for the related scenario.
+func TestAccessLogReaderCombined(t *testing.T) {
+	t.Parallel()
+
+	config, err := newInputConfig(Options{Format: formatAccessLog})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	content := `203.0.113.7 - alice [10/Oct/2024:13:55:36 -0700] "GET /search?q=a%20b HTTP/1.1" 200 2326 "https://example.com/" "Mozilla/5.0 (X11; \"Linux\")"` + "\n" +
+		"\n" +
+		`198.51.100.2 - - [10/Oct/2024:13:55:37 -0700] "\x16\x03\x01" 400 - "-" "-" rt=0.001` + "\r\n" +
+		"not an access log line\n" +
+		`2001:db8::1 - - [10/Oct/2024:13:55:38 +0000] "POST /api HTTP/2.0" 201 17 "-" "curl/8.5.0"`
+	got, err := readTestDocuments(t, config, writeTestDataFile(t, "access.log", content))
+	if err != nil {
+		t.Fatalf("reading returned error: %v", err)
+	}
+	want := []string{
+		`{"@timestamp":"2024-10-10T13:55:36-07:00","bytes":2326,"ip":"203.0.113.7","method":"GET","path":"/search?q=a%20b","protocol":"HTTP/1.1","referrer":"https://example.com/","status":200,"user":"alice","user_agent":"Mozilla/5.0 (X11; \"Linux\")"}`,
+		`{"@timestamp":"2024-10-10T13:55:37-07:00","bytes":0,"ip":"198.51.100.2","request":"\u0016\u0003\u0001","status":400}`,
+		`{"@timestamp":"2024-10-10T13:55:38Z","bytes":17,"ip":"2001:db8::1","method":"POST","path":"/api","protocol":"HTTP/2.0","status":201,"user_agent":"curl/8.5.0"}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("read\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := readTestDocuments(t, config, writeTestDataFile(t, "error.log", "[error] AH00128: File does not exist\n")); err == nil || !strings.Contains(err.Error(), "line 1: line does not match -log-format") {
+		t.Fatalf("expected a first line that does not match to fail, got %v", err)
+	}
+}
+
+// TestAccessLogCustomFormats verifies behavior for the related scenario.
+func TestAccessLogCustomFormats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		format string
+		line   string
+		want   string
+	}{
+		{
+			name:   "common",
+			format: "common",
+			line:   `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+			want:   `{"@timestamp":"2000-10-10T13:55:36-07:00","bytes":2326,"ip":"127.0.0.1","method":"GET","path":"/apache_pb.gif","protocol":"HTTP/1.0","status":200,"user":"frank"}`,
+		},
+		{
+			name:   "apache",
+			format: `%v:%p %a %{X-Forwarded-For}i %t \"%m %U%q %H\" %>s %D %{ms}T`,
+			line:   `shop.example.com:443 10.0.0.5 198.51.100.9 [01/May/2024:08:00:00 +0200] "GET /cart?id=3 HTTP/1.1" 302 5120 5`,
+			want:   `{"@timestamp":"2024-05-01T08:00:00+02:00","duration_ms":5,"duration_us":5120,"ip":"10.0.0.5","method":"GET","path":"/cart","port":443,"protocol":"HTTP/1.1","query":"?id=3","status":302,"vhost":"shop.example.com","x_forwarded_for":"198.51.100.9"}`,
+		},
+		{
+			name:   "nginx",
+			format: `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent" $request_time "$http_x_forwarded_for" ${upstream_response_time}`,
+			line:   `192.0.2.1 - - [01/May/2024:08:00:00 +0000] "GET / HTTP/1.1" 200 612 "-" "Go-http-client/1.1" 0.004 "-" 0.003`,
+			want:   `{"@timestamp":"2024-05-01T08:00:00Z","bytes":612,"ip":"192.0.2.1","method":"GET","path":"/","protocol":"HTTP/1.1","request_time":0.004,"status":200,"upstream_response_time":"0.003","user_agent":"Go-http-client/1.1"}`,
+		},
+		{
+			name:   "nginx json time",
+			format: `$time_iso8601|$msec|$host|$status`,
+			line:   `2024-05-01T08:00:00+00:00|1714550400.250|api.example.com|204`,
+			want:   `{"@timestamp":"2024-05-01T08:00:00.250Z","host":"api.example.com","status":204}`,
+		},
+	}
+	for _, tt := range tests {
+		config, err := newInputConfig(Options{LogFormat: tt.format})
+		if err != nil {
+			t.Fatalf("%s: newInputConfig returned error: %v", tt.name, err)
+		}
+		got, err := readTestDocuments(t, config, writeTestDataFile(t, "access.log", tt.line+"\n"))
+		if err != nil || len(got) != 1 || got[0] != tt.want {
+			t.Fatalf("%s: read %q and %v, want %s", tt.name, got, err, tt.want)
+		}
+	}
+
+	bad := []struct {
+		opts Options
+		want string
+	}{
+		{opts: Options{Format: formatCSV, LogFormat: "common"}, want: "-log-format needs -format accesslog"},
+		{opts: Options{LogFormat: `%{%Y-%m-%d}t %h`}, want: "is not supported"},
+		{opts: Options{LogFormat: "plain text"}, want: "has no %directives or $variables"},
+	}
+	for _, tt := range bad {
+		if _, err := newInputConfig(tt.opts); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%+v: expected error containing %q, got %v", tt.opts, tt.want, err)
+		}
+	}
+}
+
+// TestRunAccessLogDataFile verifies behavior for the related scenario.
+func TestRunAccessLogDataFile(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   writeTestDataFile(t, "access.log", `10.1.1.1 - - [10/Oct/2024:13:55:36 +0000] "GET / HTTP/1.1" 200 512 "-" "curl/8.5.0"`+"\n"),
+		AddToIndex: true,
+		Format:     formatAccessLog,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if sent := strings.Join(bodies(), ""); result.DocumentsProcessed != 1 || !strings.Contains(sent, `"status":200`) || !strings.Contains(sent, `"user_agent":"curl/8.5.0"`) {
+		t.Fatalf("unexpected result %+v and bulk bodies %s", result, sent)
+	}
+}
@@ -0,0 +1,95 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Filtered Aliases ──────────────────────────────────────────────────────────
+
+// filteredAliasKeys are the alias parameters an -aliases definition may set;
+// the loader supplies the index and alias name itself.
+var filteredAliasKeys = []string{"filter", "routing", "index_routing", "search_routing", "is_hidden", "is_write_index"}
+
+// validateFilteredAliases rejects -aliases definitions that are not alias
+// bodies, before anything is loaded.
+func validateFilteredAliases(definitions namedDefinitions, names []string, managedAlias string) error {
+	for _, name := range names {
+		if name == managedAlias {
+			return fmt.Errorf("alias %q is the -alias name the loader manages itself", name)
+		}
+		if err := validateIndexName(name); err != nil {
+			return fmt.Errorf("alias %q: %w", name, err)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(definitions[name], &body); err != nil || body == nil {
+			return fmt.Errorf("alias %q must be a JSON object", name)
+		}
+		for key := range body {
+			if !slices.Contains(filteredAliasKeys, key) {
+				return fmt.Errorf("alias %q sets %q; allowed keys are %s", name, key, strings.Join(filteredAliasKeys, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// buildFilteredAliasActions returns the _aliases actions that point every
+// alias in definitions at targets with its filter and routing, and drop it
+// from the stale indices a new alias-mode generation replaces.
+func buildFilteredAliasActions(definitions namedDefinitions, names []string, targets []string, stale map[string][]string) []map[string]map[string]any {
+	actions := make([]map[string]map[string]any, 0, len(names))
+	for _, name := range names {
+		for _, index := range stale[name] {
+			if !slices.Contains(targets, index) {
+				actions = append(actions, map[string]map[string]any{"remove": {"index": index, "alias": name}})
+			}
+		}
+		add := map[string]any{"indices": targets, "alias": name}
+		var body map[string]any
+		_ = json.Unmarshal(definitions[name], &body)
+		for key, value := range body {
+			add[key] = value
+		}
+		actions = append(actions, map[string]map[string]any{"add": add})
+	}
+	return actions
+}
+
+// applyFilteredAliases points the aliases of an -aliases file at targets in
+// one atomic _aliases request. Indices in previous, the generations a new
+// alias-mode index replaces, give the aliases up.
+func applyFilteredAliases(es *elasticsearch.Client, definitions namedDefinitions, names []string, targets []string, previous []string) {
+	stale := make(map[string][]string, len(names))
+	if len(previous) > 0 {
+		for _, name := range names {
+			for _, index := range resolveAliasTargets(es, name) {
+				if slices.Contains(previous, index) {
+					stale[name] = append(stale[name], index)
+				}
+			}
+		}
+	}
+	actions := buildFilteredAliasActions(definitions, names, targets, stale)
+	payload, err := json.Marshal(map[string]any{"actions": actions})
+	checkErr("serializing alias actions", err)
+	res, err := es.Indices.UpdateAliases(strings.NewReader(string(payload)))
+	checkErr("updating filtered aliases", err)
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		fatal().
+			Strs("aliases", names).
+			Strs("indices", targets).
+			Int("status_code", res.StatusCode).
+			Str("body", string(body)).
+			Msg("Failed to apply filtered aliases")
+	}
+	log.Info().Strs("aliases", names).Strs("indices", targets).Msg("Filtered aliases applied")
+}
@@ -0,0 +1,128 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestValidateFilteredAliases verifies behavior for the related scenario.
+func TestValidateFilteredAliases(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		definition string
+		alias      string
+		managed    string
+		wantErr    string
+	}{
+		{name: "filter and routing", alias: "cards-acme", definition: `{"filter":{"term":{"tenant":"acme"}},"routing":"acme"}`},
+		{name: "empty body", alias: "cards-all", definition: `{}`},
+		{name: "not an object", alias: "cards-acme", definition: `["acme"]`, wantErr: "must be a JSON object"},
+		{name: "index key", alias: "cards-acme", definition: `{"index":"other"}`, wantErr: `sets "index"`},
+		{name: "managed alias", alias: "cards", managed: "cards", definition: `{}`, wantErr: "manages itself"},
+	}
+	for _, tt := range tests {
+		definitions := namedDefinitions{tt.alias: json.RawMessage(tt.definition)}
+		err := validateFilteredAliases(definitions, []string{tt.alias}, tt.managed)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// TestBuildFilteredAliasActions verifies behavior for the related scenario.
+func TestBuildFilteredAliasActions(t *testing.T) {
+	t.Parallel()
+
+	definitions := namedDefinitions{
+		"cards-acme":   json.RawMessage(`{"filter":{"term":{"tenant":"acme"}},"routing":"acme"}`),
+		"cards-globex": json.RawMessage(`{"routing":"globex"}`),
+	}
+	actions := buildFilteredAliasActions(definitions, []string{"cards-acme", "cards-globex"}, []string{"cards-2"}, map[string][]string{
+		"cards-acme": {"cards-1", "cards-2"},
+	})
+	encoded, _ := json.Marshal(actions)
+	want := `[{"remove":{"alias":"cards-acme","index":"cards-1"}},` +
+		`{"add":{"alias":"cards-acme","filter":{"term":{"tenant":"acme"}},"indices":["cards-2"],"routing":"acme"}},` +
+		`{"add":{"alias":"cards-globex","indices":["cards-2"],"routing":"globex"}}]`
+	if string(encoded) != want {
+		t.Fatalf("expected actions\n%s\ngot\n%s", want, encoded)
+	}
+}
+
+// TestRunMovesFilteredAliasesToNewGeneration verifies behavior for the related scenario.
+func TestRunMovesFilteredAliasesToNewGeneration(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu           sync.Mutex
+		createdIndex string
+		aliasBodies  []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && (r.URL.Path == "/_alias/cards" || r.URL.Path == "/_alias/cards-acme"):
+			_, _ = w.Write([]byte(`{"cards-20200101000000":{"aliases":{}}}`))
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/cards-"):
+			createdIndex = strings.TrimPrefix(r.URL.Path, "/")
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/cards-"):
+			if r.URL.Path == "/"+createdIndex {
+				w.WriteHeader(http.StatusOK)
+			} else {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/_aliases":
+			body, _ := io.ReadAll(r.Body)
+			aliasBodies = append(aliasBodies, string(body))
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	aliasesFile := writeTestDataFile(t, "aliases.json", `{"${INDEX}-acme":{"filter":{"term":{"tenant":"acme"}},"routing":"acme"}}`)
+	result, err := Run(context.Background(), Options{
+		URL:         server.URL,
+		Index:       "cards",
+		DataFile:    writeTestDataFile(t, "cards.ndjson", "{\"tenant\":\"acme\"}\n"),
+		DeleteIndex: true,
+		SyncManaged: true,
+		AliasMode:   true,
+		AliasesFile: aliasesFile,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(result.FilteredAliases) != 1 || result.FilteredAliases[0] != "cards-acme" {
+		t.Fatalf("expected cards-acme to be applied, got %v", result.FilteredAliases)
+	}
+	if len(aliasBodies) != 2 {
+		t.Fatalf("expected the alias swap and then the filtered aliases, got %v", aliasBodies)
+	}
+	want := `{"actions":[{"remove":{"alias":"cards-acme","index":"cards-20200101000000"}},` +
+		`{"add":{"alias":"cards-acme","filter":{"term":{"tenant":"acme"}},"indices":["` + createdIndex + `"],"routing":"acme"}}]}`
+	if aliasBodies[1] != want {
+		t.Fatalf("expected filtered alias request\n%s\ngot\n%s", want, aliasBodies[1])
+	}
+}
@@ -0,0 +1,258 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ─── AMQP Source ───────────────────────────────────────────────────────────────
+
+const (
+	amqpDefaultPort    = "5672"
+	amqpDefaultTLSPort = "5671"
+	// amqpTimeout bounds connecting to the broker.
+	amqpTimeout = 10 * time.Second
+	// amqpHeartbeat is the heartbeat interval asked of the broker.
+	amqpHeartbeat = 10 * time.Second
+	// amqpMaxPrefetch is the largest prefetch count Basic.Qos can carry.
+	amqpMaxPrefetch = 65535
+)
+
+// amqpConsumer is one consume on an open channel.
+type amqpConsumer struct {
+	deliveries <-chan amqp.Delivery
+	// closed yields the error that closed the channel, or is closed without
+	// one when the source closed it.
+	closed <-chan *amqp.Error
+	close  func() error
+}
+
+// amqpSource consumes one queue with manual acknowledgements through the
+// RabbitMQ client library. It connects on first use and again after the
+// connection breaks.
+type amqpSource struct {
+	uri    string
+	config amqp.Config
+	queue  string
+	// prefetch caps unacknowledged deliveries; 0 uses the batch size.
+	prefetch int
+	// requeue hands failed messages back to the queue; false rejects them
+	// to its dead-letter exchange.
+	requeue bool
+	// open connects and starts consuming with a prefetch count.
+	open func(prefetch int) (*amqpConsumer, error)
+
+	consumer *amqpConsumer
+}
+
+// newAMQPSource parses amqp[s]://[user:password@]<host>[:port]/[<vhost>/]<queue>.
+// The vhost defaults to /; the query may set prefetch, requeue=false, and
+// ca for amqps.
+func newAMQPSource(u *url.URL) (*amqpSource, error) {
+	display := redactSourceURL(u.String())
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(u.EscapedPath(), "/"), "/") {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, fmt.Errorf("-source %s: %w", display, err)
+		}
+		segments = append(segments, unescaped)
+	}
+	if u.Hostname() == "" || len(segments) < 1 || len(segments) > 2 || segments[len(segments)-1] == "" {
+		return nil, fmt.Errorf("-source %s: expected %s://<host>/[<vhost>/]<queue>", display, u.Scheme)
+	}
+	query := u.Query()
+	secure := strings.EqualFold(u.Scheme, "amqps")
+	port := u.Port()
+	switch {
+	case port != "":
+	case secure:
+		port = amqpDefaultTLSPort
+	default:
+		port = amqpDefaultPort
+	}
+	user, password := "guest", "guest"
+	if u.User != nil {
+		user = u.User.Username()
+		password, _ = u.User.Password()
+	}
+	s := &amqpSource{
+		uri:     "amqp://" + net.JoinHostPort(u.Hostname(), port) + "/",
+		queue:   segments[len(segments)-1],
+		requeue: true,
+		config: amqp.Config{
+			Vhost:      "/",
+			SASL:       []amqp.Authentication{&amqp.PlainAuth{Username: user, Password: password}},
+			Heartbeat:  amqpHeartbeat,
+			Locale:     "en_US",
+			Dial:       amqp.DefaultDial(amqpTimeout),
+			Properties: amqp.Table{"product": "es-bulk-loader"},
+		},
+	}
+	s.open = s.dial
+	if len(segments) == 2 {
+		s.config.Vhost = segments[0]
+	}
+	if value := query.Get("prefetch"); value != "" {
+		prefetch, err := strconv.Atoi(value)
+		if err != nil || prefetch < 1 || prefetch > amqpMaxPrefetch {
+			return nil, fmt.Errorf("-source %s: prefetch %q must be between 1 and %d", display, value, amqpMaxPrefetch)
+		}
+		s.prefetch = prefetch
+	}
+	if value := query.Get("requeue"); value != "" {
+		requeue, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("-source %s: requeue %q is not a boolean", display, value)
+		}
+		s.requeue = requeue
+	}
+	if secure {
+		config, err := sourceTLSConfig(u.Hostname(), query)
+		if err != nil {
+			return nil, err
+		}
+		s.uri = "amqps" + strings.TrimPrefix(s.uri, "amqp")
+		s.config.TLSClientConfig = config
+	}
+	return s, nil
+}
+
+// receive returns up to max deliveries, waiting up to wait for the first.
+func (s *amqpSource) receive(ctx context.Context, max int, wait time.Duration) ([]sourceMessage, error) {
+	c, err := s.connect(max)
+	if err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	var msgs []sourceMessage
+	for len(msgs) < max {
+		var delivery amqp.Delivery
+		var ok bool
+		if len(msgs) == 0 {
+			select {
+			case delivery, ok = <-c.deliveries:
+			case <-timer.C:
+				return nil, nil
+			case <-ctx.Done():
+				return nil, nil
+			}
+		} else {
+			select {
+			case delivery, ok = <-c.deliveries:
+			default:
+				return msgs, nil
+			}
+		}
+		if !ok {
+			s.drop()
+			if reason := <-c.closed; reason != nil {
+				return msgs, fmt.Errorf("AMQP queue %s: %w", s.queue, reason)
+			}
+			return msgs, fmt.Errorf("AMQP queue %s: %w", s.queue, amqp.ErrClosed)
+		}
+		msgs = append(msgs, sourceMessage{body: delivery.Body, handle: strconv.FormatUint(delivery.DeliveryTag, 10), delivery: delivery})
+	}
+	return msgs, nil
+}
+
+// ack acknowledges msgs.
+func (s *amqpSource) ack(_ context.Context, msgs []sourceMessage) error {
+	return s.settle(msgs, func(delivery amqp.Delivery) error { return delivery.Ack(false) })
+}
+
+// nack rejects msgs, requeueing them unless the source URL said not to.
+func (s *amqpSource) nack(_ context.Context, msgs []sourceMessage) error {
+	return s.settle(msgs, func(delivery amqp.Delivery) error { return delivery.Nack(false, s.requeue) })
+}
+
+// settle applies verdict to each message. Delivery tags belong to the
+// channel they arrived on, and the broker requeues whatever was
+// unacknowledged when that channel closed, so messages from a closed
+// channel are only reported.
+func (s *amqpSource) settle(msgs []sourceMessage, verdict func(amqp.Delivery) error) error {
+	stale := 0
+	for _, msg := range msgs {
+		err := verdict(msg.delivery.(amqp.Delivery))
+		if errors.Is(err, amqp.ErrClosed) {
+			stale++
+			continue
+		}
+		if err != nil {
+			s.drop()
+			return fmt.Errorf("settling AMQP delivery: %w", err)
+		}
+	}
+	if stale > 0 {
+		return fmt.Errorf("the AMQP connection they arrived on was lost, so the broker redelivers %d messages", stale)
+	}
+	return nil
+}
+
+// close closes the connection, letting the broker process the
+// acknowledgements sent before it.
+func (s *amqpSource) close() error {
+	s.drop()
+	return nil
+}
+
+// drop closes the connection so the next receive reconnects.
+func (s *amqpSource) drop() {
+	if s.consumer != nil {
+		_ = s.consumer.close()
+		s.consumer = nil
+	}
+}
+
+// connect returns the open consumer, opening a new one if there is none.
+// Without a prefetch option, up to batch deliveries are prefetched.
+func (s *amqpSource) connect(batch int) (*amqpConsumer, error) {
+	if s.consumer != nil {
+		return s.consumer, nil
+	}
+	prefetch := s.prefetch
+	if prefetch == 0 {
+		prefetch = min(batch, amqpMaxPrefetch)
+	}
+	c, err := s.open(prefetch)
+	if err != nil {
+		return nil, err
+	}
+	s.consumer = c
+	return c, nil
+}
+
+// dial connects to the broker, opens the vhost and a channel, and starts
+// consuming the queue with prefetch unacknowledged deliveries.
+func (s *amqpSource) dial(prefetch int) (*amqpConsumer, error) {
+	conn, err := amqp.DialConfig(s.uri, s.config)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to AMQP %s: %w", s.uri, err)
+	}
+	fail := func(err error) (*amqpConsumer, error) {
+		conn.Close()
+		return nil, fmt.Errorf("AMQP queue %s: %w", s.queue, err)
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		return fail(err)
+	}
+	if err := channel.Qos(prefetch, 0, false); err != nil {
+		return fail(err)
+	}
+	closed := channel.NotifyClose(make(chan *amqp.Error, 1))
+	deliveries, err := channel.Consume(s.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return fail(err)
+	}
+	return &amqpConsumer{deliveries: deliveries, closed: closed, close: conn.Close}, nil
+}
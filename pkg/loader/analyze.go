@@ -0,0 +1,187 @@
+package loader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Analyzer Check ────────────────────────────────────────────────────────────
+
+const (
+	// analyzeCheckValues is how many distinct values of each field are
+	// analyzed.
+	analyzeCheckValues = 3
+	// analyzeCheckDocuments bounds how far into the input values are looked
+	// for.
+	analyzeCheckDocuments = 1000
+)
+
+// analyzeCheck is one -analyze-check entry: Field's values run through
+// Analyzer, or through the field's own mapped analyzer when it is "".
+type analyzeCheck struct {
+	Field    string
+	Analyzer string
+}
+
+// AnalyzedValue is one sample value of Options.AnalyzeCheck and the tokens
+// the analyzer produced for it.
+type AnalyzedValue struct {
+	Field string
+	// Analyzer is the analyzer named by the check, or "" for the field's
+	// mapped analyzer.
+	Analyzer string
+	Text     string
+	Tokens   []string
+}
+
+// parseAnalyzeChecks parses repeatable "field=analyzer" or "field"
+// -analyze-check values.
+func parseAnalyzeChecks(values []string) ([]analyzeCheck, error) {
+	checks := make([]analyzeCheck, 0, len(values))
+	for _, value := range values {
+		field, analyzer, named := strings.Cut(value, "=")
+		field, analyzer = strings.TrimSpace(field), strings.TrimSpace(analyzer)
+		if field == "" || (named && analyzer == "") {
+			return nil, fmt.Errorf("analyze check %q must be field=analyzer or field", value)
+		}
+		checks = append(checks, analyzeCheck{Field: field, Analyzer: analyzer})
+	}
+	return checks, nil
+}
+
+// sampleAnalyzeValues reads the input through the load's transforms until
+// every check has analyzeCheckValues distinct string values, or
+// analyzeCheckDocuments documents have been read, and returns each check's
+// values in input order.
+func sampleAnalyzeValues(opts Options, input inputConfig, transforms docTransformChain, settings bulkSettings, checks []analyzeCheck) ([][]string, error) {
+	samples := make([][]string, len(checks))
+	reader, err := input.openDataFiles(opts.dataFiles(), opts.ReadConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.close()
+	for skipped := 0; skipped < opts.SkipDocuments; skipped++ {
+		if _, err := reader.next(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	full := func() bool {
+		for _, values := range samples {
+			if len(values) < analyzeCheckValues {
+				return false
+			}
+		}
+		return true
+	}
+	for read := 1; read <= analyzeCheckDocuments && !full(); read++ {
+		doc, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding document %d: %w", read, err)
+		}
+		docs, err := transforms.apply(doc)
+		if err != nil {
+			return nil, fmt.Errorf("transforming document %d: %w", read, err)
+		}
+		for _, doc := range docs {
+			_, source := documentMeta(doc, opts.Index, settings)
+			for i, check := range checks {
+				value, _ := lookupField(source, check.Field)
+				samples[i] = appendAnalyzeValues(samples[i], value)
+			}
+		}
+	}
+	return samples, nil
+}
+
+// appendAnalyzeValues adds the strings value holds, alone or in an array,
+// that values lacks, up to analyzeCheckValues.
+func appendAnalyzeValues(values []string, value interface{}) []string {
+	switch typed := value.(type) {
+	case string:
+		if len(values) < analyzeCheckValues && strings.TrimSpace(typed) != "" && !slices.Contains(values, typed) {
+			values = append(values, typed)
+		}
+	case []interface{}:
+		for _, element := range typed {
+			values = appendAnalyzeValues(values, element)
+		}
+	}
+	return values
+}
+
+// analyzeText runs text through check's analyzer on index and returns the
+// tokens.
+func analyzeText(es *elasticsearch.Client, index string, check analyzeCheck, text string) ([]string, error) {
+	request := map[string]string{"text": text}
+	if check.Analyzer != "" {
+		request["analyzer"] = check.Analyzer
+	} else {
+		request["field"] = check.Field
+	}
+	body, _ := json.Marshal(request)
+	res, err := es.Indices.Analyze(strings.NewReader(string(body)), es.Indices.Analyze.WithIndex(index))
+	if err != nil {
+		return nil, fmt.Errorf("analyzing %q: %w", check.Field, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		responseBody, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("analyzing %q failed with status %d: %s", check.Field, res.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	var parsed struct {
+		Tokens []struct {
+			Token string `json:"token"`
+		} `json:"tokens"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("decoding analyze response for %q: %w", check.Field, err)
+	}
+	tokens := make([]string, 0, len(parsed.Tokens))
+	for _, token := range parsed.Tokens {
+		tokens = append(tokens, token.Token)
+	}
+	return tokens, nil
+}
+
+// runAnalyzeChecks analyzes the sampled values of every check on index and
+// writes one line per value to out, such as
+//
+//	title [autocomplete] "Quick Fox" => ["qu","qui","quic","quick","fo","fox"]
+func runAnalyzeChecks(es *elasticsearch.Client, index string, checks []analyzeCheck, samples [][]string, out io.Writer) ([]AnalyzedValue, error) {
+	var analyzed []AnalyzedValue
+	for i, check := range checks {
+		if len(samples[i]) == 0 {
+			log.Warn().Str("field", check.Field).Msg("No sampled document holds a string value for the analyze check")
+			continue
+		}
+		analyzer := check.Analyzer
+		if analyzer == "" {
+			analyzer = "mapped"
+		}
+		for _, text := range samples[i] {
+			tokens, err := analyzeText(es, index, check, text)
+			if err != nil {
+				return analyzed, err
+			}
+			quotedText, _ := json.Marshal(text)
+			quotedTokens, _ := json.Marshal(tokens)
+			if _, err := fmt.Fprintf(out, "%s [%s] %s => %s\n", check.Field, analyzer, quotedText, quotedTokens); err != nil {
+				return analyzed, err
+			}
+			analyzed = append(analyzed, AnalyzedValue{Field: check.Field, Analyzer: check.Analyzer, Text: text, Tokens: tokens})
+		}
+	}
+	return analyzed, nil
+}
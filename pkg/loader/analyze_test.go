@@ -0,0 +1,116 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestParseAnalyzeChecks verifies behavior for the related scenario.
+func TestParseAnalyzeChecks(t *testing.T) {
+	t.Parallel()
+
+	checks, err := parseAnalyzeChecks([]string{"title=autocomplete", " sku "})
+	if err != nil {
+		t.Fatalf("parseAnalyzeChecks returned error: %v", err)
+	}
+	if len(checks) != 2 || checks[0] != (analyzeCheck{Field: "title", Analyzer: "autocomplete"}) || checks[1] != (analyzeCheck{Field: "sku"}) {
+		t.Fatalf("unexpected checks %+v", checks)
+	}
+	for _, bad := range []string{"", "=standard", "title="} {
+		if _, err := parseAnalyzeChecks([]string{bad}); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+// TestRunAnalyzeCheckPrintsTokens verifies behavior for the related scenario.
+func TestRunAnalyzeCheckPrintsTokens(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var requests []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.URL.Path == "/cards/_analyze":
+			var request map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			mu.Lock()
+			requests = append(requests, request)
+			mu.Unlock()
+			if request["analyzer"] == "missing" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":{"type":"illegal_argument_exception","reason":"failed to find analyzer [missing]"}}`))
+				return
+			}
+			tokens := make([]map[string]string, 0)
+			for _, word := range strings.Fields(strings.ToLower(request["text"])) {
+				tokens = append(tokens, map[string]string{"token": word})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"tokens": tokens})
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	data := strings.Join([]string{
+		`{"title":"Quick Fox","tags":["Red Barn"]}`,
+		`{"title":"Quick Fox"}`,
+		`{"title":"Lazy Dog","tags":["Blue Sky","Red Barn"]}`,
+		`{"title":"Old Cat"}`,
+		`{"title":"Never Sampled"}`,
+	}, "\n") + "\n"
+	dataFile := writeTestDataFile(t, "cards.ndjson", data)
+
+	var out bytes.Buffer
+	result, err := Run(context.Background(), Options{
+		URL:           server.URL,
+		Index:         "cards",
+		DataFile:      dataFile,
+		AddToIndex:    true,
+		AnalyzeCheck:  []string{"title=standard", "tags"},
+		AnalyzeOutput: &out,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := strings.Join([]string{
+		`title [standard] "Quick Fox" => ["quick","fox"]`,
+		`title [standard] "Lazy Dog" => ["lazy","dog"]`,
+		`title [standard] "Old Cat" => ["old","cat"]`,
+		`tags [mapped] "Red Barn" => ["red","barn"]`,
+		`tags [mapped] "Blue Sky" => ["blue","sky"]`,
+	}, "\n") + "\n"
+	if out.String() != want {
+		t.Fatalf("expected output\n%s\ngot\n%s", want, out.String())
+	}
+	if len(result.Analyzed) != 5 || result.DocumentsProcessed != 5 {
+		t.Fatalf("expected 5 analyzed values and 5 loaded documents, got %d and %d", len(result.Analyzed), result.DocumentsProcessed)
+	}
+	if requests[3]["field"] != "tags" || requests[3]["analyzer"] != "" {
+		t.Fatalf("expected a check without an analyzer to name the field, got %v", requests[3])
+	}
+
+	_, err = Run(context.Background(), Options{
+		URL:           server.URL,
+		Index:         "cards",
+		DataFile:      dataFile,
+		AddToIndex:    true,
+		AnalyzeCheck:  []string{"title=missing"},
+		AnalyzeOutput: &out,
+	})
+	if !errors.Is(err, ErrIndexOperation) || !strings.Contains(err.Error(), "failed to find analyzer") {
+		t.Fatalf("expected an unknown analyzer to fail the run, got %v", err)
+	}
+}
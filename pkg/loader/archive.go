@@ -0,0 +1,175 @@
+package loader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ─── Archive Input ─────────────────────────────────────────────────────────────
+
+// archiveDataExtensions are the member names read from an archive when no
+// -archive-pattern or -format narrows them.
+var archiveDataExtensions = []string{".json", ".ndjson", ".jsonl", ".csv", ".tsv", ".msgpack", ".mpk", ".cbor", ".geojson", ".cef", ".leef", ".evtx", ".pcap", ".pcapng", ".cap"}
+
+// isArchive reports whether path names a .zip, .tar, .tar.gz, or .tgz file.
+func isArchive(path string) bool {
+	lowered := strings.ToLower(path)
+	for _, suffix := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lowered, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveMember is the next member of an archive to read.
+type archiveMember struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// archiveReader reads the documents of every matching archive member in
+// archive order, streaming each member without extracting it to disk.
+type archiveReader struct {
+	config  inputConfig
+	archive string
+	// nextMember returns io.EOF after the last member.
+	nextMember func() (archiveMember, error)
+	closer     io.Closer
+	current    documentReader
+	member     string
+	matched    int
+}
+
+// openArchive opens the archive at archivePath.
+func (c inputConfig) openArchive(archivePath string) (documentReader, error) {
+	r := &archiveReader{config: c, archive: archivePath}
+	if strings.HasSuffix(strings.ToLower(archivePath), ".zip") {
+		zipped, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return nil, err
+		}
+		files := zipped.File
+		r.closer = zipped
+		r.nextMember = func() (archiveMember, error) {
+			for len(files) > 0 {
+				file := files[0]
+				files = files[1:]
+				if file.Mode().IsRegular() {
+					return archiveMember{name: file.Name, open: file.Open}, nil
+				}
+			}
+			return archiveMember{}, io.EOF
+		}
+		return r, nil
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	var stream io.Reader = file
+	r.closer = file
+	if lowered := strings.ToLower(archivePath); strings.HasSuffix(lowered, ".gz") || strings.HasSuffix(lowered, ".tgz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("reading %s: %w", archivePath, err)
+		}
+		stream = gz
+	}
+	tarred := tar.NewReader(stream)
+	r.nextMember = func() (archiveMember, error) {
+		for {
+			header, err := tarred.Next()
+			if err != nil {
+				return archiveMember{}, err
+			}
+			if header.Typeflag == tar.TypeReg {
+				return archiveMember{name: header.Name, open: func() (io.ReadCloser, error) {
+					return io.NopCloser(tarred), nil
+				}}, nil
+			}
+		}
+	}
+	return r, nil
+}
+
+// matches reports whether the member called name should be read. Hidden
+// files and macOS resource forks are always skipped.
+func (r *archiveReader) matches(name string) bool {
+	base := path.Base(name)
+	if strings.HasPrefix(base, ".") || strings.HasPrefix(name, "__MACOSX/") {
+		return false
+	}
+	if r.config.archivePattern != "" {
+		matched, _ := path.Match(r.config.archivePattern, base)
+		return matched
+	}
+	if r.config.format != "" {
+		return true
+	}
+	for _, ext := range archiveDataExtensions {
+		if strings.EqualFold(path.Ext(base), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the following document, moving on to the next matching
+// member when the current one is exhausted.
+func (r *archiveReader) next() (map[string]interface{}, error) {
+	for {
+		if r.current == nil {
+			member, err := r.nextMember()
+			if errors.Is(err, io.EOF) {
+				if r.matched == 0 {
+					return nil, fmt.Errorf("archive %s has no matching data files", r.archive)
+				}
+				return nil, io.EOF
+			}
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", r.archive, err)
+			}
+			if !r.matches(member.name) {
+				continue
+			}
+			r.matched++
+			file, err := member.open()
+			if err != nil {
+				return nil, fmt.Errorf("archive member %s: %w", member.name, err)
+			}
+			r.current, err = r.config.openReader(member.name, file)
+			if err != nil {
+				return nil, fmt.Errorf("archive member %s: %w", member.name, err)
+			}
+			r.member = member.name
+		}
+		doc, err := r.current.next()
+		if errors.Is(err, io.EOF) {
+			r.current.close()
+			r.current = nil
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("archive member %s: %w", r.member, err)
+		}
+		return doc, nil
+	}
+}
+
+// close releases the current member and the archive.
+func (r *archiveReader) close() error {
+	if r.current != nil {
+		r.current.close()
+	}
+	return r.closer.Close()
+}
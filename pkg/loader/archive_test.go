@@ -0,0 +1,117 @@
+package loader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+)
+
+// archiveTestMembers are written, in order, into every test archive.
+var archiveTestMembers = []struct{ name, content string }{
+	{name: "data/a.json", content: `[{"id":"1"},{"id":"2"}]`},
+	{name: "README.txt", content: "not data"},
+	{name: "data/b.csv", content: "id,tier\n3,gold\n"},
+	{name: "__MACOSX/data/._a.json", content: "\x00\x05"},
+	{name: "data/c.ndjson", content: "{\"id\":\"4\"}\n"},
+}
+
+// writeTestArchive writes archiveTestMembers as name and returns its path.
+func writeTestArchive(t *testing.T, name string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if strings.HasSuffix(name, ".zip") {
+		zw := zip.NewWriter(&buf)
+		if _, err := zw.Create("data/"); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+		for _, member := range archiveTestMembers {
+			w, err := zw.Create(member.name)
+			if err != nil {
+				t.Fatalf("Create returned error: %v", err)
+			}
+			_, _ = w.Write([]byte(member.content))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+		return writeTestDataFile(t, name, buf.String())
+	}
+	var gz *gzip.Writer
+	tw := tar.NewWriter(&buf)
+	if strings.HasSuffix(name, "gz") {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	}
+	_ = tw.WriteHeader(&tar.Header{Name: "data/", Typeflag: tar.TypeDir, Mode: 0o755})
+	for _, member := range archiveTestMembers {
+		if err := tw.WriteHeader(&tar.Header{Name: member.name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(member.content))}); err != nil {
+			t.Fatalf("WriteHeader returned error: %v", err)
+		}
+		_, _ = tw.Write([]byte(member.content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+	}
+	return writeTestDataFile(t, name, buf.String())
+}
+
+// TestArchiveReader verifies behavior for the related scenario.
+func TestArchiveReader(t *testing.T) {
+	t.Parallel()
+
+	want := []string{`{"id":"1"}`, `{"id":"2"}`, `{"id":"3","tier":"gold"}`, `{"id":"4"}`}
+	for _, name := range []string{"cards.zip", "cards.tar", "cards.tar.gz", "cards.tgz"} {
+		archive := writeTestArchive(t, name)
+		got, err := readTestDocuments(t, inputConfig{}, archive)
+		if err != nil {
+			t.Fatalf("%s: returned error: %v", name, err)
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("%s: read %q, want %q", name, got, want)
+		}
+
+		got, err = readTestDocuments(t, inputConfig{archivePattern: "*.csv"}, archive)
+		if err != nil || strings.Join(got, "") != `{"id":"3","tier":"gold"}` {
+			t.Fatalf("%s: pattern *.csv read %q, %v", name, got, err)
+		}
+		if _, err := readTestDocuments(t, inputConfig{archivePattern: "*.xml"}, archive); err == nil || !strings.Contains(err.Error(), "no matching data files") {
+			t.Fatalf("%s: expected a no matching files error, got %v", name, err)
+		}
+		if _, err := readTestDocuments(t, inputConfig{archivePattern: "*.txt"}, archive); err == nil || !strings.Contains(err.Error(), "archive member README.txt") {
+			t.Fatalf("%s: expected an error naming README.txt, got %v", name, err)
+		}
+	}
+}
+
+// TestRunArchiveDataFile verifies behavior for the related scenario.
+func TestRunArchiveDataFile(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	result, err := Run(context.Background(), Options{
+		URL:           server.URL,
+		Index:         "cards",
+		DataFile:      writeTestArchive(t, "cards.tar.gz"),
+		AddToIndex:    true,
+		SkipDocuments: 1,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsProcessed != 3 {
+		t.Fatalf("expected 3 processed documents, got %+v", result)
+	}
+	sent := strings.Join(bodies(), "")
+	if strings.Contains(sent, `"id":"1"`) || !strings.Contains(sent, `"tier":"gold"`) || !strings.Contains(sent, `"id":"4"`) {
+		t.Fatalf("unexpected bulk bodies: %s", sent)
+	}
+}
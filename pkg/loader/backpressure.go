@@ -0,0 +1,120 @@
+package loader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Adaptive Backpressure ─────────────────────────────────────────────────────
+
+// throttleRampBatches is how many bulk requests in a row must go through
+// without a queue rejection before the throttle eases by one step.
+const throttleRampBatches = 5
+
+// bulkThrottle adapts how hard the loader pushes to how Elasticsearch copes.
+// Queue rejections (HTTP 429 and es_rejected_execution_exception items)
+// halve the number of bulk requests allowed in flight, down to one, and
+// then start a pause before each request that doubles up to maxPause.
+// Every throttleRampBatches clean requests undo one step: the pause halves
+// until it is gone, then one more request may be in flight, up to workers.
+type bulkThrottle struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	workers  int
+	limit    int
+	inflight int
+	// busy marks the worker slots in use, so live statistics keep one row
+	// per concurrent sender.
+	busy []bool
+
+	clean    int
+	pause    time.Duration
+	minPause time.Duration
+	maxPause time.Duration
+}
+
+// newBulkThrottle allows up to workers requests in flight; rejections pause
+// between minPause and maxPause.
+func newBulkThrottle(workers int, minPause, maxPause time.Duration) *bulkThrottle {
+	if workers < 1 {
+		workers = 1
+	}
+	if minPause <= 0 {
+		minPause = defaultBulkRetryBackoffBase
+	}
+	if maxPause < minPause {
+		maxPause = max(defaultBulkRetryBackoffMax, minPause)
+	}
+	t := &bulkThrottle{workers: workers, limit: workers, busy: make([]bool, workers), minPause: minPause, maxPause: maxPause}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+// acquire waits out any pause and for a free slot, and returns the slot's
+// worker number, counting from 1.
+func (t *bulkThrottle) acquire(ctx context.Context) int {
+	t.mu.Lock()
+	pause := t.pause
+	t.mu.Unlock()
+	if pause > 0 {
+		// A cancelled load still sends what is queued, so only the wait ends.
+		_ = sleepWithContext(ctx, pause)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.inflight >= t.limit {
+		t.cond.Wait()
+	}
+	t.inflight++
+	for slot, used := range t.busy {
+		if !used {
+			t.busy[slot] = true
+			return slot + 1
+		}
+	}
+	return 1
+}
+
+// release frees worker's slot and adapts to the rejections its request met.
+func (t *bulkThrottle) release(worker, rejected int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inflight--
+	if worker >= 1 && worker <= len(t.busy) {
+		t.busy[worker-1] = false
+	}
+	defer t.cond.Broadcast()
+
+	if rejected > 0 {
+		t.clean = 0
+		if t.limit > 1 {
+			t.limit /= 2
+			log.Warn().Int("rejections", rejected).Int("in_flight_limit", t.limit).Msg("Elasticsearch is rejecting bulk writes; lowering concurrent bulk requests")
+			return
+		}
+		t.pause = min(max(t.pause*2, t.minPause), t.maxPause)
+		log.Warn().Int("rejections", rejected).Str("pause", t.pause.String()).Msg("Elasticsearch is rejecting bulk writes; pausing before each bulk request")
+		return
+	}
+	t.clean++
+	if t.clean < throttleRampBatches {
+		return
+	}
+	t.clean = 0
+	switch {
+	case t.pause > 0:
+		t.pause /= 2
+		if t.pause < t.minPause {
+			t.pause = 0
+			log.Info().Msg("Bulk rejections subsided; no longer pausing between bulk requests")
+		}
+	case t.limit < t.workers:
+		t.limit++
+		log.Info().Int("in_flight_limit", t.limit).Msg("Bulk rejections subsided; raising concurrent bulk requests")
+	}
+}
@@ -0,0 +1,173 @@
+package loader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// TestBulkThrottleAdapts verifies behavior for the related scenario.
+func TestBulkThrottleAdapts(t *testing.T) {
+	t.Parallel()
+
+	throttle := newBulkThrottle(4, 10*time.Millisecond, 40*time.Millisecond)
+	release := func(rejected int) {
+		throttle.inflight++
+		throttle.release(1, rejected)
+	}
+	steps := []struct {
+		name     string
+		rejected int
+		clean    int
+		limit    int
+		pause    time.Duration
+	}{
+		{name: "first rejection halves", rejected: 1, limit: 2},
+		{name: "second rejection halves", rejected: 3, limit: 1},
+		{name: "single worker pauses", rejected: 1, limit: 1, pause: 10 * time.Millisecond},
+		{name: "pause doubles", rejected: 1, limit: 1, pause: 20 * time.Millisecond},
+		{name: "pause is capped", rejected: 2, limit: 1, pause: 40 * time.Millisecond},
+		{name: "too few clean requests", clean: throttleRampBatches - 1, limit: 1, pause: 40 * time.Millisecond},
+		{name: "clean requests halve the pause", clean: 1, limit: 1, pause: 20 * time.Millisecond},
+		{name: "pause ends", clean: 2 * throttleRampBatches, limit: 1},
+		{name: "concurrency climbs back", clean: 2 * throttleRampBatches, limit: 3},
+		{name: "up to workers", clean: 3 * throttleRampBatches, limit: 4},
+	}
+	for _, step := range steps {
+		if step.rejected > 0 {
+			release(step.rejected)
+		}
+		for i := 0; i < step.clean; i++ {
+			release(0)
+		}
+		if throttle.limit != step.limit || throttle.pause != step.pause {
+			t.Fatalf("%s: limit %d pause %s, want %d %s", step.name, throttle.limit, throttle.pause, step.limit, step.pause)
+		}
+	}
+}
+
+// TestSendBulkPayloadResendsRejectedItems verifies behavior for the related scenario.
+func TestSendBulkPayloadResendsRejectedItems(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		attempts      int
+		wantRequests  int
+		wantSucceeded int
+		wantFailed    int
+	}{
+		{name: "rejected item resent", attempts: 3, wantRequests: 2, wantSucceeded: 3},
+		{name: "no attempts left", attempts: 1, wantRequests: 1, wantSucceeded: 2, wantFailed: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				mu     sync.Mutex
+				bodies []string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Elastic-Product", "Elasticsearch")
+				w.Header().Set("Content-Type", "application/json")
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				bodies = append(bodies, string(body))
+				first := len(bodies) == 1
+				mu.Unlock()
+				if first {
+					_, _ = w.Write([]byte(`{"errors":true,"items":[` +
+						`{"index":{"_index":"cards","_id":"a","status":201}},` +
+						`{"index":{"_index":"cards","_id":"b","status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}},` +
+						`{"index":{"_index":"cards","_id":"c","status":201}}]}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"_index":"cards","_id":"b","status":201}}]}`))
+			}))
+			t.Cleanup(server.Close)
+			es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{server.URL}})
+			if err != nil {
+				t.Fatalf("NewClient returned error: %v", err)
+			}
+
+			batch := []map[string]interface{}{{"id": "a"}, {"id": "b"}, {"id": "c"}}
+			result := bulkInsert(context.Background(), es, "cards", batch, 1, 3, 3, bulkSettings{
+				RetryAttempts:    tt.attempts,
+				RetryBackoffBase: time.Millisecond,
+				RetryBackoffMax:  time.Millisecond,
+				IDField:          "id",
+			})
+			if len(bodies) != tt.wantRequests || result.Succeeded != tt.wantSucceeded || result.Failed != tt.wantFailed || result.Rejected != 1 {
+				t.Fatalf("%d requests, result %+v", len(bodies), result)
+			}
+			if tt.wantRequests > 1 && strings.Count(bodies[1], "\n") != 2 || tt.wantRequests > 1 && !strings.Contains(bodies[1], `"_id":"b"`) {
+				t.Fatalf("expected only the rejected item to be resent, got %q", bodies[1])
+			}
+		})
+	}
+}
+
+// TestRunWorkersSendConcurrently verifies behavior for the related scenario.
+func TestRunWorkersSendConcurrently(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		inflight int
+		peak     int
+		sent     int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			mu.Lock()
+			inflight++
+			peak = max(peak, inflight)
+			sent++
+			// Later batches finish first, so progress must wait for earlier ones.
+			delay := time.Duration(10-sent) * 2 * time.Millisecond
+			mu.Unlock()
+			time.Sleep(delay)
+			mu.Lock()
+			inflight--
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"_index":"cards","_id":"1","status":201}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	var progress []int
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   writeNumberedTestFile(t, "cards", 9),
+		AddToIndex: true,
+		BatchSize:  1,
+		Prefetch:   3,
+		Workers:    3,
+		OnProgress: func(offset int) { progress = append(progress, offset) },
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsSucceeded != 9 || peak < 2 || peak > 3 {
+		t.Fatalf("expected 9 documents with 2-3 requests in flight, got %+v with peak %d", result, peak)
+	}
+	if !slices.Equal(progress, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+		t.Fatalf("expected progress in batch order, got %v", progress)
+	}
+}
@@ -0,0 +1,133 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Benchmark Mode ────────────────────────────────────────────────────────────
+
+// BenchmarkOptions configures Benchmark.
+type BenchmarkOptions struct {
+	// Throwaway loads into a new "<index>-benchmark-<timestamp>" index,
+	// created from the configured settings and mappings, and deletes it
+	// afterwards, so benchmarks never touch the real index.
+	Throwaway bool
+	// Output receives the report; defaults to os.Stdout.
+	Output io.Writer
+	// Load is the Run configuration being measured.
+	Load Options
+}
+
+// Benchmark runs one load and writes a report of its throughput and
+// latency to Output. The report has one "name value" line per measurement
+// in a fixed order, so reports from runs with different -batch or
+// -prefetch settings, or against different clusters, diff cleanly.
+func Benchmark(ctx context.Context, opts BenchmarkOptions) (Result, error) {
+	if opts.Load.Tail {
+		return Result{}, &RunError{Kind: ErrInvalidOptions, Op: "validating benchmark", Err: errors.New("-benchmark cannot be combined with -tail")}
+	}
+	if len(opts.Load.Clusters) > 1 {
+		return Result{}, &RunError{Kind: ErrInvalidOptions, Op: "validating benchmark", Err: errors.New("-benchmark measures one cluster; pass a single -url")}
+	}
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	load := opts.Load
+	if opts.Throwaway {
+		load.Index = fmt.Sprintf("%s-benchmark-%s", load.Index, time.Now().UTC().Format("20060102150405"))
+		// The new index is created by -add, which also never deletes anything.
+		load.AddToIndex, load.FlushIndex, load.DeleteIndex, load.Nuke = true, false, false, false
+		load.AliasMode, load.KeepLast = false, 0
+		log.Info().Str("index", load.Index).Msg("Benchmarking against a throwaway index")
+		defer deleteBenchmarkIndex(load)
+	}
+
+	result, err := Run(ctx, load)
+	if err != nil {
+		return result, err
+	}
+	prefetch := load.Prefetch
+	if prefetch == 0 {
+		prefetch = defaultPrefetchBatches
+	}
+	if err := writeBenchmarkReport(out, load, prefetch, result); err != nil {
+		return result, fmt.Errorf("writing benchmark report: %w", err)
+	}
+	return result, nil
+}
+
+// deleteBenchmarkIndex removes a throwaway benchmark index, warning rather
+// than failing when it cannot.
+func deleteBenchmarkIndex(load Options) {
+	es, err := elasticsearch.NewClient(elasticsearchConfig(load))
+	if err != nil {
+		log.Warn().Err(err).Str("index", load.Index).Msg("Could not delete benchmark index")
+		return
+	}
+	res, err := es.Indices.Delete([]string{load.Index}, es.Indices.Delete.WithIgnoreUnavailable(true))
+	if err != nil {
+		log.Warn().Err(err).Str("index", load.Index).Msg("Could not delete benchmark index")
+		return
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		log.Warn().Int("status_code", res.StatusCode).Str("body", strings.TrimSpace(string(body))).Str("index", load.Index).Msg("Could not delete benchmark index")
+		return
+	}
+	log.Info().Str("index", load.Index).Msg("Deleted benchmark index")
+}
+
+// writeBenchmarkReport renders result as aligned name/value lines.
+func writeBenchmarkReport(w io.Writer, load Options, prefetch int, result Result) error {
+	mbPerSecond := 0.0
+	if seconds := result.LoadDuration.Seconds(); seconds > 0 {
+		mbPerSecond = float64(result.BytesSent) / (1024 * 1024) / seconds
+	}
+	index := result.WriteIndex
+	if index == "" {
+		index = load.Index
+	}
+	lines := [][2]string{
+		{"index", index},
+		{"data_file", strings.Join(load.dataFiles(), ",")},
+		{"batch_size", fmt.Sprint(load.BatchSize)},
+		{"prefetch", fmt.Sprint(prefetch)},
+		{"documents", fmt.Sprint(result.DocumentsProcessed)},
+		{"succeeded", fmt.Sprint(result.DocumentsSucceeded)},
+		{"failed", fmt.Sprint(result.DocumentsFailed)},
+		{"bytes_sent", fmt.Sprint(result.BytesSent)},
+		{"duration_s", fmt.Sprintf("%.3f", result.LoadDuration.Seconds())},
+		{"docs_per_sec", fmt.Sprintf("%.1f", result.DocumentsPerSecond)},
+		{"mb_per_sec", fmt.Sprintf("%.3f", mbPerSecond)},
+		{"batches", fmt.Sprint(result.BatchLatency.Count)},
+	}
+	for _, latency := range []struct {
+		prefix  string
+		summary LatencySummary
+	}{{"batch", result.BatchLatency}, {"took", result.TookLatency}} {
+		lines = append(lines,
+			[2]string{latency.prefix + "_min_ms", fmt.Sprintf("%.3f", durationMillis(latency.summary.Min))},
+			[2]string{latency.prefix + "_p50_ms", fmt.Sprintf("%.3f", durationMillis(latency.summary.P50))},
+			[2]string{latency.prefix + "_p95_ms", fmt.Sprintf("%.3f", durationMillis(latency.summary.P95))},
+			[2]string{latency.prefix + "_p99_ms", fmt.Sprintf("%.3f", durationMillis(latency.summary.P99))},
+			[2]string{latency.prefix + "_max_ms", fmt.Sprintf("%.3f", durationMillis(latency.summary.Max))},
+		)
+	}
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, line := range lines {
+		fmt.Fprintf(table, "%s\t%s\n", line[0], line[1])
+	}
+	return table.Flush()
+}
@@ -0,0 +1,127 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWriteBenchmarkReport verifies behavior for the related scenario.
+func TestWriteBenchmarkReport(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	err := writeBenchmarkReport(&out, Options{Index: "cards", DataFile: "cards.ndjson", BatchSize: 500}, 2, Result{
+		WriteIndex:         "cards",
+		DocumentsProcessed: 1000,
+		DocumentsSucceeded: 999,
+		DocumentsFailed:    1,
+		BytesSent:          2 * 1024 * 1024,
+		LoadDuration:       2 * time.Second,
+		DocumentsPerSecond: 500,
+		BatchLatency:       LatencySummary{Count: 2, Min: 10 * time.Millisecond, P50: 10 * time.Millisecond, P95: 20 * time.Millisecond, P99: 20 * time.Millisecond, Max: 20 * time.Millisecond},
+		TookLatency:        LatencySummary{Count: 2, Min: 5 * time.Millisecond, Max: 7 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("writeBenchmarkReport returned error: %v", err)
+	}
+	want := []string{
+		"index         cards",
+		"data_file     cards.ndjson",
+		"batch_size    500",
+		"prefetch      2",
+		"documents     1000",
+		"succeeded     999",
+		"failed        1",
+		"bytes_sent    2097152",
+		"duration_s    2.000",
+		"docs_per_sec  500.0",
+		"mb_per_sec    1.000",
+		"batches       2",
+		"batch_min_ms  10.000",
+		"batch_p50_ms  10.000",
+		"batch_p95_ms  20.000",
+		"batch_p99_ms  20.000",
+		"batch_max_ms  20.000",
+		"took_min_ms   5.000",
+		"took_p50_ms   0.000",
+		"took_p95_ms   0.000",
+		"took_p99_ms   0.000",
+		"took_max_ms   7.000",
+	}
+	if got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("report:\n%s\nwant:\n%s", out.String(), strings.Join(want, "\n"))
+	}
+}
+
+// TestBenchmarkThrowawayIndex verifies behavior for the related scenario.
+func TestBenchmarkThrowawayIndex(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu         sync.Mutex
+		created    string
+		deleted    string
+		bulkBodies int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		mu.Lock()
+		defer mu.Unlock()
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		switch {
+		case r.Method == http.MethodHead && strings.HasPrefix(name, "cards-benchmark-"):
+			if name != created {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPut && strings.HasPrefix(name, "cards-benchmark-"):
+			created = name
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			bulkBodies++
+			_, _ = w.Write([]byte(`{"took":3,"errors":false,"items":[{"index":{"_index":"x","_id":"1","status":201}}]}`))
+		case r.Method == http.MethodDelete:
+			deleted = name
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	var out bytes.Buffer
+	result, err := Benchmark(context.Background(), BenchmarkOptions{
+		Throwaway: true,
+		Output:    &out,
+		Load: Options{
+			URL:        server.URL,
+			Index:      "cards",
+			DataFile:   writeTestDataFile(t, "cards.ndjson", "{\"n\":1}\n{\"n\":2}\n"),
+			AddToIndex: true,
+			BatchSize:  1,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Benchmark returned error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if created == "" || deleted != created || result.WriteIndex != created {
+		t.Fatalf("expected throwaway index to be created and deleted, created %q deleted %q wrote %q", created, deleted, result.WriteIndex)
+	}
+	if bulkBodies != 2 || !strings.Contains(out.String(), "documents     2\n") || !strings.Contains(out.String(), "index         "+created+"\n") {
+		t.Fatalf("unexpected benchmark report after %d bulk requests:\n%s", bulkBodies, out.String())
+	}
+
+	_, err = Benchmark(context.Background(), BenchmarkOptions{Load: Options{Tail: true}})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected -tail to be rejected, got %v", err)
+	}
+}
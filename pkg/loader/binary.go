@@ -0,0 +1,151 @@
+package loader
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ─── MessagePack and CBOR Input ────────────────────────────────────────────────
+
+// binaryDecoder decodes the next top-level value of a binary stream.
+type binaryDecoder func() (interface{}, error)
+
+// binaryReader reads a stream of concatenated MessagePack or CBOR maps, the
+// usual output of embedded and IoT collectors, one document per map.
+type binaryReader struct {
+	file   io.ReadCloser
+	format string
+	decode binaryDecoder
+	count  int
+}
+
+// newMsgpackReader reads concatenated MessagePack maps from file.
+func newMsgpackReader(file io.ReadCloser) *binaryReader {
+	buffered := bufio.NewReader(file)
+	dec := msgpack.NewDecoder(buffered)
+	dec.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+		return d.DecodeUntypedMap()
+	})
+	// The decoder reports a cut-off value as io.EOF, so a clean end of
+	// stream is only recognised between values.
+	return &binaryReader{file: file, format: formatMsgpack, decode: func() (interface{}, error) {
+		if _, err := buffered.Peek(1); err != nil {
+			return nil, err
+		}
+		value, err := dec.DecodeInterface()
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return value, err
+	}}
+}
+
+// newCBORReader reads concatenated CBOR maps from file.
+func newCBORReader(file io.ReadCloser) (*binaryReader, error) {
+	mode, err := cbor.DecOptions{TimeTag: cbor.DecTagOptional}.DecMode()
+	if err != nil {
+		return nil, err
+	}
+	dec := mode.NewDecoder(bufio.NewReader(file))
+	return &binaryReader{file: file, format: formatCBOR, decode: func() (interface{}, error) {
+		var value interface{}
+		err := dec.Decode(&value)
+		return value, err
+	}}, nil
+}
+
+// next decodes the following map.
+func (r *binaryReader) next() (map[string]interface{}, error) {
+	value, err := r.decode()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	r.count++
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = fmt.Errorf("truncated value")
+		}
+		return nil, fmt.Errorf("%s value %d: %w", r.format, r.count, err)
+	}
+	doc, ok := jsonCompatible(value).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s value %d is a %T, not a map", r.format, r.count, value)
+	}
+	return doc, nil
+}
+
+// close releases the underlying file.
+func (r *binaryReader) close() error {
+	return r.file.Close()
+}
+
+// jsonCompatible converts decoded binary values into the shapes the JSON
+// readers produce, so the transform chain sees the same document a JSON file
+// would give it: integers, including CBOR bignums, become json.Number with
+// every digit kept, floats become float64, map keys become strings,
+// timestamps become RFC 3339 strings, and CBOR tags are unwrapped. Byte
+// strings are left as []byte and index as base64.
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int8:
+		return json.Number(strconv.FormatInt(int64(v), 10))
+	case int16:
+		return json.Number(strconv.FormatInt(int64(v), 10))
+	case int32:
+		return json.Number(strconv.FormatInt(int64(v), 10))
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10))
+	case int:
+		return json.Number(strconv.FormatInt(int64(v), 10))
+	case uint8:
+		return json.Number(strconv.FormatUint(uint64(v), 10))
+	case uint16:
+		return json.Number(strconv.FormatUint(uint64(v), 10))
+	case uint32:
+		return json.Number(strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		return json.Number(strconv.FormatUint(v, 10))
+	case uint:
+		return json.Number(strconv.FormatUint(uint64(v), 10))
+	case float32:
+		return float64(v)
+	case big.Int:
+		return json.Number(v.String())
+	case *big.Int:
+		return json.Number(v.String())
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case cbor.Tag:
+		return jsonCompatible(v.Content)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonCompatible(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		doc := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			name, ok := key.(string)
+			if !ok {
+				name = fmt.Sprint(jsonCompatible(key))
+			}
+			doc[name] = jsonCompatible(item)
+		}
+		return doc
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonCompatible(item)
+		}
+		return v
+	}
+	return value
+}
@@ -0,0 +1,124 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// binaryTestRecords are encoded as a stream by each binary format test.
+var binaryTestRecords = []interface{}{
+	map[string]interface{}{"id": "1", "temp": 21.5, "count": int64(3), "raw": []byte("hi")},
+	map[interface{}]interface{}{"id": "2", 7: "seven", "tags": []interface{}{uint8(1), "a"}, "at": time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+}
+
+// binaryTestWant is binaryTestRecords after decoding.
+var binaryTestWant = []string{
+	`{"count":3,"id":"1","raw":"aGk=","temp":21.5}`,
+	`{"7":"seven","at":"2026-01-02T03:04:05Z","id":"2","tags":[1,"a"]}`,
+}
+
+// TestBinaryReaders verifies behavior for the related scenario.
+func TestBinaryReaders(t *testing.T) {
+	t.Parallel()
+
+	encoders := []struct {
+		format string
+		file   string
+		encode func(interface{}) ([]byte, error)
+	}{
+		{format: formatMsgpack, file: "events.msgpack", encode: msgpack.Marshal},
+		{format: formatCBOR, file: "events.cbor", encode: func(value interface{}) ([]byte, error) {
+			mode, err := cbor.EncOptions{Time: cbor.TimeRFC3339, TimeTag: cbor.EncTagRequired}.EncMode()
+			if err != nil {
+				return nil, err
+			}
+			return mode.Marshal(value)
+		}},
+	}
+	for _, tt := range encoders {
+		var stream bytes.Buffer
+		for _, record := range binaryTestRecords {
+			encoded, err := tt.encode(record)
+			if err != nil {
+				t.Fatalf("%s: encoding returned error: %v", tt.format, err)
+			}
+			stream.Write(encoded)
+		}
+		got, err := readTestDocuments(t, inputConfig{}, writeTestDataFile(t, tt.file, stream.String()))
+		if err != nil {
+			t.Fatalf("%s: returned error: %v", tt.format, err)
+		}
+		if strings.Join(got, "\n") != strings.Join(binaryTestWant, "\n") {
+			t.Fatalf("%s: read %q, want %q", tt.format, got, binaryTestWant)
+		}
+
+		notMap, _ := tt.encode([]interface{}{"x"})
+		if _, err := readTestDocuments(t, inputConfig{format: tt.format}, writeTestDataFile(t, "events.bin", stream.String()+string(notMap))); err == nil || !strings.Contains(err.Error(), "value 3") {
+			t.Fatalf("%s: expected a value 3 error, got %v", tt.format, err)
+		}
+		truncated := stream.Bytes()[:stream.Len()-2]
+		if _, err := readTestDocuments(t, inputConfig{format: tt.format}, writeTestDataFile(t, "events.bin", string(truncated))); err == nil || !strings.Contains(err.Error(), "value 2") {
+			t.Fatalf("%s: expected a value 2 error, got %v", tt.format, err)
+		}
+	}
+}
+
+// TestMsgpackReaderReportsTruncatedValue verifies behavior for the related scenario.
+func TestMsgpackReaderReportsTruncatedValue(t *testing.T) {
+	t.Parallel()
+
+	first, err := msgpack.Marshal(map[string]interface{}{"id": "1"})
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	second, err := msgpack.Marshal(map[string]interface{}{"id": "2", "tags": []interface{}{"a", "b"}})
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	// Each cut ends where the decoder reads the next byte of a value it has
+	// started: after the map header, after a key, and after the array header.
+	for _, cut := range []int{1, 4, 11} {
+		stream := string(first) + string(second[:cut])
+		got, err := readTestDocuments(t, inputConfig{format: formatMsgpack}, writeTestDataFile(t, "events.msgpack", stream))
+		if err == nil || !strings.Contains(err.Error(), "value 2") {
+			t.Fatalf("cut %d: expected a value 2 error, got %v after reading %q", cut, err, got)
+		}
+	}
+}
+
+// TestRunMsgpackDataFile verifies behavior for the related scenario.
+func TestRunMsgpackDataFile(t *testing.T) {
+	t.Parallel()
+
+	var stream bytes.Buffer
+	for _, id := range []string{"1", "2"} {
+		encoded, err := msgpack.Marshal(map[string]interface{}{"id": id, "level": 4})
+		if err != nil {
+			t.Fatalf("Marshal returned error: %v", err)
+		}
+		stream.Write(encoded)
+	}
+	server, bodies := newWatchTestServer(t)
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   writeTestDataFile(t, "cards.bin", stream.String()),
+		AddToIndex: true,
+		Format:     formatMsgpack,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsProcessed != 2 {
+		t.Fatalf("expected 2 processed documents, got %+v", result)
+	}
+	if sent := strings.Join(bodies(), ""); strings.Count(sent, `"level":4`) != 2 {
+		t.Fatalf("unexpected bulk bodies: %s", sent)
+	}
+}
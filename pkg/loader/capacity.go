@@ -0,0 +1,293 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Capacity Guardrail ────────────────────────────────────────────────────────
+
+// Options.CapacityCheck modes.
+const (
+	capacityWarn   = "warn"
+	capacityRefuse = "refuse"
+)
+
+// Elasticsearch's defaults for the settings the guardrail reads, used when
+// the cluster does not report them.
+const (
+	defaultHighWatermark    = "90%"
+	defaultMaxShardsPerNode = 1000
+)
+
+// capacityPlan is what a load is about to add to the cluster.
+type capacityPlan struct {
+	// IncomingBytes is the size of the data files times the copies each
+	// document is stored in; 0 when no file size is known.
+	IncomingBytes int64
+	// NewShards counts the primary and replica shards of the index the load
+	// creates; 0 when it writes to existing indices.
+	NewShards int
+	// MinFreeDisk is the Options.MinFreeDisk floor every node must keep.
+	MinFreeDisk int64
+}
+
+// nodeDisk is one data node's row of _cat/allocation.
+type nodeDisk struct {
+	Node  string
+	Avail int64
+	Total int64
+}
+
+// diskWatermark is the high disk watermark, either a used ratio or, when
+// set as an absolute size, the free bytes each node must keep.
+type diskWatermark struct {
+	Ratio float64
+	Free  int64
+}
+
+// capacityMode returns Options.CapacityCheck, which defaults to refuse
+// when only MinFreeDisk is set.
+func (o Options) capacityMode() string {
+	if o.CapacityCheck == "" && o.MinFreeDisk > 0 {
+		return capacityRefuse
+	}
+	return o.CapacityCheck
+}
+
+// validateCapacityCheck rejects unknown -capacity-check modes and checks
+// for runs that load nothing.
+func validateCapacityCheck(opts Options, action dataAction) error {
+	if opts.MinFreeDisk < 0 {
+		return fmt.Errorf("-min-free-disk must be 0 or greater")
+	}
+	switch opts.capacityMode() {
+	case "":
+		return nil
+	case capacityWarn, capacityRefuse:
+	default:
+		return fmt.Errorf("-capacity-check must be %s or %s, got %q", capacityWarn, capacityRefuse, opts.CapacityCheck)
+	}
+	if !action.requiresDataFile() {
+		return fmt.Errorf("-capacity-check looks at what a load adds and needs -add, -flush, or -delete")
+	}
+	return nil
+}
+
+// newCapacityPlan sizes a load from its data files, replicas, and, when it
+// creates an index, the -settings shard count.
+func newCapacityPlan(opts Options, replicas int, createsIndex bool, settingsBody string) capacityPlan {
+	plan := capacityPlan{MinFreeDisk: opts.MinFreeDisk}
+	for _, path := range opts.dataFiles() {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			log.Debug().Str("data_file", path).Msg("Could not size data file; leaving it out of the capacity check")
+			continue
+		}
+		plan.IncomingBytes += info.Size()
+	}
+	plan.IncomingBytes *= int64(1 + replicas)
+	if createsIndex {
+		shards := 1
+		var settings map[string]json.RawMessage
+		if json.Unmarshal([]byte(settingsBody), &settings) == nil {
+			if parsed, ok := parseReplicas(settings["number_of_shards"]); ok && parsed > 0 {
+				shards = parsed
+			}
+		}
+		plan.NewShards = shards * (1 + replicas)
+	}
+	return plan
+}
+
+// checkClusterCapacity compares plan with the cluster's free disk, high
+// watermark, and shard limit, and returns one message per problem. The
+// incoming data is assumed to spread evenly over the data nodes.
+func checkClusterCapacity(es *elasticsearch.Client, plan capacityPlan) ([]string, error) {
+	nodes, err := readNodeDisks(es)
+	if err != nil {
+		return nil, fmt.Errorf("reading disk allocation: %w", err)
+	}
+	settings, err := readClusterSettings(es, "cluster.routing.allocation.disk.watermark.high", "cluster.max_shards_per_node")
+	if err != nil {
+		return nil, fmt.Errorf("reading cluster settings: %w", err)
+	}
+	watermarkText := settings["cluster.routing.allocation.disk.watermark.high"]
+	if watermarkText == "" {
+		watermarkText = defaultHighWatermark
+	}
+	watermark, err := parseDiskWatermark(watermarkText)
+	if err != nil {
+		return nil, err
+	}
+	maxShardsPerNode := defaultMaxShardsPerNode
+	if parsed, err := strconv.Atoi(settings["cluster.max_shards_per_node"]); err == nil && parsed > 0 {
+		maxShardsPerNode = parsed
+	}
+	shards, dataNodes, err := readClusterShards(es)
+	if err != nil {
+		return nil, fmt.Errorf("reading cluster stats: %w", err)
+	}
+
+	var problems []string
+	if len(nodes) > 0 && plan.IncomingBytes > 0 {
+		share := plan.IncomingBytes / int64(len(nodes))
+		for _, node := range nodes {
+			avail := node.Avail - share
+			used := float64(node.Total-avail) / float64(node.Total)
+			switch {
+			case watermark.Free == 0 && used > watermark.Ratio:
+				problems = append(problems, fmt.Sprintf("node %s would be %.0f%% full after about %s more, past the %s high disk watermark", node.Node, used*100, formatBytes(share), watermarkText))
+			case watermark.Free > 0 && avail < watermark.Free:
+				problems = append(problems, fmt.Sprintf("node %s would have %s free after about %s more, under the %s high disk watermark", node.Node, formatBytes(max(avail, 0)), formatBytes(share), watermarkText))
+			}
+			if plan.MinFreeDisk > 0 && avail < plan.MinFreeDisk {
+				problems = append(problems, fmt.Sprintf("node %s would have %s free after about %s more, under -min-free-disk %s", node.Node, formatBytes(max(avail, 0)), formatBytes(share), formatBytes(plan.MinFreeDisk)))
+			}
+		}
+	}
+	if limit := maxShardsPerNode * dataNodes; dataNodes > 0 && shards+plan.NewShards > limit {
+		problems = append(problems, fmt.Sprintf("the cluster holds %d shards and the load adds %d, past the limit of %d (%d per node on %d data nodes)", shards, plan.NewShards, limit, maxShardsPerNode, dataNodes))
+	}
+	log.Info().
+		Int64("incoming_bytes", plan.IncomingBytes).
+		Str("incoming_size", formatBytes(plan.IncomingBytes)).
+		Int("new_shards", plan.NewShards).
+		Int("cluster_shards", shards).
+		Int("data_nodes", dataNodes).
+		Str("high_watermark", watermarkText).
+		Int("problems", len(problems)).
+		Msg("Cluster capacity checked")
+	return problems, nil
+}
+
+// readNodeDisks returns the disk rows of _cat/allocation, leaving out the
+// UNASSIGNED row and nodes that report no disk.
+func readNodeDisks(es *elasticsearch.Client) ([]nodeDisk, error) {
+	res, err := es.Cat.Allocation(es.Cat.Allocation.WithFormat("json"), es.Cat.Allocation.WithBytes("b"))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var rows []struct {
+		Node  string `json:"node"`
+		Avail string `json:"disk.avail"`
+		Total string `json:"disk.total"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("parsing allocation: %w", err)
+	}
+	nodes := make([]nodeDisk, 0, len(rows))
+	for _, row := range rows {
+		avail, availErr := strconv.ParseInt(row.Avail, 10, 64)
+		total, totalErr := strconv.ParseInt(row.Total, 10, 64)
+		if availErr != nil || totalErr != nil || total <= 0 {
+			continue
+		}
+		nodes = append(nodes, nodeDisk{Node: row.Node, Avail: avail, Total: total})
+	}
+	return nodes, nil
+}
+
+// readClusterSettings returns the effective value of each named cluster
+// setting: transient over persistent over the default.
+func readClusterSettings(es *elasticsearch.Client, names ...string) (map[string]string, error) {
+	res, err := es.Cluster.GetSettings(es.Cluster.GetSettings.WithIncludeDefaults(true), es.Cluster.GetSettings.WithFlatSettings(true))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var layers struct {
+		Transient  map[string]any `json:"transient"`
+		Persistent map[string]any `json:"persistent"`
+		Defaults   map[string]any `json:"defaults"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&layers); err != nil {
+		return nil, fmt.Errorf("parsing cluster settings: %w", err)
+	}
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		for _, layer := range []map[string]any{layers.Transient, layers.Persistent, layers.Defaults} {
+			if value, ok := layer[name]; ok {
+				values[name] = fmt.Sprint(value)
+				break
+			}
+		}
+	}
+	return values, nil
+}
+
+// readClusterShards returns the cluster's shard total and data node count
+// from _cluster/stats.
+func readClusterShards(es *elasticsearch.Client) (int, int, error) {
+	res, err := es.Cluster.Stats()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return 0, 0, fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var stats struct {
+		Indices struct {
+			Shards struct {
+				Total int `json:"total"`
+			} `json:"shards"`
+		} `json:"indices"`
+		Nodes struct {
+			Count struct {
+				Data int `json:"data"`
+			} `json:"count"`
+		} `json:"nodes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&stats); err != nil {
+		return 0, 0, fmt.Errorf("parsing cluster stats: %w", err)
+	}
+	return stats.Indices.Shards.Total, stats.Nodes.Count.Data, nil
+}
+
+// parseDiskWatermark reads a watermark setting: a percentage such as 90%,
+// a ratio such as 0.9, or a free-space size such as 50gb.
+func parseDiskWatermark(value string) (diskWatermark, error) {
+	text := strings.ToLower(strings.TrimSpace(value))
+	if percent, ok := strings.CutSuffix(text, "%"); ok {
+		parsed, err := strconv.ParseFloat(percent, 64)
+		if err != nil {
+			return diskWatermark{}, fmt.Errorf("parsing disk watermark %q: %w", value, err)
+		}
+		return diskWatermark{Ratio: parsed / 100}, nil
+	}
+	if ratio, err := strconv.ParseFloat(text, 64); err == nil {
+		return diskWatermark{Ratio: ratio}, nil
+	}
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{{"pb", 1 << 50}, {"tb", 1 << 40}, {"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10}, {"b", 1}}
+	for _, unit := range units {
+		if number, ok := strings.CutSuffix(text, unit.suffix); ok {
+			parsed, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				break
+			}
+			return diskWatermark{Free: int64(parsed * float64(unit.multiplier))}, nil
+		}
+	}
+	return diskWatermark{}, fmt.Errorf("parsing disk watermark %q: expected a percentage, ratio, or size", value)
+}
@@ -0,0 +1,196 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// TestParseDiskWatermark verifies behavior for the related scenario.
+func TestParseDiskWatermark(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value   string
+		want    diskWatermark
+		wantErr bool
+	}{
+		{value: "90%", want: diskWatermark{Ratio: 0.9}},
+		{value: " 85.5% ", want: diskWatermark{Ratio: 0.855}},
+		{value: "0.95", want: diskWatermark{Ratio: 0.95}},
+		{value: "50gb", want: diskWatermark{Free: 50 << 30}},
+		{value: "500MB", want: diskWatermark{Free: 500 << 20}},
+		{value: "1024b", want: diskWatermark{Free: 1024}},
+		{value: "lots", wantErr: true},
+		{value: "x%", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDiskWatermark(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Fatalf("%q: expected an error, got %+v", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Fatalf("%q: expected %+v, got %+v, %v", tt.value, tt.want, got, err)
+		}
+	}
+}
+
+// TestValidateCapacityCheck verifies behavior for the related scenario.
+func TestValidateCapacityCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opts     Options
+		action   dataAction
+		wantMode string
+		wantErr  string
+	}{
+		{name: "off", action: dataActionNone},
+		{name: "warn", opts: Options{CapacityCheck: "warn"}, action: dataActionAdd, wantMode: capacityWarn},
+		{name: "floor implies refuse", opts: Options{MinFreeDisk: 1 << 30}, action: dataActionDelete, wantMode: capacityRefuse},
+		{name: "floor with warn", opts: Options{CapacityCheck: "warn", MinFreeDisk: 1 << 30}, action: dataActionAdd, wantMode: capacityWarn},
+		{name: "unknown mode", opts: Options{CapacityCheck: "block"}, action: dataActionAdd, wantErr: "warn or refuse"},
+		{name: "negative floor", opts: Options{MinFreeDisk: -1}, action: dataActionAdd, wantErr: "0 or greater"},
+		{name: "no load", opts: Options{CapacityCheck: "refuse"}, action: dataActionNone, wantErr: "needs -add"},
+	}
+	for _, tt := range tests {
+		err := validateCapacityCheck(tt.opts, tt.action)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			if got := tt.opts.capacityMode(); got != tt.wantMode {
+				t.Fatalf("%s: expected mode %q, got %q", tt.name, tt.wantMode, got)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// TestNewCapacityPlan verifies behavior for the related scenario.
+func TestNewCapacityPlan(t *testing.T) {
+	t.Parallel()
+
+	data := writeTestDataFile(t, "cards.ndjson", strings.Repeat("x", 100))
+	opts := Options{DataFile: data, MinFreeDisk: 10}
+
+	plan := newCapacityPlan(opts, 2, true, `{"number_of_shards":3,"number_of_replicas":2}`)
+	if plan.IncomingBytes != 300 || plan.NewShards != 9 || plan.MinFreeDisk != 10 {
+		t.Fatalf("expected 300 bytes and 9 shards, got %+v", plan)
+	}
+	plan = newCapacityPlan(opts, 1, true, "")
+	if plan.IncomingBytes != 200 || plan.NewShards != 2 {
+		t.Fatalf("expected one primary and one replica by default, got %+v", plan)
+	}
+	plan = newCapacityPlan(opts, 1, false, `{"number_of_shards":3}`)
+	if plan.NewShards != 0 {
+		t.Fatalf("expected no new shards when writing to an existing index, got %+v", plan)
+	}
+}
+
+// capacityHandler serves two data nodes with the given free bytes out of
+// 1000 each, a high watermark, and a cluster holding shards shards.
+func capacityHandler(avail, watermark, shards string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch r.URL.Path {
+		case "/_cat/allocation":
+			_, _ = w.Write([]byte(`[{"node":"n1","disk.avail":"` + avail + `","disk.total":"1000"},{"node":"n2","disk.avail":"` + avail + `","disk.total":"1000"},{"node":"UNASSIGNED","disk.avail":null,"disk.total":null}]`))
+		case "/_cluster/settings":
+			_, _ = w.Write([]byte(`{"persistent":{"cluster.max_shards_per_node":"10"},"transient":{},"defaults":{"cluster.routing.allocation.disk.watermark.high":"` + watermark + `","cluster.max_shards_per_node":"1000"}}`))
+		case "/_cluster/stats":
+			_, _ = w.Write([]byte(`{"indices":{"shards":{"total":` + shards + `}},"nodes":{"count":{"data":2}}}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	})
+}
+
+// TestCheckClusterCapacity verifies behavior for the related scenario.
+func TestCheckClusterCapacity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		avail     string
+		watermark string
+		shards    string
+		plan      capacityPlan
+		want      []string
+	}{
+		{name: "fits", avail: "500", watermark: "90%", shards: "4", plan: capacityPlan{IncomingBytes: 200, NewShards: 2}},
+		{name: "past ratio", avail: "200", watermark: "90%", shards: "4", plan: capacityPlan{IncomingBytes: 400}, want: []string{"node n1 would be 100% full", "node n2 would be 100% full"}},
+		{name: "past free size", avail: "200", watermark: "150b", shards: "4", plan: capacityPlan{IncomingBytes: 200}, want: []string{"under the 150b high disk watermark", "under the 150b high disk watermark"}},
+		{name: "under floor", avail: "500", watermark: "90%", shards: "4", plan: capacityPlan{IncomingBytes: 200, MinFreeDisk: 450}, want: []string{"under -min-free-disk", "under -min-free-disk"}},
+		{name: "shard limit", avail: "500", watermark: "90%", shards: "18", plan: capacityPlan{NewShards: 4}, want: []string{"past the limit of 20 (10 per node on 2 data nodes)"}},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(capacityHandler(tt.avail, tt.watermark, tt.shards))
+		es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{server.URL}})
+		if err != nil {
+			server.Close()
+			t.Fatalf("%s: creating client: %v", tt.name, err)
+		}
+		problems, err := checkClusterCapacity(es, tt.plan)
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if len(problems) != len(tt.want) {
+			t.Fatalf("%s: expected %d problems, got %q", tt.name, len(tt.want), problems)
+		}
+		for i, want := range tt.want {
+			if !strings.Contains(problems[i], want) {
+				t.Fatalf("%s: expected problem %d to contain %q, got %q", tt.name, i, want, problems[i])
+			}
+		}
+	}
+}
+
+// TestRunCapacityCheckRefuses verifies behavior for the related scenario.
+func TestRunCapacityCheckRefuses(t *testing.T) {
+	t.Parallel()
+
+	var bulk bool
+	handler := capacityHandler("100", "90%", "4")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/_bulk":
+			bulk = true
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			w.Header().Set("X-Elastic-Product", "Elasticsearch")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	data := writeTestDataFile(t, "cards.ndjson", strings.Repeat("{\"name\":\"card\"}\n", 20))
+
+	_, err := Run(context.Background(), Options{
+		URL:         server.URL,
+		Index:       "cards",
+		DataFile:    data,
+		AddToIndex:  true,
+		MinFreeDisk: 90,
+	})
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "under -min-free-disk") {
+		t.Fatalf("expected a capacity validation error, got %v", err)
+	}
+	if bulk {
+		t.Fatalf("expected nothing to be written after the capacity check refused the load")
+	}
+}
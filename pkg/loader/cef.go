@@ -0,0 +1,253 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ─── CEF and LEEF Input ────────────────────────────────────────────────────────
+
+// lineParserFunc adapts a function to a lineParser.
+type lineParserFunc func(line string) (map[string]interface{}, error)
+
+// parse calls f.
+func (f lineParserFunc) parse(line string) (map[string]interface{}, error) {
+	return f(line)
+}
+
+// securityLogKey matches an extension key; a key=value pair whose key
+// does not match is part of the value before it, as an unescaped = in a
+// URL often is.
+var securityLogKey = regexp.MustCompile(`^[A-Za-z0-9_.\-\[\]]+$`)
+
+// securityLogTimeLayouts are the date formats CEF rt and LEEF devTime use
+// besides milliseconds since the epoch.
+var securityLogTimeLayouts = []string{
+	"Jan 2 2006 15:04:05",
+	"Jan 2 2006 15:04:05.000",
+	"Jan 2 2006 15:04:05 MST",
+	"Jan 2 2006 15:04:05.000 MST",
+	time.RFC3339,
+}
+
+// parseCEFLine parses an ArcSight CEF line, with or without a syslog
+// header, into its header fields under cef and its extension under
+// cef.extensions.
+func parseCEFLine(line string) (map[string]interface{}, error) {
+	start := strings.Index(line, "CEF:")
+	if start < 0 {
+		return nil, errors.New("line has no CEF: header")
+	}
+	header, extension, err := splitSecurityLogHeader(line[start+len("CEF:"):], 7)
+	if err != nil {
+		return nil, fmt.Errorf("CEF %w", err)
+	}
+	extensions, err := parseCEFExtension(extension)
+	if err != nil {
+		return nil, err
+	}
+	cef := map[string]interface{}{
+		"version":    header[0],
+		"device":     map[string]interface{}{"vendor": header[1], "product": header[2], "version": header[3], "event_class_id": header[4]},
+		"name":       header[5],
+		"severity":   securityLogSeverity(header[6]),
+		"extensions": extensions,
+	}
+	doc := map[string]interface{}{"cef": cef}
+	if rt, ok := extensions["rt"].(string); ok {
+		if timestamp, ok := securityLogTime(rt); ok {
+			doc["@timestamp"] = timestamp
+		}
+	}
+	setSyslogHeader(doc, line[:start])
+	return doc, nil
+}
+
+// parseCEFExtension splits a CEF extension into its key=value pairs. A
+// value runs to the space before the next key and may hold \= \\ \n and
+// \r escapes.
+func parseCEFExtension(extension string) (map[string]interface{}, error) {
+	type pair struct{ keyStart, equals int }
+	var pairs []pair
+	for i := 0; i < len(extension); i++ {
+		switch extension[i] {
+		case '\\':
+			i++
+		case '=':
+			keyStart := strings.LastIndexByte(extension[:i], ' ') + 1
+			if securityLogKey.MatchString(extension[keyStart:i]) {
+				pairs = append(pairs, pair{keyStart: keyStart, equals: i})
+			}
+		}
+	}
+	if len(pairs) == 0 && strings.TrimSpace(extension) != "" {
+		return nil, errors.New("CEF extension has no key=value pairs")
+	}
+	extensions := make(map[string]interface{}, len(pairs))
+	for i, p := range pairs {
+		end := len(extension)
+		if i+1 < len(pairs) {
+			end = pairs[i+1].keyStart
+		}
+		extensions[extension[p.keyStart:p.equals]] = unescapeCEFValue(strings.TrimSpace(extension[p.equals+1 : end]))
+	}
+	return extensions, nil
+}
+
+// unescapeCEFValue undoes the escaping of a CEF extension value.
+func unescapeCEFValue(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			out.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		default:
+			out.WriteByte(value[i])
+		}
+	}
+	return out.String()
+}
+
+// parseLEEFLine parses an IBM QRadar LEEF 1.0 or 2.0 line, with or without
+// a syslog header, into its header fields under leef and its attributes
+// under leef.extensions.
+func parseLEEFLine(line string) (map[string]interface{}, error) {
+	start := strings.Index(line, "LEEF:")
+	if start < 0 {
+		return nil, errors.New("line has no LEEF: header")
+	}
+	body := line[start+len("LEEF:"):]
+	// LEEF 2.0 adds the attribute delimiter as a sixth header field.
+	fields := 5
+	if strings.HasPrefix(body, "2") {
+		fields = 6
+	}
+	header, attributes, err := splitSecurityLogHeader(body, fields)
+	if err != nil {
+		return nil, fmt.Errorf("LEEF %w", err)
+	}
+	delimiter := "\t"
+	if fields == 6 {
+		if delimiter, err = leefDelimiter(header[5]); err != nil {
+			return nil, err
+		}
+	}
+	extensions := make(map[string]interface{})
+	last := ""
+	for _, attribute := range strings.Split(attributes, delimiter) {
+		key, value, ok := strings.Cut(attribute, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !securityLogKey.MatchString(key) {
+			// A delimiter inside a value splits it; put it back.
+			if previous, found := extensions[last].(string); found {
+				extensions[last] = previous + delimiter + attribute
+			}
+			continue
+		}
+		extensions[key], last = value, key
+	}
+	doc := map[string]interface{}{"leef": map[string]interface{}{
+		"version":    header[0],
+		"device":     map[string]interface{}{"vendor": header[1], "product": header[2], "version": header[3]},
+		"event_id":   header[4],
+		"extensions": extensions,
+	}}
+	if devTime, ok := extensions["devTime"].(string); ok {
+		if timestamp, ok := securityLogTime(devTime); ok {
+			doc["@timestamp"] = timestamp
+		}
+	}
+	setSyslogHeader(doc, line[:start])
+	return doc, nil
+}
+
+// leefDelimiter returns the attribute delimiter of a LEEF 2.0 header: one
+// character, or its hex code as xHH or 0xHH. An empty one means a tab.
+func leefDelimiter(field string) (string, error) {
+	hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(field), "0"), "x")
+	switch {
+	case field == "":
+		return "\t", nil
+	case len(field) == 1:
+		return field, nil
+	case len(hex) == 2 && len(hex) < len(field):
+		if code, err := strconv.ParseUint(hex, 16, 8); err == nil {
+			return string(rune(code)), nil
+		}
+	}
+	return "", fmt.Errorf("LEEF delimiter %q is not a character or xHH hex code", field)
+}
+
+// splitSecurityLogHeader splits the |-separated header of a CEF or LEEF
+// line into count fields, undoing \| and \\ escapes, and returns the
+// rest. A line that stops after the last header field has no rest.
+func splitSecurityLogHeader(body string, count int) ([]string, string, error) {
+	fields := make([]string, 0, count)
+	var field strings.Builder
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '\\' && i+1 < len(body) && (body[i+1] == '|' || body[i+1] == '\\'):
+			i++
+			field.WriteByte(body[i])
+		case c == '|':
+			fields = append(fields, field.String())
+			field.Reset()
+			if len(fields) == count {
+				return fields, body[i+1:], nil
+			}
+		default:
+			field.WriteByte(c)
+		}
+	}
+	if len(fields) == count-1 {
+		return append(fields, field.String()), "", nil
+	}
+	return nil, "", fmt.Errorf("header has %d of its %d |-separated fields", len(fields)+1, count)
+}
+
+// securityLogSeverity returns a numeric severity as an integer, and a
+// named one such as High as written.
+func securityLogSeverity(severity string) interface{} {
+	if n, err := strconv.ParseInt(strings.TrimSpace(severity), 10, 64); err == nil {
+		return n
+	}
+	return severity
+}
+
+// securityLogTime converts milliseconds since the epoch, or one of
+// securityLogTimeLayouts, to an RFC 3339 timestamp. Times without a zone
+// are UTC.
+func securityLogTime(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC().Format("2006-01-02T15:04:05.000Z07:00"), true
+	}
+	for _, layout := range securityLogTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(time.RFC3339Nano), true
+		}
+	}
+	return "", false
+}
+
+// setSyslogHeader keeps the syslog header a relay wrote before the CEF or
+// LEEF payload as syslog.
+func setSyslogHeader(doc map[string]interface{}, prefix string) {
+	if prefix = strings.TrimSpace(prefix); prefix != "" {
+		doc["syslog"] = prefix
+	}
+}
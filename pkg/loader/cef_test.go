@@ -0,0 +1,96 @@
+package loader
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestCEFReaderParsesHeaderAndExtension verifies behavior for the related scenario.
+func TestCEFReaderParsesHeaderAndExtension(t *testing.T) {
+	t.Parallel()
+
+	config, err := newInputConfig(Options{})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	content := `<134>Oct 10 13:55:36 fw01 CEF:0|Acme|Fire\|Wall|2.1|100|Blocked connection|8|src=10.0.0.5 spt=51544 dst=203.0.113.7 dpt=443 rt=1728568536000 request=https://example.com/?a=b&c=d msg=Rule \= deny all\nsecond line cs1Label=Policy cs1=Default Deny` + "\n" +
+		"\n" +
+		`CEF:1|Acme|IDS|5.0|sig-7|Port scan|High|rt=Oct 10 2024 13:55:36 UTC` + "\r\n" +
+		"kernel: link up\n" +
+		`CEF:0|Acme|IDS|5.0|sig-8|Heartbeat|0`
+	got, err := readTestDocuments(t, config, writeTestDataFile(t, "events.cef", content))
+	if err != nil {
+		t.Fatalf("reading returned error: %v", err)
+	}
+	want := []string{
+		`{"@timestamp":"2024-10-10T13:55:36.000Z","cef":{"device":{"event_class_id":"100","product":"Fire|Wall","vendor":"Acme","version":"2.1"},"extensions":{"cs1":"Default Deny","cs1Label":"Policy","dpt":"443","dst":"203.0.113.7","msg":"Rule = deny all\nsecond line","request":"https://example.com/?a=b\u0026c=d","rt":"1728568536000","spt":"51544","src":"10.0.0.5"},"name":"Blocked connection","severity":8,"version":"0"},"syslog":"\u003c134\u003eOct 10 13:55:36 fw01"}`,
+		`{"@timestamp":"2024-10-10T13:55:36Z","cef":{"device":{"event_class_id":"sig-7","product":"IDS","vendor":"Acme","version":"5.0"},"extensions":{"rt":"Oct 10 2024 13:55:36 UTC"},"name":"Port scan","severity":"High","version":"1"}}`,
+		`{"cef":{"device":{"event_class_id":"sig-8","product":"IDS","vendor":"Acme","version":"5.0"},"extensions":{},"name":"Heartbeat","severity":0,"version":"0"}}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("read\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	for _, tt := range []struct {
+		raw  string
+		want string
+	}{
+		{raw: "Oct 10 13:55:36 fw01 sshd: started", want: "line 1: line has no CEF: header"},
+		{raw: "CEF:0|Acme|IDS|5.0", want: "line 1: CEF header has 4 of its 7 |-separated fields"},
+		{raw: "CEF:0|Acme|IDS|5.0|1|Name|3|just some text", want: "line 1: CEF extension has no key=value pairs"},
+	} {
+		if _, err := readTestDocuments(t, config, writeTestDataFile(t, "bad.cef", tt.raw+"\n")); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.raw, tt.want, err)
+		}
+	}
+}
+
+// TestLEEFReaderParsesVersions verifies behavior for the related scenario.
+func TestLEEFReaderParsesVersions(t *testing.T) {
+	t.Parallel()
+
+	config, err := newInputConfig(Options{Format: formatLEEF})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	content := "Jan 18 11:07:53 host LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0\tdst=172.50.123.1\tsev=5\tcat=anomaly\tmsg=a\tb\n" +
+		"LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^devTime=May 01 2024 08:00:00.250 UTC^note=x^y\n" +
+		"LEEF:2.0|Vendor|Product|1|7|x7C|a=1|b=2\n"
+	got, err := readTestDocuments(t, config, writeTestDataFile(t, "events.log", content))
+	if err != nil {
+		t.Fatalf("reading returned error: %v", err)
+	}
+	want := []string{
+		`{"leef":{"device":{"product":"MSExchange","vendor":"Microsoft","version":"4.0 SP1"},"event_id":"15345","extensions":{"cat":"anomaly","dst":"172.50.123.1","msg":"a\tb","sev":"5","src":"192.0.2.0"},"version":"1.0"},"syslog":"Jan 18 11:07:53 host"}`,
+		`{"@timestamp":"2024-05-01T08:00:00.25Z","leef":{"device":{"product":"StealthWatch","vendor":"Lancope","version":"1.0"},"event_id":"41","extensions":{"devTime":"May 01 2024 08:00:00.250 UTC","dst":"10.0.0.5","note":"x^y","src":"10.0.1.8"},"version":"2.0"}}`,
+		`{"leef":{"device":{"product":"Product","vendor":"Vendor","version":"1"},"event_id":"7","extensions":{"a":"1","b":"2"},"version":"2.0"}}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("read\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := readTestDocuments(t, config, writeTestDataFile(t, "bad.log", "LEEF:2.0|V|P|1|7|::|a=1\n")); err == nil || !strings.Contains(err.Error(), `LEEF delimiter "::" is not a character`) {
+		t.Fatalf("expected a bad delimiter to fail, got %v", err)
+	}
+}
+
+// TestRunCEFDataFile verifies behavior for the related scenario.
+func TestRunCEFDataFile(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   writeTestDataFile(t, "siem.log", "CEF:0|Acme|IDS|5.0|sig-7|Port scan|9|src=10.0.0.5\n"),
+		AddToIndex: true,
+		Format:     formatCEF,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if sent := strings.Join(bodies(), ""); result.DocumentsProcessed != 1 || !strings.Contains(sent, `"severity":9`) || !strings.Contains(sent, `"src":"10.0.0.5"`) {
+		t.Fatalf("unexpected result %+v and bulk bodies %s", result, sent)
+	}
+}
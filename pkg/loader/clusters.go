@@ -0,0 +1,108 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Multi-Cluster Fan-Out ─────────────────────────────────────────────────────
+
+// ClusterResult is the outcome of one target when a run fans out to several
+// clusters.
+type ClusterResult struct {
+	URL    string
+	Result Result
+	Err    error
+}
+
+// runClusters writes the same load to every URL in opts.Clusters. Each cluster
+// runs the full lifecycle (index setup, bulk load, alias and enrich steps) with
+// its own client and retry state, and a failure on one cluster does not stop
+// the others. Clusters run one after another because Run swaps the global
+// logger for the duration of a load.
+func runClusters(ctx context.Context, opts Options) (Result, error) {
+	if opts.Tail {
+		return Result{}, &RunError{Kind: ErrInvalidOptions, Op: "validating cluster options", Err: fmt.Errorf("-tail cannot write to more than one -url")}
+	}
+	if opts.RetryQueue != "" || opts.ErrorLog != "" {
+		// Their entries do not say which cluster they belong to, so retry
+		// would replay every cluster's documents to one of them.
+		return Result{}, &RunError{Kind: ErrInvalidOptions, Op: "validating cluster options", Err: fmt.Errorf("-retry-queue and -error-log record the failures of one cluster; pass a single -url")}
+	}
+	if opts.Journal != "" {
+		// A journal left by a failed cluster would make the next one skip
+		// documents it never received.
+		return Result{}, &RunError{Kind: ErrInvalidOptions, Op: "validating cluster options", Err: fmt.Errorf("-journal records the load of one cluster; pass a single -url")}
+	}
+	started := time.Now()
+	results := make([]ClusterResult, 0, len(opts.Clusters))
+	var (
+		failures []error
+		kind     error
+	)
+	for i, url := range opts.Clusters {
+		clusterOpts := opts
+		clusterOpts.URL = url
+		clusterOpts.Clusters = nil
+		// The fan-out sends one combined notification, and a per-cluster
+		// progress offset would move backwards between clusters.
+		clusterOpts.NotifyURL = ""
+		clusterOpts.OnProgress = nil
+
+		label := redactedURL(url)
+		log.Info().Str("cluster", label).Int("cluster_number", i+1).Int("clusters", len(opts.Clusters)).Msg("Loading cluster")
+		clusterResult, err := Run(ctx, clusterOpts)
+		results = append(results, ClusterResult{URL: url, Result: clusterResult, Err: err})
+		if err != nil {
+			if errors.Is(err, ErrInvalidOptions) {
+				// Options are shared, so every other cluster would fail the same way.
+				return Result{Clusters: results}, err
+			}
+			log.Error().Err(err).Str("cluster", label).Msg("Cluster load failed")
+			if kind == nil {
+				var typed *RunError
+				if errors.As(err, &typed) {
+					kind = typed.Kind
+				}
+			}
+			failures = append(failures, fmt.Errorf("%s: %w", label, err))
+			continue
+		}
+		log.Info().Str("cluster", label).Int("succeeded", clusterResult.DocumentsSucceeded).Int("failed", clusterResult.DocumentsFailed).Msg("Cluster load completed")
+	}
+
+	result := results[0].Result
+	result.Clusters = results
+	for _, entry := range results[1:] {
+		for _, warning := range entry.Result.Warnings {
+			result.Warnings = append(result.Warnings, redactedURL(entry.URL)+": "+warning)
+		}
+	}
+	var err error
+	if len(failures) > 0 {
+		if kind == nil {
+			kind = ErrLoaderExecution
+		}
+		err = &RunError{Kind: kind, Op: fmt.Sprintf("loading %d of %d clusters", len(failures), len(results)), Err: errors.Join(failures...)}
+	}
+	if strings.TrimSpace(opts.NotifyURL) != "" {
+		notifyRunFinished(ctx, opts.NotifyURL, opts.NotifyTemplate, buildRunSummary(opts.Index, result, err, started, time.Now()))
+	}
+	return result, err
+}
+
+// redactedURL hides any user:password embedded in a cluster URL before it is
+// logged.
+func redactedURL(raw string) string {
+	parsed, err := neturl.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return parsed.Redacted()
+}
@@ -0,0 +1,112 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunFansOutToEveryCluster verifies behavior for the related scenario.
+func TestRunFansOutToEveryCluster(t *testing.T) {
+	t.Parallel()
+
+	first, firstBodies := newWatchTestServer(t)
+	second, secondBodies := newWatchTestServer(t)
+	down := httptest.NewServer(nil)
+	down.Close()
+
+	dataFile := filepath.Join(t.TempDir(), "cards.json")
+	if err := os.WriteFile(dataFile, []byte(`[{"id":"1"}]`), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	result, err := Run(context.Background(), Options{
+		Clusters:          []string{first.URL, down.URL, second.URL},
+		Index:             "cards",
+		DataFile:          dataFile,
+		AddToIndex:        true,
+		BulkRetryAttempts: 1,
+	})
+	if err == nil {
+		t.Fatal("expected an error for the unreachable cluster")
+	}
+	if !strings.Contains(err.Error(), "loading 1 of 3 clusters") || !strings.Contains(err.Error(), down.URL) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Clusters) != 3 {
+		t.Fatalf("expected 3 cluster results, got %+v", result.Clusters)
+	}
+	if result.Clusters[0].Err != nil || result.Clusters[1].Err == nil || result.Clusters[2].Err != nil {
+		t.Fatalf("unexpected per-cluster errors: %+v", result.Clusters)
+	}
+	if result.DocumentsSucceeded != 1 || result.Clusters[2].Result.DocumentsSucceeded != 1 {
+		t.Fatalf("expected each healthy cluster to index the document, got %+v", result.Clusters)
+	}
+	for name, bodies := range map[string][]string{"first": firstBodies(), "second": secondBodies()} {
+		if len(bodies) != 1 || !strings.Contains(bodies[0], `"id":"1"`) {
+			t.Fatalf("%s cluster bulk bodies = %q", name, bodies)
+		}
+	}
+}
+
+// TestRunClustersRejectsTail verifies behavior for the related scenario.
+func TestRunClustersRejectsTail(t *testing.T) {
+	t.Parallel()
+
+	_, err := Run(context.Background(), Options{Clusters: []string{"http://a:9200", "http://b:9200"}, Index: "logs", AddToIndex: true, Tail: true})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions, got %v", err)
+	}
+}
+
+// TestRunClustersRejectsFailureFiles verifies behavior for the related scenario.
+func TestRunClustersRejectsFailureFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, opts := range []Options{{RetryQueue: filepath.Join(dir, "retry.ndjson")}, {ErrorLog: filepath.Join(dir, "errors.ndjson")}} {
+		opts.Clusters, opts.Index, opts.AddToIndex = []string{"http://a:9200", "http://b:9200"}, "logs", true
+		_, err := Run(context.Background(), opts)
+		if !errors.Is(err, ErrInvalidOptions) || !strings.Contains(err.Error(), "-retry-queue and -error-log") {
+			t.Fatalf("expected %+v to be rejected, got %v", opts, err)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected no files to be written, got %v", entries)
+	}
+}
+
+// TestRunClustersRejectsJournal verifies behavior for the related scenario.
+func TestRunClustersRejectsJournal(t *testing.T) {
+	t.Parallel()
+
+	journal := filepath.Join(t.TempDir(), "load.journal")
+	_, err := Run(context.Background(), Options{Clusters: []string{"http://a:9200", "http://b:9200"}, Index: "logs", AddToIndex: true, Journal: journal})
+	if !errors.Is(err, ErrInvalidOptions) || !strings.Contains(err.Error(), "-journal") {
+		t.Fatalf("expected -journal to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Fatalf("expected no journal to be written, got %v", err)
+	}
+}
+
+// TestRedactedURL verifies behavior for the related scenario.
+func TestRedactedURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "https://es:9200", want: "https://es:9200"},
+		{raw: "https://elastic:secret@es:9200", want: "https://elastic:xxxxx@es:9200"},
+	}
+	for _, tt := range tests {
+		if got := redactedURL(tt.raw); got != tt.want {
+			t.Fatalf("redactedURL(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+)
+
+// ─── Elasticsearch 7.x/8.x Compatibility ───────────────────────────────────────
+
+// compatTransport adapts the v9 client's requests to an older major
+// release. 8.x receives compatible-with=8 media types, so it answers in its
+// own API shape. 7.x receives plain JSON media types and, for releases
+// before 7.14 that do not send it, gets the product header the client
+// insists on added to successful responses.
+type compatTransport struct {
+	base  http.RoundTripper
+	major int
+}
+
+// RoundTrip rewrites the media type headers and forwards the request.
+func (t *compatTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	accept := req.Header.Get("Accept")
+	if accept == "" {
+		accept = "application/json"
+	}
+	req.Header.Set("Accept", compatMediaType(accept, t.major))
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		req.Header.Set("Content-Type", compatMediaType(contentType, t.major))
+	}
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	if t.major == 7 && res.StatusCode >= 200 && res.StatusCode < 300 && res.Header.Get("X-Elastic-Product") == "" {
+		res.Header.Set("X-Elastic-Product", "Elasticsearch")
+	}
+	return res, nil
+}
+
+// compatMediaType returns the JSON or NDJSON media type major accepts for
+// mediaType; other media types are returned unchanged.
+func compatMediaType(mediaType string, major int) string {
+	var kind string
+	switch {
+	case strings.Contains(mediaType, "ndjson"):
+		kind = "x-ndjson"
+	case strings.Contains(mediaType, "json"):
+		kind = "json"
+	default:
+		return mediaType
+	}
+	if major == 7 {
+		return "application/" + kind
+	}
+	return fmt.Sprintf("application/vnd.elasticsearch+%s;compatible-with=%d", kind, major)
+}
+
+// legacyMajor returns the older major release to adapt requests to, or 0
+// for a current cluster. Without a pre-flight the version is unknown, and
+// DocType alone is taken to mean 7.x. Serverless projects report a fixed
+// 8.x version but always run the current API.
+func legacyMajor(info ClusterInfo, docType string) int {
+	switch major := info.majorVersion(); {
+	case info.BuildFlavor == serverlessBuildFlavor:
+		return 0
+	case major == 7 || major == 8:
+		return major
+	case major == 0 && docType != "":
+		return 7
+	}
+	return 0
+}
+
+// validateDocType rejects a DocType the connected cluster cannot accept.
+func validateDocType(docType string, info ClusterInfo) error {
+	if docType == "" {
+		return nil
+	}
+	if strings.ContainsAny(docType, "/?#, ") {
+		return fmt.Errorf("-doc-type %q is not a valid mapping type name", docType)
+	}
+	if major := info.majorVersion(); major >= 8 {
+		return fmt.Errorf("-doc-type needs Elasticsearch 7.x; the cluster runs %s, which removed mapping types", info.Version)
+	}
+	return nil
+}
+
+// wrapMappingsInType nests the mappings of a create index body under
+// docType, as 7.x expects with include_type_name=true.
+func wrapMappingsInType(body, docType string) (string, error) {
+	var parsed map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		return "", fmt.Errorf("parsing index body: %w", err)
+	}
+	mappings := parsed["mappings"]
+	if len(mappings) == 0 {
+		mappings = json.RawMessage(`{}`)
+	}
+	typed, err := json.Marshal(map[string]json.RawMessage{docType: mappings})
+	if err != nil {
+		return "", err
+	}
+	parsed["mappings"] = typed
+	encoded, err := json.Marshal(parsed)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// createIndexRequest creates index with body. With docType the mappings are
+// typed, so the request asks 7.x to accept them with include_type_name.
+func createIndexRequest(es *elasticsearch.Client, index, body, docType string) (*esapi.Response, error) {
+	if docType == "" {
+		return es.Indices.Create(index, es.Indices.Create.WithBody(strings.NewReader(body)))
+	}
+	typed, err := wrapMappingsInType(body, docType)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPut, "/"+url.PathEscape(index)+"?include_type_name=true", bytes.NewReader([]byte(typed)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := es.Perform(req)
+	if err != nil {
+		return nil, err
+	}
+	return &esapi.Response{StatusCode: res.StatusCode, Header: res.Header, Body: res.Body}, nil
+}
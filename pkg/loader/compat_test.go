@@ -0,0 +1,149 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestCompatMediaType verifies behavior for the related scenario.
+func TestCompatMediaType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		mediaType string
+		major     int
+		want      string
+	}{
+		{mediaType: "application/json", major: 8, want: "application/vnd.elasticsearch+json;compatible-with=8"},
+		{mediaType: "application/x-ndjson", major: 8, want: "application/vnd.elasticsearch+x-ndjson;compatible-with=8"},
+		{mediaType: "application/vnd.elasticsearch+json;compatible-with=9", major: 7, want: "application/json"},
+		{mediaType: "application/vnd.elasticsearch+x-ndjson;compatible-with=9", major: 7, want: "application/x-ndjson"},
+		{mediaType: "text/plain", major: 7, want: "text/plain"},
+	}
+	for _, tt := range tests {
+		if got := compatMediaType(tt.mediaType, tt.major); got != tt.want {
+			t.Fatalf("compatMediaType(%q, %d) = %q, want %q", tt.mediaType, tt.major, got, tt.want)
+		}
+	}
+}
+
+// TestValidateDocType verifies behavior for the related scenario.
+func TestValidateDocType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		docType string
+		version string
+		want    string
+	}{
+		{name: "unset", version: "9.0.0"},
+		{name: "7.x", docType: "_doc", version: "7.17.0"},
+		{name: "version unknown", docType: "doc"},
+		{name: "8.x", docType: "_doc", version: "8.12.0", want: "needs Elasticsearch 7.x"},
+		{name: "bad name", docType: "a/b", version: "7.17.0", want: "not a valid mapping type"},
+	}
+	for _, tt := range tests {
+		err := validateDocType(tt.docType, ClusterInfo{Version: tt.version})
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+// TestRunLoadsTypedDocumentsIntoLegacyCluster verifies behavior for the related scenario.
+func TestRunLoadsTypedDocumentsIntoLegacyCluster(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		created  string
+		query    string
+		bulk     string
+		accepted []string
+	)
+	// A 7.10 cluster sends no X-Elastic-Product header.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		accepted = append(accepted, r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/":
+			_, _ = w.Write([]byte(`{"cluster_name":"old","version":{"number":"7.10.2"},"tagline":"You Know, for Search"}`))
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			if created == "" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPut && r.URL.Path == "/cards":
+			created, query = string(body), r.URL.RawQuery
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			bulk = string(body)
+			_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"_index":"cards","_type":"_doc","_id":"a","status":201}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Preflight:  true,
+		Index:      "cards",
+		DataFile:   writeTestDataFile(t, "cards.ndjson", "{\"id\":\"a\",\"n\":1}\n"),
+		AddToIndex: true,
+		IDField:    "id",
+		DocType:    "_doc",
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsSucceeded != 1 || result.Cluster.Version != "7.10.2" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if query != "include_type_name=true" || !strings.Contains(created, `"mappings":{"_doc":{`) {
+		t.Fatalf("expected typed mappings, got %q with query %q", created, query)
+	}
+	if !strings.HasPrefix(bulk, `{"index":{"_id":"a","_index":"cards","_type":"_doc"}}`) {
+		t.Fatalf("expected _type in bulk metadata, got %q", bulk)
+	}
+	for _, accept := range accepted[1:] {
+		if accept != "application/json" {
+			t.Fatalf("expected plain JSON media types for 7.x, got %q", accept)
+		}
+	}
+
+	_, err = Run(context.Background(), Options{
+		URL:       newPreflightTestServer(t, "8.15.0").URL,
+		Preflight: true,
+		Index:     "cards",
+		DataFile:  "cards.ndjson",
+		DocType:   "_doc",
+	})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions for -doc-type on 8.x, got %v", err)
+	}
+}
+
+// newPreflightTestServer answers GET / as a cluster running version.
+func newPreflightTestServer(t *testing.T, version string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"cluster_name":"c","version":{"number":"` + version + `"}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
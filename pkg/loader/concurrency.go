@@ -0,0 +1,105 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// ─── Optimistic Concurrency ────────────────────────────────────────────────────
+
+// Options.ConcurrencyControl modes.
+const (
+	// concurrencyInput takes each document's seq_no and primary_term from
+	// fields of the input, captured when it was exported.
+	concurrencyInput = "input"
+	// concurrencyFetch looks up each batch's current seq_no and
+	// primary_term with one _mget before it is sent.
+	concurrencyFetch = "fetch"
+)
+
+// Default document fields holding the captured sequence number and primary
+// term, named after the search hit keys they are usually copied from.
+const (
+	defaultSeqNoField       = "_seq_no"
+	defaultPrimaryTermField = "_primary_term"
+)
+
+// concurrencyCondition is the if_seq_no/if_primary_term condition of one
+// document; a document without one is sent as create.
+type concurrencyCondition struct {
+	SeqNo       int64
+	PrimaryTerm int64
+	Known       bool
+}
+
+// apply adds the condition to meta.
+func (c concurrencyCondition) apply(meta *bulkActionMeta) {
+	if !c.Known {
+		meta.IfSeqNo, meta.IfPrimaryTerm, meta.create = nil, nil, true
+		return
+	}
+	seqNo, primaryTerm := c.SeqNo, c.PrimaryTerm
+	meta.IfSeqNo, meta.IfPrimaryTerm, meta.create = &seqNo, &primaryTerm, false
+}
+
+// validateConcurrencyControl rejects modes that cannot protect anything.
+func validateConcurrencyControl(opts Options, action dataAction, format string) error {
+	switch opts.ConcurrencyControl {
+	case "":
+		return nil
+	case concurrencyInput, concurrencyFetch:
+	default:
+		return fmt.Errorf("-concurrency-control must be %s or %s, got %q", concurrencyInput, concurrencyFetch, opts.ConcurrencyControl)
+	}
+	if action != dataActionAdd {
+		return fmt.Errorf("-concurrency-control guards documents already in the index and needs -add")
+	}
+	if opts.IDField == "" && format != formatElasticdump {
+		return fmt.Errorf("-concurrency-control addresses documents by _id and needs -id or -format %s", formatElasticdump)
+	}
+	return nil
+}
+
+// takeConcurrencyFields moves the seq_no and primary_term fields of doc into
+// meta and returns doc without them. Without both as whole numbers the
+// document is sent as create.
+func takeConcurrencyFields(doc map[string]interface{}, meta *bulkActionMeta, seqNoField, primaryTermField string) map[string]interface{} {
+	var condition concurrencyCondition
+	seqNo, seqNoOK := integerValue(doc[seqNoField])
+	primaryTerm, primaryTermOK := integerValue(doc[primaryTermField])
+	if seqNoOK && primaryTermOK {
+		condition = concurrencyCondition{SeqNo: seqNo, PrimaryTerm: primaryTerm, Known: true}
+	}
+	condition.apply(meta)
+	_, hasSeqNo := doc[seqNoField]
+	_, hasPrimaryTerm := doc[primaryTermField]
+	if !hasSeqNo && !hasPrimaryTerm {
+		return doc
+	}
+	source := make(map[string]interface{}, len(doc))
+	for key, value := range doc {
+		if key != seqNoField && key != primaryTermField {
+			source[key] = value
+		}
+	}
+	return source
+}
+
+// fetchConcurrencyConditions looks up the current seq_no and primary_term
+// of batch with one _mget and returns one condition per document; missing
+// documents and documents without an _id get none, so they are created.
+func fetchConcurrencyConditions(ctx context.Context, es *elasticsearch.Client, index string, batch []map[string]interface{}, settings bulkSettings) ([]concurrencyCondition, error) {
+	fetched, positions, err := mgetBatch(ctx, es, index, batch, settings, es.Mget.WithSource("false"))
+	if err != nil {
+		return nil, err
+	}
+	conditions := make([]concurrencyCondition, len(batch))
+	for i, doc := range fetched.Docs {
+		if doc.Found && doc.SeqNo != nil && doc.PrimaryTerm != nil {
+			conditions[positions[i]] = concurrencyCondition{SeqNo: *doc.SeqNo, PrimaryTerm: *doc.PrimaryTerm, Known: true}
+		}
+	}
+	return conditions, nil
+}
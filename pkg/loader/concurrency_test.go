@@ -0,0 +1,187 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestValidateConcurrencyControl verifies behavior for the related scenario.
+func TestValidateConcurrencyControl(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    Options
+		action  dataAction
+		format  string
+		wantErr string
+	}{
+		{name: "off", opts: Options{}, action: dataActionFlush},
+		{name: "input", opts: Options{ConcurrencyControl: concurrencyInput, IDField: "id"}, action: dataActionAdd},
+		{name: "fetch elasticdump", opts: Options{ConcurrencyControl: concurrencyFetch}, action: dataActionAdd, format: formatElasticdump},
+		{name: "unknown mode", opts: Options{ConcurrencyControl: "lock", IDField: "id"}, action: dataActionAdd, wantErr: "must be input or fetch"},
+		{name: "flush", opts: Options{ConcurrencyControl: concurrencyInput, IDField: "id"}, action: dataActionFlush, wantErr: "needs -add"},
+		{name: "no id", opts: Options{ConcurrencyControl: concurrencyFetch}, action: dataActionAdd, wantErr: "needs -id"},
+	}
+	for _, tt := range tests {
+		err := validateConcurrencyControl(tt.opts, tt.action, tt.format)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// concurrencyBulkLines decodes the action and source lines of a bulk body.
+func concurrencyBulkLines(t *testing.T, body io.Reader) ([]map[string]map[string]any, []map[string]any) {
+	t.Helper()
+	var actions []map[string]map[string]any
+	var sources []map[string]any
+	scanner := bufio.NewScanner(body)
+	for line := 0; scanner.Scan(); line++ {
+		if line%2 == 0 {
+			var action map[string]map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+				t.Errorf("decoding action line: %v", err)
+			}
+			actions = append(actions, action)
+			continue
+		}
+		var source map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &source); err != nil {
+			t.Errorf("decoding source line: %v", err)
+		}
+		sources = append(sources, source)
+	}
+	return actions, sources
+}
+
+// TestRunConcurrencyControlInput verifies behavior for the related scenario.
+func TestRunConcurrencyControlInput(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var actions []map[string]map[string]any
+	var sources []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			gotActions, gotSources := concurrencyBulkLines(t, r.Body)
+			mu.Lock()
+			actions = append(actions, gotActions...)
+			sources = append(sources, gotSources...)
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	data := strings.Join([]string{
+		`{"id":"a","name":"Ann","_seq_no":7,"_primary_term":2}`,
+		`{"id":"b","name":"Bob"}`,
+	}, "\n") + "\n"
+	result, err := Run(context.Background(), Options{
+		URL:                server.URL,
+		Index:              "customers",
+		DataFile:           writeTestDataFile(t, "customers.ndjson", data),
+		AddToIndex:         true,
+		IDField:            "id",
+		ConcurrencyControl: concurrencyInput,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsProcessed != 2 || len(actions) != 2 {
+		t.Fatalf("expected 2 documents in 2 actions, got %d and %d", result.DocumentsProcessed, len(actions))
+	}
+	index, ok := actions[0]["index"]
+	if !ok || index["if_seq_no"] != float64(7) || index["if_primary_term"] != float64(2) {
+		t.Fatalf("expected an index action conditioned on 7/2, got %v", actions[0])
+	}
+	if _, ok := sources[0]["_seq_no"]; ok {
+		t.Fatalf("expected the captured fields to be left out of the source, got %v", sources[0])
+	}
+	if create, ok := actions[1]["create"]; !ok || create["if_seq_no"] != nil {
+		t.Fatalf("expected a document without a sequence number to be created, got %v", actions[1])
+	}
+}
+
+// TestRunConcurrencyControlFetchCountsConflicts verifies behavior for the related scenario.
+func TestRunConcurrencyControlFetchCountsConflicts(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var mgetBodies []string
+	var actions []map[string]map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.URL.Path == "/_mget":
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			mgetBodies = append(mgetBodies, string(body))
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"docs":[
+				{"_index":"customers","_id":"a","found":true,"_seq_no":11,"_primary_term":3},
+				{"_index":"customers","_id":"b","found":false}
+			]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			body, _ := io.ReadAll(r.Body)
+			gotActions, _ := concurrencyBulkLines(t, bytes.NewReader(body))
+			mu.Lock()
+			actions = append(actions, gotActions...)
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"errors":true,"items":[
+				{"index":{"_index":"customers","_id":"a","status":409,"error":{"type":"version_conflict_engine_exception","reason":"[a]: version conflict"}}},
+				{"create":{"_index":"customers","_id":"b","status":201}}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	data := "{\"id\":\"a\",\"name\":\"Ann\"}\n{\"id\":\"b\",\"name\":\"Bob\"}\n"
+	result, err := Run(context.Background(), Options{
+		URL:                server.URL,
+		Index:              "customers",
+		DataFile:           writeTestDataFile(t, "customers.ndjson", data),
+		AddToIndex:         true,
+		IDField:            "id",
+		ConcurrencyControl: concurrencyFetch,
+	})
+	if err != nil && !errors.Is(err, ErrBulkFailure) {
+		t.Fatalf("Run returned unexpected error: %v", err)
+	}
+	if len(mgetBodies) != 1 || !strings.Contains(mgetBodies[0], `"_id":"a"`) {
+		t.Fatalf("expected one _mget naming the batch, got %v", mgetBodies)
+	}
+	if len(actions) != 2 || actions[0]["index"]["if_seq_no"] != float64(11) || actions[0]["index"]["if_primary_term"] != float64(3) {
+		t.Fatalf("expected the fetched condition on the first action, got %v", actions)
+	}
+	if _, ok := actions[1]["create"]; !ok {
+		t.Fatalf("expected a document missing from the index to be created, got %v", actions[1])
+	}
+	if result.DocumentsConflicted != 1 {
+		t.Fatalf("expected 1 conflicted document, got %d", result.DocumentsConflicted)
+	}
+}
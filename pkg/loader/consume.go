@@ -0,0 +1,392 @@
+package loader
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Message Queue Sources ─────────────────────────────────────────────────────
+
+const (
+	// defaultConsumeFlushInterval bounds how long a partial batch of messages
+	// waits before it is sent.
+	defaultConsumeFlushInterval = 5 * time.Second
+	// consumeIdleWait is how long one receive waits for the first message
+	// of a batch.
+	consumeIdleWait = 20 * time.Second
+	// consumeSettleTimeout bounds acknowledging a batch, which still happens
+	// after the context ends so a stop does not leave delivered messages
+	// hanging until their visibility timeout.
+	consumeSettleTimeout = 30 * time.Second
+)
+
+// ConsumeOptions configures continuous ingestion from a message queue.
+type ConsumeOptions struct {
+	// Source names the queue: sqs://sqs.<region>.amazonaws.com/<account>/<queue>,
+	// pubsub://<project>/<subscription>, nats://<host>/<stream>/<consumer>,
+	// amqp://<host>/[<vhost>/]<queue>, redis://<host>[/<db>] with
+	// ?stream= or ?list=, or mongodb://<host>/<database>.
+	Source string
+	// Collection and Query name the MongoDB collection copied and the JSON
+	// filter its documents must match; they need a mongodb Source.
+	Collection string
+	Query      string
+	// FlushInterval bounds how long a partial batch waits; defaults to 5s.
+	FlushInterval time.Duration
+	// Load supplies the index, batch size, bulk settings, and document
+	// transforms. Each message is one JSON document.
+	Load Options
+}
+
+// sourceMessage is one delivery received from a message source.
+type sourceMessage struct {
+	// body is the message payload.
+	body []byte
+	// handle identifies the delivery to the source when it is settled: an
+	// SQS receipt handle, a Pub/Sub ack ID, a JetStream ack subject, an
+	// AMQP delivery tag, a Redis stream entry ID or list value, or a MongoDB
+	// _id.
+	handle string
+	// delivery, when set, is the client library's own message, for sources
+	// that settle through it.
+	delivery interface{}
+}
+
+// messageSource is a queue messages are consumed from. A message is
+// delivered again unless it is acknowledged.
+type messageSource interface {
+	// receive returns up to max messages, waiting up to wait for the first.
+	// It returns no messages and no error when none arrived in time.
+	receive(ctx context.Context, max int, wait time.Duration) ([]sourceMessage, error)
+	// ack settles messages that were indexed.
+	ack(ctx context.Context, msgs []sourceMessage) error
+	// nack hands messages back for redelivery as soon as possible.
+	nack(ctx context.Context, msgs []sourceMessage) error
+	// close releases the connection to the source.
+	close() error
+}
+
+// openMessageSource connects to the queue opts.Source names.
+func openMessageSource(opts ConsumeOptions) (messageSource, error) {
+	rawURL := opts.Source
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("-source is not a URL: %s", redactSourceURL(rawURL))
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if (opts.Collection != "" || opts.Query != "") && scheme != "mongodb" && scheme != "mongodb+srv" {
+		return nil, fmt.Errorf("-collection and -query need a mongodb:// -source")
+	}
+	switch scheme {
+	case "sqs":
+		return newSQSSource(u)
+	case "pubsub":
+		return newPubSubSource(u)
+	case "nats":
+		return newNATSSource(u)
+	case "amqp", "amqps":
+		return newAMQPSource(u)
+	case "redis", "rediss":
+		return newRedisSource(u)
+	case "mongodb", "mongodb+srv":
+		return newMongoSource(u, opts.Collection, opts.Query)
+	default:
+		return nil, fmt.Errorf("-source %s: expected an sqs://, pubsub://, nats://, amqp(s)://, redis(s)://, or mongodb(+srv):// URL", redactSourceURL(rawURL))
+	}
+}
+
+// sourceTLSConfig returns the TLS settings for a source on host. The ca
+// query option names a PEM file of certificates trusted instead of the
+// system roots.
+func sourceTLSConfig(host string, query url.Values) (*tls.Config, error) {
+	config := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	if file := query.Get("ca"); file != "" {
+		ca, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("-source ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("-source ca: no certificates in %s", file)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// redactSourceURL returns rawURL fit for logs and errors, without its
+// password or query string. A user name without a password is redacted
+// too, as it is then usually a token.
+func redactSourceURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid -source URL>"
+	}
+	u.RawQuery, u.ForceQuery, u.Fragment, u.RawFragment = "", false, "", ""
+	if u.User != nil {
+		if _, ok := u.User.Password(); !ok {
+			u.User = url.User("xxxxx")
+		}
+	}
+	return u.Redacted()
+}
+
+// consumer batches messages from one source into bulk requests.
+type consumer struct {
+	source        messageSource
+	es            *elasticsearch.Client
+	index         string
+	batchSize     int
+	flushInterval time.Duration
+	settings      bulkSettings
+	transforms    docTransformChain
+
+	batches   int
+	sent      int
+	received  int
+	indexed   int
+	redeliver int
+}
+
+// Consume loads the messages of opts.Source into opts.Load.Index until ctx
+// is cancelled. A message is acknowledged once every document made from it
+// is indexed; one that fails to decode, transform, or index is handed back
+// for redelivery, so a dead-letter policy on the queue catches poison
+// messages.
+func Consume(ctx context.Context, opts ConsumeOptions) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if strings.TrimSpace(opts.Source) == "" {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating source", Err: fmt.Errorf("-source requires a queue URL")}
+	}
+	if err := validateIndexName(opts.Load.Index); err != nil {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating index name", Err: fmt.Errorf("-index: %w", err)}
+	}
+	if len(opts.Load.dataFiles()) > 0 {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating source options", Err: fmt.Errorf("-data cannot be combined with -source")}
+	}
+	if opts.Load.Tail {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating source options", Err: fmt.Errorf("-tail cannot be combined with -source")}
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultConsumeFlushInterval
+	}
+	transforms, err := buildDocTransforms(opts.Load)
+	if err != nil {
+		return &RunError{Kind: ErrInvalidOptions, Op: "building document transforms", Err: err}
+	}
+	if strings.TrimSpace(opts.Load.DedupField) != "" {
+		// Keys would be recorded before the bulk request succeeds, so the
+		// redelivered copy of a failed message would be dropped and acked.
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating dedup options", Err: fmt.Errorf("-dedup-field is not supported with -source, as a redelivered message would be dropped as a duplicate")}
+	}
+	execStep, err := startExecTransform(opts.Load.TransformExec, opts.Load.TransformExecTimeout)
+	if err != nil {
+		return &RunError{Kind: ErrLoaderExecution, Op: "starting -transform-exec", Err: err}
+	}
+	defer execStep.close()
+	if execStep != nil {
+		transforms = append(transforms, execStep.transform())
+	}
+	es, err := elasticsearch.NewClient(elasticsearchConfig(opts.Load))
+	if err != nil {
+		return &RunError{Kind: ErrConnection, Op: "creating Elasticsearch client", Err: err}
+	}
+	source, err := openMessageSource(opts)
+	if err != nil {
+		return &RunError{Kind: ErrInvalidOptions, Op: "opening source", Err: err}
+	}
+	defer source.close()
+
+	c := &consumer{
+		source:        source,
+		es:            es,
+		index:         opts.Load.Index,
+		batchSize:     opts.Load.BatchSize,
+		flushInterval: opts.FlushInterval,
+		settings:      streamBulkSettings(opts.Load),
+		transforms:    transforms,
+	}
+	if c.batchSize <= 0 {
+		c.batchSize = 1000
+	}
+	if _, ok := source.(*mongoSource); ok {
+		// MongoDB documents carry their _id, which becomes the Elasticsearch _id.
+		c.settings.Metadata = true
+	}
+	if c.settings.StatsD, err = newStatsdClient(opts.Load.StatsDAddr); err != nil {
+		log.Warn().Err(err).Msg("StatsD disabled for -source")
+	}
+	return c.run(ctx, redactSourceURL(opts.Source))
+}
+
+// run receives and loads batches until ctx is cancelled. The first receive
+// must succeed, so a wrong URL or missing credentials fail at start; later
+// receive failures are retried with backoff.
+func (c *consumer) run(ctx context.Context, display string) error {
+	log.Info().Str("source", display).Str("index", c.index).Msg("Consuming messages")
+	stopWatchdog := notifyReady("consuming " + display)
+	defer stopWatchdog()
+	started := false
+	failures := 0
+	for {
+		msgs, err := c.collect(ctx)
+		if len(msgs) > 0 {
+			started = true
+			c.deliver(ctx, msgs)
+		}
+		if ctx.Err() != nil {
+			log.Info().Str("source", display).Int("received", c.received).Int("indexed", c.indexed).Int("redelivered", c.redeliver).Msg("Consuming stopped")
+			return nil
+		}
+		if err == nil {
+			started = true
+			failures = 0
+			continue
+		}
+		if !started {
+			return &RunError{Kind: ErrLoaderExecution, Op: "receiving from " + display, Err: err}
+		}
+		failures++
+		backoff := computeExponentialBackoff(failures, c.settings.RetryBackoffBase, c.settings.RetryBackoffMax)
+		log.Warn().Err(err).Str("source", display).Str("next_backoff", backoff.String()).Msg("Receiving messages failed; retrying")
+		if sleepWithContext(ctx, backoff) != nil {
+			return nil
+		}
+	}
+}
+
+// collect receives messages for one batch: up to the batch size, or what
+// arrived within the flush interval of the first one.
+func (c *consumer) collect(ctx context.Context) ([]sourceMessage, error) {
+	var msgs []sourceMessage
+	var deadline time.Time
+	for len(msgs) < c.batchSize {
+		wait := consumeIdleWait
+		if len(msgs) > 0 {
+			wait = time.Until(deadline)
+			if wait <= 0 {
+				break
+			}
+		}
+		received, err := c.source.receive(ctx, c.batchSize-len(msgs), wait)
+		msgs = append(msgs, received...)
+		if err != nil || ctx.Err() != nil {
+			return msgs, err
+		}
+		if len(msgs) > 0 && deadline.IsZero() {
+			deadline = time.Now().Add(c.flushInterval)
+		}
+		if len(received) == 0 && len(msgs) > 0 {
+			break
+		}
+	}
+	return msgs, nil
+}
+
+// deliver indexes the documents of msgs in one bulk request, then
+// acknowledges the messages whose documents were all indexed and hands the
+// rest back.
+func (c *consumer) deliver(ctx context.Context, msgs []sourceMessage) {
+	c.received += len(msgs)
+	failed := make([]bool, len(msgs))
+	var docs []map[string]interface{}
+	// owner maps each document to the message it came from.
+	var owner []int
+	for i, msg := range msgs {
+		var doc map[string]interface{}
+		if err := decodeJSON(msg.body, &doc); err != nil || doc == nil {
+			if err == nil {
+				err = fmt.Errorf("message is not a JSON object")
+			}
+			log.Warn().Err(err).Int("message", i).Msg("Skipping malformed message; it will be redelivered")
+			failed[i] = true
+			continue
+		}
+		outs := []map[string]interface{}{doc}
+		if len(c.transforms) > 0 {
+			var err error
+			if outs, err = c.transforms.apply(doc); err != nil {
+				log.Warn().Err(err).Int("message", i).Msg("Transforming message failed; it will be redelivered")
+				failed[i] = true
+				continue
+			}
+		}
+		for _, out := range outs {
+			docs = append(docs, out)
+			owner = append(owner, i)
+		}
+	}
+	if len(docs) > 0 {
+		result, err := c.send(ctx, docs)
+		if err != nil {
+			log.Error().Err(err).Int("messages", len(msgs)).Msg("Bulk request failed; returning the messages for redelivery")
+			for i := range failed {
+				failed[i] = true
+			}
+		}
+		for _, position := range result.FailedItems {
+			failed[owner[position]] = true
+		}
+	}
+	c.settle(ctx, msgs, failed)
+}
+
+// send indexes docs in one bulk request. Exhausted retries surface as an
+// error instead of stopping the consumer.
+func (c *consumer) send(ctx context.Context, docs []map[string]interface{}) (result bulkInsertResult, err error) {
+	defer func() {
+		// sendBulkPayload reports exhausted retries by panicking with a RunError.
+		if recovered := recover(); recovered != nil {
+			runErr, ok := recovered.(*RunError)
+			if !ok {
+				panic(recovered)
+			}
+			err = runErr
+		}
+	}()
+	body := newBulkBody()
+	defer body.release()
+	encodeBulkBody(body, c.index, docs, c.settings)
+	c.batches++
+	c.sent += len(docs)
+	return sendBulkPayload(ctx, c.es, c.index, body.Bytes(), len(docs), c.batches, c.sent, 0, c.settings), nil
+}
+
+// settle acknowledges the messages not marked failed and hands the failed
+// ones back. It runs past the end of ctx so a stop settles the last batch.
+func (c *consumer) settle(ctx context.Context, msgs []sourceMessage, failed []bool) {
+	var acks, nacks []sourceMessage
+	for i, msg := range msgs {
+		if failed[i] {
+			nacks = append(nacks, msg)
+		} else {
+			acks = append(acks, msg)
+		}
+	}
+	settleCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), consumeSettleTimeout)
+	defer cancel()
+	if len(acks) > 0 {
+		if err := c.source.ack(settleCtx, acks); err != nil {
+			log.Warn().Err(err).Int("messages", len(acks)).Msg("Acknowledging indexed messages failed; they will be delivered again")
+		}
+		c.indexed += len(acks)
+	}
+	if len(nacks) > 0 {
+		if err := c.source.nack(settleCtx, nacks); err != nil {
+			log.Warn().Err(err).Int("messages", len(nacks)).Msg("Returning messages failed; they are delivered again once their visibility timeout passes")
+		}
+		c.redeliver += len(nacks)
+	}
+	log.Debug().Int("batch", c.batches).Int("acked", len(acks)).Int("returned", len(nacks)).Msg("Settled messages")
+}
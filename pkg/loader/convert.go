@@ -0,0 +1,433 @@
+package loader
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Format Conversion ─────────────────────────────────────────────────────────
+
+const (
+	// convertNDJSON writes one JSON object per line.
+	convertNDJSON = "ndjson"
+	// convertCSV writes one row per document under a header of flattened
+	// field names.
+	convertCSV = "csv"
+	// convertParquet writes one row per document with a typed, flattened
+	// column per field.
+	convertParquet = "parquet"
+)
+
+// convertFormats lists the accepted ConvertOptions.Format values.
+var convertFormats = []string{convertNDJSON, convertCSV, convertParquet}
+
+// ConvertOptions configures Convert.
+type ConvertOptions struct {
+	// Output is the file written; it is replaced only once the conversion
+	// succeeds.
+	Output string
+	// Format is ndjson, csv, or parquet; empty picks it from the Output
+	// extension.
+	Format string
+	// Load names the data files, format, and transforms to read, as for
+	// Run; cluster and index settings are ignored.
+	Load Options
+}
+
+// Convert reads the documents of a load through the same input formats,
+// skipping, document transforms, and deduplication as Run, and writes them
+// to a file instead of Elasticsearch. CSV and Parquet flatten nested
+// objects into dot-joined columns and write arrays as JSON text. It returns
+// how many documents it wrote.
+func Convert(ctx context.Context, opts ConvertOptions) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	load := opts.Load
+	invalid := func(err error) (int, error) {
+		return 0, &RunError{Kind: ErrInvalidOptions, Op: "validating convert options", Err: err}
+	}
+	if strings.TrimSpace(opts.Output) == "" {
+		return invalid(fmt.Errorf("-convert-output is required"))
+	}
+	format, err := convertFormatFor(opts.Format, opts.Output)
+	if err != nil {
+		return invalid(err)
+	}
+	if len(load.dataFiles()) == 0 {
+		return invalid(fmt.Errorf("-data is required"))
+	}
+	if load.SkipDocuments < 0 {
+		return invalid(fmt.Errorf("skip documents must be 0 or greater"))
+	}
+	input, err := newInputConfig(load)
+	if err != nil {
+		return invalid(err)
+	}
+	transforms, err := buildDocTransforms(load)
+	if err != nil {
+		return invalid(err)
+	}
+	dedup, err := newDeduper(load.DedupField, load.DedupPolicy)
+	if err != nil {
+		return invalid(err)
+	}
+	if err := validateDataFiles(load, input, dedup.needsScan()); err != nil {
+		return invalid(err)
+	}
+
+	if dedup.needsScan() {
+		err := readConvertDocuments(ctx, load, input, transforms, func(doc map[string]interface{}) error {
+			dedup.observe([]map[string]interface{}{doc})
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		dedup.finishScan()
+	}
+	execStep, err := startExecTransform(load.TransformExec, load.TransformExecTimeout)
+	if err != nil {
+		return 0, &RunError{Kind: ErrLoaderExecution, Op: "starting external document filter", Err: err}
+	}
+	defer execStep.close()
+	if execStep != nil {
+		transforms = append(transforms, execStep.transform())
+	}
+	if dedup != nil {
+		transforms = append(transforms, dedup.transform())
+	}
+
+	tmp := opts.Output + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return 0, &RunError{Kind: ErrLoaderExecution, Op: "creating convert output", Err: err}
+	}
+	defer os.Remove(tmp)
+	var written int
+	switch format {
+	case convertNDJSON:
+		written, err = writeConvertNDJSON(ctx, load, input, transforms, file)
+	default:
+		written, err = writeConvertTable(ctx, load, input, transforms, format, file, filepath.Dir(opts.Output))
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = &RunError{Kind: ErrLoaderExecution, Op: "writing convert output", Err: closeErr}
+	}
+	if err != nil {
+		return written, err
+	}
+	if err := os.Rename(tmp, opts.Output); err != nil {
+		return written, &RunError{Kind: ErrLoaderExecution, Op: "writing convert output", Err: err}
+	}
+
+	log.Info().Str("output", opts.Output).Str("format", format).Int("documents", written).Msg("Conversion complete")
+	if dedup != nil && dedup.dropped > 0 {
+		log.Info().Str("dedup_field", dedup.field).Str("dedup_policy", dedup.policy).Int("duplicates", dedup.dropped).Msg("Dropped duplicate documents")
+	}
+	return written, nil
+}
+
+// convertFormatFor returns format, or the one named by path's extension.
+func convertFormatFor(format, path string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			return convertCSV, nil
+		case ".parquet":
+			return convertParquet, nil
+		default:
+			return convertNDJSON, nil
+		}
+	}
+	for _, known := range convertFormats {
+		if format == known {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("-convert-format must be one of %s", strings.Join(convertFormats, ", "))
+}
+
+// readConvertDocuments calls fn with every transformed document of the
+// load's data files, after SkipDocuments.
+func readConvertDocuments(ctx context.Context, load Options, input inputConfig, transforms docTransformChain, fn func(doc map[string]interface{}) error) error {
+	reader, err := input.openDataFiles(load.dataFiles(), load.ReadConcurrency)
+	if err != nil {
+		return &RunError{Kind: ErrLoaderExecution, Op: "opening data file", Err: err}
+	}
+	defer reader.close()
+	for read := 1; ; read++ {
+		if read%1000 == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		doc, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return &RunError{Kind: ErrLoaderExecution, Op: "decoding object in data file", Err: fmt.Errorf("document %d: %w", read, err)}
+		}
+		if read <= load.SkipDocuments {
+			continue
+		}
+		docs, err := transforms.apply(doc)
+		if err != nil {
+			return &RunError{Kind: ErrLoaderExecution, Op: "transforming document", Err: fmt.Errorf("document %d: %w", read, err)}
+		}
+		for _, doc := range docs {
+			if err := fn(doc); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeConvertNDJSON streams the documents to out, one per line.
+func writeConvertNDJSON(ctx context.Context, load Options, input inputConfig, transforms docTransformChain, out io.Writer) (int, error) {
+	writer := bufio.NewWriter(out)
+	encoder := json.NewEncoder(writer)
+	written := 0
+	err := readConvertDocuments(ctx, load, input, transforms, func(doc map[string]interface{}) error {
+		if err := encoder.Encode(doc); err != nil {
+			return &RunError{Kind: ErrLoaderExecution, Op: "writing convert output", Err: err}
+		}
+		written++
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+	if err := writer.Flush(); err != nil {
+		return written, &RunError{Kind: ErrLoaderExecution, Op: "writing convert output", Err: err}
+	}
+	return written, nil
+}
+
+// ─── Tabular Output ────────────────────────────────────────────────────────────
+
+// columnKind is the Parquet type inferred for a column: the narrowest kind
+// that holds every non-null value seen.
+type columnKind int
+
+const (
+	columnNull columnKind = iota
+	columnBoolean
+	columnInteger
+	columnFloat
+	columnString
+)
+
+// widen returns the kind holding both k and the kind of value.
+func (k columnKind) widen(value interface{}) columnKind {
+	var kind columnKind
+	switch v := value.(type) {
+	case nil:
+		return k
+	case bool:
+		kind = columnBoolean
+	case json.Number:
+		kind = columnFloat
+		if _, err := v.Int64(); err == nil {
+			kind = columnInteger
+		}
+	case float64:
+		kind = columnFloat
+	case int, int64:
+		kind = columnInteger
+	default:
+		kind = columnString
+	}
+	switch {
+	case k == columnNull || k == kind:
+		return kind
+	case (k == columnInteger && kind == columnFloat) || (k == columnFloat && kind == columnInteger):
+		return columnFloat
+	default:
+		return columnString
+	}
+}
+
+// writeConvertTable writes CSV or Parquet. Both need every column before
+// the first row, so the flattened documents are spooled to a temporary
+// NDJSON file in dir while the columns are collected, then written out.
+func writeConvertTable(ctx context.Context, load Options, input inputConfig, transforms docTransformChain, format string, out io.Writer, dir string) (int, error) {
+	spool, err := os.CreateTemp(dir, "es-bulk-loader-convert-*.ndjson")
+	if err != nil {
+		return 0, &RunError{Kind: ErrLoaderExecution, Op: "creating convert spool", Err: err}
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	kinds := make(map[string]columnKind)
+	spoolWriter := bufio.NewWriter(spool)
+	encoder := json.NewEncoder(spoolWriter)
+	written := 0
+	err = readConvertDocuments(ctx, load, input, transforms, func(doc map[string]interface{}) error {
+		flattenObjects(doc, ".")
+		for key, value := range doc {
+			kinds[key] = kinds[key].widen(value)
+		}
+		if err := encoder.Encode(doc); err != nil {
+			return &RunError{Kind: ErrLoaderExecution, Op: "writing convert spool", Err: err}
+		}
+		written++
+		return nil
+	})
+	if err == nil {
+		err = spoolWriter.Flush()
+	}
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return written, err
+	}
+
+	columns := make([]string, 0, len(kinds))
+	for column := range kinds {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	decoder := json.NewDecoder(bufio.NewReader(spool))
+	decoder.UseNumber()
+	next := func() (map[string]interface{}, error) {
+		var doc map[string]interface{}
+		err := decoder.Decode(&doc)
+		return doc, err
+	}
+	if format == convertCSV {
+		err = writeCSVRows(columns, next, out)
+	} else {
+		err = writeParquetRows(columns, kinds, next, out)
+	}
+	if err != nil {
+		return written, &RunError{Kind: ErrLoaderExecution, Op: "writing convert output", Err: err}
+	}
+	return written, nil
+}
+
+// tableCell renders a flattened value as CSV or Parquet string text:
+// strings as they are, arrays and empty objects as JSON, and null as
+// empty.
+func tableCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
+
+// writeCSVRows writes a header of columns and one row per document.
+func writeCSVRows(columns []string, next func() (map[string]interface{}, error), out io.Writer) error {
+	writer := csv.NewWriter(out)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for {
+		doc, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for i, column := range columns {
+			record[i] = tableCell(doc[column])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeParquetRows writes one optional column per entry of columns, typed
+// by kinds, and one row per document.
+func writeParquetRows(columns []string, kinds map[string]columnKind, next func() (map[string]interface{}, error), out io.Writer) error {
+	group := make(parquet.Group, len(columns))
+	for _, column := range columns {
+		switch kinds[column] {
+		case columnBoolean:
+			group[column] = parquet.Optional(parquet.Leaf(parquet.BooleanType))
+		case columnInteger:
+			group[column] = parquet.Optional(parquet.Int(64))
+		case columnFloat:
+			group[column] = parquet.Optional(parquet.Leaf(parquet.DoubleType))
+		default:
+			group[column] = parquet.Optional(parquet.String())
+		}
+	}
+	writer := parquet.NewWriter(out, parquet.NewSchema("document", group))
+	// Group columns are ordered by name, as columns is.
+	row := make(parquet.Row, len(columns))
+	for {
+		doc, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for i, column := range columns {
+			value, err := parquetValue(doc[column], kinds[column])
+			if err != nil {
+				return fmt.Errorf("column %q: %w", column, err)
+			}
+			if value.IsNull() {
+				row[i] = value.Level(0, 0, i)
+			} else {
+				row[i] = value.Level(0, 1, i)
+			}
+		}
+		if _, err := writer.WriteRows([]parquet.Row{row}); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// parquetValue converts a flattened value to its column's kind.
+func parquetValue(value interface{}, kind columnKind) (parquet.Value, error) {
+	if value == nil {
+		return parquet.NullValue(), nil
+	}
+	switch kind {
+	case columnBoolean:
+		return parquet.BooleanValue(value.(bool)), nil
+	case columnInteger:
+		n, err := value.(json.Number).Int64()
+		return parquet.Int64Value(n), err
+	case columnFloat:
+		f, err := value.(json.Number).Float64()
+		return parquet.DoubleValue(f), err
+	default:
+		return parquet.ByteArrayValue([]byte(tableCell(value))), nil
+	}
+}
@@ -0,0 +1,90 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// TestConvertWritesEachFormat verifies behavior for the related scenario.
+func TestConvertWritesEachFormat(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "cards.ndjson")
+	data := "{\"code\":\"a\",\"n\":1,\"user\":{\"name\":\"Ada\"},\"tags\":[\"x\"]}\n" +
+		"{\"code\":\"b\",\"n\":2.5,\"ok\":true,\"status\":\"closed\"}\n" +
+		"{\"code\":\"c\",\"n\":3,\"user\":{\"name\":\"Grace, H\"},\"ok\":false}\n"
+	if err := os.WriteFile(dataFile, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	load := Options{DataFile: dataFile, Filter: `status != "closed"`, Rename: []string{"code=id"}}
+
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name:   "ndjson",
+			output: "cards.jsonl",
+			want:   "{\"id\":\"a\",\"n\":1,\"tags\":[\"x\"],\"user\":{\"name\":\"Ada\"}}\n{\"id\":\"c\",\"n\":3,\"ok\":false,\"user\":{\"name\":\"Grace, H\"}}\n",
+		},
+		{
+			name:   "csv",
+			output: "cards.csv",
+			want:   "id,n,ok,tags,user.name\na,1,,\"[\"\"x\"\"]\",Ada\nc,3,false,,\"Grace, H\"\n",
+		},
+	}
+	for _, tt := range tests {
+		output := filepath.Join(dir, tt.output)
+		written, err := Convert(context.Background(), ConvertOptions{Output: output, Load: load})
+		if err != nil || written != 2 {
+			t.Fatalf("%s: Convert returned %d, %v", tt.name, written, err)
+		}
+		got, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatalf("%s: ReadFile returned error: %v", tt.name, err)
+		}
+		if string(got) != tt.want {
+			t.Fatalf("%s: unexpected output %q", tt.name, got)
+		}
+	}
+
+	output := filepath.Join(dir, "cards.parquet")
+	load.Filter = ""
+	if _, err := Convert(context.Background(), ConvertOptions{Output: output, Load: load}); err != nil {
+		t.Fatalf("parquet: Convert returned error: %v", err)
+	}
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer file.Close()
+	reader := parquet.NewReader(file)
+	defer reader.Close()
+	var columns []string
+	for _, field := range reader.Schema().Fields() {
+		columns = append(columns, field.Name()+":"+field.Type().String())
+	}
+	if got := strings.Join(columns, " "); got != "id:STRING n:DOUBLE ok:BOOLEAN status:STRING tags:STRING user.name:STRING" {
+		t.Fatalf("unexpected parquet columns %q", got)
+	}
+	rows := make([]parquet.Row, 4)
+	n, _ := reader.ReadRows(rows)
+	if n != 3 {
+		t.Fatalf("expected 3 parquet rows, got %d", n)
+	}
+	if got := rows[1][1].Double(); got != 2.5 || !rows[0][2].IsNull() || rows[1][2].Boolean() != true {
+		t.Fatalf("unexpected parquet row values %v and %v", rows[0], rows[1])
+	}
+
+	if _, err := Convert(context.Background(), ConvertOptions{Output: output, Format: "xml", Load: load}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions for an unknown format, got %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package loader
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ─── CSV Input ─────────────────────────────────────────────────────────────────
+
+// Column types a -column-map may assign. Typed columns index an empty cell
+// as null; string columns keep it as "".
+const (
+	columnTypeString  = "string"
+	columnTypeInteger = "integer"
+	columnTypeFloat   = "float"
+	columnTypeBoolean = "boolean"
+	columnTypeJSON    = "json"
+)
+
+// validColumnType reports whether columnType is one of the column types.
+func validColumnType(columnType string) bool {
+	switch columnType {
+	case columnTypeString, columnTypeInteger, columnTypeFloat, columnTypeBoolean, columnTypeJSON:
+		return true
+	}
+	return false
+}
+
+// columnMap describes a CSV layout: whether the first row is a header, the
+// delimiter, and which columns become which typed fields.
+type columnMap struct {
+	Header    bool
+	Delimiter string
+	// Columns, when set, are the only columns indexed. Without it every
+	// header name becomes a string field.
+	Columns []columnSpec
+}
+
+// columnSpec maps one CSV column, found by header Name or 1-based Position,
+// to Field with Type.
+type columnSpec struct {
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	Field    string `json:"field"`
+	Type     string `json:"type"`
+}
+
+// columnMapFile is the on-disk -column-map document; header defaults to true.
+type columnMapFile struct {
+	Header    *bool        `json:"header"`
+	Delimiter string       `json:"delimiter"`
+	Columns   []columnSpec `json:"columns"`
+}
+
+// loadColumnMap reads and validates a -column-map file.
+func loadColumnMap(path string) (*columnMap, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading -column-map: %w", err)
+	}
+	var file columnMapFile
+	decoder := json.NewDecoder(strings.NewReader(string(content)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parsing -column-map %s: %w", path, err)
+	}
+	columns := &columnMap{Header: file.Header == nil || *file.Header, Delimiter: file.Delimiter, Columns: file.Columns}
+	if err := columns.validate(); err != nil {
+		return nil, fmt.Errorf("-column-map %s: %w", path, err)
+	}
+	return columns, nil
+}
+
+// validate checks the delimiter and every column, filling in default fields
+// and types.
+func (m *columnMap) validate() error {
+	if utf8.RuneCountInString(m.Delimiter) > 1 {
+		return fmt.Errorf("delimiter must be a single character")
+	}
+	if !m.Header && len(m.Columns) == 0 {
+		return fmt.Errorf("a file without a header row needs columns")
+	}
+	for i := range m.Columns {
+		column := &m.Columns[i]
+		switch {
+		case column.Name != "" && column.Position != 0:
+			return fmt.Errorf("column %d sets both name and position", i+1)
+		case column.Name == "" && column.Position < 1:
+			return fmt.Errorf("column %d needs a name or a position of 1 or more", i+1)
+		case column.Name != "" && !m.Header:
+			return fmt.Errorf("column %q is found by name, which needs a header row", column.Name)
+		}
+		if column.Field == "" {
+			column.Field = column.Name
+		}
+		if column.Field == "" {
+			return fmt.Errorf("column %d needs a field", i+1)
+		}
+		if column.Type == "" {
+			column.Type = columnTypeString
+		}
+		if !validColumnType(column.Type) {
+			return fmt.Errorf("column %q has unknown type %q (use string, integer, float, boolean, or json)", column.Field, column.Type)
+		}
+	}
+	return nil
+}
+
+// csvReader turns CSV rows into documents.
+type csvReader struct {
+	file   io.ReadCloser
+	csv    *csv.Reader
+	fields []string
+	// columns are resolved to 0-based indexes; a nil spec is a plain string.
+	indexes []int
+	specs   []*columnSpec
+}
+
+// newCSVReader reads the header row, if any, and resolves the column map.
+// A nil columns reads a headed, comma-separated file with string fields.
+func newCSVReader(file io.ReadCloser, columns *columnMap) (*csvReader, error) {
+	if columns == nil {
+		columns = &columnMap{Header: true}
+	}
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	// Exports often carry JSON cells such as ["a"] without quoting them.
+	reader.LazyQuotes = true
+	if columns.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(columns.Delimiter)
+	}
+	r := &csvReader{file: file, csv: reader}
+
+	var header []string
+	if columns.Header {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("CSV data file is missing its header row")
+		}
+		if err != nil {
+			return nil, err
+		}
+		header = append([]string(nil), row...)
+		if len(header) > 0 {
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		}
+	}
+	if len(columns.Columns) == 0 {
+		for i, name := range header {
+			r.fields = append(r.fields, strings.TrimSpace(name))
+			r.indexes = append(r.indexes, i)
+			r.specs = append(r.specs, nil)
+		}
+		return r, nil
+	}
+	for i := range columns.Columns {
+		column := &columns.Columns[i]
+		index := column.Position - 1
+		if column.Name != "" {
+			index = -1
+			for j, name := range header {
+				if strings.TrimSpace(name) == column.Name {
+					index = j
+					break
+				}
+			}
+			if index < 0 {
+				return nil, fmt.Errorf("CSV header has no column %q", column.Name)
+			}
+		}
+		r.fields = append(r.fields, column.Field)
+		r.indexes = append(r.indexes, index)
+		r.specs = append(r.specs, column)
+	}
+	return r, nil
+}
+
+// next converts the following row. Cells missing from a short row are left
+// out of the document.
+func (r *csvReader) next() (map[string]interface{}, error) {
+	row, err := r.csv.Read()
+	if err != nil {
+		return nil, err
+	}
+	line, _ := r.csv.FieldPos(0)
+	doc := make(map[string]interface{}, len(r.fields))
+	for i, field := range r.fields {
+		index := r.indexes[i]
+		if index >= len(row) {
+			continue
+		}
+		value, err := convertCSVCell(row[index], r.specs[i])
+		if err != nil {
+			return nil, fmt.Errorf("line %d, field %q: %w", line, field, err)
+		}
+		setField(doc, field, value)
+	}
+	return doc, nil
+}
+
+// convertCSVCell applies the column type to one cell.
+func convertCSVCell(cell string, spec *columnSpec) (interface{}, error) {
+	if spec == nil {
+		return cell, nil
+	}
+	return convertTypedCell(cell, spec.Type)
+}
+
+// convertTypedCell converts cell to one of the column types; it is shared by
+// every delimited and fixed-width reader.
+func convertTypedCell(cell, columnType string) (interface{}, error) {
+	if columnType == columnTypeString {
+		return cell, nil
+	}
+	trimmed := strings.TrimSpace(cell)
+	if trimmed == "" {
+		return nil, nil
+	}
+	switch columnType {
+	case columnTypeInteger:
+		number, err := strconv.ParseInt(trimmed, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", cell)
+		}
+		return json.Number(strconv.FormatInt(number, 10)), nil
+	case columnTypeFloat:
+		number, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", cell)
+		}
+		return number, nil
+	case columnTypeBoolean:
+		switch strings.ToLower(trimmed) {
+		case "true", "t", "yes", "y", "1":
+			return true, nil
+		case "false", "f", "no", "n", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", cell)
+	case columnTypeJSON:
+		var value interface{}
+		if err := decodeJSON([]byte(trimmed), &value); err != nil {
+			return nil, fmt.Errorf("%q is not JSON", cell)
+		}
+		return value, nil
+	}
+	return cell, nil
+}
+
+// close releases the underlying file.
+func (r *csvReader) close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,106 @@
+package loader
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestColumnMapValidation verifies behavior for the related scenario.
+func TestColumnMapValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "unknown key", content: `{"colums":[]}`, want: "unknown field"},
+		{name: "headerless without columns", content: `{"header":false}`, want: "needs columns"},
+		{name: "name without header", content: `{"header":false,"columns":[{"name":"id"}]}`, want: "needs a header row"},
+		{name: "name and position", content: `{"columns":[{"name":"id","position":1}]}`, want: "both name and position"},
+		{name: "missing field", content: `{"columns":[{"position":1}]}`, want: "needs a field"},
+		{name: "bad type", content: `{"columns":[{"name":"id","type":"date"}]}`, want: "unknown type"},
+		{name: "long delimiter", content: `{"delimiter":"||"}`, want: "single character"},
+	}
+	for _, tt := range tests {
+		if _, err := loadColumnMap(writeTestDataFile(t, "map.json", tt.content)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error mentioning %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+// TestCSVColumnMapReader verifies behavior for the related scenario.
+func TestCSVColumnMapReader(t *testing.T) {
+	t.Parallel()
+
+	headerless, err := newInputConfig(Options{ColumnMap: writeTestDataFile(t, "map.json", `{
+		"header": false,
+		"delimiter": "|",
+		"columns": [
+			{"position": 1, "field": "id", "type": "integer"},
+			{"position": 3, "field": "customer.name"},
+			{"position": 4, "field": "amount", "type": "float"},
+			{"position": 5, "field": "active", "type": "boolean"},
+			{"position": 6, "field": "tags", "type": "json"}
+		]
+	}`)})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	got, err := readTestDocuments(t, headerless, writeTestDataFile(t, "export.txt", "7|skip|Ann|12.5|yes|[\"a\"]\n8|skip| Bo ||0\n"))
+	if err != nil {
+		t.Fatalf("reading headerless export returned error: %v", err)
+	}
+	want := []string{
+		`{"active":true,"amount":12.5,"customer":{"name":"Ann"},"id":7,"tags":["a"]}`,
+		`{"active":false,"amount":null,"customer":{"name":" Bo "},"id":8}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("read %q, want %q", got, want)
+	}
+
+	named, err := newInputConfig(Options{ColumnMap: writeTestDataFile(t, "map.json", `{"columns":[{"name":"ID","field":"id"},{"name":"Qty","field":"qty","type":"integer"}]}`)})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	got, err = readTestDocuments(t, named, writeTestDataFile(t, "cards.csv", "Qty,Unused,ID\n3,x,a\nlots,y,b\n"))
+	if err == nil || !strings.Contains(err.Error(), `line 3, field "qty"`) {
+		t.Fatalf("expected a line 3 qty error, got %v", err)
+	}
+	if strings.Join(got, "") != `{"id":"a","qty":3}` {
+		t.Fatalf("read %q before the error", got)
+	}
+	if _, err := readTestDocuments(t, named, writeTestDataFile(t, "cards.csv", "Qty\n3\n")); err == nil || !strings.Contains(err.Error(), `no column "ID"`) {
+		t.Fatalf("expected a missing column error, got %v", err)
+	}
+}
+
+// TestRunCSVDataFile verifies behavior for the related scenario.
+func TestRunCSVDataFile(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	result, err := Run(context.Background(), Options{
+		URL:           server.URL,
+		Index:         "cards",
+		DataFile:      writeTestDataFile(t, "cards.csv", "id,count\n1,2\n2,5\n3,8\n"),
+		AddToIndex:    true,
+		SkipDocuments: 1,
+		ColumnMap:     writeTestDataFile(t, "map.json", `{"columns":[{"name":"id"},{"name":"count","type":"integer"}]}`),
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsProcessed != 2 {
+		t.Fatalf("expected 2 processed documents, got %+v", result)
+	}
+	sent := strings.Join(bodies(), "")
+	if !strings.Contains(sent, `"count":5`) || !strings.Contains(sent, `"count":8`) || strings.Contains(sent, `"id":"1"`) {
+		t.Fatalf("unexpected bulk bodies: %s", sent)
+	}
+
+	if _, err := Run(context.Background(), Options{URL: server.URL, Index: "cards", DataFile: "cards.csv", AddToIndex: true, Tail: true, Format: "csv"}); err == nil {
+		t.Fatal("expected -tail with -format csv to fail")
+	}
+}
@@ -0,0 +1,214 @@
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ─── Terminal Dashboard ────────────────────────────────────────────────────────
+
+const (
+	// dashboardRefreshInterval controls how often the dashboard redraws.
+	dashboardRefreshInterval = 500 * time.Millisecond
+	// dashboardLogLines caps the number of recent log lines kept on screen.
+	dashboardLogLines = 8
+	// dashboardGraphWidth caps the number of throughput samples drawn.
+	dashboardGraphWidth = 60
+)
+
+// sparklineLevels holds the glyphs used to draw the throughput graph.
+var sparklineLevels = []rune("▁▂▃▄▅▆▇█")
+
+// dashboard renders live load statistics to an ANSI terminal.
+type dashboard struct {
+	out   io.Writer
+	stats *loadStats
+	index string
+
+	mu   sync.Mutex
+	logs []string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newDashboard creates a dashboard that renders stats for index to out.
+func newDashboard(out io.Writer, stats *loadStats, index string) *dashboard {
+	return &dashboard{
+		out:   out,
+		stats: stats,
+		index: index,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Write captures formatted log lines so they render inside the dashboard
+// instead of scrolling the terminal.
+func (d *dashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		d.logs = append(d.logs, line)
+	}
+	if len(d.logs) > dashboardLogLines {
+		d.logs = d.logs[len(d.logs)-dashboardLogLines:]
+	}
+	return len(p), nil
+}
+
+// start launches the redraw loop in the background.
+func (d *dashboard) start() {
+	fmt.Fprint(d.out, "\x1b[?25l")
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(dashboardRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case now := <-ticker.C:
+				d.stats.sampleThroughput(now)
+				d.draw(now)
+			}
+		}
+	}()
+}
+
+// finish stops the redraw loop, renders a final frame, and restores the cursor.
+func (d *dashboard) finish() {
+	close(d.stop)
+	<-d.done
+	d.draw(time.Now())
+	fmt.Fprint(d.out, "\x1b[?25h")
+}
+
+// draw renders one full frame.
+func (d *dashboard) draw(now time.Time) {
+	d.mu.Lock()
+	logs := append([]string(nil), d.logs...)
+	d.mu.Unlock()
+
+	frame := renderDashboard(d.index, d.stats.snapshot(), logs, now)
+	fmt.Fprint(d.out, "\x1b[H\x1b[2J"+frame)
+}
+
+// renderDashboard builds the textual dashboard frame from a stats snapshot.
+func renderDashboard(index string, snap loadStatsSnapshot, logs []string, now time.Time) string {
+	var b bytes.Buffer
+	elapsed := now.Sub(snap.Started)
+	if snap.Started.IsZero() || elapsed < 0 {
+		elapsed = 0
+	}
+
+	fmt.Fprintf(&b, "es-bulk-loader  index=%s  elapsed=%s\n\n", index, elapsed.Truncate(time.Second))
+
+	progress := 0.0
+	if snap.Total > 0 {
+		progress = float64(snap.Processed) / float64(snap.Total)
+	}
+	fmt.Fprintf(&b, "Progress   %s %5.1f%%  %d/%d docs\n", progressBar(progress, 30), progress*100, snap.Processed, snap.Total)
+
+	average := 0.0
+	if elapsed > 0 {
+		average = float64(snap.Processed) / elapsed.Seconds()
+	}
+	current := 0.0
+	if len(snap.Throughput) > 0 {
+		current = snap.Throughput[len(snap.Throughput)-1]
+	}
+	fmt.Fprintf(&b, "Throughput %.0f docs/s (avg %.0f docs/s)\n", current, average)
+	fmt.Fprintf(&b, "           %s\n\n", sparkline(snap.Throughput, dashboardGraphWidth))
+
+	fmt.Fprintf(&b, "Succeeded  %d\n", snap.Succeeded)
+	fmt.Fprintf(&b, "Failed     %d\n", snap.Failed)
+	fmt.Fprintf(&b, "Batches    %d  retries=%d  sent=%s\n", snap.Batches, snap.Retries, formatBytes(snap.BytesSent))
+	fmt.Fprintf(&b, "Rejections %d (es_rejected_execution / HTTP 429)\n", snap.Rejections)
+
+	if len(snap.ErrorTypes) > 0 {
+		types := make([]string, 0, len(snap.ErrorTypes))
+		for errorType := range snap.ErrorTypes {
+			types = append(types, errorType)
+		}
+		slices.Sort(types)
+		b.WriteString("\nErrors\n")
+		for _, errorType := range types {
+			fmt.Fprintf(&b, "  %-40s %d\n", errorType, snap.ErrorTypes[errorType])
+		}
+	}
+
+	b.WriteString("\nWorkers\n")
+	if len(snap.Workers) == 0 {
+		b.WriteString("  (waiting for first batch)\n")
+	}
+	for _, worker := range snap.Workers {
+		since := time.Duration(0)
+		if !worker.Since.IsZero() {
+			since = now.Sub(worker.Since).Truncate(100 * time.Millisecond)
+		}
+		fmt.Fprintf(&b, "  #%-3d %-9s batch=%-6d docs=%-6d retries=%-3d for %s\n", worker.ID, worker.State, worker.Batch, worker.Docs, worker.Retries, since)
+	}
+
+	if len(logs) > 0 {
+		b.WriteString("\nRecent log\n")
+		for _, line := range logs {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// progressBar renders a fixed-width progress bar for a 0..1 fraction.
+func progressBar(fraction float64, width int) string {
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * float64(width))
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", width-filled) + "]"
+}
+
+// sparkline renders the newest width samples scaled to the largest sample.
+func sparkline(samples []float64, width int) string {
+	if len(samples) > width {
+		samples = samples[len(samples)-width:]
+	}
+	peak := 0.0
+	for _, sample := range samples {
+		peak = max(peak, sample)
+	}
+	var b strings.Builder
+	for _, sample := range samples {
+		level := 0
+		if peak > 0 {
+			level = int(sample / peak * float64(len(sparklineLevels)-1))
+		}
+		b.WriteRune(sparklineLevels[level])
+	}
+	return b.String()
+}
+
+// formatBytes renders a byte count using binary units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for value := n / unit; value >= unit; value /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,124 @@
+package loader
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadStatsSnapshotAggregatesBatches verifies behavior for the related scenario.
+func TestLoadStatsSnapshotAggregatesBatches(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 3, 19, 13, 0, 0, 0, time.UTC)
+	stats := newLoadStats(start)
+	stats.setTotal(10)
+	stats.setWorkerState(1, "sending", 1, 5)
+	stats.recordBatch(5, 4, 1, 512, map[string]int{"mapper_parsing_exception": 1}, 40*time.Millisecond, 30*time.Millisecond)
+	stats.recordRejection(2)
+	stats.recordRetry(1)
+	stats.sampleThroughput(start.Add(2 * time.Second))
+
+	snap := stats.snapshot()
+	if snap.Processed != 5 || snap.Succeeded != 4 || snap.Failed != 1 {
+		t.Fatalf("unexpected counters: %+v", snap)
+	}
+	if snap.Rejections != 2 || snap.Retries != 1 || snap.BytesSent != 512 {
+		t.Fatalf("unexpected rejection/retry/bytes counters: %+v", snap)
+	}
+	if snap.ErrorTypes["mapper_parsing_exception"] != 1 {
+		t.Fatalf("expected error type breakdown, got %v", snap.ErrorTypes)
+	}
+	if len(snap.Throughput) != 1 || snap.Throughput[0] != 2.5 {
+		t.Fatalf("unexpected throughput samples: %v", snap.Throughput)
+	}
+	if len(snap.Workers) != 1 || snap.Workers[0].Retries != 1 {
+		t.Fatalf("unexpected worker state: %+v", snap.Workers)
+	}
+}
+
+// TestLoadStatsNilReceiverIsNoop verifies behavior for the related scenario.
+func TestLoadStatsNilReceiverIsNoop(t *testing.T) {
+	t.Parallel()
+
+	var stats *loadStats
+	stats.setTotal(1)
+	stats.setWorkerState(1, "sending", 1, 1)
+	stats.recordBatch(1, 1, 0, 1, nil, 0, 0)
+	if snap := stats.snapshot(); snap.Processed != 0 {
+		t.Fatalf("expected empty snapshot, got %+v", snap)
+	}
+}
+
+// TestRenderDashboardIncludesCountersAndLogs verifies behavior for the related scenario.
+func TestRenderDashboardIncludesCountersAndLogs(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 3, 19, 13, 0, 0, 0, time.UTC)
+	frame := renderDashboard("cards", loadStatsSnapshot{
+		Started:    start,
+		Total:      200,
+		Processed:  100,
+		Succeeded:  98,
+		Failed:     2,
+		Rejections: 3,
+		ErrorTypes: map[string]int{"version_conflict_engine_exception": 2},
+		Workers:    []workerStatus{{ID: 1, State: "sending", Batch: 2, Docs: 50, Since: start}},
+		Throughput: []float64{10, 50, 100},
+	}, []string{"13:00:01 WRN retrying"}, start.Add(10*time.Second))
+
+	for _, want := range []string{
+		"index=cards",
+		"50.0%",
+		"100/200 docs",
+		"Failed     2",
+		"Rejections 3",
+		"version_conflict_engine_exception",
+		"#1   sending",
+		"▁▄█",
+		"WRN retrying",
+	} {
+		if !strings.Contains(frame, want) {
+			t.Fatalf("expected dashboard frame to contain %q, got:\n%s", want, frame)
+		}
+	}
+}
+
+// TestDashboardWriteKeepsRecentLines verifies behavior for the related scenario.
+func TestDashboardWriteKeepsRecentLines(t *testing.T) {
+	t.Parallel()
+
+	dash := newDashboard(&strings.Builder{}, nil, "cards")
+	for i := 0; i < dashboardLogLines+3; i++ {
+		_, _ = dash.Write([]byte("line\n"))
+	}
+	if len(dash.logs) != dashboardLogLines {
+		t.Fatalf("expected %d retained lines, got %d", dashboardLogLines, len(dash.logs))
+	}
+}
+
+// TestSummarizeLatenciesNearestRank verifies behavior for the related scenario.
+func TestSummarizeLatenciesNearestRank(t *testing.T) {
+	t.Parallel()
+
+	samples := make([]time.Duration, 0, 100)
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+
+	got := summarizeLatencies(samples)
+	want := LatencySummary{
+		Count: 100,
+		Min:   time.Millisecond,
+		Max:   100 * time.Millisecond,
+		P50:   50 * time.Millisecond,
+		P95:   95 * time.Millisecond,
+		P99:   99 * time.Millisecond,
+	}
+	if got != want {
+		t.Fatalf("summary mismatch: got %+v want %+v", got, want)
+	}
+	if empty := summarizeLatencies(nil); empty != (LatencySummary{}) {
+		t.Fatalf("expected zero summary for no samples, got %+v", empty)
+	}
+}
@@ -0,0 +1,123 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ─── Input Deduplication ───────────────────────────────────────────────────────
+
+const (
+	// dedupKeepFirst indexes the first document seen for each key.
+	dedupKeepFirst = "keep-first"
+	// dedupKeepLast indexes the last document for each key; it needs a
+	// scan of the whole input before loading starts.
+	dedupKeepLast = "keep-last"
+)
+
+// deduper drops repeated documents that share a -dedup-field value. It runs
+// after every other document transform, so the key can be a renamed or
+// defaulted field. Documents without the field are always indexed.
+type deduper struct {
+	field  string
+	policy string
+	// seen holds keys already indexed under keep-first.
+	seen map[string]struct{}
+	// last maps each key to the ordinal of its final occurrence under
+	// keep-last; ordinal counts documents with the field in input order.
+	last    map[string]int
+	ordinal int
+	// dropped counts duplicates removed while loading.
+	dropped int
+}
+
+// newDeduper returns nil when field is empty.
+func newDeduper(field, policy string) (*deduper, error) {
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return nil, nil
+	}
+	if policy == "" {
+		policy = dedupKeepFirst
+	}
+	if policy != dedupKeepFirst && policy != dedupKeepLast {
+		return nil, fmt.Errorf("-dedup-policy must be %s or %s", dedupKeepFirst, dedupKeepLast)
+	}
+	return &deduper{
+		field:  field,
+		policy: policy,
+		seen:   make(map[string]struct{}),
+		last:   make(map[string]int),
+	}, nil
+}
+
+// needsScan reports whether observe must see the whole input first.
+func (d *deduper) needsScan() bool {
+	return d != nil && d.policy == dedupKeepLast
+}
+
+// key returns the JSON encoding of the dedup field so "1" and 1 stay distinct.
+func (d *deduper) key(doc map[string]interface{}) (string, bool) {
+	value, ok := lookupField(doc, d.field)
+	if !ok {
+		return "", false
+	}
+	encoded, err := json.Marshal(canonicalNumber(value))
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
+
+// observe records documents from the keep-last scan, in input order.
+func (d *deduper) observe(docs []map[string]interface{}) {
+	for _, doc := range docs {
+		if key, ok := d.key(doc); ok {
+			d.ordinal++
+			d.last[key] = d.ordinal
+		}
+	}
+}
+
+// seed records the keys of documents a resumed load skips, so keep-first
+// still drops their later copies. Keys are taken before -transform-exec,
+// which skipped documents do not go through.
+func (d *deduper) seed(docs []map[string]interface{}) {
+	for _, doc := range docs {
+		if key, ok := d.key(doc); ok {
+			d.seen[key] = struct{}{}
+		}
+	}
+}
+
+// finishScan resets the ordinal so loading replays the scanned order.
+func (d *deduper) finishScan() {
+	if d != nil {
+		d.ordinal = 0
+	}
+}
+
+// transform adapts the deduper to the document transform chain.
+func (d *deduper) transform() docTransform {
+	return func(doc map[string]interface{}) ([]map[string]interface{}, error) {
+		key, ok := d.key(doc)
+		if !ok {
+			return []map[string]interface{}{doc}, nil
+		}
+		if d.policy == dedupKeepLast {
+			d.ordinal++
+			if d.last[key] != d.ordinal {
+				d.dropped++
+				return nil, nil
+			}
+			return []map[string]interface{}{doc}, nil
+		}
+		if _, dup := d.seen[key]; dup {
+			d.dropped++
+			return nil, nil
+		}
+		d.seen[key] = struct{}{}
+		return []map[string]interface{}{doc}, nil
+	}
+}
@@ -0,0 +1,131 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDeduperPolicies verifies behavior for the related scenario.
+func TestDeduperPolicies(t *testing.T) {
+	t.Parallel()
+
+	input := []string{`{"id":1,"v":"a"}`, `{"id":"1","v":"b"}`, `{"v":"no key"}`, `{"id":1,"v":"c"}`, `{"id":2,"v":"d"}`}
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{policy: dedupKeepFirst, want: []string{"a", "b", "no key", "d"}},
+		{policy: dedupKeepLast, want: []string{"b", "no key", "c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			t.Parallel()
+			dedup, err := newDeduper("id", tt.policy)
+			if err != nil {
+				t.Fatalf("newDeduper returned error: %v", err)
+			}
+			if dedup.needsScan() {
+				for _, raw := range input {
+					dedup.observe([]map[string]interface{}{decodeTestDocument(t, raw)})
+				}
+				dedup.finishScan()
+			}
+			step := dedup.transform()
+			got := make([]string, 0)
+			for _, raw := range input {
+				out, err := step(decodeTestDocument(t, raw))
+				if err != nil {
+					t.Fatalf("transform returned error: %v", err)
+				}
+				for _, doc := range out {
+					got = append(got, doc["v"].(string))
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("kept %v, want %v", got, tt.want)
+			}
+			if dedup.dropped != 1 {
+				t.Fatalf("expected 1 dropped duplicate, got %d", dedup.dropped)
+			}
+		})
+	}
+}
+
+// TestNewDeduperValidatesPolicy verifies behavior for the related scenario.
+func TestNewDeduperValidatesPolicy(t *testing.T) {
+	t.Parallel()
+
+	if dedup, err := newDeduper("", "bogus"); dedup != nil || err != nil {
+		t.Fatalf("expected no deduper without a field, got %v, %v", dedup, err)
+	}
+	if _, err := newDeduper("id", "bogus"); err == nil {
+		t.Fatal("expected an unknown policy to be rejected")
+	}
+	_, err := Run(context.Background(), Options{Index: "logs", AddToIndex: true, Tail: true, DedupField: "id", DedupPolicy: dedupKeepLast})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected keep-last with -tail to be rejected, got %v", err)
+	}
+	_, err = Run(context.Background(), Options{Index: "logs", AddToIndex: true, DataFile: "logs.ndjson", TransformExec: "cat", DedupField: "id", DedupPolicy: dedupKeepLast})
+	if !errors.Is(err, ErrInvalidOptions) || !strings.Contains(err.Error(), "-transform-exec") {
+		t.Fatalf("expected keep-last with -transform-exec to be rejected, got %v", err)
+	}
+}
+
+// TestRunDedupKeepLast verifies behavior for the related scenario.
+func TestRunDedupKeepLast(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	dataFile := filepath.Join(t.TempDir(), "cards.json")
+	content := `[{"sku":"a","v":1},{"sku":"b","v":2},{"sku":"a","v":3}]`
+	if err := os.WriteFile(dataFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	result, err := Run(context.Background(), Options{
+		URL:         server.URL,
+		Index:       "cards",
+		DataFile:    dataFile,
+		AddToIndex:  true,
+		Rename:      []string{"sku=product.sku"},
+		DedupField:  "product.sku",
+		DedupPolicy: dedupKeepLast,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsProcessed != 2 || result.DocumentsSkipped != 1 {
+		t.Fatalf("expected 2 processed and 1 skipped, got %+v", result)
+	}
+	sent := strings.Join(bodies(), "")
+	if strings.Contains(sent, `"v":1`) || !strings.Contains(sent, `"v":2`) || !strings.Contains(sent, `"v":3`) {
+		t.Fatalf("unexpected bulk bodies: %s", sent)
+	}
+}
+
+// TestRunDedupKeepFirstResumeSeedsSkippedKeys verifies behavior for the related scenario.
+func TestRunDedupKeepFirstResumeSeedsSkippedKeys(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	dataFile := writeTestDataFile(t, "cards.ndjson", "{\"sku\":\"a\",\"v\":1}\n{\"sku\":\"b\",\"v\":2}\n{\"sku\":\"a\",\"v\":3}\n{\"sku\":\"c\",\"v\":4}\n")
+	// A resumed load skips the documents an earlier run already indexed.
+	_, err := Run(context.Background(), Options{
+		URL:           server.URL,
+		Index:         "cards",
+		DataFile:      dataFile,
+		AddToIndex:    true,
+		SkipDocuments: 2,
+		DedupField:    "sku",
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	sent := strings.Join(bodies(), "")
+	if strings.Contains(sent, `"v":3`) || !strings.Contains(sent, `"v":4`) {
+		t.Fatalf("expected the copy of a skipped key dropped, got bulk bodies: %s", sent)
+	}
+}
@@ -0,0 +1,427 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Diff Mode ─────────────────────────────────────────────────────────────────
+
+// diffScrollKeepAlive is how long the scroll over the index's _ids stays
+// open between pages.
+const diffScrollKeepAlive = time.Minute
+
+// DiffOptions configures Diff.
+type DiffOptions struct {
+	// Output receives one tab-separated line per difference:
+	// "missing <id>", "extra <id>", or "differs <id> <fields>". Defaults to
+	// os.Stdout.
+	Output io.Writer
+	// Load names the cluster, index, data files, and transforms to compare,
+	// as for Run; documents are matched by IDField or elasticdump _id.
+	Load Options
+}
+
+// DiffReport counts what Diff found.
+type DiffReport struct {
+	// Compared is how many input documents had an _id to look up.
+	Compared int
+	Matching int
+	// Missing input documents are not in the index.
+	Missing int
+	// Extra documents are in the index but not in the input.
+	Extra int
+	// Differing documents are in both but their sources are not equal.
+	Differing int
+	// Unkeyed input documents have no _id, so cannot be compared.
+	Unkeyed int
+}
+
+// Diff compares the documents of a load, after its transforms, with what
+// the index holds, by _id, and writes each missing, extra, and differing
+// document to Output. It only reads from the cluster, and returns
+// ErrDifferences when any document differs.
+func Diff(ctx context.Context, opts DiffOptions) (DiffReport, error) {
+	var report DiffReport
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	load := opts.Load
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	if load.URL == "" {
+		load.URL = "http://localhost:9200"
+	}
+	if load.BatchSize <= 0 {
+		load.BatchSize = 1000
+	}
+	invalid := func(err error) (DiffReport, error) {
+		return report, &RunError{Kind: ErrInvalidOptions, Op: "validating diff options", Err: err}
+	}
+	if strings.TrimSpace(load.Index) == "" {
+		return invalid(fmt.Errorf("-index is required"))
+	}
+	if len(load.dataFiles()) == 0 {
+		return invalid(fmt.Errorf("-data is required"))
+	}
+	if len(load.Clusters) > 1 {
+		return invalid(fmt.Errorf("diff compares one cluster; pass a single -url"))
+	}
+	if load.PreserveIndex {
+		return invalid(fmt.Errorf("diff compares one index and cannot be combined with -preserve-index"))
+	}
+	if load.PartitionBy != "" {
+		return invalid(fmt.Errorf("diff compares one index and cannot be combined with -partition-by"))
+	}
+	input, err := newInputConfig(load)
+	if err != nil {
+		return invalid(err)
+	}
+	metadata := input.formatFor(load.DataFile) == formatElasticdump
+	if load.IDField == "" && !metadata {
+		return invalid(fmt.Errorf("diff matches documents by _id and needs -id or -format %s", formatElasticdump))
+	}
+	transforms, err := buildDocTransforms(load)
+	if err != nil {
+		return invalid(err)
+	}
+	if err := validateDataFiles(load, input, false); err != nil {
+		return invalid(err)
+	}
+
+	var info ClusterInfo
+	if load.Preflight {
+		if info, err = preflightCluster(ctx, load); err != nil {
+			return report, err
+		}
+	}
+	cfg := elasticsearchConfig(load)
+	if major := legacyMajor(info, load.DocType); major > 0 {
+		cfg.Transport = &compatTransport{base: cfg.Transport, major: major}
+	}
+	es, err := elasticsearch.NewClient(cfg)
+	if err != nil {
+		return report, &RunError{Kind: ErrLoaderExecution, Op: "creating Elasticsearch client", Err: err}
+	}
+	exists, err := indexExists(es, load.Index)
+	if err != nil {
+		return report, &RunError{Kind: ErrIndexOperation, Op: "checking if index exists", Err: err}
+	}
+	if !exists {
+		return report, &RunError{Kind: ErrIndexOperation, Op: "checking if index exists", Err: fmt.Errorf("index %q does not exist", load.Index)}
+	}
+
+	execStep, err := startExecTransform(load.TransformExec, load.TransformExecTimeout)
+	if err != nil {
+		return report, &RunError{Kind: ErrLoaderExecution, Op: "starting external document filter", Err: err}
+	}
+	defer execStep.close()
+	if execStep != nil {
+		transforms = append(transforms, execStep.transform())
+	}
+
+	reader, err := input.openDataFiles(load.dataFiles(), load.ReadConcurrency)
+	if err != nil {
+		return report, &RunError{Kind: ErrLoaderExecution, Op: "opening data file", Err: err}
+	}
+	defer reader.close()
+	settings := bulkSettings{IDField: load.IDField, RoutingField: load.RoutingField, Metadata: metadata}
+	seen := make(map[string]struct{})
+	chunk := make([]diffEntry, 0, load.BatchSize)
+	compare := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		err := compareDiffChunk(ctx, es, load.Index, chunk, out, &report)
+		chunk = chunk[:0]
+		return err
+	}
+	for read := 1; ; read++ {
+		doc, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, &RunError{Kind: ErrLoaderExecution, Op: "decoding object in data file", Err: fmt.Errorf("document %d: %w", read, err)}
+		}
+		docs, err := transforms.apply(doc)
+		if err != nil {
+			return report, &RunError{Kind: ErrLoaderExecution, Op: "transforming document", Err: fmt.Errorf("document %d: %w", read, err)}
+		}
+		for _, sent := range docs {
+			meta, source := documentMeta(sent, load.Index, settings)
+			if meta.ID == "" {
+				report.Unkeyed++
+				continue
+			}
+			// The load's later copy of a repeated _id is the one indexed.
+			if _, dup := seen[meta.ID]; dup {
+				for i := range chunk {
+					if chunk[i].meta.ID == meta.ID {
+						chunk[i] = diffEntry{meta: meta, source: source}
+					}
+				}
+				continue
+			}
+			seen[meta.ID] = struct{}{}
+			chunk = append(chunk, diffEntry{meta: meta, source: source})
+			if len(chunk) >= load.BatchSize {
+				if err := compare(); err != nil {
+					return report, err
+				}
+			}
+		}
+	}
+	if err := compare(); err != nil {
+		return report, err
+	}
+	if err := findExtraDocuments(ctx, es, load.Index, seen, out, &report); err != nil {
+		return report, err
+	}
+
+	log.Info().
+		Str("index", load.Index).
+		Int("compared", report.Compared).
+		Int("matching", report.Matching).
+		Int("missing", report.Missing).
+		Int("extra", report.Extra).
+		Int("differing", report.Differing).
+		Int("unkeyed", report.Unkeyed).
+		Msg("Diff complete")
+	if report.Missing+report.Extra+report.Differing > 0 {
+		return report, &RunError{Kind: ErrDifferences, Op: "comparing documents", Err: fmt.Errorf("%d missing, %d extra, %d differing", report.Missing, report.Extra, report.Differing)}
+	}
+	return report, nil
+}
+
+// diffEntry is one input document waiting to be looked up.
+type diffEntry struct {
+	meta   bulkActionMeta
+	source map[string]interface{}
+}
+
+// mgetResponse is the body of a _mget answer.
+type mgetResponse struct {
+	Docs []struct {
+		ID          string          `json:"_id"`
+		Found       bool            `json:"found"`
+		SeqNo       *int64          `json:"_seq_no"`
+		PrimaryTerm *int64          `json:"_primary_term"`
+		Source      json.RawMessage `json:"_source"`
+		Error       json.RawMessage `json:"error"`
+	} `json:"docs"`
+}
+
+// mgetBatch looks up the documents of batch that have an _id with one
+// _mget, passing params through to the request. It returns the answers in
+// request order together with each one's position in batch; an answer that
+// carries an error fails the whole lookup.
+func mgetBatch(ctx context.Context, es *elasticsearch.Client, index string, batch []map[string]interface{}, settings bulkSettings, params ...func(*esapi.MgetRequest)) (mgetResponse, []int, error) {
+	type mgetDoc struct {
+		ID      string `json:"_id"`
+		Index   string `json:"_index"`
+		Routing string `json:"routing,omitempty"`
+	}
+	request := struct {
+		Docs []mgetDoc `json:"docs"`
+	}{}
+	positions := make([]int, 0, len(batch))
+	for i, doc := range batch {
+		meta, _ := documentMeta(doc, index, settings)
+		if meta.ID != "" {
+			request.Docs = append(request.Docs, mgetDoc{ID: meta.ID, Index: meta.Index, Routing: meta.Routing})
+			positions = append(positions, i)
+		}
+	}
+	var fetched mgetResponse
+	if len(request.Docs) == 0 {
+		return fetched, nil, nil
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fetched, nil, err
+	}
+	res, err := es.Mget(bytes.NewReader(body), append([]func(*esapi.MgetRequest){es.Mget.WithContext(ctx)}, params...)...)
+	if err != nil {
+		return fetched, nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		responseBody, _ := io.ReadAll(res.Body)
+		return fetched, nil, fmt.Errorf("_mget answered with status %d: %s", res.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	if err := json.NewDecoder(res.Body).Decode(&fetched); err != nil {
+		return fetched, nil, fmt.Errorf("parsing _mget response: %w", err)
+	}
+	if len(fetched.Docs) != len(positions) {
+		return fetched, nil, fmt.Errorf("_mget returned %d documents for %d ids", len(fetched.Docs), len(positions))
+	}
+	for _, doc := range fetched.Docs {
+		if len(doc.Error) > 0 {
+			return fetched, nil, fmt.Errorf("document %q: %s", doc.ID, doc.Error)
+		}
+	}
+	return fetched, positions, nil
+}
+
+// compareDiffChunk fetches chunk's documents with one _mget and reports
+// those that are missing or whose stored source differs.
+func compareDiffChunk(ctx context.Context, es *elasticsearch.Client, index string, chunk []diffEntry, out io.Writer, report *DiffReport) error {
+	type mgetDoc struct {
+		ID      string `json:"_id"`
+		Routing string `json:"routing,omitempty"`
+	}
+	request := struct {
+		Docs []mgetDoc `json:"docs"`
+	}{Docs: make([]mgetDoc, 0, len(chunk))}
+	for _, entry := range chunk {
+		request.Docs = append(request.Docs, mgetDoc{ID: entry.meta.ID, Routing: entry.meta.Routing})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	res, err := es.Mget(bytes.NewReader(body), es.Mget.WithContext(ctx), es.Mget.WithIndex(index))
+	if err != nil {
+		return &RunError{Kind: ErrConnection, Op: "fetching documents", Err: err}
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		responseBody, _ := io.ReadAll(res.Body)
+		return &RunError{Kind: ErrIndexOperation, Op: "fetching documents", Err: fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(responseBody)))}
+	}
+	var fetched mgetResponse
+	if err := json.NewDecoder(res.Body).Decode(&fetched); err != nil {
+		return &RunError{Kind: ErrIndexOperation, Op: "fetching documents", Err: fmt.Errorf("parsing _mget response: %w", err)}
+	}
+	if len(fetched.Docs) != len(chunk) {
+		return &RunError{Kind: ErrIndexOperation, Op: "fetching documents", Err: fmt.Errorf("_mget returned %d documents for %d ids", len(fetched.Docs), len(chunk))}
+	}
+
+	for i, entry := range chunk {
+		report.Compared++
+		doc := fetched.Docs[i]
+		if len(doc.Error) > 0 {
+			return &RunError{Kind: ErrIndexOperation, Op: "fetching documents", Err: fmt.Errorf("document %q: %s", entry.meta.ID, doc.Error)}
+		}
+		if !doc.Found {
+			report.Missing++
+			fmt.Fprintf(out, "missing\t%s\n", entry.meta.ID)
+			continue
+		}
+		var stored map[string]interface{}
+		if err := decodeJSON(doc.Source, &stored); err != nil {
+			return &RunError{Kind: ErrIndexOperation, Op: "fetching documents", Err: fmt.Errorf("parsing _source of %q: %w", entry.meta.ID, err)}
+		}
+		if fields := differingFields(entry.source, stored); len(fields) > 0 {
+			report.Differing++
+			fmt.Fprintf(out, "differs\t%s\t%s\n", entry.meta.ID, strings.Join(fields, ","))
+			continue
+		}
+		report.Matching++
+	}
+	return nil
+}
+
+// differingFields returns the sorted top-level fields whose values differ
+// between want and got, compared as encoded JSON so key order and
+// json.Number spelling from the same source do not matter.
+func differingFields(want, got map[string]interface{}) []string {
+	var fields []string
+	for key, value := range want {
+		other, ok := got[key]
+		if !ok || !sameJSON(value, other) {
+			fields = append(fields, key)
+		}
+	}
+	for key := range got {
+		if _, ok := want[key]; !ok {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// sameJSON reports whether a and b encode to the same JSON.
+func sameJSON(a, b interface{}) bool {
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	return err == nil && bytes.Equal(left, right)
+}
+
+// scrollResponse is the part of a search or scroll answer Diff reads.
+type scrollResponse struct {
+	ScrollID string `json:"_scroll_id"`
+	Hits     struct {
+		Hits []struct {
+			ID string `json:"_id"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// findExtraDocuments scrolls through every _id in index and reports those
+// not in seen.
+func findExtraDocuments(ctx context.Context, es *elasticsearch.Client, index string, seen map[string]struct{}, out io.Writer, report *DiffReport) error {
+	fail := func(err error) error {
+		return &RunError{Kind: ErrIndexOperation, Op: "listing index documents", Err: err}
+	}
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(index),
+		es.Search.WithBody(strings.NewReader(`{"_source":false,"sort":["_doc"],"size":1000}`)),
+		es.Search.WithScroll(diffScrollKeepAlive),
+	)
+	scrollID := ""
+	defer func() {
+		if scrollID != "" {
+			if res, err := es.ClearScroll(es.ClearScroll.WithScrollID(scrollID)); err == nil {
+				res.Body.Close()
+			}
+		}
+	}()
+	for {
+		if err != nil {
+			return fail(err)
+		}
+		var page scrollResponse
+		if res.IsError() {
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			return fail(fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body))))
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			return fail(fmt.Errorf("parsing scroll response: %w", err))
+		}
+		scrollID = page.ScrollID
+		if len(page.Hits.Hits) == 0 {
+			return nil
+		}
+		for _, hit := range page.Hits.Hits {
+			if _, ok := seen[hit.ID]; !ok {
+				report.Extra++
+				fmt.Fprintf(out, "extra\t%s\n", hit.ID)
+			}
+		}
+		res, err = es.Scroll(es.Scroll.WithContext(ctx), es.Scroll.WithScrollID(scrollID), es.Scroll.WithScroll(diffScrollKeepAlive))
+	}
+}
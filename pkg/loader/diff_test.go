@@ -0,0 +1,91 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestDiffReportsMissingExtraAndDiffering verifies behavior for the related scenario.
+func TestDiffReportsMissingExtraAndDiffering(t *testing.T) {
+	t.Parallel()
+
+	stored := map[string]string{
+		"a": `{"code":"a","price":1.50,"tags":["x","y"]}`,
+		"b": `{"code":"b","price":2}`,
+		"z": `{"code":"z"}`,
+	}
+	var writes, scrolls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/cards/_mget":
+			var request struct {
+				Docs []struct {
+					ID string `json:"_id"`
+				} `json:"docs"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			docs := make([]string, 0, len(request.Docs))
+			for _, doc := range request.Docs {
+				if source, ok := stored[doc.ID]; ok {
+					docs = append(docs, `{"_id":"`+doc.ID+`","found":true,"_source":`+source+`}`)
+				} else {
+					docs = append(docs, `{"_id":"`+doc.ID+`","found":false}`)
+				}
+			}
+			_, _ = w.Write([]byte(`{"docs":[` + strings.Join(docs, ",") + `]}`))
+		case r.URL.Path == "/cards/_search" && r.URL.Query().Get("scroll") != "":
+			_, _ = w.Write([]byte(`{"_scroll_id":"s1","hits":{"hits":[{"_id":"a"},{"_id":"b"},{"_id":"z"}]}}`))
+		case strings.HasPrefix(r.URL.Path, "/_search/scroll") && r.Method == http.MethodDelete:
+			scrolls.Add(1)
+			_, _ = w.Write([]byte(`{"succeeded":true}`))
+		case r.URL.Path == "/_search/scroll":
+			_, _ = w.Write([]byte(`{"_scroll_id":"s1","hits":{"hits":[]}}`))
+		default:
+			writes.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dataFile := filepath.Join(t.TempDir(), "cards.ndjson")
+	data := "{\"code\":\"a\",\"tags\":[\"x\",\"y\"],\"price\":1.50}\n{\"code\":\"b\",\"price\":3}\n{\"code\":\"c\"}\n{\"price\":4}\n"
+	if err := os.WriteFile(dataFile, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	var out bytes.Buffer
+	report, err := Diff(context.Background(), DiffOptions{
+		Output: &out,
+		Load:   Options{URL: server.URL, Index: "cards", DataFile: dataFile, IDField: "code", BatchSize: 2},
+	})
+	if !errors.Is(err, ErrDifferences) {
+		t.Fatalf("expected ErrDifferences, got %v", err)
+	}
+	want := DiffReport{Compared: 3, Matching: 1, Missing: 1, Extra: 1, Differing: 1, Unkeyed: 1}
+	if report != want {
+		t.Fatalf("expected report %+v, got %+v", want, report)
+	}
+	if got := out.String(); got != "differs\tb\tprice\nmissing\tc\nextra\tz\n" {
+		t.Fatalf("unexpected diff output %q", got)
+	}
+	if writes.Load() != 0 || scrolls.Load() != 1 {
+		t.Fatalf("expected only reads and one cleared scroll, got %d other requests and %d clears", writes.Load(), scrolls.Load())
+	}
+
+	_, err = Diff(context.Background(), DiffOptions{Load: Options{URL: server.URL, Index: "cards", DataFile: dataFile}})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions without -id, got %v", err)
+	}
+}
@@ -8,8 +8,177 @@
 //
 // File layout:
 //   - loader.go: runtime orchestration, API calls, option parsing helpers.
+//   - stats.go: live bulk load counters shared by senders and observers.
+//   - memory.go: -max-memory heap guard that pauses reading near the limit.
+//   - durability.go: -load-durability translog switch and restore around the bulk phase.
+//   - backpressure.go: adaptive bulk concurrency and pacing under write rejections.
+//   - shard.go: -shard-group routing hash and per-shard batch ordering.
+//   - dashboard.go: ANSI terminal dashboard rendered for -tui.
+//   - benchmark.go: -benchmark report and throwaway benchmark indices.
+//   - preflight.go: connection pre-flight check and cluster info logging.
+//   - validate.go: read-only `validate` checks of options, clusters, files, and input.
+//   - diff.go: `diff` comparison of input documents with the index by _id.
+//   - compat.go: Elasticsearch 7.x/8.x compatibility headers and -doc-type mappings.
+//   - serverless.go: serverless project detection, managed settings, and data stream targets.
+//   - kerberos.go: Kerberos credentials and the SPNEGO request transport.
+//   - oidc.go: OIDC device-code sign-in, token refresh, and the bearer transport.
+//   - transport.go: HTTP transport wrappers and dialers for the Elasticsearch client.
+//   - telemetry.go: OpenTelemetry spans and metrics exported over OTLP.
+//   - statsd.go: fire-and-forget StatsD/DogStatsD metrics over UDP.
+//   - notify.go: completion webhook summary and payload templating.
+//   - watch.go: drop-directory watch loop with per-file offset journal.
+//   - tail.go: NDJSON file follower with rotation handling for -tail.
+//   - schedule.go: cron expression parsing and the -schedule run loop.
+//   - server.go: HTTP ingestion gateway, upload spooling, and job queue for `serve`.
+//   - jobs.go: job records and the Elasticsearch-backed job history store.
+//   - grpc.go: client-streaming gRPC ingestion service for `serve -grpc-listen`.
+//   - clusters.go: sequential fan-out of one load to several -url clusters.
+//   - input.go: -format selection and the JSON array and NDJSON data file readers.
+//   - multifile.go: parallel reading of several -data files into one load.
+//   - csv.go: CSV data file reader and -column-map layouts.
+//   - fixed.go: fixed-width data file reader and -layout field positions.
+//   - binary.go: MessagePack and CBOR stream readers.
+//   - protobuf.go: length-delimited protobuf reader driven by a descriptor set.
+//   - geojson.go: GeoJSON Feature reader and automatic geo_shape mapping.
+//   - archive.go: zip and tar(.gz) data files read member by member.
+//   - elasticdump.go: elasticdump/ESM hit reader and bulk metadata handling.
+//   - number.go: json.Number decoding and numeric helpers shared by readers and transforms.
+//   - doctransform.go: per-document transform chain, field paths, and field rewrites.
+//   - geoip.go: -geoip enrichment from a local MaxMind database.
+//   - lookup.go: -lookup dictionary joins from local CSV or JSON files.
+//   - ecs.go: -ecs-preset field mappings onto Elastic Common Schema names.
+//   - filter.go: -filter expression lexer, parser, and evaluator.
+//   - doctemplate.go: -doc-template rendering of final document JSON.
+//   - script.go: -script-transform Starlark hook and value conversion.
+//   - transformexec.go: -transform-exec line protocol with an external command.
+//   - dedup.go: -dedup-field keep-first/keep-last duplicate removal.
+//   - incremental.go: -sync content hashes and unchanged document lookups.
+//   - generate.go: `generate` synthetic documents built from a field spec.
+//   - convert.go: `convert` output of transformed documents as NDJSON, CSV, or Parquet.
+//   - split.go: `split` of a data file into count- or size-bounded chunk files.
+//   - preview.go: -preview output of the first documents as bulk lines.
+//   - estimate.go: -estimate document count, size, batch, and index size report.
+//   - indexstats.go: per-target-index succeeded, failed, and byte totals.
+//   - errorlog.go: -error-log NDJSON record of failed bulk requests and items.
+//   - retryqueue.go: -retry-queue of exhausted transient failures and the `retry` replay.
+//   - journal.go: -journal write-ahead record of sent and confirmed batches for crash resumes.
+//   - idcollision.go: duplicate -id _id tracking and end-of-load collision report.
+//   - mappingcheck.go: -mapping-check comparison of sampled fields with the index mapping.
+//   - analyze.go: -analyze-check sample values run through the _analyze API.
+//   - concurrency.go: -concurrency-control if_seq_no/if_primary_term conditions.
+//   - aliases.go: -aliases filtered aliases applied after the load.
+//   - resize.go: -shrink-to and -split-to resizing of new alias generations.
+//   - finalize.go: -finalize index blocks added after a successful load.
+//   - sourcefilter.go: -source-includes and -source-excludes _source mapping.
+//   - dyntemplates.go: -dynamic-templates bulk action parameter.
+//   - indextemplates.go: -use-templates index template lookup before creation.
+//   - dumpschema.go: `dump-schema` export of cleaned index settings and mappings.
+//   - snapshot.go: `snapshot` and `restore` commands over the snapshot APIs.
+//   - partition.go: -partition-by index names and on-demand partition index creation.
+//   - indexname.go: index name validation and -sanitize-index-names rewriting.
+//   - capacity.go: -capacity-check disk, watermark, and shard limit guardrail.
+//   - tasks.go: X-Opaque-Id tagging and the `cancel` command over the tasks API.
+//   - inputshard.go: -shard i/N input slicing by record number or -id hash.
+//   - journalstore.go: ConfigMap and HTTP object stores for a remote -journal.
+//   - systemd.go: sd_notify readiness, watchdog pings, and LISTEN_FDS socket activation.
+//   - consume.go: -source message queue consumer that acknowledges messages once indexed.
+//   - sqs.go: Amazon SQS source on the AWS SDK.
+//   - pubsub.go: Google Pub/Sub source on the Cloud client library.
+//   - nats.go: NATS JetStream pull consumer source on the nats.go client.
+//   - amqp.go: RabbitMQ/AMQP 0-9-1 queue consumer source on amqp091-go, with prefetch and manual acks.
+//   - redis.go: Redis stream consumer group and reliable list source on go-redis.
+//   - mongodb.go: MongoDB collection scan and change stream source on the MongoDB Go driver.
+//   - accesslog.go: Apache and nginx -log-format parsing and the line reader shared with -format regex.
+//   - grok.go: grok pattern library, %{NAME:field} expansion, and -format regex line parsing.
+//   - cef.go: ArcSight CEF and QRadar LEEF line parsing for -format cef and leef.
+//   - evtx.go: Windows .evtx chunk, record, and Binary XML template decoding for -format evtx.
+//   - pcap.go: classic pcap and pcapng packet decoding, and flow aggregation, for -format pcap.
 //   - main_test.go: unit coverage for flags, mapping logic, and execution ordering.
 //   - pipeline_defaults_test.go: settings/pipeline normalization behavior tests.
+//   - memory_test.go: heap guard throttling and guarded load tests.
+//   - durability_test.go: translog durability switch and restore tests.
+//   - backpressure_test.go: throttle steps, rejected item resends, and concurrent sends.
+//   - shard_test.go: routing hash and shard grouping tests.
+//   - dashboard_test.go: dashboard rendering and live statistics tests.
+//   - benchmark_test.go: benchmark report layout and throwaway index cleanup tests.
+//   - preflight_test.go: pre-flight connectivity, credential, and cluster info tests.
+//   - validate_test.go: validation problem collection and no-write guarantee tests.
+//   - diff_test.go: missing, extra, and differing document report tests.
+//   - compat_test.go: compatibility media types, -doc-type validation, and typed 7.x loads.
+//   - serverless_test.go: serverless option checks, settings stripping, and data stream loads.
+//   - kerberos_test.go: Kerberos option validation and credential failure tests.
+//   - oidc_test.go: device-code polling, token refresh, and bearer request tests.
+//   - transport_test.go: connection pool tuning, dial target, and Unix socket tests.
+//   - telemetry_test.go: span and metric recording tests.
+//   - statsd_test.go: StatsD packet formatting tests.
+//   - notify_test.go: completion webhook payload and delivery tests.
+//   - watch_test.go: watch directory scanning, moving, and resume tests.
+//   - tail_test.go: tail append, partial line, and rotation tests.
+//   - schedule_test.go: cron parsing and next-run calculation tests.
+//   - server_test.go: upload, job status, and request validation tests.
+//   - jobs_test.go: job history persistence and restart recovery tests.
+//   - grpc_test.go: gRPC stream batching, summary, and authorization tests.
+//   - clusters_test.go: multi-cluster fan-out and partial failure tests.
+//   - input_test.go: format detection and JSON array/NDJSON reader tests.
+//   - multifile_test.go: parallel multi-file reading and validation tests.
+//   - csv_test.go: column map validation, typed cells, and CSV load tests.
+//   - fixed_test.go: layout validation and fixed-width slicing tests.
+//   - binary_test.go: MessagePack and CBOR stream decoding tests.
+//   - protobuf_test.go: descriptor loading and delimited message decoding tests.
+//   - geojson_test.go: Feature promotion and geo_shape mapping tests.
+//   - archive_test.go: archive member matching and streaming tests.
+//   - elasticdump_test.go: hit decoding and _id/_index preservation tests.
+//   - number_test.go: number precision through decoding, transforms, and keys.
+//   - doctransform_test.go: transform chain and field rewrite tests.
+//   - geoip_test.go: MMDB lookup and geoip field mapping tests.
+//   - lookup_test.go: lookup file parsing and key matching tests.
+//   - ecs_test.go: ECS preset mapping and value normalization tests.
+//   - filter_test.go: filter expression parsing, evaluation, and skip counting tests.
+//   - doctemplate_test.go: document template rendering, splitting, and dropping tests.
+//   - script_test.go: Starlark transform contract and conversion tests.
+//   - transformexec_test.go: external command answer, timeout, and exit tests.
+//   - dedup_test.go: duplicate key policy tests.
+//   - incremental_test.go: -sync new, changed, and unchanged document tests.
+//   - generate_test.go: field spec generation, seeding, and spec error tests.
+//   - convert_test.go: NDJSON, CSV header, and Parquet column output tests.
+//   - split_test.go: chunk limits, CSV header, and JSON array chunk tests.
+//   - preview_test.go: preview bulk line output and no-write tests.
+//   - estimate_test.go: estimate sizes, replica lookup, and no-write tests.
+//   - indexstats_test.go: single and per-document target index total tests.
+//   - errorlog_test.go: failed item and failed request error log entry tests.
+//   - retryqueue_test.go: queued request and item failures and repeated replay tests.
+//   - journal_test.go: crashed, finished, and mismatched journal resume tests.
+//   - idcollision_test.go: repeated _id count and sample report tests.
+//   - mappingcheck_test.go: dropped, rejected, and dynamic type finding tests.
+//   - analyze_test.go: analyze check parsing, value sampling, and token output tests.
+//   - concurrency_test.go: captured and fetched condition, create, and conflict tests.
+//   - aliases_test.go: filtered alias validation, actions, and generation roll tests.
+//   - resize_test.go: resize validation, shard count, and shrink orchestration tests.
+//   - finalize_test.go: finalize validation, block, and failed load tests.
+//   - sourcefilter_test.go: _source mapping merge and existing index tests.
+//   - dyntemplates_test.go: dynamic template file, version, and action tests.
+//   - indextemplates_test.go: template matching, empty create body, and data stream tests.
+//   - dumpschema_test.go: volatile setting stripping and written schema file tests.
+//   - snapshot_test.go: repository verification, request body, and shard failure tests.
+//   - partition_test.go: partition naming, validation, creation, and cardinality tests.
+//   - indexname_test.go: naming rule, sanitization, and _index check tests.
+//   - capacity_test.go: watermark parsing, capacity problem, and validation tests.
+//   - tasks_test.go: opaque id header, task matching, and cancellation tests.
+//   - inputshard_test.go: shard parsing, slicing, and per-shard journal tests.
+//   - journalstore_test.go: journal compaction, HTTP and ConfigMap store, and remote resume tests.
+//   - systemd_test.go: notify socket, watchdog interval, and passed socket naming tests.
+//   - consume_test.go: batching, acknowledgement, receive retry, and -source validation tests.
+//   - sqs_test.go: SQS settlement and URL validation tests.
+//   - pubsub_test.go: Pub/Sub pull and settlement against pstest, and URL validation tests.
+//   - nats_test.go: JetStream pull, settlement, consumer checks, and token sign-in tests against an embedded server.
+//   - amqp_test.go: AMQP settlement, reconnect, broker error, and URL tests over a fake channel.
+//   - redis_test.go: Redis stream group, pending re-read, list processing, and AUTH tests against miniredis.
+//   - mongodb_test.go: MongoDB scan resume, change stream resume and recovery, and BSON conversion tests.
+//   - accesslog_test.go: combined, common, and custom access log format parsing tests.
+//   - grok_test.go: grok expansion, pattern directory, type conversion, and regex format tests.
+//   - cef_test.go: CEF header and extension, LEEF 1.0 and 2.0, and timestamp parsing tests.
+//   - evtx_test.go: EVTX template reuse, substitution types, embedded Binary XML, and corrupt record tests.
+//   - pcap_test.go: pcap packet and flow metadata, pcapng timestamp resolution, and -pcap-mode validation tests.
 //   - doc.go: package contract and lifecycle semantics.
 //
 // Non-obvious decisions:
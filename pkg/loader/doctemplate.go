@@ -0,0 +1,96 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// ─── Document Templates ────────────────────────────────────────────────────────
+
+// docTemplateFuncs are available to -doc-template files in addition to the
+// text/template builtins.
+var docTemplateFuncs = template.FuncMap{
+	// json encodes any value, so strings are quoted and escaped and missing
+	// fields render as null.
+	"json": func(value interface{}) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+	// field looks up a dot-notation path, for keys text/template cannot
+	// address directly such as "First Name".
+	"field": func(path string, doc map[string]interface{}) interface{} {
+		value, _ := lookupField(doc, path)
+		return value
+	},
+	// default returns fallback when value is missing, null, or "".
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"split": strings.Split,
+	"join": func(sep string, values []interface{}) string {
+		parts := make([]string, len(values))
+		for i, value := range values {
+			parts[i] = fmt.Sprint(value)
+		}
+		return strings.Join(parts, sep)
+	},
+}
+
+// newDocTemplateTransform parses the -doc-template file at path. Each source
+// document is the template's dot; the rendered text is decoded as the
+// document or documents to index in its place.
+func newDocTemplateTransform(path string) (docTransform, error) {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(docTemplateFuncs).ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("parsing -doc-template: %w", err)
+	}
+	return func(doc map[string]interface{}) ([]map[string]interface{}, error) {
+		var rendered bytes.Buffer
+		if err := tmpl.Execute(&rendered, doc); err != nil {
+			return nil, fmt.Errorf("rendering -doc-template: %w", err)
+		}
+		return decodeRenderedDocuments(rendered.Bytes(), "-doc-template")
+	}, nil
+}
+
+// decodeRenderedDocuments accepts a JSON object, an array of objects to
+// split the source into several documents, or blank output or null to drop
+// it. Null array elements are skipped. source names the producing option in
+// errors.
+func decodeRenderedDocuments(rendered []byte, source string) ([]map[string]interface{}, error) {
+	trimmed := bytes.TrimSpace(rendered)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+	if trimmed[0] == '[' {
+		var docs []map[string]interface{}
+		if err := decodeJSON(trimmed, &docs); err != nil {
+			return nil, fmt.Errorf("%s output is not an array of JSON objects: %w", source, err)
+		}
+		kept := docs[:0]
+		for _, doc := range docs {
+			if doc != nil {
+				kept = append(kept, doc)
+			}
+		}
+		return kept, nil
+	}
+	var doc map[string]interface{}
+	if err := decodeJSON(trimmed, &doc); err != nil {
+		return nil, fmt.Errorf("%s output is not a JSON object: %w", source, err)
+	}
+	if doc == nil {
+		return nil, nil
+	}
+	return []map[string]interface{}{doc}, nil
+}
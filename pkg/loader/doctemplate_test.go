@@ -0,0 +1,136 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestTemplate writes a -doc-template file for a test.
+func writeTestTemplate(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "doc.tmpl")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	return path
+}
+
+// TestDocTemplateTransformRenders verifies behavior for the related scenario.
+func TestDocTemplateTransformRenders(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		template string
+		doc      string
+		want     []string
+	}{
+		{
+			name:     "reshape",
+			template: `{"name": {{json (upper .user.name)}}, "first": {{json (field "First Name" .)}}, "tier": {{json (default "free" .tier)}}, "missing": {{json .missing}}}`,
+			doc:      `{"user":{"name":"ada"},"First Name":"Ada","tier":""}`,
+			want:     []string{`{"first":"Ada","missing":null,"name":"ADA","tier":"free"}`},
+		},
+		{
+			name:     "split",
+			template: `[{{range $i, $tag := .tags}}{{if $i}},{{end}}{"id": {{json $.id}}, "tag": {{json $tag}}}{{end}}]`,
+			doc:      `{"id":"7","tags":["a","b"]}`,
+			want:     []string{`{"id":"7","tag":"a"}`, `{"id":"7","tag":"b"}`},
+		},
+		{
+			name:     "drop",
+			template: `{{if .keep}}{"id": {{json .id}}}{{end}}`,
+			doc:      `{"id":"1","keep":false}`,
+		},
+		{
+			name:     "null drops",
+			template: `null`,
+			doc:      `{"id":"1"}`,
+		},
+		{
+			name:     "join",
+			template: `{"path": {{json (join "/" .parts)}}, "first": {{json (index (split .csv ",") 0)}}}`,
+			doc:      `{"parts":["a",1,true],"csv":" x ,y"}`,
+			want:     []string{`{"first":" x ","path":"a/1/true"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			step, err := newDocTemplateTransform(writeTestTemplate(t, tt.template))
+			if err != nil {
+				t.Fatalf("newDocTemplateTransform returned error: %v", err)
+			}
+			out, err := step(decodeTestDocument(t, tt.doc))
+			if err != nil {
+				t.Fatalf("transform returned error: %v", err)
+			}
+			got := make([]string, 0, len(out))
+			for _, doc := range out {
+				got = append(got, encodeTestDocument(t, doc))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("rendered %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDocTemplateTransformErrors verifies behavior for the related scenario.
+func TestDocTemplateTransformErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newDocTemplateTransform(writeTestTemplate(t, `{{.broken`)); err == nil {
+		t.Fatal("expected a template parse error")
+	}
+	for _, content := range []string{`{"name": {{.name}}}`, `["x"]`, `{{index .tags 5}}`} {
+		step, err := newDocTemplateTransform(writeTestTemplate(t, content))
+		if err != nil {
+			t.Fatalf("newDocTemplateTransform returned error: %v", err)
+		}
+		if _, err := step(decodeTestDocument(t, `{"name":"ada","tags":[]}`)); err == nil {
+			t.Fatalf("expected template %s to fail for the document", content)
+		}
+	}
+}
+
+// TestRunDocTemplateSplitsAndDrops verifies behavior for the related scenario.
+func TestRunDocTemplateSplitsAndDrops(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	dataFile := filepath.Join(t.TempDir(), "orders.json")
+	content := `[{"id":"1","items":["a","b"]},{"id":"2","items":[]},{"id":"3","items":["c"]}]`
+	if err := os.WriteFile(dataFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	template := writeTestTemplate(t, `{{if .items}}[{{range $i, $item := .items}}{{if $i}},{{end}}{"order": {{json $.id}}, "item": {{json $item}}}{{end}}]{{end}}`)
+	result, err := Run(context.Background(), Options{
+		URL:         server.URL,
+		Index:       "cards",
+		DataFile:    dataFile,
+		AddToIndex:  true,
+		DocTemplate: template,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsProcessed != 3 || result.DocumentsSkipped != 1 {
+		t.Fatalf("expected 3 processed and 1 skipped, got %+v", result)
+	}
+	sent := strings.Join(bodies(), "")
+	for _, want := range []string{`"item":"a"`, `"item":"b"`, `"item":"c"`} {
+		if !strings.Contains(sent, want) {
+			t.Fatalf("expected %s in bulk bodies: %s", want, sent)
+		}
+	}
+
+	_, err = Run(context.Background(), Options{Index: "logs", AddToIndex: true, DocTemplate: filepath.Join(t.TempDir(), "absent.tmpl")})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected a missing template to be rejected, got %v", err)
+	}
+}
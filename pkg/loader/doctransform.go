@@ -0,0 +1,1051 @@
+package loader
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// ─── Document Transforms ───────────────────────────────────────────────────────
+
+const (
+	// defaultFlattenSeparator joins parent and child keys for -flatten.
+	defaultFlattenSeparator = "."
+	// nullPolicyKeep indexes nulls and empty strings unchanged.
+	nullPolicyKeep = "keep"
+	// nullPolicyDrop removes fields whose value is null or an empty string.
+	nullPolicyDrop = "drop"
+	// nullPolicyEmptyToNull indexes empty strings as null.
+	nullPolicyEmptyToNull = "empty-to-null"
+)
+
+// docTransform rewrites one source document before it is indexed. It returns
+// the documents to index in its place: none drops the record, one replaces it,
+// and several split it.
+type docTransform func(doc map[string]interface{}) ([]map[string]interface{}, error)
+
+// docTransformChain applies transforms in order; each output of one step is
+// fed to the next.
+type docTransformChain []docTransform
+
+// apply runs doc through the chain. An empty chain returns doc unchanged.
+func (c docTransformChain) apply(doc map[string]interface{}) ([]map[string]interface{}, error) {
+	docs := []map[string]interface{}{doc}
+	for _, step := range c {
+		next := make([]map[string]interface{}, 0, len(docs))
+		for _, current := range docs {
+			out, err := step(current)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, out...)
+		}
+		docs = next
+	}
+	return docs, nil
+}
+
+// inPlace adapts a mutator that never drops or splits documents.
+func inPlace(mutate func(doc map[string]interface{})) docTransform {
+	return func(doc map[string]interface{}) ([]map[string]interface{}, error) {
+		mutate(doc)
+		return []map[string]interface{}{doc}, nil
+	}
+}
+
+// buildDocTransforms assembles the per-document transforms selected in opts,
+// in the documented order.
+func buildDocTransforms(opts Options) (docTransformChain, error) {
+	var chain docTransformChain
+	if len(opts.Rename) > 0 {
+		rules, err := parseRenameRules(opts.Rename)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, inPlace(func(doc map[string]interface{}) { renameFields(doc, rules) }))
+	}
+	if strings.TrimSpace(opts.ECSPreset) != "" {
+		mappings, err := parseECSPresets(opts.ECSPreset)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, inPlace(func(doc map[string]interface{}) { applyECSMappings(doc, mappings) }))
+	}
+	if len(opts.Decode) > 0 {
+		rules, err := parseDecodeRules(opts.Decode)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, inPlace(func(doc map[string]interface{}) { decodeFields(doc, rules) }))
+	}
+	if opts.ExpandDots && opts.Flatten {
+		return nil, fmt.Errorf("-expand-dots and -flatten cannot be combined")
+	}
+	if opts.ExpandDots {
+		chain = append(chain, inPlace(expandDottedKeys))
+	}
+	if opts.Flatten {
+		separator := opts.FlattenSeparator
+		if separator == "" {
+			separator = defaultFlattenSeparator
+		}
+		chain = append(chain, inPlace(func(doc map[string]interface{}) { flattenObjects(doc, separator) }))
+	}
+	switch opts.NullPolicy {
+	case "", nullPolicyKeep:
+	case nullPolicyDrop, nullPolicyEmptyToNull:
+		policy := opts.NullPolicy
+		chain = append(chain, inPlace(func(doc map[string]interface{}) { applyNullPolicy(doc, policy) }))
+	default:
+		return nil, fmt.Errorf("-null-policy must be %s, %s, or %s", nullPolicyKeep, nullPolicyDrop, nullPolicyEmptyToNull)
+	}
+	if len(opts.Defaults) > 0 {
+		defaults, err := parseFieldDefaults(opts.Defaults)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, inPlace(func(doc map[string]interface{}) { applyFieldDefaults(doc, defaults) }))
+	}
+	if len(opts.Convert) > 0 {
+		rules, err := parseConvertRules(opts.Convert)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, inPlace(func(doc map[string]interface{}) { convertFields(doc, rules) }))
+	}
+	if len(opts.Normalize) > 0 {
+		rules, err := parseNormalizeRules(opts.Normalize)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, inPlace(func(doc map[string]interface{}) { normalizeFields(doc, rules) }))
+	}
+	if len(opts.GeoIP) > 0 {
+		geoip, err := newGeoIPTransform(opts.GeoIP, opts.GeoIPDatabase)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, geoip)
+	}
+	if len(opts.Lookups) > 0 {
+		lookup, err := newLookupTransform(opts.Lookups)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, lookup)
+	}
+	if len(opts.Explode) > 0 {
+		rules, err := parseExplodeRules(opts.Explode)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range rules {
+			chain = append(chain, func(doc map[string]interface{}) ([]map[string]interface{}, error) {
+				return explodeDocument(doc, rule), nil
+			})
+		}
+	}
+	if field := strings.TrimSpace(opts.UUIDField); field != "" {
+		stamp, err := newUUIDStamper(field, opts.UUIDVersion)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, inPlace(stamp))
+	}
+	if strings.TrimSpace(opts.Filter) != "" {
+		filter, err := newFilterTransform(opts.Filter)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, filter)
+	}
+	if len(opts.HashFields) > 0 || len(opts.RedactFields) > 0 {
+		redactions, err := parseRedactRules(opts.RedactFields)
+		if err != nil {
+			return nil, err
+		}
+		hashFields := slices.Clone(opts.HashFields)
+		salt := opts.HashSalt
+		chain = append(chain, inPlace(func(doc map[string]interface{}) { protectFields(doc, hashFields, salt, redactions) }))
+	}
+	if strings.TrimSpace(opts.DocTemplate) != "" {
+		render, err := newDocTemplateTransform(opts.DocTemplate)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, render)
+	}
+	if strings.TrimSpace(opts.ScriptTransform) != "" {
+		script, err := newScriptTransform(opts.ScriptTransform)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, script)
+	}
+	return chain, nil
+}
+
+// ─── Field Paths ───────────────────────────────────────────────────────────────
+
+// lookupField finds path in doc. A top-level key spelled exactly like path
+// wins, so exported names such as "cust.id#" still match; otherwise each dot
+// descends into a nested object.
+func lookupField(doc map[string]interface{}, path string) (interface{}, bool) {
+	parent, key, ok := findField(doc, path)
+	if !ok {
+		return nil, false
+	}
+	return parent[key], true
+}
+
+// findField returns the object holding path and the key it is stored under
+// there, a literal dotted key or the last segment of a nested path, using
+// the same rules as lookupField.
+func findField(doc map[string]interface{}, path string) (map[string]interface{}, string, bool) {
+	if _, ok := doc[path]; ok {
+		return doc, path, true
+	}
+	head, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		return nil, "", false
+	}
+	child, ok := doc[head].(map[string]interface{})
+	if !ok {
+		return nil, "", false
+	}
+	return findField(child, rest)
+}
+
+// replaceField overwrites the value lookupField finds at path under the key
+// it was found by, so a literal dotted key is not left beside a new nested
+// copy. It reports whether the field existed.
+func replaceField(doc map[string]interface{}, path string, value interface{}) bool {
+	parent, key, ok := findField(doc, path)
+	if ok {
+		parent[key] = value
+	}
+	return ok
+}
+
+// deleteField removes path from doc using the same rules as lookupField and
+// reports whether anything was removed. Objects left empty by the removal are
+// removed too.
+func deleteField(doc map[string]interface{}, path string) bool {
+	if _, ok := doc[path]; ok {
+		delete(doc, path)
+		return true
+	}
+	head, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		return false
+	}
+	child, ok := doc[head].(map[string]interface{})
+	if !ok || !deleteField(child, rest) {
+		return false
+	}
+	if len(child) == 0 {
+		delete(doc, head)
+	}
+	return true
+}
+
+// setField stores value at path, creating intermediate objects for each dot.
+// A non-object value in the way is replaced.
+func setField(doc map[string]interface{}, path string, value interface{}) {
+	head, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		doc[path] = value
+		return
+	}
+	child, ok := doc[head].(map[string]interface{})
+	if !ok {
+		child = make(map[string]interface{})
+		doc[head] = child
+	}
+	setField(child, rest, value)
+}
+
+// ─── Field Renaming ────────────────────────────────────────────────────────────
+
+// renameRule moves the value at From to To.
+type renameRule struct {
+	From string
+	To   string
+}
+
+// parseRenameRules parses repeatable "old=new" -rename values.
+func parseRenameRules(values []string) ([]renameRule, error) {
+	rules := make([]renameRule, 0, len(values))
+	for _, value := range values {
+		from, to, ok := strings.Cut(value, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("rename %q must be old=new", value)
+		}
+		rules = append(rules, renameRule{From: from, To: to})
+	}
+	return rules, nil
+}
+
+// renameFields applies rules in order; a missing source field is left alone.
+func renameFields(doc map[string]interface{}, rules []renameRule) {
+	for _, rule := range rules {
+		value, ok := lookupField(doc, rule.From)
+		if !ok {
+			continue
+		}
+		deleteField(doc, rule.From)
+		setField(doc, rule.To, value)
+	}
+}
+
+// ─── Field Decoding ──────────────────────────────────────────────────────────
+
+// decodeRule decodes the encoded string at Field; JSON parses the result as
+// JSON instead of keeping it as a string.
+type decodeRule struct {
+	Field    string
+	Encoding string
+	JSON     bool
+}
+
+// parseDecodeRules parses repeatable "field=base64|hex[:json]" -decode values.
+func parseDecodeRules(values []string) ([]decodeRule, error) {
+	rules := make([]decodeRule, 0, len(values))
+	for _, value := range values {
+		field, spec, ok := strings.Cut(value, "=")
+		field = strings.TrimSpace(field)
+		encoding, format, _ := strings.Cut(strings.ToLower(strings.TrimSpace(spec)), ":")
+		if !ok || field == "" || (encoding != "base64" && encoding != "hex") || (format != "" && format != "json") {
+			return nil, fmt.Errorf("decode %q must be field=base64 or field=hex, optionally with :json", value)
+		}
+		rules = append(rules, decodeRule{Field: field, Encoding: encoding, JSON: format == "json"})
+	}
+	return rules, nil
+}
+
+// decodeFields applies rules in order. Arrays are decoded element by element.
+// A value that is not a string, is not validly encoded, does not decode to
+// UTF-8 text, or (with :json) is not JSON is left unchanged.
+func decodeFields(doc map[string]interface{}, rules []decodeRule) {
+	for _, rule := range rules {
+		value, ok := lookupField(doc, rule.Field)
+		if !ok {
+			continue
+		}
+		if items, isArray := value.([]interface{}); isArray {
+			decoded := make([]interface{}, len(items))
+			for i, item := range items {
+				decoded[i] = rule.decode(item)
+			}
+			replaceField(doc, rule.Field, decoded)
+			continue
+		}
+		replaceField(doc, rule.Field, rule.decode(value))
+	}
+}
+
+// decode returns the decoded form of one value, or the value itself.
+func (r decodeRule) decode(value interface{}) interface{} {
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+	var raw []byte
+	var err error
+	if r.Encoding == "hex" {
+		raw, err = hex.DecodeString(strings.TrimSpace(text))
+	} else {
+		raw, err = decodeBase64(strings.TrimSpace(text))
+	}
+	if err != nil || !utf8.Valid(raw) {
+		return value
+	}
+	if !r.JSON {
+		return string(raw)
+	}
+	var parsed interface{}
+	if err := decodeJSON(raw, &parsed); err != nil {
+		return value
+	}
+	return parsed
+}
+
+// decodeBase64 accepts the standard and URL-safe alphabets, padded or not.
+func decodeBase64(text string) ([]byte, error) {
+	var err error
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		var raw []byte
+		if raw, err = encoding.DecodeString(text); err == nil {
+			return raw, nil
+		}
+	}
+	return nil, err
+}
+
+// ─── Dot Expansion ─────────────────────────────────────────────────────────────
+
+// expandDottedKeys turns keys such as "user.address.city" into nested objects,
+// merging into objects that already exist, at every level of doc including
+// objects inside arrays. A key that would overwrite a non-object value, or
+// that has an empty segment, is left as-is.
+func expandDottedKeys(doc map[string]interface{}) {
+	dotted := make([]string, 0)
+	for key, value := range doc {
+		expandDottedValue(value)
+		if strings.Contains(key, ".") {
+			dotted = append(dotted, key)
+		}
+	}
+	// Sorted so "a.b" is placed before "a.b.c" and conflicts resolve the same
+	// way on every run.
+	slices.Sort(dotted)
+	for _, key := range dotted {
+		segments := strings.Split(key, ".")
+		if slices.Contains(segments, "") {
+			continue
+		}
+		if insertExpanded(doc, segments, doc[key]) {
+			delete(doc, key)
+		}
+	}
+}
+
+// expandDottedValue recurses into nested objects and arrays.
+func expandDottedValue(value interface{}) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		expandDottedKeys(typed)
+	case []interface{}:
+		for _, item := range typed {
+			expandDottedValue(item)
+		}
+	}
+}
+
+// insertExpanded places value under segments, reporting false without changing
+// doc when a non-object value or an existing leaf is in the way.
+func insertExpanded(doc map[string]interface{}, segments []string, value interface{}) bool {
+	current := doc
+	for i, segment := range segments[:len(segments)-1] {
+		existing, ok := current[segment]
+		if !ok {
+			nested := make(map[string]interface{})
+			current[segment] = nested
+			// Everything below a new object is new too.
+			setField(nested, strings.Join(segments[i+1:], "."), value)
+			return true
+		}
+		child, ok := existing.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		current = child
+	}
+	last := segments[len(segments)-1]
+	if _, exists := current[last]; exists {
+		return false
+	}
+	current[last] = value
+	return true
+}
+
+// ─── Flattening ────────────────────────────────────────────────────────────────
+
+// flattenObjects collapses nested objects into top-level keys joined with
+// separator. Arrays are values and are kept whole. Existing top-level keys win
+// over a flattened key with the same name, and empty objects are kept so no
+// field silently disappears.
+func flattenObjects(doc map[string]interface{}, separator string) {
+	nested := make([]string, 0)
+	for key, value := range doc {
+		if child, ok := value.(map[string]interface{}); ok && len(child) > 0 {
+			nested = append(nested, key)
+		}
+	}
+	slices.Sort(nested)
+	for _, key := range nested {
+		child := doc[key].(map[string]interface{})
+		delete(doc, key)
+		flattenInto(doc, key+separator, child, separator)
+	}
+}
+
+// flattenInto copies child into doc under prefix, recursing into objects.
+func flattenInto(doc map[string]interface{}, prefix string, child map[string]interface{}, separator string) {
+	keys := make([]string, 0, len(child))
+	for key := range child {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		value := child[key]
+		if grandchild, ok := value.(map[string]interface{}); ok && len(grandchild) > 0 {
+			flattenInto(doc, prefix+key+separator, grandchild, separator)
+			continue
+		}
+		if _, exists := doc[prefix+key]; !exists {
+			doc[prefix+key] = value
+		}
+	}
+}
+
+// ─── Null Handling ─────────────────────────────────────────────────────────────
+
+// applyNullPolicy drops or converts null and empty-string fields at every
+// object level. Array elements are not removed, but objects inside arrays are
+// processed.
+func applyNullPolicy(doc map[string]interface{}, policy string) {
+	for key, value := range doc {
+		switch typed := value.(type) {
+		case nil:
+			if policy == nullPolicyDrop {
+				delete(doc, key)
+			}
+		case string:
+			if typed != "" {
+				continue
+			}
+			if policy == nullPolicyDrop {
+				delete(doc, key)
+			} else {
+				doc[key] = nil
+			}
+		case map[string]interface{}:
+			applyNullPolicy(typed, policy)
+		case []interface{}:
+			for _, item := range typed {
+				if child, ok := item.(map[string]interface{}); ok {
+					applyNullPolicy(child, policy)
+				}
+			}
+		}
+	}
+}
+
+// ─── Field Defaults ────────────────────────────────────────────────────────────
+
+// fieldDefault fills Field when a document does not have it.
+type fieldDefault struct {
+	Field string
+	// Raw is the JSON encoding of the value; objects and arrays are decoded
+	// again for every document so later transforms cannot share them.
+	Raw   json.RawMessage
+	value interface{}
+}
+
+// parseFieldDefaults parses repeatable "field=value" -default values. A value
+// that is valid JSON (42, true, null, {"a":1}, "quoted") is used as that JSON
+// value; anything else is a plain string.
+func parseFieldDefaults(values []string) ([]fieldDefault, error) {
+	defaults := make([]fieldDefault, 0, len(values))
+	for _, value := range values {
+		field, raw, ok := strings.Cut(value, "=")
+		field = strings.TrimSpace(field)
+		if !ok || field == "" {
+			return nil, fmt.Errorf("default %q must be field=value", value)
+		}
+		entry := fieldDefault{Field: field}
+		if json.Valid([]byte(raw)) {
+			entry.Raw = json.RawMessage(raw)
+			_ = decodeJSON(entry.Raw, &entry.value)
+		} else {
+			entry.value = raw
+			entry.Raw, _ = json.Marshal(raw)
+		}
+		defaults = append(defaults, entry)
+	}
+	return defaults, nil
+}
+
+// fresh returns the default value, decoding a new copy of objects and arrays.
+func (d fieldDefault) fresh() interface{} {
+	switch d.value.(type) {
+	case map[string]interface{}, []interface{}:
+		var value interface{}
+		_ = decodeJSON(d.Raw, &value)
+		return value
+	}
+	return d.value
+}
+
+// applyFieldDefaults sets each missing field. A field that is present, even as
+// null, is left alone.
+func applyFieldDefaults(doc map[string]interface{}, defaults []fieldDefault) {
+	for _, entry := range defaults {
+		if _, ok := lookupField(doc, entry.Field); ok {
+			continue
+		}
+		setField(doc, entry.Field, entry.fresh())
+	}
+}
+
+// ─── Array Explosion ─────────────────────────────────────────────────────────
+
+// explodeRule splits a document on the array at Field, placing each element
+// at Target (Field itself when no target is given).
+type explodeRule struct {
+	Field  string
+	Target string
+}
+
+// parseExplodeRules parses repeatable "field" or "field:target" -explode values.
+func parseExplodeRules(values []string) ([]explodeRule, error) {
+	rules := make([]explodeRule, 0, len(values))
+	for _, value := range values {
+		field, target, _ := strings.Cut(value, ":")
+		field, target = strings.TrimSpace(field), strings.TrimSpace(target)
+		if field == "" {
+			return nil, fmt.Errorf("explode %q must be field or field:target", value)
+		}
+		if target == "" {
+			target = field
+		}
+		rules = append(rules, explodeRule{Field: field, Target: target})
+	}
+	return rules, nil
+}
+
+// explodeDocument emits one copy of doc per element of the rule's array, with
+// every other field copied from the parent. A missing field, a non-array
+// value, or an empty array leaves the document as it is.
+func explodeDocument(doc map[string]interface{}, rule explodeRule) []map[string]interface{} {
+	value, _ := lookupField(doc, rule.Field)
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return []map[string]interface{}{doc}
+	}
+	docs := make([]map[string]interface{}, len(items))
+	if rule.Target == rule.Field {
+		// Keep the key findField matched, so a literal dotted key stays literal.
+		// The array is cleared first so it is not copied into every child.
+		parent, key, _ := findField(doc, rule.Field)
+		parent[key] = nil
+		for i, item := range items {
+			child := cloneValue(doc).(map[string]interface{})
+			replaceField(child, rule.Field, item)
+			docs[i] = child
+		}
+		return docs
+	}
+	deleteField(doc, rule.Field)
+	for i, item := range items {
+		child := cloneValue(doc).(map[string]interface{})
+		setField(child, rule.Target, item)
+		docs[i] = child
+	}
+	return docs
+}
+
+// cloneValue deep-copies decoded JSON so split documents never share objects
+// or arrays that later transforms might modify.
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			copied[key] = cloneValue(child)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, child := range v {
+			copied[i] = cloneValue(child)
+		}
+		return copied
+	}
+	return value
+}
+
+// ─── Unit Conversion ─────────────────────────────────────────────────────────
+
+// unitDefinition converts a unit to its dimension's base unit (bytes, seconds,
+// or kelvin) as value*scale + offset.
+type unitDefinition struct {
+	dimension string
+	scale     float64
+	offset    float64
+}
+
+// convertUnits lists the units -convert understands. kb, mb, gb, and tb are
+// decimal; kib, mib, gib, and tib are binary.
+var convertUnits = map[string]unitDefinition{
+	"b":   {dimension: "data", scale: 1},
+	"kb":  {dimension: "data", scale: 1e3},
+	"mb":  {dimension: "data", scale: 1e6},
+	"gb":  {dimension: "data", scale: 1e9},
+	"tb":  {dimension: "data", scale: 1e12},
+	"kib": {dimension: "data", scale: 1 << 10},
+	"mib": {dimension: "data", scale: 1 << 20},
+	"gib": {dimension: "data", scale: 1 << 30},
+	"tib": {dimension: "data", scale: 1 << 40},
+	"ns":  {dimension: "time", scale: 1e-9},
+	"us":  {dimension: "time", scale: 1e-6},
+	"ms":  {dimension: "time", scale: 1e-3},
+	"s":   {dimension: "time", scale: 1},
+	"min": {dimension: "time", scale: 60},
+	"h":   {dimension: "time", scale: 3600},
+	"d":   {dimension: "time", scale: 86400},
+	"c":   {dimension: "temperature", scale: 1, offset: 273.15},
+	"f":   {dimension: "temperature", scale: 5.0 / 9, offset: 459.67 * 5 / 9},
+	"k":   {dimension: "temperature", scale: 1},
+}
+
+// convertUnitAliases maps longer spellings onto convertUnits keys.
+var convertUnitAliases = map[string]string{
+	"bytes": "b", "byte": "b",
+	"sec": "s", "seconds": "s", "second": "s", "m": "min", "minutes": "min",
+	"hours": "h", "days": "d",
+	"celsius": "c", "fahrenheit": "f", "kelvin": "k",
+}
+
+// convertRule rewrites the number at Field from one unit to another.
+type convertRule struct {
+	Field string
+	From  unitDefinition
+	To    unitDefinition
+}
+
+// parseUnit resolves a unit name or alias.
+func parseUnit(name string) (unitDefinition, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := convertUnitAliases[name]; ok {
+		name = alias
+	}
+	unit, ok := convertUnits[name]
+	return unit, ok
+}
+
+// parseConvertRules parses repeatable "field=from:to" -convert values.
+func parseConvertRules(values []string) ([]convertRule, error) {
+	rules := make([]convertRule, 0, len(values))
+	for _, value := range values {
+		field, spec, ok := strings.Cut(value, "=")
+		field = strings.TrimSpace(field)
+		fromName, toName, hasTo := strings.Cut(spec, ":")
+		if !ok || !hasTo || field == "" {
+			return nil, fmt.Errorf("convert %q must be field=from:to, such as size=b:mb", value)
+		}
+		from, fromOK := parseUnit(fromName)
+		to, toOK := parseUnit(toName)
+		if !fromOK || !toOK {
+			return nil, fmt.Errorf("convert %q uses an unknown unit", value)
+		}
+		if from.dimension != to.dimension {
+			return nil, fmt.Errorf("convert %q mixes %s and %s units", value, from.dimension, to.dimension)
+		}
+		rules = append(rules, convertRule{Field: field, From: from, To: to})
+	}
+	return rules, nil
+}
+
+// convertFields applies rules in order. Numbers and numeric strings are
+// converted, arrays element by element; anything else is left unchanged.
+func convertFields(doc map[string]interface{}, rules []convertRule) {
+	for _, rule := range rules {
+		value, ok := lookupField(doc, rule.Field)
+		if !ok {
+			continue
+		}
+		if items, isArray := value.([]interface{}); isArray {
+			converted := make([]interface{}, len(items))
+			for i, item := range items {
+				converted[i] = rule.convert(item)
+			}
+			replaceField(doc, rule.Field, converted)
+			continue
+		}
+		replaceField(doc, rule.Field, rule.convert(value))
+	}
+}
+
+// convert returns value in the target unit, rounded to 12 significant digits
+// so 98.6 °F becomes 37 °C rather than 37.00000000000001.
+// Whole numbers converted by a whole factor, such as mb to b or ms to s,
+// stay exact integers.
+func (r convertRule) convert(value interface{}) interface{} {
+	if whole, ok := wholeNumber(value); ok {
+		if converted, ok := r.convertInteger(whole); ok {
+			return converted
+		}
+	}
+	number, ok := numberValue(value)
+	if text, isText := value.(string); isText {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		number, ok = parsed, err == nil
+	}
+	if !ok {
+		return value
+	}
+	base := number*r.From.scale + r.From.offset
+	converted := (base - r.To.offset) / r.To.scale
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(converted, 'g', 12, 64), 64)
+	if err != nil {
+		return converted
+	}
+	return rounded
+}
+
+// wholeNumber returns value as an int64 when it is an integer, or a string
+// spelling one, parsed without going through float64.
+func wholeNumber(value interface{}) (int64, bool) {
+	var text string
+	switch typed := value.(type) {
+	case json.Number:
+		text = typed.String()
+	case string:
+		text = strings.TrimSpace(typed)
+	case int64:
+		return typed, true
+	case int:
+		return int64(typed), true
+	default:
+		return 0, false
+	}
+	number, err := strconv.ParseInt(text, 10, 64)
+	return number, err == nil
+}
+
+// convertInteger converts whole when both units differ by a whole factor
+// and the result is a whole number that fits an int64.
+func (r convertRule) convertInteger(whole int64) (int64, bool) {
+	if r.From.offset != 0 || r.To.offset != 0 {
+		return 0, false
+	}
+	factor := func(ratio float64) (int64, bool) {
+		rounded := math.Round(ratio)
+		if rounded < 1 || rounded > 1<<53 || math.Abs(ratio-rounded) > 1e-9*rounded {
+			return 0, false
+		}
+		return int64(rounded), true
+	}
+	if r.From.scale >= r.To.scale {
+		multiplier, ok := factor(r.From.scale / r.To.scale)
+		if !ok {
+			return 0, false
+		}
+		hi, lo := bits.Mul64(uint64(absInt64(whole)), uint64(multiplier))
+		if hi != 0 || lo > math.MaxInt64 {
+			return 0, false
+		}
+		return whole * multiplier, true
+	}
+	divisor, ok := factor(r.To.scale / r.From.scale)
+	if !ok || whole%divisor != 0 {
+		return 0, false
+	}
+	return whole / divisor, true
+}
+
+// absInt64 returns the magnitude of n; math.MinInt64 maps to itself, which
+// the overflow check then rejects.
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// ─── String Normalization ────────────────────────────────────────────────────
+
+// stringNormalizers implement the -normalize operations.
+var stringNormalizers = map[string]func(string) string{
+	"trim":            strings.TrimSpace,
+	"lower":           strings.ToLower,
+	"upper":           strings.ToUpper,
+	"collapse-spaces": func(text string) string { return strings.Join(strings.Fields(text), " ") },
+}
+
+// normalizeRule applies Ops, in order, to the string at Field.
+type normalizeRule struct {
+	Field string
+	Ops   []func(string) string
+}
+
+// parseNormalizeRules parses repeatable "field=op[,op...]" -normalize values.
+func parseNormalizeRules(values []string) ([]normalizeRule, error) {
+	rules := make([]normalizeRule, 0, len(values))
+	for _, value := range values {
+		field, spec, ok := strings.Cut(value, "=")
+		field = strings.TrimSpace(field)
+		if !ok || field == "" {
+			return nil, fmt.Errorf("normalize %q must be field=op[,op...]", value)
+		}
+		rule := normalizeRule{Field: field}
+		for _, name := range strings.Split(spec, ",") {
+			op, ok := stringNormalizers[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return nil, fmt.Errorf("normalize %q: unknown operation %q (use trim, lower, upper, or collapse-spaces)", value, strings.TrimSpace(name))
+			}
+			rule.Ops = append(rule.Ops, op)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// normalizeFields applies rules in order to strings and to the strings in
+// arrays; other values are left unchanged.
+func normalizeFields(doc map[string]interface{}, rules []normalizeRule) {
+	for _, rule := range rules {
+		value, ok := lookupField(doc, rule.Field)
+		if !ok {
+			continue
+		}
+		if items, isArray := value.([]interface{}); isArray {
+			normalized := make([]interface{}, len(items))
+			for i, item := range items {
+				normalized[i] = rule.normalize(item)
+			}
+			replaceField(doc, rule.Field, normalized)
+			continue
+		}
+		replaceField(doc, rule.Field, rule.normalize(value))
+	}
+}
+
+// normalize returns value with every operation applied, if it is a string.
+func (r normalizeRule) normalize(value interface{}) interface{} {
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+	for _, op := range r.Ops {
+		text = op(text)
+	}
+	return text
+}
+
+// ─── UUID Fields ─────────────────────────────────────────────────────────────
+
+// newUUIDStamper returns a mutator that sets field to a new UUID in documents
+// that lack it. Version 7 UUIDs start with a millisecond timestamp, so they
+// sort in load order; version 4 UUIDs are fully random.
+func newUUIDStamper(field string, version int) (func(doc map[string]interface{}), error) {
+	var generate func() (uuid.UUID, error)
+	switch version {
+	case 0, 4:
+		generate = uuid.NewRandom
+	case 7:
+		generate = uuid.NewV7
+	default:
+		return nil, fmt.Errorf("-uuid-version must be 4 or 7")
+	}
+	return func(doc map[string]interface{}) {
+		if _, ok := lookupField(doc, field); ok {
+			return
+		}
+		id, err := generate()
+		if err != nil {
+			fatal().Err(err).Msg("Error generating document UUID")
+		}
+		setField(doc, field, id.String())
+	}, nil
+}
+
+// ─── PII Hashing and Redaction ─────────────────────────────────────────────────
+
+// redactMask replaces objects and masked characters under -redact-field.
+const redactMask = "[REDACTED]"
+
+// redactRule masks Field, leaving the last Visible characters readable.
+type redactRule struct {
+	Field   string
+	Visible int
+}
+
+// parseRedactRules parses repeatable "field" or "field:N" -redact-field values.
+func parseRedactRules(values []string) ([]redactRule, error) {
+	rules := make([]redactRule, 0, len(values))
+	for _, value := range values {
+		field, visible := strings.TrimSpace(value), 0
+		if head, tail, ok := strings.Cut(field, ":"); ok {
+			parsed, err := strconv.Atoi(strings.TrimSpace(tail))
+			if err != nil || parsed < 0 {
+				return nil, fmt.Errorf("redact %q must be field or field:N with N >= 0", value)
+			}
+			field, visible = strings.TrimSpace(head), parsed
+		}
+		if field == "" {
+			return nil, fmt.Errorf("redact %q must name a field", value)
+		}
+		rules = append(rules, redactRule{Field: field, Visible: visible})
+	}
+	return rules, nil
+}
+
+// protectFields hashes and then masks the configured fields. Array elements
+// are processed one by one; missing and null fields are left alone.
+func protectFields(doc map[string]interface{}, hashFields []string, salt string, redactions []redactRule) {
+	for _, field := range hashFields {
+		if value, ok := lookupField(doc, field); ok && value != nil {
+			replaceField(doc, field, mapScalars(value, func(text string) string { return hashValue(salt, text) }))
+		}
+	}
+	for _, rule := range redactions {
+		value, ok := lookupField(doc, rule.Field)
+		switch {
+		case !ok || value == nil:
+		case isObject(value):
+			// No part of an object can be shown safely.
+			replaceField(doc, rule.Field, redactMask)
+		default:
+			replaceField(doc, rule.Field, mapScalars(value, func(text string) string { return maskValue(text, rule.Visible) }))
+		}
+	}
+}
+
+// isObject reports whether value is a JSON object.
+func isObject(value interface{}) bool {
+	_, ok := value.(map[string]interface{})
+	return ok
+}
+
+// mapScalars applies fn to a string, to the JSON text of any other value, or
+// to each element of an array.
+func mapScalars(value interface{}, fn func(string) string) interface{} {
+	switch typed := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return fn(typed)
+	case []interface{}:
+		out := make([]interface{}, len(typed))
+		for i, item := range typed {
+			out[i] = mapScalars(item, fn)
+		}
+		return out
+	}
+	encoded, _ := json.Marshal(canonicalNumber(value))
+	return fn(string(encoded))
+}
+
+// hashValue returns the hex SHA-256 of salt followed by text.
+func hashValue(salt, text string) string {
+	sum := sha256.Sum256([]byte(salt + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// maskValue returns redactMask, or with visible > 0 replaces all but the last
+// visible characters with '*'. A value no longer than visible is fully masked
+// rather than shown whole.
+func maskValue(text string, visible int) string {
+	if visible <= 0 {
+		return redactMask
+	}
+	runes := []rune(text)
+	if len(runes) <= visible {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-visible) + string(runes[len(runes)-visible:])
+}
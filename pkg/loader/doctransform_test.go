@@ -0,0 +1,542 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// decodeTestDocument parses a JSON object for transform tests.
+func decodeTestDocument(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var doc map[string]interface{}
+	if err := decodeJSON([]byte(raw), &doc); err != nil {
+		t.Fatalf("decodeJSON(%s) returned error: %v", raw, err)
+	}
+	return doc
+}
+
+// encodeTestDocument renders doc as compact JSON with sorted keys.
+func encodeTestDocument(t *testing.T, doc map[string]interface{}) string {
+	t.Helper()
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	return string(encoded)
+}
+
+// TestDocTransformChainApply verifies behavior for the related scenario.
+func TestDocTransformChainApply(t *testing.T) {
+	t.Parallel()
+
+	split := func(doc map[string]interface{}) ([]map[string]interface{}, error) {
+		copyDoc := map[string]interface{}{"copy": true}
+		return []map[string]interface{}{doc, copyDoc}, nil
+	}
+	drop := func(doc map[string]interface{}) ([]map[string]interface{}, error) {
+		if doc["copy"] == true {
+			return nil, nil
+		}
+		return []map[string]interface{}{doc}, nil
+	}
+	tag := inPlace(func(doc map[string]interface{}) { doc["tagged"] = true })
+
+	docs, err := docTransformChain{split, drop, tag}.apply(map[string]interface{}{"id": "1"})
+	if err != nil {
+		t.Fatalf("apply returned error: %v", err)
+	}
+	want := []map[string]interface{}{{"id": "1", "tagged": true}}
+	if !reflect.DeepEqual(docs, want) {
+		t.Fatalf("apply = %v, want %v", docs, want)
+	}
+}
+
+// TestRenameFields verifies behavior for the related scenario.
+func TestRenameFields(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		rules []string
+		in    string
+		want  string
+	}{
+		{name: "literal key with spaces", rules: []string{"First Name=first_name"}, in: `{"First Name":"Ada"}`, want: `{"first_name":"Ada"}`},
+		{name: "literal key with dot", rules: []string{"cust.id#=customer.id"}, in: `{"cust.id#":7}`, want: `{"customer":{"id":7}}`},
+		{name: "nested source prunes empty parent", rules: []string{"user.name=name"}, in: `{"user":{"name":"ada"}}`, want: `{"name":"ada"}`},
+		{name: "nested source keeps siblings", rules: []string{"user.name=user.login"}, in: `{"user":{"name":"ada","id":1}}`, want: `{"user":{"id":1,"login":"ada"}}`},
+		{name: "missing source unchanged", rules: []string{"absent=present"}, in: `{"a":1}`, want: `{"a":1}`},
+		{name: "rules apply in order", rules: []string{"a=b", "b=c"}, in: `{"a":1}`, want: `{"c":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			rules, err := parseRenameRules(tt.rules)
+			if err != nil {
+				t.Fatalf("parseRenameRules returned error: %v", err)
+			}
+			doc := decodeTestDocument(t, tt.in)
+			renameFields(doc, rules)
+			if want := decodeTestDocument(t, tt.want); !reflect.DeepEqual(doc, want) {
+				t.Fatalf("renameFields = %v, want %v", doc, want)
+			}
+		})
+	}
+}
+
+// TestParseRenameRulesRejectsInvalidValues verifies behavior for the related scenario.
+func TestParseRenameRulesRejectsInvalidValues(t *testing.T) {
+	t.Parallel()
+
+	for _, value := range []string{"noequals", "=new", "old=", " = "} {
+		if _, err := parseRenameRules([]string{value}); err == nil || !strings.Contains(err.Error(), "old=new") {
+			t.Fatalf("parseRenameRules(%q) error = %v, want old=new error", value, err)
+		}
+	}
+}
+
+// TestDecodeFields verifies behavior for the related scenario.
+func TestDecodeFields(t *testing.T) {
+	t.Parallel()
+
+	rules, err := parseDecodeRules([]string{"b64=base64", "url=base64", "raw=BASE64", "hex=hex", "nested.json=base64:json", "list=hex", "bad=base64", "binary=hex", "notjson=hex:json", "number=hex"})
+	if err != nil {
+		t.Fatalf("parseDecodeRules returned error: %v", err)
+	}
+	doc := decodeTestDocument(t, `{
+		"b64": "aGVsbG8gd29ybGQ=",
+		"url": "Pz8_",
+		"raw": "aGk",
+		"hex": "48454C4C4F",
+		"nested": {"json": "eyJhIjpbMSwyXX0="},
+		"list": ["6869", "zz", 7],
+		"bad": "not base64!",
+		"binary": "ff00",
+		"notjson": "7b",
+		"number": 42
+	}`)
+	decodeFields(doc, rules)
+	want := `{"b64":"hello world","bad":"not base64!","binary":"ff00","hex":"HELLO","list":["hi","zz",7],"nested":{"json":{"a":[1,2]}},"notjson":"7b","number":42,"raw":"hi","url":"???"}`
+	if got := encodeTestDocument(t, doc); got != want {
+		t.Fatalf("decodeFields = %s, want %s", got, want)
+	}
+}
+
+// TestDecodeFieldsLiteralDottedKey verifies behavior for the related scenario.
+func TestDecodeFieldsLiteralDottedKey(t *testing.T) {
+	t.Parallel()
+
+	rules, err := parseDecodeRules([]string{"msg.body=base64"})
+	if err != nil {
+		t.Fatalf("parseDecodeRules returned error: %v", err)
+	}
+	doc := decodeTestDocument(t, `{"msg.body":"aGVsbG8="}`)
+	decodeFields(doc, rules)
+	if got, want := encodeTestDocument(t, doc), `{"msg.body":"hello"}`; got != want {
+		t.Fatalf("decodeFields = %s, want %s", got, want)
+	}
+}
+
+// TestConvertFields verifies behavior for the related scenario.
+func TestConvertFields(t *testing.T) {
+	t.Parallel()
+
+	rules, err := parseConvertRules([]string{
+		"size=b:mb", "mem=gib:MiB", "latency=ms:s", "uptime=seconds:h", "temp=f:c", "boil=c:f", "cold=k:c",
+		"list=kb:b", "text=b:kb", "label=b:kb",
+	})
+	if err != nil {
+		t.Fatalf("parseConvertRules returned error: %v", err)
+	}
+	doc := decodeTestDocument(t, `{"size":2500000,"mem":1.5,"latency":"250","uptime":5400,"temp":98.6,"boil":100,"cold":0,"list":[1,"2",null],"text":" 1200 ","label":"n/a"}`)
+	convertFields(doc, rules)
+	want := `{"boil":212,"cold":-273.15,"label":"n/a","latency":0.25,"list":[1000,2000,null],"mem":1536,"size":2.5,"temp":37,"text":1.2,"uptime":1.5}`
+	if got := encodeTestDocument(t, doc); got != want {
+		t.Fatalf("convertFields = %s, want %s", got, want)
+	}
+}
+
+// TestConvertFieldsLiteralDottedKey verifies behavior for the related scenario.
+func TestConvertFieldsLiteralDottedKey(t *testing.T) {
+	t.Parallel()
+
+	rules, err := parseConvertRules([]string{"disk.size=kb:b", "disk.total=b:kb", "disk.odd=b:kb"})
+	if err != nil {
+		t.Fatalf("parseConvertRules returned error: %v", err)
+	}
+	// Integers above 2^53 would lose digits on the way through float64.
+	doc := decodeTestDocument(t, `{"disk.size":9007199254740993,"disk.total":"9007199254740993000","disk.odd":1500}`)
+	convertFields(doc, rules)
+	want := `{"disk.odd":1.5,"disk.size":9007199254740993000,"disk.total":9007199254740993}`
+	if got := encodeTestDocument(t, doc); got != want {
+		t.Fatalf("convertFields = %s, want %s", got, want)
+	}
+}
+
+// TestNormalizeFields verifies behavior for the related scenario.
+func TestNormalizeFields(t *testing.T) {
+	t.Parallel()
+
+	rules, err := parseNormalizeRules([]string{"country=trim,UPPER", "tags=lower", "title=collapse-spaces", "name=upper,trim", "count=lower"})
+	if err != nil {
+		t.Fatalf("parseNormalizeRules returned error: %v", err)
+	}
+	doc := decodeTestDocument(t, `{"country":" us ","tags":["Go","RUST",3],"title":"  hello \t  big\n world ","name":" ada","count":4}`)
+	normalizeFields(doc, rules)
+	want := `{"count":4,"country":"US","name":"ADA","tags":["go","rust",3],"title":"hello big world"}`
+	if got := encodeTestDocument(t, doc); got != want {
+		t.Fatalf("normalizeFields = %s, want %s", got, want)
+	}
+}
+
+// TestNormalizeFieldsLiteralDottedKey verifies behavior for the related scenario.
+func TestNormalizeFieldsLiteralDottedKey(t *testing.T) {
+	t.Parallel()
+
+	rules, err := parseNormalizeRules([]string{"geo.country=trim,upper"})
+	if err != nil {
+		t.Fatalf("parseNormalizeRules returned error: %v", err)
+	}
+	doc := decodeTestDocument(t, `{"geo.country":" us "}`)
+	normalizeFields(doc, rules)
+	if got, want := encodeTestDocument(t, doc), `{"geo.country":"US"}`; got != want {
+		t.Fatalf("normalizeFields = %s, want %s", got, want)
+	}
+}
+
+// TestUUIDStamper verifies behavior for the related scenario.
+func TestUUIDStamper(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []int{4, 7} {
+		stamp, err := newUUIDStamper("meta.uid", version)
+		if err != nil {
+			t.Fatalf("newUUIDStamper(%d) returned error: %v", version, err)
+		}
+		seen := make(map[string]bool)
+		previous := ""
+		for i := 0; i < 3; i++ {
+			doc := map[string]interface{}{}
+			stamp(doc)
+			value, _ := lookupField(doc, "meta.uid")
+			id, err := uuid.Parse(value.(string))
+			if err != nil || int(id.Version()) != version {
+				t.Fatalf("expected a version %d UUID, got %v (%v)", version, value, err)
+			}
+			if seen[id.String()] {
+				t.Fatalf("expected unique UUIDs, got %s twice", id)
+			}
+			seen[id.String()] = true
+			if version == 7 && id.String() < previous {
+				t.Fatalf("expected version 7 UUIDs to sort in creation order, got %s after %s", id, previous)
+			}
+			previous = id.String()
+		}
+		doc := decodeTestDocument(t, `{"meta":{"uid":"keep-me"}}`)
+		stamp(doc)
+		if got, _ := lookupField(doc, "meta.uid"); got != "keep-me" {
+			t.Fatalf("expected an existing field to be kept, got %v", got)
+		}
+	}
+}
+
+// TestExpandDottedKeys verifies behavior for the related scenario.
+func TestExpandDottedKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "flat csv headers", in: `{"user.name":"ada","user.address.city":"London","id":1}`, want: `{"id":1,"user":{"name":"ada","address":{"city":"London"}}}`},
+		{name: "merges into existing object", in: `{"user":{"id":1},"user.name":"ada"}`, want: `{"user":{"id":1,"name":"ada"}}`},
+		{name: "nested objects and arrays", in: `{"items":[{"sku.id":"a"}],"meta":{"geo.lat":1}}`, want: `{"items":[{"sku":{"id":"a"}}],"meta":{"geo":{"lat":1}}}`},
+		{name: "conflict with scalar left as-is", in: `{"user":"ada","user.name":"x"}`, want: `{"user":"ada","user.name":"x"}`},
+		{name: "conflict with placed leaf left as-is", in: `{"a.b":1,"a.b.c":2}`, want: `{"a":{"b":1},"a.b.c":2}`},
+		{name: "empty segments left as-is", in: `{".hidden":1,"trailing.":2,"a..b":3}`, want: `{".hidden":1,"trailing.":2,"a..b":3}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			doc := decodeTestDocument(t, tt.in)
+			expandDottedKeys(doc)
+			if want := decodeTestDocument(t, tt.want); !reflect.DeepEqual(doc, want) {
+				t.Fatalf("expandDottedKeys = %v, want %v", doc, want)
+			}
+		})
+	}
+}
+
+// TestFlattenObjects verifies behavior for the related scenario.
+func TestFlattenObjects(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		separator string
+		in        string
+		want      string
+	}{
+		{name: "nested objects", separator: ".", in: `{"user":{"name":"ada","address":{"city":"London"}},"id":1}`, want: `{"id":1,"user.name":"ada","user.address.city":"London"}`},
+		{name: "custom separator", separator: "_", in: `{"geo":{"lat":1,"lon":2}}`, want: `{"geo_lat":1,"geo_lon":2}`},
+		{name: "arrays kept whole", separator: ".", in: `{"order":{"items":[{"sku":"a"}]}}`, want: `{"order.items":[{"sku":"a"}]}`},
+		{name: "empty object kept", separator: ".", in: `{"meta":{"tags":{}}}`, want: `{"meta.tags":{}}`},
+		{name: "existing key wins", separator: ".", in: `{"a.b":1,"a":{"b":2,"c":3}}`, want: `{"a.b":1,"a.c":3}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			doc := decodeTestDocument(t, tt.in)
+			flattenObjects(doc, tt.separator)
+			if want := decodeTestDocument(t, tt.want); !reflect.DeepEqual(doc, want) {
+				t.Fatalf("flattenObjects = %v, want %v", doc, want)
+			}
+		})
+	}
+}
+
+// TestApplyNullPolicy verifies behavior for the related scenario.
+func TestApplyNullPolicy(t *testing.T) {
+	t.Parallel()
+
+	const in = `{"name":"ada","email":"","phone":null,"zero":0,"address":{"city":"","zip":null},"items":[{"sku":""},null,""]}`
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{policy: nullPolicyDrop, want: `{"name":"ada","zero":0,"address":{},"items":[{},null,""]}`},
+		{policy: nullPolicyEmptyToNull, want: `{"name":"ada","email":null,"phone":null,"zero":0,"address":{"city":null,"zip":null},"items":[{"sku":null},null,""]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			t.Parallel()
+			doc := decodeTestDocument(t, in)
+			applyNullPolicy(doc, tt.policy)
+			if want := decodeTestDocument(t, tt.want); !reflect.DeepEqual(doc, want) {
+				t.Fatalf("applyNullPolicy(%s) = %v, want %v", tt.policy, doc, want)
+			}
+		})
+	}
+}
+
+// TestApplyFieldDefaults verifies behavior for the related scenario.
+func TestApplyFieldDefaults(t *testing.T) {
+	t.Parallel()
+
+	defaults, err := parseFieldDefaults([]string{"status=active", "amount=0", "flags={\"vip\":false}", "geo.country=US", "note=\"42\""})
+	if err != nil {
+		t.Fatalf("parseFieldDefaults returned error: %v", err)
+	}
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "missing fields filled", in: `{}`, want: `{"status":"active","amount":0,"flags":{"vip":false},"geo":{"country":"US"},"note":"42"}`},
+		{name: "present fields kept", in: `{"status":"closed","amount":null,"flags":{},"geo":{"country":"DE"},"note":"x"}`, want: `{"status":"closed","amount":null,"flags":{},"geo":{"country":"DE"},"note":"x"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			doc := decodeTestDocument(t, tt.in)
+			applyFieldDefaults(doc, defaults)
+			if want := decodeTestDocument(t, tt.want); !reflect.DeepEqual(doc, want) {
+				t.Fatalf("applyFieldDefaults = %v, want %v", doc, want)
+			}
+		})
+	}
+
+	first, second := map[string]interface{}{}, map[string]interface{}{}
+	applyFieldDefaults(first, defaults)
+	applyFieldDefaults(second, defaults)
+	first["flags"].(map[string]interface{})["vip"] = true
+	if second["flags"].(map[string]interface{})["vip"] != false {
+		t.Fatal("expected object defaults to be copied per document")
+	}
+}
+
+// TestProtectFields verifies behavior for the related scenario.
+func TestProtectFields(t *testing.T) {
+	t.Parallel()
+
+	redactions, err := parseRedactRules([]string{"ssn:4", "card.number", "address", "pin:6"})
+	if err != nil {
+		t.Fatalf("parseRedactRules returned error: %v", err)
+	}
+	doc := decodeTestDocument(t, `{"email":"ada@example.com","emails":["a@x","b@x"],"ssn":"123-45-6789","card":{"number":4111},"address":{"city":"London"},"pin":"1234","phone":null}`)
+	protectFields(doc, []string{"email", "emails", "phone", "missing"}, "pepper", redactions)
+
+	want := map[string]interface{}{
+		"email":   hashValue("pepper", "ada@example.com"),
+		"emails":  []interface{}{hashValue("pepper", "a@x"), hashValue("pepper", "b@x")},
+		"ssn":     "*******6789",
+		"card":    map[string]interface{}{"number": redactMask},
+		"address": redactMask,
+		"pin":     "****",
+		"phone":   nil,
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("protectFields = %v, want %v", doc, want)
+	}
+	if got := hashValue("a", "bc"); got != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Fatalf("hashValue(a, bc) = %q, want SHA-256 of abc", got)
+	}
+}
+
+// TestProtectFieldsLiteralDottedKeys verifies behavior for the related scenario.
+func TestProtectFieldsLiteralDottedKeys(t *testing.T) {
+	t.Parallel()
+
+	redactions, err := parseRedactRules([]string{"user.ssn:4"})
+	if err != nil {
+		t.Fatalf("parseRedactRules returned error: %v", err)
+	}
+	// Flattened and CSV documents carry dotted names as literal keys.
+	doc := decodeTestDocument(t, `{"user.email":"alice@example.com","user.ssn":"123-45-6789","user":{"name":"alice"}}`)
+	protectFields(doc, []string{"user.email"}, "pepper", redactions)
+
+	want := map[string]interface{}{
+		"user.email": hashValue("pepper", "alice@example.com"),
+		"user.ssn":   "*******6789",
+		"user":       map[string]interface{}{"name": "alice"},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("protectFields = %v, want %v", doc, want)
+	}
+}
+
+// TestExplodeDocument verifies behavior for the related scenario.
+func TestExplodeDocument(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		rules []string
+		doc   string
+		want  []string
+	}{
+		{
+			name:  "in place",
+			rules: []string{"items"},
+			doc:   `{"order":"7","customer":{"id":"c1"},"items":[{"sku":"a"},{"sku":"b"}]}`,
+			want: []string{
+				`{"customer":{"id":"c1"},"items":{"sku":"a"},"order":"7"}`,
+				`{"customer":{"id":"c1"},"items":{"sku":"b"},"order":"7"}`,
+			},
+		},
+		{
+			name:  "literal dotted key in place",
+			rules: []string{"order.items", "order.line.tags"},
+			doc:   `{"id":"1","order.items":["a","b"],"order":{"line.tags":["x"]}}`,
+			want: []string{
+				`{"id":"1","order":{"line.tags":"x"},"order.items":"a"}`,
+				`{"id":"1","order":{"line.tags":"x"},"order.items":"b"}`,
+			},
+		},
+		{
+			name:  "to target",
+			rules: []string{"order.lines:line"},
+			doc:   `{"order":{"id":"7","lines":["x","y"]}}`,
+			want:  []string{`{"line":"x","order":{"id":"7"}}`, `{"line":"y","order":{"id":"7"}}`},
+		},
+		{
+			name:  "combinations",
+			rules: []string{"sizes:size", "colors:color"},
+			doc:   `{"sizes":["s","m"],"colors":["red","blue"]}`,
+			want: []string{
+				`{"color":"red","size":"s"}`, `{"color":"blue","size":"s"}`,
+				`{"color":"red","size":"m"}`, `{"color":"blue","size":"m"}`,
+			},
+		},
+		{name: "empty array", rules: []string{"items"}, doc: `{"id":"1","items":[]}`, want: []string{`{"id":"1","items":[]}`}},
+		{name: "not an array", rules: []string{"items"}, doc: `{"items":"none"}`, want: []string{`{"items":"none"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			chain, err := buildDocTransforms(Options{Explode: tt.rules})
+			if err != nil {
+				t.Fatalf("buildDocTransforms returned error: %v", err)
+			}
+			out, err := chain.apply(decodeTestDocument(t, tt.doc))
+			if err != nil {
+				t.Fatalf("apply returned error: %v", err)
+			}
+			got := make([]string, 0, len(out))
+			for _, doc := range out {
+				got = append(got, encodeTestDocument(t, doc))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("explode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	docs := explodeDocument(decodeTestDocument(t, `{"meta":{"tags":["t"]},"items":[1,2]}`), explodeRule{Field: "items", Target: "items"})
+	docs[0]["meta"].(map[string]interface{})["tags"].([]interface{})[0] = "changed"
+	if got := encodeTestDocument(t, docs[1]); got != `{"items":2,"meta":{"tags":["t"]}}` {
+		t.Fatalf("expected split documents not to share nested values, got %s", got)
+	}
+}
+
+// TestBuildDocTransformsRejectsInvalidOptions verifies behavior for the related scenario.
+func TestBuildDocTransformsRejectsInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "expand with flatten", opts: Options{ExpandDots: true, Flatten: true}},
+		{name: "unknown null policy", opts: Options{NullPolicy: "zero"}},
+		{name: "default without value", opts: Options{Defaults: []string{"status"}}},
+		{name: "redact with bad count", opts: Options{RedactFields: []string{"ssn:x"}}},
+		{name: "explode without field", opts: Options{Explode: []string{":item"}}},
+		{name: "unknown decode encoding", opts: Options{Decode: []string{"payload=rot13"}}},
+		{name: "unknown decode format", opts: Options{Decode: []string{"payload=hex:xml"}}},
+		{name: "convert without units", opts: Options{Convert: []string{"size=mb"}}},
+		{name: "convert unknown unit", opts: Options{Convert: []string{"size=b:parsec"}}},
+		{name: "convert across dimensions", opts: Options{Convert: []string{"size=b:ms"}}},
+		{name: "normalize without operation", opts: Options{Normalize: []string{"country"}}},
+		{name: "unknown normalize operation", opts: Options{Normalize: []string{"country=trim,titlecase"}}},
+		{name: "unknown uuid version", opts: Options{UUIDField: "uid", UUIDVersion: 5}},
+	}
+	for _, tt := range tests {
+		if _, err := buildDocTransforms(tt.opts); err == nil {
+			t.Fatalf("%s: expected buildDocTransforms to return an error", tt.name)
+		}
+	}
+}
+
+// TestRunAppliesDocumentTransforms verifies behavior for the related scenario.
+func TestRunAppliesDocumentTransforms(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	dataFile := filepath.Join(t.TempDir(), "cards.json")
+	if err := os.WriteFile(dataFile, []byte(`[{"Card Name":"Ace","card.suit":"spades","id":"1"}]`), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	if _, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   dataFile,
+		AddToIndex: true,
+		Rename:     []string{"Card Name=card.name"},
+		ExpandDots: true,
+	}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	sent := bodies()
+	if len(sent) != 1 || !strings.Contains(sent[0], `"card":{"name":"Ace","suit":"spades"}`) || strings.Contains(sent[0], "Card Name") {
+		t.Fatalf("unexpected bulk bodies: %q", sent)
+	}
+}
@@ -0,0 +1,218 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Schema Dump ───────────────────────────────────────────────────────────────
+
+// dumpSchemaVolatileSettings are the index settings, as paths under
+// "index", that Elasticsearch assigns to one particular index and refuses,
+// or should not copy, on create.
+var dumpSchemaVolatileSettings = [][]string{
+	{"creation_date"},
+	{"creation_date_string"},
+	{"uuid"},
+	{"version"},
+	{"provided_name"},
+	{"resize"},
+	{"shrink"},
+	{"history", "uuid"},
+	{"routing", "allocation", "initial_recovery"},
+}
+
+// DumpSchemaOptions configures DumpSchema.
+type DumpSchemaOptions struct {
+	// Dir receives settings.json and mappings.json; defaults to the
+	// working directory.
+	Dir string
+	// Load names the cluster and index, as for Run.
+	Load Options
+}
+
+// SchemaDump names the files DumpSchema wrote.
+type SchemaDump struct {
+	// Index is the concrete index the schema was read from, which differs
+	// from Load.Index when that is an alias.
+	Index        string
+	SettingsFile string
+	MappingsFile string
+	// Stripped lists the volatile settings left out, as dotted names.
+	Stripped []string
+}
+
+// DumpSchema writes the settings and mappings of an existing index to
+// settings.json and mappings.json in Dir, without the creation date, UUID,
+// version, and other settings tied to that one index, so the files can be
+// passed back as -settings and -mappings to create an identical index
+// elsewhere. It only reads from the cluster.
+func DumpSchema(ctx context.Context, opts DumpSchemaOptions) (SchemaDump, error) {
+	var dump SchemaDump
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	load := opts.Load
+	if load.URL == "" {
+		load.URL = "http://localhost:9200"
+	}
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+	invalid := func(err error) (SchemaDump, error) {
+		return dump, &RunError{Kind: ErrInvalidOptions, Op: "validating dump-schema options", Err: err}
+	}
+	if strings.TrimSpace(load.Index) == "" {
+		return invalid(fmt.Errorf("-index is required"))
+	}
+	if len(load.Clusters) > 1 {
+		return invalid(fmt.Errorf("dump-schema reads one cluster; pass a single -url"))
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return invalid(fmt.Errorf("-schema-dir %s must be an existing directory", dir))
+	}
+
+	var info ClusterInfo
+	var err error
+	if load.Preflight {
+		if info, err = preflightCluster(ctx, load); err != nil {
+			return dump, err
+		}
+	}
+	cfg := elasticsearchConfig(load)
+	if major := legacyMajor(info, load.DocType); major > 0 {
+		cfg.Transport = &compatTransport{base: cfg.Transport, major: major}
+	}
+	es, err := elasticsearch.NewClient(cfg)
+	if err != nil {
+		return dump, &RunError{Kind: ErrLoaderExecution, Op: "creating Elasticsearch client", Err: err}
+	}
+	index, settings, mappings, err := fetchIndexSchema(es, load.Index)
+	if err != nil {
+		return dump, &RunError{Kind: ErrIndexOperation, Op: "reading index schema", Err: err}
+	}
+	dump.Index = index
+	dump.Stripped = stripVolatileSettings(settings)
+
+	dump.SettingsFile = filepath.Join(dir, "settings.json")
+	if err := writeSchemaFile(dump.SettingsFile, map[string]any{"settings": settings}); err != nil {
+		return dump, &RunError{Kind: ErrLoaderExecution, Op: "writing settings file", Err: err}
+	}
+	dump.MappingsFile = filepath.Join(dir, "mappings.json")
+	if err := writeSchemaFile(dump.MappingsFile, map[string]any{"mappings": mappings}); err != nil {
+		return dump, &RunError{Kind: ErrLoaderExecution, Op: "writing mappings file", Err: err}
+	}
+	log.Info().
+		Str("index", dump.Index).
+		Str("settings", dump.SettingsFile).
+		Str("mappings", dump.MappingsFile).
+		Strs("stripped", dump.Stripped).
+		Msg("Schema dump complete")
+	return dump, nil
+}
+
+// fetchIndexSchema returns the concrete index name, settings, and mappings
+// of name, which must resolve to exactly one index.
+func fetchIndexSchema(es *elasticsearch.Client, name string) (string, map[string]any, map[string]any, error) {
+	res, err := es.Indices.Get([]string{name})
+	if err != nil {
+		return "", nil, nil, err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode == 404 {
+		return "", nil, nil, fmt.Errorf("index %q does not exist", name)
+	}
+	if res.IsError() {
+		return "", nil, nil, fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var payload map[string]struct {
+		Settings map[string]any `json:"settings"`
+		Mappings map[string]any `json:"mappings"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", nil, nil, fmt.Errorf("parsing index response: %w", err)
+	}
+	if len(payload) != 1 {
+		indices := make([]string, 0, len(payload))
+		for index := range payload {
+			indices = append(indices, index)
+		}
+		sort.Strings(indices)
+		return "", nil, nil, fmt.Errorf("%q resolves to %d indices (%s); name one index", name, len(indices), strings.Join(indices, ", "))
+	}
+	for index, schema := range payload {
+		if schema.Mappings == nil {
+			schema.Mappings = map[string]any{}
+		}
+		if schema.Settings == nil {
+			schema.Settings = map[string]any{}
+		}
+		return index, schema.Settings, schema.Mappings, nil
+	}
+	return "", nil, nil, nil
+}
+
+// stripVolatileSettings removes dumpSchemaVolatileSettings from settings in
+// place, pruning objects they leave empty, and returns the dotted names of
+// those it found.
+func stripVolatileSettings(settings map[string]any) []string {
+	index, _ := settings["index"].(map[string]any)
+	if index == nil {
+		return nil
+	}
+	var stripped []string
+	for _, path := range dumpSchemaVolatileSettings {
+		if deleteSettingPath(index, path) {
+			stripped = append(stripped, "index."+strings.Join(path, "."))
+		}
+	}
+	sort.Strings(stripped)
+	return stripped
+}
+
+// deleteSettingPath deletes path from settings, and any parent object the
+// deletion leaves empty, reporting whether path was set.
+func deleteSettingPath(settings map[string]any, path []string) bool {
+	if len(path) == 1 {
+		_, ok := settings[path[0]]
+		delete(settings, path[0])
+		return ok
+	}
+	child, _ := settings[path[0]].(map[string]any)
+	if child == nil || !deleteSettingPath(child, path[1:]) {
+		return false
+	}
+	if len(child) == 0 {
+		delete(settings, path[0])
+	}
+	return true
+}
+
+// writeSchemaFile writes value as indented JSON to path, replacing it only
+// once the whole file is written.
+func writeSchemaFile(path string, value any) error {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(content, '\n'), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// TestStripVolatileSettings verifies behavior for the related scenario.
+func TestStripVolatileSettings(t *testing.T) {
+	t.Parallel()
+
+	settings := map[string]any{"index": map[string]any{
+		"number_of_shards": "2",
+		"creation_date":    "1700000000000",
+		"uuid":             "abc",
+		"provided_name":    "cards-1",
+		"version":          map[string]any{"created": "8110099"},
+		"routing": map[string]any{"allocation": map[string]any{
+			"initial_recovery": map[string]any{"_id": "node-1"},
+			"require":          map[string]any{"box": "hot"},
+		}},
+		"resize": map[string]any{"source": map[string]any{"name": "cards-0", "uuid": "def"}},
+	}}
+	stripped := stripVolatileSettings(settings)
+	want := []string{"index.creation_date", "index.provided_name", "index.resize", "index.routing.allocation.initial_recovery", "index.uuid", "index.version"}
+	if !slices.Equal(stripped, want) {
+		t.Fatalf("expected stripped %v, got %v", want, stripped)
+	}
+	wantSettings := map[string]any{"index": map[string]any{
+		"number_of_shards": "2",
+		"routing":          map[string]any{"allocation": map[string]any{"require": map[string]any{"box": "hot"}}},
+	}}
+	if !reflect.DeepEqual(settings, wantSettings) {
+		t.Fatalf("expected settings %v, got %v", wantSettings, settings)
+	}
+	if got := stripVolatileSettings(map[string]any{}); got != nil {
+		t.Fatalf("expected nothing stripped without index settings, got %v", got)
+	}
+}
+
+// TestDumpSchema verifies behavior for the related scenario.
+func TestDumpSchema(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/cards":
+			_, _ = w.Write([]byte(`{"cards-2":{"aliases":{"cards":{}},"mappings":{"properties":{"code":{"type":"keyword"}}},
+"settings":{"index":{"number_of_shards":"2","creation_date":"1700000000000","uuid":"abc","provided_name":"cards-2","version":{"created":"8110099"}}}}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/logs-*":
+			_, _ = w.Write([]byte(`{"logs-a":{"mappings":{},"settings":{}},"logs-b":{"mappings":{},"settings":{}}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"type":"index_not_found_exception"},"status":404}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	dump, err := DumpSchema(context.Background(), DumpSchemaOptions{Dir: dir, Load: Options{URL: server.URL, Index: "cards"}})
+	if err != nil {
+		t.Fatalf("DumpSchema: %v", err)
+	}
+	if dump.Index != "cards-2" || len(dump.Stripped) != 4 {
+		t.Fatalf("expected cards-2 with 4 stripped settings, got %+v", dump)
+	}
+	settings, err := os.ReadFile(filepath.Join(dir, "settings.json"))
+	if err != nil {
+		t.Fatalf("reading settings.json: %v", err)
+	}
+	var parsedSettings map[string]any
+	if err := json.Unmarshal(settings, &parsedSettings); err != nil {
+		t.Fatalf("parsing settings.json: %v", err)
+	}
+	wantSettings := map[string]any{"settings": map[string]any{"index": map[string]any{"number_of_shards": "2"}}}
+	if !reflect.DeepEqual(parsedSettings, wantSettings) {
+		t.Fatalf("expected settings.json %v, got %s", wantSettings, settings)
+	}
+	if normalized := normalizeIndexSettings(dump.SettingsFile, "", nil); normalized != `{"number_of_shards":"2"}` {
+		t.Fatalf("expected settings.json to normalize as -settings input, got %s", normalized)
+	}
+	mappings, err := os.ReadFile(dump.MappingsFile)
+	if err != nil {
+		t.Fatalf("reading mappings.json: %v", err)
+	}
+	var parsedMappings map[string]any
+	if err := json.Unmarshal(mappings, &parsedMappings); err != nil {
+		t.Fatalf("parsing mappings.json: %v", err)
+	}
+	wantMappings := map[string]any{"mappings": map[string]any{"properties": map[string]any{"code": map[string]any{"type": "keyword"}}}}
+	if !reflect.DeepEqual(parsedMappings, wantMappings) {
+		t.Fatalf("expected mappings.json %v, got %s", wantMappings, mappings)
+	}
+
+	tests := []struct {
+		name  string
+		opts  DumpSchemaOptions
+		error error
+	}{
+		{name: "no index", opts: DumpSchemaOptions{Dir: dir, Load: Options{URL: server.URL}}, error: ErrInvalidOptions},
+		{name: "missing dir", opts: DumpSchemaOptions{Dir: filepath.Join(dir, "absent"), Load: Options{URL: server.URL, Index: "cards"}}, error: ErrInvalidOptions},
+		{name: "several indices", opts: DumpSchemaOptions{Dir: dir, Load: Options{URL: server.URL, Index: "logs-*"}}, error: ErrIndexOperation},
+		{name: "missing index", opts: DumpSchemaOptions{Dir: dir, Load: Options{URL: server.URL, Index: "missing"}}, error: ErrIndexOperation},
+	}
+	for _, tt := range tests {
+		if _, err := DumpSchema(context.Background(), tt.opts); !errors.Is(err, tt.error) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.error, err)
+		}
+	}
+}
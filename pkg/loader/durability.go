@@ -0,0 +1,117 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Load Durability ───────────────────────────────────────────────────────────
+
+// loadDurabilityAsync is the -load-durability value that relaxes the
+// translog while documents are loaded.
+const loadDurabilityAsync = "async"
+
+// translogDurabilitySetting is the dynamic index setting -load-durability
+// changes.
+const translogDurabilitySetting = "index.translog.durability"
+
+// validateLoadDurability accepts "" (leave the index alone) and async.
+func validateLoadDurability(value string) error {
+	switch value {
+	case "", loadDurabilityAsync:
+		return nil
+	}
+	return fmt.Errorf("-load-durability must be async, got %q", value)
+}
+
+// relaxTranslogDurability sets index.translog.durability=async on index for
+// the bulk phase, so each bulk request returns without waiting for its
+// translog fsync, and returns a function that restores the previous value.
+// An acknowledged document can be lost if a node crashes before the next
+// periodic sync, which a load that is re-run on failure does not mind.
+func relaxTranslogDurability(es *elasticsearch.Client, index string) func() {
+	previous, err := getTranslogDurability(es, index)
+	if err != nil {
+		fatal().Err(err).Str("index", index).Msg("Reading index translog durability")
+	}
+	if previous == loadDurabilityAsync {
+		log.Info().Str("index", index).Msg("Index translog durability is already async")
+		return func() {}
+	}
+	if err := putTranslogDurability(es, index, loadDurabilityAsync); err != nil {
+		fatal().Err(err).Str("index", index).Msg("Setting index translog durability to async")
+	}
+	log.Info().Str("index", index).Msg("Set index translog durability to async for the load")
+	return func() {
+		if err := putTranslogDurability(es, index, previous); err != nil {
+			log.Error().Err(err).Str("index", index).Str("durability", restoredDurability(previous)).
+				Msg("Could not restore index translog durability; reset index.translog.durability by hand")
+			return
+		}
+		log.Info().Str("index", index).Str("durability", restoredDurability(previous)).Msg("Restored index translog durability")
+	}
+}
+
+// restoredDurability names the value a restore writes back.
+func restoredDurability(previous string) string {
+	if previous == "" {
+		return "default"
+	}
+	return previous
+}
+
+// getTranslogDurability returns the explicit translog durability of index,
+// or "" when it uses the cluster default.
+func getTranslogDurability(es *elasticsearch.Client, index string) (string, error) {
+	res, err := es.Indices.GetSettings(
+		es.Indices.GetSettings.WithIndex(index),
+		es.Indices.GetSettings.WithName(translogDurabilitySetting),
+		es.Indices.GetSettings.WithFlatSettings(true),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return "", fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var parsed map[string]struct {
+		Settings map[string]string `json:"settings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("parsing index settings: %w", err)
+	}
+	for _, settings := range parsed {
+		return settings.Settings[translogDurabilitySetting], nil
+	}
+	return "", nil
+}
+
+// putTranslogDurability sets the translog durability of index; "" removes
+// the explicit setting so the default applies again.
+func putTranslogDurability(es *elasticsearch.Client, index, durability string) error {
+	var value interface{}
+	if durability != "" {
+		value = durability
+	}
+	body, err := json.Marshal(map[string]interface{}{translogDurabilitySetting: value})
+	if err != nil {
+		return err
+	}
+	res, err := es.Indices.PutSettings(strings.NewReader(string(body)), es.Indices.PutSettings.WithIndex(index))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		responseBody, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	return nil
+}
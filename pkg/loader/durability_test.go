@@ -0,0 +1,163 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newDurabilityTestServer serves index "cards" with the given translog
+// durability ("" for unset) and records the settings and bulk calls it sees.
+func newDurabilityTestServer(t *testing.T, durability string, bulkStatus int) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	record := func(call string) {
+		mu.Lock()
+		calls = append(calls, call)
+		mu.Unlock()
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodGet && r.URL.Path == "/cards/_settings/"+translogDurabilitySetting:
+			record("get")
+			settings := `{}`
+			if durability != "" {
+				settings = `{"` + translogDurabilitySetting + `":"` + durability + `"}`
+			}
+			_, _ = w.Write([]byte(`{"cards":{"settings":` + settings + `}}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/cards/_settings":
+			body, _ := io.ReadAll(r.Body)
+			record("put " + string(body))
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/cards/_block/read_only":
+			record("block read_only")
+			_, _ = w.Write([]byte(`{"acknowledged":true,"shards_acknowledged":true,"indices":[{"name":"cards","blocked":true}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			record("bulk")
+			if bulkStatus != http.StatusOK {
+				w.WriteHeader(bulkStatus)
+				_, _ = w.Write([]byte(`{"error":{"type":"illegal_argument_exception"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"_index":"cards","_id":"1","status":201}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), calls...)
+	}
+}
+
+// TestRunLoadDurabilityRestoresSetting verifies behavior for the related scenario.
+func TestRunLoadDurabilityRestoresSetting(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		durability string
+		bulkStatus int
+		wantErr    bool
+		want       []string
+	}{
+		{
+			name:       "explicit request durability",
+			durability: "request",
+			bulkStatus: http.StatusOK,
+			want:       []string{"get", `put {"index.translog.durability":"async"}`, "bulk", `put {"index.translog.durability":"request"}`},
+		},
+		{
+			name:       "default durability",
+			bulkStatus: http.StatusOK,
+			want:       []string{"get", `put {"index.translog.durability":"async"}`, "bulk", `put {"index.translog.durability":null}`},
+		},
+		{
+			name:       "already async",
+			durability: "async",
+			bulkStatus: http.StatusOK,
+			want:       []string{"get", "bulk"},
+		},
+		{
+			name:       "failed load",
+			durability: "request",
+			bulkStatus: http.StatusBadRequest,
+			wantErr:    true,
+			want:       []string{"get", `put {"index.translog.durability":"async"}`, "bulk", `put {"index.translog.durability":"request"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server, calls := newDurabilityTestServer(t, tt.durability, tt.bulkStatus)
+			_, err := Run(context.Background(), Options{
+				URL:            server.URL,
+				Index:          "cards",
+				DataFile:       writeTestDataFile(t, "cards.ndjson", "{\"n\":1}\n"),
+				AddToIndex:     true,
+				LoadDurability: loadDurabilityAsync,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run returned error %v, want error %v", err, tt.wantErr)
+			}
+			if got := calls(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("unexpected calls:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRunLoadDurabilityRestoresBeforeFinalize verifies behavior for the related scenario.
+func TestRunLoadDurabilityRestoresBeforeFinalize(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newDurabilityTestServer(t, "request", http.StatusOK)
+	result, err := Run(context.Background(), Options{
+		URL:            server.URL,
+		Index:          "cards",
+		DataFile:       writeTestDataFile(t, "cards.ndjson", "{\"n\":1}\n"),
+		AddToIndex:     true,
+		LoadDurability: loadDurabilityAsync,
+		Finalize:       "read-only",
+	})
+	if err != nil || len(result.FinalizedIndices) != 1 {
+		t.Fatalf("expected the index to be finalized, got %+v and %v", result, err)
+	}
+	want := []string{"get", `put {"index.translog.durability":"async"}`, "bulk", `put {"index.translog.durability":"request"}`, "block read_only"}
+	if got := calls(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected calls:\n got %q\nwant %q", got, want)
+	}
+}
+
+// TestRunRejectsUnknownLoadDurability verifies behavior for the related scenario.
+func TestRunRejectsUnknownLoadDurability(t *testing.T) {
+	t.Parallel()
+
+	_, err := Run(context.Background(), Options{
+		URL:            "http://127.0.0.1:0",
+		Index:          "cards",
+		DataFile:       "cards.ndjson",
+		AddToIndex:     true,
+		LoadDurability: "fsync",
+	})
+	var runErr *RunError
+	if !errors.As(err, &runErr) || runErr.Kind != ErrInvalidOptions || !strings.Contains(err.Error(), "-load-durability") {
+		t.Fatalf("expected invalid -load-durability error, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// ─── Bulk Dynamic Templates ────────────────────────────────────────────────────
+
+// readDynamicTemplates reads an Options.DynamicTemplatesFile: a JSON object
+// from full field path to the name of a dynamic template in the index
+// mapping.
+func readDynamicTemplates(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var templates map[string]string
+	if err := json.Unmarshal(content, &templates); err != nil {
+		return nil, fmt.Errorf("%s must be a JSON object of field paths to dynamic template names: %w", path, err)
+	}
+	for field, name := range templates {
+		if strings.TrimSpace(field) == "" || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%s maps %q to %q; field paths and template names must not be empty", path, field, name)
+		}
+	}
+	return templates, nil
+}
+
+// validateDynamicTemplatesVersion rejects clusters older than 7.13, which
+// do not read dynamic_templates on bulk actions. An unknown version passes.
+func validateDynamicTemplatesVersion(info ClusterInfo) error {
+	major, rest, _ := strings.Cut(info.Version, ".")
+	minorText, _, _ := strings.Cut(rest, ".")
+	majorVersion, err := strconv.Atoi(major)
+	if err != nil {
+		return nil
+	}
+	minor, _ := strconv.Atoi(minorText)
+	if majorVersion < 7 || (majorVersion == 7 && minor < 13) {
+		return fmt.Errorf("-dynamic-templates needs Elasticsearch 7.13 or later; the cluster runs %s", info.Version)
+	}
+	return nil
+}
+
+// missingDynamicTemplates returns the template names of templates that the
+// dynamic_templates of index do not define, sorted.
+func missingDynamicTemplates(es *elasticsearch.Client, index string, templates map[string]string) ([]string, error) {
+	mappings, err := fetchIndexMappings(es, index)
+	if err != nil {
+		return nil, err
+	}
+	defined := make(map[string]struct{})
+	entries, _ := mappings["dynamic_templates"].([]any)
+	for _, entry := range entries {
+		named, _ := entry.(map[string]any)
+		for name := range named {
+			defined[name] = struct{}{}
+		}
+	}
+	var missing []string
+	for _, name := range templates {
+		if _, ok := defined[name]; !ok && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	slices.Sort(missing)
+	return missing, nil
+}
+
+// documentDynamicTemplates returns the entries of templates whose field
+// source holds, or nil when it holds none.
+func documentDynamicTemplates(source map[string]interface{}, templates map[string]string) map[string]string {
+	var matched map[string]string
+	for field, name := range templates {
+		if _, ok := lookupField(source, field); !ok {
+			continue
+		}
+		if matched == nil {
+			matched = make(map[string]string, len(templates))
+		}
+		matched[field] = name
+	}
+	return matched
+}
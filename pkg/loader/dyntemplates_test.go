@@ -0,0 +1,108 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestReadDynamicTemplates verifies behavior for the related scenario.
+func TestReadDynamicTemplates(t *testing.T) {
+	t.Parallel()
+
+	templates, err := readDynamicTemplates(writeTestDataFile(t, "templates.json", `{"location":"geo_point","labels.priority":"keyword_only"}`))
+	if err != nil {
+		t.Fatalf("readDynamicTemplates returned error: %v", err)
+	}
+	if len(templates) != 2 || templates["labels.priority"] != "keyword_only" {
+		t.Fatalf("unexpected templates %v", templates)
+	}
+	for _, bad := range []string{`["geo_point"]`, `{"location":""}`, `{"location":1}`} {
+		if _, err := readDynamicTemplates(writeTestDataFile(t, "bad.json", bad)); err == nil {
+			t.Fatalf("expected %s to be rejected", bad)
+		}
+	}
+}
+
+// TestValidateDynamicTemplatesVersion verifies behavior for the related scenario.
+func TestValidateDynamicTemplatesVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		version string
+		ok      bool
+	}{
+		{version: "", ok: true},
+		{version: "6.8.23"},
+		{version: "7.12.1"},
+		{version: "7.13.0", ok: true},
+		{version: "8.15.0", ok: true},
+	}
+	for _, tt := range tests {
+		if err := validateDynamicTemplatesVersion(ClusterInfo{Version: tt.version}); (err == nil) != tt.ok {
+			t.Fatalf("version %q: unexpected result %v", tt.version, err)
+		}
+	}
+}
+
+// TestRunSendsDynamicTemplates verifies behavior for the related scenario.
+func TestRunSendsDynamicTemplates(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var actions []map[string]map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/places/_mapping":
+			_, _ = w.Write([]byte(`{"places":{"mappings":{"dynamic_templates":[{"geo_point":{"mapping":{"type":"geo_point"}}}]}}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			gotActions, _ := concurrencyBulkLines(t, r.Body)
+			mu.Lock()
+			actions = append(actions, gotActions...)
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	data := "{\"name\":\"a\",\"location\":\"41.1,-71.3\"}\n{\"name\":\"b\"}\n"
+	_, err := Run(context.Background(), Options{
+		URL:                  server.URL,
+		Index:                "places",
+		DataFile:             writeTestDataFile(t, "places.ndjson", data),
+		AddToIndex:           true,
+		DynamicTemplatesFile: writeTestDataFile(t, "templates.json", `{"location":"geo_point"}`),
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("expected 2 actions, got %v", actions)
+	}
+	templates, _ := actions[0]["index"]["dynamic_templates"].(map[string]any)
+	if templates["location"] != "geo_point" {
+		t.Fatalf("expected the first action to name the geo_point template, got %v", actions[0])
+	}
+	if _, ok := actions[1]["index"]["dynamic_templates"]; ok {
+		t.Fatalf("expected no dynamic_templates for a document without the field, got %v", actions[1])
+	}
+
+	_, err = Run(context.Background(), Options{
+		URL:                  server.URL,
+		Index:                "places",
+		DataFile:             writeTestDataFile(t, "places.ndjson", data),
+		AddToIndex:           true,
+		DynamicTemplatesFile: writeTestDataFile(t, "templates.json", `{"location":"geo_shape"}`),
+	})
+	if !errors.Is(err, ErrIndexOperation) || !strings.Contains(err.Error(), "missing from the index mapping") {
+		t.Fatalf("expected a missing template to stop the run, got %v", err)
+	}
+}
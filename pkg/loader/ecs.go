@@ -0,0 +1,151 @@
+package loader
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ─── ECS Presets ───────────────────────────────────────────────────────────────
+
+// ecsVersion is written to ecs.version by every -ecs-preset.
+const ecsVersion = "8.17.0"
+
+// ecsMapping moves the first source field a document has onto an Elastic
+// Common Schema field. normalize, when set, rewrites the moved value.
+type ecsMapping struct {
+	Target    string
+	Sources   []string
+	normalize func(value interface{}) interface{}
+}
+
+// ecsPresets lists the field names each -ecs-preset recognizes. Sources are
+// tried in order; the spellings cover Apache/nginx JSON logs, Logstash grok
+// output, and the usual NetFlow/IPFIX exporters.
+var ecsPresets = map[string][]ecsMapping{
+	"weblogs": {
+		{Target: "@timestamp", Sources: []string{"timestamp", "time", "time_local", "time_iso8601"}},
+		{Target: "source.ip", Sources: []string{"client_ip", "clientip", "remote_addr", "remote_ip", "c-ip"}},
+		{Target: "user.name", Sources: []string{"remote_user", "auth", "cs-username"}},
+		{Target: "http.request.method", Sources: []string{"method", "request_method", "verb", "cs-method"}},
+		{Target: "url.original", Sources: []string{"request_uri", "request", "uri", "cs-uri-stem"}},
+		{Target: "http.version", Sources: []string{"http_version", "httpversion", "server_protocol"}},
+		{Target: "http.response.status_code", Sources: []string{"status", "status_code", "response", "sc-status"}, normalize: ecsNumber},
+		{Target: "http.response.body.bytes", Sources: []string{"body_bytes_sent", "bytes", "bytes_sent", "sc-bytes"}, normalize: ecsNumber},
+		{Target: "http.request.referrer", Sources: []string{"http_referer", "referer", "referrer", "cs(Referer)"}},
+		{Target: "user_agent.original", Sources: []string{"http_user_agent", "user_agent", "agent", "useragent", "cs(User-Agent)"}},
+	},
+	"netflow": {
+		{Target: "@timestamp", Sources: []string{"timestamp", "time"}},
+		{Target: "event.start", Sources: []string{"flow_start", "first_switched", "start", "first"}},
+		{Target: "event.end", Sources: []string{"flow_end", "last_switched", "end", "last"}},
+		{Target: "source.ip", Sources: []string{"src_ip", "srcaddr", "src_addr", "ipv4_src_addr", "ipv6_src_addr", "source_ip"}},
+		{Target: "source.port", Sources: []string{"src_port", "srcport", "l4_src_port", "source_port"}, normalize: ecsNumber},
+		{Target: "destination.ip", Sources: []string{"dst_ip", "dstaddr", "dst_addr", "ipv4_dst_addr", "ipv6_dst_addr", "destination_ip"}},
+		{Target: "destination.port", Sources: []string{"dst_port", "dstport", "l4_dst_port", "destination_port"}, normalize: ecsNumber},
+		{Target: "network.transport", Sources: []string{"protocol", "proto", "prot"}, normalize: ecsTransport},
+		{Target: "network.bytes", Sources: []string{"bytes", "in_bytes", "doctets", "octets"}, normalize: ecsNumber},
+		{Target: "network.packets", Sources: []string{"packets", "in_pkts", "dpkts", "pkts"}, normalize: ecsNumber},
+		{Target: "observer.ip", Sources: []string{"exporter", "exporter_ip", "sampler_address"}},
+	},
+}
+
+// ecsPresetNames returns the known presets for error messages.
+func ecsPresetNames() string {
+	names := make([]string, 0, len(ecsPresets))
+	for name := range ecsPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parseECSPresets resolves a comma-separated -ecs-preset value into the
+// mappings to apply, in the order named.
+func parseECSPresets(value string) ([]ecsMapping, error) {
+	var mappings []ecsMapping
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		preset, ok := ecsPresets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown -ecs-preset %q (available: %s)", name, ecsPresetNames())
+		}
+		mappings = append(mappings, preset...)
+	}
+	return mappings, nil
+}
+
+// applyECSMappings moves recognized fields onto their ECS names and sets
+// ecs.version. A target the document already has is left alone, together
+// with its source fields.
+func applyECSMappings(doc map[string]interface{}, mappings []ecsMapping) {
+	for _, mapping := range mappings {
+		if _, ok := lookupField(doc, mapping.Target); ok {
+			continue
+		}
+		for _, source := range mapping.Sources {
+			value, ok := lookupField(doc, source)
+			if !ok {
+				continue
+			}
+			deleteField(doc, source)
+			if mapping.normalize != nil {
+				value = mapping.normalize(value)
+			}
+			setField(doc, mapping.Target, value)
+			break
+		}
+	}
+	setField(doc, "ecs.version", ecsVersion)
+}
+
+// ecsNumber converts numeric strings such as CSV cells to numbers, so fields
+// like http.response.status_code match their ECS long mappings. Other values
+// ("-" for no body, for example) are kept.
+func ecsNumber(value interface{}) interface{} {
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+	if parsed, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64); err == nil {
+		return parsed
+	}
+	return value
+}
+
+// ecsTransport names common IANA protocol numbers and lowercases names, the
+// form ECS expects in network.transport.
+func ecsTransport(value interface{}) interface{} {
+	number, ok := integerValue(value)
+	if text, isText := value.(string); isText {
+		parsed, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		if err != nil {
+			return strings.ToLower(strings.TrimSpace(text))
+		}
+		number, ok = parsed, true
+	}
+	if !ok {
+		return value
+	}
+	switch number {
+	case 1:
+		return "icmp"
+	case 6:
+		return "tcp"
+	case 17:
+		return "udp"
+	case 47:
+		return "gre"
+	case 50:
+		return "esp"
+	case 58:
+		return "ipv6-icmp"
+	case 132:
+		return "sctp"
+	}
+	return strconv.FormatInt(number, 10)
+}
@@ -0,0 +1,67 @@
+package loader
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestApplyECSMappings verifies behavior for the related scenario.
+func TestApplyECSMappings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		preset string
+		doc    string
+		want   string
+	}{
+		{
+			name:   "weblogs",
+			preset: "weblogs",
+			doc:    `{"remote_addr":"10.0.0.1","request_method":"GET","request_uri":"/","status":"200","body_bytes_sent":"-","http_user_agent":"curl/8"}`,
+			want:   `{"ecs":{"version":"` + ecsVersion + `"},"http":{"request":{"method":"GET"},"response":{"body":{"bytes":"-"},"status_code":200}},"source":{"ip":"10.0.0.1"},"url":{"original":"/"},"user_agent":{"original":"curl/8"}}`,
+		},
+		{
+			name:   "netflow",
+			preset: "NetFlow",
+			doc:    `{"srcaddr":"10.0.0.1","dst_port":"443","protocol":6,"in_bytes":1200,"prot":"ignored"}`,
+			want:   `{"destination":{"port":443},"ecs":{"version":"` + ecsVersion + `"},"network":{"bytes":1200,"transport":"tcp"},"prot":"ignored","source":{"ip":"10.0.0.1"}}`,
+		},
+		{
+			name:   "existing target wins",
+			preset: "weblogs",
+			doc:    `{"source":{"ip":"192.0.2.1"},"clientip":"10.0.0.1"}`,
+			want:   `{"clientip":"10.0.0.1","ecs":{"version":"` + ecsVersion + `"},"source":{"ip":"192.0.2.1"}}`,
+		},
+		{
+			name:   "combined presets",
+			preset: "weblogs, netflow",
+			doc:    `{"src_port":"5000","proto":"UDP"}`,
+			want:   `{"ecs":{"version":"` + ecsVersion + `"},"network":{"transport":"udp"},"source":{"port":5000}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mappings, err := parseECSPresets(tt.preset)
+			if err != nil {
+				t.Fatalf("parseECSPresets returned error: %v", err)
+			}
+			doc := decodeTestDocument(t, tt.doc)
+			applyECSMappings(doc, mappings)
+			if got := encodeTestDocument(t, doc); got != tt.want {
+				t.Fatalf("applyECSMappings = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseECSPresetsRejectsUnknownPreset verifies behavior for the related scenario.
+func TestParseECSPresetsRejectsUnknownPreset(t *testing.T) {
+	t.Parallel()
+
+	_, err := parseECSPresets("weblogs,syslog")
+	if err == nil || !strings.Contains(err.Error(), "netflow, weblogs") {
+		t.Fatalf("expected an unknown preset error listing the presets, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package loader
+
+import (
+	"fmt"
+	"io"
+)
+
+// ─── Elasticdump Input ─────────────────────────────────────────────────────────
+
+// Document metadata keys an elasticdump or ESM export carries beside
+// _source. The reader keeps them as top-level keys so transforms can read
+// them, and bulkInsert moves them into the bulk action line.
+const (
+	metaID      = "_id"
+	metaIndex   = "_index"
+	metaRouting = "_routing"
+)
+
+// elasticdumpReader reads elasticdump/ESM NDJSON exports, one search hit
+// per line, as its _source plus the hit's _id, _index, and _routing.
+type elasticdumpReader struct {
+	lines *ndjsonReader
+}
+
+// newElasticdumpReader reads hits from file.
+func newElasticdumpReader(file io.ReadCloser) *elasticdumpReader {
+	return &elasticdumpReader{lines: newNDJSONReader(file)}
+}
+
+// next converts the following hit.
+func (r *elasticdumpReader) next() (map[string]interface{}, error) {
+	hit, err := r.lines.next()
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := hit["_source"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("line %d: elasticdump hit has no _source object", r.lines.line)
+	}
+	for _, key := range []string{metaID, metaIndex, metaRouting} {
+		if value, ok := hit[key].(string); ok && value != "" {
+			doc[key] = value
+		}
+	}
+	return doc, nil
+}
+
+// close releases the underlying file.
+func (r *elasticdumpReader) close() error {
+	return r.lines.close()
+}
+
+// takeDocumentMetadata moves the metadata keys of doc into the bulk action
+// meta and returns doc without them. _index is only honoured with
+// preserveIndex; otherwise it is dropped and the load's index is used.
+func takeDocumentMetadata(doc map[string]interface{}, meta *bulkActionMeta, preserveIndex bool) map[string]interface{} {
+	present := false
+	for _, key := range []string{metaID, metaIndex, metaRouting} {
+		if _, ok := doc[key]; ok {
+			present = true
+			break
+		}
+	}
+	if !present {
+		return doc
+	}
+	source := make(map[string]interface{}, len(doc))
+	for key, value := range doc {
+		switch key {
+		case metaID:
+			if id, ok := value.(string); ok && id != "" {
+				meta.ID = id
+			}
+		case metaIndex:
+			if index, ok := value.(string); ok && index != "" && preserveIndex {
+				meta.Index = index
+			}
+		case metaRouting:
+			if routing, ok := value.(string); ok && routing != "" {
+				meta.Routing = routing
+			}
+		default:
+			source[key] = value
+		}
+	}
+	return source
+}
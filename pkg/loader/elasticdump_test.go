@@ -0,0 +1,83 @@
+package loader
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// elasticdumpTestExport is a three-hit elasticdump export.
+const elasticdumpTestExport = `{"_index":"cards-2024","_type":"_doc","_id":"a1","_score":1,"_source":{"name":"Ann"}}
+{"_index":"cards-2025","_id":"b2","_routing":"eu","_source":{"name":"Bo","_id":"ignored"}}
+{"_index":"cards-2025","_source":{"name":"Cy"}}
+`
+
+// TestElasticdumpReader verifies behavior for the related scenario.
+func TestElasticdumpReader(t *testing.T) {
+	t.Parallel()
+
+	config := inputConfig{format: formatElasticdump}
+	got, err := readTestDocuments(t, config, writeTestDataFile(t, "cards.json", elasticdumpTestExport))
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	want := []string{
+		`{"_id":"a1","_index":"cards-2024","name":"Ann"}`,
+		`{"_id":"b2","_index":"cards-2025","_routing":"eu","name":"Bo"}`,
+		`{"_index":"cards-2025","name":"Cy"}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("read %q, want %q", got, want)
+	}
+	if _, err := readTestDocuments(t, config, writeTestDataFile(t, "cards.json", "{\"_id\":\"1\"}\n")); err == nil || !strings.Contains(err.Error(), "line 1: elasticdump hit has no _source") {
+		t.Fatalf("expected a missing _source error, got %v", err)
+	}
+}
+
+// TestRunElasticdumpPreservesMetadata verifies behavior for the related scenario.
+func TestRunElasticdumpPreservesMetadata(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		preserveIndex bool
+		want          []string
+	}{
+		{want: []string{
+			`{"index":{"_id":"a1","_index":"cards"}}`,
+			`{"index":{"_id":"b2","_index":"cards","routing":"eu"}}`,
+			`{"index":{"_index":"cards"}}`,
+		}},
+		{preserveIndex: true, want: []string{
+			`{"index":{"_id":"a1","_index":"cards-2024"}}`,
+			`{"index":{"_id":"b2","_index":"cards-2025","routing":"eu"}}`,
+			`{"index":{"_index":"cards-2025"}}`,
+		}},
+	}
+	for _, tt := range tests {
+		server, bodies := newWatchTestServer(t)
+		_, err := Run(context.Background(), Options{
+			URL:           server.URL,
+			Index:         "cards",
+			DataFile:      writeTestDataFile(t, "cards.json", elasticdumpTestExport),
+			AddToIndex:    true,
+			Format:        formatElasticdump,
+			PreserveIndex: tt.preserveIndex,
+		})
+		if err != nil {
+			t.Fatalf("preserveIndex=%v: Run returned error: %v", tt.preserveIndex, err)
+		}
+		sent := strings.Join(bodies(), "")
+		for _, meta := range tt.want {
+			if !strings.Contains(sent, meta+"\n") {
+				t.Fatalf("preserveIndex=%v: expected action %s in %s", tt.preserveIndex, meta, sent)
+			}
+		}
+		if strings.Contains(sent, `"_source"`) || strings.Contains(sent, `"ignored"`) || !strings.Contains(sent, `{"name":"Bo"}`) {
+			t.Fatalf("preserveIndex=%v: metadata leaked into sources: %s", tt.preserveIndex, sent)
+		}
+	}
+
+	if _, err := Run(context.Background(), Options{Index: "cards", DataFile: "cards.json", AddToIndex: true, PreserveIndex: true}); err == nil || !strings.Contains(err.Error(), "-preserve-index needs -format elasticdump") {
+		t.Fatalf("expected -preserve-index without elasticdump to fail, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package loader
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// ─── Bulk Error Log ────────────────────────────────────────────────────────────
+
+// errorLog appends every failed bulk request and bulk item to -error-log as
+// one NDJSON line, with the raw error JSON Elasticsearch answered with.
+type errorLog struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	encoder *json.Encoder
+	// entries counts lines written this run.
+	entries int
+}
+
+// errorLogEntry is one -error-log line. BatchOffset is how many documents
+// were sent before the batch, so BatchOffset+Item is the failed document's
+// 0-based position in the load.
+type errorLogEntry struct {
+	Time        time.Time       `json:"time"`
+	Index       string          `json:"index"`
+	Batch       int             `json:"batch"`
+	BatchOffset int             `json:"batch_offset"`
+	Documents   int             `json:"documents,omitempty"`
+	Item        *int            `json:"item,omitempty"`
+	Action      string          `json:"action,omitempty"`
+	DocIndex    string          `json:"_index,omitempty"`
+	ID          string          `json:"_id,omitempty"`
+	Status      int             `json:"status"`
+	Error       json.RawMessage `json:"error,omitempty"`
+	Response    json.RawMessage `json:"response,omitempty"`
+}
+
+// openErrorLog opens path for appending, so repeated -watch and -schedule
+// runs keep earlier entries. It returns nil for an empty path.
+func openErrorLog(path string) (*errorLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &errorLog{path: path, file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// write appends entry; a failed write is not worth failing the load over.
+func (l *errorLog) write(entry errorLogEntry) {
+	if l == nil {
+		return
+	}
+	entry.Time = time.Now().UTC()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.encoder.Encode(entry) == nil {
+		l.entries++
+	}
+}
+
+// batchFailure records a bulk request Elasticsearch rejected as a whole.
+func (l *errorLog) batchFailure(index string, batch, offset, docs, status int, body []byte) {
+	if l == nil {
+		return
+	}
+	entry := errorLogEntry{Index: index, Batch: batch, BatchOffset: offset, Documents: docs, Status: status}
+	if json.Valid(body) {
+		entry.Response = body
+	} else {
+		entry.Response, _ = json.Marshal(string(body))
+	}
+	l.write(entry)
+}
+
+// itemFailure records one failed bulk item at position item of its batch.
+func (l *errorLog) itemFailure(index string, batch, offset, item int, action string, result bulkItemResponse) {
+	if l == nil {
+		return
+	}
+	entry := errorLogEntry{
+		Index:       index,
+		Batch:       batch,
+		BatchOffset: offset,
+		Item:        &item,
+		Action:      action,
+		DocIndex:    result.Index,
+		ID:          result.ID,
+		Status:      result.Status,
+	}
+	if result.Error != nil {
+		entry.Error = result.Error.raw
+	}
+	l.write(entry)
+}
+
+// close closes the file and returns how many entries this run wrote.
+func (l *errorLog) close() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_ = l.file.Close()
+	return l.entries
+}
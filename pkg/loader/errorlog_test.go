@@ -0,0 +1,97 @@
+package loader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunWritesErrorLog verifies behavior for the related scenario.
+func TestRunWritesErrorLog(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.Method != http.MethodPost || r.URL.Path != "/_bulk" {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		scanner := bufio.NewScanner(r.Body)
+		var items []string
+		for scanner.Scan() {
+			scanner.Scan()
+			switch source := scanner.Text(); {
+			case strings.Contains(source, `"broken"`):
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":{"type":"x_content_parse_exception","reason":"bad body"},"status":400}`))
+				return
+			case strings.Contains(source, `"c"`):
+				items = append(items, `{"index":{"_index":"cards","_id":"c","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [n]","caused_by":{"type":"number_format_exception"}}}}`)
+			default:
+				items = append(items, `{"index":{"_index":"cards","status":201}}`)
+			}
+		}
+		_, _ = w.Write([]byte(`{"errors":true,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+		want    []string
+	}{
+		{
+			name: "item failure",
+			data: "{\"code\":\"a\"}\n{\"code\":\"b\"}\n{\"code\":\"c\"}\n",
+			want: []string{`{"index":"cards","batch":2,"batch_offset":2,"item":0,"action":"index","_index":"cards","_id":"c","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [n]","caused_by":{"type":"number_format_exception"}}}`},
+		},
+		{
+			name:    "request failure",
+			data:    "{\"code\":\"broken\"}\n",
+			wantErr: true,
+			want:    []string{`{"index":"cards","batch":1,"batch_offset":0,"documents":1,"status":400,"response":{"error":{"type":"x_content_parse_exception","reason":"bad body"},"status":400}}`},
+		},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		dataFile := filepath.Join(dir, "cards.ndjson")
+		if err := os.WriteFile(dataFile, []byte(tt.data), 0o600); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+		errorLog := filepath.Join(dir, "errors.ndjson")
+		_, err := Run(context.Background(), Options{URL: server.URL, Index: "cards", DataFile: dataFile, AddToIndex: true, BatchSize: 2, ErrorLog: errorLog})
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: Run returned error %v", tt.name, err)
+		}
+		content, err := os.ReadFile(errorLog)
+		if err != nil {
+			t.Fatalf("%s: ReadFile returned error: %v", tt.name, err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if len(lines) != len(tt.want) {
+			t.Fatalf("%s: expected %d entries, got %q", tt.name, len(tt.want), content)
+		}
+		for i, line := range lines {
+			var entry map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(line), &entry); err != nil || entry["time"] == nil {
+				t.Fatalf("%s: entry %d has no time: %s", tt.name, i+1, line)
+			}
+			delete(entry, "time")
+			got, _ := json.Marshal(entry)
+			var want map[string]json.RawMessage
+			_ = json.Unmarshal([]byte(tt.want[i]), &want)
+			wantJSON, _ := json.Marshal(want)
+			if string(got) != string(wantJSON) {
+				t.Fatalf("%s: entry %d: expected %s, got %s", tt.name, i+1, wantJSON, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package loader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Load Estimate ─────────────────────────────────────────────────────────────
+
+// defaultEstimateReplicas is the replica count assumed when neither the index
+// nor -settings names one, matching Elasticsearch's own default.
+const defaultEstimateReplicas = 1
+
+// LoadEstimate is the -estimate report: what a load would send, from a scan
+// of the input, without writing anything.
+type LoadEstimate struct {
+	// Documents counts the documents the load would send, after skipping,
+	// transforms, and deduplication.
+	Documents int
+	// SourceBytes is the encoded size of their sources; BulkBytes adds the
+	// action lines.
+	SourceBytes          int64
+	BulkBytes            int64
+	AverageDocumentBytes float64
+	Batches              int
+	// Replicas is the index's number_of_replicas, read from the existing
+	// index or -settings, else 1.
+	Replicas int
+	// ProjectedIndexBytes assumes each copy stores about SourceBytes; the
+	// real size varies with mappings and compression.
+	ProjectedIndexBytes int64
+}
+
+// estimateLoad scans the whole input through the load's transforms and
+// measures each document as it would be encoded in a bulk request.
+func estimateLoad(opts Options, input inputConfig, transforms docTransformChain, index string, settings bulkSettings, batchSize, replicas int) (LoadEstimate, error) {
+	estimate := LoadEstimate{Replicas: replicas}
+	reader, err := input.openDataFiles(opts.dataFiles(), opts.ReadConcurrency)
+	if err != nil {
+		return estimate, err
+	}
+	defer reader.close()
+	for skipped := 0; skipped < opts.SkipDocuments; skipped++ {
+		if _, err := reader.next(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return estimate, err
+		}
+	}
+
+	body := newBulkBody()
+	defer body.release()
+	measure := func(docs []map[string]interface{}) {
+		for _, doc := range docs {
+			body.Reset()
+			meta, source := documentMeta(doc, index, settings)
+			encodeBulkAction(body, meta, settings.Create)
+			action := body.Len()
+			_ = body.enc.Encode(source)
+			estimate.Documents++
+			estimate.SourceBytes += int64(body.Len() - action)
+			estimate.BulkBytes += int64(body.Len())
+		}
+	}
+	if rawReader, ok := reader.(rawDocumentReader); ok && len(transforms) == 0 && !settings.decodesDocuments() {
+		for {
+			doc, err := rawReader.nextRaw()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return estimate, fmt.Errorf("decoding document %d: %w", estimate.Documents+1, err)
+			}
+			body.Reset()
+			encodeRawBulkBody(body, index, []json.RawMessage{doc}, settings)
+			estimate.Documents++
+			estimate.SourceBytes += int64(len(doc) + 1)
+			estimate.BulkBytes += int64(body.Len())
+		}
+	} else {
+		for read := 1; ; read++ {
+			doc, err := reader.next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return estimate, fmt.Errorf("decoding document %d: %w", read, err)
+			}
+			docs, err := transforms.apply(doc)
+			if err != nil {
+				return estimate, fmt.Errorf("transforming document %d: %w", read, err)
+			}
+			measure(docs)
+		}
+	}
+
+	if estimate.Documents > 0 {
+		estimate.AverageDocumentBytes = float64(estimate.SourceBytes) / float64(estimate.Documents)
+		estimate.Batches = (estimate.Documents + batchSize - 1) / batchSize
+	}
+	estimate.ProjectedIndexBytes = estimate.SourceBytes * int64(1+replicas)
+	return estimate, nil
+}
+
+// estimateReplicas returns number_of_replicas from the existing index when
+// the load writes to it, else from the normalized -settings body.
+func estimateReplicas(es *elasticsearch.Client, index string, existing bool, settingsBody string) int {
+	if existing {
+		res, err := es.Indices.GetSettings(es.Indices.GetSettings.WithIndex(index), es.Indices.GetSettings.WithName("index.number_of_replicas"))
+		if err == nil {
+			defer res.Body.Close()
+			var indices map[string]struct {
+				Settings struct {
+					Index struct {
+						Replicas json.RawMessage `json:"number_of_replicas"`
+					} `json:"index"`
+				} `json:"settings"`
+			}
+			if !res.IsError() && json.NewDecoder(res.Body).Decode(&indices) == nil {
+				for _, settings := range indices {
+					if replicas, ok := parseReplicas(settings.Settings.Index.Replicas); ok {
+						return replicas
+					}
+				}
+			}
+		}
+		log.Debug().Str("index", index).Msg("Could not read number_of_replicas; estimating with -settings or the default")
+	}
+	var settings map[string]json.RawMessage
+	if json.Unmarshal([]byte(settingsBody), &settings) == nil {
+		if replicas, ok := parseReplicas(settings["number_of_replicas"]); ok {
+			return replicas
+		}
+	}
+	return defaultEstimateReplicas
+}
+
+// parseReplicas reads a replica count written as a number or a string.
+func parseReplicas(raw json.RawMessage) (int, bool) {
+	text := strings.Trim(strings.TrimSpace(string(raw)), `"`)
+	replicas, err := strconv.Atoi(text)
+	return replicas, err == nil && replicas >= 0
+}
+
+// logLoadEstimate reports estimate as one event.
+func logLoadEstimate(index string, estimate LoadEstimate) {
+	log.Info().
+		Str("index", index).
+		Int("documents", estimate.Documents).
+		Int64("source_bytes", estimate.SourceBytes).
+		Str("source_size", formatBytes(estimate.SourceBytes)).
+		Int64("bulk_bytes", estimate.BulkBytes).
+		Str("average_document_size", formatBytes(int64(estimate.AverageDocumentBytes))).
+		Int("batches", estimate.Batches).
+		Int("replicas", estimate.Replicas).
+		Int64("projected_index_bytes", estimate.ProjectedIndexBytes).
+		Str("projected_index_size", formatBytes(estimate.ProjectedIndexBytes)).
+		Msg("Load estimate; nothing was written")
+}
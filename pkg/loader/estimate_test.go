@@ -0,0 +1,85 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// TestRunEstimateReportsWithoutWriting verifies behavior for the related scenario.
+func TestRunEstimateReportsWithoutWriting(t *testing.T) {
+	t.Parallel()
+
+	var writes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodGet && r.URL.Path == "/cards/_settings/index.number_of_replicas":
+			_, _ = w.Write([]byte(`{"cards-000001":{"settings":{"index":{"number_of_replicas":"2"}}}}`))
+		case r.Method == http.MethodGet || r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			writes.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "cards.ndjson")
+	data := "{\"code\":\"a\"}\n{\"code\":\"b\"}\n{\"code\":\"b\"}\n{\"code\":\"c\"}\n"
+	if err := os.WriteFile(dataFile, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	settingsFile := filepath.Join(dir, "settings.json")
+	if err := os.WriteFile(settingsFile, []byte(`{"index":{"number_of_replicas":0}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want LoadEstimate
+	}{
+		{
+			// Each raw line is 13 bytes and its action line 29.
+			name: "existing index",
+			opts: Options{AddToIndex: true, BatchSize: 3},
+			want: LoadEstimate{Documents: 4, SourceBytes: 52, BulkBytes: 168, AverageDocumentBytes: 13, Batches: 2, Replicas: 2, ProjectedIndexBytes: 156},
+		},
+		{
+			name: "recreated with settings and deduplicated",
+			opts: Options{DeleteIndex: true, SettingsFile: settingsFile, DedupField: "code", DedupPolicy: dedupKeepLast, BatchSize: 3},
+			want: LoadEstimate{Documents: 3, SourceBytes: 39, BulkBytes: 126, AverageDocumentBytes: 13, Batches: 1, Replicas: 0, ProjectedIndexBytes: 39},
+		},
+	}
+	for _, tt := range tests {
+		opts := tt.opts
+		opts.URL = server.URL
+		opts.Index = "cards"
+		opts.DataFile = dataFile
+		opts.Estimate = true
+		result, err := Run(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		if result.Estimate == nil || *result.Estimate != tt.want {
+			t.Fatalf("%s: expected estimate %+v, got %+v", tt.name, tt.want, result.Estimate)
+		}
+	}
+	if writes.Load() != 0 {
+		t.Fatalf("expected no writes, got %d", writes.Load())
+	}
+
+	_, err := Run(context.Background(), Options{URL: server.URL, Index: "cards", DataFile: dataFile, AddToIndex: true, Estimate: true, Preview: 1})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions for -estimate with -preview, got %v", err)
+	}
+}
@@ -0,0 +1,667 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Windows EVTX Input ────────────────────────────────────────────────────────
+
+const (
+	// evtxHeaderSize is the file header before the first chunk.
+	evtxHeaderSize = 4096
+	// evtxChunkSize is the size of every chunk of event records.
+	evtxChunkSize = 65536
+	// evtxRecordsStart is where a chunk's records follow its header, string
+	// table, and template table.
+	evtxRecordsStart = 512
+	// evtxRecordBudget caps the bytes one record may decode. Templates and
+	// embedded values are decoded each time they are used, so a record that
+	// refers to them repeatedly can otherwise cost far more than its size.
+	evtxRecordBudget = 16 * evtxChunkSize
+)
+
+// errEVTXMalformed reports a record whose Binary XML does not decode.
+var errEVTXMalformed = errors.New("malformed Binary XML")
+
+// Binary XML tokens. The 0x40 bit flags an element with attributes, or
+// more attributes or values following.
+const (
+	binXMLEOF                = 0x00
+	binXMLOpenStartElement   = 0x01
+	binXMLCloseStartElement  = 0x02
+	binXMLCloseEmptyElement  = 0x03
+	binXMLEndElement         = 0x04
+	binXMLValue              = 0x05
+	binXMLAttribute          = 0x06
+	binXMLCDATA              = 0x07
+	binXMLCharRef            = 0x08
+	binXMLEntityRef          = 0x09
+	binXMLPITarget           = 0x0a
+	binXMLPIData             = 0x0b
+	binXMLTemplateInstance   = 0x0c
+	binXMLNormalSubstitution = 0x0d
+	binXMLOptionalSubst      = 0x0e
+	binXMLFragmentHeader     = 0x0f
+	binXMLMoreFlag           = 0x40
+)
+
+// Binary XML value types used by substitutions. The 0x80 bit makes an
+// array of the type.
+const (
+	evtxNull       = 0x00
+	evtxString     = 0x01
+	evtxANSIString = 0x02
+	evtxInt8       = 0x03
+	evtxUint8      = 0x04
+	evtxInt16      = 0x05
+	evtxUint16     = 0x06
+	evtxInt32      = 0x07
+	evtxUint32     = 0x08
+	evtxInt64      = 0x09
+	evtxUint64     = 0x0a
+	evtxReal32     = 0x0b
+	evtxReal64     = 0x0c
+	evtxBool       = 0x0d
+	evtxBinary     = 0x0e
+	evtxGUID       = 0x0f
+	evtxSizeT      = 0x10
+	evtxFileTime   = 0x11
+	evtxSystemTime = 0x12
+	evtxSID        = 0x13
+	evtxHexInt32   = 0x14
+	evtxHexInt64   = 0x15
+	evtxBinXML     = 0x21
+	evtxArray      = 0x80
+)
+
+// evtxFixedSizes holds the encoded length of the fixed-size value types,
+// for splitting arrays.
+var evtxFixedSizes = map[byte]int{
+	evtxInt8: 1, evtxUint8: 1, evtxInt16: 2, evtxUint16: 2, evtxInt32: 4, evtxUint32: 4,
+	evtxInt64: 8, evtxUint64: 8, evtxReal32: 4, evtxReal64: 8, evtxBool: 4, evtxGUID: 16,
+	evtxFileTime: 8, evtxSystemTime: 16, evtxHexInt32: 4, evtxHexInt64: 8,
+}
+
+// xmlNode is an element of a decoded event.
+type xmlNode struct {
+	name     string
+	attrs    []xmlAttr
+	children []*xmlNode
+	text     string
+	// list holds the items of an array substitution.
+	list []string
+}
+
+// xmlAttr is an attribute of an xmlNode.
+type xmlAttr struct {
+	name, value string
+}
+
+// evtxValue is one substitution of a template instance. offset locates
+// data in the chunk, as Binary XML values refer to names by chunk offset.
+type evtxValue struct {
+	kind   byte
+	data   []byte
+	offset int
+}
+
+// xmlEntities are the predefined XML entities an entity reference names.
+var xmlEntities = map[string]string{"lt": "<", "gt": ">", "amp": "&", "quot": `"`, "apos": "'"}
+
+// evtxCursor decodes the Binary XML of one chunk. The first read past end
+// sets err, and later reads return zero.
+type evtxCursor struct {
+	chunk    []byte
+	pos, end int
+	// budget counts down the bytes the record may still decode, shared with
+	// the cursors of the templates and values it uses.
+	budget *int
+	err    error
+}
+
+// spend charges n decoded bytes to the record's budget.
+func (c *evtxCursor) spend(n int) bool {
+	*c.budget -= n
+	if *c.budget < 0 && c.err == nil {
+		c.err = fmt.Errorf("%w: record decodes to more than %d bytes", errEVTXMalformed, evtxRecordBudget)
+	}
+	return c.err == nil
+}
+
+// take returns the next n bytes.
+func (c *evtxCursor) take(n int) []byte {
+	if c.err == nil && (n < 0 || c.pos+n > c.end) {
+		c.err = errEVTXMalformed
+	}
+	if c.err != nil || !c.spend(n) {
+		return make([]byte, max(n, 0))
+	}
+	data := c.chunk[c.pos : c.pos+n]
+	c.pos += n
+	return data
+}
+
+func (c *evtxCursor) u8() byte    { return c.take(1)[0] }
+func (c *evtxCursor) u16() uint16 { return binary.LittleEndian.Uint16(c.take(2)) }
+func (c *evtxCursor) u32() uint32 { return binary.LittleEndian.Uint32(c.take(4)) }
+
+// peek returns the next token without consuming it, or EOF at the end.
+func (c *evtxCursor) peek() byte {
+	if c.err != nil || c.pos >= c.end {
+		return binXMLEOF
+	}
+	return c.chunk[c.pos]
+}
+
+// utf16String reads a string of n UTF-16 code units.
+func (c *evtxCursor) utf16String(n int) string {
+	return decodeUTF16(c.take(2 * n))
+}
+
+// name reads an element, attribute, or entity name: an offset into the
+// chunk's names, written in place the first time the chunk uses it.
+func (c *evtxCursor) name() string {
+	offset := int(c.u32())
+	if c.err == nil && offset+8 > len(c.chunk) {
+		c.err = errEVTXMalformed
+	}
+	if c.err != nil {
+		return ""
+	}
+	length := int(binary.LittleEndian.Uint16(c.chunk[offset+6:]))
+	if offset+8+2*length > len(c.chunk) {
+		c.err = errEVTXMalformed
+		return ""
+	}
+	if !c.spend(2 * length) {
+		return ""
+	}
+	if offset == c.pos {
+		// Next offset, hash, length, the string, and its terminator.
+		c.take(8 + 2*length + 2)
+	}
+	return decodeUTF16(c.chunk[offset+8 : offset+8+2*length])
+}
+
+// content decodes tokens into parent until its end element or the end of
+// the fragment, filling substitutions from values.
+func (c *evtxCursor) content(parent *xmlNode, values []evtxValue, depth int) {
+	if depth > 64 {
+		c.err = errEVTXMalformed
+		return
+	}
+	for c.err == nil {
+		token := c.peek()
+		switch token &^ binXMLMoreFlag {
+		case binXMLEOF:
+			c.pos++
+			return
+		case binXMLEndElement:
+			c.pos++
+			return
+		case binXMLFragmentHeader:
+			c.take(4)
+		case binXMLOpenStartElement:
+			parent.children = append(parent.children, c.element(values, depth))
+		case binXMLTemplateInstance:
+			c.template(parent, depth)
+		case binXMLPITarget:
+			c.take(1)
+			c.name()
+		case binXMLPIData:
+			c.take(1)
+			c.utf16String(int(c.u16()))
+		case binXMLValue, binXMLCDATA, binXMLCharRef, binXMLEntityRef, binXMLNormalSubstitution, binXMLOptionalSubst:
+			c.value(parent, values, depth)
+		default:
+			c.err = fmt.Errorf("%w: token 0x%02x at chunk offset %d", errEVTXMalformed, token, c.pos)
+		}
+	}
+}
+
+// value decodes a text, character or entity reference, or substitution
+// into parent.
+func (c *evtxCursor) value(parent *xmlNode, values []evtxValue, depth int) {
+	switch c.u8() &^ binXMLMoreFlag {
+	case binXMLValue:
+		c.u8()
+		parent.text += c.utf16String(int(c.u16()))
+	case binXMLCDATA:
+		parent.text += c.utf16String(int(c.u16()))
+	case binXMLCharRef:
+		parent.text += string(rune(c.u16()))
+	case binXMLEntityRef:
+		name := c.name()
+		if entity, ok := xmlEntities[name]; ok {
+			parent.text += entity
+		} else {
+			parent.text += "&" + name + ";"
+		}
+	case binXMLNormalSubstitution, binXMLOptionalSubst:
+		id := int(c.u16())
+		c.u8()
+		if c.err != nil || id >= len(values) {
+			return
+		}
+		value := values[id]
+		if value.kind == evtxBinXML {
+			embedded := &evtxCursor{chunk: c.chunk, pos: value.offset, end: value.offset + len(value.data), budget: c.budget}
+			embedded.content(parent, nil, depth+1)
+			c.err = embedded.err
+			return
+		}
+		if !c.spend(len(value.data)) {
+			return
+		}
+		if value.kind&evtxArray != 0 {
+			parent.list = append(parent.list, evtxArrayStrings(value)...)
+			return
+		}
+		parent.text += evtxValueString(value.kind, value.data)
+	}
+}
+
+// element decodes an element and its content. Attributes whose values are
+// all empty substitutions are left out.
+func (c *evtxCursor) element(values []evtxValue, depth int) *xmlNode {
+	token := c.u8()
+	c.u16() // dependency identifier
+	c.u32() // data size
+	element := &xmlNode{name: c.name()}
+	if token&binXMLMoreFlag != 0 {
+		c.u32() // attribute list size
+		for c.err == nil && c.peek()&^binXMLMoreFlag == binXMLAttribute {
+			c.take(1)
+			attr := &xmlNode{name: c.name()}
+			for c.err == nil {
+				next := c.peek() &^ binXMLMoreFlag
+				if next != binXMLValue && next != binXMLCharRef && next != binXMLEntityRef && next != binXMLNormalSubstitution && next != binXMLOptionalSubst {
+					break
+				}
+				c.value(attr, values, depth)
+			}
+			if attr.text != "" || len(attr.list) > 0 {
+				element.attrs = append(element.attrs, xmlAttr{name: attr.name, value: attr.text + strings.Join(attr.list, ",")})
+			}
+		}
+	}
+	switch c.u8() {
+	case binXMLCloseEmptyElement:
+	case binXMLCloseStartElement:
+		c.content(element, values, depth+1)
+	default:
+		if c.err == nil {
+			c.err = errEVTXMalformed
+		}
+	}
+	return element
+}
+
+// template decodes a template instance: the template's Binary XML, defined
+// in place the first time a chunk uses it, filled with the substitution
+// values that follow.
+func (c *evtxCursor) template(parent *xmlNode, depth int) {
+	c.take(2) // token and an unknown byte
+	c.u32()   // template id
+	definition := int(c.u32())
+	if c.err == nil && definition+24 > len(c.chunk) {
+		c.err = errEVTXMalformed
+	}
+	if c.err != nil {
+		return
+	}
+	size := int(binary.LittleEndian.Uint32(c.chunk[definition+20:]))
+	if definition == c.pos {
+		c.take(24 + size)
+	}
+	count := int(c.u32())
+	if c.err == nil && count > (c.end-c.pos)/4 {
+		c.err = errEVTXMalformed
+	}
+	if c.err != nil {
+		return
+	}
+	values := make([]evtxValue, count)
+	sizes := make([]int, count)
+	for i := range values {
+		sizes[i] = int(c.u16())
+		values[i].kind = c.u8()
+		c.u8()
+	}
+	for i := range values {
+		values[i].offset = c.pos
+		values[i].data = c.take(sizes[i])
+	}
+	if c.err != nil {
+		return
+	}
+	body := &evtxCursor{chunk: c.chunk, pos: definition + 24, end: definition + 24 + size, budget: c.budget}
+	if body.end > len(c.chunk) {
+		c.err = errEVTXMalformed
+		return
+	}
+	body.content(parent, values, depth+1)
+	c.err = body.err
+}
+
+// evtxValueString renders a substitution value the way Windows shows it in
+// the event XML.
+func evtxValueString(kind byte, data []byte) string {
+	le := binary.LittleEndian
+	if n, ok := evtxFixedSizes[kind]; ok && len(data) < n {
+		return fmt.Sprintf("%X", data)
+	}
+	switch kind {
+	case evtxNull:
+		return ""
+	case evtxString:
+		return strings.TrimRight(decodeUTF16(data), "\x00")
+	case evtxANSIString:
+		return strings.TrimRight(string(data), "\x00")
+	case evtxInt8:
+		return strconv.FormatInt(int64(int8(data[0])), 10)
+	case evtxUint8:
+		return strconv.FormatUint(uint64(data[0]), 10)
+	case evtxInt16:
+		return strconv.FormatInt(int64(int16(le.Uint16(data))), 10)
+	case evtxUint16:
+		return strconv.FormatUint(uint64(le.Uint16(data)), 10)
+	case evtxInt32:
+		return strconv.FormatInt(int64(int32(le.Uint32(data))), 10)
+	case evtxUint32:
+		return strconv.FormatUint(uint64(le.Uint32(data)), 10)
+	case evtxInt64:
+		return strconv.FormatInt(int64(le.Uint64(data)), 10)
+	case evtxUint64:
+		return strconv.FormatUint(le.Uint64(data), 10)
+	case evtxReal32:
+		return strconv.FormatFloat(float64(math.Float32frombits(le.Uint32(data))), 'g', -1, 32)
+	case evtxReal64:
+		return strconv.FormatFloat(math.Float64frombits(le.Uint64(data)), 'g', -1, 64)
+	case evtxBool:
+		return strconv.FormatBool(le.Uint32(data) != 0)
+	case evtxGUID:
+		return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}", le.Uint32(data), le.Uint16(data[4:]), le.Uint16(data[6:]), data[8:10], data[10:16])
+	case evtxSizeT, evtxHexInt32, evtxHexInt64:
+		if len(data) == 4 {
+			return fmt.Sprintf("0x%x", le.Uint32(data))
+		}
+		if len(data) == 8 {
+			return fmt.Sprintf("0x%x", le.Uint64(data))
+		}
+	case evtxFileTime:
+		return evtxFileTimeString(le.Uint64(data))
+	case evtxSystemTime:
+		t := time.Date(int(le.Uint16(data)), time.Month(le.Uint16(data[2:])), int(le.Uint16(data[6:])),
+			int(le.Uint16(data[8:])), int(le.Uint16(data[10:])), int(le.Uint16(data[12:])), int(le.Uint16(data[14:]))*int(time.Millisecond), time.UTC)
+		return t.Format(time.RFC3339Nano)
+	case evtxSID:
+		if len(data) >= 8 && len(data) >= 8+4*int(data[1]) {
+			authority := uint64(0)
+			for _, b := range data[2:8] {
+				authority = authority<<8 | uint64(b)
+			}
+			sid := fmt.Sprintf("S-%d-%d", data[0], authority)
+			for i := range int(data[1]) {
+				sid += "-" + strconv.FormatUint(uint64(le.Uint32(data[8+4*i:])), 10)
+			}
+			return sid
+		}
+	}
+	return fmt.Sprintf("%X", data)
+}
+
+// evtxArrayStrings renders each item of an array substitution.
+func evtxArrayStrings(value evtxValue) []string {
+	kind := value.kind &^ evtxArray
+	var items []string
+	switch size, fixed := evtxFixedSizes[kind]; {
+	case kind == evtxString:
+		items = strings.Split(strings.TrimRight(decodeUTF16(value.data), "\x00"), "\x00")
+	case fixed:
+		for data := value.data; len(data) >= size; data = data[size:] {
+			items = append(items, evtxValueString(kind, data[:size]))
+		}
+	default:
+		items = append(items, fmt.Sprintf("%X", value.data))
+	}
+	return items
+}
+
+// evtxFileTimeString converts a FILETIME, 100ns intervals since 1601, to
+// an RFC 3339 UTC time.
+func evtxFileTimeString(filetime uint64) string {
+	const secondsTo1970 = 11644473600
+	return time.Unix(int64(filetime/1e7)-secondsTo1970, int64(filetime%1e7)*100).UTC().Format(time.RFC3339Nano)
+}
+
+// decodeUTF16 decodes little-endian UTF-16.
+func decodeUTF16(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// evtxReader reads the event records of a Windows .evtx file, one document
+// per record, chunk by chunk.
+type evtxReader struct {
+	file  io.ReadCloser
+	chunk []byte
+	// chunkIndex counts chunks read, and pos is the next record in chunk.
+	chunkIndex int
+	pos, end   int
+}
+
+// newEVTXReader checks the file header of file and returns a reader of its
+// records.
+func newEVTXReader(file io.ReadCloser) (*evtxReader, error) {
+	header := make([]byte, evtxHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil || !bytes.HasPrefix(header, []byte("ElfFile\x00")) {
+		return nil, errors.New("not a Windows event log: no ElfFile header")
+	}
+	return &evtxReader{file: file, chunk: make([]byte, evtxChunkSize)}, nil
+}
+
+// next decodes the following record. A record that does not decode is
+// logged and skipped, as triage copies of live logs often end mid-write.
+func (r *evtxReader) next() (map[string]interface{}, error) {
+	for {
+		for r.pos+24 <= r.end {
+			start := r.pos
+			size := int(binary.LittleEndian.Uint32(r.chunk[start+4:]))
+			if !bytes.Equal(r.chunk[start:start+4], []byte("**\x00\x00")) || size < 28 || start+size > r.end {
+				r.pos = r.end
+				break
+			}
+			r.pos += size
+			recordID := binary.LittleEndian.Uint64(r.chunk[start+8:])
+			root := &xmlNode{}
+			budget := evtxRecordBudget
+			cursor := &evtxCursor{chunk: r.chunk, pos: start + 24, end: start + size - 4, budget: &budget}
+			cursor.content(root, nil, 0)
+			if cursor.err == nil && len(root.children) == 0 {
+				cursor.err = errEVTXMalformed
+			}
+			if cursor.err != nil {
+				log.Warn().Err(cursor.err).Int("chunk", r.chunkIndex).Uint64("record_id", recordID).Msg("Skipping event record")
+				continue
+			}
+			doc := evtxDocument(root.children[0])
+			if _, ok := doc["@timestamp"]; !ok {
+				doc["@timestamp"] = evtxFileTimeString(binary.LittleEndian.Uint64(r.chunk[start+16:]))
+			}
+			if winlog, ok := doc["winlog"].(map[string]interface{}); ok && winlog["record_id"] == nil {
+				winlog["record_id"] = int64(recordID)
+			}
+			return doc, nil
+		}
+		if _, err := io.ReadFull(r.file, r.chunk); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		r.chunkIndex++
+		r.pos, r.end = evtxRecordsStart, 0
+		if !bytes.HasPrefix(r.chunk, []byte("ElfChnk\x00")) {
+			// Preallocated chunks after the last one are zero.
+			continue
+		}
+		r.end = min(int(binary.LittleEndian.Uint32(r.chunk[48:])), evtxChunkSize)
+	}
+}
+
+// close releases the underlying file.
+func (r *evtxReader) close() error {
+	return r.file.Close()
+}
+
+// evtxSystemNumbers are the System elements indexed as integers.
+var evtxSystemNumbers = map[string]string{
+	"EventID": "event_id", "Version": "version", "Level": "level", "Task": "task", "Opcode": "opcode", "EventRecordID": "record_id",
+}
+
+// evtxDocument converts an Event element to a document, with the System
+// fields under winlog as Winlogbeat names them.
+func evtxDocument(event *xmlNode) map[string]interface{} {
+	winlog := map[string]interface{}{}
+	doc := map[string]interface{}{"winlog": winlog}
+	for _, section := range event.children {
+		switch section.name {
+		case "System":
+			for _, field := range section.children {
+				text := strings.TrimSpace(field.text)
+				if name, ok := evtxSystemNumbers[field.name]; ok {
+					if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+						winlog[name] = n
+					} else if text != "" {
+						winlog[name] = text
+					}
+					continue
+				}
+				switch field.name {
+				case "Provider":
+					setAttrField(winlog, "provider_name", field, "Name")
+					setAttrField(winlog, "provider_guid", field, "Guid")
+					setAttrField(winlog, "event_source_name", field, "EventSourceName")
+				case "TimeCreated":
+					setAttrField(doc, "@timestamp", field, "SystemTime")
+				case "Keywords":
+					winlog["keywords"] = text
+				case "Correlation":
+					setAttrField(winlog, "activity_id", field, "ActivityID")
+					setAttrField(winlog, "related_activity_id", field, "RelatedActivityID")
+				case "Execution":
+					setAttrField(winlog, "process.pid", field, "ProcessID")
+					setAttrField(winlog, "process.thread.id", field, "ThreadID")
+				case "Channel":
+					winlog["channel"] = text
+				case "Computer":
+					winlog["computer_name"] = text
+				case "Security":
+					setAttrField(winlog, "user.identifier", field, "UserID")
+				}
+			}
+		case "EventData":
+			data := map[string]interface{}{}
+			for i, item := range section.children {
+				name, value := fmt.Sprintf("param%d", i+1), *item
+				value.attrs = nil
+				for _, attr := range item.attrs {
+					if attr.name == "Name" {
+						name = attr.value
+					} else {
+						value.attrs = append(value.attrs, attr)
+					}
+				}
+				data[name] = xmlNodeValue(&value)
+			}
+			winlog["event_data"] = data
+		case "UserData":
+			if len(section.children) > 0 {
+				data, _ := xmlNodeValue(section.children[0]).(map[string]interface{})
+				if data == nil {
+					data = map[string]interface{}{}
+				}
+				data["xml_name"] = section.children[0].name
+				winlog["user_data"] = data
+			}
+		case "RenderingInfo":
+			for _, field := range section.children {
+				if field.name == "Message" {
+					doc["message"] = field.text
+				}
+			}
+		}
+	}
+	return doc
+}
+
+// setAttrField sets field in doc to the attribute of node, converting
+// process and thread IDs to integers.
+func setAttrField(doc map[string]interface{}, field string, node *xmlNode, attr string) {
+	for _, a := range node.attrs {
+		if a.name != attr {
+			continue
+		}
+		if strings.HasPrefix(field, "process.") {
+			if n, err := strconv.ParseInt(a.value, 10, 64); err == nil {
+				setField(doc, field, n)
+				return
+			}
+		}
+		setField(doc, field, a.value)
+	}
+}
+
+// xmlNodeValue converts an element to its text, the items of an array, or
+// for an element with children or attributes, an object. Repeated child
+// names collect into an array.
+func xmlNodeValue(node *xmlNode) interface{} {
+	if len(node.list) > 0 {
+		items := make([]interface{}, len(node.list))
+		for i, item := range node.list {
+			items[i] = item
+		}
+		return items
+	}
+	object := map[string]interface{}{}
+	for _, attr := range node.attrs {
+		if attr.name != "xmlns" {
+			object[attr.name] = attr.value
+		}
+	}
+	if len(node.children) == 0 && len(object) == 0 {
+		return node.text
+	}
+	repeated := map[string][]interface{}{}
+	for _, child := range node.children {
+		value := xmlNodeValue(child)
+		existing, seen := object[child.name]
+		switch {
+		case !seen:
+			object[child.name] = value
+		case repeated[child.name] == nil:
+			repeated[child.name] = []interface{}{existing, value}
+		default:
+			repeated[child.name] = append(repeated[child.name], value)
+		}
+	}
+	for name, items := range repeated {
+		object[name] = items
+	}
+	return object
+}
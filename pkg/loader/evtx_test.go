@@ -0,0 +1,409 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// evtxTestChunk writes the Binary XML of a chunk, placing each name in
+// line the first time it is used, as Windows does.
+type evtxTestChunk struct {
+	buf   []byte
+	names map[string]int
+}
+
+// newEVTXTestChunk starts a chunk after its header.
+func newEVTXTestChunk() *evtxTestChunk {
+	chunk := &evtxTestChunk{buf: make([]byte, evtxRecordsStart), names: map[string]int{}}
+	copy(chunk.buf, "ElfChnk\x00")
+	return chunk
+}
+
+// raw, u16, u32, and u64 append little-endian data.
+func (c *evtxTestChunk) raw(data ...byte) { c.buf = append(c.buf, data...) }
+func (c *evtxTestChunk) u16(v uint16)     { c.buf = binary.LittleEndian.AppendUint16(c.buf, v) }
+func (c *evtxTestChunk) u32(v uint32)     { c.buf = binary.LittleEndian.AppendUint32(c.buf, v) }
+func (c *evtxTestChunk) u64(v uint64)     { c.buf = binary.LittleEndian.AppendUint64(c.buf, v) }
+
+// name writes a name reference.
+func (c *evtxTestChunk) name(name string) {
+	if offset, ok := c.names[name]; ok {
+		c.u32(uint32(offset))
+		return
+	}
+	offset := len(c.buf) + 4
+	c.names[name] = offset
+	c.u32(uint32(offset))
+	c.u32(0)
+	c.u16(0)
+	c.u16(uint16(len(name)))
+	c.raw(evtxTestUTF16(name)...)
+	c.u16(0)
+}
+
+// open writes an open start element token.
+func (c *evtxTestChunk) open(name string, attrs bool) {
+	if attrs {
+		c.raw(binXMLOpenStartElement | binXMLMoreFlag)
+	} else {
+		c.raw(binXMLOpenStartElement)
+	}
+	c.u16(0)
+	c.u32(0)
+	c.name(name)
+	if attrs {
+		c.u32(0)
+	}
+}
+
+// attr writes an attribute token; its value follows.
+func (c *evtxTestChunk) attr(name string, more bool) {
+	if more {
+		c.raw(binXMLAttribute | binXMLMoreFlag)
+	} else {
+		c.raw(binXMLAttribute)
+	}
+	c.name(name)
+}
+
+// text writes a string value token.
+func (c *evtxTestChunk) text(value string) {
+	c.raw(binXMLValue, evtxString)
+	c.u16(uint16(len(value)))
+	c.raw(evtxTestUTF16(value)...)
+}
+
+// subst writes an optional substitution token.
+func (c *evtxTestChunk) subst(id uint16, kind byte) {
+	c.raw(binXMLOptionalSubst)
+	c.u16(id)
+	c.raw(kind)
+}
+
+// element writes <name>substitution id</name>.
+func (c *evtxTestChunk) element(name string, id uint16, kind byte) {
+	c.open(name, false)
+	c.raw(binXMLCloseStartElement)
+	c.subst(id, kind)
+	c.raw(binXMLEndElement)
+}
+
+// record wraps the Binary XML written by body in an event record.
+func (c *evtxTestChunk) record(id uint64, body func()) {
+	start := len(c.buf)
+	c.raw('*', '*', 0, 0)
+	c.u32(0)
+	c.u64(id)
+	c.u64(evtxTestFileTime)
+	body()
+	size := len(c.buf) - start + 4
+	binary.LittleEndian.PutUint32(c.buf[start+4:], uint32(size))
+	c.u32(uint32(size))
+}
+
+// template writes a template instance, defining it in line the first time
+// and referring to definition otherwise, followed by values and, when
+// embedded is set, a last Binary XML value it writes in place.
+func (c *evtxTestChunk) template(definition *int, body func(), embedded func(), values ...evtxValue) {
+	c.raw(binXMLTemplateInstance, 1)
+	c.u32(7)
+	if *definition == 0 {
+		*definition = len(c.buf) + 4
+		c.u32(uint32(*definition))
+		c.u32(0)
+		c.raw(make([]byte, 16)...)
+		c.u32(0)
+		start := len(c.buf)
+		body()
+		binary.LittleEndian.PutUint32(c.buf[*definition+20:], uint32(len(c.buf)-start))
+	} else {
+		c.u32(uint32(*definition))
+	}
+	count := len(values)
+	if embedded != nil {
+		count++
+	}
+	c.u32(uint32(count))
+	for _, value := range values {
+		c.u16(uint16(len(value.data)))
+		c.raw(value.kind, 0)
+	}
+	size := len(c.buf)
+	if embedded != nil {
+		c.u16(0)
+		c.raw(evtxBinXML, 0)
+	}
+	for _, value := range values {
+		c.raw(value.data...)
+	}
+	if embedded != nil {
+		start := len(c.buf)
+		embedded()
+		binary.LittleEndian.PutUint16(c.buf[size:], uint16(len(c.buf)-start))
+	}
+}
+
+// bytes returns the chunk padded to its full size.
+func (c *evtxTestChunk) bytes() []byte {
+	binary.LittleEndian.PutUint32(c.buf[48:], uint32(len(c.buf)))
+	return append(c.buf, make([]byte, evtxChunkSize-len(c.buf))...)
+}
+
+// evtxTestUTF16 encodes s as little-endian UTF-16.
+func evtxTestUTF16(s string) []byte {
+	var out []byte
+	for _, unit := range utf16.Encode([]rune(s)) {
+		out = binary.LittleEndian.AppendUint16(out, unit)
+	}
+	return out
+}
+
+// evtxTestFileTime is 2024-05-01T08:00:00.1234567Z.
+const evtxTestFileTime = 1714550400*10000000 + 1234567 + 116444736000000000
+
+// evtxTestValue and evtxTestNumber build substitution values.
+func evtxTestValue(kind byte, data []byte) evtxValue {
+	return evtxValue{kind: kind, data: data}
+}
+
+func evtxTestNumber(kind byte, n uint64, size int) evtxValue {
+	data := binary.LittleEndian.AppendUint64(nil, n)
+	return evtxValue{kind: kind, data: data[:size]}
+}
+
+// writeTestEVTX returns an event log of a logon event from an in-line
+// template, a second one reusing it, a UserData event built from an
+// embedded Binary XML value, and a corrupt record, followed by an unused
+// chunk.
+func writeTestEVTX(t *testing.T) string {
+	t.Helper()
+
+	chunk := newEVTXTestChunk()
+	var security, cleared int
+	securityBody := func() {
+		chunk.raw(binXMLFragmentHeader, 1, 1, 0)
+		chunk.open("Event", true)
+		chunk.attr("xmlns", false)
+		chunk.text("http://schemas.microsoft.com/win/2004/08/events/event")
+		chunk.raw(binXMLCloseStartElement)
+		chunk.open("System", false)
+		chunk.raw(binXMLCloseStartElement)
+		chunk.open("Provider", true)
+		chunk.attr("Name", true)
+		chunk.subst(0, evtxString)
+		chunk.attr("Guid", false)
+		chunk.subst(1, evtxGUID)
+		chunk.raw(binXMLCloseEmptyElement)
+		chunk.element("EventID", 2, evtxUint16)
+		chunk.element("Level", 3, evtxUint8)
+		chunk.open("TimeCreated", true)
+		chunk.attr("SystemTime", false)
+		chunk.subst(4, evtxFileTime)
+		chunk.raw(binXMLCloseEmptyElement)
+		chunk.element("EventRecordID", 5, evtxUint64)
+		chunk.open("Execution", true)
+		chunk.attr("ProcessID", true)
+		chunk.subst(6, evtxUint32)
+		chunk.attr("ThreadID", false)
+		chunk.subst(7, evtxUint32)
+		chunk.raw(binXMLCloseEmptyElement)
+		chunk.element("Channel", 8, evtxString)
+		chunk.element("Computer", 9, evtxString)
+		chunk.open("Security", true)
+		chunk.attr("UserID", false)
+		chunk.subst(10, evtxSID)
+		chunk.raw(binXMLCloseEmptyElement)
+		chunk.raw(binXMLEndElement)
+		chunk.open("EventData", false)
+		chunk.raw(binXMLCloseStartElement)
+		for i, name := range []string{"TargetUserName", "LogonType"} {
+			chunk.open("Data", true)
+			chunk.attr("Name", false)
+			chunk.text(name)
+			chunk.raw(binXMLCloseStartElement)
+			chunk.subst(uint16(11+i), evtxString)
+			chunk.raw(binXMLEndElement)
+		}
+		chunk.element("Data", 13, evtxString|evtxArray)
+		chunk.raw(binXMLEndElement, binXMLEndElement, binXMLEOF)
+	}
+	guid := []byte{0x25, 0x96, 0x84, 0x54, 0x78, 0x54, 0x94, 0x49, 0xA5, 0xBA, 0x3E, 0x3B, 0x03, 0x28, 0xC3, 0x0D}
+	logon := func(id uint64, user string, sid []byte) []evtxValue {
+		return []evtxValue{
+			evtxTestValue(evtxString, evtxTestUTF16("Microsoft-Windows-Security-Auditing")),
+			evtxTestValue(evtxGUID, guid),
+			evtxTestNumber(evtxUint16, 4624, 2),
+			evtxTestNumber(evtxUint8, 0, 1),
+			evtxTestNumber(evtxFileTime, evtxTestFileTime, 8),
+			evtxTestNumber(evtxUint64, id, 8),
+			evtxTestNumber(evtxUint32, 636, 4),
+			evtxTestNumber(evtxUint32, 1234, 4),
+			evtxTestValue(evtxString, evtxTestUTF16("Security\x00")),
+			evtxTestValue(evtxString, evtxTestUTF16("DC01.corp.example")),
+			evtxTestValue(evtxSID, sid),
+			evtxTestValue(evtxString, evtxTestUTF16(user)),
+			evtxTestValue(evtxString, evtxTestUTF16("3")),
+			evtxTestValue(evtxString|evtxArray, evtxTestUTF16("a\x00b\x00")),
+		}
+	}
+	chunk.record(1001, func() {
+		chunk.raw(binXMLFragmentHeader, 1, 1, 0)
+		chunk.template(&security, securityBody, nil, logon(1001, "alice", []byte{1, 1, 0, 0, 0, 0, 0, 5, 18, 0, 0, 0})...)
+		chunk.raw(binXMLEOF)
+	})
+	chunk.record(1002, func() {
+		chunk.raw(binXMLFragmentHeader, 1, 1, 0)
+		chunk.template(&security, nil, nil, logon(1002, "bob", nil)...)
+		chunk.raw(binXMLEOF)
+	})
+	chunk.record(1003, func() {
+		chunk.raw(binXMLFragmentHeader, 1, 1, 0)
+		chunk.template(&cleared, func() {
+			chunk.raw(binXMLFragmentHeader, 1, 1, 0)
+			chunk.open("Event", false)
+			chunk.raw(binXMLCloseStartElement)
+			chunk.open("System", false)
+			chunk.raw(binXMLCloseStartElement)
+			chunk.element("EventID", 0, evtxUint16)
+			chunk.raw(binXMLEndElement)
+			chunk.subst(1, evtxBinXML)
+			chunk.raw(binXMLEndElement, binXMLEOF)
+		}, func() {
+			chunk.raw(binXMLFragmentHeader, 1, 1, 0)
+			chunk.open("UserData", false)
+			chunk.raw(binXMLCloseStartElement)
+			chunk.open("LogFileCleared", true)
+			chunk.attr("xmlns", false)
+			chunk.text("http://manifests.microsoft.com/win/2004/08/windows/eventlog")
+			chunk.raw(binXMLCloseStartElement)
+			chunk.open("SubjectUserName", false)
+			chunk.raw(binXMLCloseStartElement)
+			chunk.text("admin")
+			chunk.raw(binXMLEndElement, binXMLEndElement, binXMLEndElement, binXMLEOF)
+		}, evtxTestNumber(evtxUint16, 1102, 2))
+		chunk.raw(binXMLEOF)
+	})
+	chunk.record(1004, func() { chunk.raw(binXMLFragmentHeader, 1, 1, 0, 0xff) })
+
+	header := make([]byte, evtxHeaderSize)
+	copy(header, "ElfFile\x00")
+	var file bytes.Buffer
+	file.Write(header)
+	file.Write(chunk.bytes())
+	file.Write(make([]byte, evtxChunkSize))
+	return writeTestDataFile(t, "Security.evtx", file.String())
+}
+
+// TestEVTXReaderDecodesRecords verifies behavior for the related scenario.
+func TestEVTXReaderDecodesRecords(t *testing.T) {
+	t.Parallel()
+
+	config, err := newInputConfig(Options{})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	got, err := readTestDocuments(t, config, writeTestEVTX(t))
+	if err != nil {
+		t.Fatalf("reading returned error: %v", err)
+	}
+	want := []string{
+		`{"@timestamp":"2024-05-01T08:00:00.1234567Z","winlog":{"channel":"Security","computer_name":"DC01.corp.example","event_data":{"LogonType":"3","TargetUserName":"alice","param3":["a","b"]},"event_id":4624,"level":0,"process":{"pid":636,"thread":{"id":1234}},"provider_guid":"{54849625-5478-4994-A5BA-3E3B0328C30D}","provider_name":"Microsoft-Windows-Security-Auditing","record_id":1001,"user":{"identifier":"S-1-5-18"}}}`,
+		`{"@timestamp":"2024-05-01T08:00:00.1234567Z","winlog":{"channel":"Security","computer_name":"DC01.corp.example","event_data":{"LogonType":"3","TargetUserName":"bob","param3":["a","b"]},"event_id":4624,"level":0,"process":{"pid":636,"thread":{"id":1234}},"provider_guid":"{54849625-5478-4994-A5BA-3E3B0328C30D}","provider_name":"Microsoft-Windows-Security-Auditing","record_id":1002}}`,
+		`{"@timestamp":"2024-05-01T08:00:00.1234567Z","winlog":{"event_id":1102,"record_id":1003,"user_data":{"SubjectUserName":"admin","xml_name":"LogFileCleared"}}}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("read\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := readTestDocuments(t, config, writeTestDataFile(t, "notes.evtx", "not an event log")); err == nil || !strings.Contains(err.Error(), "no ElfFile header") {
+		t.Fatalf("expected a file without an EVTX header to fail, got %v", err)
+	}
+}
+
+// TestEVTXReaderLimitsTemplateExpansion verifies behavior for the related scenario.
+func TestEVTXReaderLimitsTemplateExpansion(t *testing.T) {
+	t.Parallel()
+
+	// Record 1 instantiates the first of 40 templates, each of which
+	// instantiates the next one twice: 2^40 elements from a few bytes.
+	const links = 40
+	chunk := newEVTXTestChunk()
+	instance := func() int {
+		chunk.raw(binXMLTemplateInstance, 1)
+		chunk.u32(7)
+		chunk.u32(0) // definition, patched below
+		chunk.u32(0) // no values
+		return len(chunk.buf) - 8
+	}
+	var refs [links + 1][]int
+	chunk.record(1, func() {
+		chunk.raw(binXMLFragmentHeader, 1, 1, 0)
+		refs[0] = append(refs[0], instance())
+		chunk.raw(binXMLEOF)
+	})
+	chunk.record(2, func() {
+		chunk.raw(binXMLFragmentHeader, 1, 1, 0)
+		chunk.open("Event", false)
+		chunk.raw(binXMLCloseStartElement)
+		chunk.element("EventID", 0, evtxUint16)
+		chunk.raw(binXMLEndElement, binXMLEOF)
+	})
+	for i := range links {
+		for _, ref := range refs[i] {
+			binary.LittleEndian.PutUint32(chunk.buf[ref:], uint32(len(chunk.buf)))
+		}
+		definition := len(chunk.buf)
+		chunk.raw(make([]byte, 24)...)
+		if i+1 < links {
+			refs[i+1] = append(refs[i+1], instance(), instance())
+		} else {
+			chunk.open("Event", false)
+			chunk.raw(binXMLCloseEmptyElement)
+		}
+		chunk.raw(binXMLEOF)
+		binary.LittleEndian.PutUint32(chunk.buf[definition+20:], uint32(len(chunk.buf)-definition-24))
+	}
+	header := make([]byte, evtxHeaderSize)
+	copy(header, "ElfFile\x00")
+	path := writeTestDataFile(t, "bomb.evtx", string(header)+string(chunk.bytes()))
+
+	config, err := newInputConfig(Options{})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	started := time.Now()
+	got, err := readTestDocuments(t, config, path)
+	if err != nil {
+		t.Fatalf("reading returned error: %v", err)
+	}
+	if len(got) != 1 || !strings.Contains(got[0], `"record_id":2`) {
+		t.Fatalf("expected the expanding record to be skipped and the next one read, got %v", got)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("expected the expanding record to fail fast, took %s", elapsed)
+	}
+}
+
+// TestRunEVTXDataFile verifies behavior for the related scenario.
+func TestRunEVTXDataFile(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   writeTestEVTX(t),
+		AddToIndex: true,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if sent := strings.Join(bodies(), ""); result.DocumentsProcessed != 3 || !strings.Contains(sent, `"event_id":4624`) || !strings.Contains(sent, `"xml_name":"LogFileCleared"`) {
+		t.Fatalf("unexpected result %+v and bulk bodies %s", result, sent)
+	}
+}
@@ -0,0 +1,459 @@
+package loader
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ─── Document Filter Expressions ───────────────────────────────────────────────
+
+// filterNode is one node of a parsed -filter expression.
+type filterNode interface {
+	eval(doc map[string]interface{}) interface{}
+}
+
+// filterLiteral is a string, number, boolean, or null constant.
+type filterLiteral struct{ value interface{} }
+
+// filterField reads a dot-notation path; a missing field evaluates to null.
+type filterField struct{ path string }
+
+// filterNot negates the truthiness of its operand.
+type filterNot struct{ operand filterNode }
+
+// filterLogical is a short-circuit && or ||.
+type filterLogical struct {
+	op          string
+	left, right filterNode
+}
+
+// filterCompare is a binary comparison or regular-expression match.
+type filterCompare struct {
+	op          string
+	left, right filterNode
+	// pattern is compiled at parse time for =~ and !~.
+	pattern *regexp.Regexp
+}
+
+// eval applies method-specific behavior to keep package workflows consistent.
+func (n filterLiteral) eval(map[string]interface{}) interface{} { return n.value }
+
+// eval applies method-specific behavior to keep package workflows consistent.
+func (n filterField) eval(doc map[string]interface{}) interface{} {
+	value, _ := lookupField(doc, n.path)
+	return value
+}
+
+// eval applies method-specific behavior to keep package workflows consistent.
+func (n filterNot) eval(doc map[string]interface{}) interface{} {
+	return !filterTruthy(n.operand.eval(doc))
+}
+
+// eval applies method-specific behavior to keep package workflows consistent.
+func (n filterLogical) eval(doc map[string]interface{}) interface{} {
+	left := filterTruthy(n.left.eval(doc))
+	if n.op == "&&" {
+		return left && filterTruthy(n.right.eval(doc))
+	}
+	return left || filterTruthy(n.right.eval(doc))
+}
+
+// eval applies method-specific behavior to keep package workflows consistent.
+func (n filterCompare) eval(doc map[string]interface{}) interface{} {
+	left := n.left.eval(doc)
+	if n.pattern != nil {
+		text, ok := left.(string)
+		matched := ok && n.pattern.MatchString(text)
+		return matched == (n.op == "=~")
+	}
+	right := n.right.eval(doc)
+	switch n.op {
+	case "==":
+		return filterEqual(left, right)
+	case "!=":
+		return !filterEqual(left, right)
+	}
+	order, ok := filterOrder(left, right)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	default:
+		return order >= 0
+	}
+}
+
+// filterTruthy treats null, false, zero, and empty strings, arrays, and objects
+// as false.
+func filterTruthy(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return typed
+	case float64, json.Number, int64, int:
+		number, _ := numberValue(typed)
+		return number != 0
+	case string:
+		return typed != ""
+	case []interface{}:
+		return len(typed) > 0
+	case map[string]interface{}:
+		return len(typed) > 0
+	}
+	return true
+}
+
+// filterNumber returns value as a number, accepting numeric strings so CSV
+// columns compare naturally against numeric literals.
+func filterNumber(value interface{}) (float64, bool) {
+	if text, ok := value.(string); ok {
+		number, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		return number, err == nil
+	}
+	return numberValue(value)
+}
+
+// filterInteger returns value as an exact integer, accepting integer strings,
+// so 64-bit IDs compare without float64 rounding.
+func filterInteger(value interface{}) (int64, bool) {
+	if text, ok := value.(string); ok {
+		number, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		return number, err == nil
+	}
+	return integerValue(value)
+}
+
+// filterEqual compares two values; a number equals a numeric string with the
+// same value.
+func filterEqual(left, right interface{}) bool {
+	if isNumber(left) || isNumber(right) {
+		if l, lok := filterInteger(left); lok {
+			if r, rok := filterInteger(right); rok {
+				return l == r
+			}
+		}
+		l, lok := filterNumber(left)
+		r, rok := filterNumber(right)
+		return lok && rok && l == r
+	}
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case string:
+		r, ok := right.(string)
+		return ok && l == r
+	case bool:
+		r, ok := right.(bool)
+		return ok && l == r
+	}
+	return false
+}
+
+// filterOrder compares numbers numerically (when either side is a number) and
+// strings lexicographically; other combinations are unordered.
+func filterOrder(left, right interface{}) (int, bool) {
+	if isNumber(left) || isNumber(right) {
+		if l, lok := filterInteger(left); lok {
+			if r, rok := filterInteger(right); rok {
+				return cmp.Compare(l, r), true
+			}
+		}
+		l, lok := filterNumber(left)
+		r, rok := filterNumber(right)
+		if !lok || !rok {
+			return 0, false
+		}
+		switch {
+		case l < r:
+			return -1, true
+		case l > r:
+			return 1, true
+		}
+		return 0, true
+	}
+	l, lok := left.(string)
+	r, rok := right.(string)
+	if !lok || !rok {
+		return 0, false
+	}
+	return strings.Compare(l, r), true
+}
+
+// ─── Filter Parsing ────────────────────────────────────────────────────────────
+
+// filterToken is a lexed piece of a filter expression.
+type filterToken struct {
+	kind  string // "op", "ident", "string", "number", or "eof"
+	text  string
+	value interface{}
+	pos   int
+}
+
+// filterParser is a recursive-descent parser over lexed tokens.
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// parseFilterExpression parses expressions such as
+// `status == "active" && amount > 0`. Supported: field paths (dot notation,
+// or `backquoted` for names with spaces), string/number/true/false/null
+// literals, == != < <= > >=, =~ and !~ against a regex string literal, !, &&,
+// ||, and parentheses.
+func parseFilterExpression(expr string) (filterNode, error) {
+	tokens, err := lexFilterExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	parser := &filterParser{tokens: tokens}
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := parser.peek(); next.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q at position %d", next.text, next.pos+1)
+	}
+	return node, nil
+}
+
+// peek returns the current token without consuming it.
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+// next consumes and returns the current token.
+func (p *filterParser) next() filterToken {
+	token := p.tokens[p.pos]
+	if token.kind != "eof" {
+		p.pos++
+	}
+	return token
+}
+
+// parseOr parses `and ("||" and)*`.
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "op" && p.peek().text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterLogical{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd parses `unary ("&&" unary)*`.
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "op" && p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = filterLogical{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseUnary parses `"!" unary | comparison`.
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.peek().kind == "op" && p.peek().text == "!" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses `primary (compareOp primary)?`.
+func (p *filterParser) parseComparison() (filterNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	token := p.peek()
+	if token.kind != "op" {
+		return left, nil
+	}
+	switch token.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return filterCompare{op: token.text, left: left, right: right}, nil
+	case "=~", "!~":
+		p.next()
+		patternToken := p.next()
+		if patternToken.kind != "string" {
+			return nil, fmt.Errorf("%s at position %d must be followed by a string pattern", token.text, token.pos+1)
+		}
+		pattern, err := regexp.Compile(patternToken.value.(string))
+		if err != nil {
+			return nil, fmt.Errorf("pattern at position %d: %w", patternToken.pos+1, err)
+		}
+		return filterCompare{op: token.text, left: left, pattern: pattern}, nil
+	}
+	return left, nil
+}
+
+// parsePrimary parses a literal, a field, or a parenthesized expression.
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	token := p.next()
+	switch token.kind {
+	case "string", "number":
+		return filterLiteral{value: token.value}, nil
+	case "ident":
+		switch token.text {
+		case "true":
+			return filterLiteral{value: true}, nil
+		case "false":
+			return filterLiteral{value: false}, nil
+		case "null":
+			return filterLiteral{value: nil}, nil
+		}
+		return filterField{path: token.value.(string)}, nil
+	case "op":
+		if token.text == "(" {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if closing := p.next(); closing.kind != "op" || closing.text != ")" {
+				return nil, fmt.Errorf("missing ) for ( at position %d", token.pos+1)
+			}
+			return node, nil
+		}
+	case "eof":
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", token.text, token.pos+1)
+}
+
+// filterOperators lists operators longest first so "<=" wins over "<".
+var filterOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")"}
+
+// lexFilterExpression splits expr into tokens.
+func lexFilterExpression(expr string) ([]filterToken, error) {
+	tokens := make([]filterToken, 0)
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			text, value, err := lexFilterString(expr[i:], byte(c))
+			if err != nil {
+				return nil, fmt.Errorf("string at position %d: %w", i+1, err)
+			}
+			tokens = append(tokens, filterToken{kind: "string", text: text, value: value, pos: i})
+			i += len(text)
+		case c == '`':
+			end := strings.IndexByte(expr[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated field name at position %d", i+1)
+			}
+			name := expr[i+1 : i+1+end]
+			tokens = append(tokens, filterToken{kind: "ident", text: expr[i : i+end+2], value: name, pos: i})
+			i += end + 2
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(expr) && unicode.IsDigit(rune(expr[i+1]))):
+			start := i
+			i++
+			for i < len(expr) && (unicode.IsDigit(rune(expr[i])) || strings.ContainsRune(".eE+-", rune(expr[i]))) {
+				if (expr[i] == '+' || expr[i] == '-') && expr[i-1] != 'e' && expr[i-1] != 'E' {
+					break
+				}
+				i++
+			}
+			if _, err := strconv.ParseFloat(expr[start:i], 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", expr[start:i], start+1)
+			}
+			tokens = append(tokens, filterToken{kind: "number", text: expr[start:i], value: json.Number(expr[start:i]), pos: start})
+		case unicode.IsLetter(c) || c == '_' || c == '@':
+			start := i
+			for i < len(expr) && (unicode.IsLetter(rune(expr[i])) || unicode.IsDigit(rune(expr[i])) || strings.ContainsRune("_.@", rune(expr[i]))) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: "ident", text: expr[start:i], value: expr[start:i], pos: start})
+		default:
+			matched := ""
+			for _, op := range filterOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					matched = op
+					break
+				}
+			}
+			if matched == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+			}
+			tokens = append(tokens, filterToken{kind: "op", text: matched, pos: i})
+			i += len(matched)
+		}
+	}
+	return append(tokens, filterToken{kind: "eof", pos: len(expr)}), nil
+}
+
+// lexFilterString reads a quoted string starting at input[0], returning the
+// raw token text and its unescaped value. Only \\ and an escaped quote are
+// unescaped, so regex classes such as \d pass through unchanged.
+func lexFilterString(input string, quote byte) (string, string, error) {
+	var value strings.Builder
+	for i := 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			if i+1 >= len(input) {
+				return "", "", fmt.Errorf("unterminated escape")
+			}
+			if input[i+1] != '\\' && input[i+1] != quote {
+				value.WriteByte('\\')
+				continue
+			}
+			i++
+			value.WriteByte(input[i])
+		case quote:
+			return input[:i+1], value.String(), nil
+		default:
+			value.WriteByte(input[i])
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+// newFilterTransform drops documents for which expr is not truthy.
+func newFilterTransform(expr string) (docTransform, error) {
+	node, err := parseFilterExpression(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing -filter: %w", err)
+	}
+	return func(doc map[string]interface{}) ([]map[string]interface{}, error) {
+		if !filterTruthy(node.eval(doc)) {
+			return nil, nil
+		}
+		return []map[string]interface{}{doc}, nil
+	}, nil
+}
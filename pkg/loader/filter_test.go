@@ -0,0 +1,110 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseFilterExpressionEvaluates verifies behavior for the related scenario.
+func TestParseFilterExpressionEvaluates(t *testing.T) {
+	t.Parallel()
+
+	const doc = `{"status":"active","amount":12.5,"count":"7","tags":[],"user":{"name":"Ada","email":"ada@example.com"},"First Name":"Ada","deleted":false,"note":null,"id":9007199254740993}`
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: `status == "active" && amount > 0`, want: true},
+		{expr: `status == 'inactive' || amount >= 12.5`, want: true},
+		{expr: `amount < 10`, want: false},
+		{expr: `count == 7 && count > 6.5`, want: true},
+		{expr: `user.name == "Ada"`, want: true},
+		{expr: "`First Name` == \"Ada\"", want: true},
+		{expr: `user.email =~ "@example\.com$"`, want: true},
+		{expr: `user.email !~ "^ada"`, want: false},
+		{expr: `!deleted && !note && !tags`, want: true},
+		{expr: `missing == null && note == null`, want: true},
+		{expr: `missing > 0`, want: false},
+		{expr: `status != "active" || (amount > 100 && status == "active")`, want: false},
+		{expr: `amount > -1e3`, want: true},
+		{expr: `status`, want: true},
+		{expr: `status == 1`, want: false},
+		{expr: `"b" > "a"`, want: true},
+		{expr: `'it\'s' == "it's"`, want: true},
+		{expr: `id == 9007199254740993 && id != 9007199254740992`, want: true},
+		{expr: `id > 9007199254740992 && id < 9007199254740994`, want: true},
+		{expr: `id == "9007199254740993"`, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			node, err := parseFilterExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("parseFilterExpression returned error: %v", err)
+			}
+			if got := filterTruthy(node.eval(decodeTestDocument(t, doc))); got != tt.want {
+				t.Fatalf("%s evaluated to %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseFilterExpressionRejectsInvalidInput verifies behavior for the related scenario.
+func TestParseFilterExpressionRejectsInvalidInput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: ``, want: "unexpected end"},
+		{expr: `status ==`, want: "unexpected end"},
+		{expr: `(status == "a"`, want: "missing )"},
+		{expr: `status == "a" extra`, want: `unexpected "extra"`},
+		{expr: `status == "unterminated`, want: "unterminated string"},
+		{expr: `name =~ status`, want: "string pattern"},
+		{expr: `name =~ "("`, want: "pattern"},
+		{expr: `amount # 3`, want: "unexpected character"},
+		{expr: "`open", want: "unterminated field name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			if _, err := parseFilterExpression(tt.expr); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("parseFilterExpression(%q) error = %v, want it to mention %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestRunFilterCountsSkippedDocuments verifies behavior for the related scenario.
+func TestRunFilterCountsSkippedDocuments(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	dataFile := filepath.Join(t.TempDir(), "orders.json")
+	content := `[{"id":"1","status":"active","amount":5},{"id":"2","status":"closed","amount":5},{"id":"3","status":"active","amount":0}]`
+	if err := os.WriteFile(dataFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   dataFile,
+		AddToIndex: true,
+		Filter:     `status == "active" && amount > 0`,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsProcessed != 1 || result.DocumentsSkipped != 2 {
+		t.Fatalf("expected 1 processed and 2 skipped, got %+v", result)
+	}
+	sent := bodies()
+	if len(sent) != 1 || !strings.Contains(sent[0], `"id":"1"`) || strings.Contains(sent[0], `"id":"2"`) {
+		t.Fatalf("unexpected bulk bodies: %q", sent)
+	}
+}
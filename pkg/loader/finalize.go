@@ -0,0 +1,51 @@
+package loader
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Finalize Blocks ───────────────────────────────────────────────────────────
+
+// finalizeBlocks maps Options.Finalize values to index blocks: read-only
+// also refuses settings and mapping changes, write-block only documents.
+var finalizeBlocks = map[string]string{
+	"read-only":   "read_only",
+	"write-block": "write",
+}
+
+// validateFinalize rejects unknown -finalize values and runs that load
+// nothing to finalize.
+func validateFinalize(opts Options, action dataAction) error {
+	if opts.Finalize == "" {
+		return nil
+	}
+	if _, ok := finalizeBlocks[opts.Finalize]; !ok {
+		return fmt.Errorf("-finalize must be read-only or write-block, got %q", opts.Finalize)
+	}
+	if !action.requiresDataFile() {
+		return fmt.Errorf("-finalize blocks the index after a load and needs -add, -flush, or -delete")
+	}
+	return nil
+}
+
+// finalizeIndices adds the -finalize block to indices once the load has
+// finished without failed documents.
+func finalizeIndices(es *elasticsearch.Client, indices []string, finalize string) error {
+	block := finalizeBlocks[finalize]
+	res, err := es.Indices.AddBlock(indices, block)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("adding %s block: status %d: %s", block, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	log.Info().Strs("indices", indices).Str("block", block).Msg("Index blocked after load")
+	return nil
+}
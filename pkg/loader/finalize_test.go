@@ -0,0 +1,97 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestValidateFinalize verifies behavior for the related scenario.
+func TestValidateFinalize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		finalize string
+		action   dataAction
+		wantErr  string
+	}{
+		{name: "off", action: dataActionNone},
+		{name: "read-only", finalize: "read-only", action: dataActionDelete},
+		{name: "write-block", finalize: "write-block", action: dataActionAdd},
+		{name: "unknown", finalize: "frozen", action: dataActionAdd, wantErr: "must be read-only or write-block"},
+		{name: "no load", finalize: "read-only", action: dataActionNone, wantErr: "needs -add"},
+	}
+	for _, tt := range tests {
+		err := validateFinalize(Options{Finalize: tt.finalize}, tt.action)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// TestRunFinalizeBlocksOnlyCompleteLoads verifies behavior for the related scenario.
+func TestRunFinalizeBlocksOnlyCompleteLoads(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		bulk       string
+		wantBlocks []string
+	}{
+		{
+			name:       "complete",
+			bulk:       `{"errors":false,"items":[{"index":{"_index":"logs","status":201}}]}`,
+			wantBlocks: []string{"/logs/_block/read_only"},
+		},
+		{
+			name: "failed document",
+			bulk: `{"errors":true,"items":[{"index":{"_index":"logs","status":400,"error":{"type":"mapper_parsing_exception","reason":"bad"}}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		var mu sync.Mutex
+		var blocks []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Elastic-Product", "Elasticsearch")
+			switch {
+			case r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/_block/"):
+				mu.Lock()
+				blocks = append(blocks, r.URL.Path)
+				mu.Unlock()
+				_, _ = w.Write([]byte(`{"acknowledged":true}`))
+			case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+				_, _ = w.Write([]byte(tt.bulk))
+			default:
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{}`))
+			}
+		}))
+		result, err := Run(context.Background(), Options{
+			URL:        server.URL,
+			Index:      "logs",
+			DataFile:   writeTestDataFile(t, "logs.ndjson", "{\"message\":\"a\"}\n"),
+			AddToIndex: true,
+			Finalize:   "read-only",
+		})
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		if strings.Join(blocks, ",") != strings.Join(tt.wantBlocks, ",") {
+			t.Fatalf("%s: expected blocks %v, got %v", tt.name, tt.wantBlocks, blocks)
+		}
+		if len(result.FinalizedIndices) != len(tt.wantBlocks) {
+			t.Fatalf("%s: expected %d finalized indices, got %v", tt.name, len(tt.wantBlocks), result.FinalizedIndices)
+		}
+	}
+}
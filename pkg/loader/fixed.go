@@ -0,0 +1,138 @@
+package loader
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ─── Fixed-Width Input ─────────────────────────────────────────────────────────
+
+// fixedLayout describes a fixed-width record: how many leading lines to skip
+// and where each field sits on a line.
+type fixedLayout struct {
+	Skip   int          `json:"skip"`
+	Fields []fixedField `json:"fields"`
+}
+
+// fixedField is one field of a fixed-width record, starting at the 1-based
+// character Start and running for Length characters.
+type fixedField struct {
+	Field  string `json:"field"`
+	Start  int    `json:"start"`
+	Length int    `json:"length"`
+	Type   string `json:"type"`
+}
+
+// loadFixedLayout reads and validates a -layout file.
+func loadFixedLayout(path string) (*fixedLayout, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading -layout: %w", err)
+	}
+	var layout fixedLayout
+	decoder := json.NewDecoder(strings.NewReader(string(content)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&layout); err != nil {
+		return nil, fmt.Errorf("parsing -layout %s: %w", path, err)
+	}
+	if err := layout.validate(); err != nil {
+		return nil, fmt.Errorf("-layout %s: %w", path, err)
+	}
+	return &layout, nil
+}
+
+// validate checks every field, defaulting the type to string.
+func (l *fixedLayout) validate() error {
+	if l.Skip < 0 {
+		return fmt.Errorf("skip must not be negative")
+	}
+	if len(l.Fields) == 0 {
+		return fmt.Errorf("fields must list at least one field")
+	}
+	for i := range l.Fields {
+		field := &l.Fields[i]
+		if field.Field == "" {
+			return fmt.Errorf("field %d needs a field name", i+1)
+		}
+		if field.Start < 1 || field.Length < 1 {
+			return fmt.Errorf("field %q needs a start and length of 1 or more", field.Field)
+		}
+		if field.Type == "" {
+			field.Type = columnTypeString
+		}
+		if !validColumnType(field.Type) {
+			return fmt.Errorf("field %q has unknown type %q (use string, integer, float, boolean, or json)", field.Field, field.Type)
+		}
+	}
+	return nil
+}
+
+// fixedReader slices fixed-width lines into documents.
+type fixedReader struct {
+	file   io.ReadCloser
+	reader *bufio.Reader
+	layout *fixedLayout
+	line   int
+}
+
+// newFixedReader skips the layout's leading lines.
+func newFixedReader(file io.ReadCloser, layout *fixedLayout) (*fixedReader, error) {
+	r := &fixedReader{file: file, reader: bufio.NewReader(file), layout: layout}
+	for r.line < layout.Skip {
+		if _, err := r.readLine(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// readLine returns the following line without its line ending.
+func (r *fixedReader) readLine() (string, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+	r.line++
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// next converts the following non-blank line. String fields are trimmed of
+// their padding; fields past the end of a short line are left out.
+func (r *fixedReader) next() (map[string]interface{}, error) {
+	for {
+		text, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		line := []rune(text)
+		doc := make(map[string]interface{}, len(r.layout.Fields))
+		for _, field := range r.layout.Fields {
+			start := field.Start - 1
+			if start >= len(line) {
+				continue
+			}
+			end := min(start+field.Length, len(line))
+			value, err := convertTypedCell(strings.TrimSpace(string(line[start:end])), field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("line %d, field %q: %w", r.line, field.Field, err)
+			}
+			setField(doc, field.Field, value)
+		}
+		return doc, nil
+	}
+}
+
+// close releases the underlying file.
+func (r *fixedReader) close() error {
+	return r.file.Close()
+}
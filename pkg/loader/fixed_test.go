@@ -0,0 +1,99 @@
+package loader
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestFixedLayoutValidation verifies behavior for the related scenario.
+func TestFixedLayoutValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "no fields", content: `{"skip":1}`, want: "at least one field"},
+		{name: "negative skip", content: `{"skip":-1,"fields":[{"field":"id","start":1,"length":2}]}`, want: "must not be negative"},
+		{name: "missing name", content: `{"fields":[{"start":1,"length":2}]}`, want: "needs a field name"},
+		{name: "zero start", content: `{"fields":[{"field":"id","length":2}]}`, want: "start and length"},
+		{name: "bad type", content: `{"fields":[{"field":"id","start":1,"length":2,"type":"packed"}]}`, want: "unknown type"},
+		{name: "unknown key", content: `{"fields":[{"field":"id","start":1,"width":2}]}`, want: "unknown field"},
+	}
+	for _, tt := range tests {
+		if _, err := loadFixedLayout(writeTestDataFile(t, "layout.json", tt.content)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error mentioning %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	bad := []Options{
+		{Format: formatFixed},
+		{Format: formatCSV, Layout: "layout.json"},
+		{ColumnMap: writeTestDataFile(t, "map.json", `{}`), Layout: "layout.json"},
+	}
+	for _, opts := range bad {
+		if _, err := newInputConfig(opts); err == nil {
+			t.Fatalf("expected newInputConfig(%+v) to fail", opts)
+		}
+	}
+}
+
+// TestFixedReader verifies behavior for the related scenario.
+func TestFixedReader(t *testing.T) {
+	t.Parallel()
+
+	config, err := newInputConfig(Options{Layout: writeTestDataFile(t, "layout.json", `{
+		"skip": 1,
+		"fields": [
+			{"field": "account", "start": 1, "length": 6},
+			{"field": "balance", "start": 7, "length": 8, "type": "float"},
+			{"field": "owner.name", "start": 15, "length": 10}
+		]
+	}`)})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	content := "ACCT  BALANCE OWNER\r\n" +
+		"A00001  120.50Zoë Smith \r\n" +
+		"\r\n" +
+		"A00002   -3.00\n" +
+		"A00003      xx"
+	got, err := readTestDocuments(t, config, writeTestDataFile(t, "accounts.dat", content))
+	if err == nil || !strings.Contains(err.Error(), `line 5, field "balance"`) {
+		t.Fatalf("expected a line 5 balance error, got %v", err)
+	}
+	want := []string{
+		`{"account":"A00001","balance":120.5,"owner":{"name":"Zoë Smith"}}`,
+		`{"account":"A00002","balance":-3}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("read %q, want %q", got, want)
+	}
+}
+
+// TestRunFixedDataFile verifies behavior for the related scenario.
+func TestRunFixedDataFile(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   writeTestDataFile(t, "cards.txt", "001gold  \n002silver\n"),
+		AddToIndex: true,
+		Format:     formatFixed,
+		Layout:     writeTestDataFile(t, "layout.json", `{"fields":[{"field":"id","start":1,"length":3,"type":"integer"},{"field":"tier","start":4,"length":6}]}`),
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsProcessed != 2 {
+		t.Fatalf("expected 2 processed documents, got %+v", result)
+	}
+	sent := strings.Join(bodies(), "")
+	if !strings.Contains(sent, `"id":1`) || !strings.Contains(sent, `"tier":"silver"`) || strings.Contains(sent, `"gold  "`) {
+		t.Fatalf("unexpected bulk bodies: %s", sent)
+	}
+}
@@ -0,0 +1,420 @@
+package loader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ─── Synthetic Data Generator ──────────────────────────────────────────────────
+
+// GenerateOptions configures Generate.
+type GenerateOptions struct {
+	// SpecFile is a JSON object mapping each field name to its field spec;
+	// see fieldSpec for the types.
+	SpecFile string
+	// Count is how many documents to write.
+	Count int
+	// Seed makes the output repeatable; 0 picks a random seed.
+	Seed uint64
+	// Output receives one NDJSON document per line; defaults to os.Stdout.
+	Output io.Writer
+}
+
+// fieldSpec describes how one generated field is filled.
+//
+//   - name, first_name, last_name, email (at Domain), word, sentence
+//   - string: Length random lowercase letters (default 8)
+//   - uuid, ip (IPv4), boolean (true with probability P, default 0.5)
+//   - integer and float: uniform between Min and Max, or with
+//     Distribution normal (Mean, StdDev) or exponential (Mean), clamped to
+//     Min and Max when set
+//   - timestamp: uniform between From and To (RFC 3339), written as
+//     RFC 3339 or with Format epoch_millis
+//   - choice: one of Values, optionally by Weights
+//   - sequence: Start, Start+1, ... in document order
+//   - object: nested Fields; array: MinItems to MaxItems of Items
+//
+// Null is the probability that the field is null instead.
+type fieldSpec struct {
+	Type         string                `json:"type"`
+	Min          *float64              `json:"min"`
+	Max          *float64              `json:"max"`
+	Distribution string                `json:"distribution"`
+	Mean         float64               `json:"mean"`
+	StdDev       float64               `json:"stddev"`
+	From         string                `json:"from"`
+	To           string                `json:"to"`
+	Format       string                `json:"format"`
+	Values       []json.RawMessage     `json:"values"`
+	Weights      []float64             `json:"weights"`
+	Domain       string                `json:"domain"`
+	Length       int                   `json:"length"`
+	P            *float64              `json:"p"`
+	Start        int64                 `json:"start"`
+	Fields       map[string]*fieldSpec `json:"fields"`
+	Items        *fieldSpec            `json:"items"`
+	MinItems     int                   `json:"min_items"`
+	MaxItems     int                   `json:"max_items"`
+	Null         float64               `json:"null"`
+}
+
+// valueGenerator returns one field value for the n-th document, counted
+// from 0.
+type valueGenerator func(r *rand.Rand, n int) interface{}
+
+// namedGenerator fills one field of an object.
+type namedGenerator struct {
+	name     string
+	generate valueGenerator
+}
+
+var (
+	// generatorFirstNames and generatorLastNames feed the name types.
+	generatorFirstNames = []string{"Ada", "Alan", "Barbara", "Claude", "Dennis", "Edsger", "Frances", "Grace", "Hedy", "Ivan", "Joan", "Ken", "Linus", "Margaret", "Niklaus", "Radia", "Shafi", "Tim"}
+	generatorLastNames  = []string{"Allen", "Backus", "Cerf", "Dijkstra", "Engelbart", "Hamilton", "Hopper", "Kay", "Knuth", "Lamarr", "Liskov", "Lovelace", "Perlman", "Ritchie", "Shannon", "Thompson", "Turing", "Wirth"}
+	// generatorWords feeds word and sentence.
+	generatorWords = []string{"alpha", "bulk", "cluster", "data", "event", "field", "index", "join", "kernel", "log", "metric", "node", "order", "query", "record", "shard", "token", "value", "window", "zone"}
+)
+
+// Generate writes Count documents built from the field spec in SpecFile to
+// Output as NDJSON, for loading with -format ndjson or saving to disk. It
+// returns how many documents it wrote.
+func Generate(ctx context.Context, opts GenerateOptions) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Count < 0 {
+		return 0, &RunError{Kind: ErrInvalidOptions, Op: "validating generate options", Err: fmt.Errorf("-generate-count must be 0 or greater")}
+	}
+	fields, err := loadGenerateSpec(opts.SpecFile)
+	if err != nil {
+		return 0, &RunError{Kind: ErrInvalidOptions, Op: "reading generate spec", Err: err}
+	}
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	r := rand.New(rand.NewPCG(seed, seed))
+
+	writer := bufio.NewWriter(out)
+	encoder := json.NewEncoder(writer)
+	for n := 0; n < opts.Count; n++ {
+		if n%1000 == 0 && ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		if err := encoder.Encode(generateObject(fields, r, n)); err != nil {
+			return n, &RunError{Kind: ErrLoaderExecution, Op: "writing generated documents", Err: err}
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return opts.Count, &RunError{Kind: ErrLoaderExecution, Op: "writing generated documents", Err: err}
+	}
+	return opts.Count, nil
+}
+
+// loadGenerateSpec reads and compiles the field spec in path.
+func loadGenerateSpec(path string) ([]namedGenerator, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("-generate-spec is required")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec map[string]*fieldSpec
+	if err := json.Unmarshal(content, &spec); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(spec) == 0 {
+		return nil, fmt.Errorf("%s declares no fields", path)
+	}
+	return compileFields(spec, "")
+}
+
+// compileFields compiles every field of an object spec in name order, so a
+// seed always consumes random numbers the same way.
+func compileFields(spec map[string]*fieldSpec, prefix string) ([]namedGenerator, error) {
+	names := make([]string, 0, len(spec))
+	for name := range spec {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]namedGenerator, 0, len(names))
+	for _, name := range names {
+		generate, err := compileField(spec[name], prefix+name)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, namedGenerator{name: name, generate: generate})
+	}
+	return fields, nil
+}
+
+// compileField turns one field spec into its generator.
+func compileField(spec *fieldSpec, path string) (valueGenerator, error) {
+	if spec == nil {
+		return nil, fmt.Errorf("field %q needs a spec object", path)
+	}
+	generate, err := compileValue(spec, path)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Null < 0 || spec.Null > 1 {
+		return nil, fmt.Errorf("field %q: null must be between 0 and 1", path)
+	}
+	if spec.Null == 0 {
+		return generate, nil
+	}
+	null := spec.Null
+	return func(r *rand.Rand, n int) interface{} {
+		if r.Float64() < null {
+			return nil
+		}
+		return generate(r, n)
+	}, nil
+}
+
+// compileValue builds the generator for spec.Type.
+func compileValue(spec *fieldSpec, path string) (valueGenerator, error) {
+	pick := func(values []string) valueGenerator {
+		return func(r *rand.Rand, _ int) interface{} { return values[r.IntN(len(values))] }
+	}
+	switch spec.Type {
+	case "first_name":
+		return pick(generatorFirstNames), nil
+	case "last_name":
+		return pick(generatorLastNames), nil
+	case "name":
+		return func(r *rand.Rand, _ int) interface{} {
+			return generatorFirstNames[r.IntN(len(generatorFirstNames))] + " " + generatorLastNames[r.IntN(len(generatorLastNames))]
+		}, nil
+	case "email":
+		domain := spec.Domain
+		if domain == "" {
+			domain = "example.com"
+		}
+		return func(r *rand.Rand, n int) interface{} {
+			first := generatorFirstNames[r.IntN(len(generatorFirstNames))]
+			last := generatorLastNames[r.IntN(len(generatorLastNames))]
+			return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(first), strings.ToLower(last), n, domain)
+		}, nil
+	case "word":
+		return pick(generatorWords), nil
+	case "sentence":
+		return func(r *rand.Rand, _ int) interface{} {
+			words := make([]string, 4+r.IntN(8))
+			for i := range words {
+				words[i] = generatorWords[r.IntN(len(generatorWords))]
+			}
+			sentence := strings.Join(words, " ")
+			return strings.ToUpper(sentence[:1]) + sentence[1:] + "."
+		}, nil
+	case "string":
+		length := spec.Length
+		if length <= 0 {
+			length = 8
+		}
+		return func(r *rand.Rand, _ int) interface{} {
+			text := make([]byte, length)
+			for i := range text {
+				text[i] = byte('a' + r.IntN(26))
+			}
+			return string(text)
+		}, nil
+	case "uuid":
+		return func(r *rand.Rand, _ int) interface{} {
+			var id uuid.UUID
+			for i := range id {
+				id[i] = byte(r.Uint32())
+			}
+			id[6] = id[6]&0x0f | 0x40
+			id[8] = id[8]&0x3f | 0x80
+			return id.String()
+		}, nil
+	case "ip":
+		return func(r *rand.Rand, _ int) interface{} {
+			v := r.Uint32()
+			return netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}).String()
+		}, nil
+	case "boolean":
+		p := 0.5
+		if spec.P != nil {
+			p = *spec.P
+		}
+		if p < 0 || p > 1 {
+			return nil, fmt.Errorf("field %q: p must be between 0 and 1", path)
+		}
+		return func(r *rand.Rand, _ int) interface{} { return r.Float64() < p }, nil
+	case "integer", "float":
+		number, err := compileNumber(spec, path)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Type == "float" {
+			return func(r *rand.Rand, _ int) interface{} { return number(r) }, nil
+		}
+		return func(r *rand.Rand, _ int) interface{} { return int64(math.Round(number(r))) }, nil
+	case "timestamp":
+		return compileTimestamp(spec, path)
+	case "choice":
+		return compileChoice(spec, path)
+	case "sequence":
+		start := spec.Start
+		return func(_ *rand.Rand, n int) interface{} { return start + int64(n) }, nil
+	case "object":
+		fields, err := compileFields(spec.Fields, path+".")
+		if err != nil {
+			return nil, err
+		}
+		return func(r *rand.Rand, n int) interface{} { return generateObject(fields, r, n) }, nil
+	case "array":
+		item, err := compileField(spec.Items, path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		minItems, maxItems := spec.MinItems, spec.MaxItems
+		if maxItems == 0 {
+			maxItems = max(minItems, 3)
+		}
+		if minItems < 0 || maxItems < minItems {
+			return nil, fmt.Errorf("field %q: min_items must be between 0 and max_items", path)
+		}
+		return func(r *rand.Rand, n int) interface{} {
+			items := make([]interface{}, minItems+r.IntN(maxItems-minItems+1))
+			for i := range items {
+				items[i] = item(r, n)
+			}
+			return items
+		}, nil
+	case "":
+		return nil, fmt.Errorf("field %q needs a type", path)
+	default:
+		return nil, fmt.Errorf("field %q has unknown type %q", path, spec.Type)
+	}
+}
+
+// compileNumber builds the draw for integer and float fields.
+func compileNumber(spec *fieldSpec, path string) (func(r *rand.Rand) float64, error) {
+	low, high := math.Inf(-1), math.Inf(1)
+	if spec.Min != nil {
+		low = *spec.Min
+	}
+	if spec.Max != nil {
+		high = *spec.Max
+	}
+	if low > high {
+		return nil, fmt.Errorf("field %q: min is above max", path)
+	}
+	clamp := func(v float64) float64 { return math.Min(math.Max(v, low), high) }
+	switch spec.Distribution {
+	case "", "uniform":
+		if spec.Min == nil || spec.Max == nil {
+			return nil, fmt.Errorf("field %q: a uniform %s needs min and max", path, spec.Type)
+		}
+		return func(r *rand.Rand) float64 { return low + r.Float64()*(high-low) }, nil
+	case "normal":
+		if spec.StdDev < 0 {
+			return nil, fmt.Errorf("field %q: stddev must be 0 or greater", path)
+		}
+		mean, stddev := spec.Mean, spec.StdDev
+		return func(r *rand.Rand) float64 { return clamp(mean + r.NormFloat64()*stddev) }, nil
+	case "exponential":
+		if spec.Mean <= 0 {
+			return nil, fmt.Errorf("field %q: an exponential distribution needs a mean above 0", path)
+		}
+		mean := spec.Mean
+		return func(r *rand.Rand) float64 { return clamp(r.ExpFloat64() * mean) }, nil
+	default:
+		return nil, fmt.Errorf("field %q has unknown distribution %q; use uniform, normal, or exponential", path, spec.Distribution)
+	}
+}
+
+// compileTimestamp builds the generator for timestamp fields.
+func compileTimestamp(spec *fieldSpec, path string) (valueGenerator, error) {
+	from, err := time.Parse(time.RFC3339, spec.From)
+	if err != nil {
+		return nil, fmt.Errorf("field %q: from must be an RFC 3339 time: %w", path, err)
+	}
+	to, err := time.Parse(time.RFC3339, spec.To)
+	if err != nil {
+		return nil, fmt.Errorf("field %q: to must be an RFC 3339 time: %w", path, err)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("field %q: to is before from", path)
+	}
+	span := to.Sub(from)
+	at := func(r *rand.Rand) time.Time {
+		if span == 0 {
+			return from
+		}
+		return from.Add(time.Duration(r.Int64N(int64(span))))
+	}
+	switch spec.Format {
+	case "", "rfc3339":
+		return func(r *rand.Rand, _ int) interface{} { return at(r).UTC().Format(time.RFC3339Nano) }, nil
+	case "epoch_millis":
+		return func(r *rand.Rand, _ int) interface{} { return at(r).UnixMilli() }, nil
+	default:
+		return nil, fmt.Errorf("field %q has unknown format %q; use rfc3339 or epoch_millis", path, spec.Format)
+	}
+}
+
+// compileChoice builds the generator for choice fields.
+func compileChoice(spec *fieldSpec, path string) (valueGenerator, error) {
+	if len(spec.Values) == 0 {
+		return nil, fmt.Errorf("field %q: choice needs values", path)
+	}
+	values := make([]interface{}, len(spec.Values))
+	for i, raw := range spec.Values {
+		if err := decodeJSON(raw, &values[i]); err != nil {
+			return nil, fmt.Errorf("field %q: value %d: %w", path, i+1, err)
+		}
+	}
+	if len(spec.Weights) == 0 {
+		return func(r *rand.Rand, _ int) interface{} { return cloneValue(values[r.IntN(len(values))]) }, nil
+	}
+	if len(spec.Weights) != len(values) {
+		return nil, fmt.Errorf("field %q: weights must match values one to one", path)
+	}
+	cumulative := make([]float64, len(spec.Weights))
+	total := 0.0
+	for i, weight := range spec.Weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("field %q: weights must be 0 or greater", path)
+		}
+		total += weight
+		cumulative[i] = total
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("field %q: weights must not all be 0", path)
+	}
+	return func(r *rand.Rand, _ int) interface{} {
+		i := sort.SearchFloat64s(cumulative, r.Float64()*total)
+		return cloneValue(values[min(i, len(values)-1)])
+	}, nil
+}
+
+// generateObject builds one object from fields.
+func generateObject(fields []namedGenerator, r *rand.Rand, n int) map[string]interface{} {
+	doc := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		doc[field.name] = field.generate(r, n)
+	}
+	return doc
+}
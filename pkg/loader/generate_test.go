@@ -0,0 +1,119 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGenerateFollowsSpec verifies behavior for the related scenario.
+func TestGenerateFollowsSpec(t *testing.T) {
+	t.Parallel()
+
+	spec := `{
+		"id": {"type": "sequence", "start": 100},
+		"name": {"type": "name"},
+		"email": {"type": "email", "domain": "corp.test"},
+		"age": {"type": "integer", "min": 18, "max": 65},
+		"score": {"type": "float", "distribution": "normal", "mean": 50, "stddev": 10, "min": 0, "max": 100},
+		"seen": {"type": "timestamp", "from": "2024-01-01T00:00:00Z", "to": "2024-02-01T00:00:00Z"},
+		"tier": {"type": "choice", "values": ["gold", "silver"], "weights": [0, 1]},
+		"note": {"type": "string", "null": 1},
+		"address": {"type": "object", "fields": {"ip": {"type": "ip"}, "tags": {"type": "array", "items": {"type": "word"}, "min_items": 2, "max_items": 2}}}
+	}`
+	specFile := filepath.Join(t.TempDir(), "spec.json")
+	if err := os.WriteFile(specFile, []byte(spec), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	var out bytes.Buffer
+	count, err := Generate(context.Background(), GenerateOptions{SpecFile: specFile, Count: 50, Seed: 7, Output: &out})
+	if err != nil || count != 50 {
+		t.Fatalf("Generate returned %d, %v", count, err)
+	}
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	scanner := bufio.NewScanner(bytes.NewReader(out.Bytes()))
+	for n := 0; scanner.Scan(); n++ {
+		var doc struct {
+			ID      int     `json:"id"`
+			Name    string  `json:"name"`
+			Email   string  `json:"email"`
+			Age     float64 `json:"age"`
+			Score   float64 `json:"score"`
+			Seen    string  `json:"seen"`
+			Tier    string  `json:"tier"`
+			Note    *string `json:"note"`
+			Address struct {
+				IP   string   `json:"ip"`
+				Tags []string `json:"tags"`
+			} `json:"address"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			t.Fatalf("line %d is not JSON: %v", n+1, err)
+		}
+		seen, err := time.Parse(time.RFC3339Nano, doc.Seen)
+		switch {
+		case doc.ID != 100+n:
+			t.Fatalf("line %d: expected id %d, got %d", n+1, 100+n, doc.ID)
+		case !strings.Contains(doc.Name, " ") || !strings.HasSuffix(doc.Email, "@corp.test"):
+			t.Fatalf("line %d: unexpected name %q or email %q", n+1, doc.Name, doc.Email)
+		case doc.Age < 18 || doc.Age > 65 || doc.Age != float64(int(doc.Age)):
+			t.Fatalf("line %d: age %v out of range", n+1, doc.Age)
+		case doc.Score < 0 || doc.Score > 100:
+			t.Fatalf("line %d: score %v out of range", n+1, doc.Score)
+		case err != nil || seen.Before(from) || !seen.Before(to):
+			t.Fatalf("line %d: timestamp %q out of range (%v)", n+1, doc.Seen, err)
+		case doc.Tier != "silver" || doc.Note != nil:
+			t.Fatalf("line %d: unexpected tier %q or note %v", n+1, doc.Tier, doc.Note)
+		case strings.Count(doc.Address.IP, ".") != 3 || len(doc.Address.Tags) != 2:
+			t.Fatalf("line %d: unexpected address %+v", n+1, doc.Address)
+		}
+	}
+
+	var again bytes.Buffer
+	if _, err := Generate(context.Background(), GenerateOptions{SpecFile: specFile, Count: 50, Seed: 7, Output: &again}); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if again.String() != out.String() {
+		t.Fatal("expected the same seed to generate the same documents")
+	}
+}
+
+// TestGenerateRejectsInvalidSpecs verifies behavior for the related scenario.
+func TestGenerateRejectsInvalidSpecs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{name: "empty", spec: `{}`, want: "declares no fields"},
+		{name: "unknown type", spec: `{"a": {"type": "color"}}`, want: `unknown type "color"`},
+		{name: "uniform without bounds", spec: `{"a": {"type": "integer", "min": 1}}`, want: "needs min and max"},
+		{name: "bad timestamp", spec: `{"a": {"type": "timestamp", "from": "yesterday", "to": "2024-01-01T00:00:00Z"}}`, want: "RFC 3339"},
+		{name: "mismatched weights", spec: `{"a": {"type": "choice", "values": [1, 2], "weights": [1]}}`, want: "weights must match"},
+		{name: "nested path", spec: `{"a": {"type": "object", "fields": {"b": {"type": "nope"}}}}`, want: `"a.b"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			specFile := filepath.Join(t.TempDir(), "spec.json")
+			if err := os.WriteFile(specFile, []byte(tt.spec), 0o600); err != nil {
+				t.Fatalf("WriteFile returned error: %v", err)
+			}
+			_, err := Generate(context.Background(), GenerateOptions{SpecFile: specFile, Count: 1, Output: &bytes.Buffer{}})
+			if !errors.Is(err, ErrInvalidOptions) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected ErrInvalidOptions mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
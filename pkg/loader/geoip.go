@@ -0,0 +1,170 @@
+package loader
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// ─── GeoIP Enrichment ──────────────────────────────────────────────────────────
+
+// defaultGeoIPTarget matches the target_field default of the Elasticsearch
+// geoip processor.
+const defaultGeoIPTarget = "geoip"
+
+// geoIPRule enriches the object at Target from the address in Field.
+type geoIPRule struct {
+	Field  string
+	Target string
+}
+
+// geoIPRecord is the subset of GeoLite2/GeoIP2 City, Country, and ASN records
+// the loader copies into documents. Databases without a section leave it empty.
+type geoIPRecord struct {
+	Continent struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"continent"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Postal struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"postal"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+		TimeZone  string   `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+	ASN          uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+var (
+	geoIPReadersMu sync.Mutex
+	// geoIPReaders keeps each database open for the life of the process so
+	// -watch, -schedule, and serve jobs do not re-map it for every run.
+	geoIPReaders = map[string]*maxminddb.Reader{}
+)
+
+// openGeoIPDatabase returns the shared reader for path, opening it on first use.
+func openGeoIPDatabase(path string) (*maxminddb.Reader, error) {
+	geoIPReadersMu.Lock()
+	defer geoIPReadersMu.Unlock()
+	if reader, ok := geoIPReaders[path]; ok {
+		return reader, nil
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening -geoip-db %s: %w", path, err)
+	}
+	geoIPReaders[path] = reader
+	return reader, nil
+}
+
+// parseGeoIPRules parses "ipField" or "ipField:targetField" entries.
+func parseGeoIPRules(values []string) ([]geoIPRule, error) {
+	rules := make([]geoIPRule, 0, len(values))
+	for _, value := range values {
+		field, target, _ := strings.Cut(value, ":")
+		field, target = strings.TrimSpace(field), strings.TrimSpace(target)
+		if target == "" {
+			target = defaultGeoIPTarget
+		}
+		if field == "" {
+			return nil, fmt.Errorf("geoip %q must be ipField or ipField:targetField", value)
+		}
+		rules = append(rules, geoIPRule{Field: field, Target: target})
+	}
+	return rules, nil
+}
+
+// newGeoIPTransform looks up each rule's address in the database at path.
+func newGeoIPTransform(values []string, path string) (docTransform, error) {
+	rules, err := parseGeoIPRules(values)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("-geoip requires -geoip-db")
+	}
+	reader, err := openGeoIPDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	return inPlace(func(doc map[string]interface{}) { enrichGeoIP(doc, reader, rules) }), nil
+}
+
+// enrichGeoIP sets each rule's target when its field holds an address found
+// in the database. Missing fields, unparsable addresses, and addresses the
+// database does not cover leave the document unchanged, as the ingest
+// processor does with ignore_missing.
+func enrichGeoIP(doc map[string]interface{}, reader *maxminddb.Reader, rules []geoIPRule) {
+	for _, rule := range rules {
+		value, ok := lookupField(doc, rule.Field)
+		if !ok {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+		addr, err := netip.ParseAddr(strings.TrimSpace(text))
+		if err != nil {
+			continue
+		}
+		result := reader.Lookup(addr.Unmap())
+		if !result.Found() {
+			continue
+		}
+		var record geoIPRecord
+		if err := result.Decode(&record); err != nil {
+			continue
+		}
+		if geo := record.fields(); len(geo) > 0 {
+			setField(doc, rule.Target, geo)
+		}
+	}
+}
+
+// fields renders the record with the field names of the Elasticsearch geoip
+// processor, so documents match mappings written for ingest-side enrichment.
+func (r geoIPRecord) fields() map[string]interface{} {
+	geo := make(map[string]interface{})
+	put := func(key, value string) {
+		if value != "" {
+			geo[key] = value
+		}
+	}
+	put("continent_name", r.Continent.Names["en"])
+	put("country_iso_code", r.Country.ISOCode)
+	put("country_name", r.Country.Names["en"])
+	if len(r.Subdivisions) > 0 {
+		region := r.Subdivisions[0]
+		if region.ISOCode != "" && r.Country.ISOCode != "" {
+			geo["region_iso_code"] = r.Country.ISOCode + "-" + region.ISOCode
+		}
+		put("region_name", region.Names["en"])
+	}
+	put("city_name", r.City.Names["en"])
+	put("postal_code", r.Postal.Code)
+	put("timezone", r.Location.TimeZone)
+	if r.Location.Latitude != nil && r.Location.Longitude != nil {
+		geo["location"] = map[string]interface{}{"lat": *r.Location.Latitude, "lon": *r.Location.Longitude}
+	}
+	if r.ASN != 0 {
+		geo["asn"] = r.ASN
+	}
+	put("organization_name", r.Organization)
+	return geo
+}
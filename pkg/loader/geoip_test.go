@@ -0,0 +1,201 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// writeTestMMDB writes an IPv4 MaxMind database with 24-bit records that maps
+// 81.2.69.0/24 to record and every other address to nothing.
+func writeTestMMDB(t *testing.T, record map[string]interface{}) string {
+	t.Helper()
+
+	const prefixBits, nodeCount = 24, 24
+	network := [4]byte{81, 2, 69, 0}
+	var tree bytes.Buffer
+	for node := 0; node < nodeCount; node++ {
+		bit := network[node/8] >> (7 - uint(node%8)) & 1
+		next := uint32(node + 1)
+		if node == prefixBits-1 {
+			next = nodeCount + 16 // first byte of the data section
+		}
+		records := [2]uint32{nodeCount, nodeCount}
+		records[bit] = next
+		for _, value := range records {
+			tree.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+
+	var out bytes.Buffer
+	out.Write(tree.Bytes())
+	out.Write(make([]byte, 16))
+	writeTestMMDBValue(&out, record)
+	out.WriteString("\xAB\xCD\xEFMaxMind.com")
+	writeTestMMDBValue(&out, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"database_type":               "Test-City",
+		"description":                 map[string]interface{}{"en": "test"},
+		"ip_version":                  uint16(4),
+		"languages":                   []interface{}{"en"},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	})
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	return path
+}
+
+// writeTestMMDBValue encodes the handful of MMDB data types the tests need.
+func writeTestMMDBValue(out *bytes.Buffer, value interface{}) {
+	control := func(kind, size int) {
+		sizeByte, extra := size, -1
+		if size >= 29 {
+			sizeByte, extra = 29, size-29 // sizes up to 284 take one extra byte
+		}
+		if kind <= 7 {
+			out.WriteByte(byte(kind<<5 | sizeByte))
+		} else {
+			out.Write([]byte{byte(sizeByte), byte(kind - 7)})
+		}
+		if extra >= 0 {
+			out.WriteByte(byte(extra))
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		control(2, len(v))
+		out.WriteString(v)
+	case float64:
+		control(3, 8)
+		_ = binary.Write(out, binary.BigEndian, math.Float64bits(v))
+	case uint16:
+		control(5, 2)
+		_ = binary.Write(out, binary.BigEndian, v)
+	case uint32:
+		control(6, 4)
+		_ = binary.Write(out, binary.BigEndian, v)
+	case uint64:
+		control(9, 8)
+		_ = binary.Write(out, binary.BigEndian, v)
+	case []interface{}:
+		control(11, len(v))
+		for _, item := range v {
+			writeTestMMDBValue(out, item)
+		}
+	case map[string]interface{}:
+		control(7, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			writeTestMMDBValue(out, key)
+			writeTestMMDBValue(out, v[key])
+		}
+	}
+}
+
+// TestGeoIPTransformEnrichesDocuments verifies behavior for the related scenario.
+func TestGeoIPTransformEnrichesDocuments(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestMMDB(t, map[string]interface{}{
+		"city":      map[string]interface{}{"names": map[string]interface{}{"en": "London"}},
+		"continent": map[string]interface{}{"code": "EU", "names": map[string]interface{}{"en": "Europe"}},
+		"country":   map[string]interface{}{"iso_code": "GB", "names": map[string]interface{}{"en": "United Kingdom"}},
+		"location": map[string]interface{}{
+			"latitude":  51.5142,
+			"longitude": -0.0931,
+			"time_zone": "Europe/London",
+		},
+		"subdivisions": []interface{}{
+			map[string]interface{}{"iso_code": "ENG", "names": map[string]interface{}{"en": "England"}},
+		},
+		"autonomous_system_number":       uint32(20712),
+		"autonomous_system_organization": "Andrews & Arnold Ltd",
+	})
+	step, err := newGeoIPTransform([]string{"client.ip:client.geo", "server_ip"}, path)
+	if err != nil {
+		t.Fatalf("newGeoIPTransform returned error: %v", err)
+	}
+
+	out, err := step(decodeTestDocument(t, `{"client":{"ip":"81.2.69.160"},"server_ip":"::ffff:81.2.69.1"}`))
+	if err != nil {
+		t.Fatalf("transform returned error: %v", err)
+	}
+	doc := out[0]
+	client, _ := lookupField(doc, "client.geo")
+	geo, ok := client.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected client.geo object, got %#v", doc)
+	}
+	want := map[string]interface{}{
+		"continent_name":    "Europe",
+		"country_iso_code":  "GB",
+		"country_name":      "United Kingdom",
+		"region_iso_code":   "GB-ENG",
+		"region_name":       "England",
+		"city_name":         "London",
+		"timezone":          "Europe/London",
+		"organization_name": "Andrews & Arnold Ltd",
+	}
+	for key, value := range want {
+		if geo[key] != value {
+			t.Fatalf("client.geo.%s = %#v, want %#v", key, geo[key], value)
+		}
+	}
+	if geo["asn"] != uint(20712) {
+		t.Fatalf("client.geo.asn = %#v, want 20712", geo["asn"])
+	}
+	location, _ := geo["location"].(map[string]interface{})
+	if location["lat"] != 51.5142 || location["lon"] != -0.0931 {
+		t.Fatalf("unexpected location %#v", geo["location"])
+	}
+	if _, ok := doc[defaultGeoIPTarget].(map[string]interface{}); !ok {
+		t.Fatalf("expected IPv4-mapped server_ip to enrich the default target, got %#v", doc)
+	}
+
+	for _, raw := range []string{`{"ip":"10.0.0.1"}`, `{"ip":"not an ip"}`, `{"ip":42}`, `{"other":"81.2.69.1"}`} {
+		out, err := step(decodeTestDocument(t, raw))
+		if err != nil {
+			t.Fatalf("transform returned error: %v", err)
+		}
+		if _, ok := out[0][defaultGeoIPTarget]; ok {
+			t.Fatalf("expected %s to be left unchanged, got %#v", raw, out[0])
+		}
+	}
+}
+
+// TestNewGeoIPTransformRejectsInvalidOptions verifies behavior for the related scenario.
+func TestNewGeoIPTransformRejectsInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		values []string
+		path   string
+	}{
+		{name: "missing database", values: []string{"ip"}},
+		{name: "empty field", values: []string{":geo"}, path: "unused.mmdb"},
+		{name: "unreadable database", values: []string{"ip"}, path: filepath.Join(t.TempDir(), "missing.mmdb")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := newGeoIPTransform(tt.values, tt.path); err == nil {
+				t.Fatalf("expected newGeoIPTransform(%q, %q) to fail", tt.values, tt.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── GeoJSON Input ─────────────────────────────────────────────────────────────
+
+// defaultGeoShapeField receives each Feature's geometry.
+const defaultGeoShapeField = "geometry"
+
+// geojsonReader streams the Features of a GeoJSON FeatureCollection, or the
+// one Feature of a Feature file, as documents.
+type geojsonReader struct {
+	file  io.ReadCloser
+	dec   *json.Decoder
+	field string
+	// pending holds a lone Feature, or nil once it has been returned.
+	pending  map[string]interface{}
+	features bool
+	count    int
+}
+
+// newGeoJSONReader reads up to the features array, keeping any members
+// that come before it.
+func newGeoJSONReader(file io.ReadCloser, field string) (*geojsonReader, error) {
+	dec := json.NewDecoder(file)
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("GeoJSON data file must be a FeatureCollection or Feature object")
+	}
+	r := &geojsonReader{file: file, dec: dec, field: field}
+	members := make(map[string]interface{})
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("parsing GeoJSON: %w", err)
+		}
+		key, _ := tok.(string)
+		if key == "features" {
+			if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+				return nil, fmt.Errorf("GeoJSON features must be an array")
+			}
+			r.features = true
+			return r, nil
+		}
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("parsing GeoJSON member %q: %w", key, err)
+		}
+		members[key] = value
+	}
+	if members["type"] != "Feature" {
+		return nil, fmt.Errorf("GeoJSON data file of type %v has no features array", members["type"])
+	}
+	r.pending = members
+	return r, nil
+}
+
+// next converts the following Feature.
+func (r *geojsonReader) next() (map[string]interface{}, error) {
+	if !r.features {
+		if r.pending == nil {
+			return nil, io.EOF
+		}
+		feature := r.pending
+		r.pending = nil
+		r.count++
+		return r.document(feature)
+	}
+	if !r.dec.More() {
+		return nil, io.EOF
+	}
+	var feature map[string]interface{}
+	if err := r.dec.Decode(&feature); err != nil {
+		return nil, err
+	}
+	r.count++
+	return r.document(feature)
+}
+
+// document promotes a Feature's properties to top-level fields and stores
+// its geometry in r.field. The Feature id becomes an id field unless the
+// properties already have one.
+func (r *geojsonReader) document(feature map[string]interface{}) (map[string]interface{}, error) {
+	if feature["type"] != "Feature" {
+		return nil, fmt.Errorf("GeoJSON feature %d has type %v, not Feature", r.count, feature["type"])
+	}
+	doc := make(map[string]interface{})
+	if properties, ok := feature["properties"].(map[string]interface{}); ok {
+		doc = properties
+	} else if feature["properties"] != nil {
+		return nil, fmt.Errorf("GeoJSON feature %d properties must be an object", r.count)
+	}
+	if id, ok := feature["id"]; ok {
+		if _, exists := doc["id"]; !exists {
+			doc["id"] = id
+		}
+	}
+	if geometry := feature["geometry"]; geometry != nil {
+		setField(doc, r.field, geometry)
+	}
+	return doc, nil
+}
+
+// close releases the underlying file.
+func (r *geojsonReader) close() error {
+	return r.file.Close()
+}
+
+// geoShapeMapping returns the mapping properties declaring field, in
+// dot-notation, as a geo_shape.
+func geoShapeMapping(field string) map[string]interface{} {
+	mapping := map[string]interface{}{"type": "geo_shape"}
+	segments := strings.Split(field, ".")
+	for i := len(segments) - 1; i > 0; i-- {
+		mapping = map[string]interface{}{"properties": map[string]interface{}{segments[i]: mapping}}
+	}
+	return map[string]interface{}{segments[0]: mapping}
+}
+
+// addGeoShapeMapping adds field as a geo_shape to a create-index body,
+// unless the mappings file already declares it.
+func addGeoShapeMapping(body, field string) (string, error) {
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		return "", err
+	}
+	mappings, _ := parsed["mappings"].(map[string]interface{})
+	if mappings == nil {
+		mappings = make(map[string]interface{})
+		parsed["mappings"] = mappings
+	}
+	if _, declared := resolveFieldType(mappings, field); declared {
+		return body, nil
+	}
+	properties, _ := mappings["properties"].(map[string]interface{})
+	if properties == nil {
+		properties = make(map[string]interface{})
+		mappings["properties"] = properties
+	}
+	mergeMappingProperties(properties, geoShapeMapping(field))
+	encoded, err := json.Marshal(parsed)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// mergeMappingProperties copies add into properties, descending into object
+// fields both already declare.
+func mergeMappingProperties(properties, add map[string]interface{}) {
+	for name, mapping := range add {
+		existing, _ := properties[name].(map[string]interface{})
+		addMapping, _ := mapping.(map[string]interface{})
+		existingChildren, _ := existing["properties"].(map[string]interface{})
+		addChildren, _ := addMapping["properties"].(map[string]interface{})
+		if existingChildren != nil && addChildren != nil {
+			mergeMappingProperties(existingChildren, addChildren)
+			continue
+		}
+		if existing != nil && addChildren != nil {
+			existing["properties"] = addChildren
+			continue
+		}
+		properties[name] = mapping
+	}
+}
+
+// ensureGeoShapeMapping maps field as a geo_shape on an existing index that
+// has not mapped it yet, so the first Feature does not map it as an object.
+func ensureGeoShapeMapping(es *elasticsearch.Client, index, field string) error {
+	mappings, err := fetchIndexMappings(es, index)
+	if err != nil {
+		return err
+	}
+	if fieldType, declared := resolveFieldType(mappings, field); declared {
+		if fieldType != "geo_shape" && fieldType != "shape" {
+			log.Warn().Str("index", index).Str("field", field).Str("type", fieldType).Msg("GeoJSON geometry field is not mapped as geo_shape")
+		}
+		return nil
+	}
+	body, err := json.Marshal(map[string]interface{}{"properties": geoShapeMapping(field)})
+	if err != nil {
+		return err
+	}
+	res, err := es.Indices.PutMapping([]string{index}, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("mapping %s as geo_shape: %w", field, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		responseBody, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("mapping %s as geo_shape failed with status %d: %s", field, res.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	log.Info().Str("index", index).Str("field", field).Msg("Mapped GeoJSON geometry field as geo_shape")
+	return nil
+}
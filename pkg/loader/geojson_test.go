@@ -0,0 +1,162 @@
+package loader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestGeoJSONReader verifies behavior for the related scenario.
+func TestGeoJSONReader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "collection",
+			content: `{"type":"FeatureCollection","name":"parks","features":[
+				{"type":"Feature","id":7,"properties":{"name":"Elm"},"geometry":{"type":"Point","coordinates":[1,2]}},
+				{"type":"Feature","properties":{"id":"p2"},"id":8,"geometry":null},
+				{"type":"Feature","properties":null,"geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]}}
+			],"bbox":[0,0,1,2]}`,
+			want: []string{
+				`{"geometry":{"coordinates":[1,2],"type":"Point"},"id":7,"name":"Elm"}`,
+				`{"id":"p2"}`,
+				`{"geometry":{"coordinates":[[0,0],[1,1]],"type":"LineString"}}`,
+			},
+		},
+		{
+			name:    "single feature",
+			content: `{"geometry":{"type":"Point","coordinates":[3,4]},"properties":{"name":"Oak"},"type":"Feature"}`,
+			want:    []string{`{"geometry":{"coordinates":[3,4],"type":"Point"},"name":"Oak"}`},
+		},
+		{name: "bare geometry", content: `{"type":"Point","coordinates":[1,2]}`, wantErr: "has no features array"},
+		{name: "array", content: `[{"type":"Feature"}]`, wantErr: "must be a FeatureCollection or Feature"},
+		{name: "wrong member", content: `{"features":[{"type":"Point"}]}`, wantErr: "feature 1 has type Point"},
+	}
+	for _, tt := range tests {
+		got, err := readTestDocuments(t, inputConfig{geoShapeField: defaultGeoShapeField}, writeTestDataFile(t, "parks.geojson", tt.content))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("%s: expected error mentioning %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: returned error: %v", tt.name, err)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Fatalf("%s: read %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestAddGeoShapeMapping verifies behavior for the related scenario.
+func TestAddGeoShapeMapping(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		body  string
+		field string
+		want  string
+	}{
+		{
+			body:  `{"settings": {}, "mappings": {}}`,
+			field: "geometry",
+			want:  `{"mappings":{"properties":{"geometry":{"type":"geo_shape"}}},"settings":{}}`,
+		},
+		{
+			body:  `{"settings": {}, "mappings": {"properties":{"place":{"properties":{"name":{"type":"keyword"}}}}}}`,
+			field: "place.shape",
+			want:  `{"mappings":{"properties":{"place":{"properties":{"name":{"type":"keyword"},"shape":{"type":"geo_shape"}}}}},"settings":{}}`,
+		},
+		{
+			body:  `{"settings": {}, "mappings": {"properties":{"geometry":{"type":"shape"}}}}`,
+			field: "geometry",
+			want:  `{"settings": {}, "mappings": {"properties":{"geometry":{"type":"shape"}}}}`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := addGeoShapeMapping(tt.body, tt.field)
+		if err != nil {
+			t.Fatalf("addGeoShapeMapping(%s) returned error: %v", tt.body, err)
+		}
+		if got != tt.want {
+			t.Fatalf("addGeoShapeMapping(%s, %q) = %s, want %s", tt.body, tt.field, got, tt.want)
+		}
+	}
+}
+
+// TestRunGeoJSONMapsGeoShape verifies behavior for the related scenario.
+func TestRunGeoJSONMapsGeoShape(t *testing.T) {
+	t.Parallel()
+
+	for _, exists := range []bool{false, true} {
+		var (
+			mu       sync.Mutex
+			created  bool
+			requests = map[string]string{}
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Elastic-Product", "Elasticsearch")
+			w.Header().Set("Content-Type", "application/json")
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			defer mu.Unlock()
+			requests[r.Method+" "+r.URL.Path] = string(body)
+			switch {
+			case r.Method == http.MethodHead && r.URL.Path == "/parks":
+				if !exists && !created {
+					w.WriteHeader(http.StatusNotFound)
+				}
+			case r.Method == http.MethodPut && r.URL.Path == "/parks":
+				created = true
+				_, _ = w.Write([]byte(`{"acknowledged":true}`))
+			case r.Method == http.MethodGet && r.URL.Path == "/parks/_mapping":
+				_, _ = w.Write([]byte(`{"parks":{"mappings":{"properties":{"name":{"type":"keyword"}}}}}`))
+			case r.Method == http.MethodPut && r.URL.Path == "/parks/_mapping":
+				_, _ = w.Write([]byte(`{"acknowledged":true}`))
+			case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+				_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"_index":"parks","_id":"1","status":201}}]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		result, err := Run(context.Background(), Options{
+			URL:           server.URL,
+			Index:         "parks",
+			DataFile:      writeTestDataFile(t, "parks.geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"name":"Elm"},"geometry":{"type":"Point","coordinates":[1,2]}}]}`),
+			AddToIndex:    true,
+			GeoShapeField: "shape",
+		})
+		if err != nil {
+			t.Fatalf("exists=%v: Run returned error: %v", exists, err)
+		}
+		if result.DocumentsProcessed != 1 {
+			t.Fatalf("exists=%v: expected 1 processed document, got %+v", exists, result)
+		}
+		mu.Lock()
+		mappingBody := requests["PUT /parks"]
+		if exists {
+			mappingBody = requests["PUT /parks/_mapping"]
+		}
+		bulk := requests["POST /_bulk"]
+		mu.Unlock()
+		if !strings.Contains(mappingBody, `"shape":{"type":"geo_shape"}`) {
+			t.Fatalf("exists=%v: expected a geo_shape mapping, got %q", exists, mappingBody)
+		}
+		if !strings.Contains(bulk, `"shape":{"coordinates":[1,2],"type":"Point"}`) {
+			t.Fatalf("exists=%v: unexpected bulk body: %s", exists, bulk)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ─── Regex and Grok Input ──────────────────────────────────────────────────────
+
+// grokBuiltinPatterns is the pattern library every -grok-pattern can use,
+// named as in Logstash. Definitions are RE2, so the Logstash ones that rely
+// on lookaround or atomic groups are simplified.
+const grokBuiltinPatterns = `
+USERNAME [a-zA-Z0-9._-]+
+USER %{USERNAME}
+INT [+-]?[0-9]+
+BASE10NUM [+-]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)
+NUMBER %{BASE10NUM}
+BASE16NUM (?:0[xX])?[0-9A-Fa-f]+
+POSINT \b[1-9][0-9]*\b
+NONNEGINT \b[0-9]+\b
+WORD \b\w+\b
+NOTSPACE \S+
+SPACE \s*
+DATA .*?
+GREEDYDATA .*
+QUOTEDSTRING "(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'
+UUID [A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}
+MAC (?:[A-Fa-f0-9]{2}[:-]){5}[A-Fa-f0-9]{2}
+IPV4 (?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9]{1,2})\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9]{1,2})
+IPV6 (?:[0-9A-Fa-f]{0,4}:){2,7}(?:%{IPV4}|[0-9A-Fa-f]{1,4})?
+IP (?:%{IPV6}|%{IPV4})
+HOSTNAME \b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b
+HOST %{HOSTNAME}
+IPORHOST (?:%{IP}|%{HOSTNAME})
+HOSTPORT %{IPORHOST}:%{POSINT}
+PATH (?:/[^\s]*)+
+URIPATH /[^\s?#]*
+URIPARAM \?\S*
+URI [A-Za-z][A-Za-z0-9+.-]*://\S+
+EMAILADDRESS [a-zA-Z0-9!#$%&'*+/=?^_{|}~.-]+@%{HOSTNAME}
+MONTH \b(?:[Jj]an(?:uary)?|[Ff]eb(?:ruary)?|[Mm]ar(?:ch)?|[Aa]pr(?:il)?|[Mm]ay|[Jj]une?|[Jj]uly?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo]ct(?:ober)?|[Nn]ov(?:ember)?|[Dd]ec(?:ember)?)\b
+MONTHNUM (?:0?[1-9]|1[0-2])
+MONTHDAY (?:0?[1-9]|[12][0-9]|3[01])
+DAY \b(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)\b
+YEAR [0-9]{4}
+HOUR (?:2[0-3]|[01]?[0-9])
+MINUTE [0-5][0-9]
+SECOND (?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?
+TIME %{HOUR}:%{MINUTE}:%{SECOND}
+ISO8601_TIMEZONE (?:Z|[+-]%{HOUR}(?::?%{MINUTE}))
+TIMESTAMP_ISO8601 %{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?
+SYSLOGTIMESTAMP %{MONTH} +%{MONTHDAY} %{TIME}
+HTTPDATE %{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}
+PROG [\w._/%-]+
+LOGLEVEL \b(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert)\b
+`
+
+// grokReference matches %{NAME}, %{NAME:field}, and %{NAME:field:type}.
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::(\w+))?\}`)
+
+// grokPatternName matches the NAME of a pattern file line.
+var grokPatternName = regexp.MustCompile(`^\w+$`)
+
+// grokGroupPrefix names the capture groups made for %{NAME:field}, as
+// field names need not be valid group names.
+const grokGroupPrefix = "_grok"
+
+// grokFormat is a compiled -grok-pattern and the field each capture group
+// sets.
+type grokFormat struct {
+	pattern *regexp.Regexp
+	// fields and types are indexed by capture group; an empty field is not
+	// indexed.
+	fields []string
+	types  []string
+}
+
+// grokCompiler expands %{NAME} references into one regular expression.
+type grokCompiler struct {
+	library map[string]string
+	groups  []grokGroup
+	// expanding holds the references being expanded, to catch cycles.
+	expanding []string
+}
+
+// grokGroup is the field and type of one %{NAME:field:type} capture.
+type grokGroup struct {
+	field, kind string
+}
+
+// compileGrokFormat compiles pattern, a regular expression that may use
+// named groups and %{NAME} references to the built-in patterns and those
+// in the files of patternsDir.
+func compileGrokFormat(pattern, patternsDir string) (*grokFormat, error) {
+	library := make(map[string]string)
+	if err := parseGrokPatterns(library, grokBuiltinPatterns, "built-in patterns"); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(patternsDir) != "" {
+		if err := loadGrokPatternsDir(library, patternsDir); err != nil {
+			return nil, err
+		}
+	}
+	compiler := &grokCompiler{library: library}
+	expanded, err := compiler.expand(pattern)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("-grok-pattern: %w", err)
+	}
+	format := &grokFormat{pattern: re, fields: make([]string, re.NumSubexp()+1), types: make([]string, re.NumSubexp()+1)}
+	named := false
+	for i, name := range re.SubexpNames() {
+		if rest, ok := strings.CutPrefix(name, grokGroupPrefix); ok {
+			n, err := strconv.Atoi(rest)
+			if err == nil && n < len(compiler.groups) {
+				format.fields[i], format.types[i] = compiler.groups[n].field, compiler.groups[n].kind
+				named = true
+				continue
+			}
+		}
+		if name != "" {
+			format.fields[i], format.types[i] = name, columnTypeString
+			named = true
+		}
+	}
+	if !named {
+		return nil, errors.New("-grok-pattern has no named groups or %{NAME:field} captures")
+	}
+	return format, nil
+}
+
+// expand replaces the %{NAME} references in pattern with their
+// definitions.
+func (c *grokCompiler) expand(pattern string) (string, error) {
+	var failure error
+	expanded := grokReference.ReplaceAllStringFunc(pattern, func(reference string) string {
+		if failure != nil {
+			return ""
+		}
+		match := grokReference.FindStringSubmatch(reference)
+		name, field, kind := match[1], match[2], match[3]
+		definition, ok := c.library[name]
+		if !ok {
+			failure = fmt.Errorf("-grok-pattern refers to unknown pattern %%{%s}", name)
+			return ""
+		}
+		for _, outer := range c.expanding {
+			if outer == name {
+				failure = fmt.Errorf("grok pattern %s refers to itself", name)
+				return ""
+			}
+		}
+		c.expanding = append(c.expanding, name)
+		inner, err := c.expand(definition)
+		c.expanding = c.expanding[:len(c.expanding)-1]
+		if err != nil {
+			failure = err
+			return ""
+		}
+		if field == "" {
+			return "(?:" + inner + ")"
+		}
+		columnType, err := grokValueType(kind)
+		if err != nil {
+			failure = fmt.Errorf("%%{%s:%s:%s}: %w", name, field, kind, err)
+			return ""
+		}
+		c.groups = append(c.groups, grokGroup{field: grokFieldPath(field), kind: columnType})
+		return fmt.Sprintf("(?P<%s%d>%s)", grokGroupPrefix, len(c.groups)-1, inner)
+	})
+	return expanded, failure
+}
+
+// grokValueType returns the column type of a %{NAME:field:type} suffix:
+// Logstash's int and float, or any -column-map type.
+func grokValueType(kind string) (string, error) {
+	switch kind {
+	case "":
+		return columnTypeString, nil
+	case "int":
+		return columnTypeInteger, nil
+	case columnTypeString, columnTypeInteger, columnTypeFloat, columnTypeBoolean, columnTypeJSON:
+		return kind, nil
+	}
+	return "", fmt.Errorf("type %q is not int, float, boolean, or json", kind)
+}
+
+// grokFieldPath turns a Logstash [a][b] field reference into the a.b
+// dot-notation used elsewhere.
+func grokFieldPath(field string) string {
+	if !strings.HasPrefix(field, "[") || !strings.HasSuffix(field, "]") {
+		return field
+	}
+	return strings.ReplaceAll(strings.Trim(field, "[]"), "][", ".")
+}
+
+// loadGrokPatternsDir adds the patterns of every file in dir, in name
+// order, to library. Later definitions replace earlier ones.
+func loadGrokPatternsDir(library map[string]string, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("-grok-patterns-dir: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		name := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("-grok-patterns-dir: %w", err)
+		}
+		if err := parseGrokPatterns(library, string(content), name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseGrokPatterns adds the NAME PATTERN lines of text to library. Blank
+// lines and # comments are skipped.
+func parseGrokPatterns(library map[string]string, text, source string) error {
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, definition, ok := strings.Cut(line, " ")
+		definition = strings.TrimSpace(definition)
+		if !ok || definition == "" || !grokPatternName.MatchString(name) {
+			return fmt.Errorf("%s line %d: expected NAME PATTERN", source, i+1)
+		}
+		library[name] = definition
+	}
+	return nil
+}
+
+// parse returns the document of the first match in line. Groups that do
+// not take part in the match, and empty typed values, are left out.
+func (f *grokFormat) parse(line string) (map[string]interface{}, error) {
+	match := f.pattern.FindStringSubmatchIndex(line)
+	if match == nil {
+		return nil, errors.New("line does not match -grok-pattern")
+	}
+	doc := make(map[string]interface{}, len(f.fields))
+	for i, field := range f.fields {
+		if field == "" || match[2*i] < 0 {
+			continue
+		}
+		value, err := convertTypedCell(line[match[2*i]:match[2*i+1]], f.types[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		if value != nil {
+			setField(doc, field, value)
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,117 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGrokPatternParsesLines verifies behavior for the related scenario.
+func TestGrokPatternParsesLines(t *testing.T) {
+	t.Parallel()
+
+	config, err := newInputConfig(Options{GrokPattern: `^%{TIMESTAMP_ISO8601:@timestamp} +%{LOGLEVEL:level} +\[%{DATA:[service][name]}\] (?:took %{NUMBER:took_ms:float}ms )?%{GREEDYDATA:message}$`})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	content := "2024-05-01T08:00:00.123Z INFO [checkout] took 12.5ms order placed\n" +
+		"\n" +
+		"2024-05-01 08:00:01,004 WARN  [billing] card declined\r\n" +
+		"\tat com.example.Billing.charge(Billing.java:42)\n" +
+		"2024-05-01T08:00:02Z ERROR [checkout] timeout"
+	got, err := readTestDocuments(t, config, writeTestDataFile(t, "app.log", content))
+	if err != nil {
+		t.Fatalf("reading returned error: %v", err)
+	}
+	want := []string{
+		`{"@timestamp":"2024-05-01T08:00:00.123Z","level":"INFO","message":"order placed","service":{"name":"checkout"},"took_ms":12.5}`,
+		`{"@timestamp":"2024-05-01 08:00:01,004","level":"WARN","message":"card declined","service":{"name":"billing"}}`,
+		`{"@timestamp":"2024-05-01T08:00:02Z","level":"ERROR","message":"timeout","service":{"name":"checkout"}}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("read\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := readTestDocuments(t, config, writeTestDataFile(t, "other.log", "Starting up\n")); err == nil || !strings.Contains(err.Error(), "line 1: line does not match -grok-pattern") {
+		t.Fatalf("expected a first line that does not match to fail, got %v", err)
+	}
+}
+
+// TestGrokPatternsDirAndNamedGroups verifies behavior for the related scenario.
+func TestGrokPatternsDirAndNamedGroups(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"haproxy":   "# HAProxy pieces\nHAPROXYTIME %{HOUR}:%{MINUTE}:%{SECOND}\nBACKEND %{NOTSPACE:backend}/%{NOTSPACE:server}\n",
+		"override":  "LOGLEVEL (?:LOW|HIGH)\n",
+		".hidden":   "not a pattern file\n",
+		"vendor.md": "VENDOR [A-Z]+\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+	}
+	config, err := newInputConfig(Options{
+		Format:          formatRegex,
+		GrokPattern:     `%{HAPROXYTIME:time} %{BACKEND} (?P<status>\d{3}) (?<bytes>\d+) %{INT:retries:int} %{LOGLEVEL:priority} %{VENDOR:vendor}`,
+		GrokPatternsDir: dir,
+	})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	got, err := readTestDocuments(t, config, writeTestDataFile(t, "haproxy.log", "proxy: 10:02:03.5 web/web-01 503 212 3 HIGH ACME trailing\n"))
+	want := `{"backend":"web","bytes":"212","priority":"HIGH","retries":3,"server":"web-01","status":"503","time":"10:02:03.5","vendor":"ACME"}`
+	if err != nil || len(got) != 1 || got[0] != want {
+		t.Fatalf("read %q and %v, want %s", got, err, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken"), []byte("NO_DEFINITION\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	loop := t.TempDir()
+	if err := os.WriteFile(filepath.Join(loop, "loop"), []byte("A %{B}\nB x%{A}\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	bad := []struct {
+		opts Options
+		want string
+	}{
+		{opts: Options{Format: formatRegex}, want: "-format regex needs a -grok-pattern"},
+		{opts: Options{Format: formatCSV, GrokPattern: "(?P<a>.)"}, want: "-grok-pattern needs -format regex"},
+		{opts: Options{GrokPattern: "%{WORD} %{INT}"}, want: "has no named groups"},
+		{opts: Options{GrokPattern: "%{NOPE:x}"}, want: "unknown pattern %{NOPE}"},
+		{opts: Options{GrokPattern: "%{INT:n:long}"}, want: `type "long" is not`},
+		{opts: Options{GrokPattern: "(?P<a>(?=x))"}, want: "-grok-pattern: error parsing regexp"},
+		{opts: Options{GrokPattern: "%{WORD:a}", GrokPatternsDir: dir}, want: "broken line 1: expected NAME PATTERN"},
+		{opts: Options{GrokPattern: "%{A:a}", GrokPatternsDir: loop}, want: "grok pattern A refers to itself"},
+	}
+	for _, tt := range bad {
+		if _, err := newInputConfig(tt.opts); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%+v: expected error containing %q, got %v", tt.opts, tt.want, err)
+		}
+	}
+}
+
+// TestRunRegexDataFile verifies behavior for the related scenario.
+func TestRunRegexDataFile(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	result, err := Run(context.Background(), Options{
+		URL:         server.URL,
+		Index:       "cards",
+		DataFile:    writeTestDataFile(t, "app.log", "May  1 08:00:00 web01 sshd[812]: Accepted publickey for deploy\n"),
+		AddToIndex:  true,
+		GrokPattern: `^%{SYSLOGTIMESTAMP:timestamp} %{HOSTNAME:host} %{PROG:program}\[%{POSINT:pid:int}\]: %{GREEDYDATA:message}`,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if sent := strings.Join(bodies(), ""); result.DocumentsProcessed != 1 || !strings.Contains(sent, `"pid":812`) || !strings.Contains(sent, `"program":"sshd"`) {
+		t.Fatalf("unexpected result %+v and bulk bodies %s", result, sent)
+	}
+}
@@ -0,0 +1,312 @@
+package loader
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// ─── gRPC Streaming Ingestion ──────────────────────────────────────────────────
+
+const (
+	// grpcIngestService is the fully-qualified service name. Its messages are
+	// protobuf well-known types, so clients need no generated loader package.
+	grpcIngestService = "esbulkloader.v1.Ingest"
+	// defaultGRPCFlushInterval bounds how long a partial batch waits on a stream.
+	defaultGRPCFlushInterval = 5 * time.Second
+)
+
+// grpcIngest serves client-streaming RPCs that feed documents into bulkBatcher.
+type grpcIngest struct {
+	es            *elasticsearch.Client
+	token         string
+	batchSize     int
+	flushInterval time.Duration
+	settings      bulkSettings
+	// load supplies document transform options; chains are built per stream
+	// because some transforms keep state.
+	load Options
+}
+
+// newGRPCIngest builds the Elasticsearch client, bulk settings, and document
+// transforms shared by every stream from the server's load defaults.
+func newGRPCIngest(opts ServerOptions) (*grpcIngest, error) {
+	es, err := elasticsearch.NewClient(elasticsearchConfig(opts.Load))
+	if err != nil {
+		return nil, err
+	}
+	load := opts.Load
+	if _, err := buildDocTransforms(load); err != nil {
+		return nil, err
+	}
+	if _, err := newDeduper(load.DedupField, load.DedupPolicy); err != nil {
+		return nil, err
+	}
+	ingest := &grpcIngest{
+		es:            es,
+		token:         opts.Token,
+		batchSize:     load.BatchSize,
+		flushInterval: opts.GRPCFlushInterval,
+		load:          load,
+		settings:      streamBulkSettings(load),
+	}
+	if ingest.batchSize <= 0 {
+		ingest.batchSize = 1000
+	}
+	if ingest.flushInterval <= 0 {
+		ingest.flushInterval = defaultGRPCFlushInterval
+	}
+	if ingest.settings.StatsD, err = newStatsdClient(load.StatsDAddr); err != nil {
+		log.Warn().Err(err).Msg("StatsD disabled for gRPC ingestion")
+	}
+	return ingest, nil
+}
+
+// streamBulkSettings returns the bulk settings of documents that arrive one
+// at a time rather than from a data file, with retry defaults filled in.
+func streamBulkSettings(load Options) bulkSettings {
+	settings := bulkSettings{
+		RetryAttempts:    load.BulkRetryAttempts,
+		RetryBackoffBase: load.BulkRetryBackoffBase,
+		RetryBackoffMax:  load.BulkRetryBackoffMax,
+		IDField:          load.IDField,
+		RoutingField:     load.RoutingField,
+		DocType:          load.DocType,
+		SlowThreshold:    load.SlowBatchThreshold,
+		Worker:           1,
+	}
+	if settings.RetryAttempts <= 0 {
+		settings.RetryAttempts = defaultBulkRetryAttempts
+	}
+	if settings.RetryBackoffBase <= 0 {
+		settings.RetryBackoffBase = defaultBulkRetryBackoffBase
+	}
+	if settings.RetryBackoffMax <= 0 {
+		settings.RetryBackoffMax = defaultBulkRetryBackoffMax
+	}
+	if settings.RetryBackoffMax < settings.RetryBackoffBase {
+		settings.RetryBackoffMax = settings.RetryBackoffBase
+	}
+	return settings
+}
+
+// serviceDesc describes the Ingest service by hand; both methods reply with a
+// google.protobuf.Struct summary once the client closes its side of the stream.
+func (g *grpcIngest) serviceDesc() *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: grpcIngestService,
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    "StreamStructs",
+				ClientStreams: true,
+				Handler: func(_ any, stream grpc.ServerStream) error {
+					return g.ingest(stream, func() (map[string]interface{}, error) {
+						msg := new(structpb.Struct)
+						if err := stream.RecvMsg(msg); err != nil {
+							return nil, err
+						}
+						return msg.AsMap(), nil
+					})
+				},
+			},
+			{
+				StreamName:    "StreamJSON",
+				ClientStreams: true,
+				Handler: func(_ any, stream grpc.ServerStream) error {
+					return g.ingest(stream, func() (map[string]interface{}, error) {
+						msg := new(wrapperspb.BytesValue)
+						if err := stream.RecvMsg(msg); err != nil {
+							return nil, err
+						}
+						var doc map[string]interface{}
+						if err := decodeJSON(msg.GetValue(), &doc); err != nil {
+							return nil, malformedDocumentError{err}
+						}
+						return doc, nil
+					})
+				},
+			},
+		},
+		Metadata: "es-bulk-loader/ingest.proto",
+	}
+}
+
+// malformedDocumentError marks a JSON message that could not be decoded; the
+// stream skips it and keeps reading.
+type malformedDocumentError struct{ err error }
+
+// Error applies method-specific behavior to keep package workflows consistent.
+func (e malformedDocumentError) Error() string { return e.err.Error() }
+
+// grpcMessage is one receive result handed from the reader goroutine.
+type grpcMessage struct {
+	doc map[string]interface{}
+	err error
+}
+
+// ingest authorizes a stream, batches its documents into the index named by
+// the "index" metadata key, and replies with the stream's totals. Messages are
+// received on a separate goroutine through an unbuffered channel, so a slow
+// bulk request stops reading and gRPC flow control pushes back on the client.
+func (g *grpcIngest) ingest(stream grpc.ServerStream, recv func() (map[string]interface{}, error)) (err error) {
+	ctx := stream.Context()
+	md, _ := metadata.FromIncomingContext(ctx)
+	if g.token != "" {
+		presented := strings.TrimPrefix(firstMetadata(md, "authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(g.token)) != 1 {
+			return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+		}
+	}
+	index := strings.TrimSpace(firstMetadata(md, "index"))
+	if index == "" {
+		return status.Error(codes.InvalidArgument, "index metadata is required")
+	}
+
+	transforms, err := buildDocTransforms(g.load)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	dedup, err := newDeduper(g.load.DedupField, g.load.DedupPolicy)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if dedup.needsScan() {
+		return status.Errorf(codes.FailedPrecondition, "-dedup-policy %s needs the whole input and is not supported on streams", dedupKeepLast)
+	}
+	execStep, err := startExecTransform(g.load.TransformExec, g.load.TransformExecTimeout)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer execStep.close()
+	if execStep != nil {
+		transforms = append(transforms, execStep.transform())
+	}
+	if dedup != nil {
+		transforms = append(transforms, dedup.transform())
+	}
+	batcher := newBulkBatcher(ctx, g.es, index, g.batchSize, 0, g.settings)
+	batcher.transforms = transforms
+	received, rejected := 0, 0
+	defer func() {
+		// bulkInsert reports exhausted retries by panicking with a RunError.
+		if recovered := recover(); recovered != nil {
+			runErr, ok := recovered.(*RunError)
+			if !ok {
+				panic(recovered)
+			}
+			log.Error().Err(runErr).Str("index", index).Int("received", received).Msg("gRPC ingestion stream failed")
+			err = status.Error(codes.Unavailable, runErr.Error())
+		}
+	}()
+
+	messages := make(chan grpcMessage)
+	readCtx, stopReading := context.WithCancel(ctx)
+	defer stopReading()
+	go func() {
+		for {
+			doc, err := recv()
+			select {
+			case messages <- grpcMessage{doc: doc, err: err}:
+			case <-readCtx.Done():
+				return
+			}
+			var malformed malformedDocumentError
+			if err != nil && !errors.As(err, &malformed) {
+				return
+			}
+		}
+	}()
+
+	log.Info().Str("index", index).Msg("gRPC ingestion stream opened")
+	ticker := time.NewTicker(g.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			batcher.flush()
+		case msg := <-messages:
+			var malformed malformedDocumentError
+			switch {
+			case msg.err == nil:
+				received++
+				batcher.add(msg.doc)
+			case errors.As(msg.err, &malformed):
+				rejected++
+				log.Warn().Err(msg.err).Str("index", index).Msg("Skipping malformed document on gRPC stream")
+			case errors.Is(msg.err, io.EOF):
+				batcher.flush()
+				log.Info().Str("index", index).Int("received", received).Int("succeeded", batcher.succeeded).Int("failed", batcher.failed).Msg("gRPC ingestion stream closed")
+				return g.reply(stream, index, received, rejected, batcher)
+			default:
+				return msg.err
+			}
+		}
+	}
+}
+
+// reply sends the stream summary.
+func (g *grpcIngest) reply(stream grpc.ServerStream, index string, received, rejected int, batcher *bulkBatcher) error {
+	summary, err := structpb.NewStruct(map[string]interface{}{
+		"index":     index,
+		"received":  received,
+		"rejected":  rejected,
+		"succeeded": batcher.succeeded,
+		"failed":    batcher.failed,
+		"batches":   batcher.batches,
+	})
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return stream.SendMsg(summary)
+}
+
+// firstMetadata returns the first value for key, or "".
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// serveGRPC runs the ingestion service on listener until ctx is cancelled.
+// Open streams get serveShutdownTimeout to finish before they are cut off.
+func (g *grpcIngest) serveGRPC(ctx context.Context, listener net.Listener) error {
+	grpcServer := grpc.NewServer()
+	grpcServer.RegisterService(g.serviceDesc(), nil)
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- grpcServer.Serve(listener) }()
+	log.Info().Str("addr", listener.Addr().String()).Msg("gRPC ingestion listening")
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(serveShutdownTimeout):
+		log.Warn().Msg("gRPC streams still open at shutdown; closing them")
+		grpcServer.Stop()
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package loader
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// startGRPCIngest serves the ingestion service against a fake cluster that
+// records bulk bodies, returning a connected client.
+func startGRPCIngest(t *testing.T, token string) (*grpc.ClientConn, func() string) {
+	t.Helper()
+	var (
+		mu     sync.Mutex
+		bodies strings.Builder
+	)
+	cluster := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.Method != http.MethodPost || r.URL.Path != "/_bulk" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies.Write(body)
+		mu.Unlock()
+		items := strings.Repeat(`{"index":{"status":201}},`, strings.Count(string(body), "\n")/2)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"errors":false,"items":[` + strings.TrimSuffix(items, ",") + `]}`))
+	}))
+	t.Cleanup(cluster.Close)
+
+	ingest, err := newGRPCIngest(ServerOptions{Token: token, Load: Options{URL: cluster.URL, BatchSize: 2}})
+	if err != nil {
+		t.Fatalf("newGRPCIngest returned error: %v", err)
+	}
+	listener := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ingest.serveGRPC(ctx, listener) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("serveGRPC returned error: %v", err)
+		}
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return bodies.String()
+	}
+}
+
+// streamDocuments sends messages on method and returns the summary reply.
+func streamDocuments(ctx context.Context, conn *grpc.ClientConn, method string, messages []proto.Message) (*structpb.Struct, error) {
+	desc := &grpc.StreamDesc{StreamName: method, ClientStreams: true}
+	stream, err := conn.NewStream(ctx, desc, "/"+grpcIngestService+"/"+method)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range messages {
+		if err := stream.SendMsg(msg); err != nil {
+			return nil, err
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	summary := new(structpb.Struct)
+	if err := stream.RecvMsg(summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// TestGRPCIngestBatchesStreamedDocuments verifies behavior for the related scenario.
+func TestGRPCIngestBatchesStreamedDocuments(t *testing.T) {
+	t.Parallel()
+	conn, sent := startGRPCIngest(t, "secret")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "index", "events", "authorization", "Bearer secret")
+
+	first, _ := structpb.NewStruct(map[string]interface{}{"msg": "one"})
+	second, _ := structpb.NewStruct(map[string]interface{}{"msg": "two"})
+	third, _ := structpb.NewStruct(map[string]interface{}{"msg": "three"})
+	summary, err := streamDocuments(ctx, conn, "StreamStructs", []proto.Message{first, second, third})
+	if err != nil {
+		t.Fatalf("StreamStructs returned error: %v", err)
+	}
+	fields := summary.AsMap()
+	if fields["received"] != float64(3) || fields["succeeded"] != float64(3) || fields["batches"] != float64(2) {
+		t.Fatalf("unexpected StreamStructs summary: %v", fields)
+	}
+
+	summary, err = streamDocuments(ctx, conn, "StreamJSON", []proto.Message{
+		wrapperspb.Bytes([]byte(`{"msg":"four"}`)),
+		wrapperspb.Bytes([]byte(`not json`)),
+	})
+	if err != nil {
+		t.Fatalf("StreamJSON returned error: %v", err)
+	}
+	fields = summary.AsMap()
+	if fields["received"] != float64(1) || fields["rejected"] != float64(1) || fields["succeeded"] != float64(1) {
+		t.Fatalf("unexpected StreamJSON summary: %v", fields)
+	}
+
+	body := sent()
+	for _, want := range []string{`"_index":"events"`, `"msg":"one"`, `"msg":"three"`, `"msg":"four"`} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("bulk bodies missing %s:\n%s", want, body)
+		}
+	}
+}
+
+// TestGRPCIngestRejectsInvalidStreams verifies behavior for the related scenario.
+func TestGRPCIngestRejectsInvalidStreams(t *testing.T) {
+	t.Parallel()
+	conn, _ := startGRPCIngest(t, "secret")
+
+	tests := []struct {
+		name string
+		md   []string
+		code codes.Code
+	}{
+		{name: "missing token", md: []string{"index", "events"}, code: codes.Unauthenticated},
+		{name: "wrong token", md: []string{"index", "events", "authorization", "Bearer nope"}, code: codes.Unauthenticated},
+		{name: "missing index", md: []string{"authorization", "Bearer secret"}, code: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			ctx = metadata.AppendToOutgoingContext(ctx, tt.md...)
+			_, err := streamDocuments(ctx, conn, "StreamJSON", nil)
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
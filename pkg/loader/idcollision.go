@@ -0,0 +1,76 @@
+package loader
+
+import (
+	"hash/maphash"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Duplicate _id Report ──────────────────────────────────────────────────────
+
+// idCollisionSamples is how many repeated _ids the report names.
+const idCollisionSamples = 5
+
+// idCollisions tracks the _ids -id gave the documents of a load, so ids the
+// input repeats, whose later documents silently overwrite earlier ones, can
+// be reported. Seen ids are kept as 64-bit hashes to bound memory.
+type idCollisions struct {
+	mu   sync.Mutex
+	seed maphash.Seed
+	seen map[uint64]struct{}
+	// count is how many documents reused an earlier document's _id.
+	count   int
+	samples []string
+}
+
+// newIDCollisions creates a tracker for a load that sets _ids from idField;
+// without one it returns nil, which observes nothing.
+func newIDCollisions(idField string) *idCollisions {
+	if idField == "" {
+		return nil
+	}
+	return &idCollisions{seed: maphash.MakeSeed(), seen: make(map[uint64]struct{})}
+}
+
+// observe records that a document with id is sent to index. The same _id in
+// two indices is not a collision.
+func (c *idCollisions) observe(index, id string) {
+	if c == nil || id == "" {
+		return
+	}
+	var h maphash.Hash
+	h.SetSeed(c.seed)
+	h.WriteString(index)
+	h.WriteByte(0)
+	h.WriteString(id)
+	key := h.Sum64()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.seen[key]; !ok {
+		c.seen[key] = struct{}{}
+		return
+	}
+	c.count++
+	if len(c.samples) < idCollisionSamples {
+		for _, sample := range c.samples {
+			if sample == id {
+				return
+			}
+		}
+		c.samples = append(c.samples, id)
+	}
+}
+
+// report logs the collisions, if any, and returns their count and samples.
+func (c *idCollisions) report(idField string) (int, []string) {
+	if c == nil || c.count == 0 {
+		return 0, nil
+	}
+	log.Warn().
+		Str("id_field", idField).
+		Int("duplicates", c.count).
+		Strs("sample_ids", c.samples).
+		Msg("Input repeats document _ids; each later document overwrote an earlier one")
+	return c.count, c.samples
+}
@@ -0,0 +1,60 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRunReportsDuplicateIDs verifies behavior for the related scenario.
+func TestRunReportsDuplicateIDs(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.Method != http.MethodPost || r.URL.Path != "/_bulk" {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	data := strings.Join([]string{
+		`{"code":"a"}`, `{"code":"b"}`, `{"code":"a"}`, `{"code":"c"}`, `{"code":"b"}`, `{"code":"a"}`, `{"name":"no code"}`, `{"name":"no code"}`,
+	}, "\n") + "\n"
+	tests := []struct {
+		name        string
+		idField     string
+		rename      bool
+		wantCount   int
+		wantSamples []string
+	}{
+		{name: "raw lines", idField: "code", wantCount: 3, wantSamples: []string{"a", "b"}},
+		{name: "transformed documents", idField: "code", rename: true, wantCount: 3, wantSamples: []string{"a", "b"}},
+		{name: "generated ids", wantCount: 0},
+	}
+	for _, tt := range tests {
+		opts := Options{
+			URL:        server.URL,
+			Index:      "cards",
+			IDField:    tt.idField,
+			DataFile:   writeTestDataFile(t, "cards.ndjson", data),
+			AddToIndex: true,
+			BatchSize:  2,
+		}
+		if tt.rename {
+			opts.Rename = []string{"name=title"}
+		}
+		result, err := Run(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		if result.DuplicateIDs != tt.wantCount || strings.Join(result.DuplicateIDSamples, ",") != strings.Join(tt.wantSamples, ",") {
+			t.Fatalf("%s: expected %d duplicates %v, got %d %v", tt.name, tt.wantCount, tt.wantSamples, result.DuplicateIDs, result.DuplicateIDSamples)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package loader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// ─── Incremental Sync ──────────────────────────────────────────────────────────
+
+// defaultSyncHashField stores each document's content hash for -sync.
+const defaultSyncHashField = "content_hash"
+
+// incrementalSync stamps each document with a hash of its content and, per
+// batch, drops the documents the index already holds with the same hash.
+type incrementalSync struct {
+	field string
+	// unchanged counts documents left out because their hash matched.
+	unchanged int
+}
+
+// newIncrementalSync returns the -sync step that keeps hashes in field.
+func newIncrementalSync(field string) *incrementalSync {
+	if strings.TrimSpace(field) == "" {
+		field = defaultSyncHashField
+	}
+	return &incrementalSync{field: field}
+}
+
+// validateIncrementalSync rejects -sync where it cannot skip anything.
+func validateIncrementalSync(opts Options, action dataAction, format string) error {
+	if !opts.Sync {
+		return nil
+	}
+	if action != dataActionAdd {
+		return fmt.Errorf("-sync compares with the existing index and needs -add")
+	}
+	if opts.IDField == "" && format != formatElasticdump {
+		return fmt.Errorf("-sync matches documents by _id and needs -id or -format %s", formatElasticdump)
+	}
+	return nil
+}
+
+// transform sets the hash field to the SHA-256 of the rest of the
+// document, encoded as JSON with sorted keys.
+func (s *incrementalSync) transform() docTransform {
+	return inPlace(func(doc map[string]interface{}) {
+		delete(doc, s.field)
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			return
+		}
+		sum := sha256.Sum256(encoded)
+		doc[s.field] = hex.EncodeToString(sum[:])
+	})
+}
+
+// changed looks up the stored hashes of batch with one _mget and returns
+// the documents that are new or whose hash differs. Documents without an
+// _id are always kept.
+func (s *incrementalSync) changed(ctx context.Context, es *elasticsearch.Client, index string, batch []map[string]interface{}, settings bulkSettings) ([]map[string]interface{}, error) {
+	fetched, positions, err := mgetBatch(ctx, es, index, batch, settings, es.Mget.WithSourceIncludes(s.field))
+	if err != nil {
+		return nil, err
+	}
+
+	skip := make(map[int]bool)
+	for i, doc := range fetched.Docs {
+		if !doc.Found {
+			continue
+		}
+		var stored map[string]interface{}
+		if err := json.Unmarshal(doc.Source, &stored); err != nil {
+			continue
+		}
+		position := positions[i]
+		if hash, ok := stored[s.field].(string); ok && hash == batch[position][s.field] {
+			skip[position] = true
+		}
+	}
+	if len(skip) == 0 {
+		return batch, nil
+	}
+	kept := make([]map[string]interface{}, 0, len(batch)-len(skip))
+	for i, doc := range batch {
+		if !skip[i] {
+			kept = append(kept, doc)
+		}
+	}
+	s.unchanged += len(skip)
+	return kept, nil
+}
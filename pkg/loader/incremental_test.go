@@ -0,0 +1,113 @@
+package loader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestRunSyncSkipsUnchangedDocuments verifies behavior for the related scenario.
+func TestRunSyncSkipsUnchangedDocuments(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		stored  = map[string]string{}
+		indexed []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/_mget":
+			if r.URL.Query().Get("_source_includes") != "content_hash" {
+				t.Errorf("expected _source_includes=content_hash, got %q", r.URL.RawQuery)
+			}
+			var request struct {
+				Docs []struct {
+					ID    string `json:"_id"`
+					Index string `json:"_index"`
+				} `json:"docs"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			docs := make([]string, 0, len(request.Docs))
+			for _, doc := range request.Docs {
+				if source, ok := stored[doc.ID]; ok && doc.Index == "cards" {
+					docs = append(docs, `{"_id":"`+doc.ID+`","found":true,"_source":`+source+`}`)
+				} else {
+					docs = append(docs, `{"_id":"`+doc.ID+`","found":false}`)
+				}
+			}
+			_, _ = w.Write([]byte(`{"docs":[` + strings.Join(docs, ",") + `]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			scanner := bufio.NewScanner(r.Body)
+			var items []string
+			for scanner.Scan() {
+				var action struct {
+					Index struct {
+						ID string `json:"_id"`
+					} `json:"index"`
+				}
+				_ = json.Unmarshal(scanner.Bytes(), &action)
+				scanner.Scan()
+				stored[action.Index.ID] = scanner.Text()
+				indexed = append(indexed, action.Index.ID)
+				items = append(items, `{"index":{"_index":"cards","_id":"`+action.Index.ID+`","status":201}}`)
+			}
+			_, _ = w.Write([]byte(`{"errors":false,"items":[` + strings.Join(items, ",") + `]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dataFile := filepath.Join(t.TempDir(), "cards.ndjson")
+	write := func(body string) {
+		if err := os.WriteFile(dataFile, []byte(body), 0o600); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+	}
+	opts := Options{URL: server.URL, Index: "cards", DataFile: dataFile, AddToIndex: true, IDField: "code", BatchSize: 2, Sync: true}
+	tests := []struct {
+		name          string
+		data          string
+		wantIndexed   []string
+		wantUnchanged int
+	}{
+		{name: "first load", data: "{\"code\":\"a\",\"n\":1}\n{\"code\":\"b\",\"n\":2}\n{\"code\":\"c\",\"n\":3}\n", wantIndexed: []string{"a", "b", "c"}},
+		{name: "unchanged", data: "{\"n\":1,\"code\":\"a\"}\n{\"code\":\"b\",\"n\":2}\n{\"code\":\"c\",\"n\":3}\n", wantUnchanged: 3},
+		{name: "one changed, one new", data: "{\"code\":\"a\",\"n\":1}\n{\"code\":\"b\",\"n\":20}\n{\"code\":\"c\",\"n\":3}\n{\"code\":\"d\",\"n\":4}\n", wantIndexed: []string{"b", "d"}, wantUnchanged: 2},
+	}
+	for _, tt := range tests {
+		write(tt.data)
+		mu.Lock()
+		indexed = nil
+		mu.Unlock()
+		result, err := Run(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		mu.Lock()
+		got := strings.Join(indexed, ",")
+		mu.Unlock()
+		if got != strings.Join(tt.wantIndexed, ",") || result.DocumentsUnchanged != tt.wantUnchanged {
+			t.Fatalf("%s: indexed %q with %d unchanged, want %v with %d", tt.name, got, result.DocumentsUnchanged, tt.wantIndexed, tt.wantUnchanged)
+		}
+	}
+
+	opts.AddToIndex, opts.FlushIndex = false, true
+	if _, err := Run(context.Background(), opts); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions for -sync with -flush, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package loader
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ─── Index Names ───────────────────────────────────────────────────────────────
+
+// forbiddenIndexNameChars cannot appear anywhere in an index or alias name.
+const forbiddenIndexNameChars = `\/*?"<>| ,#:`
+
+// forbiddenIndexNamePrefixes cannot start an index or alias name.
+const forbiddenIndexNamePrefixes = "-_+"
+
+// validateIndexName checks name against Elasticsearch's naming rules for
+// indices and aliases, so a bad name fails before anything is written
+// instead of as a 400 from the create or bulk request.
+func validateIndexName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("index name must not be empty")
+	case name == "." || name == "..":
+		return fmt.Errorf("index name must not be %q", name)
+	case len(name) > maxIndexNameBytes:
+		return fmt.Errorf("index name %q is %d bytes; the limit is %d", name, len(name), maxIndexNameBytes)
+	case name != strings.ToLower(name):
+		return fmt.Errorf("index name %q must be lowercase", name)
+	case strings.ContainsAny(name[:1], forbiddenIndexNamePrefixes):
+		return fmt.Errorf("index name %q must not start with %q", name, name[:1])
+	}
+	if i := strings.IndexAny(name, forbiddenIndexNameChars); i >= 0 {
+		return fmt.Errorf("index name %q must not contain %q", name, name[i:i+1])
+	}
+	return nil
+}
+
+// sanitizeIndexName rewrites name to follow validateIndexName: it is
+// lowercased, forbidden characters become hyphens, forbidden leading
+// characters are dropped, and it is cut to the length limit. It returns ""
+// when nothing usable is left.
+func sanitizeIndexName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(forbiddenIndexNameChars, r) {
+			return '-'
+		}
+		return r
+	}, strings.ToLower(name))
+	name = strings.TrimLeft(name, forbiddenIndexNamePrefixes)
+	for len(name) > maxIndexNameBytes {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "." || name == ".." {
+		return ""
+	}
+	return name
+}
+
+// documentIndexNameTransform checks the _index each -preserve-index
+// document is written back to. An invalid name fails the load before its
+// batch is sent or, with sanitize, is rewritten; one that sanitizes to
+// nothing is dropped, so the document goes to -index.
+func documentIndexNameTransform(sanitize bool) docTransform {
+	return func(doc map[string]interface{}) ([]map[string]interface{}, error) {
+		index, ok := doc[metaIndex].(string)
+		if !ok || index == "" {
+			return []map[string]interface{}{doc}, nil
+		}
+		err := validateIndexName(index)
+		switch {
+		case err == nil:
+		case !sanitize:
+			return nil, fmt.Errorf("document %s: %w; pass -sanitize-index-names to rewrite such names", metaIndex, err)
+		case sanitizeIndexName(index) == "":
+			delete(doc, metaIndex)
+		default:
+			doc[metaIndex] = sanitizeIndexName(index)
+		}
+		return []map[string]interface{}{doc}, nil
+	}
+}
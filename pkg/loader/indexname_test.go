@@ -0,0 +1,133 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestValidateIndexName verifies behavior for the related scenario.
+func TestValidateIndexName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		index   string
+		wantErr string
+	}{
+		{name: "plain", index: "logs-2024.01"},
+		{name: "hidden", index: ".internal"},
+		{name: "unicode", index: "ventes-côte"},
+		{name: "empty", index: "", wantErr: "must not be empty"},
+		{name: "dot", index: "..", wantErr: `must not be ".."`},
+		{name: "uppercase", index: "Logs", wantErr: "must be lowercase"},
+		{name: "leading underscore", index: "_logs", wantErr: `must not start with "_"`},
+		{name: "leading hyphen", index: "-logs", wantErr: `must not start with "-"`},
+		{name: "wildcard", index: "logs-*", wantErr: `must not contain "*"`},
+		{name: "space", index: "my logs", wantErr: `must not contain " "`},
+		{name: "colon", index: "remote:logs", wantErr: `must not contain ":"`},
+		{name: "too long", index: strings.Repeat("a", 256), wantErr: "the limit is 255"},
+	}
+	for _, tt := range tests {
+		err := validateIndexName(tt.index)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// TestSanitizeIndexName verifies behavior for the related scenario.
+func TestSanitizeIndexName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		index string
+		want  string
+	}{
+		{index: "Logs 2024/01", want: "logs-2024-01"},
+		{index: "_+-Cards", want: "cards"},
+		{index: "a*b?c", want: "a-b-c"},
+		{index: "already-fine", want: "already-fine"},
+		{index: "__", want: ""},
+		{index: "..", want: ""},
+		{index: strings.Repeat("b", 300), want: strings.Repeat("b", 255)},
+	}
+	for _, tt := range tests {
+		got := sanitizeIndexName(tt.index)
+		if got != tt.want {
+			t.Fatalf("%q: expected %q, got %q", tt.index, tt.want, got)
+		}
+		if got != "" {
+			if err := validateIndexName(got); err != nil {
+				t.Fatalf("%q: sanitized name %q is still invalid: %v", tt.index, got, err)
+			}
+		}
+	}
+}
+
+// TestDocumentIndexNameTransform verifies behavior for the related scenario.
+func TestDocumentIndexNameTransform(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		doc       map[string]interface{}
+		sanitize  bool
+		wantIndex interface{}
+		wantErr   string
+	}{
+		{name: "valid", doc: map[string]interface{}{"_index": "cards-2024"}, wantIndex: "cards-2024"},
+		{name: "no index", doc: map[string]interface{}{"name": "x"}, wantIndex: nil},
+		{name: "invalid", doc: map[string]interface{}{"_index": "Cards 2024"}, wantErr: "-sanitize-index-names"},
+		{name: "sanitized", doc: map[string]interface{}{"_index": "Cards 2024"}, sanitize: true, wantIndex: "cards-2024"},
+		{name: "nothing left", doc: map[string]interface{}{"_index": "_"}, sanitize: true, wantIndex: nil},
+	}
+	for _, tt := range tests {
+		docs, err := documentIndexNameTransform(tt.sanitize)(tt.doc)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || len(docs) != 1 {
+			t.Fatalf("%s: expected one document, got %v, %v", tt.name, docs, err)
+		}
+		if got := docs[0]["_index"]; got != tt.wantIndex {
+			t.Fatalf("%s: expected _index %v, got %v", tt.name, tt.wantIndex, got)
+		}
+	}
+}
+
+// TestRunRejectsInvalidIndexName verifies behavior for the related scenario.
+func TestRunRejectsInvalidIndexName(t *testing.T) {
+	t.Parallel()
+
+	data := writeTestDataFile(t, "cards.ndjson", "{\"name\":\"a\"}\n")
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{name: "uppercase index", opts: Options{Index: "Cards", DataFile: data, AddToIndex: true}, wantErr: "must be lowercase"},
+		{name: "wildcard index", opts: Options{Index: "cards-*", DataFile: data, DeleteIndex: true}, wantErr: `must not contain "*"`},
+		{name: "sanitize without preserve", opts: Options{Index: "cards", DataFile: data, AddToIndex: true, SanitizeIndexNames: true}, wantErr: "needs -preserve-index"},
+		// 241 bytes fit on their own but not with the 15-byte generation suffix.
+		{name: "alias generation too long", opts: Options{Index: strings.Repeat("c", 241), DataFile: data, DeleteIndex: true, AliasMode: true}, wantErr: "with -alias each generation"},
+	}
+	for _, tt := range tests {
+		// No cluster is needed: the options are rejected before connecting.
+		tt.opts.URL = "http://127.0.0.1:1"
+		_, err := Run(context.Background(), tt.opts)
+		if !errors.Is(err, ErrInvalidOptions) || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected ErrInvalidOptions containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
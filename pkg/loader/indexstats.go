@@ -0,0 +1,117 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Per-Index Totals ──────────────────────────────────────────────────────────
+
+// IndexResult totals one target index of a load. Documents routed to their
+// own _index, as -preserve-index does, are counted under that index.
+type IndexResult struct {
+	Index              string
+	DocumentsSucceeded int
+	DocumentsFailed    int
+	// BytesSent counts the action and source lines sent for the index,
+	// retries included.
+	BytesSent int64
+}
+
+// indexTallies maps each target index to its totals.
+type indexTallies map[string]*IndexResult
+
+// add adds size bytes and the document counts to index's totals.
+func (t indexTallies) add(index string, size int64, succeeded, failed int) {
+	tally, ok := t[index]
+	if !ok {
+		tally = &IndexResult{Index: index}
+		t[index] = tally
+	}
+	tally.DocumentsSucceeded += succeeded
+	tally.DocumentsFailed += failed
+	tally.BytesSent += size
+}
+
+// merge adds every total of other.
+func (t indexTallies) merge(other indexTallies) {
+	for index, tally := range other {
+		t.add(index, tally.BytesSent, tally.DocumentsSucceeded, tally.DocumentsFailed)
+	}
+}
+
+// sorted returns the totals ordered by index name.
+func (t indexTallies) sorted() []IndexResult {
+	results := make([]IndexResult, 0, len(t))
+	for _, tally := range t {
+		results = append(results, *tally)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	return results
+}
+
+// tallyBulkIndices splits a sent batch's outcome by target index. Without
+// per-document routing everything goes to index; otherwise each item's
+// action line names its target. failed and requeued flag items by
+// position; requeued items only add their bytes here.
+func tallyBulkIndices(index string, payload []byte, docs int, failed, requeued []bool, perDocument bool) indexTallies {
+	tallies := make(indexTallies)
+	lines := bytes.SplitAfter(payload, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if !perDocument || len(lines) != 2*docs {
+		failures, resent := 0, 0
+		for i := range docs {
+			if i < len(failed) && failed[i] {
+				failures++
+			} else if i < len(requeued) && requeued[i] {
+				resent++
+			}
+		}
+		tallies.add(index, int64(len(payload)), docs-failures-resent, failures)
+		return tallies
+	}
+	for i := range docs {
+		target := index
+		var action map[string]struct {
+			Index string `json:"_index"`
+		}
+		if json.Unmarshal(lines[2*i], &action) == nil {
+			for _, meta := range action {
+				if meta.Index != "" {
+					target = meta.Index
+				}
+			}
+		}
+		size := int64(len(lines[2*i]) + len(lines[2*i+1]))
+		switch {
+		case i < len(failed) && failed[i]:
+			tallies.add(target, size, 0, 1)
+		case i < len(requeued) && requeued[i]:
+			tallies.add(target, size, 0, 0)
+		default:
+			tallies.add(target, size, 1, 0)
+		}
+	}
+	return tallies
+}
+
+// logIndexResults logs one summary line per index when a load wrote to
+// more than one.
+func logIndexResults(results []IndexResult) {
+	if len(results) < 2 {
+		return
+	}
+	for _, result := range results {
+		log.Info().
+			Str("index", result.Index).
+			Int("succeeded", result.DocumentsSucceeded).
+			Int("failed", result.DocumentsFailed).
+			Int64("bytes", result.BytesSent).
+			Msg("Bulk load index summary")
+	}
+}
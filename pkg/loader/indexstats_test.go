@@ -0,0 +1,88 @@
+package loader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRunReportsPerIndexResults verifies behavior for the related scenario.
+func TestRunReportsPerIndexResults(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.Method != http.MethodPost || r.URL.Path != "/_bulk" {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		scanner := bufio.NewScanner(r.Body)
+		var items []string
+		for scanner.Scan() {
+			var action map[string]struct {
+				Index string `json:"_index"`
+				ID    string `json:"_id"`
+			}
+			_ = json.Unmarshal(scanner.Bytes(), &action)
+			scanner.Scan()
+			meta := action["index"]
+			if meta.ID == "b2" {
+				items = append(items, `{"index":{"_index":"`+meta.Index+`","status":400,"error":{"type":"mapper_parsing_exception","reason":"bad"}}}`)
+			} else {
+				items = append(items, `{"index":{"_index":"`+meta.Index+`","status":201}}`)
+			}
+		}
+		_, _ = w.Write([]byte(`{"errors":true,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name          string
+		preserveIndex bool
+		want          []IndexResult
+	}{
+		{
+			name: "one target",
+			want: []IndexResult{{Index: "cards", DocumentsSucceeded: 2, DocumentsFailed: 1, BytesSent: 167}},
+		},
+		{
+			name:          "per-document targets",
+			preserveIndex: true,
+			want: []IndexResult{
+				{Index: "cards-2024", DocumentsSucceeded: 1, BytesSent: 60},
+				{Index: "cards-2025", DocumentsFailed: 1, DocumentsSucceeded: 1, BytesSent: 122},
+			},
+		},
+	}
+	for _, tt := range tests {
+		result, err := Run(context.Background(), Options{
+			URL:           server.URL,
+			Index:         "cards",
+			DataFile:      writeTestDataFile(t, "cards.json", elasticdumpTestExport),
+			AddToIndex:    true,
+			Format:        formatElasticdump,
+			PreserveIndex: tt.preserveIndex,
+		})
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		if len(result.Indices) != len(tt.want) {
+			t.Fatalf("%s: expected %+v, got %+v", tt.name, tt.want, result.Indices)
+		}
+		var bytes int64
+		for i, want := range tt.want {
+			if result.Indices[i] != want {
+				t.Fatalf("%s: expected %+v, got %+v", tt.name, want, result.Indices[i])
+			}
+			bytes += want.BytesSent
+		}
+		if bytes != result.BytesSent {
+			t.Fatalf("%s: per-index bytes %d do not add up to %d", tt.name, bytes, result.BytesSent)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Template-Managed Indices ──────────────────────────────────────────────────
+
+// validateUseTemplates rejects -use-templates with index definitions of the
+// loader's own, which would override what the templates set.
+func validateUseTemplates(opts Options) error {
+	if !opts.UseTemplates {
+		return nil
+	}
+	if opts.SettingsFile != "" || opts.MappingsFile != "" {
+		return fmt.Errorf("-use-templates creates indices from installed index templates; drop -settings and -mappings")
+	}
+	return nil
+}
+
+// legacyTemplateList is the body of GET /_template.
+type legacyTemplateList map[string]struct {
+	IndexPatterns []string `json:"index_patterns"`
+	Order         int      `json:"order"`
+}
+
+// verifyIndexTemplate returns the template index would be created from: the
+// highest priority composable template, or else the highest order legacy
+// template, matching its name.
+func verifyIndexTemplate(es *elasticsearch.Client, index string) (indexTemplateMatch, error) {
+	match, matched, err := matchIndexTemplate(es, index)
+	if err != nil || matched {
+		return match, err
+	}
+	res, err := es.Indices.GetTemplate()
+	if err != nil {
+		return match, err
+	}
+	defer res.Body.Close()
+	if res.IsError() && res.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(res.Body)
+		return match, fmt.Errorf("listing legacy index templates: status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var legacy legacyTemplateList
+	if res.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(res.Body).Decode(&legacy); err != nil {
+			return match, fmt.Errorf("parsing legacy index templates: %w", err)
+		}
+	}
+	order := 0
+	for name, template := range legacy {
+		if matched && (template.Order < order || template.Order == order && name > match.Name) {
+			continue
+		}
+		for _, pattern := range template.IndexPatterns {
+			if wildcardMatch(pattern, index) {
+				match, order, matched = indexTemplateMatch{Name: name}, template.Order, true
+				break
+			}
+		}
+	}
+	if !matched {
+		return match, fmt.Errorf("no index template matches %q; install one or drop -use-templates", index)
+	}
+	return match, nil
+}
+
+// logIndexTemplate reports the template an index will be created from.
+func logIndexTemplate(index string, template indexTemplateMatch) {
+	event := log.Info().Str("index", index).Str("template", template.Name)
+	if template.DataStream {
+		event.Msg("Index template creates a data stream on first write")
+		return
+	}
+	event.Msg("Creating index from its index template")
+}
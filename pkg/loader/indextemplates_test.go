@@ -0,0 +1,176 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// indexTemplateServer answers template listings with composable and legacy,
+// and records index creation and bulk request bodies.
+type indexTemplateServer struct {
+	mu         sync.Mutex
+	composable string
+	legacy     string
+	createBody string
+	created    bool
+	bulkBodies []string
+}
+
+func (s *indexTemplateServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/_index_template":
+		_, _ = w.Write([]byte(s.composable))
+	case r.Method == http.MethodGet && r.URL.Path == "/_template":
+		_, _ = w.Write([]byte(s.legacy))
+	case r.Method == http.MethodHead && r.URL.Path == "/logs-app":
+		if s.created {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPut && r.URL.Path == "/logs-app":
+		body, _ := io.ReadAll(r.Body)
+		s.createBody, s.created = string(body), true
+		_, _ = w.Write([]byte(`{"acknowledged":true}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+		body, _ := io.ReadAll(r.Body)
+		s.bulkBodies = append(s.bulkBodies, string(body))
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	default:
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+// TestVerifyIndexTemplate verifies behavior for the related scenario.
+func TestVerifyIndexTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		composable string
+		legacy     string
+		want       indexTemplateMatch
+		wantErr    bool
+	}{
+		{
+			name: "composable by priority",
+			composable: `{"index_templates":[
+				{"name":"logs","index_template":{"index_patterns":["logs-*"],"priority":100}},
+				{"name":"logs-app","index_template":{"index_patterns":["logs-app*"],"priority":200,"data_stream":{}}},
+				{"name":"metrics","index_template":{"index_patterns":["metrics-*"],"priority":300}}
+			]}`,
+			legacy: `{}`,
+			want:   indexTemplateMatch{Name: "logs-app", DataStream: true},
+		},
+		{
+			name:       "legacy by order",
+			composable: `{"index_templates":[]}`,
+			legacy:     `{"old":{"index_patterns":["logs-*"],"order":1},"older":{"index_patterns":["*"],"order":0}}`,
+			want:       indexTemplateMatch{Name: "old"},
+		},
+		{
+			name:       "none",
+			composable: `{"index_templates":[{"name":"metrics","index_template":{"index_patterns":["metrics-*"]}}]}`,
+			legacy:     `{}`,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(&indexTemplateServer{composable: tt.composable, legacy: tt.legacy})
+		es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{server.URL}})
+		if err != nil {
+			t.Fatalf("%s: NewClient returned error: %v", tt.name, err)
+		}
+		got, err := verifyIndexTemplate(es, "logs-app")
+		server.Close()
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "no index template matches") {
+				t.Fatalf("%s: expected a missing template error, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Fatalf("%s: expected %+v, got %+v (%v)", tt.name, tt.want, got, err)
+		}
+	}
+}
+
+// TestRunUseTemplates verifies behavior for the related scenario.
+func TestRunUseTemplates(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		composable string
+		wantCreate bool
+		wantAction string
+		wantErr    error
+	}{
+		{
+			name:       "index template",
+			composable: `{"index_templates":[{"name":"logs","index_template":{"index_patterns":["logs-*"]}}]}`,
+			wantCreate: true,
+			wantAction: "index",
+		},
+		{
+			name:       "data stream template",
+			composable: `{"index_templates":[{"name":"logs","index_template":{"index_patterns":["logs-*"],"data_stream":{}}}]}`,
+			wantAction: "create",
+		},
+		{
+			name:       "no template",
+			composable: `{"index_templates":[]}`,
+			wantErr:    ErrIndexOperation,
+		},
+	}
+	for _, tt := range tests {
+		handler := &indexTemplateServer{composable: tt.composable, legacy: `{}`}
+		server := httptest.NewServer(handler)
+		_, err := Run(context.Background(), Options{
+			URL:          server.URL,
+			Index:        "logs-app",
+			DataFile:     writeTestDataFile(t, "logs.ndjson", "{\"message\":\"a\"}\n"),
+			AddToIndex:   true,
+			UseTemplates: true,
+		})
+		server.Close()
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("%s: expected %v, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		if handler.created != tt.wantCreate || (tt.wantCreate && handler.createBody != "{}") {
+			t.Fatalf("%s: expected create %v with an empty body, got %v %q", tt.name, tt.wantCreate, handler.created, handler.createBody)
+		}
+		if len(handler.bulkBodies) != 1 || !strings.HasPrefix(handler.bulkBodies[0], `{"`+tt.wantAction+`":`) {
+			t.Fatalf("%s: expected one request of %s actions, got %v", tt.name, tt.wantAction, handler.bulkBodies)
+		}
+	}
+
+	_, err := Run(context.Background(), Options{
+		Index:        "logs-app",
+		DataFile:     writeTestDataFile(t, "logs.ndjson", "{\"message\":\"a\"}\n"),
+		MappingsFile: "mappings.json",
+		AddToIndex:   true,
+		UseTemplates: true,
+	})
+	if !errors.Is(err, ErrInvalidOptions) || !strings.Contains(err.Error(), "-use-templates") {
+		t.Fatalf("expected -use-templates with -mappings to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,426 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ─── Input Formats ─────────────────────────────────────────────────────────────
+
+const (
+	// formatJSON is a JSON array of objects, the original -data format.
+	formatJSON = "json"
+	// formatNDJSON is one JSON object per line.
+	formatNDJSON = "ndjson"
+	// formatCSV is comma-separated values, named by a header row or -column-map.
+	formatCSV = "csv"
+	// formatFixed is fixed-width records sliced by a -layout.
+	formatFixed = "fixed"
+	// formatMsgpack is a stream of concatenated MessagePack maps.
+	formatMsgpack = "msgpack"
+	// formatCBOR is a stream of concatenated CBOR maps.
+	formatCBOR = "cbor"
+	// formatProtobuf is a stream of length-delimited -message records.
+	formatProtobuf = "protobuf"
+	// formatGeoJSON is a GeoJSON FeatureCollection, one document per Feature.
+	formatGeoJSON = "geojson"
+	// formatElasticdump is an elasticdump/ESM export of one hit per line.
+	formatElasticdump = "elasticdump"
+	// formatAccessLog is an Apache or nginx access log in a -log-format.
+	formatAccessLog = "accesslog"
+	// formatRegex is a text log parsed by a -grok-pattern.
+	formatRegex = "regex"
+	// formatCEF is ArcSight Common Event Format, one event per line.
+	formatCEF = "cef"
+	// formatLEEF is QRadar Log Event Extended Format, one event per line.
+	formatLEEF = "leef"
+	// formatEVTX is a Windows event log file, one document per record.
+	formatEVTX = "evtx"
+	// formatPcap is a pcap or pcapng capture, one document per packet or
+	// per -pcap-mode flow.
+	formatPcap = "pcap"
+)
+
+// inputFormats lists every -format value.
+var inputFormats = []string{formatJSON, formatNDJSON, formatCSV, formatFixed, formatMsgpack, formatCBOR, formatProtobuf, formatGeoJSON, formatElasticdump, formatAccessLog, formatRegex, formatCEF, formatLEEF, formatEVTX, formatPcap}
+
+// documentReader yields the documents of one data file in order.
+type documentReader interface {
+	// next returns the next document, or io.EOF after the last one.
+	next() (map[string]interface{}, error)
+	close() error
+}
+
+// rawDocumentReader is implemented by readers whose documents are already
+// single-line JSON objects, so a load without transforms can send them as
+// read.
+type rawDocumentReader interface {
+	// nextRaw returns the next document's JSON, or io.EOF after the last one.
+	nextRaw() ([]byte, error)
+}
+
+// inputConfig is the validated -format selection for a load.
+type inputConfig struct {
+	format    string
+	columnMap *columnMap
+	layout    *fixedLayout
+	protobuf  *protobufSchema
+	accessLog *accessLogFormat
+	grok      *grokFormat
+	// pcapFlows indexes captures as flows rather than packets.
+	pcapFlows bool
+	// geoShapeField receives GeoJSON geometries.
+	geoShapeField string
+	// archivePattern selects archive members by base name.
+	archivePattern string
+	// shard, when set, limits each opened file to one Options.Shard slice.
+	shard *inputShard
+}
+
+// newInputConfig validates the format options in opts.
+func newInputConfig(opts Options) (inputConfig, error) {
+	config := inputConfig{
+		format:         strings.ToLower(strings.TrimSpace(opts.Format)),
+		geoShapeField:  strings.TrimSpace(opts.GeoShapeField),
+		archivePattern: strings.TrimSpace(opts.ArchivePattern),
+	}
+	if config.geoShapeField == "" {
+		config.geoShapeField = defaultGeoShapeField
+	}
+	if config.format != "" && !slices.Contains(inputFormats, config.format) {
+		return config, fmt.Errorf("-format must be one of %s", strings.Join(inputFormats, ", "))
+	}
+	if strings.TrimSpace(opts.ColumnMap) != "" {
+		if config.format != "" && config.format != formatCSV {
+			return config, fmt.Errorf("-column-map needs -format %s", formatCSV)
+		}
+		columns, err := loadColumnMap(opts.ColumnMap)
+		if err != nil {
+			return config, err
+		}
+		config.columnMap = columns
+	}
+	if strings.TrimSpace(opts.Layout) != "" {
+		if config.format != "" && config.format != formatFixed {
+			return config, fmt.Errorf("-layout needs -format %s", formatFixed)
+		}
+		if config.columnMap != nil {
+			return config, fmt.Errorf("-layout and -column-map cannot be combined")
+		}
+		layout, err := loadFixedLayout(opts.Layout)
+		if err != nil {
+			return config, err
+		}
+		config.layout = layout
+	} else if config.format == formatFixed {
+		return config, fmt.Errorf("-format %s needs a -layout", formatFixed)
+	}
+	if strings.TrimSpace(opts.Descriptor) != "" {
+		if config.format != "" && config.format != formatProtobuf {
+			return config, fmt.Errorf("-descriptor needs -format %s", formatProtobuf)
+		}
+		if config.columnMap != nil || config.layout != nil {
+			return config, fmt.Errorf("-descriptor cannot be combined with -column-map or -layout")
+		}
+		schema, err := loadProtobufSchema(opts.Descriptor, strings.TrimSpace(opts.Message))
+		if err != nil {
+			return config, err
+		}
+		config.protobuf = schema
+	} else if config.format == formatProtobuf || strings.TrimSpace(opts.Message) != "" {
+		return config, fmt.Errorf("-format %s needs a -descriptor and -message", formatProtobuf)
+	}
+	if strings.TrimSpace(opts.LogFormat) != "" || config.format == formatAccessLog {
+		if config.format != "" && config.format != formatAccessLog {
+			return config, fmt.Errorf("-log-format needs -format %s", formatAccessLog)
+		}
+		if config.columnMap != nil || config.layout != nil || config.protobuf != nil {
+			return config, fmt.Errorf("-log-format cannot be combined with -column-map, -layout, or -descriptor")
+		}
+		format, err := compileAccessLogFormat(opts.LogFormat)
+		if err != nil {
+			return config, err
+		}
+		config.accessLog = format
+	}
+	if strings.TrimSpace(opts.GrokPattern) != "" {
+		if config.format != "" && config.format != formatRegex {
+			return config, fmt.Errorf("-grok-pattern needs -format %s", formatRegex)
+		}
+		if config.columnMap != nil || config.layout != nil || config.protobuf != nil {
+			return config, fmt.Errorf("-grok-pattern cannot be combined with -column-map, -layout, or -descriptor")
+		}
+		format, err := compileGrokFormat(opts.GrokPattern, opts.GrokPatternsDir)
+		if err != nil {
+			return config, err
+		}
+		config.grok = format
+	} else if config.format == formatRegex || strings.TrimSpace(opts.GrokPatternsDir) != "" {
+		return config, fmt.Errorf("-format %s needs a -grok-pattern", formatRegex)
+	}
+	switch mode := strings.ToLower(strings.TrimSpace(opts.PcapMode)); mode {
+	case "", pcapModePacket:
+	case pcapModeFlow:
+		if config.format != "" && config.format != formatPcap {
+			return config, fmt.Errorf("-pcap-mode needs -format %s", formatPcap)
+		}
+		config.pcapFlows = true
+	default:
+		return config, fmt.Errorf("-pcap-mode must be %s or %s", pcapModePacket, pcapModeFlow)
+	}
+	if config.archivePattern != "" {
+		if _, err := path.Match(config.archivePattern, ""); err != nil {
+			return config, fmt.Errorf("-archive-pattern %q: %w", config.archivePattern, err)
+		}
+	}
+	return config, nil
+}
+
+// formatFor returns the explicit -format, or one chosen from path's
+// extension: .csv and .tsv read as CSV, .ndjson and .jsonl as NDJSON,
+// .msgpack and .mpk as MessagePack, .cbor as CBOR, .geojson as GeoJSON,
+// .cef and .leef as those security log formats, .evtx as a Windows event
+// log, .pcap, .pcapng, and .cap as captures, and anything else as a JSON
+// array. A -column-map implies CSV, a -layout fixed-width, a -descriptor
+// protobuf, a -log-format an access log, a -grok-pattern regex, and a
+// -pcap-mode flow a capture.
+func (c inputConfig) formatFor(path string) string {
+	if c.format != "" {
+		return c.format
+	}
+	if c.columnMap != nil {
+		return formatCSV
+	}
+	if c.layout != nil {
+		return formatFixed
+	}
+	if c.protobuf != nil {
+		return formatProtobuf
+	}
+	if c.accessLog != nil {
+		return formatAccessLog
+	}
+	if c.grok != nil {
+		return formatRegex
+	}
+	if c.pcapFlows {
+		return formatPcap
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".tsv":
+		return formatCSV
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	case ".msgpack", ".mpk":
+		return formatMsgpack
+	case ".cbor":
+		return formatCBOR
+	case ".geojson":
+		return formatGeoJSON
+	case ".cef":
+		return formatCEF
+	case ".leef":
+		return formatLEEF
+	case ".evtx":
+		return formatEVTX
+	case ".pcap", ".pcapng", ".cap":
+		return formatPcap
+	}
+	return formatJSON
+}
+
+// geoShapeFieldFor returns the field to map as a geo_shape when path is
+// read as GeoJSON, or "" otherwise.
+func (c inputConfig) geoShapeFieldFor(path string) string {
+	if path == "" || c.formatFor(path) != formatGeoJSON {
+		return ""
+	}
+	return c.geoShapeField
+}
+
+// open returns a reader for the data file at path. Archives are read
+// member by member; see archiveReader.
+func (c inputConfig) open(path string) (documentReader, error) {
+	var reader documentReader
+	var err error
+	if isArchive(path) {
+		reader, err = c.openArchive(path)
+	} else {
+		var file *os.File
+		if file, err = os.Open(path); err != nil {
+			return nil, err
+		}
+		reader, err = c.openReader(path, file)
+	}
+	if err != nil || c.shard == nil {
+		return reader, err
+	}
+	return c.shard.wrap(reader), nil
+}
+
+// openReader returns a reader in the format for name over file, closing file
+// if the reader cannot be set up.
+func (c inputConfig) openReader(name string, file io.ReadCloser) (documentReader, error) {
+	var (
+		reader documentReader
+		err    error
+	)
+	switch c.formatFor(name) {
+	case formatNDJSON:
+		reader = newNDJSONReader(file)
+	case formatCSV:
+		columns := c.columnMap
+		if columns == nil && strings.EqualFold(filepath.Ext(name), ".tsv") {
+			columns = &columnMap{Header: true, Delimiter: "\t"}
+		}
+		reader, err = newCSVReader(file, columns)
+	case formatFixed:
+		reader, err = newFixedReader(file, c.layout)
+	case formatMsgpack:
+		reader = newMsgpackReader(file)
+	case formatCBOR:
+		reader, err = newCBORReader(file)
+	case formatProtobuf:
+		reader = newProtobufReader(file, c.protobuf)
+	case formatGeoJSON:
+		reader, err = newGeoJSONReader(file, c.geoShapeField)
+	case formatElasticdump:
+		reader = newElasticdumpReader(file)
+	case formatAccessLog:
+		reader = newLogLineReader(file, c.accessLog)
+	case formatRegex:
+		reader = newLogLineReader(file, c.grok)
+	case formatCEF:
+		reader = newLogLineReader(file, lineParserFunc(parseCEFLine))
+	case formatLEEF:
+		reader = newLogLineReader(file, lineParserFunc(parseLEEFLine))
+	case formatEVTX:
+		reader, err = newEVTXReader(file)
+	case formatPcap:
+		reader, err = newPcapReader(file, c.pcapFlows)
+	default:
+		reader, err = newJSONArrayReader(file)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+// ─── JSON Array Input ──────────────────────────────────────────────────────────
+
+// jsonArrayReader streams the objects of a top-level JSON array.
+type jsonArrayReader struct {
+	file io.ReadCloser
+	dec  *json.Decoder
+}
+
+// newJSONArrayReader consumes the opening bracket of the array.
+func newJSONArrayReader(file io.ReadCloser) (*jsonArrayReader, error) {
+	dec := json.NewDecoder(file)
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("data file must be a JSON array")
+	}
+	return &jsonArrayReader{file: file, dec: dec}, nil
+}
+
+// next decodes the following array element.
+func (r *jsonArrayReader) next() (map[string]interface{}, error) {
+	if !r.dec.More() {
+		return nil, io.EOF
+	}
+	var doc map[string]interface{}
+	if err := r.dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// close releases the underlying file.
+func (r *jsonArrayReader) close() error {
+	return r.file.Close()
+}
+
+// ─── NDJSON Input ──────────────────────────────────────────────────────────────
+
+// ndjsonReader decodes one object per line, skipping blank lines.
+type ndjsonReader struct {
+	file   io.ReadCloser
+	reader *bufio.Reader
+	line   int
+}
+
+// newNDJSONReader wraps file; lines may be of any length.
+func newNDJSONReader(file io.ReadCloser) *ndjsonReader {
+	return &ndjsonReader{file: file, reader: bufio.NewReader(file)}
+}
+
+// next decodes the following non-blank line.
+func (r *ndjsonReader) next() (map[string]interface{}, error) {
+	line, err := r.nextRaw()
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := decodeJSON(line, &doc); err != nil {
+		return nil, fmt.Errorf("line %d: %w", r.line, err)
+	}
+	return doc, nil
+}
+
+// nextRaw returns the following non-blank line once it is known to hold a
+// single JSON object.
+func (r *ndjsonReader) nextRaw() ([]byte, error) {
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if len(line) == 0 && errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		r.line++
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			continue
+		}
+		if err := checkJSONObject(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return line, nil
+	}
+}
+
+// checkJSONObject validates one NDJSON line without decoding it.
+func checkJSONObject(line []byte) error {
+	if !json.Valid(line) {
+		var discard interface{}
+		if err := json.Unmarshal(line, &discard); err != nil {
+			return err
+		}
+	}
+	if line[0] != '{' {
+		return fmt.Errorf("not a JSON object")
+	}
+	return nil
+}
+
+// close releases the underlying file.
+func (r *ndjsonReader) close() error {
+	return r.file.Close()
+}
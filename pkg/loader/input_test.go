@@ -0,0 +1,174 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestDataFile writes a data file named name for a test.
+func writeTestDataFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	return path
+}
+
+// readTestDocuments opens path with config and encodes every document.
+func readTestDocuments(t *testing.T, config inputConfig, path string) ([]string, error) {
+	t.Helper()
+	reader, err := config.open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.close()
+	var docs []string
+	for {
+		doc, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return docs, err
+		}
+		docs = append(docs, encodeTestDocument(t, doc))
+	}
+}
+
+// TestInputFormatFor verifies behavior for the related scenario.
+func TestInputFormatFor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		config inputConfig
+		path   string
+		want   string
+	}{
+		{path: "cards.json", want: formatJSON},
+		{path: "cards", want: formatJSON},
+		{path: "cards.NDJSON", want: formatNDJSON},
+		{path: "cards.jsonl", want: formatNDJSON},
+		{path: "cards.csv", want: formatCSV},
+		{path: "cards.tsv", want: formatCSV},
+		{config: inputConfig{format: formatNDJSON}, path: "cards.json", want: formatNDJSON},
+		{config: inputConfig{columnMap: &columnMap{Header: true}}, path: "cards.txt", want: formatCSV},
+	}
+	for _, tt := range tests {
+		if got := tt.config.formatFor(tt.path); got != tt.want {
+			t.Fatalf("formatFor(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	for _, opts := range []Options{{Format: "xml"}, {Format: "ndjson", ColumnMap: "map.json"}, {ArchivePattern: "[a-"}} {
+		if _, err := newInputConfig(opts); err == nil {
+			t.Fatalf("expected newInputConfig(%+v) to fail", opts)
+		}
+	}
+}
+
+// TestInputReaders verifies behavior for the related scenario.
+func TestInputReaders(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "json array",
+			file:    "cards.json",
+			content: `[{"id":"1"}, {"id":"2","n":2}]`,
+			want:    []string{`{"id":"1"}`, `{"id":"2","n":2}`},
+		},
+		{name: "json object", file: "cards.json", content: `{"id":"1"}`, wantErr: "must be a JSON array"},
+		{
+			name:    "ndjson",
+			file:    "cards.ndjson",
+			content: "{\"id\":\"1\"}\n\n  {\"id\":\"2\"}  \n{\"id\":\"3\"}",
+			want:    []string{`{"id":"1"}`, `{"id":"2"}`, `{"id":"3"}`},
+		},
+		{name: "ndjson bad line", file: "cards.jsonl", content: "{\"id\":\"1\"}\n[1]\n", want: []string{`{"id":"1"}`}, wantErr: "line 2"},
+		{
+			name:    "csv header",
+			file:    "cards.csv",
+			content: "\ufeffid,user.name\n1,Ann\n2,\"Bo, Jr\"\n",
+			want:    []string{`{"id":"1","user":{"name":"Ann"}}`, `{"id":"2","user":{"name":"Bo, Jr"}}`},
+		},
+		{name: "tsv", file: "cards.tsv", content: "id\tname\n1\tAnn\n", want: []string{`{"id":"1","name":"Ann"}`}},
+	}
+	for _, tt := range tests {
+		got, err := readTestDocuments(t, inputConfig{}, writeTestDataFile(t, tt.file, tt.content))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("%s: expected error mentioning %q, got %v", tt.name, tt.wantErr, err)
+			}
+		} else if err != nil {
+			t.Fatalf("%s: returned error: %v", tt.name, err)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Fatalf("%s: read %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestRunNDJSONPassthrough verifies behavior for the related scenario.
+func TestRunNDJSONPassthrough(t *testing.T) {
+	t.Parallel()
+
+	content := "{\"id\":\"a\",\"n\":12345678901234567891,\"price\":1.50}\n\n{ \"id\": \"b\" }\n"
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "passthrough",
+			opts: Options{IDField: "id"},
+			want: []string{
+				`{"index":{"_id":"a","_index":"cards"}}` + "\n" + `{"id":"a","n":12345678901234567891,"price":1.50}` + "\n",
+				`{"index":{"_id":"b","_index":"cards"}}` + "\n" + `{ "id": "b" }` + "\n",
+			},
+		},
+		{
+			name: "transformed",
+			opts: Options{Rename: []string{"price=cost"}},
+			want: []string{`{"cost":1.50,"id":"a","n":12345678901234567891}`, `{"id":"b"}`},
+		},
+	}
+	for _, tt := range tests {
+		server, bodies := newWatchTestServer(t)
+		opts := tt.opts
+		opts.URL = server.URL
+		opts.Index = "cards"
+		opts.DataFile = writeTestDataFile(t, "cards.ndjson", content)
+		opts.AddToIndex = true
+		result, err := Run(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		if result.DocumentsProcessed != 2 {
+			t.Fatalf("%s: expected 2 processed documents, got %+v", tt.name, result)
+		}
+		sent := strings.Join(bodies(), "")
+		for _, want := range tt.want {
+			if !strings.Contains(sent, want) {
+				t.Fatalf("%s: expected %s in bulk body %s", tt.name, want, sent)
+			}
+		}
+	}
+
+	server, _ := newWatchTestServer(t)
+	_, err := Run(context.Background(), Options{URL: server.URL, Index: "cards", DataFile: writeTestDataFile(t, "cards.ndjson", "{\"id\":\"a\"}\n[1]\n"), AddToIndex: true})
+	if err == nil || !strings.Contains(err.Error(), "counting") {
+		t.Fatalf("expected a non-object line to fail the count, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package loader
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// ─── Input Shards ──────────────────────────────────────────────────────────────
+
+// Options.ShardBy values.
+const (
+	shardByRecord = "record"
+	shardByID     = "id"
+)
+
+// inputShard is the slice of the input an Options.Shard load reads: the
+// index-th, counting from 1, of count disjoint slices.
+type inputShard struct {
+	index   int
+	count   int
+	by      string
+	idField string
+}
+
+// newInputShard parses Options.Shard and checks that the other options let
+// several machines load slices of one dataset side by side. It returns nil
+// when the input is not sharded.
+func newInputShard(opts Options, action dataAction) (*inputShard, error) {
+	if opts.Shard == "" {
+		if opts.ShardBy != "" {
+			return nil, fmt.Errorf("-shard-by needs -shard")
+		}
+		return nil, nil
+	}
+	before, after, ok := strings.Cut(opts.Shard, "/")
+	index, indexErr := strconv.Atoi(strings.TrimSpace(before))
+	count, countErr := strconv.Atoi(strings.TrimSpace(after))
+	if !ok || indexErr != nil || countErr != nil || count < 1 || index < 1 || index > count {
+		return nil, fmt.Errorf("-shard must be i/N with 1 <= i <= N, such as 2/4; got %q", opts.Shard)
+	}
+	shard := &inputShard{index: index, count: count, by: opts.ShardBy, idField: opts.IDField}
+	if shard.by == "" {
+		shard.by = shardByRecord
+	}
+	switch {
+	case shard.by != shardByRecord && shard.by != shardByID:
+		return nil, fmt.Errorf("-shard-by must be %s or %s, got %q", shardByRecord, shardByID, opts.ShardBy)
+	case shard.by == shardByID && opts.IDField == "":
+		return nil, fmt.Errorf("-shard-by %s hashes each document's -id and needs -id", shardByID)
+	case action != dataActionAdd:
+		return nil, fmt.Errorf("-shard needs -add: with -delete or -flush every shard would reset the index the others load into")
+	case opts.AliasMode:
+		return nil, fmt.Errorf("-shard cannot be combined with -alias, which creates a new index per run")
+	case opts.Tail:
+		return nil, fmt.Errorf("-shard cannot be combined with -tail")
+	case opts.DedupField != "" && (shard.by != shardByID || opts.DedupField != opts.IDField):
+		return nil, fmt.Errorf("-dedup-field with -shard needs -shard-by %s on the same field as -id, so every copy of a key is read by one shard", shardByID)
+	}
+	return shard, nil
+}
+
+// String returns the shard as i/N.
+func (s *inputShard) String() string {
+	return fmt.Sprintf("%d/%d", s.index, s.count)
+}
+
+// journalPath returns the -journal of this shard, so shards sharing a
+// directory or store each keep their own checkpoint.
+func (s *inputShard) journalPath(path string) string {
+	return journalPathWithSuffix(path, fmt.Sprintf(".shard-%d-of-%d", s.index, s.count))
+}
+
+// keeps reports whether doc, the record-th document of its data file
+// counting from 0, belongs to the shard.
+func (s *inputShard) keeps(record int, doc map[string]interface{}) (bool, error) {
+	if s.by == shardByRecord {
+		return record%s.count == s.index-1, nil
+	}
+	value, _ := lookupField(doc, s.idField)
+	id := routingValue(value)
+	if id == "" {
+		return false, fmt.Errorf("document %d has no -id %s value to shard by", record+1, s.idField)
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(id))
+	return int(hash.Sum32()%uint32(s.count)) == s.index-1, nil
+}
+
+// wrap returns reader limited to the shard's documents. Records are
+// counted per data file, so several files shard the same way however
+// they interleave.
+func (s *inputShard) wrap(reader documentReader) documentReader {
+	sharded := &shardReader{reader: reader, shard: s}
+	if raw, ok := reader.(rawDocumentReader); ok && s.by == shardByRecord {
+		return &rawShardReader{shardReader: sharded, raw: raw}
+	}
+	return sharded
+}
+
+// shardReader yields the documents of reader that belong to shard.
+type shardReader struct {
+	reader documentReader
+	shard  *inputShard
+	// record counts the documents read from reader, kept or not.
+	record int
+}
+
+// next implements documentReader.
+func (r *shardReader) next() (map[string]interface{}, error) {
+	for {
+		doc, err := r.reader.next()
+		if err != nil {
+			return nil, err
+		}
+		keep, err := r.shard.keeps(r.record, doc)
+		r.record++
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			return doc, nil
+		}
+	}
+}
+
+// close implements documentReader.
+func (r *shardReader) close() error {
+	return r.reader.close()
+}
+
+// rawShardReader also passes through raw lines when shards are taken by
+// record number, which needs no parsing.
+type rawShardReader struct {
+	*shardReader
+	raw rawDocumentReader
+}
+
+// nextRaw implements rawDocumentReader.
+func (r *rawShardReader) nextRaw() ([]byte, error) {
+	for {
+		line, err := r.raw.nextRaw()
+		if err != nil {
+			return nil, err
+		}
+		record := r.record
+		r.record++
+		if record%r.shard.count == r.shard.index-1 {
+			return line, nil
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestNewInputShard verifies behavior for the related scenario.
+func TestNewInputShard(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    Options
+		action  dataAction
+		want    string
+		wantBy  string
+		wantErr string
+	}{
+		{name: "off", action: dataActionAdd},
+		{name: "record", opts: Options{Shard: "2/4"}, action: dataActionAdd, want: "2/4", wantBy: shardByRecord},
+		{name: "id", opts: Options{Shard: " 1 / 3 ", ShardBy: "id", IDField: "sku"}, action: dataActionAdd, want: "1/3", wantBy: shardByID},
+		{name: "dedup on id", opts: Options{Shard: "1/2", ShardBy: "id", IDField: "sku", DedupField: "sku"}, action: dataActionAdd, want: "1/2", wantBy: shardByID},
+		{name: "by without shard", opts: Options{ShardBy: "id"}, action: dataActionAdd, wantErr: "needs -shard"},
+		{name: "zero index", opts: Options{Shard: "0/4"}, action: dataActionAdd, wantErr: "1 <= i <= N"},
+		{name: "past count", opts: Options{Shard: "5/4"}, action: dataActionAdd, wantErr: "1 <= i <= N"},
+		{name: "no slash", opts: Options{Shard: "2"}, action: dataActionAdd, wantErr: "i/N"},
+		{name: "unknown by", opts: Options{Shard: "1/2", ShardBy: "hash"}, action: dataActionAdd, wantErr: "record or id"},
+		{name: "id without field", opts: Options{Shard: "1/2", ShardBy: "id"}, action: dataActionAdd, wantErr: "needs -id"},
+		{name: "delete", opts: Options{Shard: "1/2"}, action: dataActionDelete, wantErr: "needs -add"},
+		{name: "alias", opts: Options{Shard: "1/2", AliasMode: true}, action: dataActionAdd, wantErr: "-alias"},
+		{name: "tail", opts: Options{Shard: "1/2", Tail: true}, action: dataActionAdd, wantErr: "-tail"},
+		{name: "dedup by record", opts: Options{Shard: "1/2", IDField: "sku", DedupField: "sku"}, action: dataActionAdd, wantErr: "-dedup-field"},
+	}
+	for _, tt := range tests {
+		shard, err := newInputShard(tt.opts, tt.action)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if tt.want == "" {
+			if shard != nil {
+				t.Fatalf("%s: expected no shard, got %v", tt.name, shard)
+			}
+			continue
+		}
+		if shard.String() != tt.want || shard.by != tt.wantBy {
+			t.Fatalf("%s: expected %s by %s, got %s by %s", tt.name, tt.want, tt.wantBy, shard, shard.by)
+		}
+	}
+}
+
+// readShardIDs returns the sku of every document of path the shard reads,
+// through next or, when raw is set, nextRaw.
+func readShardIDs(t *testing.T, path string, shard *inputShard, raw bool) []string {
+	t.Helper()
+	reader, err := inputConfig{shard: shard}.open(path)
+	if err != nil {
+		t.Fatalf("opening %s: %v", path, err)
+	}
+	defer reader.close()
+	var ids []string
+	for {
+		var doc map[string]interface{}
+		if raw {
+			rawReader, ok := reader.(rawDocumentReader)
+			if !ok {
+				t.Fatalf("expected a raw reader for shard %s by %s", shard, shard.by)
+			}
+			var line []byte
+			if line, err = rawReader.nextRaw(); err == nil {
+				err = json.Unmarshal(line, &doc)
+			}
+		} else {
+			doc, err = reader.next()
+		}
+		if errors.Is(err, io.EOF) {
+			return ids
+		}
+		if err != nil {
+			t.Fatalf("reading shard %s: %v", shard, err)
+		}
+		ids = append(ids, fmt.Sprint(doc["sku"]))
+	}
+}
+
+// TestInputShardSlicesDisjointly verifies behavior for the related scenario.
+func TestInputShardSlicesDisjointly(t *testing.T) {
+	t.Parallel()
+
+	var lines strings.Builder
+	var all []string
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&lines, "{\"sku\":\"sku-%02d\"}\n\n", i)
+		all = append(all, fmt.Sprintf("sku-%02d", i))
+	}
+	data := writeTestDataFile(t, "cards.ndjson", lines.String())
+
+	for _, by := range []string{shardByRecord, shardByID} {
+		var seen []string
+		for index := 1; index <= 3; index++ {
+			shard := &inputShard{index: index, count: 3, by: by, idField: "sku"}
+			ids := readShardIDs(t, data, shard, false)
+			if by == shardByRecord {
+				if raw := readShardIDs(t, data, shard, true); !slices.Equal(raw, ids) {
+					t.Fatalf("shard %s: raw lines %v differ from documents %v", shard, raw, ids)
+				}
+				if ids[0] != all[index-1] {
+					t.Fatalf("shard %s: expected to start at %s, got %v", shard, all[index-1], ids)
+				}
+			}
+			if again := readShardIDs(t, data, shard, false); !slices.Equal(again, ids) {
+				t.Fatalf("shard %s by %s: expected the same slice every time, got %v and %v", shard, by, ids, again)
+			}
+			seen = append(seen, ids...)
+		}
+		slices.Sort(seen)
+		if !slices.Equal(seen, all) {
+			t.Fatalf("by %s: expected the shards to cover every document once, got %v", by, seen)
+		}
+	}
+
+	missing := writeTestDataFile(t, "missing.ndjson", "{\"name\":\"no sku\"}\n")
+	reader, err := inputConfig{shard: &inputShard{index: 1, count: 2, by: shardByID, idField: "sku"}}.open(missing)
+	if err != nil {
+		t.Fatalf("opening %s: %v", missing, err)
+	}
+	defer reader.close()
+	if _, err := reader.next(); err == nil || !strings.Contains(err.Error(), "no -id sku value") {
+		t.Fatalf("expected a document without an id to fail, got %v", err)
+	}
+}
+
+// TestRunShardWritesOwnJournal verifies behavior for the related scenario.
+func TestRunShardWritesOwnJournal(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.URL.Path == "/_bulk":
+			body, _ := io.ReadAll(r.Body)
+			var items []string
+			mu.Lock()
+			for line, text := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+				if line%2 == 0 {
+					var action map[string]struct {
+						ID string `json:"_id"`
+					}
+					_ = json.Unmarshal([]byte(text), &action)
+					ids = append(ids, action["index"].ID)
+					items = append(items, `{"index":{"status":201}}`)
+				}
+			}
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"errors":false,"items":[` + strings.Join(items, ",") + `]}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	data := writeTestDataFile(t, "cards.ndjson", "{\"sku\":\"a\"}\n{\"sku\":\"b\"}\n{\"sku\":\"c\"}\n{\"sku\":\"d\"}\n{\"sku\":\"e\"}\n")
+	journal := filepath.Join(t.TempDir(), "cards.journal")
+
+	for _, shard := range []string{"1/2", "2/2"} {
+		result, err := Run(context.Background(), Options{
+			URL:        server.URL,
+			Index:      "cards",
+			DataFile:   data,
+			AddToIndex: true,
+			IDField:    "sku",
+			Shard:      shard,
+			Journal:    journal,
+		})
+		if err != nil {
+			t.Fatalf("shard %s: Run returned error: %v", shard, err)
+		}
+		index, _, _ := strings.Cut(shard, "/")
+		if _, err := os.Stat(journal + ".shard-" + index + "-of-2"); err != nil {
+			t.Fatalf("shard %s: expected its own journal: %v", shard, err)
+		}
+		want := map[string]int{"1/2": 3, "2/2": 2}[shard]
+		if result.DocumentsSucceeded != want {
+			t.Fatalf("shard %s: expected %d documents, got %d", shard, want, result.DocumentsSucceeded)
+		}
+	}
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Fatalf("expected no unsharded journal, got %v", err)
+	}
+	if !slices.Equal(ids, []string{"a", "c", "e", "b", "d"}) {
+		t.Fatalf("expected each document loaded by one shard, got %v", ids)
+	}
+}
@@ -0,0 +1,404 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Job Records ───────────────────────────────────────────────────────────────
+
+const (
+	// DefaultJobsIndex stores job history for server and daemon modes.
+	DefaultJobsIndex = ".bulkloader-jobs"
+	// jobsListLimit caps how many records the list endpoint returns.
+	jobsListLimit = 100
+)
+
+// Job modes identify which long-running entry point created a record.
+const (
+	JobModeServe    = "serve"
+	JobModeWatch    = "watch"
+	JobModeSchedule = "schedule"
+)
+
+// Job states recorded for server and daemon runs.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job is the externally visible record of one load executed by the server,
+// watch, or schedule modes.
+type Job struct {
+	ID                 string     `json:"id"`
+	Mode               string     `json:"mode"`
+	Host               string     `json:"host,omitempty"`
+	Index              string     `json:"index"`
+	Action             string     `json:"action"`
+	Alias              bool       `json:"alias,omitempty"`
+	Source             string     `json:"source,omitempty"`
+	State              string     `json:"state"`
+	SubmittedAt        time.Time  `json:"submitted_at"`
+	StartedAt          *time.Time `json:"started_at,omitempty"`
+	FinishedAt         *time.Time `json:"finished_at,omitempty"`
+	UploadBytes        int64      `json:"upload_bytes"`
+	DocumentsUploaded  int        `json:"documents_uploaded"`
+	DocumentsProcessed int        `json:"documents_processed"`
+	DocumentsSucceeded int        `json:"documents_succeeded"`
+	DocumentsFailed    int        `json:"documents_failed"`
+	DocumentsSkipped   int        `json:"documents_skipped"`
+	WriteIndex         string     `json:"write_index,omitempty"`
+	Warnings           []string   `json:"warnings,omitempty"`
+	Error              string     `json:"error,omitempty"`
+}
+
+// jobStore persists job records for status endpoints and history.
+type jobStore interface {
+	save(job Job) error
+	get(id string) (Job, bool, error)
+	list() ([]Job, error)
+}
+
+// memoryJobStore keeps job records for the lifetime of the process.
+type memoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+// newMemoryJobStore creates an empty in-process job store.
+func newMemoryJobStore() *memoryJobStore {
+	return &memoryJobStore{jobs: make(map[string]Job)}
+}
+
+// save inserts or replaces a job record.
+func (s *memoryJobStore) save(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+// get returns the job with id when present.
+func (s *memoryJobStore) get(id string) (Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	return job, ok, nil
+}
+
+// list returns every job, newest submission first.
+func (s *memoryJobStore) list() ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	slices.SortFunc(jobs, func(a, b Job) int {
+		return b.SubmittedAt.Compare(a.SubmittedAt)
+	})
+	return jobs, nil
+}
+
+// jobsIndexMappings types job record fields for sorting and Kibana filters.
+const jobsIndexMappings = `{
+  "mappings": {
+    "dynamic": false,
+    "properties": {
+      "id": {"type": "keyword"},
+      "mode": {"type": "keyword"},
+      "host": {"type": "keyword"},
+      "index": {"type": "keyword"},
+      "action": {"type": "keyword"},
+      "alias": {"type": "boolean"},
+      "source": {"type": "keyword"},
+      "state": {"type": "keyword"},
+      "submitted_at": {"type": "date"},
+      "started_at": {"type": "date"},
+      "finished_at": {"type": "date"},
+      "upload_bytes": {"type": "long"},
+      "documents_uploaded": {"type": "long"},
+      "documents_processed": {"type": "long"},
+      "documents_succeeded": {"type": "long"},
+      "documents_failed": {"type": "long"},
+      "documents_skipped": {"type": "long"},
+      "write_index": {"type": "keyword"},
+      "warnings": {"type": "text"},
+      "error": {"type": "text"}
+    }
+  }
+}`
+
+// esJobStore persists job records as documents in an Elasticsearch index so
+// history survives restarts and can be browsed in Kibana.
+type esJobStore struct {
+	es    *elasticsearch.Client
+	index string
+}
+
+// newESJobStore connects with the load's connection settings and creates the
+// jobs index when it is missing.
+func newESJobStore(load Options, index string) (*esJobStore, error) {
+	es, err := elasticsearch.NewClient(elasticsearchConfig(load))
+	if err != nil {
+		return nil, err
+	}
+	store := &esJobStore{es: es, index: index}
+	if err := store.ensureIndex(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// ensureIndex creates the jobs index with typed mappings if it does not exist.
+func (s *esJobStore) ensureIndex() error {
+	exists, err := indexExists(s.es, s.index)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	res, err := s.es.Indices.Create(s.index, s.es.Indices.Create.WithBody(strings.NewReader(jobsIndexMappings)))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	// A concurrent instance may have created it first.
+	if res.IsError() && !strings.Contains(string(body), "resource_already_exists_exception") {
+		return fmt.Errorf("creating jobs index %s: status %d: %s", s.index, res.StatusCode, body)
+	}
+	log.Info().Str("index", s.index).Msg("Created jobs index")
+	return nil
+}
+
+// save indexes the job record under its id, waiting for refresh so a status
+// request issued right after submission sees it.
+func (s *esJobStore) save(job Job) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	res, err := s.es.Index(
+		s.index,
+		bytes.NewReader(body),
+		s.es.Index.WithDocumentID(job.ID),
+		s.es.Index.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		responseBody, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("saving job %s: status %d: %s", job.ID, res.StatusCode, responseBody)
+	}
+	return nil
+}
+
+// get fetches one job record.
+func (s *esJobStore) get(id string) (Job, bool, error) {
+	res, err := s.es.Get(s.index, id)
+	if err != nil {
+		return Job{}, false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return Job{}, false, nil
+	}
+	body, _ := io.ReadAll(res.Body)
+	if res.IsError() {
+		return Job{}, false, fmt.Errorf("reading job %s: status %d: %s", id, res.StatusCode, body)
+	}
+	var parsed struct {
+		Found  bool `json:"found"`
+		Source Job  `json:"_source"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return Job{}, false, err
+	}
+	return parsed.Source, parsed.Found, nil
+}
+
+// list returns the newest job records.
+func (s *esJobStore) list() ([]Job, error) {
+	return s.search(fmt.Sprintf(`{"size":%d,"sort":[{"submitted_at":"desc"}],"query":{"match_all":{}}}`, jobsListLimit))
+}
+
+// search runs a query against the jobs index and decodes the hits.
+func (s *esJobStore) search(query string) ([]Job, error) {
+	res, err := s.es.Search(
+		s.es.Search.WithIndex(s.index),
+		s.es.Search.WithBody(strings.NewReader(query)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.IsError() {
+		return nil, fmt.Errorf("searching jobs: status %d: %s", res.StatusCode, body)
+	}
+	var parsed struct {
+		Hits struct {
+			Hits []struct {
+				Source Job `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, err
+	}
+	jobs := make([]Job, 0, len(parsed.Hits.Hits))
+	for _, hit := range parsed.Hits.Hits {
+		jobs = append(jobs, hit.Source)
+	}
+	return jobs, nil
+}
+
+// unfinished returns this host's queued or running records for mode.
+func (s *esJobStore) unfinished(mode, host string) ([]Job, error) {
+	query := map[string]interface{}{
+		"size": 1000,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"mode": mode}},
+					map[string]interface{}{"term": map[string]interface{}{"host": host}},
+					map[string]interface{}{"terms": map[string]interface{}{"state": []string{JobQueued, JobRunning}}},
+				},
+			},
+		},
+	}
+	encoded, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.search(string(encoded))
+}
+
+// openJobStore returns the Elasticsearch store for index, or nil when job
+// history is disabled with an empty index name.
+func openJobStore(load Options, index string) (jobStore, error) {
+	if strings.TrimSpace(index) == "" {
+		return nil, nil
+	}
+	store, err := newESJobStore(load, index)
+	if err != nil {
+		return nil, &RunError{Kind: ErrLoaderExecution, Op: "opening jobs index", Err: err}
+	}
+	return store, nil
+}
+
+// recoverInterruptedJobs marks records left queued or running by a previous
+// process on this host as failed, since their work did not complete.
+func recoverInterruptedJobs(store jobStore, mode, host string) {
+	esStore, ok := store.(*esJobStore)
+	if !ok {
+		return
+	}
+	jobs, err := esStore.unfinished(mode, host)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not look up interrupted jobs")
+		return
+	}
+	for _, job := range jobs {
+		finished := time.Now().UTC()
+		job.State = JobFailed
+		job.FinishedAt = &finished
+		job.Error = "interrupted: the process stopped before the job finished"
+		if err := store.save(job); err != nil {
+			log.Warn().Err(err).Str("job", job.ID).Msg("Could not mark interrupted job as failed")
+			continue
+		}
+		log.Warn().Str("job", job.ID).Str("index", job.Index).Msg("Marked interrupted job as failed")
+	}
+}
+
+// jobHost identifies this process in job records.
+func jobHost() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// newJob creates a record for a load about to be queued or run.
+func newJob(mode, source string, load Options) (Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+	action := "none"
+	switch {
+	case load.AddToIndex:
+		action = "add"
+	case load.FlushIndex:
+		action = "flush"
+	case load.DeleteIndex:
+		action = "delete"
+	}
+	return Job{
+		ID:          id,
+		Mode:        mode,
+		Host:        jobHost(),
+		Index:       load.Index,
+		Action:      action,
+		Alias:       load.AliasMode,
+		Source:      source,
+		State:       JobQueued,
+		SubmittedAt: time.Now().UTC(),
+	}, nil
+}
+
+// saveJobRecord stores job when a store is configured, logging failures so
+// history problems never interrupt loading.
+func saveJobRecord(store jobStore, job Job) {
+	if store == nil {
+		return
+	}
+	if err := store.save(job); err != nil {
+		log.Warn().Err(err).Str("job", job.ID).Msg("Could not persist job record")
+	}
+}
+
+// runJob records job as running, executes run, and records the outcome.
+func runJob(ctx context.Context, store jobStore, job Job, load Options, run func(context.Context, Options) (Result, error)) (Job, Result, error) {
+	started := time.Now().UTC()
+	job.State = JobRunning
+	job.StartedAt = &started
+	saveJobRecord(store, job)
+
+	result, err := run(ctx, load)
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	job.DocumentsProcessed = result.DocumentsProcessed
+	job.DocumentsSucceeded = result.DocumentsSucceeded
+	job.DocumentsFailed = result.DocumentsFailed
+	job.DocumentsSkipped = result.DocumentsSkipped
+	job.WriteIndex = result.WriteIndex
+	job.Warnings = result.Warnings
+	job.State = JobSucceeded
+	if err != nil {
+		job.State = JobFailed
+		job.Error = err.Error()
+	}
+	saveJobRecord(store, job)
+	return job, result, err
+}
@@ -0,0 +1,133 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeJobsCluster serves the subset of the document APIs used by esJobStore.
+type fakeJobsCluster struct {
+	mu      sync.Mutex
+	created bool
+	mapping string
+	docs    map[string]json.RawMessage
+}
+
+// handler routes fake index, document, and search requests.
+func (c *fakeJobsCluster) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/.bulkloader-jobs":
+			if !c.created {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPut && r.URL.Path == "/.bulkloader-jobs":
+			c.created = true
+			c.mapping = string(body)
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/.bulkloader-jobs/_doc/"):
+			if r.URL.Query().Get("refresh") != "wait_for" {
+				t.Errorf("expected refresh=wait_for on job save, got %q", r.URL.RawQuery)
+			}
+			c.docs[strings.TrimPrefix(r.URL.Path, "/.bulkloader-jobs/_doc/")] = body
+			_, _ = w.Write([]byte(`{"result":"created"}`))
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/.bulkloader-jobs/_doc/"):
+			doc, ok := c.docs[strings.TrimPrefix(r.URL.Path, "/.bulkloader-jobs/_doc/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"found":false}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"found":true,"_source":` + string(doc) + `}`))
+		case r.URL.Path == "/.bulkloader-jobs/_search":
+			// Return running jobs for the recovery query and everything otherwise.
+			recovery := strings.Contains(string(body), `"terms"`)
+			hits := make([]string, 0, len(c.docs))
+			ids := make([]string, 0, len(c.docs))
+			for id := range c.docs {
+				ids = append(ids, id)
+			}
+			slices.Sort(ids)
+			for _, id := range ids {
+				var job Job
+				_ = json.Unmarshal(c.docs[id], &job)
+				if recovery && job.State != JobRunning && job.State != JobQueued {
+					continue
+				}
+				hits = append(hits, `{"_source":`+string(c.docs[id])+`}`)
+			}
+			_, _ = w.Write([]byte(`{"hits":{"hits":[` + strings.Join(hits, ",") + `]}}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+}
+
+// TestESJobStorePersistsAndRecoversJobs verifies behavior for the related scenario.
+func TestESJobStorePersistsAndRecoversJobs(t *testing.T) {
+	t.Parallel()
+
+	cluster := &fakeJobsCluster{docs: make(map[string]json.RawMessage)}
+	server := httptest.NewServer(cluster.handler(t))
+	t.Cleanup(server.Close)
+
+	store, err := newESJobStore(Options{URL: server.URL}, DefaultJobsIndex)
+	if err != nil {
+		t.Fatalf("newESJobStore returned error: %v", err)
+	}
+	if !cluster.created || !strings.Contains(cluster.mapping, `"submitted_at": {"type": "date"}`) {
+		t.Fatalf("expected jobs index to be created with mappings, got %q", cluster.mapping)
+	}
+
+	load := Options{Index: "cards", AddToIndex: true}
+	job, err := newJob(JobModeServe, "body", load)
+	if err != nil {
+		t.Fatalf("newJob returned error: %v", err)
+	}
+	finished, result, runErr := runJob(t.Context(), store, job, load, func(ctx context.Context, opts Options) (Result, error) {
+		return Result{DocumentsProcessed: 3, DocumentsSucceeded: 2, DocumentsFailed: 1, WriteIndex: "cards"}, nil
+	})
+	if runErr != nil || result.DocumentsSucceeded != 2 {
+		t.Fatalf("runJob returned %+v, %v", result, runErr)
+	}
+
+	stored, ok, err := store.get(job.ID)
+	if err != nil || !ok {
+		t.Fatalf("get returned ok=%v err=%v", ok, err)
+	}
+	if stored.State != JobSucceeded || stored.Action != "add" || stored.DocumentsFailed != 1 || stored.FinishedAt == nil || stored.ID != finished.ID {
+		t.Fatalf("unexpected stored job: %+v", stored)
+	}
+	if _, ok, err := store.get("missing"); ok || err != nil {
+		t.Fatalf("expected missing job to be absent, got ok=%v err=%v", ok, err)
+	}
+
+	interrupted := Job{ID: "stale", Mode: JobModeServe, Host: jobHost(), Index: "cards", State: JobRunning, SubmittedAt: time.Now().UTC()}
+	if err := store.save(interrupted); err != nil {
+		t.Fatalf("save returned error: %v", err)
+	}
+	recoverInterruptedJobs(store, JobModeServe, jobHost())
+	recovered, _, err := store.get("stale")
+	if err != nil || recovered.State != JobFailed || !strings.Contains(recovered.Error, "interrupted") {
+		t.Fatalf("expected stale job to be marked failed, got %+v (%v)", recovered, err)
+	}
+
+	jobs, err := store.list()
+	if err != nil || len(jobs) != 2 {
+		t.Fatalf("expected two listed jobs, got %d (%v)", len(jobs), err)
+	}
+}
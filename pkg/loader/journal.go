@@ -0,0 +1,290 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Load Journal ──────────────────────────────────────────────────────────────
+
+// journalRecord is one -journal line. A load writes a start record, a sent
+// record before each bulk request, an acked record as each batch completes
+// in order, and a done record once the load finishes.
+type journalRecord struct {
+	Type  string        `json:"type"`
+	Time  time.Time     `json:"time"`
+	Index string        `json:"index,omitempty"`
+	File  *journalInput `json:"file,omitempty"`
+	// Skip is how many source documents the load started after.
+	Skip      int `json:"skip,omitempty"`
+	Batch     int `json:"batch,omitempty"`
+	Documents int `json:"documents,omitempty"`
+	// Consumed is how many source documents, skipped ones included, are
+	// read once the batch is taken.
+	Consumed int `json:"consumed,omitempty"`
+}
+
+// journalInput identifies the data file a journal belongs to, so a changed
+// file is never resumed at a stale offset.
+type journalInput struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// journalInputFor describes path as it is now.
+func journalInputFor(path string) *journalInput {
+	input := &journalInput{Path: path}
+	if info, err := os.Stat(path); err == nil {
+		input.Size = info.Size()
+		input.ModTime = info.ModTime().UTC()
+	}
+	return input
+}
+
+// journalState is what an earlier load's journal says about it.
+type journalState struct {
+	start journalRecord
+	// confirmed is how many source documents every acked batch covers.
+	confirmed int
+	// uncertain counts the batches and documents that were sent but never
+	// acked; Elasticsearch may or may not have indexed them.
+	uncertainBatches   int
+	uncertainDocuments int
+	// through is the highest consumed count of a sent batch.
+	through int
+	done    bool
+	// remote is set for a journal kept in a journalStore, whose data file
+	// may have been fetched again since, with a new modification time.
+	remote bool
+}
+
+// readLoadJournal reads the journal at path, a file or a journalStore URL.
+// It returns nil when there is no journal; a final line cut short by a
+// crash is ignored.
+func readLoadJournal(path string) (*journalState, error) {
+	if isRemoteJournal(path) {
+		store, err := openJournalStore(path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := store.read()
+		if err != nil || data == nil {
+			return nil, err
+		}
+		state, err := parseLoadJournal(bytes.NewReader(data))
+		if state != nil {
+			state.remote = true
+		}
+		return state, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseLoadJournal(file)
+}
+
+// parseLoadJournal reads journal records from r.
+func parseLoadJournal(r io.Reader) (*journalState, error) {
+	var state *journalState
+	sent := make(map[int]journalRecord)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var record journalRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			continue
+		}
+		switch record.Type {
+		case "start":
+			state = &journalState{start: record, confirmed: record.Skip, through: record.Skip}
+			sent = make(map[int]journalRecord)
+		case "sent":
+			if state != nil {
+				sent[record.Batch] = record
+				state.through = max(state.through, record.Consumed)
+			}
+		case "acked":
+			if state != nil {
+				delete(sent, record.Batch)
+				state.confirmed = max(state.confirmed, record.Consumed)
+			}
+		case "done":
+			if state != nil {
+				state.done = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if state != nil {
+		for _, record := range sent {
+			state.uncertainBatches++
+			state.uncertainDocuments += record.Documents
+		}
+	}
+	return state, nil
+}
+
+// resumes reports whether the journal holds an unfinished load of the same
+// index and unchanged data file. A remote journal does not compare
+// modification times.
+func (s *journalState) resumes(index string, input *journalInput) bool {
+	if s == nil || s.done || s.start.File == nil {
+		return false
+	}
+	file := s.start.File
+	return s.start.Index == index && file.Path == input.Path && file.Size == input.Size && (s.remote || file.ModTime.Equal(input.ModTime))
+}
+
+// loadJournal appends the records of one load to -journal, syncing each to
+// disk before the bulk request it describes is sent.
+type loadJournal struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	// store, when set instead of file, receives the whole compacted journal
+	// after every record.
+	store   journalStore
+	compact compactJournal
+	// skip is added to the batcher's consumed counts, which start after
+	// the skipped documents.
+	skip int
+}
+
+// startLoadJournal replaces the journal at path with a start record for a
+// load of input into index after skip documents. The start record is
+// written to a temp file and renamed into place, so a crash leaves either
+// the old journal or the new one. It returns nil for an empty path.
+func startLoadJournal(path, index string, input *journalInput, skip int) (*loadJournal, error) {
+	if path == "" {
+		return nil, nil
+	}
+	start := journalRecord{Type: "start", Time: time.Now().UTC(), Index: index, File: input, Skip: skip}
+	if isRemoteJournal(path) {
+		store, err := openJournalStore(path)
+		if err != nil {
+			return nil, err
+		}
+		j := &loadJournal{path: path, store: store, compact: newCompactJournal(start), skip: skip}
+		data, err := j.compact.encode()
+		if err == nil {
+			err = store.write(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return j, nil
+	}
+	line, err := json.Marshal(start)
+	if err != nil {
+		return nil, err
+	}
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(append(line, '\n')); err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+	return &loadJournal{path: path, file: file, skip: skip}, nil
+}
+
+// write appends record and syncs it. Without the record a crash could skip
+// documents that were never indexed, so a failed write fails the load.
+func (j *loadJournal) write(record journalRecord) {
+	if j == nil {
+		return
+	}
+	record.Time = time.Now().UTC()
+	var err error
+	if j.store != nil {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.compact.add(record)
+		var data []byte
+		if data, err = j.compact.encode(); err == nil {
+			err = j.store.write(data)
+		}
+	} else if line, marshalErr := json.Marshal(record); marshalErr != nil {
+		err = marshalErr
+	} else {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if _, err = j.file.Write(append(line, '\n')); err == nil {
+			err = j.file.Sync()
+		}
+	}
+	if err != nil {
+		fatal().Err(err).Str("path", redactJournalPath(j.path)).Msg("Error writing load journal")
+	}
+}
+
+// sent records batch before it is sent.
+func (j *loadJournal) sent(batch, docs, consumed int) {
+	if j == nil {
+		return
+	}
+	j.write(journalRecord{Type: "sent", Batch: batch, Documents: docs, Consumed: j.skip + consumed})
+}
+
+// acked records that batch and every batch before it completed.
+func (j *loadJournal) acked(batch, consumed int) {
+	if j == nil {
+		return
+	}
+	j.write(journalRecord{Type: "acked", Batch: batch, Consumed: j.skip + consumed})
+}
+
+// finish records that the load completed, so the next one starts afresh.
+func (j *loadJournal) finish() {
+	j.write(journalRecord{Type: "done"})
+}
+
+// close closes the journal file.
+func (j *loadJournal) close() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file != nil {
+		_ = j.file.Close()
+	}
+}
+
+// logJournalResume describes the load a journal is resuming.
+func logJournalResume(state *journalState, keyed bool) {
+	log.Warn().
+		Int("confirmed", state.confirmed).
+		Int("uncertain_batches", state.uncertainBatches).
+		Int("uncertain_documents", state.uncertainDocuments).
+		Int("uncertain_through", state.through).
+		Msg("Resuming an interrupted load from the journal")
+	if state.uncertainDocuments > 0 && !keyed {
+		log.Warn().
+			Int("uncertain_documents", state.uncertainDocuments).
+			Msg("Documents without an _id in the uncertain window may be indexed twice; use -id to make resends overwrite")
+	}
+}
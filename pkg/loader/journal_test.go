@@ -0,0 +1,124 @@
+package loader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestRunJournalResumesAfterConfirmedBatches verifies behavior for the related scenario.
+func TestRunJournalResumesAfterConfirmedBatches(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu   sync.Mutex
+		sent []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.Method != http.MethodPost || r.URL.Path != "/_bulk" {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		scanner := bufio.NewScanner(r.Body)
+		var items []string
+		for scanner.Scan() {
+			scanner.Scan()
+			var doc struct {
+				Code string `json:"code"`
+			}
+			_ = json.Unmarshal(scanner.Bytes(), &doc)
+			mu.Lock()
+			sent = append(sent, doc.Code)
+			mu.Unlock()
+			items = append(items, `{"index":{"_index":"cards","status":201}}`)
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "cards.ndjson")
+	data := "{\"code\":\"a\"}\n{\"code\":\"b\"}\n{\"code\":\"c\"}\n{\"code\":\"d\"}\n{\"code\":\"e\"}\n"
+	if err := os.WriteFile(dataFile, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	input, _ := json.Marshal(journalInputFor(dataFile))
+	other, _ := json.Marshal(journalInput{Path: dataFile, Size: 1})
+	crashed := func(file []byte) string {
+		return `{"type":"start","index":"cards","file":` + string(file) + "}\n" +
+			`{"type":"sent","batch":1,"documents":2,"consumed":2}` + "\n" +
+			`{"type":"acked","batch":1,"consumed":2}` + "\n" +
+			`{"type":"sent","batch":2,"documents":2,"consumed":4}` + "\n" +
+			`{"type":"ack`
+	}
+
+	tests := []struct {
+		name    string
+		journal string
+		delete  bool
+		wantErr bool
+		want    string
+	}{
+		{name: "no journal", want: "a,b,c,d,e"},
+		{name: "crashed load", journal: crashed(input), want: "c,d,e"},
+		{name: "finished load", journal: crashed(input) + "\n" + `{"type":"done"}` + "\n", want: "a,b,c,d,e"},
+		{name: "changed data file", journal: crashed(other), want: "a,b,c,d,e"},
+		{name: "crashed load recreated", journal: crashed(input), delete: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		journal := filepath.Join(t.TempDir(), "cards.journal")
+		if tt.journal != "" {
+			if err := os.WriteFile(journal, []byte(tt.journal), 0o600); err != nil {
+				t.Fatalf("WriteFile returned error: %v", err)
+			}
+		}
+		mu.Lock()
+		sent = nil
+		mu.Unlock()
+		opts := Options{URL: server.URL, Index: "cards", IDField: "code", DataFile: dataFile, AddToIndex: !tt.delete, DeleteIndex: tt.delete, BatchSize: 2, Journal: journal}
+		_, err := Run(context.Background(), opts)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidOptions) {
+				t.Fatalf("%s: expected ErrInvalidOptions, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		mu.Lock()
+		got := strings.Join(sent, ",")
+		mu.Unlock()
+		if got != tt.want {
+			t.Fatalf("%s: expected %s sent, got %s", tt.name, tt.want, got)
+		}
+
+		content, err := os.ReadFile(journal)
+		if err != nil {
+			t.Fatalf("%s: ReadFile returned error: %v", tt.name, err)
+		}
+		var types []string
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			var record journalRecord
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("%s: invalid journal line %q", tt.name, line)
+			}
+			types = append(types, record.Type)
+		}
+		batches := strings.Count(tt.want, ",")/2 + 1
+		want := "start" + strings.Repeat(",sent,acked", batches) + ",done"
+		if strings.Join(types, ",") != want {
+			t.Fatalf("%s: expected journal %s, got %s", tt.name, want, strings.Join(types, ","))
+		}
+	}
+}
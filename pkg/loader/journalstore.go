@@ -0,0 +1,376 @@
+package loader
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ─── Remote Journal Stores ─────────────────────────────────────────────────────
+
+// journalStore keeps a whole -journal somewhere other than a local file, so
+// a restarted pod without its old disk can still resume. The journal is
+// compacted to the records a resume needs and replaced on every write.
+type journalStore interface {
+	// read returns the stored journal, or nil when there is none.
+	read() ([]byte, error)
+	// write replaces the stored journal with data.
+	write(data []byte) error
+}
+
+// Schemes of a -journal that names a journalStore instead of a file.
+const (
+	journalSchemeConfigMap = "configmap"
+	journalSchemeHTTP      = "http"
+	journalSchemeHTTPS     = "https"
+)
+
+// kubernetesServiceAccountDir holds the token, CA, and namespace Kubernetes
+// mounts into every pod.
+const kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// configMapJournalKey is the ConfigMap data key the journal is stored under.
+const configMapJournalKey = "journal"
+
+// journalStoreTimeout bounds each read or write of a remote journal.
+const journalStoreTimeout = 30 * time.Second
+
+// configMapNamePattern is the DNS subdomain form Kubernetes requires of
+// ConfigMap names.
+var configMapNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// isRemoteJournal reports whether path names a journalStore.
+func isRemoteJournal(path string) bool {
+	scheme, _, ok := strings.Cut(path, "://")
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(scheme) {
+	case journalSchemeConfigMap, journalSchemeHTTP, journalSchemeHTTPS:
+		return true
+	}
+	return false
+}
+
+// openJournalStore returns the store a remote -journal names:
+// configmap://[namespace/]name for a ConfigMap in the pod's cluster, or an
+// http(s) URL any object store answers GET and PUT on.
+func openJournalStore(path string) (journalStore, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		// The parse error quotes the whole URL, credentials included.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("-journal is not a valid URL: %w", err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case journalSchemeConfigMap:
+		return newConfigMapJournalStore(u)
+	default:
+		return &httpJournalStore{url: path, display: redactJournalPath(path), client: &http.Client{Timeout: journalStoreTimeout}}, nil
+	}
+}
+
+// redactJournalPath returns path fit for logs and errors: a remote -journal
+// loses its password and query string, which may hold basic auth
+// credentials or a presigned URL's signature.
+func redactJournalPath(path string) string {
+	if !isRemoteJournal(path) {
+		return path
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return "<invalid -journal URL>"
+	}
+	u.RawQuery, u.ForceQuery, u.Fragment, u.RawFragment = "", false, "", ""
+	return u.Redacted()
+}
+
+// journalPathWithSuffix appends suffix to the file name, ConfigMap name, or
+// URL path -journal names, keeping any query string intact.
+func journalPathWithSuffix(path, suffix string) string {
+	if !isRemoteJournal(path) {
+		return path + suffix
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return path + suffix
+	}
+	if strings.EqualFold(u.Scheme, journalSchemeConfigMap) && strings.Trim(u.Path, "/") == "" {
+		u.Host += suffix
+	} else {
+		u.Path = strings.TrimSuffix(u.Path, "/") + suffix
+	}
+	return u.String()
+}
+
+// compactJournal is the part of a journal a resume reads: the start record,
+// the batches sent but not yet acked, the latest ack, and whether the load
+// finished.
+type compactJournal struct {
+	start journalRecord
+	sent  map[int]journalRecord
+	acked *journalRecord
+	done  bool
+}
+
+// newCompactJournal starts a compacted journal at start.
+func newCompactJournal(start journalRecord) compactJournal {
+	return compactJournal{start: start, sent: make(map[int]journalRecord)}
+}
+
+// add folds record into the journal.
+func (c *compactJournal) add(record journalRecord) {
+	switch record.Type {
+	case "sent":
+		c.sent[record.Batch] = record
+	case "acked":
+		delete(c.sent, record.Batch)
+		if c.acked == nil || record.Consumed >= c.acked.Consumed {
+			c.acked = &record
+		}
+	case "done":
+		c.done = true
+	}
+}
+
+// encode returns the journal as NDJSON that readLoadJournal reads back to
+// the same state as the full journal.
+func (c *compactJournal) encode() ([]byte, error) {
+	records := []journalRecord{c.start}
+	batches := make([]int, 0, len(c.sent))
+	for batch := range c.sent {
+		batches = append(batches, batch)
+	}
+	sort.Ints(batches)
+	for _, batch := range batches {
+		records = append(records, c.sent[batch])
+	}
+	if c.acked != nil {
+		// The sent twin of the latest ack keeps the uncertain_through the
+		// full journal would report.
+		records = append(records, journalRecord{Type: "sent", Time: c.acked.Time, Batch: c.acked.Batch, Consumed: c.acked.Consumed}, *c.acked)
+	}
+	if c.done {
+		records = append(records, journalRecord{Type: "done", Time: time.Now().UTC()})
+	}
+	var buf bytes.Buffer
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// httpJournalStore keeps the journal at a URL that answers GET with the
+// object and PUT by replacing it, such as a presigned or token-authorized
+// object store URL. Credentials in the URL are sent as basic auth.
+type httpJournalStore struct {
+	url string
+	// display is url as redactJournalPath shows it.
+	display string
+	client  *http.Client
+}
+
+// redact replaces the full URL a client error quotes with s.display.
+func (s *httpJournalStore) redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = s.display
+	}
+	return err
+}
+
+// read implements journalStore.
+func (s *httpJournalStore) read() ([]byte, error) {
+	res, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, s.redact(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case res.StatusCode/100 != 2:
+		return nil, fmt.Errorf("reading journal %s: status %d: %s", s.display, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// write implements journalStore.
+func (s *httpJournalStore) write(data []byte) error {
+	req, err := http.NewRequest(http.MethodPut, s.url, bytes.NewReader(data))
+	if err != nil {
+		return s.redact(err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	res, err := s.client.Do(req)
+	if err != nil {
+		return s.redact(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("writing journal %s: status %d: %s", s.display, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// configMapJournalStore keeps the journal in a ConfigMap, through the
+// Kubernetes API with the pod's service account.
+type configMapJournalStore struct {
+	api       string
+	namespace string
+	name      string
+	tokenFile string
+	client    *http.Client
+}
+
+// newConfigMapJournalStore reads the in-cluster API address and service
+// account for configmap://[namespace/]name; the namespace defaults to the
+// pod's own.
+func newConfigMapJournalStore(u *url.URL) (*configMapJournalStore, error) {
+	display := redactJournalPath(u.String())
+	namespace, name := u.Host, strings.Trim(u.Path, "/")
+	if name == "" {
+		namespace, name = "", u.Host
+	}
+	if !configMapNamePattern.MatchString(name) || len(name) > 253 {
+		return nil, fmt.Errorf("-journal %s: %q is not a valid ConfigMap name", display, name)
+	}
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("-journal %s needs to run in a Kubernetes pod: KUBERNETES_SERVICE_HOST is not set", display)
+	}
+	if namespace == "" {
+		content, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("-journal %s: reading the pod namespace: %w", display, err)
+		}
+		namespace = strings.TrimSpace(string(content))
+	}
+	ca, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("-journal %s: reading the cluster CA: %w", display, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("-journal %s: no certificates in the cluster CA", display)
+	}
+	return &configMapJournalStore{
+		api:       "https://" + net.JoinHostPort(host, port),
+		namespace: namespace,
+		name:      name,
+		tokenFile: filepath.Join(kubernetesServiceAccountDir, "token"),
+		client: &http.Client{
+			Timeout:   journalStoreTimeout,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+		},
+	}, nil
+}
+
+// collectionURL returns the ConfigMaps URL of the store's namespace.
+func (s *configMapJournalStore) collectionURL() string {
+	return s.api + "/api/v1/namespaces/" + url.PathEscape(s.namespace) + "/configmaps"
+}
+
+// do sends one Kubernetes API request. The token is read every time,
+// since projected service account tokens rotate.
+func (s *configMapJournalStore) do(method, target string, body []byte) (int, []byte, error) {
+	token, err := os.ReadFile(s.tokenFile)
+	if err != nil {
+		return 0, nil, fmt.Errorf("reading the service account token: %w", err)
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+	answer, err := io.ReadAll(res.Body)
+	return res.StatusCode, answer, err
+}
+
+// read implements journalStore.
+func (s *configMapJournalStore) read() ([]byte, error) {
+	status, body, err := s.do(http.MethodGet, s.collectionURL()+"/"+url.PathEscape(s.name), nil)
+	switch {
+	case err != nil:
+		return nil, err
+	case status == http.StatusNotFound:
+		return nil, nil
+	case status/100 != 2:
+		return nil, fmt.Errorf("reading ConfigMap %s/%s: status %d: %s", s.namespace, s.name, status, strings.TrimSpace(string(body)))
+	}
+	var configMap struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(body, &configMap); err != nil {
+		return nil, fmt.Errorf("parsing ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+	journal, ok := configMap.Data[configMapJournalKey]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(journal), nil
+}
+
+// write implements journalStore, replacing the ConfigMap or creating it
+// on the first write.
+func (s *configMapJournalStore) write(data []byte) error {
+	body, err := json.Marshal(map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":      s.name,
+			"namespace": s.namespace,
+			"labels":    map[string]string{"app.kubernetes.io/managed-by": "es-bulk-loader"},
+		},
+		"data": map[string]string{configMapJournalKey: string(data)},
+	})
+	if err != nil {
+		return err
+	}
+	status, answer, err := s.do(http.MethodPut, s.collectionURL()+"/"+url.PathEscape(s.name), body)
+	if err == nil && status == http.StatusNotFound {
+		status, answer, err = s.do(http.MethodPost, s.collectionURL(), body)
+	}
+	if err != nil {
+		return err
+	}
+	if status/100 != 2 {
+		return fmt.Errorf("writing ConfigMap %s/%s: status %d: %s", s.namespace, s.name, status, strings.TrimSpace(string(answer)))
+	}
+	return nil
+}
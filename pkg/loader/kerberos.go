@@ -0,0 +1,151 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+)
+
+// ─── Kerberos/SPNEGO Authentication ────────────────────────────────────────────
+
+// defaultKrb5Config is read when neither KerberosOptions.Config nor
+// KRB5_CONFIG names a krb5.conf.
+const defaultKrb5Config = "/etc/krb5.conf"
+
+// errKerberos marks failures to obtain Kerberos credentials or tickets, so
+// they are reported as authentication rather than connection errors.
+var errKerberos = errors.New("kerberos authentication failed")
+
+// KerberosOptions authenticates every request with SPNEGO (an
+// "Authorization: Negotiate" header), for clusters behind proxies that
+// accept neither basic auth nor API keys. Credentials come from Keytab
+// with Principal, or from the credential cache CCache that kinit wrote.
+type KerberosOptions struct {
+	// Keytab is the keytab file holding Principal's keys.
+	Keytab string
+	// Principal is the client principal, as user@REALM; without a realm the
+	// krb5.conf default_realm is used.
+	Principal string
+	// CCache is a credential cache path, with or without a FILE: prefix.
+	CCache string
+	// Config is the krb5.conf path; defaults to $KRB5_CONFIG, then
+	// /etc/krb5.conf.
+	Config string
+	// SPN is the proxy's service principal; defaults to HTTP/<URL host>.
+	SPN string
+}
+
+// enabled reports whether Kerberos credentials were configured.
+func (k KerberosOptions) enabled() bool {
+	return k.Keytab != "" || k.CCache != ""
+}
+
+// validateKerberos rejects Kerberos options that cannot work together.
+func validateKerberos(opts Options) error {
+	k := opts.Kerberos
+	switch {
+	case !k.enabled():
+		if k.Principal != "" || k.SPN != "" {
+			return fmt.Errorf("-kerberos-principal and -kerberos-spn need -kerberos-keytab or -kerberos-ccache")
+		}
+		return nil
+	case k.Keytab != "" && k.CCache != "":
+		return fmt.Errorf("use either -kerberos-keytab or -kerberos-ccache, not both")
+	case k.Keytab != "" && k.Principal == "":
+		return fmt.Errorf("-kerberos-keytab needs -kerberos-principal")
+	case opts.User != "" || opts.APIKey != "":
+		return fmt.Errorf("Kerberos cannot be combined with basic auth or an API key")
+	}
+	return nil
+}
+
+// newKerberosClient loads the configured credentials and makes sure they
+// hold a valid ticket-granting ticket.
+func newKerberosClient(k KerberosOptions) (*client.Client, error) {
+	path := k.Config
+	if path == "" {
+		path = os.Getenv("KRB5_CONFIG")
+	}
+	if path == "" {
+		path = defaultKrb5Config
+	}
+	conf, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading krb5 config %s: %v", errKerberos, path, err)
+	}
+
+	var cl *client.Client
+	if k.Keytab != "" {
+		kt, err := keytab.Load(k.Keytab)
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading keytab %s: %v", errKerberos, k.Keytab, err)
+		}
+		user, realm, _ := strings.Cut(k.Principal, "@")
+		if realm == "" {
+			realm = conf.LibDefaults.DefaultRealm
+		}
+		cl = client.NewWithKeytab(user, realm, kt, conf, client.DisablePAFXFAST(true))
+	} else {
+		cache := strings.TrimPrefix(k.CCache, "FILE:")
+		cc, err := credentials.LoadCCache(cache)
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading credential cache %s: %v", errKerberos, cache, err)
+		}
+		cl, err = client.NewFromCCache(cc, conf, client.DisablePAFXFAST(true))
+		if err != nil {
+			return nil, fmt.Errorf("%w: using credential cache %s: %v", errKerberos, cache, err)
+		}
+	}
+	if err := cl.AffirmLogin(); err != nil {
+		return nil, fmt.Errorf("%w: logging in: %v", errKerberos, err)
+	}
+	return cl, nil
+}
+
+// spnegoTransport adds a Negotiate header to every request. The Kerberos
+// client is created on the first request, and its service tickets are
+// reused until they expire.
+type spnegoTransport struct {
+	base http.RoundTripper
+	opts KerberosOptions
+
+	once   sync.Once
+	client *client.Client
+	err    error
+}
+
+// withKerberos wraps base in SPNEGO authentication when opts configure it.
+func withKerberos(opts Options, base http.RoundTripper) http.RoundTripper {
+	if !opts.Kerberos.enabled() {
+		return base
+	}
+	return &spnegoTransport{base: base, opts: opts.Kerberos}
+}
+
+// RoundTrip authenticates the request and forwards it.
+func (t *spnegoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.once.Do(func() {
+		t.client, t.err = newKerberosClient(t.opts)
+	})
+	if t.err != nil {
+		return nil, t.err
+	}
+	spn := t.opts.SPN
+	if spn == "" {
+		spn = "HTTP/" + strings.ToLower(req.URL.Hostname())
+	}
+	req = req.Clone(req.Context())
+	if err := spnego.SetSPNEGOHeader(t.client, req, spn); err != nil {
+		return nil, fmt.Errorf("%w: getting a service ticket for %s: %v", errKerberos, spn, err)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,103 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+)
+
+// TestValidateKerberos verifies behavior for the related scenario.
+func TestValidateKerberos(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{name: "unset"},
+		{name: "keytab", opts: Options{Kerberos: KerberosOptions{Keytab: "svc.keytab", Principal: "loader@EXAMPLE.COM"}}},
+		{name: "credential cache", opts: Options{Kerberos: KerberosOptions{CCache: "FILE:/tmp/krb5cc_1000"}}},
+		{name: "keytab without principal", opts: Options{Kerberos: KerberosOptions{Keytab: "svc.keytab"}}, want: "needs -kerberos-principal"},
+		{name: "both sources", opts: Options{Kerberos: KerberosOptions{Keytab: "svc.keytab", Principal: "a", CCache: "/tmp/cc"}}, want: "not both"},
+		{name: "principal alone", opts: Options{Kerberos: KerberosOptions{Principal: "a"}}, want: "need -kerberos-keytab"},
+		{name: "with api key", opts: Options{APIKey: "abc", Kerberos: KerberosOptions{CCache: "/tmp/cc"}}, want: "cannot be combined"},
+	}
+	for _, tt := range tests {
+		err := validateKerberos(tt.opts)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+// TestPreflightKerberosFailure verifies behavior for the related scenario.
+func TestPreflightKerberosFailure(t *testing.T) {
+	t.Parallel()
+
+	// The KDC address refuses connections, so login fails after the
+	// configuration and keytab have been read.
+	kdc, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen returned error: %v", err)
+	}
+	kdcAddr := kdc.Addr().String()
+	kdc.Close()
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "krb5.conf")
+	confBody := "[libdefaults]\n  default_realm = EXAMPLE.COM\n  udp_preference_limit = 1\n" +
+		"[realms]\n  EXAMPLE.COM = {\n    kdc = " + kdcAddr + "\n  }\n"
+	if err := os.WriteFile(conf, []byte(confBody), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	kt := keytab.New()
+	if err := kt.AddEntry("loader", "EXAMPLE.COM", "secret", time.Now(), 1, etypeID.AES128_CTS_HMAC_SHA1_96); err != nil {
+		t.Fatalf("AddEntry returned error: %v", err)
+	}
+	ktBytes, err := kt.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	ktPath := filepath.Join(dir, "loader.keytab")
+	if err := os.WriteFile(ktPath, ktBytes, 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name     string
+		kerberos KerberosOptions
+		want     string
+	}{
+		{name: "missing config", kerberos: KerberosOptions{Keytab: ktPath, Principal: "loader", Config: filepath.Join(dir, "missing.conf")}, want: "reading krb5 config"},
+		{name: "missing keytab", kerberos: KerberosOptions{Keytab: filepath.Join(dir, "missing.keytab"), Principal: "loader", Config: conf}, want: "reading keytab"},
+		{name: "unreachable KDC", kerberos: KerberosOptions{Keytab: ktPath, Principal: "loader@EXAMPLE.COM", Config: conf}, want: "logging in"},
+	}
+	for _, tt := range tests {
+		_, err := preflightCluster(context.Background(), Options{URL: server.URL, Kerberos: tt.kerberos})
+		if !errors.Is(err, ErrAuthentication) || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected ErrAuthentication mentioning %q, got %v", tt.name, tt.want, err)
+		}
+	}
+	if requests.Load() != 0 {
+		t.Fatalf("expected no unauthenticated requests, got %d", requests.Load())
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
-	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -18,12 +17,14 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v9"
 	"github.com/elastic/go-elasticsearch/v9/esapi"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ─── Error Sentinels ───────────────────────────────────────────────────────────
@@ -41,6 +42,14 @@ var (
 	ErrEnrichExecution = errors.New("enrich execution failed")
 	// ErrLoaderExecution defines package-level state shared by related execution paths.
 	ErrLoaderExecution = errors.New("loader execution failed")
+	// ErrConnection defines package-level state shared by related execution paths.
+	ErrConnection = errors.New("cluster connection failed")
+	// ErrAuthentication defines package-level state shared by related execution paths.
+	ErrAuthentication = errors.New("cluster authentication failed")
+	// ErrValidation defines package-level state shared by related execution paths.
+	ErrValidation = errors.New("validation failed")
+	// ErrDifferences defines package-level state shared by related execution paths.
+	ErrDifferences = errors.New("documents differ")
 )
 
 // ─── Core Runtime Types ────────────────────────────────────────────────────────
@@ -95,35 +104,336 @@ type EnrichOptions struct {
 
 // Options groups state used to coordinate related package behavior.
 type Options struct {
+	// URL is the cluster endpoint; unix:///path/to.sock sends every request
+	// over that Unix domain socket.
 	URL                string
 	InsecureSkipVerify bool
-	Index              string
-	SettingsFile       string
-	MappingsFile       string
-	PipelinesFile      string
-	PoliciesFile       string
-	TransformsFile     string
-	DataFile           string
-	BatchSize          int
-	DeleteIndex        bool
-	AddToIndex         bool
-	FlushIndex         bool
-	SyncManaged        bool
-	AliasMode          bool
-	KeepLast           int
-	Nuke               bool
-	IDField            string
+	// DialTarget, as host:port or unix:///path, receives every connection
+	// instead of the URL's host, such as an SSH tunnel or sidecar proxy;
+	// the URL still names the server for the Host header and TLS.
+	DialTarget string
+	// DialContext, when set, opens every connection instead, for callers
+	// that bring their own tunnel or proxy dialer.
+	DialContext DialFunc
+	// Preflight checks connectivity and credentials with GET / before any
+	// other request, logs the cluster's version, distribution, and license,
+	// and fails with ErrConnection or ErrAuthentication instead of partway
+	// through the run.
+	Preflight      bool
+	Index          string
+	SettingsFile   string
+	MappingsFile   string
+	PipelinesFile  string
+	PoliciesFile   string
+	TransformsFile string
+	// AliasesFile is a keyed JSON object of aliases, such as per-tenant
+	// views with a filter and routing, pointed at the write index after the
+	// load; in -alias mode they move with each new generation.
+	AliasesFile string
+	DataFile    string
+	// DataFiles are loaded with DataFile into the same index. Several files
+	// are read and parsed concurrently, up to ReadConcurrency at a time
+	// (default: one per CPU), and their documents interleave.
+	DataFiles       []string
+	ReadConcurrency int
+	BatchSize       int
+	DeleteIndex     bool
+	AddToIndex      bool
+	FlushIndex      bool
+	SyncManaged     bool
+	AliasMode       bool
+	KeepLast        int
+	Nuke            bool
+	IDField         string
+	// ShrinkTo or SplitTo, with AliasMode, resize each new generation to
+	// that many primary shards after it is loaded and before the alias
+	// moves to it; the loaded index is replaced by a write-blocked copy.
+	ShrinkTo int
+	SplitTo  int
+	// UseTemplates creates indices without settings or mappings of the
+	// loader's own, after checking an installed index template matches, so
+	// the template manages them; one that declares a data stream is written
+	// to with create actions.
+	UseTemplates bool
+	// SourceIncludes and SourceExcludes are added to the _source mapping of
+	// a created index, so excluded fields are indexed but not stored.
+	SourceIncludes []string
+	SourceExcludes []string
+	// Finalize, read-only or write-block, blocks the loaded index once a
+	// load finishes without failed documents, so finished datasets are not
+	// written to by accident.
+	Finalize string
+	// RoutingField names a document field whose string or number value is
+	// sent as the document's bulk routing.
+	RoutingField string
+	// DocType sends this mapping type as each document's _type and nests
+	// created index mappings under it; Elasticsearch 7.x only.
+	DocType string
+	// ConcurrencyControl sends every document with if_seq_no and
+	// if_primary_term, so one a concurrent writer changed since is left
+	// alone and counted in Result.DocumentsConflicted. "input" takes the
+	// values from the document's SeqNoField and PrimaryTermField (default
+	// _seq_no and _primary_term), which are left out of the source; "fetch"
+	// looks them up per batch. Documents without them are sent as create,
+	// so they never overwrite one that exists. It needs AddToIndex and
+	// IDField or elasticdump metadata.
+	ConcurrencyControl string
+	SeqNoField         string
+	PrimaryTermField   string
+	// DynamicTemplatesFile is a JSON object from full field path to the
+	// name of a dynamic template in the index mapping, sent as each bulk
+	// action's dynamic_templates for the fields the document holds, so new
+	// fields get the right type without mapping them all up front.
+	// Elasticsearch 7.13 or later.
+	DynamicTemplatesFile string
+	// PartitionBy writes each document to "<Index>-<value>", the value of
+	// this field lowercased with every run of characters other than
+	// letters, digits, '_', and '.' replaced by a hyphen. Each partition
+	// index is created with Index's settings and mappings when a document
+	// first reaches it; documents without a string or number value go to
+	// Index. PartitionMax caps the partitions one run may write, so a
+	// high-cardinality field fails the load instead of creating thousands
+	// of indices; defaults to 100.
+	PartitionBy  string
+	PartitionMax int
 	// BulkRetryAttempts controls total bulk request attempts, including the first attempt.
 	BulkRetryAttempts int
 	// BulkRetryBackoffBase controls the first retry wait for retryable bulk failures.
 	BulkRetryBackoffBase time.Duration
 	// BulkRetryBackoffMax caps exponential bulk retry waits.
 	BulkRetryBackoffMax time.Duration
-	User                string
-	Pass                string
-	APIKey              string
-	TemplateVariables   map[string]string
-	Enrich              EnrichOptions
+	// SlowBatchThreshold logs a warning for any bulk request slower than this (0 disables).
+	SlowBatchThreshold time.Duration
+	// ErrorLog appends the raw Elasticsearch error of every failed bulk
+	// request and item to this file as NDJSON (empty disables).
+	ErrorLog string
+	// RetryQueue appends the bulk lines of documents whose transient
+	// failures outlast every retry to this file, instead of failing the
+	// load, for Retry to replay later (empty disables).
+	RetryQueue string
+	// Journal records each bulk request before it is sent and each batch
+	// once it completes in this file, synced to disk, so a run after a
+	// crash resumes -add after the confirmed documents and only resends the
+	// batches whose outcome is unknown (empty disables).
+	Journal string
+	// Prefetch is how many encoded batches may wait while the previous one
+	// is in flight, so reading and encoding overlap with the network;
+	// defaults to 1, and a negative value sends each batch before reading on.
+	Prefetch int
+	// Workers is the most bulk requests sent at once (default 1). Queue
+	// rejections from Elasticsearch lower it for a while and then pause
+	// between requests; it climbs back as they stop.
+	Workers int
+	// CapacityCheck, warn or refuse, compares what the load adds with the
+	// cluster before anything is written: the data files' size times the
+	// copies each document is stored in, spread over the data nodes, against
+	// each node's free disk and the high disk watermark, and the shards of
+	// a created index against cluster.max_shards_per_node. MinFreeDisk, in
+	// bytes, is a floor of free disk every node must keep; setting it alone
+	// turns the check on as refuse.
+	CapacityCheck string
+	MinFreeDisk   int64
+	// MaxMemory, in bytes, pauses reading whenever the heap nears it until
+	// the batches already encoded have been sent, and is also set as the
+	// runtime's soft memory limit; 0 disables the guard.
+	MaxMemory int64
+	// ShardGroup, when above 0, collects that many batches at a time and
+	// reorders them by the write index shard each document routes to (by
+	// RoutingField, else its _id) before splitting them into bulk
+	// requests, so each request reaches fewer shards.
+	ShardGroup int
+	// MaxIdleConns is how many idle connections per host are kept for reuse
+	// (default 16, where net/http keeps 2); MaxConnsPerHost caps open
+	// connections per host (0 is unlimited). KeepAlive is the TCP keep-alive
+	// period (default 30s, negative disables) and IdleConnTimeout how long an
+	// idle connection is kept (default 90s).
+	MaxIdleConns    int
+	MaxConnsPerHost int
+	KeepAlive       time.Duration
+	IdleConnTimeout time.Duration
+	// LoadDurability "async" sets index.translog.durability=async on the
+	// write index for the bulk phase and restores the previous value
+	// afterwards, including when the load fails; "" leaves it alone.
+	LoadDurability string
+	User           string
+	Pass           string
+	APIKey         string
+	// OpaqueID is sent as the X-Opaque-Id header of every request, so the
+	// tasks a load starts can be found with the tasks API and stopped with
+	// Cancel; Run defaults it to es-bulk-loader/<index>/<UTC start time>.
+	OpaqueID string
+	// Kerberos authenticates with SPNEGO instead of User/Pass or APIKey.
+	Kerberos KerberosOptions
+	// BearerToken, when set, supplies an "Authorization: Bearer" token for
+	// every request, such as OIDCSession.Token after an -oidc sign-in.
+	BearerToken       TokenFunc
+	TemplateVariables map[string]string
+	Enrich            EnrichOptions
+	// Sync indexes only documents whose _id is new or whose content changed:
+	// each document carries the SHA-256 of its source in SyncHashField
+	// (default content_hash), and documents whose stored hash matches are
+	// left out. It needs AddToIndex and IDField or elasticdump metadata.
+	Sync          bool
+	SyncHashField string
+	// TUI renders a live terminal dashboard during the bulk load instead of streaming logs.
+	TUI bool
+	// TUIOutput receives dashboard frames; defaults to os.Stderr.
+	TUIOutput io.Writer
+	// Preview, when above 0, writes the first Preview documents to
+	// PreviewOutput (default os.Stdout) as the bulk lines they would be
+	// sent as, then returns before anything is written to the cluster.
+	Preview       int
+	PreviewOutput io.Writer
+	// MappingCheck, when above 0, compares the fields of the first
+	// MappingCheck documents with the write index mapping before loading
+	// and warns about fields dynamic: false drops, dynamic: strict rejects,
+	// or dynamic mapping is likely to give the wrong type. Findings are
+	// returned in Result.MappingIssues; none of them stops the load.
+	MappingCheck int
+	// AnalyzeCheck runs a few sample string values of each listed field
+	// through the write index's _analyze API before loading and writes the
+	// tokens to AnalyzeOutput (default os.Stdout); each entry is
+	// "field=analyzer", or "field" for the field's mapped analyzer.
+	AnalyzeCheck  []string
+	AnalyzeOutput io.Writer
+	// Estimate scans the whole input and reports the document count, sizes,
+	// batches, and projected index size in Result.Estimate, then returns
+	// without writing.
+	Estimate bool
+	// Telemetry exports OpenTelemetry traces and metrics over OTLP,
+	// configured through the standard OTEL_EXPORTER_OTLP_* environment variables.
+	Telemetry bool
+	// StatsDAddr sends per-batch StatsD/DogStatsD metrics to this host:port over UDP.
+	StatsDAddr string
+	// NotifyURL receives a POSTed JSON run summary when the run succeeds or fails.
+	NotifyURL string
+	// NotifyTemplate replaces the default summary payload with a $VAR-templated file.
+	NotifyTemplate string
+	// SkipDocuments discards this many leading documents from DataFile before
+	// loading, resuming a partially loaded file.
+	SkipDocuments int
+	// Shard, as i/N, loads only the i-th of N disjoint slices of the input,
+	// so N machines can load one dataset at once without overlap. ShardBy
+	// picks each document's slice: record, by its position in its data
+	// file (the default), or id, by a hash of IDField. SkipDocuments and
+	// Journal then count the slice's documents, and Journal gets a
+	// .shard-i-of-N suffix.
+	Shard   string
+	ShardBy string
+	// OnProgress is called after each bulk batch with the number of documents
+	// from DataFile that are now handled, including skipped ones.
+	OnProgress func(offset int)
+	// Format is json (an array of objects), ndjson, csv, fixed, msgpack,
+	// cbor, protobuf, geojson, elasticdump, accesslog, regex, cef, leef,
+	// evtx, or pcap; empty picks one from the DataFile extension.
+	// ColumnMap is a JSON file describing CSV columns (see columnMap), and
+	// Layout one describing fixed-width fields (see fixedLayout). Descriptor
+	// is a protobuf FileDescriptorSet holding the Message record type.
+	// LogFormat is the accesslog line format: combined (the default),
+	// common, or an Apache LogFormat or nginx log_format string.
+	// GrokPattern is the regex format's regular expression, which may use
+	// %{NAME:field} references to built-in patterns and to those in the
+	// NAME PATTERN files of GrokPatternsDir. PcapMode is packet (the
+	// default) or flow.
+	// GeoShapeField receives GeoJSON geometries and is mapped as a geo_shape;
+	// defaults to geometry. A .zip, .tar, .tar.gz, or .tgz DataFile is read
+	// member by member; ArchivePattern selects members by base name.
+	Format          string
+	ColumnMap       string
+	Layout          string
+	Descriptor      string
+	Message         string
+	LogFormat       string
+	GrokPattern     string
+	GrokPatternsDir string
+	PcapMode        string
+	GeoShapeField   string
+	ArchivePattern  string
+	// PreserveIndex writes elasticdump documents to the _index they were
+	// exported from instead of Index.
+	PreserveIndex bool
+	// SanitizeIndexNames rewrites a PreserveIndex document's _index that
+	// breaks Elasticsearch's index naming rules, lowercasing it and
+	// replacing forbidden characters with hyphens, instead of failing the
+	// load at the first such document.
+	SanitizeIndexNames bool
+	// Tail follows DataFile as NDJSON, indexing appended lines until ctx is cancelled.
+	Tail bool
+	// TailFlushInterval sends a partial batch after this long in tail mode; defaults to 5s.
+	TailFlushInterval time.Duration
+	// Rename moves fields before indexing; each entry is "old=new" and both
+	// sides accept dot-notation paths.
+	Rename []string
+	// ECSPreset maps common source field names onto Elastic Common Schema
+	// fields and sets ecs.version; it is a comma-separated list such as
+	// "weblogs" or "netflow". See ecsPresets.
+	ECSPreset string
+	// Decode decodes encoded payload fields; each entry is
+	// "field=base64" or "field=hex", with an optional ":json" suffix to
+	// parse the decoded text as JSON.
+	Decode []string
+	// ExpandDots turns flat keys such as "user.name" into nested objects.
+	ExpandDots bool
+	// Flatten collapses nested objects into top-level keys joined with
+	// FlattenSeparator (default ".").
+	Flatten          bool
+	FlattenSeparator string
+	// NullPolicy is keep (default), drop to remove null and empty-string
+	// fields, or empty-to-null to index empty strings as null.
+	NullPolicy string
+	// Defaults fills fields missing from a document; each entry is
+	// "field=value" where value is JSON or a plain string.
+	Defaults []string
+	// Convert rewrites numeric fields between units; each entry is
+	// "field=from:to" such as "size=b:mb", "latency=ms:s", or "temp=f:c".
+	Convert []string
+	// Normalize cleans up keyword strings; each entry is "field=op[,op...]"
+	// with ops trim, lower, upper, and collapse-spaces applied in order.
+	Normalize []string
+	// GeoIP enriches documents from GeoIPDatabase, a local MaxMind MMDB
+	// file; each entry is "ipField" or "ipField:targetField" (default
+	// target "geoip").
+	GeoIP         []string
+	GeoIPDatabase string
+	// Lookups joins documents against local dictionaries; each entry is
+	// "lookupFile:keyField:targetField". See loadLookupTable for formats.
+	Lookups []string
+	// Explode splits each document into one document per element of an
+	// array field; each entry is "field" or "field:target". Rules chain, so
+	// two of them produce every combination.
+	Explode []string
+	// UUIDField receives a new UUID in every document that lacks it;
+	// UUIDVersion is 4 (random, the default) or 7 (time-ordered).
+	UUIDField   string
+	UUIDVersion int
+	// Filter indexes only documents for which this expression is true, such
+	// as `status == "active" && amount > 0`; the rest are counted as skipped.
+	Filter string
+	// DedupField drops documents repeating an earlier value of this field;
+	// DedupPolicy is keep-first (default) or keep-last.
+	DedupField  string
+	DedupPolicy string
+	// HashFields replaces each listed field with the hex SHA-256 of HashSalt
+	// followed by the value.
+	HashFields []string
+	HashSalt   string
+	// RedactFields masks each listed field; "field:N" keeps the last N
+	// characters visible.
+	RedactFields []string
+	// DocTemplate is a text/template file that renders each document's
+	// final JSON, with the transformed source document as its dot.
+	DocTemplate string
+	// ScriptTransform is a Starlark file defining transform(doc), called for
+	// each document. See newScriptTransform for the contract.
+	ScriptTransform string
+	// TransformExec is a shell command that receives each transformed
+	// document as an NDJSON line on stdin and answers with one line on
+	// stdout; TransformExecTimeout (default 30s) bounds each answer.
+	TransformExec        string
+	TransformExecTimeout time.Duration
+	// Clusters, when it lists more than one URL, runs the whole load against
+	// each cluster in turn and overrides URL. See runClusters.
+	Clusters []string
 }
 
 // Result groups state used to coordinate related package behavior.
@@ -134,15 +444,65 @@ type Result struct {
 	DocumentsProcessed  int
 	DocumentsSucceeded  int
 	DocumentsFailed     int
-	EnrichSelected      []string
-	EnrichMissing       []string
-	EnrichSucceeded     int
-	EnrichFailed        int
-	Warnings            []string
+	// DocumentsUnchanged counts documents -sync left out because the index
+	// already held them with the same content hash.
+	DocumentsUnchanged int
+	// DocumentsSkipped counts source documents that transforms such as -filter
+	// dropped before indexing.
+	DocumentsSkipped int
+	// DocumentsQueued counts documents written to Options.RetryQueue.
+	DocumentsQueued int
+	// DocumentsConflicted counts the failed documents that
+	// Options.ConcurrencyControl kept from overwriting a newer version.
+	DocumentsConflicted int
+	// DuplicateIDs counts documents whose IDField value repeated an earlier
+	// document's in the same index, so they overwrote it; DuplicateIDSamples
+	// names a few of those ids.
+	DuplicateIDs       int
+	DuplicateIDSamples []string
+	// BytesSent counts bulk request payload bytes across all batches.
+	BytesSent int64
+	// LoadDuration is the wall-clock time spent in the bulk phase.
+	LoadDuration time.Duration
+	// DocumentsPerSecond is the processed document rate over LoadDuration.
+	DocumentsPerSecond float64
+	// BatchLatency summarizes client-observed bulk request latency.
+	BatchLatency LatencySummary
+	// TookLatency summarizes the server-reported bulk "took" time.
+	TookLatency     LatencySummary
+	EnrichSelected  []string
+	EnrichMissing   []string
+	EnrichSucceeded int
+	EnrichFailed    int
+	Warnings        []string
+	// Clusters holds one entry per target when Options.Clusters fans out; the
+	// top-level fields then mirror the first cluster.
+	Clusters []ClusterResult
+	// Cluster describes the cluster when Options.Preflight is set.
+	Cluster ClusterInfo
+	// Estimate holds the Options.Estimate report.
+	Estimate *LoadEstimate
+	// Indices totals each target index, sorted by name; a load routing
+	// documents to their own _index has one entry per index.
+	Indices []IndexResult
+	// MappingIssues holds the Options.MappingCheck findings, sorted by field.
+	MappingIssues []MappingIssue
+	// Analyzed holds the Options.AnalyzeCheck values and their tokens.
+	Analyzed []AnalyzedValue
+	// FilteredAliases lists the Options.AliasesFile aliases applied.
+	FilteredAliases []string
+	// FinalizedIndices lists the indices Options.Finalize blocked.
+	FinalizedIndices []string
+	// PartitionIndices lists the Options.PartitionBy indices written, in the
+	// order documents first reached them.
+	PartitionIndices []string
+	// OpaqueID is the X-Opaque-Id the run tagged its requests with.
+	OpaqueID string
 }
 
 // bulkResponse groups state used to coordinate related package behavior.
 type bulkResponse struct {
+	Took   int64                         `json:"took"`
 	Errors bool                          `json:"errors"`
 	Items  []map[string]bulkItemResponse `json:"items"`
 }
@@ -159,12 +519,97 @@ type bulkItemResponse struct {
 type bulkItemError struct {
 	Type   string `json:"type"`
 	Reason string `json:"reason"`
+	// raw is the error object as Elasticsearch sent it, for -error-log.
+	raw json.RawMessage
+}
+
+// UnmarshalJSON keeps the raw error object next to the decoded fields.
+func (e *bulkItemError) UnmarshalJSON(data []byte) error {
+	type fields bulkItemError
+	if err := json.Unmarshal(data, (*fields)(e)); err != nil {
+		return err
+	}
+	e.raw = append(json.RawMessage(nil), data...)
+	return nil
 }
 
 // bulkInsertResult groups state used to coordinate related package behavior.
 type bulkInsertResult struct {
 	Succeeded int
 	Failed    int
+	// Rejected counts queue rejections met on the way, including ones a
+	// retry got past, so senders can ease off.
+	Rejected int
+	// Queued counts documents written to the retry queue after their
+	// transient failures outlasted every retry.
+	Queued int
+	// Conflicts counts failed items whose concurrency condition no longer
+	// matched.
+	Conflicts int
+	// Indices splits the outcome by target index.
+	Indices indexTallies
+	// FailedItems holds the request positions of the failed documents.
+	FailedItems []int
+}
+
+// bulkSettings groups per-run bulk request behavior shared by every batch.
+type bulkSettings struct {
+	RetryAttempts    int
+	RetryBackoffBase time.Duration
+	RetryBackoffMax  time.Duration
+	IDField          string
+	// RoutingField sets each action's routing from this document field.
+	RoutingField string
+	// DocType sets each action's _type for Elasticsearch 7.x.
+	DocType string
+	// ConcurrencyControl, SeqNoField, and PrimaryTermField add
+	// if_seq_no/if_primary_term conditions; see Options.ConcurrencyControl.
+	ConcurrencyControl string
+	SeqNoField         string
+	PrimaryTermField   string
+	// DynamicTemplates maps field paths to dynamic template names; see
+	// Options.DynamicTemplatesFile.
+	DynamicTemplates map[string]string
+	// PartitionField writes each document to its partition of the batch
+	// index; see Options.PartitionBy.
+	PartitionField string
+	// conditions holds the fetched condition of each document of the batch
+	// being encoded, by position.
+	conditions []concurrencyCondition
+	// Create sends create actions instead of index actions, as data
+	// streams require.
+	Create bool
+	// Metadata moves each document's top-level _id, _index, and _routing
+	// keys into its bulk action, as elasticdump input carries them.
+	Metadata bool
+	// PreserveIndex honours a document's _index when Metadata is set.
+	PreserveIndex bool
+	// SlowThreshold warns about bulk requests slower than this duration.
+	SlowThreshold time.Duration
+	// Worker identifies the sender in live statistics.
+	Worker int
+	// Stats receives live counters; nil disables collection.
+	Stats *loadStats
+	// Telemetry receives per-batch spans and metrics; nil disables export.
+	Telemetry *telemetry
+	// StatsD receives per-batch metrics; nil disables emission.
+	StatsD *statsdClient
+	// ErrorLog receives every failed request and item; nil disables it.
+	ErrorLog *errorLog
+	// RetryQueue receives documents whose transient failures outlast every
+	// retry, instead of failing the load; nil disables it.
+	RetryQueue *retryQueue
+	// IDs receives the _id of every encoded document; nil disables the
+	// duplicate _id report.
+	IDs *idCollisions
+	// itemRetries counts how many times the items of this batch that hit
+	// a full write queue have already been resent.
+	itemRetries int
+	// itemPositions maps the items of a resent batch back to their
+	// positions in the original batch, which started after batchOffset
+	// documents.
+	itemPositions []int
+	batchOffset   int
 }
 
 // namedDefinitions groups state used to coordinate related package behavior.
@@ -201,6 +646,8 @@ const (
 	defaultBulkRetryBackoffBase = 500 * time.Millisecond
 	// defaultBulkRetryBackoffMax defines package-level values shared by related execution paths.
 	defaultBulkRetryBackoffMax = 5 * time.Second
+	// defaultPrefetchBatches defines package-level values shared by related execution paths.
+	defaultPrefetchBatches = 1
 )
 
 // enrichPolicySummary groups state used to coordinate related package behavior.
@@ -380,6 +827,32 @@ func classifyRunErrorKind(op string) error {
 	}
 }
 
+// elasticsearchConfig builds the client configuration shared by every
+// Elasticsearch client the loader creates. Retries are handled by the loader.
+func elasticsearchConfig(opts Options) elasticsearch.Config {
+	url := endpointURL(opts)
+	if url == "" {
+		url = "http://localhost:9200"
+	}
+	cfg := elasticsearch.Config{
+		Addresses:    []string{url},
+		DisableRetry: true,
+		MaxRetries:   0,
+		Transport:    &nodeTrackingTransport{base: authTransport(opts)},
+	}
+	if opts.User != "" && opts.Pass != "" {
+		cfg.Username = opts.User
+		cfg.Password = opts.Pass
+	}
+	if opts.APIKey != "" {
+		cfg.APIKey = opts.APIKey
+	}
+	if opts.OpaqueID != "" {
+		cfg.Header = http.Header{opaqueIDHeader: []string{opts.OpaqueID}}
+	}
+	return cfg
+}
+
 // ─── Fatal/Error Bridging ──────────────────────────────────────────────────────
 
 // fatalEvent groups state used to coordinate related package behavior.
@@ -462,6 +935,9 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	if len(opts.Clusters) > 1 {
+		return runClusters(ctx, opts)
+	}
 	previousLogger := log.Logger
 	log.Logger = withTimestampLogger(log.Logger)
 	defer func() {
@@ -469,7 +945,6 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 	}()
 
 	url := &opts.URL
-	insecure := &opts.InsecureSkipVerify
 	index := &opts.Index
 	settingsFile := &opts.SettingsFile
 	mappingsFile := &opts.MappingsFile
@@ -513,6 +988,18 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 	if *bulkRetryBackoffMax < *bulkRetryBackoffBase {
 		*bulkRetryBackoffMax = *bulkRetryBackoffBase
 	}
+	if opts.OpaqueID == "" {
+		opts.OpaqueID = defaultOpaqueID(*index, time.Now())
+	}
+	result.OpaqueID = opts.OpaqueID
+
+	if strings.TrimSpace(opts.NotifyURL) != "" {
+		started := time.Now()
+		// Registered before the recover handler so it observes the final error.
+		defer func() {
+			notifyRunFinished(ctx, opts.NotifyURL, opts.NotifyTemplate, buildRunSummary(*index, result, err, started, time.Now()))
+		}()
+	}
 
 	defer func() {
 		if recovered := recover(); recovered != nil {
@@ -535,18 +1022,62 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 	if (*user != "" || *pass != "") && *apiKey != "" {
 		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating auth options", Err: fmt.Errorf("cannot use both basic auth and API key")}
 	}
+	if opts.BearerToken != nil && (opts.User != "" || opts.APIKey != "" || opts.Kerberos.enabled()) {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating auth options", Err: fmt.Errorf("a bearer token cannot be combined with basic auth, an API key, or Kerberos")}
+	}
+	if err := validateKerberos(opts); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating auth options", Err: err}
+	}
+	if err := validateDialTarget(opts); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating dial target", Err: err}
+	}
 
 	action, err := selectedDataAction(*addToIndex, *flushIndex, *deleteIndex)
 	if err != nil {
 		return result, &RunError{Kind: ErrInvalidOptions, Op: "selecting data action", Err: err}
 	}
+	docTransforms, err := buildDocTransforms(opts)
+	if err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "parsing document transforms", Err: err}
+	}
+	dedup, err := newDeduper(opts.DedupField, opts.DedupPolicy)
+	if err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "parsing document transforms", Err: err}
+	}
+	if dedup.needsScan() && opts.Tail {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "parsing document transforms", Err: fmt.Errorf("-dedup-policy %s cannot be combined with -tail", dedupKeepLast)}
+	}
+	if dedup.needsScan() && opts.TransformExec != "" {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "parsing document transforms", Err: fmt.Errorf("-dedup-policy %s cannot be combined with -transform-exec, which its pre-scan would run over every document a second time", dedupKeepLast)}
+	}
+	input, err := newInputConfig(opts)
+	if err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating input format", Err: err}
+	}
+	if opts.PreserveIndex && input.format != formatElasticdump {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating input format", Err: fmt.Errorf("-preserve-index needs -format %s", formatElasticdump)}
+	}
+	if opts.Tail && input.format != "" && input.format != formatNDJSON {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating input format", Err: fmt.Errorf("-tail reads NDJSON and cannot be combined with -format %s", input.format)}
+	}
 	effectiveSyncManaged := *syncManaged
 
 	if *index == "" {
 		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating index option", Err: fmt.Errorf("-index is required")}
 	}
+	if err := validateIndexName(*index); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating index option", Err: fmt.Errorf("-index: %w", err)}
+	}
+	// Alias mode loads into -index plus a timestamp suffix, which must fit
+	// the length limit too.
+	if generation := buildTimestampedIndexName(*index, time.Now().UTC()); *aliasMode && len(generation) > maxIndexNameBytes {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating index option", Err: fmt.Errorf("-index: with -alias each generation is named %s-YYYYMMDDHHMMSS, %d bytes; the limit is %d", *index, len(generation), maxIndexNameBytes)}
+	}
+	if opts.SanitizeIndexNames && !opts.PreserveIndex {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating index option", Err: fmt.Errorf("-sanitize-index-names rewrites -preserve-index _index values and needs -preserve-index")}
+	}
 
-	if action.requiresDataFile() && *dataFile == "" {
+	if action.requiresDataFile() && len(opts.dataFiles()) == 0 {
 		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating data option", Err: fmt.Errorf("-data is required for -add, -flush, and -delete")}
 	}
 
@@ -556,6 +1087,118 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 	if *keepLast < 0 {
 		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating keep-last", Err: fmt.Errorf("-keep-last must be 0 or greater")}
 	}
+	if opts.SkipDocuments < 0 {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating skip documents", Err: fmt.Errorf("skip documents must be 0 or greater")}
+	}
+	if opts.ShardGroup < 0 {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating shard group", Err: fmt.Errorf("-shard-group must be 0 or greater")}
+	}
+	if opts.ShardGroup > 0 && opts.RoutingField == "" && *idField == "" && input.formatFor(*dataFile) != formatElasticdump {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating shard group", Err: fmt.Errorf("-shard-group needs -routing-field or -id, since generated _ids cannot be grouped")}
+	}
+	if err := validateDataFiles(opts, input, dedup != nil && dedup.needsScan()); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating data option", Err: err}
+	}
+	if opts.Workers < 0 {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating workers", Err: fmt.Errorf("-workers must be 0 or greater")}
+	}
+	if opts.Workers > 1 && opts.Prefetch < 0 {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating workers", Err: fmt.Errorf("-workers above 1 needs -prefetch of at least 1")}
+	}
+	if err := validateLoadDurability(opts.LoadDurability); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating load durability", Err: err}
+	}
+	if err := validateIncrementalSync(opts, action, input.formatFor(*dataFile)); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating sync", Err: err}
+	}
+	if err := validateConcurrencyControl(opts, action, input.formatFor(*dataFile)); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating concurrency control", Err: err}
+	}
+	dynamicTemplates, err := readDynamicTemplates(opts.DynamicTemplatesFile)
+	if err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "reading dynamic templates", Err: err}
+	}
+	if dynamicTemplates != nil && !action.requiresDataFile() {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "reading dynamic templates", Err: fmt.Errorf("-dynamic-templates applies to loaded documents and needs -add, -flush, or -delete")}
+	}
+	resize, err := resizePlanFor(opts, action)
+	if err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating resize", Err: err}
+	}
+	if err := validateUseTemplates(opts); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating use templates", Err: err}
+	}
+	if err := validateFinalize(opts, action); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating finalize", Err: err}
+	}
+	if err := validateSourceFiltering(opts.SourceIncludes, opts.SourceExcludes); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating source filtering", Err: err}
+	}
+	if err := validatePartitioning(opts, action); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating partitioning", Err: err}
+	}
+	if err := validateCapacityCheck(opts, action); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating capacity check", Err: err}
+	}
+	if input.shard, err = newInputShard(opts, action); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating shard", Err: err}
+	}
+	if input.shard != nil {
+		if opts.Journal != "" {
+			opts.Journal = input.shard.journalPath(opts.Journal)
+		}
+		log.Info().Str("shard", input.shard.String()).Str("shard_by", input.shard.by).Str("journal", redactJournalPath(opts.Journal)).Msg("Loading one shard of the input")
+	}
+	if opts.SeqNoField == "" {
+		opts.SeqNoField = defaultSeqNoField
+	}
+	if opts.PrimaryTermField == "" {
+		opts.PrimaryTermField = defaultPrimaryTermField
+	}
+	if opts.Preview < 0 {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating preview", Err: fmt.Errorf("-preview must be 0 or greater")}
+	}
+	if opts.Preview > 0 && !action.requiresDataFile() {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating preview", Err: fmt.Errorf("-preview needs -add, -flush, or -delete")}
+	}
+	if opts.MappingCheck < 0 {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating mapping check", Err: fmt.Errorf("-mapping-check must be 0 or greater")}
+	}
+	if opts.MappingCheck > 0 && !action.requiresDataFile() {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating mapping check", Err: fmt.Errorf("-mapping-check needs -add, -flush, or -delete")}
+	}
+	analyzeChecks, err := parseAnalyzeChecks(opts.AnalyzeCheck)
+	if err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating analyze check", Err: err}
+	}
+	if len(analyzeChecks) > 0 && !action.requiresDataFile() {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating analyze check", Err: fmt.Errorf("-analyze-check needs -add, -flush, or -delete")}
+	}
+	if opts.Estimate && !action.requiresDataFile() {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating estimate", Err: fmt.Errorf("-estimate needs -add, -flush, or -delete")}
+	}
+	if opts.Estimate && opts.Preview > 0 {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating estimate", Err: fmt.Errorf("-estimate and -preview cannot be combined")}
+	}
+	if opts.Journal != "" && opts.Tail {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating journal", Err: fmt.Errorf("-journal cannot be combined with -tail")}
+	}
+	if opts.Journal != "" && action.requiresDataFile() {
+		state, err := readLoadJournal(opts.Journal)
+		if err != nil {
+			return result, &RunError{Kind: ErrLoaderExecution, Op: "reading load journal", Err: err}
+		}
+		switch {
+		case state.resumes(*index, journalInputFor(opts.dataFiles()[0])):
+			if action != dataActionAdd {
+				return result, &RunError{Kind: ErrInvalidOptions, Op: "validating journal", Err: fmt.Errorf("%s holds an unfinished load; rerun with -add to resume it, or remove it to start over", redactJournalPath(opts.Journal))}
+			}
+			logJournalResume(state, *idField != "" || input.formatFor(opts.dataFiles()[0]) == formatElasticdump)
+			opts.SkipDocuments = state.confirmed
+		case state != nil && !state.done:
+			warn("Ignoring the unfinished -journal load of another index or a changed data file; starting over")
+		}
+	}
 	if *keepLast > 0 && !*aliasMode {
 		warn("Ignoring -keep-last because -alias is not enabled")
 	}
@@ -570,23 +1213,46 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 		warn("Ignoring -transforms because -sync-managed is not enabled")
 	}
 
-	cfg := elasticsearch.Config{
-		Addresses:    []string{*url},
-		DisableRetry: true,
-		MaxRetries:   0,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: *insecure,
-			},
-		},
+	tel, err := newTelemetry(ctx, opts.Telemetry)
+	checkErr("configuring OpenTelemetry exporters", err)
+	defer tel.close()
+	ctx, runSpan := tel.startSpan(ctx, "es-bulk-loader run", attribute.String("es_bulk_loader.index", *index))
+	defer runSpan.End()
+
+	statsd, err := newStatsdClient(opts.StatsDAddr)
+	checkErr("configuring StatsD client", err)
+	defer statsd.close()
+
+	if opts.Preflight {
+		info, err := preflightCluster(ctx, opts)
+		if err != nil {
+			return result, err
+		}
+		result.Cluster = info
+	}
+	if err := validateDocType(opts.DocType, result.Cluster); err != nil {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating doc type", Err: err}
+	}
+	if dynamicTemplates != nil {
+		if err := validateDynamicTemplatesVersion(result.Cluster); err != nil {
+			return result, &RunError{Kind: ErrInvalidOptions, Op: "validating dynamic templates", Err: err}
+		}
+	}
+	serverless := isServerless(opts, result.Cluster)
+	if serverless {
+		if err := validateServerless(opts); err != nil {
+			return result, err
+		}
+		log.Info().Msg("Targeting an Elastic serverless project")
 	}
 
-	if *user != "" && *pass != "" {
-		cfg.Username = *user
-		cfg.Password = *pass
+	cfg := elasticsearchConfig(opts)
+	if opts.Telemetry {
+		cfg.Instrumentation = elasticsearch.NewOpenTelemetryInstrumentation(tel.tracerProvider, false)
 	}
-	if *apiKey != "" {
-		cfg.APIKey = *apiKey
+	if major := legacyMajor(result.Cluster, opts.DocType); major > 0 {
+		cfg.Transport = &compatTransport{base: cfg.Transport, major: major}
+		log.Info().Int("major_version", major).Msg("Sending Elasticsearch compatibility headers")
 	}
 	es, err := elasticsearch.NewClient(cfg)
 	checkErr("creating Elasticsearch client", err)
@@ -595,6 +1261,14 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 	pipelineDefinitions, pipelineNames := readNamedDefinitions(*pipelinesFile, "pipeline", variables)
 	logicalPolicyDefinitions, logicalPolicyNames := readNamedDefinitions(*policiesFile, "policy", variables)
 	logicalTransformDefinitions, logicalTransformNames := readNamedDefinitions(*transformsFile, "transform", variables)
+	aliasDefinitions, aliasNames := readNamedDefinitions(opts.AliasesFile, "alias", variables)
+	managedAlias := ""
+	if *aliasMode {
+		managedAlias = *index
+	}
+	if err := validateFilteredAliases(aliasDefinitions, aliasNames, managedAlias); err != nil {
+		fatal().Err(err).Str("path", opts.AliasesFile).Msg("Invalid alias definitions file")
+	}
 	policyPlan := buildManagedPolicyPlan(logicalPolicyDefinitions, logicalPolicyNames)
 	policyNameMapping := make(map[string]string, len(policyPlan.LogicalToDesired))
 	for logical, desired := range policyPlan.LogicalToDesired {
@@ -650,6 +1324,100 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 		exists, err = indexExists(es, *index)
 		checkErr("checking if index exists", err)
 	}
+	// Serverless projects favour data streams; one takes create actions and
+	// creates itself on first write, so there is no index to manage.
+	dataStream := false
+	if serverless && !*aliasMode {
+		var err error
+		dataStream, err = isDataStreamTarget(es, *index)
+		checkErr("checking for a data stream target", err)
+		if dataStream && (*nuke || action == dataActionDelete) {
+			return result, &RunError{Kind: ErrInvalidOptions, Op: "validating data stream target", Err: fmt.Errorf("%q is a data stream; -delete and -nuke only replace indices, use -add or -flush", *index)}
+		}
+		if dataStream {
+			log.Info().Str("data_stream", *index).Msg("Writing to data stream with create actions")
+		}
+	}
+
+	// Everything up to here only reads from the cluster.
+	if opts.Preview > 0 || opts.Estimate {
+		transforms := docTransforms
+		execStep, err := startExecTransform(opts.TransformExec, opts.TransformExecTimeout)
+		if err != nil {
+			fatal().Err(err).Msg("Error starting external document filter")
+		}
+		defer execStep.close()
+		if execStep != nil {
+			transforms = append(transforms, execStep.transform())
+		}
+		if dedup != nil && !dedup.needsScan() {
+			transforms = append(transforms, dedup.transform())
+		} else if dedup != nil && opts.Estimate {
+			// keep-last keeps one document per key, as keep-first does.
+			first, _ := newDeduper(dedup.field, dedupKeepFirst)
+			transforms = append(transforms, first.transform())
+		}
+		settings := bulkSettings{
+			IDField:          *idField,
+			RoutingField:     opts.RoutingField,
+			DocType:          opts.DocType,
+			Create:           dataStream,
+			Metadata:         input.formatFor(*dataFile) == formatElasticdump,
+			PreserveIndex:    opts.PreserveIndex,
+			SeqNoField:       opts.SeqNoField,
+			PrimaryTermField: opts.PrimaryTermField,
+			DynamicTemplates: dynamicTemplates,
+			PartitionField:   opts.PartitionBy,
+		}
+		if opts.ConcurrencyControl == concurrencyInput {
+			// Fetched conditions depend on the index at send time.
+			settings.ConcurrencyControl = concurrencyInput
+		}
+		target := previewTarget(opts, action, exists)
+		if opts.Estimate {
+			writesExisting := exists && !dataStream && action != dataActionDelete && (!*aliasMode || action == dataActionAdd)
+			replicas := estimateReplicas(es, *index, writesExisting, normalizeIndexSettings(*settingsFile, "", variables))
+			estimate, err := estimateLoad(opts, input, transforms, target, settings, *batchSize, replicas)
+			if err != nil {
+				fatal().Err(err).Msg("Error estimating load")
+			}
+			logLoadEstimate(target, estimate)
+			result.Estimate = &estimate
+			return result, nil
+		}
+		out := opts.PreviewOutput
+		if out == nil {
+			out = os.Stdout
+		}
+		previewed, err := previewDocuments(opts, input, transforms, target, settings, opts.Preview, out)
+		if err != nil {
+			fatal().Err(err).Msg("Error previewing documents")
+		}
+		log.Info().Int("documents", previewed).Msg("Preview complete; nothing was written")
+		return result, nil
+	}
+
+	if mode := opts.capacityMode(); mode != "" && serverless {
+		warn("Skipping -capacity-check: Elastic Serverless manages disk and shards itself")
+	} else if mode != "" {
+		writesExisting := exists && !dataStream && action != dataActionDelete && (!*aliasMode || action == dataActionAdd)
+		createsIndex := !dataStream && !exists
+		if *aliasMode {
+			createsIndex = action == dataActionDelete || len(aliasTargets) == 0
+		}
+		settingsBody := normalizeIndexSettings(*settingsFile, "", variables)
+		plan := newCapacityPlan(opts, estimateReplicas(es, *index, writesExisting, settingsBody), createsIndex, settingsBody)
+		problems, err := checkClusterCapacity(es, plan)
+		if err != nil {
+			fatal().Err(err).Msg("Failed to check cluster capacity")
+		}
+		if len(problems) > 0 && mode == capacityRefuse {
+			return result, &RunError{Kind: ErrValidation, Op: "checking cluster capacity", Err: fmt.Errorf("%s; pass -capacity-check warn to load anyway", strings.Join(problems, "; "))}
+		}
+		for _, problem := range problems {
+			warn("Capacity check: " + problem)
+		}
+	}
 
 	if *nuke {
 		if *aliasMode {
@@ -745,7 +1513,7 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 		}
 	}
 
-	shouldCreateIndex := !exists && (action.requiresDataFile() || (effectiveSyncManaged && (*settingsFile != "" || *mappingsFile != "")))
+	shouldCreateIndex := !exists && !dataStream && (action.requiresDataFile() || (effectiveSyncManaged && (*settingsFile != "" || *mappingsFile != "")))
 	writeIndex := *index
 	createdIndex := ""
 	if *aliasMode && shouldCreateIndex {
@@ -753,6 +1521,22 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 		writeIndex = createdIndex
 		log.Info().Str("alias", *index).Str("index", createdIndex).Msg("Preparing timestamped index for alias")
 	}
+	if opts.UseTemplates && shouldCreateIndex {
+		template, err := verifyIndexTemplate(es, writeIndex)
+		if err != nil {
+			fatal().Err(err).Str("index", writeIndex).Msg("Failed to find the index template for -use-templates")
+		}
+		if template.DataStream && *aliasMode {
+			fatal().Str("index", writeIndex).Str("template", template.Name).Msg("Index template for -use-templates creates a data stream, which -alias cannot roll")
+		}
+		logIndexTemplate(writeIndex, template)
+		if template.DataStream {
+			dataStream, shouldCreateIndex = true, false
+		}
+	}
+	if dataStream && opts.PartitionBy != "" {
+		fatal().Str("index", writeIndex).Msg("-partition-by creates indices and cannot write to a data stream")
+	}
 	result.WriteIndex = writeIndex
 	result.CreatedIndex = createdIndex
 	if *aliasMode {
@@ -763,13 +1547,41 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 		createPipelines(es, pipelineDefinitions, pipelineNames)
 	}
 
-	if shouldCreateIndex {
+	// createIndexBody builds the create index body for -index, which
+	// -partition-by partitions share.
+	createIndexBody := func() string {
 		body := buildCreateIndexBody(*settingsFile, *mappingsFile, defaultPipeline, variables)
+		if opts.UseTemplates {
+			// Only what a load feature needs, so the templates supply the rest.
+			body = "{}"
+		}
+		if field := input.geoShapeFieldFor(*dataFile); field != "" && action.requiresDataFile() {
+			body, err = addGeoShapeMapping(body, field)
+			if err != nil {
+				fatal().Err(err).Str("field", field).Msg("Failed to add geo_shape mapping to index body")
+			}
+		}
+		if len(opts.SourceIncludes) > 0 || len(opts.SourceExcludes) > 0 {
+			body, err = addSourceFiltering(body, opts.SourceIncludes, opts.SourceExcludes)
+			if err != nil {
+				fatal().Err(err).Msg("Failed to add _source filtering to index body")
+			}
+		}
+		if serverless {
+			body, err = stripServerlessSettings(body)
+			if err != nil {
+				fatal().Err(err).Msg("Failed to remove serverless managed settings from index body")
+			}
+		}
+		return body
+	}
+	if shouldCreateIndex {
+		body := createIndexBody()
 		createIndex := *index
 		if *aliasMode {
 			createIndex = createdIndex
 		}
-		res, err := es.Indices.Create(createIndex, es.Indices.Create.WithBody(strings.NewReader(body)))
+		res, err := createIndexRequest(es, createIndex, body, opts.DocType)
 		checkErr("creating index", err)
 		defer res.Body.Close()
 		if res.IsError() {
@@ -804,7 +1616,32 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 		}
 	}
 
+	// partitions creates the Options.PartitionBy indices during the load.
+	var partitions *partitioner
 	if action.requiresDataFile() {
+		if field := input.geoShapeFieldFor(*dataFile); field != "" && !shouldCreateIndex {
+			if err := ensureGeoShapeMapping(es, writeIndex, field); err != nil {
+				fatal().Err(err).Str("index", writeIndex).Msg("Failed to map GeoJSON geometry field")
+			}
+		}
+		if (len(opts.SourceIncludes) > 0 || len(opts.SourceExcludes) > 0) && !shouldCreateIndex && !dataStream {
+			missing, err := missingSourceFilters(es, writeIndex, opts.SourceIncludes, opts.SourceExcludes)
+			if err != nil {
+				fatal().Err(err).Str("index", writeIndex).Msg("Failed to read _source mapping of index")
+			}
+			if len(missing) > 0 {
+				warn(fmt.Sprintf("Index %q was created without _source filtering for %s; it applies only when the index is created, so those fields stay stored", writeIndex, strings.Join(missing, ", ")))
+			}
+		}
+		if dynamicTemplates != nil {
+			missing, err := missingDynamicTemplates(es, writeIndex, dynamicTemplates)
+			if err != nil {
+				fatal().Err(err).Str("index", writeIndex).Msg("Failed to read dynamic templates of index")
+			}
+			if len(missing) > 0 {
+				fatal().Strs("templates", missing).Str("index", writeIndex).Msg("Dynamic templates named by -dynamic-templates are missing from the index mapping")
+			}
+		}
 		preflightPlan, err := buildMappingPreflightPlan(*mappingsFile, variables)
 		if err != nil {
 			fatal().Err(err).Str("path", *mappingsFile).Msg("Failed to build mapping preflight plan")
@@ -814,93 +1651,307 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 				fatal().Err(err).Str("index", writeIndex).Msg("Mapping preflight failed before bulk insert")
 			}
 		}
-		log.Info().Msg("Starting bulk insert")
-
-		f, err := os.Open(*dataFile)
-		checkErr("opening data file", err)
-		defer f.Close()
+		log.Info().Str("opaque_id", opts.OpaqueID).Msg("Starting bulk insert")
 
-		dec := json.NewDecoder(f)
-		tok, err := dec.Token()
-		if err != nil || tok != json.Delim('[') {
-			fatal().Msg("Data file must be a JSON array")
+		// keyTransforms rebuild dedup keys for documents that are read but
+		// not loaded, without running -transform-exec on them.
+		keyTransforms := slices.Clip(docTransforms)
+		execStep, err := startExecTransform(opts.TransformExec, opts.TransformExecTimeout)
+		if err != nil {
+			fatal().Err(err).Msg("Error starting external document filter")
+		}
+		defer execStep.close()
+		if execStep != nil {
+			docTransforms = append(docTransforms, execStep.transform())
+		}
+		// The pre-load checks see each document's source as it will be sent.
+		sampleSettings := bulkSettings{IDField: *idField, Metadata: input.formatFor(*dataFile) == formatElasticdump, PreserveIndex: opts.PreserveIndex, SeqNoField: opts.SeqNoField, PrimaryTermField: opts.PrimaryTermField, PartitionField: opts.PartitionBy}
+		if opts.ConcurrencyControl == concurrencyInput {
+			sampleSettings.ConcurrencyControl = concurrencyInput
+		}
+		if opts.MappingCheck > 0 {
+			issues, sampled, err := checkMappingSample(es, writeIndex, opts, input, docTransforms, sampleSettings, opts.MappingCheck)
+			if err != nil {
+				fatal().Err(err).Str("index", writeIndex).Msg("Mapping check failed before bulk insert")
+			}
+			logMappingIssues(writeIndex, sampled, issues)
+			result.MappingIssues = issues
+		}
+		if len(analyzeChecks) > 0 {
+			samples, err := sampleAnalyzeValues(opts, input, docTransforms, sampleSettings, analyzeChecks)
+			if err != nil {
+				fatal().Err(err).Msg("Error sampling values for the analyze check")
+			}
+			out := opts.AnalyzeOutput
+			if out == nil {
+				out = os.Stdout
+			}
+			result.Analyzed, err = runAnalyzeChecks(es, writeIndex, analyzeChecks, samples, out)
+			if err != nil {
+				fatal().Err(err).Str("index", writeIndex).Msg("Analyze check failed on the write index")
+			}
 		}
 
-		log.Debug().Str("data_file", *dataFile).Msg("Counting documents in data file")
+		var reader documentReader
 		total := 0
-		for dec.More() {
-			var tmp map[string]interface{}
-			if err := dec.Decode(&tmp); err != nil {
-				fatal().Err(err).Msg("Error counting objects in data file")
+		if !opts.Tail {
+			counter, err := input.openDataFiles(opts.dataFiles(), opts.ReadConcurrency)
+			if err != nil {
+				fatal().Err(err).Str("data_file", *dataFile).Msg("Error opening data file")
+			}
+
+			log.Debug().Str("data_file", *dataFile).Msg("Counting documents in data file")
+			rawCounter, countRaw := counter.(rawDocumentReader)
+			countRaw = countRaw && !dedup.needsScan()
+			for {
+				var tmp map[string]interface{}
+				if countRaw {
+					_, err = rawCounter.nextRaw()
+				} else {
+					tmp, err = counter.next()
+				}
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					fatal().Err(err).Msg("Error counting objects in data file")
+				}
+				total++
+				// keep-last needs every key's final position before loading;
+				// documents a resume will skip are not loaded, so not counted.
+				if dedup.needsScan() && total > opts.SkipDocuments {
+					docs, err := keyTransforms.apply(tmp)
+					if err != nil {
+						fatal().Err(err).Int("document", total).Msg("Error transforming document")
+					}
+					dedup.observe(docs)
+				}
+			}
+			counter.close()
+			dedup.finishScan()
+			log.Debug().Str("data_file", *dataFile).Int("total", total).Msg("Document count complete")
+
+			reader, err = input.openDataFiles(opts.dataFiles(), opts.ReadConcurrency)
+			if err != nil {
+				fatal().Err(err).Msg("Error re-reading data file")
 			}
-			total++
+			defer reader.close()
 		}
-		log.Debug().Str("data_file", *dataFile).Int("total", total).Msg("Document count complete")
 
-		if _, err := f.Seek(0, 0); err != nil {
-			fatal().Err(err).Msg("Error rewinding data file")
+		stats := newLoadStats(time.Now())
+		stats.setTotal(total)
+		errorLog, err := openErrorLog(opts.ErrorLog)
+		if err != nil {
+			fatal().Err(err).Str("path", opts.ErrorLog).Msg("Error opening error log")
 		}
-		dec = json.NewDecoder(f)
-		_, err = dec.Token()
+		defer func() {
+			if entries := errorLog.close(); entries > 0 {
+				log.Warn().Str("path", opts.ErrorLog).Int("entries", entries).Msg("Wrote bulk failures to the error log")
+			}
+		}()
+		retryQueue, err := openRetryQueue(opts.RetryQueue)
 		if err != nil {
-			fatal().Err(err).Msg("Error re-reading data file")
+			fatal().Err(err).Str("path", opts.RetryQueue).Msg("Error opening retry queue")
+		}
+		defer func() {
+			if queued := retryQueue.close(); queued > 0 {
+				log.Warn().Str("path", opts.RetryQueue).Int("queued", queued).Msg("Queued documents for a later retry")
+			}
+		}()
+		bulk := bulkSettings{
+			RetryAttempts:      *bulkRetryAttempts,
+			RetryBackoffBase:   *bulkRetryBackoffBase,
+			RetryBackoffMax:    *bulkRetryBackoffMax,
+			IDField:            *idField,
+			RoutingField:       opts.RoutingField,
+			DocType:            opts.DocType,
+			ConcurrencyControl: opts.ConcurrencyControl,
+			SeqNoField:         opts.SeqNoField,
+			PrimaryTermField:   opts.PrimaryTermField,
+			DynamicTemplates:   dynamicTemplates,
+			PartitionField:     opts.PartitionBy,
+			Create:             dataStream,
+			Metadata:           input.formatFor(*dataFile) == formatElasticdump,
+			PreserveIndex:      opts.PreserveIndex,
+			SlowThreshold:      opts.SlowBatchThreshold,
+			ErrorLog:           errorLog,
+			RetryQueue:         retryQueue,
+			IDs:                newIDCollisions(*idField),
+			Worker:             1,
+			Stats:              stats,
+			Telemetry:          tel,
+			StatsD:             statsd,
+		}
+		stopDashboard := func() {}
+		if opts.TUI {
+			out := opts.TUIOutput
+			if out == nil {
+				out = os.Stderr
+			}
+			dash := newDashboard(out, stats, writeIndex)
+			dashLogger := log.Logger
+			log.Logger = log.Logger.Output(zerolog.ConsoleWriter{Out: dash, NoColor: true, TimeFormat: "15:04:05"})
+			dash.start()
+			var once sync.Once
+			stopDashboard = func() {
+				once.Do(func() {
+					dash.finish()
+					log.Logger = dashLogger
+				})
+			}
+			defer stopDashboard()
+		}
+
+		skipped := 0
+		for !opts.Tail && skipped < opts.SkipDocuments {
+			doc, err := reader.next()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				fatal().Err(err).Msg("Error skipping object in data file")
+			}
+			skipped++
+			// keep-first drops later copies of keys a previous run loaded.
+			if dedup != nil && !dedup.needsScan() {
+				docs, err := keyTransforms.apply(doc)
+				if err != nil {
+					fatal().Err(err).Int("document", skipped).Msg("Error transforming document")
+				}
+				dedup.seed(docs)
+			}
+		}
+		if skipped > 0 {
+			log.Info().Int("skipped", skipped).Msg("Resuming data file after previously loaded documents")
+			stats.setTotal(total - skipped)
+		}
+		journal, err := startLoadJournal(opts.Journal, *index, journalInputFor(opts.dataFiles()[0]), skipped)
+		if err != nil {
+			fatal().Err(err).Str("path", redactJournalPath(opts.Journal)).Msg("Error starting load journal")
+		}
+		defer journal.close()
+
+		restoreDurability := func() {}
+		if opts.LoadDurability == loadDurabilityAsync {
+			// Restored once the batcher stops; the defer covers a load that
+			// stops early.
+			restoreDurability = sync.OnceFunc(relaxTranslogDurability(es, writeIndex))
+			defer restoreDurability()
 		}
 
 		overallStart := time.Now()
-		batch := make([]map[string]interface{}, 0, *batchSize)
-		processed := 0
-		succeededTotal := 0
-		failedTotal := 0
-		for dec.More() {
-			var doc map[string]interface{}
-			if err := dec.Decode(&doc); err != nil {
-				fatal().Err(err).Msg("Error decoding object in data file")
-			}
-			batch = append(batch, doc)
-			if len(batch) == *batchSize {
-				batchResult := bulkInsert(
-					ctx,
-					es,
-					writeIndex,
-					batch,
-					processed+len(batch),
-					total,
-					*bulkRetryAttempts,
-					*bulkRetryBackoffBase,
-					*bulkRetryBackoffMax,
-					*idField,
-				)
-				processed += len(batch)
-				succeededTotal += batchResult.Succeeded
-				failedTotal += batchResult.Failed
-				batch = batch[:0]
-			}
-		}
-		if len(batch) > 0 {
-			batchResult := bulkInsert(
-				ctx,
-				es,
-				writeIndex,
-				batch,
-				processed+len(batch),
-				total,
-				*bulkRetryAttempts,
-				*bulkRetryBackoffBase,
-				*bulkRetryBackoffMax,
-				*idField,
-			)
-			processed += len(batch)
-			succeededTotal += batchResult.Succeeded
-			failedTotal += batchResult.Failed
+		batchCtx := ctx
+		if opts.Tail {
+			// Cancelling ctx stops tailing; queued documents are still sent.
+			batchCtx = context.WithoutCancel(ctx)
+		}
+		batcher := newBulkBatcher(batchCtx, es, writeIndex, *batchSize, total-skipped, bulk)
+		batcher.transforms = docTransforms
+		batcher.journal = journal
+		batcher.prefetch = opts.Prefetch
+		if batcher.prefetch == 0 {
+			batcher.prefetch = defaultPrefetchBatches
+		}
+		defer batcher.stop()
+		batcher.throttle = newBulkThrottle(opts.Workers, bulk.RetryBackoffBase, bulk.RetryBackoffMax)
+		batcher.memory = newMemoryGuard(opts.MaxMemory)
+		if opts.ShardGroup > 0 {
+			batcher.configureShardGroups(opts.ShardGroup)
+		}
+		defer batcher.memory.setGCLimit()()
+		if dedup != nil {
+			batcher.transforms = append(batcher.transforms, dedup.transform())
+		}
+		if opts.Sync {
+			batcher.incremental = newIncrementalSync(opts.SyncHashField)
+			batcher.transforms = append(batcher.transforms, batcher.incremental.transform())
+		}
+		if partitions = newPartitioner(es, opts, createIndexBody()); partitions != nil {
+			batcher.transforms = append(batcher.transforms, partitions.transform())
+		}
+		if opts.PreserveIndex {
+			batcher.transforms = append(batcher.transforms, documentIndexNameTransform(opts.SanitizeIndexNames))
+		}
+		batcher.onFlush = func(consumed int) {
+			if opts.OnProgress != nil {
+				opts.OnProgress(skipped + consumed)
+			}
+		}
+		if opts.Tail {
+			if err := tailDataFile(ctx, *dataFile, batcher, opts.TailFlushInterval); err != nil {
+				fatal().Err(err).Str("data_file", *dataFile).Msg("Error tailing data file")
+			}
+		} else if rawReader, ok := reader.(rawDocumentReader); ok && batcher.rawPassthrough() {
+			log.Debug().Str("data_file", *dataFile).Msg("Sending NDJSON lines without re-encoding")
+			for {
+				doc, err := rawReader.nextRaw()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					fatal().Err(err).Msg("Error decoding object in data file")
+				}
+				batcher.addRaw(doc)
+			}
+		} else {
+			for {
+				doc, err := reader.next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					fatal().Err(err).Msg("Error decoding object in data file")
+				}
+				batcher.add(doc)
+			}
 		}
+		batcher.finish()
+		// Before any post-load step: -finalize blocks settings updates, and a
+		// resize copies the settings to an index the restore does not name.
+		restoreDurability()
+		if batcher.memory != nil && batcher.memory.throttled > 0 {
+			log.Info().Int64("max_memory", opts.MaxMemory).Int("throttled_batches", batcher.memory.throttled).Msg("Paused reading to stay under -max-memory")
+		}
+		if batcher.incremental != nil {
+			log.Info().Str("hash_field", batcher.incremental.field).Int("unchanged", batcher.incremental.unchanged).Msg("Left out documents the index already holds unchanged")
+			result.DocumentsUnchanged = batcher.incremental.unchanged
+		}
+		if batcher.conflicted > 0 {
+			log.Warn().Str("concurrency_control", opts.ConcurrencyControl).Int("conflicts", batcher.conflicted).Msg("Left alone documents a concurrent writer changed since their sequence numbers were captured")
+		}
+		if dedup != nil && dedup.dropped > 0 {
+			log.Info().Str("dedup_field", dedup.field).Str("dedup_policy", dedup.policy).Int("duplicates", dedup.dropped).Msg("Dropped duplicate documents")
+		}
+		processed := batcher.processed
+		succeededTotal := batcher.succeeded
+		failedTotal := batcher.failed
+
+		stopDashboard()
 
 		overallDuration := time.Since(overallStart)
+		batchLatency, tookLatency := stats.latencySummaries()
+		snap := stats.snapshot()
+		docsPerSecond := 0.0
+		if overallDuration > 0 {
+			docsPerSecond = float64(processed) / overallDuration.Seconds()
+		}
 		log.Info().
 			Int("processed", processed).
 			Int("succeeded", succeededTotal).
 			Int("failed", failedTotal).
+			Int("queued", batcher.retried).
+			Int("skipped", batcher.dropped).
 			Float64("total_time", overallDuration.Seconds()).
 			Msg("Bulk load completed")
+		result.Indices = batcher.indices.sorted()
+		logIndexResults(result.Indices)
+		result.DuplicateIDs, result.DuplicateIDSamples = bulk.IDs.report(*idField)
+		latencyEvent := log.Info().
+			Int("batches", batchLatency.Count).
+			Float64("docs_per_sec", docsPerSecond).
+			Float64("mb_per_sec", float64(snap.BytesSent)/(1024*1024)/max(overallDuration.Seconds(), 1e-9))
+		latencyEvent = logLatencySummary(latencyEvent, "batch", batchLatency)
+		latencyEvent = logLatencySummary(latencyEvent, "took", tookLatency)
+		latencyEvent.Msg("Bulk load latency summary")
 
 		if failedTotal > 0 {
 			log.Warn().
@@ -911,11 +1962,59 @@ func Run(ctx context.Context, opts Options) (result Result, err error) {
 		result.DocumentsProcessed = processed
 		result.DocumentsSucceeded = succeededTotal
 		result.DocumentsFailed = failedTotal
+		result.DocumentsSkipped = batcher.dropped
+		result.DocumentsQueued = batcher.retried
+		result.DocumentsConflicted = batcher.conflicted
+		journal.finish()
+		result.BytesSent = snap.BytesSent
+		result.LoadDuration = overallDuration
+		result.DocumentsPerSecond = docsPerSecond
+		result.BatchLatency = batchLatency
+		result.TookLatency = tookLatency
+	}
+
+	if resize.Kind != "" && !shouldCreateIndex {
+		warn(fmt.Sprintf("Skipping -%s-to: the run loaded into an existing generation of alias %q", resize.Kind, *index))
+	} else if resize.Kind != "" {
+		base := time.Now().UTC()
+		if created, ok := parseTimestampedIndexName(*index, createdIndex); ok && !base.Truncate(time.Second).After(created) {
+			base = created.Add(time.Second)
+		}
+		target := nextAvailableTimestampedIndexName(es, *index, base)
+		if err := resizeIndex(es, createdIndex, target, resize); err != nil {
+			fatal().Err(err).Str("index", createdIndex).Str("target", target).Msgf("Failed to %s index", resize.Kind)
+		}
+		deleteAndCheck(es, createdIndex)
+		createdIndex, writeIndex = target, target
+		result.CreatedIndex, result.WriteIndex = target, target
 	}
-
 	if *aliasMode && shouldCreateIndex {
 		updateAlias(es, *index, createdIndex)
 	}
+	loadedIndices := []string{writeIndex}
+	if *aliasMode && createdIndex == "" {
+		loadedIndices = aliasTargets
+	}
+	if partitions != nil {
+		loadedIndices = append(loadedIndices, partitions.indices...)
+		result.PartitionIndices = partitions.indices
+	}
+	if len(aliasNames) > 0 && exists {
+		previous := []string(nil)
+		if *aliasMode && createdIndex != "" {
+			previous = aliasTargets
+		}
+		applyFilteredAliases(es, aliasDefinitions, aliasNames, loadedIndices, previous)
+		result.FilteredAliases = aliasNames
+	}
+	if opts.Finalize != "" && (result.DocumentsFailed > 0 || result.DocumentsQueued > 0) {
+		warn(fmt.Sprintf("Skipping -finalize %s: %d documents failed to load", opts.Finalize, result.DocumentsFailed+result.DocumentsQueued))
+	} else if opts.Finalize != "" && len(loadedIndices) > 0 {
+		if err := finalizeIndices(es, loadedIndices, opts.Finalize); err != nil {
+			fatal().Err(err).Strs("indices", loadedIndices).Msg("Failed to block index after load")
+		}
+		result.FinalizedIndices = loadedIndices
+	}
 	if deferPolicyCreationUntilAliasSwap {
 		createPolicies(es, policyDefinitions, policyNames)
 		garbageCollectManagedPolicies(es, policyPlan.LogicalNames, policyPlan.DesiredSet)
@@ -2078,8 +3177,13 @@ func readTemplatedFile(path string, variables templateVariables) ([]byte, error)
 	if err != nil {
 		return nil, err
 	}
+	return []byte(expandTemplateVariables(string(content), variables)), nil
+}
 
-	expanded := templateVariablePattern.ReplaceAllStringFunc(string(content), func(match string) string {
+// expandTemplateVariables replaces $NAME and ${NAME} references from variables,
+// falling back to the environment and leaving unknown references untouched.
+func expandTemplateVariables(content string, variables templateVariables) string {
+	return templateVariablePattern.ReplaceAllStringFunc(content, func(match string) string {
 		name := strings.TrimPrefix(match, "$")
 		name = strings.TrimPrefix(name, "{")
 		name = strings.TrimSuffix(name, "}")
@@ -2092,8 +3196,6 @@ func readTemplatedFile(path string, variables templateVariables) ([]byte, error)
 		}
 		return match
 	})
-
-	return []byte(expanded), nil
 }
 
 // ─── Managed Resource Lifecycle ────────────────────────────────────────────────
@@ -2969,40 +4071,534 @@ func transformStartAlreadyStarted(statusCode int, body []byte) bool {
 
 // ─── Bulk Insert ───────────────────────────────────────────────────────────────
 
+// bulkBatcher accumulates documents into batches of size and sends each full
+// batch through bulkInsert, keeping running totals across the load.
+type bulkBatcher struct {
+	ctx      context.Context
+	es       *elasticsearch.Client
+	index    string
+	size     int
+	total    int
+	settings bulkSettings
+
+	batch []map[string]interface{}
+	// raw holds pre-encoded documents queued by addRaw. A load queues either
+	// decoded or raw documents, never both.
+	raw       []json.RawMessage
+	batches   int
+	processed int
+	succeeded int
+	failed    int
+	// retried counts documents handed to the retry queue.
+	retried int
+	// conflicted counts failed documents whose concurrency condition no
+	// longer matched.
+	conflicted int
+	// journal, when set, records each batch before it is sent and once it
+	// and every earlier batch have completed.
+	journal *loadJournal
+	// indices splits succeeded and failed by target index.
+	indices indexTallies
+
+	// transforms rewrite each added document before it is queued.
+	transforms docTransformChain
+	// consumed counts added source documents whose outputs are all queued.
+	consumed int
+	// dropped counts source documents the transforms produced no output for.
+	dropped int
+	// incremental, when set, leaves out documents the index already holds
+	// unchanged before each batch is encoded.
+	incremental *incrementalSync
+
+	// onFlush, when set, is called after every sent batch with the consumed count.
+	onFlush func(consumed int)
+
+	// prefetch is how many encoded batches may queue for the sender
+	// goroutine while one is in flight; 0 sends each batch from flush.
+	prefetch int
+	queue    chan bulkJob
+	// sent is closed once the sender goroutine has stopped.
+	sent chan struct{}
+	// failure is the panic that stopped the sender, re-raised by finish.
+	failure interface{}
+	// queued counts documents handed to the sender, for progress logs.
+	queued int
+	// memory, when set, holds back encoding while the heap is near its limit.
+	memory *memoryGuard
+	// shards, when set, reorders shardGroup batches at a time by
+	// destination shard before they are split into bulk requests.
+	shards     *shardLayout
+	shardGroup int
+	// sentConsumed is the consumed count before the documents being
+	// grouped, which every bulk request but the window's last reports.
+	sentConsumed int
+
+	// throttle limits the bulk requests in flight and eases off when
+	// Elasticsearch rejects writes; with prefetch and more than one worker,
+	// several batches may be sent at once.
+	throttle *bulkThrottle
+	// mu guards the totals and progress while several batches are in flight.
+	mu sync.Mutex
+	// completed holds the consumed counts of batches sent ahead of an
+	// earlier one, so progress is only reported in batch order.
+	completed map[int]int
+	reported  int
+}
+
+// bulkJob is one encoded batch waiting to be sent.
+type bulkJob struct {
+	body                        *bulkBody
+	docs, batchNumber, inserted int
+	// consumed is the batcher's consumed count when the batch was encoded.
+	consumed int
+}
+
+// newBulkBatcher creates a batcher for index; total is only used for progress logs.
+func newBulkBatcher(ctx context.Context, es *elasticsearch.Client, index string, size, total int, settings bulkSettings) *bulkBatcher {
+	return &bulkBatcher{
+		ctx:      ctx,
+		es:       es,
+		index:    index,
+		size:     size,
+		total:    total,
+		settings: settings,
+		batch:    make([]map[string]interface{}, 0, size),
+		throttle: newBulkThrottle(1, settings.RetryBackoffBase, settings.RetryBackoffMax),
+	}
+}
+
+// add transforms doc, queues the results, and sends the batch whenever it
+// reaches the configured size.
+func (b *bulkBatcher) add(doc map[string]interface{}) {
+	docs := []map[string]interface{}{doc}
+	if len(b.transforms) > 0 {
+		var err error
+		if docs, err = b.transforms.apply(doc); err != nil {
+			fatal().Err(err).Int("document", b.consumed+1).Msg("Error transforming document")
+		}
+	}
+	if len(docs) == 0 {
+		b.dropped++
+		b.consumed++
+		return
+	}
+	for i, out := range docs {
+		// The source document is consumed once its last output is queued, so a
+		// flush triggered by that output already reports it.
+		if i == len(docs)-1 {
+			b.consumed++
+		}
+		b.batch = append(b.batch, out)
+		if len(b.batch) >= b.capacity() {
+			b.flush()
+		}
+	}
+}
+
+// rawPassthrough reports whether documents can be sent exactly as read:
+// nothing rewrites them, so decoding and re-encoding would only cost CPU.
+func (b *bulkBatcher) rawPassthrough() bool {
+	return len(b.transforms) == 0 && !b.settings.decodesDocuments()
+}
+
+// decodesDocuments reports whether documents must be decoded for their
+// bulk actions even without a transform.
+func (s bulkSettings) decodesDocuments() bool {
+	return s.Metadata || s.ConcurrencyControl != "" || s.DynamicTemplates != nil
+}
+
+// perDocumentIndex reports whether documents of one batch may be written to
+// different indices.
+func (s bulkSettings) perDocumentIndex() bool {
+	return s.Metadata && s.PreserveIndex || s.PartitionField != ""
+}
+
+// addRaw queues one pre-encoded JSON object, which must be a single line.
+func (b *bulkBatcher) addRaw(doc []byte) {
+	b.consumed++
+	b.raw = append(b.raw, doc)
+	if len(b.raw) >= b.capacity() {
+		b.flush()
+	}
+}
+
+// capacity is how many documents are queued before flush sends them.
+func (b *bulkBatcher) capacity() int {
+	if b.shards != nil {
+		return b.size * b.shardGroup
+	}
+	return b.size
+}
+
+// pending returns the number of queued documents.
+func (b *bulkBatcher) pending() int {
+	return len(b.batch) + len(b.raw)
+}
+
+// flush encodes any queued documents and sends them, or with prefetch hands
+// them to the sender goroutine; it is a no-op for an empty batch. With shard
+// grouping the queue is reordered and sent as several bulk requests.
+func (b *bulkBatcher) flush() {
+	if b.pending() == 0 {
+		return
+	}
+	if b.shards == nil {
+		b.enqueue(b.batch, b.raw, b.consumed)
+	} else {
+		b.groupByShard()
+		// Progress only covers the window once its last request is sent,
+		// because earlier requests carry documents from all over it.
+		for start := 0; start < b.pending(); start += b.size {
+			end := min(start+b.size, b.pending())
+			consumed := b.sentConsumed
+			if end == b.pending() {
+				consumed = b.consumed
+			}
+			if len(b.raw) > 0 {
+				b.enqueue(nil, b.raw[start:end], consumed)
+			} else {
+				b.enqueue(b.batch[start:end], nil, consumed)
+			}
+		}
+	}
+	b.sentConsumed = b.consumed
+	b.batch = b.batch[:0]
+	b.raw = b.raw[:0]
+}
+
+// enqueue encodes one bulk request and sends it, or with prefetch hands it
+// to the sender goroutine.
+func (b *bulkBatcher) enqueue(batch []map[string]interface{}, raw []json.RawMessage, consumed int) {
+	if b.incremental != nil && len(batch) > 0 {
+		var err error
+		if batch, err = b.incremental.changed(b.ctx, b.es, b.index, batch, b.settings); err != nil {
+			fatal().Err(err).Str("index", b.index).Msg("Failed to look up stored content hashes in index")
+		}
+	}
+	settings := b.settings
+	if settings.ConcurrencyControl == concurrencyFetch && len(batch) > 0 {
+		var err error
+		if settings.conditions, err = fetchConcurrencyConditions(b.ctx, b.es, b.index, batch, settings); err != nil {
+			fatal().Err(err).Str("index", b.index).Msg("Failed to look up current sequence numbers in index")
+		}
+	}
+	docs := len(batch) + len(raw)
+	b.memory.admit(b.drain)
+	b.batches++
+	body := newBulkBody()
+	if len(raw) > 0 {
+		encodeRawBulkBody(body, b.index, raw, settings)
+	} else {
+		encodeBulkBody(body, b.index, batch, settings)
+	}
+	b.queued += docs
+	job := bulkJob{body: body, docs: docs, batchNumber: b.batches, inserted: b.queued, consumed: consumed}
+	if b.prefetch <= 0 {
+		worker := b.throttle.acquire(b.ctx)
+		b.throttle.release(worker, b.send(job, worker))
+		return
+	}
+	if b.queue == nil {
+		b.startSender()
+	}
+	select {
+	case b.queue <- job:
+	case <-b.sent:
+		// The sender stopped on a failed batch; surface it here.
+		panic(b.failure)
+	}
+}
+
+// send sends one encoded batch as worker, adds its outcome to the totals,
+// and returns the queue rejections it met.
+func (b *bulkBatcher) send(job bulkJob, worker int) int {
+	settings := b.settings
+	if b.throttle.workers > 1 {
+		settings.Worker = worker
+	}
+	var batchResult bulkInsertResult
+	b.journal.sent(job.batchNumber, job.docs, job.consumed)
+	// A -sync batch whose documents were all unchanged still reports progress.
+	if job.docs > 0 {
+		batchResult = sendBulkPayload(b.ctx, b.es, b.index, job.body.Bytes(), job.docs, job.batchNumber, job.inserted, b.total, settings)
+	}
+	job.body.release()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.processed += job.docs
+	b.succeeded += batchResult.Succeeded
+	b.failed += batchResult.Failed
+	b.retried += batchResult.Queued
+	b.conflicted += batchResult.Conflicts
+	if b.indices == nil {
+		b.indices = make(indexTallies)
+	}
+	b.indices.merge(batchResult.Indices)
+	if b.completed == nil {
+		b.completed = make(map[int]int)
+	}
+	b.completed[job.batchNumber] = job.consumed
+	for {
+		consumed, ok := b.completed[b.reported+1]
+		if !ok {
+			break
+		}
+		delete(b.completed, b.reported+1)
+		b.reported++
+		b.journal.acked(b.reported, consumed)
+		if b.onFlush != nil {
+			b.onFlush(consumed)
+		}
+	}
+	return batchResult.Rejected
+}
+
+// startSender starts the goroutine that sends queued batches in order, as
+// many at once as the throttle allows. Up to prefetch batches wait for it:
+// prefetch-1 in the channel and one held by a blocked flush.
+func (b *bulkBatcher) startSender() {
+	b.queue = make(chan bulkJob, b.prefetch-1)
+	b.sent = make(chan struct{})
+	go func() {
+		defer close(b.sent)
+		var inflight sync.WaitGroup
+		defer inflight.Wait()
+		for job := range b.queue {
+			if b.stopped() {
+				// flush re-raises the failure once the sender has stopped.
+				job.body.release()
+				break
+			}
+			worker := b.throttle.acquire(b.ctx)
+			inflight.Add(1)
+			go func() {
+				defer inflight.Done()
+				rejected := 0
+				defer func() { b.throttle.release(worker, rejected) }()
+				// sendBulkPayload reports exhausted retries by panicking with
+				// a RunError, which must reach the loading goroutine's recover.
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						b.mu.Lock()
+						if b.failure == nil {
+							b.failure = recovered
+						}
+						b.mu.Unlock()
+					}
+				}()
+				rejected = b.send(job, worker)
+			}()
+		}
+	}()
+}
+
+// stopped reports whether a batch sent by the sender goroutine failed.
+func (b *bulkBatcher) stopped() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failure != nil
+}
+
+// stop waits for the sender goroutine to send every queued batch. It is
+// safe to call more than once and on a batcher without prefetch.
+func (b *bulkBatcher) stop() {
+	if b.queue == nil {
+		return
+	}
+	close(b.queue)
+	<-b.sent
+	b.queue = nil
+}
+
+// drain waits until every queued batch is sent, re-raising a failed batch
+// on the caller's goroutine.
+func (b *bulkBatcher) drain() {
+	b.stop()
+	if b.failure != nil {
+		panic(b.failure)
+	}
+}
+
+// finish sends any queued documents and drains the sender. The totals are
+// only final once it returns.
+func (b *bulkBatcher) finish() {
+	b.flush()
+	b.drain()
+}
+
 // bulkInsert handles a batch of documents and validates per-item bulk response status.
 func bulkInsert(
 	ctx context.Context,
 	es *elasticsearch.Client,
 	index string,
 	batch []map[string]interface{},
-	inserted, total int,
-	retryAttempts int,
-	retryBackoffBase, retryBackoffMax time.Duration,
-	idField string,
+	batchNumber, inserted, total int,
+	settings bulkSettings,
 ) bulkInsertResult {
-	if ctx == nil {
-		ctx = context.Background()
+	body := newBulkBody()
+	defer body.release()
+	encodeBulkBody(body, index, batch, settings)
+	return sendBulkPayload(ctx, es, index, body.Bytes(), len(batch), batchNumber, inserted, total, settings)
+}
+
+// maxPooledBulkBody is the largest request body kept for reuse; rarer,
+// larger batches are left to the garbage collector instead of pinning
+// their memory for the rest of the load.
+const maxPooledBulkBody = 64 << 20
+
+// bulkBody is a reusable bulk request body with a JSON encoder bound to it,
+// so batches are encoded straight into the bytes sent to Elasticsearch and
+// their buffers are recycled across batches instead of reallocated.
+type bulkBody struct {
+	bytes.Buffer
+	enc *json.Encoder
+}
+
+// bulkBodyPool holds released bulkBody values.
+var bulkBodyPool = sync.Pool{New: func() interface{} {
+	body := new(bulkBody)
+	body.enc = json.NewEncoder(&body.Buffer)
+	return body
+}}
+
+// newBulkBody returns an empty body from the pool.
+func newBulkBody() *bulkBody {
+	body := bulkBodyPool.Get().(*bulkBody)
+	body.Reset()
+	return body
+}
+
+// release returns the body to the pool; its bytes must no longer be used.
+func (b *bulkBody) release() {
+	if b.Cap() <= maxPooledBulkBody {
+		bulkBodyPool.Put(b)
+	}
+}
+
+// bulkAction is the action line preceding each document. The field order
+// matches the sorted keys earlier releases produced.
+type bulkAction struct {
+	Index bulkActionMeta `json:"index"`
+}
+
+// bulkCreateAction is the action line of a document written with create.
+type bulkCreateAction struct {
+	Create bulkActionMeta `json:"create"`
+}
+
+// encodeBulkAction writes the action line for meta.
+func encodeBulkAction(body *bulkBody, meta bulkActionMeta, create bool) {
+	if create || meta.create {
+		_ = body.enc.Encode(bulkCreateAction{Create: meta})
+		return
+	}
+	_ = body.enc.Encode(bulkAction{Index: meta})
+}
+
+// bulkActionMeta addresses one indexed document.
+type bulkActionMeta struct {
+	ID            string `json:"_id,omitempty"`
+	Index         string `json:"_index"`
+	Type          string `json:"_type,omitempty"`
+	Routing       string `json:"routing,omitempty"`
+	IfSeqNo       *int64 `json:"if_seq_no,omitempty"`
+	IfPrimaryTerm *int64 `json:"if_primary_term,omitempty"`
+	// DynamicTemplates names the dynamic template for new fields, by path.
+	DynamicTemplates map[string]string `json:"dynamic_templates,omitempty"`
+	// create sends this document as create whatever the load's action.
+	create bool
+}
+
+// encodeBulkBody writes batch to body as bulk index actions.
+func encodeBulkBody(body *bulkBody, index string, batch []map[string]interface{}, settings bulkSettings) {
+	for i, doc := range batch {
+		meta, source := documentMeta(doc, index, settings)
+		if settings.conditions != nil {
+			settings.conditions[i].apply(&meta)
+		}
+		settings.IDs.observe(meta.Index, meta.ID)
+		// Encode appends the newline that ends each NDJSON line.
+		encodeBulkAction(body, meta, settings.Create)
+		_ = body.enc.Encode(source)
+	}
+}
+
+// documentMeta returns the bulk action metadata for doc and the source sent
+// with it, which leaves out elasticdump metadata keys.
+func documentMeta(doc map[string]interface{}, index string, settings bulkSettings) (bulkActionMeta, map[string]interface{}) {
+	meta := bulkActionMeta{Index: index, Type: settings.DocType}
+	if settings.ConcurrencyControl == concurrencyInput {
+		doc = takeConcurrencyFields(doc, &meta, settings.SeqNoField, settings.PrimaryTermField)
+	}
+	if settings.Metadata {
+		doc = takeDocumentMetadata(doc, &meta, settings.PreserveIndex)
+	}
+
+	if settings.IDField != "" {
+		if v, ok := doc[settings.IDField]; ok {
+			if idStr, ok := v.(string); ok && idStr != "" {
+				meta.ID = idStr
+			}
+		}
+	}
+	if settings.RoutingField != "" {
+		if routing := routingValue(doc[settings.RoutingField]); routing != "" {
+			meta.Routing = routing
+		}
+	}
+	if settings.DynamicTemplates != nil {
+		meta.DynamicTemplates = documentDynamicTemplates(doc, settings.DynamicTemplates)
 	}
-	var buf strings.Builder
-	for _, doc := range batch {
-		meta := map[string]map[string]string{"index": {"_index": index}}
+	if settings.PartitionField != "" {
+		meta.Index, _ = partitionIndexName(index, settings.PartitionField, doc)
+	}
+	return meta, doc
+}
 
-		if idField != "" {
-			if v, ok := doc[idField]; ok {
-				if idStr, ok := v.(string); ok && idStr != "" {
-					meta["index"]["_id"] = idStr
+// encodeRawBulkBody writes pre-encoded documents to body as bulk index
+// actions, copying each one unchanged. Only the IDField and RoutingField
+// values are decoded.
+func encodeRawBulkBody(body *bulkBody, index string, docs []json.RawMessage, settings bulkSettings) {
+	for _, doc := range docs {
+		action := bulkAction{Index: bulkActionMeta{Index: index, Type: settings.DocType}}
+		if settings.IDField != "" || settings.RoutingField != "" {
+			var fields map[string]json.RawMessage
+			if json.Unmarshal(doc, &fields) == nil {
+				var idStr string
+				if settings.IDField != "" && json.Unmarshal(fields[settings.IDField], &idStr) == nil && idStr != "" {
+					action.Index.ID = idStr
+				}
+				var routing interface{}
+				if settings.RoutingField != "" && decodeJSON(fields[settings.RoutingField], &routing) == nil {
+					action.Index.Routing = routingValue(routing)
 				}
 			}
 		}
 
-		metaLine, _ := json.Marshal(meta)
-		docLine, _ := json.Marshal(doc)
-		buf.Write(metaLine)
-		buf.WriteByte('\n')
-		buf.Write(docLine)
-		buf.WriteByte('\n')
+		settings.IDs.observe(action.Index.Index, action.Index.ID)
+		encodeBulkAction(body, action.Index, settings.Create)
+		body.Write(doc)
+		body.WriteByte('\n')
 	}
-	payload := buf.String()
+}
+
+// sendBulkPayload sends one encoded batch of docs documents, retrying
+// retryable failures, and validates per-item bulk response status.
+func sendBulkPayload(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index string,
+	payload []byte,
+	docs, batchNumber, inserted, total int,
+	settings bulkSettings,
+) bulkInsertResult {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	retryAttempts := settings.RetryAttempts
+	retryBackoffBase := settings.RetryBackoffBase
+	retryBackoffMax := settings.RetryBackoffMax
 	if retryAttempts <= 0 {
 		retryAttempts = defaultBulkRetryAttempts
 	}
@@ -3015,15 +4611,52 @@ func bulkInsert(
 	if retryBackoffMax < retryBackoffBase {
 		retryBackoffMax = retryBackoffBase
 	}
+	stats := settings.Stats
+	defer stats.setWorkerState(settings.Worker, "idle", batchNumber, 0)
+	batchOffset := inserted - docs
+	position := func(item int) int { return item }
+	if settings.itemPositions != nil {
+		batchOffset = settings.batchOffset
+		position = func(item int) int { return settings.itemPositions[item] }
+	}
+	// queueBatch hands the whole batch to the retry queue.
+	queueBatch := func(reason string) bulkInsertResult {
+		settings.RetryQueue.add(payload, docs)
+		stats.recordBatch(docs, 0, 0, int64(len(payload)), nil, 0, 0)
+		log.Warn().
+			Int("batch", batchNumber).
+			Int("documents", docs).
+			Str("reason", reason).
+			Str("path", settings.RetryQueue.path).
+			Msg("Bulk request retries exhausted; queued the batch for a later retry")
+		requeued := make([]bool, docs)
+		for i := range requeued {
+			requeued[i] = true
+		}
+		return bulkInsertResult{
+			Queued:  docs,
+			Indices: tallyBulkIndices(index, payload, docs, nil, requeued, settings.perDocumentIndex()),
+		}
+	}
+	ctx, span := settings.Telemetry.startSpan(ctx, "bulk batch",
+		attribute.String("es_bulk_loader.index", index),
+		attribute.Int("es_bulk_loader.batch", batchNumber),
+		attribute.Int("es_bulk_loader.docs", docs),
+	)
 
 	var (
-		res      *esapi.Response
-		err      error
-		duration time.Duration
+		res        *esapi.Response
+		err        error
+		duration   time.Duration
+		node       *respondingNode
+		rejections int
 	)
 	for attempt := 1; attempt <= retryAttempts; attempt++ {
+		stats.setWorkerState(settings.Worker, "sending", batchNumber, docs)
+		attemptCtx, attemptNode := withRespondingNode(ctx)
+		node = attemptNode
 		startTime := time.Now()
-		res, err = es.Bulk(strings.NewReader(payload), es.Bulk.WithContext(ctx))
+		res, err = es.Bulk(bytes.NewReader(payload), es.Bulk.WithContext(attemptCtx))
 		duration = time.Since(startTime)
 
 		if err != nil {
@@ -3032,6 +4665,8 @@ func bulkInsert(
 			}
 			if shouldRetryBulkRequest(0, err) && attempt < retryAttempts {
 				nextBackoff := computeExponentialBackoff(attempt, retryBackoffBase, retryBackoffMax)
+				stats.recordRetry(settings.Worker)
+				stats.setWorkerState(settings.Worker, "backoff", batchNumber, docs)
 				log.Warn().
 					Err(err).
 					Int("attempt", attempt).
@@ -3043,14 +4678,23 @@ func bulkInsert(
 				}
 				continue
 			}
+			if settings.RetryQueue != nil && shouldRetryBulkRequest(0, err) {
+				return queueBatch(err.Error())
+			}
 			fatal().Err(err).Msg("Bulk API request failed")
 		}
 
 		if res.IsError() {
 			body, _ := io.ReadAll(res.Body)
 			_ = res.Body.Close()
+			if res.StatusCode == http.StatusTooManyRequests {
+				stats.recordRejection(1)
+				rejections++
+			}
 			if shouldRetryBulkRequest(res.StatusCode, nil) && attempt < retryAttempts {
 				nextBackoff := computeExponentialBackoff(attempt, retryBackoffBase, retryBackoffMax)
+				stats.recordRetry(settings.Worker)
+				stats.setWorkerState(settings.Worker, "backoff", batchNumber, docs)
 				log.Warn().
 					Int("status_code", res.StatusCode).
 					Str("body", string(body)).
@@ -3063,6 +4707,10 @@ func bulkInsert(
 				}
 				continue
 			}
+			if settings.RetryQueue != nil && shouldRetryBulkRequest(res.StatusCode, nil) {
+				return queueBatch(http.StatusText(res.StatusCode))
+			}
+			settings.ErrorLog.batchFailure(index, batchNumber, batchOffset, docs, res.StatusCode, body)
 			fatal().
 				Int("status_code", res.StatusCode).
 				Str("body", string(body)).
@@ -3087,17 +4735,53 @@ func bulkInsert(
 
 	failed := 0
 	logged := 0
+	rejected := 0
+	conflicts := 0
+	errorTypes := make(map[string]int)
+	// Items rejected by a full write queue were never indexed, so while
+	// attempts remain they are resent instead of counted as failures.
+	var requeue []int
+	retryItems := settings.itemRetries+1 < retryAttempts
+	// Rejected items that ran out of attempts go to the retry queue, if any.
+	var queued []int
+	itemFailed := make([]bool, len(parsed.Items))
+	itemRequeued := make([]bool, len(parsed.Items))
+	var failedItems []int
 	for itemIdx, item := range parsed.Items {
 		for action, result := range item {
 			if result.Status >= 300 || result.Error != nil {
+				errorType := ""
+				errorReason := ""
+				if result.Error != nil {
+					errorType = result.Error.Type
+					errorReason = result.Error.Reason
+				}
+				if isBulkItemRejection(result) {
+					rejected++
+					if retryItems {
+						requeue = append(requeue, itemIdx)
+						itemRequeued[itemIdx] = true
+						continue
+					}
+					if settings.RetryQueue != nil {
+						queued = append(queued, itemIdx)
+						itemRequeued[itemIdx] = true
+						continue
+					}
+				}
 				failed++
+				itemFailed[itemIdx] = true
+				failedItems = append(failedItems, position(itemIdx))
+				if result.Status == http.StatusConflict && settings.ConcurrencyControl != "" {
+					conflicts++
+				}
+				settings.ErrorLog.itemFailure(index, batchNumber, batchOffset, position(itemIdx), action, result)
+				countedType := errorType
+				if countedType == "" {
+					countedType = "status_" + strconv.Itoa(result.Status)
+				}
+				errorTypes[countedType]++
 				if logged < 10 {
-					errorType := ""
-					errorReason := ""
-					if result.Error != nil {
-						errorType = result.Error.Type
-						errorReason = result.Error.Reason
-					}
 					log.Error().
 						Int("item", itemIdx).
 						Str("action", action).
@@ -3120,18 +4804,132 @@ func bulkInsert(
 			Msg("Additional bulk item failures omitted from logs")
 	}
 
-	succeeded := len(batch) - failed
+	if len(queued) > 0 {
+		if lines, ok := bulkItemLines(payload, queued); ok {
+			settings.RetryQueue.add(lines, len(queued))
+			log.Warn().
+				Int("batch", batchNumber).
+				Int("documents", len(queued)).
+				Str("path", settings.RetryQueue.path).
+				Msg("Bulk item retries exhausted; queued the items for a later retry")
+		} else {
+			// The body cannot be split per item, so the rejections stand.
+			for _, itemIdx := range queued {
+				itemFailed[itemIdx] = true
+				itemRequeued[itemIdx] = false
+				failedItems = append(failedItems, position(itemIdx))
+			}
+			failed += len(queued)
+			errorTypes["es_rejected_execution_exception"] += len(queued)
+			queued = nil
+		}
+	}
+
+	succeeded := docs - failed - len(requeue) - len(queued)
+	if settings.SlowThreshold > 0 && duration > settings.SlowThreshold {
+		log.Warn().
+			Int("batch", batchNumber).
+			Int("batch_size", docs).
+			Int("bytes", len(payload)).
+			Str("node", node.Host()).
+			Int64("took_ms", parsed.Took).
+			Float64("time_taken", duration.Seconds()).
+			Str("threshold", settings.SlowThreshold.String()).
+			Msg("Slow bulk request exceeded threshold")
+	}
+	settings.Telemetry.recordBatch(ctx, span, index, succeeded, failed, int64(len(payload)), duration, time.Duration(parsed.Took)*time.Millisecond)
+	settings.StatsD.recordBatch(index, succeeded, failed, int64(len(payload)), duration, time.Duration(parsed.Took)*time.Millisecond)
+	stats.recordRejection(rejected)
+	stats.recordBatch(docs-len(requeue), succeeded, failed, int64(len(payload)), errorTypes, duration, time.Duration(parsed.Took)*time.Millisecond)
 	log.Debug().
 		Int("inserted", inserted).
 		Int("total", total).
-		Int("batch_size", len(batch)).
+		Int("batch_size", docs).
 		Int("succeeded", succeeded).
 		Int("failed", failed).
 		Float64("time_taken", duration.Seconds()).
 		Msg("Processed batch")
 
+	result := bulkInsertResult{
+		Succeeded:   succeeded,
+		Failed:      failed,
+		Rejected:    rejections + rejected,
+		Queued:      len(queued),
+		Conflicts:   conflicts,
+		Indices:     tallyBulkIndices(index, payload, docs, itemFailed, itemRequeued, settings.perDocumentIndex()),
+		FailedItems: failedItems,
+	}
+	if len(requeue) > 0 {
+		retryPayload, ok := bulkItemLines(payload, requeue)
+		if !ok {
+			// The body cannot be split per item, so the rejections stand.
+			result.Failed += len(requeue)
+			for _, item := range requeue {
+				result.FailedItems = append(result.FailedItems, position(item))
+			}
+			result.Indices.add(index, 0, 0, len(requeue))
+			errorTypes := map[string]int{"es_rejected_execution_exception": len(requeue)}
+			stats.recordBatch(len(requeue), 0, len(requeue), 0, errorTypes, 0, 0)
+			return result
+		}
+		nextBackoff := computeExponentialBackoff(settings.itemRetries+1, retryBackoffBase, retryBackoffMax)
+		stats.recordRetry(settings.Worker)
+		stats.setWorkerState(settings.Worker, "backoff", batchNumber, len(requeue))
+		log.Warn().
+			Int("batch", batchNumber).
+			Int("rejected_items", len(requeue)).
+			Int("attempt", settings.itemRetries+1).
+			Int("max_attempts", retryAttempts).
+			Str("next_backoff", nextBackoff.String()).
+			Msg("Bulk items rejected by a full write queue; resending them")
+		if sleepErr := sleepWithContext(ctx, nextBackoff); sleepErr != nil {
+			fatal().Err(sleepErr).Msg("Bulk API request failed")
+		}
+		retrySettings := settings
+		retrySettings.itemRetries++
+		retrySettings.batchOffset = batchOffset
+		retrySettings.itemPositions = make([]int, len(requeue))
+		for i, item := range requeue {
+			retrySettings.itemPositions[i] = position(item)
+		}
+		retried := sendBulkPayload(ctx, es, index, retryPayload, len(requeue), batchNumber, inserted, total, retrySettings)
+		result.Succeeded += retried.Succeeded
+		result.Failed += retried.Failed
+		result.Rejected += retried.Rejected
+		result.Queued += retried.Queued
+		result.Conflicts += retried.Conflicts
+		result.Indices.merge(retried.Indices)
+		result.FailedItems = append(result.FailedItems, retried.FailedItems...)
+	}
+
 	// TODO: Persist non-retryable item failures to a dead-letter file for later replay.
-	return bulkInsertResult{Succeeded: succeeded, Failed: failed}
+	return result
+}
+
+// isBulkItemRejection reports whether a bulk item failed only because the
+// write thread pool queue was full, which a later retry can get past.
+func isBulkItemRejection(result bulkItemResponse) bool {
+	if result.Status == http.StatusTooManyRequests {
+		return true
+	}
+	return result.Error != nil && result.Error.Type == "es_rejected_execution_exception"
+}
+
+// bulkItemLines returns the action and source lines of the given items of
+// payload, which holds two lines per item; ok is false when it does not.
+func bulkItemLines(payload []byte, items []int) (lines []byte, ok bool) {
+	all := bytes.SplitAfter(payload, []byte("\n"))
+	if len(all) > 0 && len(all[len(all)-1]) == 0 {
+		all = all[:len(all)-1]
+	}
+	for _, item := range items {
+		if 2*item+1 >= len(all) {
+			return nil, false
+		}
+		lines = append(lines, all[2*item]...)
+		lines = append(lines, all[2*item+1]...)
+	}
+	return lines, true
 }
 
 // shouldRetryBulkRequest centralizes retryability checks for bulk request failures.
@@ -0,0 +1,168 @@
+package loader
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ─── Lookup Enrichment ─────────────────────────────────────────────────────────
+
+// lookupTable joins documents against a local dictionary loaded for -lookup.
+type lookupTable struct {
+	Path   string
+	Key    string
+	Target string
+	// entries holds the JSON encoding of each value so every match decodes a
+	// fresh copy that later transforms can modify freely.
+	entries map[string]json.RawMessage
+}
+
+// parseLookupSpec splits "lookupFile:keyField:targetField". The file is
+// everything before the last two colons, so Windows drive letters still work.
+func parseLookupSpec(value string) (path, key, target string, err error) {
+	rest, target, ok := cutLast(value, ":")
+	if ok {
+		path, key, ok = cutLast(rest, ":")
+	}
+	path, key, target = strings.TrimSpace(path), strings.TrimSpace(key), strings.TrimSpace(target)
+	if !ok || path == "" || key == "" || target == "" {
+		return "", "", "", fmt.Errorf("lookup %q must be lookupFile:keyField:targetField", value)
+	}
+	return path, key, target, nil
+}
+
+// cutLast is strings.Cut around the last occurrence of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// loadLookupTable reads the dictionary named by a -lookup value. A .csv file
+// uses its first column as the key and maps it to an object of the remaining
+// columns, named by the header row. Any other file must hold a JSON object
+// mapping each key to the value to insert.
+func loadLookupTable(value string) (*lookupTable, error) {
+	path, key, target, err := parseLookupSpec(value)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening lookup file: %w", err)
+	}
+	defer file.Close()
+
+	table := &lookupTable{Path: path, Key: key, Target: target}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		table.entries, err = readCSVLookup(file)
+	} else {
+		table.entries, err = readJSONLookup(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading lookup file %s: %w", path, err)
+	}
+	return table, nil
+}
+
+// readCSVLookup keys each row by its first column. Later rows win.
+func readCSVLookup(r io.Reader) (map[string]json.RawMessage, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(header) < 2 {
+		return nil, fmt.Errorf("header needs a key column and at least one value column")
+	}
+	entries := make(map[string]json.RawMessage)
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]interface{}, len(header)-1)
+		for i, column := range header[1:] {
+			if i+1 < len(row) {
+				values[column] = row[i+1]
+			}
+		}
+		raw, err := json.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+		entries[row[0]] = raw
+	}
+}
+
+// readJSONLookup reads a JSON object of key to value.
+func readJSONLookup(r io.Reader) (map[string]json.RawMessage, error) {
+	var entries map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("expected a JSON object of key to value: %w", err)
+	}
+	return entries, nil
+}
+
+// enrich sets Target from the entry matching the document's Key. Strings
+// match as-is and other scalars by their JSON text, so 42 matches "42".
+// Documents without the key, or with a key the table lacks, are unchanged.
+func (t *lookupTable) enrich(doc map[string]interface{}) {
+	value, ok := lookupField(doc, t.Key)
+	if !ok {
+		return
+	}
+	var key string
+	switch v := value.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		return
+	case string:
+		key = v
+	default:
+		encoded, err := json.Marshal(canonicalNumber(v))
+		if err != nil {
+			return
+		}
+		key = string(encoded)
+	}
+	raw, ok := t.entries[key]
+	if !ok {
+		return
+	}
+	var entry interface{}
+	if err := decodeJSON(raw, &entry); err != nil {
+		return
+	}
+	setField(doc, t.Target, entry)
+}
+
+// newLookupTransform loads every -lookup table and applies them in order.
+func newLookupTransform(values []string) (docTransform, error) {
+	tables := make([]*lookupTable, 0, len(values))
+	for _, value := range values {
+		table, err := loadLookupTable(value)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return inPlace(func(doc map[string]interface{}) {
+		for _, table := range tables {
+			table.enrich(doc)
+		}
+	}), nil
+}
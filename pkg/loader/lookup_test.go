@@ -0,0 +1,116 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseLookupSpec verifies behavior for the related scenario.
+func TestParseLookupSpec(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value             string
+		path, key, target string
+		wantErr           bool
+	}{
+		{value: "products.csv:product_id:product", path: "products.csv", key: "product_id", target: "product"},
+		{value: `C:\data\products.json:sku:catalog.item`, path: `C:\data\products.json`, key: "sku", target: "catalog.item"},
+		{value: "products.csv:product_id", wantErr: true},
+		{value: "products.csv::product", wantErr: true},
+		{value: ":id:product", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+			path, key, target, err := parseLookupSpec(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected parseLookupSpec(%q) to fail", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLookupSpec returned error: %v", err)
+			}
+			if path != tt.path || key != tt.key || target != tt.target {
+				t.Fatalf("parseLookupSpec(%q) = %q, %q, %q", tt.value, path, key, target)
+			}
+		})
+	}
+}
+
+// TestLookupTransformJoinsTables verifies behavior for the related scenario.
+func TestLookupTransformJoinsTables(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	productsCSV := filepath.Join(dir, "products.csv")
+	if err := os.WriteFile(productsCSV, []byte("id,category,brand\nP1,tools,acme\n42,toys,\"Big, Co\"\nP1,hardware,acme\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	regionsJSON := filepath.Join(dir, "regions.json")
+	if err := os.WriteFile(regionsJSON, []byte(`{"acme":{"region":"emea","tier":1},"Big, Co":"amer"}`), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	step, err := newLookupTransform([]string{productsCSV + ":product_id:product", regionsJSON + ":product.brand:vendor"})
+	if err != nil {
+		t.Fatalf("newLookupTransform returned error: %v", err)
+	}
+
+	tests := []struct {
+		doc  string
+		want []string
+	}{
+		{doc: `{"product_id":"P1"}`, want: []string{`"category":"hardware"`, `"vendor":{"region":"emea","tier":1}`}},
+		{doc: `{"product_id":42}`, want: []string{`"brand":"Big, Co"`, `"vendor":"amer"`}},
+		{doc: `{"product_id":"missing"}`, want: []string{`{"product_id":"missing"}`}},
+		{doc: `{"other":"P1"}`, want: []string{`{"other":"P1"}`}},
+	}
+	for _, tt := range tests {
+		out, err := step(decodeTestDocument(t, tt.doc))
+		if err != nil {
+			t.Fatalf("transform returned error: %v", err)
+		}
+		got := encodeTestDocument(t, out[0])
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Fatalf("lookup of %s = %s, want it to contain %s", tt.doc, got, want)
+			}
+		}
+	}
+
+	first, _ := step(decodeTestDocument(t, `{"product_id":"P1"}`))
+	first[0]["product"].(map[string]interface{})["category"] = "changed"
+	second, _ := step(decodeTestDocument(t, `{"product_id":"P1"}`))
+	if got := second[0]["product"].(map[string]interface{})["category"]; got != "hardware" {
+		t.Fatalf("expected each match to get its own copy, got %v", got)
+	}
+}
+
+// TestRunRejectsInvalidLookup verifies behavior for the related scenario.
+func TestRunRejectsInvalidLookup(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "list.json")
+	if err := os.WriteFile(invalid, []byte(`["not","an","object"]`), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	headerOnly := filepath.Join(dir, "keys.csv")
+	if err := os.WriteFile(headerOnly, []byte("id\nP1\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	for _, value := range []string{invalid + ":id:x", headerOnly + ":id:x", filepath.Join(dir, "absent.csv") + ":id:x"} {
+		if _, err := buildDocTransforms(Options{Lookups: []string{value}}); err == nil {
+			t.Fatalf("expected lookup %q to be rejected", value)
+		}
+	}
+	if _, err := Run(context.Background(), Options{Index: "logs", AddToIndex: true, Lookups: []string{invalid + ":id:x"}}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected Run to reject an invalid lookup file, got %v", err)
+	}
+}
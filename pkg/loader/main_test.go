@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -938,6 +940,53 @@ func TestRunCreatesPipelinesBeforeIndexWhenDefaultPipelineIsConfigured(t *testin
 	}
 }
 
+// TestEncodeBulkBodyReusesPooledBuffers verifies behavior for the related scenario.
+func TestEncodeBulkBodyReusesPooledBuffers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		settings bulkSettings
+		docs     []map[string]interface{}
+		raw      []json.RawMessage
+		want     string
+	}{
+		{
+			name:     "decoded with id field",
+			settings: bulkSettings{IDField: "id"},
+			docs:     []map[string]interface{}{{"id": "a", "n": json.Number("12345678901234567891")}, {"id": 7, "note": "<b>"}},
+			want: `{"index":{"_id":"a","_index":"cards"}}` + "\n" + `{"id":"a","n":12345678901234567891}` + "\n" +
+				`{"index":{"_index":"cards"}}` + "\n" + `{"id":7,"note":"\u003cb\u003e"}` + "\n",
+		},
+		{
+			name:     "metadata",
+			settings: bulkSettings{Metadata: true, PreserveIndex: true},
+			docs:     []map[string]interface{}{{"_id": "x", "_index": "old", "_routing": "r", "v": true}},
+			want:     `{"index":{"_id":"x","_index":"old","routing":"r"}}` + "\n" + `{"v":true}` + "\n",
+		},
+		{
+			name:     "raw",
+			settings: bulkSettings{IDField: "id"},
+			raw:      []json.RawMessage{json.RawMessage(`{ "id": "b", "price": 1.50 }`)},
+			want:     `{"index":{"_id":"b","_index":"cards"}}` + "\n" + `{ "id": "b", "price": 1.50 }` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		for round := 0; round < 2; round++ {
+			body := newBulkBody()
+			if tt.raw != nil {
+				encodeRawBulkBody(body, "cards", tt.raw, tt.settings)
+			} else {
+				encodeBulkBody(body, "cards", tt.docs, tt.settings)
+			}
+			if got := body.String(); got != tt.want {
+				t.Fatalf("%s round %d: encoded %q, want %q", tt.name, round, got, tt.want)
+			}
+			body.release()
+		}
+	}
+}
+
 // TestRunRetriesBulkOnRetryableStatus verifies behavior for the related scenario.
 func TestRunRetriesBulkOnRetryableStatus(t *testing.T) {
 	t.Parallel()
@@ -1066,6 +1115,86 @@ func TestRunDoesNotRetryBulkOnBadRequest(t *testing.T) {
 	}
 }
 
+// TestRunPrefetchSendsBatchesInOrder verifies behavior for the related scenario.
+func TestRunPrefetchSendsBatchesInOrder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		prefetch int
+		failAt   int
+	}{
+		{name: "synchronous", prefetch: -1},
+		{name: "default", prefetch: 0},
+		{name: "deep", prefetch: 3},
+		{name: "failed batch", prefetch: 3, failAt: 3},
+	}
+	for _, tt := range tests {
+		var (
+			mu     sync.Mutex
+			bodies []string
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Elastic-Product", "Elasticsearch")
+			switch {
+			case r.Method == http.MethodHead && r.URL.Path == "/cards":
+				w.WriteHeader(http.StatusOK)
+			case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				bodies = append(bodies, string(body))
+				count := len(bodies)
+				mu.Unlock()
+				// Give the reader time to run ahead of the request in flight.
+				time.Sleep(5 * time.Millisecond)
+				if count == tt.failAt {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"_index":"cards","_id":"1","status":201}}]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		var progress []int
+		result, err := Run(context.Background(), Options{
+			URL:        server.URL,
+			Index:      "cards",
+			DataFile:   writeTestDataFile(t, "cards.ndjson", "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n{\"n\":4}\n{\"n\":5}\n"),
+			AddToIndex: true,
+			BatchSize:  1,
+			Prefetch:   tt.prefetch,
+			OnProgress: func(offset int) { progress = append(progress, offset) },
+		})
+		if tt.failAt > 0 {
+			if err == nil {
+				t.Fatalf("%s: expected Run to fail", tt.name)
+			}
+			if want := []int{1, 2}; !reflect.DeepEqual(progress, want) {
+				t.Fatalf("%s: progress %v, want %v", tt.name, progress, want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		if result.DocumentsProcessed != 5 || result.DocumentsSucceeded != 5 {
+			t.Fatalf("%s: unexpected result %+v", tt.name, result)
+		}
+		if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(progress, want) {
+			t.Fatalf("%s: progress %v, want %v", tt.name, progress, want)
+		}
+		for i, body := range bodies {
+			if want := fmt.Sprintf(`{"n":%d}`, i+1); !strings.Contains(body, want) {
+				t.Fatalf("%s: batch %d sent %s, want %s", tt.name, i+1, body, want)
+			}
+		}
+	}
+}
+
 // TestRunExhaustsRetriesOnRetryableStatus verifies behavior for the related scenario.
 func TestRunExhaustsRetriesOnRetryableStatus(t *testing.T) {
 	t.Parallel()
@@ -1156,6 +1285,53 @@ func TestRunRetryBackoffCapsAtConfiguredMax(t *testing.T) {
 	}
 }
 
+// TestRunLogsSlowBatchWithNodeAndTook verifies behavior for the related scenario.
+func TestRunLogsSlowBatchWithNodeAndTook(t *testing.T) {
+	previousLogger := log.Logger
+	previousLevel := zerolog.GlobalLevel()
+	t.Cleanup(func() {
+		log.Logger = previousLogger
+		zerolog.SetGlobalLevel(previousLevel)
+	})
+
+	var output bytes.Buffer
+	log.Logger = zerolog.New(&output)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Found-Handling-Instance", "instance-0000000007")
+			_, _ = w.Write([]byte(`{"took":1234,"errors":false,"items":[{"index":{"_index":"cards","_id":"1","status":201}}]}`))
+		default:
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := Run(context.Background(), Options{
+		URL:                server.URL,
+		Index:              "cards",
+		DataFile:           writeBulkDataFixture(t),
+		AddToIndex:         true,
+		SlowBatchThreshold: time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	logs := output.String()
+	for _, want := range []string{"Slow bulk request exceeded threshold", `"node":"instance-0000000007"`, `"took_ms":1234`, `"batch_size":1`} {
+		if !strings.Contains(logs, want) {
+			t.Fatalf("expected %s in logs, got: %s", want, logs)
+		}
+	}
+}
+
 // writeBulkDataFixture centralizes this code path so package behavior stays consistent.
 func writeBulkDataFixture(t *testing.T) string {
 	t.Helper()
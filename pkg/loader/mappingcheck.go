@@ -0,0 +1,354 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Mapping Sample Check ──────────────────────────────────────────────────────
+
+// Kinds of MappingIssue.
+const (
+	// mappingIssueDropped fields sit under dynamic: false, so they are kept
+	// in _source but not indexed or searchable.
+	mappingIssueDropped = "dropped"
+	// mappingIssueRejected fields sit under dynamic: strict, so every
+	// document holding one fails with strict_dynamic_mapping_exception.
+	mappingIssueRejected = "rejected"
+	// mappingIssueType fields are created by dynamic mapping with a type
+	// the sampled values suggest is wrong.
+	mappingIssueType = "dynamic_type"
+)
+
+// MappingIssue is one field of the Options.MappingCheck sample that the
+// index mapping will not take the way the input presumably expects.
+type MappingIssue struct {
+	// Field is the dot-notation path of the input field.
+	Field string
+	// Kind is dropped, rejected, or dynamic_type.
+	Kind string
+	// Documents counts the sampled documents holding the field.
+	Documents int
+	// Detail says what Elasticsearch will do with the field.
+	Detail string
+}
+
+// dynamicDateLayouts approximate Elasticsearch's default
+// dynamic_date_formats, strict_date_optional_time and yyyy/MM/dd HH:mm:ss Z.
+var dynamicDateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.999999999",
+	time.RFC3339Nano,
+	"2006/01/02 15:04:05 -0700",
+	"2006/01/02 -0700",
+}
+
+// sampledField collects what the sample held for one field path.
+type sampledField struct {
+	kind      string
+	documents int
+	// types counts values by the type dynamic mapping would give them.
+	types map[string]int
+	// strings counts string values mapped as text; numeric and dates count
+	// those of them that parse as a number or a date.
+	strings int
+	numeric int
+	dates   int
+}
+
+// mappingSample compares sampled documents with one index mapping.
+type mappingSample struct {
+	root             map[string]any
+	rootDynamic      string
+	dateDetection    bool
+	numericDetection bool
+	// templates is set when the mapping has dynamic_templates, which may
+	// map new fields differently than the defaults modeled here.
+	templates bool
+	fields    map[string]*sampledField
+}
+
+// newMappingSample prepares a comparison against mappings as returned by
+// GET <index>/_mapping.
+func newMappingSample(mappings map[string]any) *mappingSample {
+	s := &mappingSample{
+		rootDynamic:   dynamicSetting(mappings, "true"),
+		dateDetection: true,
+		fields:        make(map[string]*sampledField),
+	}
+	s.root, _ = mappings["properties"].(map[string]any)
+	if detection, ok := mappings["date_detection"].(bool); ok {
+		s.dateDetection = detection
+	}
+	s.numericDetection, _ = mappings["numeric_detection"].(bool)
+	templates, _ := mappings["dynamic_templates"].([]any)
+	s.templates = len(templates) > 0
+	return s
+}
+
+// dynamicSetting returns the dynamic parameter of an object mapping, or
+// inherited when it does not set one.
+func dynamicSetting(mapping map[string]any, inherited string) string {
+	switch typed := mapping["dynamic"].(type) {
+	case bool:
+		return strconv.FormatBool(typed)
+	case string:
+		return strings.ToLower(typed)
+	}
+	return inherited
+}
+
+// observe adds one document source to the sample.
+func (s *mappingSample) observe(doc map[string]interface{}) {
+	s.walk(s.root, s.rootDynamic, "", doc, make(map[string]struct{}))
+}
+
+// walk compares object with the properties it is indexed under; subtrees
+// without a mapping pass nil properties and the dynamic setting in force.
+func (s *mappingSample) walk(properties map[string]any, dynamic, prefix string, object map[string]interface{}, seen map[string]struct{}) {
+	for key, value := range object {
+		// Elasticsearch reads a dotted key as nested objects.
+		if head, rest, dotted := strings.Cut(key, "."); dotted && head != "" {
+			key, value = head, map[string]interface{}{rest: value}
+		}
+		path := prefix + key
+		if mapping, ok := properties[key].(map[string]any); ok {
+			children, isObject := mapping["properties"].(map[string]any)
+			fieldType, _ := mapping["type"].(string)
+			if enabled, ok := mapping["enabled"].(bool); (ok && !enabled) || !isObject && fieldType != "object" && fieldType != "nested" {
+				// A mapped leaf, which Elasticsearch validates itself, or an
+				// object it does not parse.
+				continue
+			}
+			for _, child := range objectValues(value) {
+				s.walk(children, dynamicSetting(mapping, dynamic), path+".", child, seen)
+			}
+			continue
+		}
+		switch dynamic {
+		case "false":
+			s.field(path, mappingIssueDropped, seen)
+		case "strict":
+			s.field(path, mappingIssueRejected, seen)
+		case "runtime":
+			// New fields become runtime fields, read from _source at query time.
+		default:
+			s.observeDynamic(path, value, seen)
+		}
+	}
+}
+
+// observeDynamic records the types dynamic mapping would give value.
+func (s *mappingSample) observeDynamic(path string, value interface{}, seen map[string]struct{}) {
+	switch typed := value.(type) {
+	case nil:
+		return
+	case []interface{}:
+		for _, element := range typed {
+			s.observeDynamic(path, element, seen)
+		}
+		return
+	case map[string]interface{}:
+		s.field(path, "", seen).types["object"]++
+		s.walk(nil, "true", path+".", typed, seen)
+		return
+	}
+	field := s.field(path, "", seen)
+	fieldType := s.dynamicType(value)
+	field.types[fieldType]++
+	if text, ok := value.(string); ok && fieldType == "text" {
+		field.strings++
+		if _, err := strconv.ParseFloat(strings.TrimSpace(text), 64); err == nil {
+			field.numeric++
+		}
+		if looksLikeDate(text) {
+			field.dates++
+		}
+	}
+}
+
+// dynamicType is the field type Elasticsearch's default dynamic mapping
+// gives a scalar value.
+func (s *mappingSample) dynamicType(value interface{}) string {
+	switch typed := value.(type) {
+	case bool:
+		return "boolean"
+	case string:
+		if s.dateDetection && looksLikeDate(typed) {
+			return "date"
+		}
+		if s.numericDetection {
+			if _, err := strconv.ParseInt(typed, 10, 64); err == nil {
+				return "long"
+			}
+			if _, err := strconv.ParseFloat(typed, 64); err == nil {
+				return "float"
+			}
+		}
+		return "text"
+	}
+	if _, ok := integerValue(value); ok {
+		return "long"
+	}
+	if isNumber(value) {
+		return "float"
+	}
+	return "object"
+}
+
+// field returns the entry for path, counting the current document once.
+func (s *mappingSample) field(path, kind string, seen map[string]struct{}) *sampledField {
+	field, ok := s.fields[path]
+	if !ok {
+		field = &sampledField{kind: kind, types: make(map[string]int)}
+		s.fields[path] = field
+	}
+	if _, counted := seen[path]; !counted {
+		seen[path] = struct{}{}
+		field.documents++
+	}
+	return field
+}
+
+// issues returns the sample's findings sorted by field. Fields under a
+// dropped or rejected parent are reported once, at the parent.
+func (s *mappingSample) issues() []MappingIssue {
+	var issues []MappingIssue
+	for path, field := range s.fields {
+		issue := MappingIssue{Field: path, Kind: field.kind, Documents: field.documents}
+		switch field.kind {
+		case mappingIssueDropped:
+			issue.Detail = "unmapped under dynamic: false; kept in _source but not indexed or searchable"
+		case mappingIssueRejected:
+			issue.Detail = "unmapped under dynamic: strict; documents holding it are rejected"
+		default:
+			if s.templates {
+				// dynamic_templates decide these types instead.
+				continue
+			}
+			issue.Kind = mappingIssueType
+			issue.Detail = field.typeDetail(s.dateDetection)
+			if issue.Detail == "" {
+				continue
+			}
+		}
+		issues = append(issues, issue)
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Field < issues[j].Field })
+	return issues
+}
+
+// typeDetail explains why the dynamically created mapping of field looks
+// wrong, or returns "" when it does not.
+func (f *sampledField) typeDetail(dateDetection bool) string {
+	types := make([]string, 0, len(f.types))
+	for fieldType := range f.types {
+		types = append(types, fieldType)
+	}
+	sort.Strings(types)
+	switch {
+	case len(types) == 2 && types[0] == "float" && types[1] == "long":
+		return "mixes whole and fractional numbers; if a whole number is indexed first the field is mapped long and fractions are truncated"
+	case len(types) > 1:
+		return fmt.Sprintf("values map as %s; the first one indexed sets the type and values of the others may be rejected", strings.Join(types, " and "))
+	case f.strings > 0 && f.numeric == f.strings:
+		return "every value is a numeric string, which is mapped as text and keyword rather than a number"
+	case f.strings > 0 && f.dates == f.strings && !dateDetection:
+		return "every value looks like a date, but date_detection is off, so it is mapped as text and keyword"
+	}
+	return ""
+}
+
+// objectValues returns the objects value holds, alone or in an array.
+func objectValues(value interface{}) []map[string]interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{typed}
+	case []interface{}:
+		var objects []map[string]interface{}
+		for _, element := range typed {
+			objects = append(objects, objectValues(element)...)
+		}
+		return objects
+	}
+	return nil
+}
+
+// looksLikeDate reports whether text parses with one of dynamicDateLayouts.
+func looksLikeDate(text string) bool {
+	// Cheap guard: every layout starts with a four-digit year.
+	if len(text) < len("2006-01-02") || text[0] < '0' || text[0] > '9' {
+		return false
+	}
+	for _, layout := range dynamicDateLayouts {
+		if _, err := time.Parse(layout, text); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMappingSample reads the first n documents of the load through its
+// transforms and compares their fields with the mapping of index.
+func checkMappingSample(es *elasticsearch.Client, index string, opts Options, input inputConfig, transforms docTransformChain, settings bulkSettings, n int) ([]MappingIssue, int, error) {
+	mappings, err := fetchIndexMappings(es, index)
+	if err != nil {
+		return nil, 0, err
+	}
+	sample := newMappingSample(mappings)
+	reader, err := input.openDataFiles(opts.dataFiles(), opts.ReadConcurrency)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer reader.close()
+	for skipped := 0; skipped < opts.SkipDocuments; skipped++ {
+		if _, err := reader.next(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, 0, err
+		}
+	}
+	sampled := 0
+	for read := 1; sampled < n; read++ {
+		doc, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, sampled, fmt.Errorf("decoding document %d: %w", read, err)
+		}
+		docs, err := transforms.apply(doc)
+		if err != nil {
+			return nil, sampled, fmt.Errorf("transforming document %d: %w", read, err)
+		}
+		for _, doc := range docs {
+			_, source := documentMeta(doc, index, settings)
+			sample.observe(source)
+			sampled++
+		}
+	}
+	return sample.issues(), sampled, nil
+}
+
+// logMappingIssues warns about each issue, then summarizes the check.
+func logMappingIssues(index string, sampled int, issues []MappingIssue) {
+	for _, issue := range issues {
+		log.Warn().
+			Str("index", index).
+			Str("field", issue.Field).
+			Str("issue", issue.Kind).
+			Int("documents", issue.Documents).
+			Msg(issue.Detail)
+	}
+	log.Info().Str("index", index).Int("sampled", sampled).Int("issues", len(issues)).Msg("Mapping check complete")
+}
@@ -0,0 +1,137 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMappingSampleIssues verifies behavior for the related scenario.
+func TestMappingSampleIssues(t *testing.T) {
+	t.Parallel()
+
+	mappings := `{
+		"properties": {
+			"name": {"type": "keyword"},
+			"meta": {"dynamic": false, "properties": {"source": {"type": "keyword"}}},
+			"audit": {"type": "object", "dynamic": "strict", "properties": {"by": {"type": "keyword"}}},
+			"blob": {"type": "object", "enabled": false}
+		}
+	}`
+	docs := []string{
+		`{"name":"a","meta":{"source":"x","notes":"n"},"audit":{"by":"u","at":"now"},"blob":{"any":1},"zip":"02134","amount":1,"flag":true}`,
+		`{"name":"b","meta.notes":"m","zip":"10001","amount":1.5,"flag":"yes","when":"2024-01-02T03:04:05Z","tags":["a","b"]}`,
+	}
+	tests := []struct {
+		name     string
+		mappings string
+		want     []string
+	}{
+		{
+			name:     "defaults",
+			mappings: mappings,
+			want: []string{
+				"amount dynamic_type 2",
+				"audit.at rejected 1",
+				"flag dynamic_type 2",
+				"meta.notes dropped 2",
+				"zip dynamic_type 2",
+			},
+		},
+		{
+			name:     "date detection off",
+			mappings: strings.Replace(mappings, `"properties": {`, `"date_detection": false, "properties": {`, 1),
+			want: []string{
+				"amount dynamic_type 2",
+				"audit.at rejected 1",
+				"flag dynamic_type 2",
+				"meta.notes dropped 2",
+				"when dynamic_type 1",
+				"zip dynamic_type 2",
+			},
+		},
+		{
+			name:     "dynamic templates",
+			mappings: strings.Replace(mappings, `"properties": {`, `"dynamic_templates": [{"strings": {"match_mapping_type": "string", "mapping": {"type": "keyword"}}}], "properties": {`, 1),
+			want: []string{
+				"audit.at rejected 1",
+				"meta.notes dropped 2",
+			},
+		},
+		{
+			name:     "strict root",
+			mappings: `{"dynamic": "strict", "properties": {"name": {"type": "keyword"}, "meta": {"dynamic": true, "properties": {}}}}`,
+			want: []string{
+				"amount rejected 2",
+				"audit rejected 1",
+				"blob rejected 1",
+				"flag rejected 2",
+				"tags rejected 1",
+				"when rejected 1",
+				"zip rejected 2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		var parsed map[string]any
+		if err := json.Unmarshal([]byte(tt.mappings), &parsed); err != nil {
+			t.Fatalf("%s: Unmarshal returned error: %v", tt.name, err)
+		}
+		sample := newMappingSample(parsed)
+		for _, doc := range docs {
+			var decoded map[string]interface{}
+			if err := decodeJSON([]byte(doc), &decoded); err != nil {
+				t.Fatalf("%s: decodeJSON returned error: %v", tt.name, err)
+			}
+			sample.observe(decoded)
+		}
+		var got []string
+		for _, issue := range sample.issues() {
+			got = append(got, fmt.Sprintf("%s %s %d", issue.Field, issue.Kind, issue.Documents))
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Fatalf("%s: expected issues\n%s\ngot\n%s", tt.name, strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+// TestRunMappingCheckWarnsBeforeLoading verifies behavior for the related scenario.
+func TestRunMappingCheckWarnsBeforeLoading(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/cards/_mapping":
+			_, _ = w.Write([]byte(`{"cards":{"mappings":{"dynamic":"strict","properties":{"code":{"type":"keyword"}}}}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	data := "{\"code\":\"a\"}\n{\"code\":\"b\",\"color\":\"red\"}\n{\"code\":\"c\",\"size\":2}\n"
+	result, err := Run(context.Background(), Options{
+		URL:          server.URL,
+		Index:        "cards",
+		DataFile:     writeTestDataFile(t, "cards.ndjson", data),
+		AddToIndex:   true,
+		MappingCheck: 2,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(result.MappingIssues) != 1 || result.MappingIssues[0].Field != "color" || result.MappingIssues[0].Kind != mappingIssueRejected {
+		t.Fatalf("expected only color to be rejected from the first two documents, got %+v", result.MappingIssues)
+	}
+	if result.DocumentsProcessed != 3 {
+		t.Fatalf("expected the load to go on with 3 documents, got %d", result.DocumentsProcessed)
+	}
+}
@@ -0,0 +1,81 @@
+package loader
+
+import (
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Memory Guard ──────────────────────────────────────────────────────────────
+
+// heapObjectsMetric is the live and not yet swept heap, readable without
+// stopping the world.
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// memoryGuardHighWater is the share of -max-memory at which reading pauses.
+const memoryGuardHighWater = 0.9
+
+// memoryGuard applies -max-memory backpressure. Before each batch is encoded
+// it checks the heap; near the limit it stops reading ahead, waits for the
+// batches already encoded to be sent so their buffers can be collected, and
+// forces a collection before reading on.
+type memoryGuard struct {
+	limit int64
+	high  uint64
+	// heap reports the current heap size; tests replace it.
+	heap func() uint64
+	// throttled counts the batches that waited for memory.
+	throttled int
+	warned    bool
+}
+
+// newMemoryGuard returns a guard for limit bytes, or nil when limit is 0.
+func newMemoryGuard(limit int64) *memoryGuard {
+	if limit <= 0 {
+		return nil
+	}
+	return &memoryGuard{limit: limit, high: uint64(float64(limit) * memoryGuardHighWater), heap: readHeapObjects}
+}
+
+// readHeapObjects samples heapObjectsMetric.
+func readHeapObjects() uint64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// setGCLimit also makes limit the runtime's soft memory limit, so the
+// collector works harder before the guard has to pause reading. The
+// returned function restores the previous limit.
+func (g *memoryGuard) setGCLimit() func() {
+	if g == nil {
+		return func() {}
+	}
+	previous := debug.SetMemoryLimit(g.limit)
+	return func() { debug.SetMemoryLimit(previous) }
+}
+
+// admit returns once there is room to encode another batch. drain waits
+// for every queued batch to be sent.
+func (g *memoryGuard) admit(drain func()) {
+	if g == nil || g.heap() < g.high {
+		return
+	}
+	g.throttled++
+	before := g.heap()
+	drain()
+	runtime.GC()
+	after := g.heap()
+	log.Debug().Uint64("heap_bytes", before).Uint64("heap_after_bytes", after).Int64("max_memory", g.limit).Msg("Heap near -max-memory; paused reading until queued batches were sent")
+	if after >= g.high && !g.warned {
+		// What is left is live data such as the batch being built or a
+		// dedup index, which waiting cannot free.
+		g.warned = true
+		log.Warn().Uint64("heap_bytes", after).Int64("max_memory", g.limit).Msg("Heap stays near -max-memory with nothing in flight; lower -batch or raise -max-memory")
+	}
+}
@@ -0,0 +1,72 @@
+package loader
+
+import (
+	"context"
+	"testing"
+)
+
+// TestMemoryGuardAdmit verifies behavior for the related scenario.
+func TestMemoryGuardAdmit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		heap          []uint64
+		wantDrains    int
+		wantThrottled int
+		wantWarned    bool
+	}{
+		{name: "below high water", heap: []uint64{899}},
+		{name: "freed by draining", heap: []uint64{950, 950, 400}, wantDrains: 1, wantThrottled: 1},
+		{name: "live data stays", heap: []uint64{990, 990, 980}, wantDrains: 1, wantThrottled: 1, wantWarned: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			guard := newMemoryGuard(1000)
+			samples := tt.heap
+			guard.heap = func() uint64 {
+				next := samples[0]
+				if len(samples) > 1 {
+					samples = samples[1:]
+				}
+				return next
+			}
+			drains := 0
+			guard.admit(func() { drains++ })
+			if drains != tt.wantDrains || guard.throttled != tt.wantThrottled || guard.warned != tt.wantWarned {
+				t.Fatalf("drains %d throttled %d warned %v, want %d %d %v", drains, guard.throttled, guard.warned, tt.wantDrains, tt.wantThrottled, tt.wantWarned)
+			}
+		})
+	}
+
+	if newMemoryGuard(0) != nil {
+		t.Fatal("expected no guard without a limit")
+	}
+	var disabled *memoryGuard
+	disabled.admit(func() { t.Fatal("a nil guard must not drain") })
+	disabled.setGCLimit()()
+}
+
+// TestRunMaxMemoryStillLoadsEverything verifies behavior for the related scenario.
+func TestRunMaxMemoryStillLoadsEverything(t *testing.T) {
+	server, bodies := newWatchTestServer(t)
+	var progress []int
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   writeTestDataFile(t, "cards.ndjson", "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n"),
+		AddToIndex: true,
+		BatchSize:  1,
+		Prefetch:   2,
+		// Far below any real heap, so every batch waits for the queue to drain.
+		MaxMemory:  1,
+		OnProgress: func(offset int) { progress = append(progress, offset) },
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsSucceeded != 3 || len(bodies()) != 3 || len(progress) != 3 || progress[2] != 3 {
+		t.Fatalf("unexpected load under -max-memory: result %+v, %d bodies, progress %v", result, len(bodies()), progress)
+	}
+}
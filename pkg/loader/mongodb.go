@@ -0,0 +1,549 @@
+package loader
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// ─── MongoDB Source ────────────────────────────────────────────────────────────
+
+const (
+	// mongoTimeout bounds server selection and each command, on top of how
+	// long a change stream read waits on the server.
+	mongoTimeout = 10 * time.Second
+	// mongoChangeAwait is how long one change stream getMore waits on the
+	// server for new events, so a read may end that much after its wait.
+	mongoChangeAwait = time.Second
+	// Server error codes the source reacts to.
+	mongoErrHistoryLost   = 286
+	mongoErrNotReplicaSet = 40573
+)
+
+// bsonDateLayout formats BSON dates and timestamps for JSON documents.
+const bsonDateLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// mongoCursor is the part of a find cursor or change stream the source
+// reads.
+type mongoCursor interface {
+	Next(ctx context.Context) bool
+	TryNext(ctx context.Context) bool
+	RemainingBatchLength() int
+	Decode(val any) error
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// mongoChangeStream is a change stream cursor.
+type mongoChangeStream interface {
+	mongoCursor
+	SetBatchSize(size int32)
+	ResumeToken() bson.Raw
+}
+
+// mongoCollection is the collection the source copies.
+type mongoCollection interface {
+	// operationTime returns the server's operation time, or nil on a
+	// standalone server.
+	operationTime(ctx context.Context) (*bson.Timestamp, error)
+	// find returns the documents matching filter in _id order.
+	find(ctx context.Context, filter bson.D, batch int32) (mongoCursor, error)
+	// watch opens the collection's change stream.
+	watch(ctx context.Context, pipeline mongo.Pipeline, opts *options.ChangeStreamOptionsBuilder) (mongoChangeStream, error)
+	disconnect(ctx context.Context) error
+}
+
+// mongoSource copies a collection with the MongoDB Go driver: it scans the
+// documents matching filter in _id order, then follows the collection's
+// change stream from the time the scan started. Nothing is settled on the
+// server, so nacked documents are kept and handed out again with the next
+// batch.
+type mongoSource struct {
+	// uri is the -source URL without the options only the loader reads.
+	uri        string
+	db         string
+	collection string
+	filter     bson.D
+	// open connects to the collection.
+	open func() (mongoCollection, error)
+
+	coll mongoCollection
+	// scan is set until the scan has returned every matching document.
+	scan   bool
+	cursor mongoCursor
+	// lastID is the _id of the last scanned document, after which a broken
+	// scan continues.
+	lastID  any
+	scanned int
+	// startAt is the operation time taken before the scan, where the change
+	// stream starts; it stays nil on a standalone server.
+	startAt *bson.Timestamp
+	started bool
+	stream  mongoChangeStream
+	// token resumes the change stream after the last event received;
+	// startAfter marks it as an invalidate event's.
+	token      bson.Raw
+	startAfter bool
+	// unwatchable is set once the server turned down a change stream.
+	unwatchable bool
+	retry       []sourceMessage
+}
+
+// newMongoSource parses mongodb[+srv]://[user:password@]<host>[:27017][,<host>...]/<database>
+// for collection, keeping the documents query matches. The driver reads
+// the connection string options, such as tls, authSource, and
+// authMechanism; the loader adds scan and ca.
+func newMongoSource(u *url.URL, collection, query string) (*mongoSource, error) {
+	display := redactSourceURL(u.String())
+	params := u.Query()
+	s := &mongoSource{
+		db:         strings.Trim(u.Path, "/"),
+		collection: collection,
+		scan:       true,
+	}
+	s.open = s.dial
+	if u.Host == "" || s.db == "" || strings.Contains(s.db, "/") {
+		return nil, fmt.Errorf("-source %s: expected %s://<host>/<database>", display, u.Scheme)
+	}
+	if strings.TrimSpace(collection) == "" {
+		return nil, fmt.Errorf("-source %s: -collection is required", display)
+	}
+	if strings.EqualFold(u.Scheme, "mongodb+srv") && strings.Contains(u.Host, ",") {
+		return nil, fmt.Errorf("-source %s: mongodb+srv takes one host", display)
+	}
+	if value := params.Get("scan"); value != "" {
+		scan, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("-source %s: scan %q is not a boolean", display, value)
+		}
+		s.scan = scan
+	}
+	params.Del("scan")
+	if ca := params.Get("ca"); ca != "" {
+		params.Del("ca")
+		params.Set("tlsCAFile", ca)
+	}
+	driverURL := *u
+	driverURL.RawQuery = params.Encode()
+	s.uri = driverURL.String()
+	// Parsing a mongodb+srv URL looks up its DNS records, which waits for
+	// the first connect.
+	if !strings.EqualFold(u.Scheme, "mongodb+srv") {
+		if err := options.Client().ApplyURI(s.uri).Validate(); err != nil {
+			return nil, fmt.Errorf("-source %s: %w", display, err)
+		}
+	}
+	filter, err := parseMongoQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	s.filter = filter
+	return s, nil
+}
+
+// receive returns up to max documents: kept ones first, then the next scan
+// batch, or else the changes that arrive within wait.
+func (s *mongoSource) receive(ctx context.Context, max int, wait time.Duration) ([]sourceMessage, error) {
+	if len(s.retry) > 0 {
+		msgs := s.retry[:min(max, len(s.retry))]
+		s.retry = slices.Clone(s.retry[len(msgs):])
+		return msgs, nil
+	}
+	if !s.scan && s.unwatchable {
+		_ = sleepWithContext(ctx, wait)
+		return nil, nil
+	}
+	if s.coll == nil {
+		coll, err := s.open()
+		if err != nil {
+			return nil, err
+		}
+		s.coll = coll
+	}
+	var msgs []sourceMessage
+	var err error
+	if s.scan {
+		msgs, err = s.receiveScan(ctx, max)
+	} else {
+		msgs, err = s.receiveChanges(ctx, max, wait)
+	}
+	if ctx.Err() != nil {
+		return msgs, nil
+	}
+	return msgs, err
+}
+
+// receiveScan returns the next batch of the scan, starting it first.
+func (s *mongoSource) receiveScan(ctx context.Context, limit int) ([]sourceMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, mongoTimeout)
+	defer cancel()
+	if !s.started {
+		// The change stream picks up from before the first document is read.
+		at, err := s.coll.operationTime(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("MongoDB ping: %w", err)
+		}
+		s.startAt, s.started = at, true
+	}
+	if s.cursor == nil {
+		filter := s.filter
+		if s.lastID != nil {
+			filter = bson.D{{Key: "$and", Value: bson.A{s.filter, bson.D{{Key: "_id", Value: bson.D{{Key: "$gt", Value: s.lastID}}}}}}}
+		}
+		cursor, err := s.coll.find(ctx, filter, int32(limit))
+		if err != nil {
+			return nil, fmt.Errorf("MongoDB find %s.%s: %w", s.db, s.collection, err)
+		}
+		s.cursor = cursor
+	}
+	var msgs []sourceMessage
+	defer func() { s.scanned += len(msgs) }()
+	// Only the first document may wait for a batch from the server.
+	for len(msgs) < limit && (len(msgs) == 0 || s.cursor.RemainingBatchLength() > 0) {
+		if !s.cursor.Next(ctx) {
+			err := s.cursor.Err()
+			_ = s.cursor.Close(ctx)
+			s.cursor = nil
+			if err != nil {
+				return msgs, fmt.Errorf("MongoDB find %s.%s: %w", s.db, s.collection, err)
+			}
+			s.scan = false
+			log.Info().Str("collection", s.collection).Int("documents", s.scanned+len(msgs)).Msg("Scanned collection; following its change stream")
+			break
+		}
+		var doc bson.D
+		if err := s.cursor.Decode(&doc); err != nil {
+			return msgs, fmt.Errorf("MongoDB find %s.%s: %w", s.db, s.collection, err)
+		}
+		msgs = append(msgs, mongoMessage(doc))
+		for _, elem := range doc {
+			if elem.Key == "_id" {
+				s.lastID = elem.Value
+			}
+		}
+	}
+	return msgs, nil
+}
+
+// receiveChanges returns the documents inserted, replaced, or updated
+// within wait, opening the change stream first.
+func (s *mongoSource) receiveChanges(ctx context.Context, limit int, wait time.Duration) ([]sourceMessage, error) {
+	if s.stream == nil {
+		opts := options.ChangeStream().SetFullDocument(options.UpdateLookup).SetBatchSize(int32(limit)).SetMaxAwaitTime(mongoChangeAwait)
+		switch {
+		case s.token != nil && s.startAfter:
+			opts.SetStartAfter(s.token)
+		case s.token != nil:
+			opts.SetResumeAfter(s.token)
+		case s.startAt != nil:
+			opts.SetStartAtOperationTime(s.startAt)
+		}
+		watchCtx, cancel := context.WithTimeout(ctx, mongoTimeout)
+		stream, err := s.coll.watch(watchCtx, mongo.Pipeline{{{Key: "$match", Value: mongoChangeMatch(s.filter)}}}, opts)
+		cancel()
+		if hasMongoErrorCode(err, mongoErrNotReplicaSet) {
+			s.unwatchable = true
+			log.Warn().Err(err).Str("collection", s.collection).Msg("Change streams need a replica set or sharded cluster; no further changes are loaded")
+			return nil, nil
+		}
+		if err != nil {
+			return nil, s.changeStreamError(err)
+		}
+		s.stream = stream
+	}
+	s.stream.SetBatchSize(int32(limit))
+	deadline := time.Now().Add(wait)
+	var msgs []sourceMessage
+	for len(msgs) < limit {
+		readCtx, cancel := context.WithTimeout(ctx, mongoChangeAwait+mongoTimeout)
+		ok := s.stream.TryNext(readCtx)
+		cancel()
+		if !ok {
+			if err := s.stream.Err(); err != nil {
+				_ = s.stream.Close(context.Background())
+				s.stream = nil
+				return msgs, s.changeStreamError(err)
+			}
+			if len(msgs) > 0 || !time.Now().Before(deadline) || ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		var event struct {
+			OperationType string `bson:"operationType"`
+			FullDocument  bson.D `bson:"fullDocument"`
+		}
+		if err := s.stream.Decode(&event); err != nil {
+			return msgs, fmt.Errorf("MongoDB change stream %s.%s: %w", s.db, s.collection, err)
+		}
+		if event.OperationType == "invalidate" {
+			log.Warn().Str("collection", s.collection).Msg("Change stream invalidated by a dropped or renamed collection; starting after it")
+			s.token, s.startAfter = s.stream.ResumeToken(), true
+			_ = s.stream.Close(context.Background())
+			s.stream = nil
+			return msgs, nil
+		}
+		if event.FullDocument != nil {
+			msgs = append(msgs, mongoMessage(event.FullDocument))
+		}
+		if len(msgs) > 0 && s.stream.RemainingBatchLength() == 0 {
+			break
+		}
+	}
+	s.token, s.startAfter = s.stream.ResumeToken(), false
+	return msgs, nil
+}
+
+// changeStreamError describes a change stream that could not be opened or
+// read. When the oplog no longer reaches back to the resume point, the
+// collection is scanned again instead.
+func (s *mongoSource) changeStreamError(err error) error {
+	if hasMongoErrorCode(err, mongoErrHistoryLost) {
+		log.Warn().Err(err).Str("collection", s.collection).Msg("Change stream can no longer resume; scanning the collection again")
+		s.scan, s.started, s.lastID, s.scanned, s.token, s.startAfter = true, false, nil, 0, nil, false
+		return nil
+	}
+	return fmt.Errorf("MongoDB change stream %s.%s: %w", s.db, s.collection, err)
+}
+
+// ack has nothing to settle: the source keeps its own position.
+func (s *mongoSource) ack(context.Context, []sourceMessage) error {
+	return nil
+}
+
+// nack keeps msgs to hand out again first.
+func (s *mongoSource) nack(_ context.Context, msgs []sourceMessage) error {
+	s.retry = append(s.retry, msgs...)
+	return nil
+}
+
+// close ends the open cursor and disconnects.
+func (s *mongoSource) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	if s.cursor != nil {
+		_ = s.cursor.Close(ctx)
+		s.cursor = nil
+	}
+	if s.stream != nil {
+		_ = s.stream.Close(ctx)
+		s.stream = nil
+	}
+	if s.coll == nil {
+		return nil
+	}
+	err := s.coll.disconnect(ctx)
+	s.coll = nil
+	return err
+}
+
+// dial creates a driver client for the collection. The driver connects,
+// and reconnects, in the background.
+func (s *mongoSource) dial() (mongoCollection, error) {
+	client, err := mongo.Connect(options.Client().ApplyURI(s.uri).SetAppName("es-bulk-loader").SetServerSelectionTimeout(mongoTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to MongoDB %s: %w", redactSourceURL(s.uri), err)
+	}
+	coll := client.Database(s.db).Collection(s.collection, options.Collection().SetReadPreference(readpref.PrimaryPreferred()))
+	return &mongoDriverCollection{client: client, coll: coll}, nil
+}
+
+// mongoDriverCollection is a collection reached through the driver.
+type mongoDriverCollection struct {
+	client *mongo.Client
+	coll   *mongo.Collection
+}
+
+func (c *mongoDriverCollection) operationTime(ctx context.Context) (*bson.Timestamp, error) {
+	var reply struct {
+		OperationTime *bson.Timestamp `bson:"operationTime"`
+	}
+	if err := c.client.Database("admin").RunCommand(ctx, bson.D{{Key: "ping", Value: 1}}).Decode(&reply); err != nil {
+		return nil, err
+	}
+	return reply.OperationTime, nil
+}
+
+func (c *mongoDriverCollection) find(ctx context.Context, filter bson.D, batch int32) (mongoCursor, error) {
+	cursor, err := c.coll.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetBatchSize(batch))
+	if err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+func (c *mongoDriverCollection) watch(ctx context.Context, pipeline mongo.Pipeline, opts *options.ChangeStreamOptionsBuilder) (mongoChangeStream, error) {
+	stream, err := c.coll.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (c *mongoDriverCollection) disconnect(ctx context.Context) error {
+	return c.client.Disconnect(ctx)
+}
+
+// hasMongoErrorCode reports whether err is a server error with code.
+func hasMongoErrorCode(err error, code int) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(code)
+}
+
+// mongoMessage returns doc as a message whose JSON body carries the _id
+// as a string, for the Elasticsearch _id.
+func mongoMessage(doc bson.D) sourceMessage {
+	fields := make(map[string]interface{}, len(doc))
+	id := ""
+	for _, elem := range doc {
+		if elem.Key == "_id" {
+			id = mongoIDString(elem.Value)
+			fields[elem.Key] = id
+			continue
+		}
+		fields[elem.Key] = bsonJSONValue(elem.Value)
+	}
+	body, _ := json.Marshal(fields)
+	return sourceMessage{body: body, handle: id}
+}
+
+// mongoIDString returns an _id as text: the hex of an ObjectID, a string
+// as is, and other values as JSON.
+func mongoIDString(value interface{}) string {
+	switch id := value.(type) {
+	case bson.ObjectID:
+		return id.Hex()
+	case string:
+		return id
+	}
+	text, _ := json.Marshal(bsonJSONValue(value))
+	return string(text)
+}
+
+// parseMongoQuery returns the -query filter, in MongoDB Extended JSON: plain
+// JSON, plus {"$oid": "<hex>"} for an ObjectID and {"$date": "<RFC 3339>"}
+// for a date. Operators that cannot also filter change events are rejected.
+func parseMongoQuery(query string) (bson.D, error) {
+	if strings.TrimSpace(query) == "" {
+		return bson.D{}, nil
+	}
+	var value interface{}
+	if err := decodeJSON([]byte(query), &value); err != nil {
+		return nil, fmt.Errorf("-query is not valid JSON: %w", err)
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return nil, errors.New("-query must be a JSON object")
+	}
+	var filter bson.D
+	if err := bson.UnmarshalExtJSON([]byte(query), false, &filter); err != nil {
+		return nil, fmt.Errorf("-query: %w", err)
+	}
+	for _, elem := range filter {
+		switch elem.Key {
+		case "$where", "$text", "$expr":
+			return nil, fmt.Errorf("-query %s is not supported, as the filter also applies to change events", elem.Key)
+		}
+	}
+	return filter, nil
+}
+
+// mongoChangeMatch returns the $match stage of the change stream: inserts,
+// replacements, and updates whose full document matches filter, and
+// invalidate events, which end the stream.
+func mongoChangeMatch(filter bson.D) bson.D {
+	changes := bson.D{{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "replace", "update"}}}}}
+	if len(filter) > 0 {
+		changes = bson.D{{Key: "$and", Value: bson.A{changes, mongoChangeFilter(filter)}}}
+	}
+	return bson.D{{Key: "$or", Value: bson.A{bson.D{{Key: "operationType", Value: "invalidate"}}, changes}}}
+}
+
+// mongoChangeFilter prefixes the field paths of filter with fullDocument.
+func mongoChangeFilter(filter bson.D) bson.D {
+	out := make(bson.D, len(filter))
+	for i, elem := range filter {
+		switch {
+		case elem.Key == "$and" || elem.Key == "$or" || elem.Key == "$nor":
+			items, _ := elem.Value.(bson.A)
+			prefixed := make(bson.A, len(items))
+			for j, item := range items {
+				prefixed[j] = item
+				if doc, ok := item.(bson.D); ok {
+					prefixed[j] = mongoChangeFilter(doc)
+				}
+			}
+			out[i] = bson.E{Key: elem.Key, Value: prefixed}
+		case strings.HasPrefix(elem.Key, "$"):
+			out[i] = elem
+		default:
+			out[i] = bson.E{Key: "fullDocument." + elem.Key, Value: elem.Value}
+		}
+	}
+	return out
+}
+
+// bsonJSONValue converts a BSON value for a JSON document: ObjectIDs become
+// hex strings, dates and timestamps RFC 3339 strings in UTC, decimals
+// numbers, UUIDs their text form, other binary data base64, and values
+// JSON cannot hold (NaN, infinities, MinKey, MaxKey, undefined) null.
+func bsonJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.D:
+		doc := make(map[string]interface{}, len(v))
+		for _, elem := range v {
+			doc[elem.Key] = bsonJSONValue(elem.Value)
+		}
+		return doc
+	case bson.A:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = bsonJSONValue(item)
+		}
+		return items
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+		return v
+	case int32:
+		return int64(v)
+	case bson.ObjectID:
+		return v.Hex()
+	case bson.DateTime:
+		return v.Time().UTC().Format(bsonDateLayout)
+	case bson.Timestamp:
+		return time.Unix(int64(v.T), 0).UTC().Format(bsonDateLayout)
+	case bson.Decimal128:
+		text := v.String()
+		if text == "NaN" || strings.HasSuffix(text, "Infinity") {
+			return nil
+		}
+		return json.Number(text)
+	case bson.Binary:
+		if (v.Subtype == 3 || v.Subtype == 4) && len(v.Data) == 16 {
+			text := hex.EncodeToString(v.Data)
+			return text[:8] + "-" + text[8:12] + "-" + text[12:16] + "-" + text[16:20] + "-" + text[20:]
+		}
+		return base64.StdEncoding.EncodeToString(v.Data)
+	case bson.Regex:
+		return "/" + v.Pattern + "/" + v.Options
+	case bson.MinKey, bson.MaxKey, bson.Undefined:
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,192 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// ─── Multiple Data Files ───────────────────────────────────────────────────────
+
+// parallelChunkSize is how many documents a file reader hands over at a
+// time, so the channel is not touched once per document.
+const parallelChunkSize = 256
+
+// dataFiles returns every data file of the load in the order given.
+func (o Options) dataFiles() []string {
+	if o.DataFile == "" {
+		return o.DataFiles
+	}
+	return append([]string{o.DataFile}, o.DataFiles...)
+}
+
+// validateDataFiles checks that several data files can share one load:
+// their documents interleave, so nothing may depend on document order, and
+// they must read the same way once opened.
+func validateDataFiles(opts Options, input inputConfig, keepLast bool) error {
+	files := opts.dataFiles()
+	if len(files) < 2 {
+		return nil
+	}
+	switch {
+	case opts.Tail:
+		return fmt.Errorf("-tail follows one file and cannot be combined with several -data files")
+	case opts.SkipDocuments > 0:
+		return fmt.Errorf("resuming with skipped documents needs a single -data file")
+	case opts.Journal != "":
+		return fmt.Errorf("-journal needs a single -data file")
+	case keepLast:
+		return fmt.Errorf("-dedup-policy %s needs a single -data file", dedupKeepLast)
+	case opts.ReadConcurrency < 0:
+		return fmt.Errorf("-read-concurrency must be 0 or greater")
+	}
+	for _, path := range files[1:] {
+		if input.formatFor(path) != input.formatFor(files[0]) {
+			return fmt.Errorf("-data files must share one format: %s reads as %s, %s as %s", files[0], input.formatFor(files[0]), path, input.formatFor(path))
+		}
+	}
+	return nil
+}
+
+// openDataFiles returns a reader over every file in paths: one file is read
+// directly, several are read by parallelReader.
+func (c inputConfig) openDataFiles(paths []string, concurrency int) (documentReader, error) {
+	if len(paths) == 1 {
+		return c.open(paths[0])
+	}
+	return c.openParallel(paths, concurrency), nil
+}
+
+// parallelReader reads several data files at once, up to concurrency at a
+// time, so parse-bound formats keep a fast cluster busy. Documents of one
+// file stay in order, but files interleave.
+type parallelReader struct {
+	chunks  chan parallelChunk
+	done    chan struct{}
+	current []map[string]interface{}
+	err     error
+	once    sync.Once
+}
+
+// parallelChunk is a run of documents read from one file, or the error that
+// stopped it.
+type parallelChunk struct {
+	docs []map[string]interface{}
+	err  error
+}
+
+// openParallel starts reading paths; concurrency 0 reads as many files at
+// once as there are CPUs.
+func (c inputConfig) openParallel(paths []string, concurrency int) *parallelReader {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	concurrency = min(concurrency, len(paths))
+	r := &parallelReader{
+		chunks: make(chan parallelChunk, concurrency),
+		done:   make(chan struct{}),
+	}
+	queue := make(chan string, len(paths))
+	for _, path := range paths {
+		queue <- path
+	}
+	close(queue)
+
+	var readers sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for path := range queue {
+				select {
+				case <-r.done:
+					return
+				default:
+				}
+				if err := r.readFile(c, path); err != nil {
+					r.send(parallelChunk{err: fmt.Errorf("%s: %w", path, err)})
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		readers.Wait()
+		close(r.chunks)
+	}()
+	return r
+}
+
+// readFile sends every document of path in chunks. It stops early, without
+// an error, once the reader is closed.
+func (r *parallelReader) readFile(c inputConfig, path string) error {
+	reader, err := c.open(path)
+	if err != nil {
+		return err
+	}
+	defer reader.close()
+	docs := make([]map[string]interface{}, 0, parallelChunkSize)
+	for {
+		doc, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+		if len(docs) == parallelChunkSize {
+			if !r.send(parallelChunk{docs: docs}) {
+				return nil
+			}
+			docs = make([]map[string]interface{}, 0, parallelChunkSize)
+		}
+	}
+	if len(docs) > 0 {
+		r.send(parallelChunk{docs: docs})
+	}
+	return nil
+}
+
+// send hands chunk to next, reporting false once the reader is closed.
+func (r *parallelReader) send(chunk parallelChunk) bool {
+	select {
+	case r.chunks <- chunk:
+		return true
+	case <-r.done:
+		return false
+	}
+}
+
+// next implements documentReader; the first failed file ends the load.
+func (r *parallelReader) next() (map[string]interface{}, error) {
+	for len(r.current) == 0 {
+		if r.err != nil {
+			return nil, r.err
+		}
+		chunk, ok := <-r.chunks
+		if !ok {
+			return nil, io.EOF
+		}
+		if chunk.err != nil {
+			r.err = chunk.err
+			return nil, r.err
+		}
+		r.current = chunk.docs
+	}
+	doc := r.current[0]
+	r.current = r.current[1:]
+	return doc, nil
+}
+
+// close stops the file readers and waits for them to close their files.
+func (r *parallelReader) close() error {
+	r.once.Do(func() {
+		close(r.done)
+		for range r.chunks {
+		}
+	})
+	return nil
+}
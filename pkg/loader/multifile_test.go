@@ -0,0 +1,130 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// writeNumberedTestFile writes an NDJSON file of count documents tagged with
+// file and a running n.
+func writeNumberedTestFile(t *testing.T, file string, count int) string {
+	t.Helper()
+	var data strings.Builder
+	for n := 1; n <= count; n++ {
+		fmt.Fprintf(&data, "{\"file\":%q,\"n\":%d}\n", file, n)
+	}
+	return writeTestDataFile(t, file+".ndjson", data.String())
+}
+
+// TestParallelReaderReadsEveryFile verifies behavior for the related scenario.
+func TestParallelReaderReadsEveryFile(t *testing.T) {
+	t.Parallel()
+
+	counts := map[string]int{"a": parallelChunkSize*2 + 7, "b": 5, "c": 1}
+	paths := []string{writeNumberedTestFile(t, "a", counts["a"]), writeNumberedTestFile(t, "b", counts["b"]), writeNumberedTestFile(t, "c", counts["c"])}
+	reader, err := inputConfig{}.openDataFiles(paths, 2)
+	if err != nil {
+		t.Fatalf("openDataFiles returned error: %v", err)
+	}
+	defer reader.close()
+
+	last := map[string]int64{}
+	for {
+		doc, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("next returned error: %v", err)
+		}
+		file := doc["file"].(string)
+		n, _ := integerValue(doc["n"])
+		if n != last[file]+1 {
+			t.Fatalf("file %s: document %d after %d", file, n, last[file])
+		}
+		last[file] = n
+	}
+	for file, count := range counts {
+		if last[file] != int64(count) {
+			t.Fatalf("file %s: read %d documents, want %d", file, last[file], count)
+		}
+	}
+}
+
+// TestParallelReaderStopsOnFailure verifies behavior for the related scenario.
+func TestParallelReaderStopsOnFailure(t *testing.T) {
+	t.Parallel()
+
+	good := writeNumberedTestFile(t, "good", parallelChunkSize*4)
+	bad := writeTestDataFile(t, "bad.ndjson", "{\"n\":1}\nnot json\n")
+	reader, err := inputConfig{}.openDataFiles([]string{good, bad}, 2)
+	if err != nil {
+		t.Fatalf("openDataFiles returned error: %v", err)
+	}
+	for err == nil {
+		_, err = reader.next()
+	}
+	if errors.Is(err, io.EOF) || !strings.Contains(err.Error(), bad) {
+		t.Fatalf("expected an error naming %s, got %v", bad, err)
+	}
+	if _, again := reader.next(); again != err {
+		t.Fatalf("expected the failure to repeat, got %v", again)
+	}
+	// Closing with a reader still blocked on a full channel must not hang.
+	reader.close()
+	reader.close()
+}
+
+// TestValidateDataFiles verifies behavior for the related scenario.
+func TestValidateDataFiles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opts     Options
+		keepLast bool
+		want     string
+	}{
+		{name: "single file", opts: Options{DataFile: "a.ndjson", Tail: true}},
+		{name: "several files", opts: Options{DataFile: "a.ndjson", DataFiles: []string{"b.jsonl"}}},
+		{name: "tail", opts: Options{DataFile: "a.ndjson", DataFiles: []string{"b.ndjson"}, Tail: true}, want: "-tail"},
+		{name: "resume", opts: Options{DataFile: "a.ndjson", DataFiles: []string{"b.ndjson"}, SkipDocuments: 3}, want: "single -data file"},
+		{name: "keep last", opts: Options{DataFile: "a.ndjson", DataFiles: []string{"b.ndjson"}}, keepLast: true, want: "keep-last"},
+		{name: "mixed formats", opts: Options{DataFile: "a.ndjson", DataFiles: []string{"b.csv"}}, want: "share one format"},
+	}
+	for _, tt := range tests {
+		err := validateDataFiles(tt.opts, inputConfig{}, tt.keepLast)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+// TestRunLoadsSeveralDataFiles verifies behavior for the related scenario.
+func TestRunLoadsSeveralDataFiles(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   writeNumberedTestFile(t, "a", 40),
+		DataFiles:  []string{writeNumberedTestFile(t, "b", 25), writeNumberedTestFile(t, "c", 10)},
+		AddToIndex: true,
+		BatchSize:  20,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	lines := 0
+	for _, body := range bodies() {
+		lines += strings.Count(body, "\n")
+	}
+	if result.DocumentsProcessed != 75 || lines != 150 {
+		t.Fatalf("expected 75 documents sent, got %+v with %d bulk lines", result, lines)
+	}
+}
@@ -0,0 +1,246 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── NATS JetStream Source ─────────────────────────────────────────────────────
+
+const (
+	natsDefaultPort = "4222"
+	// natsRequestTimeout bounds connecting, each JetStream API request, and
+	// each round trip that confirms acknowledgements reached the server.
+	natsRequestTimeout = 10 * time.Second
+)
+
+// natsSource pulls messages from a durable JetStream consumer with the NATS
+// client library. It connects on first use; the client reconnects by itself
+// after the connection breaks.
+type natsSource struct {
+	url     string
+	options []nats.Option
+	domain  string
+	stream  string
+	durable string
+	// filter and ackWait configure the consumer when it has to be created.
+	filter  string
+	ackWait time.Duration
+
+	conn     *nats.Conn
+	consumer jetstream.Consumer
+}
+
+// newNATSSource parses nats://[user:password@|token@]<host>[:port]/<stream>/<consumer>.
+// The query may set subject and ack_wait for a consumer that does not exist
+// yet, domain, creds (a .creds file), and tls=true with ca.
+func newNATSSource(u *url.URL) (*natsSource, error) {
+	display := redactSourceURL(u.String())
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Hostname() == "" || len(parts) != 2 || !validJetStreamName(parts[0]) || !validJetStreamName(parts[1]) {
+		return nil, fmt.Errorf("-source %s: expected nats://<host>/<stream>/<consumer>", display)
+	}
+	query := u.Query()
+	port := u.Port()
+	if port == "" {
+		port = natsDefaultPort
+	}
+	s := &natsSource{
+		url:     "nats://" + u.Hostname() + ":" + port,
+		stream:  parts[0],
+		durable: parts[1],
+		filter:  query.Get("subject"),
+		options: []nats.Option{
+			nats.Name("es-bulk-loader"),
+			nats.Timeout(natsRequestTimeout),
+			nats.MaxReconnects(-1),
+		},
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			s.options = append(s.options, nats.UserInfo(u.User.Username(), password))
+		} else {
+			s.options = append(s.options, nats.Token(u.User.Username()))
+		}
+	}
+	if creds := query.Get("creds"); creds != "" {
+		s.options = append(s.options, nats.UserCredentials(creds))
+	}
+	if domain := query.Get("domain"); domain != "" {
+		if !validJetStreamName(domain) {
+			return nil, fmt.Errorf("-source %s: domain %q is not a valid JetStream domain", display, domain)
+		}
+		s.domain = domain
+	}
+	if value := query.Get("ack_wait"); value != "" {
+		wait, err := time.ParseDuration(value)
+		if err != nil || wait < time.Second {
+			return nil, fmt.Errorf("-source %s: ack_wait %q must be a duration of at least 1s", display, value)
+		}
+		s.ackWait = wait
+	}
+	config, err := sourceTLSConfig(u.Hostname(), query)
+	if err != nil {
+		return nil, err
+	}
+	// The client upgrades to TLS with config when the server requires it;
+	// tls=true insists on it.
+	s.options = append(s.options, func(o *nats.Options) error {
+		o.TLSConfig = config
+		return nil
+	})
+	if value := query.Get("tls"); value != "" {
+		force, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("-source %s: tls %q is not a boolean", display, value)
+		}
+		if force {
+			s.options = append(s.options, nats.Secure(config))
+		}
+	}
+	return s, nil
+}
+
+// validJetStreamName reports whether name can be used as a stream, consumer,
+// or domain name inside a subject.
+func validJetStreamName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, ".*> \t\r\n")
+}
+
+// receive pulls up to max messages, waiting up to wait for them.
+func (s *natsSource) receive(ctx context.Context, max int, wait time.Duration) ([]sourceMessage, error) {
+	consumer, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := consumer.Fetch(max, jetstream.FetchMaxWait(wait))
+	if err != nil {
+		return nil, s.fetchError(err)
+	}
+	var msgs []sourceMessage
+	for msg := range batch.Messages() {
+		msgs = append(msgs, sourceMessage{body: msg.Data(), handle: msg.Reply(), delivery: msg})
+	}
+	if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+		return msgs, s.fetchError(err)
+	}
+	return msgs, nil
+}
+
+// fetchError describes a failed pull. A consumer deleted meanwhile, which
+// leaves no responder for the pull request, is looked up, and created
+// again, on the next receive.
+func (s *natsSource) fetchError(err error) error {
+	if errors.Is(err, jetstream.ErrConsumerDeleted) || errors.Is(err, jetstream.ErrConsumerNotFound) || errors.Is(err, nats.ErrNoResponders) {
+		s.consumer = nil
+	}
+	return fmt.Errorf("JetStream consumer %s > %s: %w", s.stream, s.durable, err)
+}
+
+// ack acknowledges msgs.
+func (s *natsSource) ack(ctx context.Context, msgs []sourceMessage) error {
+	return s.settle(ctx, msgs, jetstream.Msg.Ack)
+}
+
+// nack asks the server to redeliver msgs at once.
+func (s *natsSource) nack(ctx context.Context, msgs []sourceMessage) error {
+	return s.settle(ctx, msgs, jetstream.Msg.Nak)
+}
+
+// settle applies verdict to each message and waits until the server has
+// read them. Ack subjects are not tied to a connection, so messages pulled
+// before a reconnect can still be settled.
+func (s *natsSource) settle(ctx context.Context, msgs []sourceMessage, verdict func(jetstream.Msg) error) error {
+	for _, msg := range msgs {
+		if err := verdict(msg.delivery.(jetstream.Msg)); err != nil {
+			return fmt.Errorf("settling JetStream message: %w", err)
+		}
+	}
+	if s.conn == nil {
+		return nil
+	}
+	flushCtx, cancel := context.WithTimeout(ctx, natsRequestTimeout)
+	defer cancel()
+	if err := s.conn.FlushWithContext(flushCtx); err != nil {
+		return fmt.Errorf("settling JetStream messages: %w", err)
+	}
+	return nil
+}
+
+// close disconnects from the server.
+func (s *natsSource) close() error {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return nil
+}
+
+// connect returns the consumer, connecting first when there is no
+// connection yet. The consumer is looked up, and created when missing, on
+// first use and after it was deleted.
+func (s *natsSource) connect(ctx context.Context) (jetstream.Consumer, error) {
+	if s.consumer != nil {
+		return s.consumer, nil
+	}
+	if s.conn == nil {
+		conn, err := nats.Connect(s.url, s.options...)
+		if err != nil {
+			return nil, fmt.Errorf("connecting to NATS %s: %w", s.url, err)
+		}
+		s.conn = conn
+	}
+	js, err := jetstream.New(s.conn)
+	if s.domain != "" {
+		js, err = jetstream.NewWithDomain(s.conn, s.domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := s.ensureConsumer(ctx, js)
+	if err != nil {
+		return nil, err
+	}
+	s.consumer = consumer
+	return consumer, nil
+}
+
+// ensureConsumer checks that the durable consumer exists and is a pull
+// consumer with explicit acks, creating it when it does not exist.
+func (s *natsSource) ensureConsumer(ctx context.Context, js jetstream.JetStream) (jetstream.Consumer, error) {
+	name := s.stream + " > " + s.durable
+	requestCtx, cancel := context.WithTimeout(ctx, natsRequestTimeout)
+	defer cancel()
+	consumer, err := js.Consumer(requestCtx, s.stream, s.durable)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		config := jetstream.ConsumerConfig{
+			Durable:       s.durable,
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			DeliverPolicy: jetstream.DeliverAllPolicy,
+			FilterSubject: s.filter,
+			AckWait:       s.ackWait,
+		}
+		if consumer, err = js.CreateConsumer(requestCtx, s.stream, config); err == nil {
+			log.Info().Str("consumer", name).Msg("Created JetStream consumer")
+		}
+	}
+	if errors.Is(err, jetstream.ErrNotPullConsumer) {
+		return nil, fmt.Errorf("JetStream consumer %s is a push consumer; -source needs a pull consumer", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("JetStream consumer %s: %w", name, err)
+	}
+	if policy := consumer.CachedInfo().Config.AckPolicy; policy != jetstream.AckExplicitPolicy {
+		return nil, fmt.Errorf("JetStream consumer %s uses ack_policy %q; -source needs explicit acks", name, policy.String())
+	}
+	return consumer, nil
+}
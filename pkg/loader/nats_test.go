@@ -0,0 +1,234 @@
+package loader
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// startJetStream runs an embedded NATS server with JetStream and a stream
+// EVENTS on events.>, and returns the server and a JetStream client.
+func startJetStream(t *testing.T, options *natsserver.Options) (*natsserver.Server, jetstream.JetStream) {
+	t.Helper()
+
+	options.Host, options.Port = "127.0.0.1", -1
+	options.JetStream, options.StoreDir = true, t.TempDir()
+	options.NoLog, options.NoSigs = true, true
+	srv, err := natsserver.NewServer(options)
+	if err != nil {
+		t.Fatalf("NewServer returned error: %v", err)
+	}
+	srv.Start()
+	t.Cleanup(srv.Shutdown)
+	if !srv.ReadyForConnections(10 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+	var auth []nats.Option
+	if len(options.Users) > 0 {
+		auth = append(auth, nats.UserInfo(options.Users[0].Username, options.Users[0].Password))
+	}
+	if options.Authorization != "" {
+		auth = append(auth, nats.Token(options.Authorization))
+	}
+	conn, err := nats.Connect(srv.ClientURL(), auth...)
+	if err != nil {
+		t.Fatalf("Connect returned error: %v", err)
+	}
+	t.Cleanup(conn.Close)
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatalf("jetstream.New returned error: %v", err)
+	}
+	if _, err := js.CreateStream(context.Background(), jetstream.StreamConfig{Name: "EVENTS", Subjects: []string{"events.>"}}); err != nil {
+		t.Fatalf("CreateStream returned error: %v", err)
+	}
+	return srv, js
+}
+
+// jetStreamSourceURL returns a source URL for consumer loader of stream
+// EVENTS on srv.
+func jetStreamSourceURL(t *testing.T, srv *natsserver.Server, userinfo, query string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse("nats://" + userinfo + srv.Addr().String() + "/EVENTS/loader" + query)
+	if err != nil {
+		t.Fatalf("url.Parse returned error: %v", err)
+	}
+	return u
+}
+
+// TestNATSSourceSettlesMessages verifies behavior for the related scenario.
+func TestNATSSourceSettlesMessages(t *testing.T) {
+	t.Parallel()
+
+	srv, js := startJetStream(t, &natsserver.Options{Users: []*natsserver.User{{Username: "loader", Password: "s3cret"}}})
+	ctx := context.Background()
+	for n := 1; n <= 3; n++ {
+		if _, err := js.Publish(ctx, "events.created", []byte(`{"n":`+strconv.Itoa(n)+`}`)); err != nil {
+			t.Fatalf("Publish returned error: %v", err)
+		}
+	}
+	source, err := newNATSSource(jetStreamSourceURL(t, srv, "loader:s3cret@", "?subject=events.>&ack_wait=90s"))
+	if err != nil {
+		t.Fatalf("newNATSSource returned error: %v", err)
+	}
+	defer source.close()
+
+	first, err := source.receive(ctx, 2, time.Second)
+	if err != nil || len(first) != 2 || string(first[0].body) != `{"n":1}` {
+		t.Fatalf("expected the first two messages, got %+v and %v", first, err)
+	}
+	second, err := source.receive(ctx, 2, time.Second)
+	if err != nil || len(second) != 1 || string(second[0].body) != `{"n":3}` {
+		t.Fatalf("expected the third message, got %+v and %v", second, err)
+	}
+	if err := source.ack(ctx, first); err != nil {
+		t.Fatalf("ack returned error: %v", err)
+	}
+	if err := source.nack(ctx, second); err != nil {
+		t.Fatalf("nack returned error: %v", err)
+	}
+	again, err := source.receive(ctx, 2, time.Second)
+	if err != nil || len(again) != 1 || string(again[0].body) != `{"n":3}` {
+		t.Fatalf("expected the nacked message again, got %+v and %v", again, err)
+	}
+	if err := source.ack(ctx, again); err != nil {
+		t.Fatalf("ack returned error: %v", err)
+	}
+	empty, err := source.receive(ctx, 2, 50*time.Millisecond)
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("expected an expired pull to return nothing, got %+v and %v", empty, err)
+	}
+
+	consumer, err := js.Consumer(ctx, "EVENTS", "loader")
+	if err != nil {
+		t.Fatalf("Consumer returned error: %v", err)
+	}
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		t.Fatalf("Info returned error: %v", err)
+	}
+	if config := info.Config; config.FilterSubject != "events.>" || config.AckPolicy != jetstream.AckExplicitPolicy || config.AckWait != 90*time.Second {
+		t.Fatalf("unexpected consumer config %+v", config)
+	}
+	if info.NumAckPending != 0 || info.NumPending != 0 {
+		t.Fatalf("expected every message settled, got %d pending acks and %d pending messages", info.NumAckPending, info.NumPending)
+	}
+}
+
+// TestNATSSourceRecreatesDeletedConsumer verifies behavior for the related scenario.
+func TestNATSSourceRecreatesDeletedConsumer(t *testing.T) {
+	t.Parallel()
+
+	srv, js := startJetStream(t, &natsserver.Options{})
+	ctx := context.Background()
+	source, err := newNATSSource(jetStreamSourceURL(t, srv, "", ""))
+	if err != nil {
+		t.Fatalf("newNATSSource returned error: %v", err)
+	}
+	defer source.close()
+	if _, err := source.receive(ctx, 1, 50*time.Millisecond); err != nil {
+		t.Fatalf("receive returned error: %v", err)
+	}
+	if err := js.DeleteConsumer(ctx, "EVENTS", "loader"); err != nil {
+		t.Fatalf("DeleteConsumer returned error: %v", err)
+	}
+	if _, err := js.Publish(ctx, "events.created", []byte(`{"n":1}`)); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+	msgs, err := source.receive(ctx, 1, time.Second)
+	if err != nil {
+		msgs, err = source.receive(ctx, 1, time.Second)
+	}
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("expected the message from a recreated consumer, got %+v and %v", msgs, err)
+	}
+}
+
+// TestNATSSourceChecksConsumer verifies behavior for the related scenario.
+func TestNATSSourceChecksConsumer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  jetstream.ConsumerConfig
+		wantErr string
+	}{
+		{name: "push consumer", config: jetstream.ConsumerConfig{AckPolicy: jetstream.AckExplicitPolicy, DeliverSubject: "deliver.events"}, wantErr: "is a push consumer"},
+		{name: "no acks", config: jetstream.ConsumerConfig{AckPolicy: jetstream.AckNonePolicy}, wantErr: `uses ack_policy "AckNone"`},
+	}
+	for _, tt := range tests {
+		srv, js := startJetStream(t, &natsserver.Options{})
+		tt.config.Durable = "loader"
+		if _, err := js.CreateConsumer(context.Background(), "EVENTS", tt.config); err != nil {
+			t.Fatalf("%s: CreateConsumer returned error: %v", tt.name, err)
+		}
+		source, err := newNATSSource(jetStreamSourceURL(t, srv, "", ""))
+		if err != nil {
+			t.Fatalf("%s: newNATSSource returned error: %v", tt.name, err)
+		}
+		_, err = source.receive(context.Background(), 1, time.Second)
+		source.close()
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// TestNATSSourceAuthentication verifies behavior for the related scenario.
+func TestNATSSourceAuthentication(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		userinfo string
+		wantErr  string
+	}{
+		{name: "token", userinfo: "t0ken@"},
+		{name: "wrong token", userinfo: "other@", wantErr: "Authorization Violation"},
+	}
+	for _, tt := range tests {
+		srv, _ := startJetStream(t, &natsserver.Options{Authorization: "t0ken"})
+		source, err := newNATSSource(jetStreamSourceURL(t, srv, tt.userinfo, ""))
+		if err != nil {
+			t.Fatalf("%s: newNATSSource returned error: %v", tt.name, err)
+		}
+		_, err = source.receive(context.Background(), 1, 50*time.Millisecond)
+		source.close()
+		if tt.wantErr == "" && err != nil {
+			t.Fatalf("%s: receive returned error: %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(strings.ToLower(err.Error()), strings.ToLower(tt.wantErr))) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// TestNewNATSSourceValidatesURL verifies behavior for the related scenario.
+func TestNewNATSSourceValidatesURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		raw     string
+		wantErr string
+	}{
+		{raw: "nats://localhost/EVENTS", wantErr: "expected nats://<host>/<stream>/<consumer>"},
+		{raw: "nats://localhost/EVENTS/a.b", wantErr: "expected nats://<host>/<stream>/<consumer>"},
+		{raw: "nats://localhost/EVENTS/loader?ack_wait=fast", wantErr: "ack_wait"},
+		{raw: "nats://localhost/EVENTS/loader?domain=hub.eu", wantErr: "domain"},
+		{raw: "nats://localhost/EVENTS/loader?tls=maybe", wantErr: "tls"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.raw)
+		if _, err := newNATSSource(u); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.raw, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Completion Notifications ──────────────────────────────────────────────────
+
+// notifyTimeout bounds the webhook request so a slow receiver cannot hold the
+// process open after the load has finished.
+const notifyTimeout = 10 * time.Second
+
+// runSummary is the JSON document posted to -notify-url when a run ends.
+type runSummary struct {
+	Status             string  `json:"status"`
+	Index              string  `json:"index"`
+	WriteIndex         string  `json:"write_index,omitempty"`
+	CreatedIndex       string  `json:"created_index,omitempty"`
+	DocumentsProcessed int     `json:"documents_processed"`
+	DocumentsSucceeded int     `json:"documents_succeeded"`
+	DocumentsFailed    int     `json:"documents_failed"`
+	DocumentsSkipped   int     `json:"documents_skipped"`
+	BytesSent          int64   `json:"bytes_sent"`
+	DocumentsPerSecond float64 `json:"docs_per_sec"`
+	EnrichSucceeded    int     `json:"enrich_succeeded"`
+	EnrichFailed       int     `json:"enrich_failed"`
+	// Indices breaks the totals down when the load wrote to several indices.
+	Indices         []indexSummary `json:"indices,omitempty"`
+	Warnings        []string       `json:"warnings,omitempty"`
+	Error           string         `json:"error,omitempty"`
+	ErrorKind       string         `json:"error_kind,omitempty"`
+	StartedAt       time.Time      `json:"started_at"`
+	FinishedAt      time.Time      `json:"finished_at"`
+	DurationSeconds float64        `json:"duration_seconds"`
+}
+
+// indexSummary is one entry of runSummary.Indices.
+type indexSummary struct {
+	Index              string `json:"index"`
+	DocumentsSucceeded int    `json:"documents_succeeded"`
+	DocumentsFailed    int    `json:"documents_failed"`
+	BytesSent          int64  `json:"bytes_sent"`
+}
+
+// buildRunSummary folds a finished run into the notification document.
+func buildRunSummary(index string, result Result, runErr error, started, finished time.Time) runSummary {
+	summary := runSummary{
+		Status:             "succeeded",
+		Index:              index,
+		WriteIndex:         result.WriteIndex,
+		CreatedIndex:       result.CreatedIndex,
+		DocumentsProcessed: result.DocumentsProcessed,
+		DocumentsSucceeded: result.DocumentsSucceeded,
+		DocumentsFailed:    result.DocumentsFailed,
+		DocumentsSkipped:   result.DocumentsSkipped,
+		BytesSent:          result.BytesSent,
+		DocumentsPerSecond: result.DocumentsPerSecond,
+		EnrichSucceeded:    result.EnrichSucceeded,
+		EnrichFailed:       result.EnrichFailed,
+		Warnings:           result.Warnings,
+		StartedAt:          started.UTC(),
+		FinishedAt:         finished.UTC(),
+		DurationSeconds:    finished.Sub(started).Seconds(),
+	}
+	if len(result.Indices) > 1 {
+		for _, index := range result.Indices {
+			summary.Indices = append(summary.Indices, indexSummary{
+				Index:              index.Index,
+				DocumentsSucceeded: index.DocumentsSucceeded,
+				DocumentsFailed:    index.DocumentsFailed,
+				BytesSent:          index.BytesSent,
+			})
+		}
+	}
+	if runErr != nil {
+		summary.Status = "failed"
+		summary.Error = runErr.Error()
+		var typed *RunError
+		if errors.As(runErr, &typed) && typed.Kind != nil {
+			summary.ErrorKind = typed.Kind.Error()
+		}
+	}
+	return summary
+}
+
+// notificationVariables exposes summary fields to -notify-template as
+// $NAME/${NAME} references. String values are JSON-escaped (without quotes) so
+// they can be embedded inside JSON string literals in the template.
+func notificationVariables(summary runSummary, encoded []byte) templateVariables {
+	escape := func(value string) string {
+		quoted, _ := json.Marshal(value)
+		return string(quoted[1 : len(quoted)-1])
+	}
+	return templateVariables{
+		"SUMMARY":             string(encoded),
+		"STATUS":              summary.Status,
+		"INDEX":               escape(summary.Index),
+		"WRITE_INDEX":         escape(summary.WriteIndex),
+		"DOCUMENTS_PROCESSED": strconv.Itoa(summary.DocumentsProcessed),
+		"DOCUMENTS_SUCCEEDED": strconv.Itoa(summary.DocumentsSucceeded),
+		"DOCUMENTS_FAILED":    strconv.Itoa(summary.DocumentsFailed),
+		"DOCUMENTS_SKIPPED":   strconv.Itoa(summary.DocumentsSkipped),
+		"BYTES_SENT":          strconv.FormatInt(summary.BytesSent, 10),
+		"DOCS_PER_SEC":        strconv.FormatFloat(summary.DocumentsPerSecond, 'f', 1, 64),
+		"DURATION":            (time.Duration(summary.DurationSeconds * float64(time.Second))).Round(time.Millisecond).String(),
+		"ERROR":               escape(summary.Error),
+		"ERROR_KIND":          escape(summary.ErrorKind),
+		"WARNING_COUNT":       strconv.Itoa(len(summary.Warnings)),
+	}
+}
+
+// renderNotificationPayload returns the raw summary JSON, or the expanded
+// template when templatePath is set.
+func renderNotificationPayload(summary runSummary, templatePath string) ([]byte, error) {
+	encoded, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(templatePath) == "" {
+		return encoded, nil
+	}
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(expandTemplateVariables(string(content), notificationVariables(summary, encoded))), nil
+}
+
+// sendNotification POSTs payload to url and treats any non-2xx reply as an error.
+func sendNotification(ctx context.Context, url string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// notifyRunFinished delivers the completion webhook. Delivery failures are
+// logged rather than returned so a flaky receiver never changes the run outcome.
+func notifyRunFinished(ctx context.Context, url, templatePath string, summary runSummary) {
+	payload, err := renderNotificationPayload(summary, templatePath)
+	if err != nil {
+		log.Warn().Err(err).Str("template", templatePath).Msg("Could not render completion notification")
+		return
+	}
+	// The run context may already be cancelled; notifications still go out.
+	if err := sendNotification(context.WithoutCancel(ctx), url, payload); err != nil {
+		log.Warn().Err(err).Msg("Completion notification failed")
+		return
+	}
+	log.Info().Str("status", summary.Status).Msg("Completion notification sent")
+}
@@ -0,0 +1,85 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRunNotifiesWebhookOnFailure verifies behavior for the related scenario.
+func TestRunNotifiesWebhookOnFailure(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := Run(context.Background(), Options{NotifyURL: server.URL})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected invalid options error, got %v", err)
+	}
+
+	var summary runSummary
+	select {
+	case body := <-received:
+		if err := json.Unmarshal(body, &summary); err != nil {
+			t.Fatalf("webhook body is not a JSON summary: %v: %s", err, body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+	if summary.Status != "failed" || summary.ErrorKind != ErrInvalidOptions.Error() || summary.Error == "" {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+}
+
+// TestRenderNotificationPayloadExpandsTemplate verifies behavior for the related scenario.
+func TestRenderNotificationPayloadExpandsTemplate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	templatePath := writeTempJSON(t, dir, `{"text":"load of $INDEX $STATUS: ${DOCUMENTS_SUCCEEDED} ok, $ERROR","summary":$SUMMARY}`)
+	started := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	summary := buildRunSummary("cards", Result{DocumentsSucceeded: 42}, &RunError{Kind: ErrBulkFailure, Op: "bulk", Err: errors.New(`bad "quote"`)}, started, started.Add(time.Second))
+
+	payload, err := renderNotificationPayload(summary, templatePath)
+	if err != nil {
+		t.Fatalf("renderNotificationPayload returned error: %v", err)
+	}
+	var rendered struct {
+		Text    string     `json:"text"`
+		Summary runSummary `json:"summary"`
+	}
+	if err := json.Unmarshal(payload, &rendered); err != nil {
+		t.Fatalf("rendered payload is not valid JSON: %v: %s", err, payload)
+	}
+	if rendered.Text != `load of cards failed: 42 ok, bulk: bad "quote"` {
+		t.Fatalf("unexpected text: %q", rendered.Text)
+	}
+	if rendered.Summary.DurationSeconds != 1 || rendered.Summary.ErrorKind != ErrBulkFailure.Error() {
+		t.Fatalf("unexpected embedded summary: %+v", rendered.Summary)
+	}
+}
+
+// TestSendNotificationRejectsErrorStatus verifies behavior for the related scenario.
+func TestSendNotificationRejectsErrorStatus(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	t.Cleanup(server.Close)
+
+	if err := sendNotification(context.Background(), server.URL, []byte(`{}`)); err == nil {
+		t.Fatal("expected error for non-2xx webhook response")
+	}
+}
@@ -0,0 +1,93 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// ─── JSON Numbers ──────────────────────────────────────────────────────────────
+
+// decodeJSON decodes data like json.Unmarshal but keeps numbers as
+// json.Number, so 64-bit IDs and long decimals reach Elasticsearch with the
+// digits they were read with instead of being rounded through float64.
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid character after top-level value")
+	}
+	return nil
+}
+
+// numberValue returns value as a float64 for arithmetic and ordering. It
+// accepts json.Number from decoded documents as well as the float64 and
+// integer values produced by transforms and scripts.
+func numberValue(value interface{}) (float64, bool) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, true
+	case json.Number:
+		number, err := typed.Float64()
+		return number, err == nil
+	case int64:
+		return float64(typed), true
+	case int:
+		return float64(typed), true
+	}
+	return 0, false
+}
+
+// integerValue returns value as an int64 when it is a whole number that
+// fits, so integers above 2^53 compare exactly.
+func integerValue(value interface{}) (int64, bool) {
+	switch typed := value.(type) {
+	case json.Number:
+		number, err := typed.Int64()
+		return number, err == nil
+	case int64:
+		return typed, true
+	case int:
+		return int64(typed), true
+	case float64:
+		if typed == math.Trunc(typed) && math.Abs(typed) < 1<<63 {
+			return int64(typed), true
+		}
+	}
+	return 0, false
+}
+
+// isNumber reports whether value is one of the numeric types documents hold.
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case float64, json.Number, int64, int:
+		return true
+	}
+	return false
+}
+
+// canonicalNumber returns a json.Number as the int64 or float64 it holds, so
+// keys and hashes do not depend on how a number was written: 1.50 and 1.5
+// give the same dedup key, and hashes match those of earlier releases.
+func canonicalNumber(value interface{}) interface{} {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if integer, err := strconv.ParseInt(string(number), 10, 64); err == nil {
+		return integer
+	}
+	if float, err := number.Float64(); err == nil {
+		return float
+	}
+	return value
+}
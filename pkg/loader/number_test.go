@@ -0,0 +1,111 @@
+package loader
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestDecodeJSONKeepsNumbers verifies behavior for the related scenario.
+func TestDecodeJSONKeepsNumbers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: `{"id":12345678901234567891,"price":1.50,"tags":[1e3,-0]}`, want: `{"id":12345678901234567891,"price":1.50,"tags":[1e3,-0]}`},
+		{in: " {\"a\":1} \n", want: `{"a":1}`},
+		{in: `{"a":1} {"b":2}`, wantErr: "after top-level value"},
+		{in: `{"a":`, wantErr: "unexpected EOF"},
+		{in: ``, wantErr: "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		var doc map[string]interface{}
+		err := decodeJSON([]byte(tt.in), &doc)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("decodeJSON(%q): expected error mentioning %q, got %v", tt.in, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("decodeJSON(%q) returned error: %v", tt.in, err)
+		}
+		if got := encodeTestDocument(t, doc); got != tt.want {
+			t.Fatalf("decodeJSON(%q) re-encoded as %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestDocTransformsPreserveNumbers verifies behavior for the related scenario.
+func TestDocTransformsPreserveNumbers(t *testing.T) {
+	t.Parallel()
+
+	chain, err := buildDocTransforms(Options{
+		Rename:   []string{"id=user.id"},
+		Defaults: []string{"seq=9007199254740993"},
+		Convert:  []string{"size=b:kb"},
+		Filter:   `user.id == 9007199254740993`,
+	})
+	if err != nil {
+		t.Fatalf("buildDocTransforms returned error: %v", err)
+	}
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{
+			in:   `{"id":9007199254740993,"price":19.990,"size":2048}`,
+			want: []string{`{"price":19.990,"seq":9007199254740993,"size":2.048,"user":{"id":9007199254740993}}`},
+		},
+		{in: `{"id":9007199254740992}`},
+	}
+	for _, tt := range tests {
+		docs, err := chain.apply(decodeTestDocument(t, tt.in))
+		if err != nil {
+			t.Fatalf("apply(%s) returned error: %v", tt.in, err)
+		}
+		got := make([]string, 0, len(docs))
+		for _, doc := range docs {
+			got = append(got, encodeTestDocument(t, doc))
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Fatalf("apply(%s) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestCanonicalNumber verifies behavior for the related scenario.
+func TestCanonicalNumber(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{in: json.Number("9007199254740993"), want: int64(9007199254740993)},
+		{in: json.Number("1.50"), want: 1.5},
+		{in: json.Number("1.0"), want: 1.0},
+		{in: json.Number("12345678901234567891"), want: 12345678901234567891.0},
+		{in: 2.5, want: 2.5},
+		{in: "7", want: "7"},
+	}
+	for _, tt := range tests {
+		if got := canonicalNumber(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("canonicalNumber(%#v) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+
+	deduper, err := newDeduper("price", "")
+	if err != nil {
+		t.Fatalf("newDeduper returned error: %v", err)
+	}
+	first, _ := deduper.key(decodeTestDocument(t, `{"price":1.50}`))
+	second, _ := deduper.key(decodeTestDocument(t, `{"price":1.5}`))
+	if first != second {
+		t.Fatalf("dedup keys %s and %s differ for the same number", first, second)
+	}
+}
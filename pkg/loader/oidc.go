@@ -0,0 +1,330 @@
+package loader
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── OIDC Device-Code Sign-In ──────────────────────────────────────────────────
+
+const (
+	// deviceCodeGrantType is the RFC 8628 token request grant.
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	// defaultDevicePollInterval is used when the IdP does not name one.
+	defaultDevicePollInterval = 5 * time.Second
+	// tokenRefreshMargin refreshes a token this long before it expires, so
+	// a bulk request never leaves with one about to lapse.
+	tokenRefreshMargin = time.Minute
+)
+
+// errBearerToken marks failures to obtain a bearer token, so they are
+// reported as authentication rather than connection errors.
+var errBearerToken = errors.New("bearer token unavailable")
+
+// TokenFunc returns the bearer token to send with a request, refreshing it
+// as needed.
+type TokenFunc func(ctx context.Context) (string, error)
+
+// OIDCOptions configures the device-code sign-in against an OpenID Connect
+// identity provider. The token it yields is sent as the Elasticsearch
+// bearer credential, so the cluster needs a JWT realm that trusts Issuer.
+type OIDCOptions struct {
+	// Issuer is the IdP's issuer URL; its endpoints are discovered from
+	// /.well-known/openid-configuration.
+	Issuer   string
+	ClientID string
+	// Scopes default to openid and offline_access, the latter so the
+	// session can refresh its token during long loads.
+	Scopes []string
+	// Prompt receives the verification URL and user code; defaults to
+	// os.Stderr.
+	Prompt io.Writer
+	// HTTPClient talks to the IdP; defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// OIDCSession holds the tokens from a device-code sign-in and refreshes
+// them before they expire.
+type OIDCSession struct {
+	opts          OIDCOptions
+	tokenEndpoint string
+
+	mu           sync.Mutex
+	token        string
+	refreshToken string
+	expiry       time.Time
+}
+
+// oidcDiscovery is the part of the provider metadata the flow needs.
+type oidcDiscovery struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+}
+
+// deviceAuthorization is the device authorization response.
+type deviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// oidcTokenResponse is a token endpoint answer, successful or not.
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	RefreshToken     string `json:"refresh_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// NewOIDCDeviceSession signs in with the device-code flow: it asks the IdP
+// for a user code, prints where to enter it, and waits until the user has
+// approved the request or it expires. Failures are ErrAuthentication.
+func NewOIDCDeviceSession(ctx context.Context, opts OIDCOptions) (*OIDCSession, error) {
+	if opts.Issuer == "" || opts.ClientID == "" {
+		return nil, &RunError{Kind: ErrInvalidOptions, Op: "validating OIDC options", Err: fmt.Errorf("-oidc needs -oidc-issuer and -oidc-client-id")}
+	}
+	if len(opts.Scopes) == 0 {
+		opts.Scopes = []string{"openid", "offline_access"}
+	}
+	if opts.Prompt == nil {
+		opts.Prompt = os.Stderr
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	fail := func(err error) error {
+		return &RunError{Kind: ErrAuthentication, Op: "signing in with OIDC", Err: err}
+	}
+
+	var discovery oidcDiscovery
+	if err := getJSON(ctx, opts.HTTPClient, strings.TrimRight(opts.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fail(fmt.Errorf("discovering %s: %w", opts.Issuer, err))
+	}
+	if discovery.DeviceAuthorizationEndpoint == "" || discovery.TokenEndpoint == "" {
+		return nil, fail(fmt.Errorf("%s does not offer the device authorization grant", opts.Issuer))
+	}
+
+	var device deviceAuthorization
+	form := url.Values{"client_id": {opts.ClientID}, "scope": {strings.Join(opts.Scopes, " ")}}
+	if err := postForm(ctx, opts.HTTPClient, discovery.DeviceAuthorizationEndpoint, form, &device); err != nil {
+		return nil, fail(fmt.Errorf("requesting a device code: %w", err))
+	}
+	verification := device.VerificationURIComplete
+	if verification == "" {
+		verification = device.VerificationURI
+	}
+	fmt.Fprintf(opts.Prompt, "To sign in, open %s and enter the code %s\n", verification, device.UserCode)
+
+	session := &OIDCSession{opts: opts, tokenEndpoint: discovery.TokenEndpoint}
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultDevicePollInterval
+	}
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+	form = url.Values{"grant_type": {deviceCodeGrantType}, "device_code": {device.DeviceCode}, "client_id": {opts.ClientID}}
+	for {
+		if err := sleepWithContext(ctx, interval); err != nil {
+			return nil, fail(err)
+		}
+		token, err := session.requestToken(ctx, form)
+		if err != nil {
+			return nil, fail(err)
+		}
+		switch token.Error {
+		case "":
+			if err := session.store(token); err != nil {
+				return nil, fail(err)
+			}
+			log.Info().Str("issuer", opts.Issuer).Time("expires", session.expiry).Msg("Signed in with OIDC")
+			return session, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return nil, fail(fmt.Errorf("sign-in was not completed: %s", token.describe()))
+		}
+		if device.ExpiresIn > 0 && time.Now().After(deadline) {
+			return nil, fail(fmt.Errorf("the device code expired before sign-in was approved"))
+		}
+	}
+}
+
+// Token returns the current token, refreshing it first when it is about
+// to expire. It satisfies TokenFunc.
+func (s *OIDCSession) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.expiry.IsZero() || time.Until(s.expiry) > tokenRefreshMargin {
+		return s.token, nil
+	}
+	if s.refreshToken == "" {
+		if time.Now().Before(s.expiry) {
+			return s.token, nil
+		}
+		return "", fmt.Errorf("OIDC token expired and the IdP issued no refresh token; request the offline_access scope")
+	}
+	token, err := s.requestToken(ctx, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {s.refreshToken}, "client_id": {s.opts.ClientID}})
+	if err == nil && token.Error != "" {
+		err = errors.New(token.describe())
+	}
+	if err == nil {
+		err = s.store(token)
+	}
+	if err != nil {
+		// The token still works until it expires; a later request retries.
+		if time.Now().Before(s.expiry) {
+			log.Warn().Err(err).Msg("Refreshing OIDC token failed; using the current token until it expires")
+			return s.token, nil
+		}
+		return "", fmt.Errorf("refreshing OIDC token: %w", err)
+	}
+	log.Debug().Time("expires", s.expiry).Msg("Refreshed OIDC token")
+	return s.token, nil
+}
+
+// store keeps the ID token, which JWT realms validate by default, for the
+// whole session: a refresh that returns none fails rather than switching to
+// the access token mid-load. The expiry comes from the ID token's exp claim,
+// since expires_in describes the access token. A refresh that returns no
+// new refresh token keeps the old one.
+func (s *OIDCSession) store(token oidcTokenResponse) error {
+	if token.IDToken == "" {
+		return fmt.Errorf("the IdP returned no ID token; request the openid scope")
+	}
+	expiry, err := jwtExpiry(token.IDToken)
+	if err != nil {
+		return fmt.Errorf("reading the ID token: %w", err)
+	}
+	s.token = token.IDToken
+	s.expiry = expiry
+	if token.RefreshToken != "" {
+		s.refreshToken = token.RefreshToken
+	}
+	return nil
+}
+
+// jwtExpiry returns the exp claim of a JWT. The signature is not checked;
+// that is the JWT realm's job.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decoding the JWT payload: %w", err)
+	}
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("parsing the JWT claims: %w", err)
+	}
+	if claims.Exp <= 0 {
+		return time.Time{}, fmt.Errorf("the JWT has no exp claim")
+	}
+	return time.Unix(int64(claims.Exp), 0), nil
+}
+
+// requestToken posts form to the token endpoint. OAuth errors come back in
+// the response's Error field rather than as err.
+func (s *OIDCSession) requestToken(ctx context.Context, form url.Values) (oidcTokenResponse, error) {
+	var token oidcTokenResponse
+	err := postForm(ctx, s.opts.HTTPClient, s.tokenEndpoint, form, &token)
+	if err != nil && token.Error != "" {
+		err = nil
+	}
+	return token, err
+}
+
+// describe renders an OAuth error code with its description.
+func (t oidcTokenResponse) describe() string {
+	if t.ErrorDescription != "" {
+		return t.Error + ": " + t.ErrorDescription
+	}
+	return t.Error
+}
+
+// getJSON decodes the JSON answer to a GET of endpoint into out.
+func getJSON(ctx context.Context, client *http.Client, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return doJSON(client, req, out)
+}
+
+// postForm posts form to endpoint and decodes the JSON answer into out.
+// A non-2xx answer is decoded too, then reported as an error.
+func postForm(ctx context.Context, client *http.Client, endpoint string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	return doJSON(client, req, out)
+}
+
+// doJSON sends req and decodes its JSON answer into out.
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	decodeErr := json.Unmarshal(body, out)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s answered with status %d: %s", req.URL.Redacted(), res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("parsing answer from %s: %w", req.URL.Redacted(), decodeErr)
+	}
+	return nil
+}
+
+// bearerTransport sends the token from its TokenFunc with every request.
+type bearerTransport struct {
+	base  http.RoundTripper
+	token TokenFunc
+}
+
+// withBearerToken wraps base in bearer authentication when opts carry a
+// BearerToken.
+func withBearerToken(opts Options, base http.RoundTripper) http.RoundTripper {
+	if opts.BearerToken == nil {
+		return base
+	}
+	return &bearerTransport{base: base, token: opts.BearerToken}
+}
+
+// RoundTrip adds the Authorization header and forwards the request.
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.token(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errBearerToken, err)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
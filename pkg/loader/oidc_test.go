@@ -0,0 +1,204 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testIDToken returns an unsigned JWT with the given subject and expiry.
+func testIDToken(subject string, expiry time.Time) string {
+	claims, _ := json.Marshal(map[string]interface{}{"sub": subject, "exp": expiry.Unix()})
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+}
+
+// newOIDCTestProvider serves discovery, device authorization, and a token
+// endpoint that answers pending once and then with outcome. The outcome
+// "no_id_token" issues an expired ID token at sign-in and none on refresh.
+func newOIDCTestProvider(t *testing.T, outcome string) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var (
+		mu     sync.Mutex
+		grants []string
+		polls  int
+		issued int
+	)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"device_authorization_endpoint": server.URL + "/device",
+				"token_endpoint":                server.URL + "/token",
+			})
+		case "/device":
+			_, _ = w.Write([]byte(`{"device_code":"dev-1","user_code":"WXYZ-1234","verification_uri":"https://idp.example/activate","expires_in":600,"interval":1}`))
+		case "/token":
+			_ = r.ParseForm()
+			grants = append(grants, r.PostForm.Get("grant_type"))
+			if r.PostForm.Get("grant_type") == deviceCodeGrantType && polls == 0 {
+				polls++
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"authorization_pending"}`))
+				return
+			}
+			if outcome == "no_id_token" {
+				token := map[string]interface{}{"access_token": "access", "refresh_token": "refresh", "expires_in": 3600}
+				if r.PostForm.Get("grant_type") == deviceCodeGrantType {
+					token["id_token"] = testIDToken("id-1", time.Now().Add(-time.Second))
+				}
+				_ = json.NewEncoder(w).Encode(token)
+				return
+			}
+			if outcome != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"` + outcome + `","error_description":"user declined"}`))
+				return
+			}
+			issued++
+			// The ID token expires inside the refresh margin, so every Token
+			// call refreshes; expires_in describes the access token only.
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "access",
+				"id_token":      testIDToken("id-"+string(rune('0'+issued)), time.Now().Add(30*time.Second)),
+				"refresh_token": "refresh",
+				"expires_in":    3600,
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), grants...)
+	}
+}
+
+// TestOIDCDeviceSession verifies behavior for the related scenario.
+func TestOIDCDeviceSession(t *testing.T) {
+	var slept []time.Duration
+	previousSleep := sleepWithContext
+	sleepWithContext = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	defer func() {
+		sleepWithContext = previousSleep
+	}()
+
+	provider, grants := newOIDCTestProvider(t, "")
+	var prompt bytes.Buffer
+	session, err := NewOIDCDeviceSession(context.Background(), OIDCOptions{Issuer: provider.URL, ClientID: "loader", Prompt: &prompt})
+	if err != nil {
+		t.Fatalf("NewOIDCDeviceSession returned error: %v", err)
+	}
+	if !strings.Contains(prompt.String(), "https://idp.example/activate") || !strings.Contains(prompt.String(), "WXYZ-1234") {
+		t.Fatalf("expected the verification URL and code, got %q", prompt.String())
+	}
+	if len(slept) != 2 || slept[0] != time.Second {
+		t.Fatalf("expected two one-second polls, got %v", slept)
+	}
+	token, err := session.Token(context.Background())
+	if err != nil || token != testIDToken("id-2", session.expiry) {
+		t.Fatalf("expected the refreshed ID token, got %q, %v", token, err)
+	}
+	if got := grants(); len(got) != 3 || got[2] != "refresh_token" {
+		t.Fatalf("expected two device polls and a refresh, got %v", got)
+	}
+
+	noID, _ := newOIDCTestProvider(t, "no_id_token")
+	session, err = NewOIDCDeviceSession(context.Background(), OIDCOptions{Issuer: noID.URL, ClientID: "loader", Prompt: &prompt})
+	if err != nil {
+		t.Fatalf("NewOIDCDeviceSession returned error: %v", err)
+	}
+	if _, err := session.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "no ID token") {
+		t.Fatalf("expected a refresh without an ID token to fail, got %v", err)
+	}
+
+	denied, _ := newOIDCTestProvider(t, "access_denied")
+	_, err = NewOIDCDeviceSession(context.Background(), OIDCOptions{Issuer: denied.URL, ClientID: "loader", Prompt: &prompt})
+	if !errors.Is(err, ErrAuthentication) || !strings.Contains(err.Error(), "user declined") {
+		t.Fatalf("expected ErrAuthentication for a declined sign-in, got %v", err)
+	}
+	_, err = NewOIDCDeviceSession(context.Background(), OIDCOptions{Issuer: denied.URL})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions without a client ID, got %v", err)
+	}
+}
+
+// TestRunSendsBearerToken verifies behavior for the related scenario.
+func TestRunSendsBearerToken(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu   sync.Mutex
+		auth []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auth = append(auth, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"_index":"cards","_id":"1","status":201}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	calls := 0
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   writeTestDataFile(t, "cards.ndjson", "{\"n\":1}\n"),
+		AddToIndex: true,
+		BearerToken: func(context.Context) (string, error) {
+			calls++
+			return "tok", nil
+		},
+	})
+	if err != nil || result.DocumentsSucceeded != 1 {
+		t.Fatalf("got %+v, %v", result, err)
+	}
+	for _, header := range auth {
+		if header != "Bearer tok" {
+			t.Fatalf("expected every request to carry the bearer token, got %v", auth)
+		}
+	}
+	if calls != len(auth) {
+		t.Fatalf("expected a token lookup per request, got %d for %d requests", calls, len(auth))
+	}
+
+	_, err = Run(context.Background(), Options{
+		URL:        server.URL,
+		Preflight:  true,
+		Index:      "cards",
+		DataFile:   "cards.ndjson",
+		AddToIndex: true,
+		BearerToken: func(context.Context) (string, error) {
+			return "", errors.New("no refresh token")
+		},
+	})
+	if !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("expected ErrAuthentication when no token is available, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package loader
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Partitioned Indices ───────────────────────────────────────────────────────
+
+// defaultPartitionMax caps the partition indices one run may write when
+// Options.PartitionMax is 0.
+const defaultPartitionMax = 100
+
+// maxIndexNameBytes is the longest index name Elasticsearch accepts.
+const maxIndexNameBytes = 255
+
+// validatePartitioning rejects -partition-by combinations whose documents
+// would not all land in the base index the option assumes.
+func validatePartitioning(opts Options, action dataAction) error {
+	switch {
+	case opts.PartitionBy == "":
+		if opts.PartitionMax != 0 {
+			return fmt.Errorf("-partition-max needs -partition-by")
+		}
+		return nil
+	case opts.PartitionMax < 0:
+		return fmt.Errorf("-partition-max must be 0 or greater")
+	case !action.requiresDataFile():
+		return fmt.Errorf("-partition-by routes loaded documents and needs -add, -flush, or -delete")
+	case opts.AliasMode:
+		return fmt.Errorf("-partition-by cannot be combined with -alias")
+	case opts.PreserveIndex:
+		return fmt.Errorf("-partition-by cannot be combined with -preserve-index")
+	case opts.Sync:
+		return fmt.Errorf("-partition-by cannot be combined with -sync, which reads hashes from -index")
+	case opts.ConcurrencyControl == concurrencyFetch:
+		return fmt.Errorf("-partition-by cannot be combined with -concurrency-control %s, which looks documents up in -index", concurrencyFetch)
+	case opts.ShardGroup > 0:
+		return fmt.Errorf("-partition-by cannot be combined with -shard-group, which groups by the shards of -index")
+	}
+	return nil
+}
+
+// partitionIndexName returns the index doc is written to under
+// -partition-by field: index, a hyphen, and the field's sanitized string or
+// number value. ok is false when the document has no usable value and
+// stays in index.
+func partitionIndexName(index, field string, doc map[string]interface{}) (string, bool) {
+	value, _ := lookupField(doc, field)
+	suffix := sanitizeIndexNamePart(routingValue(value))
+	if suffix == "" {
+		return index, false
+	}
+	name := index + "-" + suffix
+	for len(name) > maxIndexNameBytes {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name, true
+}
+
+// sanitizeIndexNamePart lowercases value and replaces each run of
+// characters other than letters, digits, '_', and '.' with one hyphen,
+// trimming hyphens and dots from both ends.
+func sanitizeIndexNamePart(value string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(value) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' {
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		if !hyphen {
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+	return strings.Trim(b.String(), "-.")
+}
+
+// partitioner creates partition indices as documents first reach them.
+type partitioner struct {
+	es      *elasticsearch.Client
+	index   string
+	field   string
+	limit   int
+	body    string
+	docType string
+	// indices lists the partition indices written, in first-use order.
+	indices []string
+	seen    map[string]struct{}
+}
+
+// newPartitioner returns the partitioner for Options.PartitionBy, or nil
+// when documents are not partitioned. body is the create index body of
+// index, reused for every partition.
+func newPartitioner(es *elasticsearch.Client, opts Options, body string) *partitioner {
+	if opts.PartitionBy == "" {
+		return nil
+	}
+	limit := opts.PartitionMax
+	if limit == 0 {
+		limit = defaultPartitionMax
+	}
+	return &partitioner{
+		es:      es,
+		index:   opts.Index,
+		field:   opts.PartitionBy,
+		limit:   limit,
+		body:    body,
+		docType: opts.DocType,
+		seen:    make(map[string]struct{}),
+	}
+}
+
+// transform makes sure each document's partition index exists before the
+// document is batched, and fails once more than limit partitions are seen.
+// It must run after every transform that can change the field.
+func (p *partitioner) transform() docTransform {
+	return func(doc map[string]interface{}) ([]map[string]interface{}, error) {
+		name, ok := partitionIndexName(p.index, p.field, doc)
+		if !ok {
+			return []map[string]interface{}{doc}, nil
+		}
+		if _, known := p.seen[name]; known {
+			return []map[string]interface{}{doc}, nil
+		}
+		if err := validateIndexName(name); err != nil {
+			return nil, fmt.Errorf("-partition-by %s: %w", p.field, err)
+		}
+		if len(p.seen) >= p.limit {
+			return nil, fmt.Errorf("-partition-by %s has more than %d distinct values (next: %s); raise -partition-max if this is expected", p.field, p.limit, name)
+		}
+		if err := p.ensure(name); err != nil {
+			return nil, fmt.Errorf("creating partition index %s: %w", name, err)
+		}
+		p.seen[name] = struct{}{}
+		p.indices = append(p.indices, name)
+		return []map[string]interface{}{doc}, nil
+	}
+}
+
+// ensure creates the partition index name with the base index body unless
+// it already exists.
+func (p *partitioner) ensure(name string) error {
+	exists, err := indexExists(p.es, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		log.Info().Str("index", name).Str("partition_by", p.field).Msg("Appending documents to existing partition index")
+		return nil
+	}
+	res, err := createIndexRequest(p.es, name, p.body, p.docType)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	waitForIndex(p.es, name)
+	log.Info().Str("index", name).Str("partition_by", p.field).Msg("Partition index created")
+	return nil
+}
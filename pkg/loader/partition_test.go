@@ -0,0 +1,187 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestPartitionIndexName verifies behavior for the related scenario.
+func TestPartitionIndexName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		doc    map[string]interface{}
+		field  string
+		want   string
+		wantOK bool
+	}{
+		{name: "string", doc: map[string]interface{}{"country": "US"}, field: "country", want: "sales-us", wantOK: true},
+		{name: "unsafe characters", doc: map[string]interface{}{"country": "Côte d'Ivoire"}, field: "country", want: "sales-côte-d-ivoire", wantOK: true},
+		{name: "trims edges", doc: map[string]interface{}{"country": "  *UK?/ "}, field: "country", want: "sales-uk", wantOK: true},
+		{name: "number", doc: map[string]interface{}{"year": json.Number("2024")}, field: "year", want: "sales-2024", wantOK: true},
+		{name: "nested", doc: map[string]interface{}{"geo": map[string]interface{}{"country": "DE"}}, field: "geo.country", want: "sales-de", wantOK: true},
+		{name: "missing", doc: map[string]interface{}{"other": "x"}, field: "country", want: "sales"},
+		{name: "nothing left", doc: map[string]interface{}{"country": "***"}, field: "country", want: "sales"},
+		{name: "object", doc: map[string]interface{}{"country": map[string]interface{}{"code": "US"}}, field: "country", want: "sales"},
+		{name: "too long", doc: map[string]interface{}{"country": strings.Repeat("é", 200)}, field: "country", want: "sales-" + strings.Repeat("é", 124), wantOK: true},
+	}
+	for _, tt := range tests {
+		got, ok := partitionIndexName("sales", tt.field, tt.doc)
+		if got != tt.want || ok != tt.wantOK {
+			t.Fatalf("%s: expected %q, %t, got %q, %t", tt.name, tt.want, tt.wantOK, got, ok)
+		}
+	}
+}
+
+// TestValidatePartitioning verifies behavior for the related scenario.
+func TestValidatePartitioning(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    Options
+		action  dataAction
+		wantErr string
+	}{
+		{name: "off", action: dataActionNone},
+		{name: "add", opts: Options{PartitionBy: "country"}, action: dataActionAdd},
+		{name: "max without field", opts: Options{PartitionMax: 5}, action: dataActionAdd, wantErr: "needs -partition-by"},
+		{name: "negative max", opts: Options{PartitionBy: "country", PartitionMax: -1}, action: dataActionAdd, wantErr: "0 or greater"},
+		{name: "no load", opts: Options{PartitionBy: "country"}, action: dataActionNone, wantErr: "needs -add"},
+		{name: "alias", opts: Options{PartitionBy: "country", AliasMode: true}, action: dataActionAdd, wantErr: "-alias"},
+		{name: "sync", opts: Options{PartitionBy: "country", Sync: true}, action: dataActionAdd, wantErr: "-sync"},
+		{name: "fetch", opts: Options{PartitionBy: "country", ConcurrencyControl: concurrencyFetch}, action: dataActionAdd, wantErr: "-concurrency-control"},
+	}
+	for _, tt := range tests {
+		err := validatePartitioning(tt.opts, tt.action)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// partitionServer serves the indices in existing, records created indices
+// and the _index of every bulk action, and answers each bulk item as
+// created.
+type partitionServer struct {
+	mu       sync.Mutex
+	existing map[string]bool
+	created  map[string]string
+	targets  []string
+}
+
+func (s *partitionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case r.Method == http.MethodHead && strings.HasPrefix(name, "sales"):
+		if s.existing[name] {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPut && strings.HasPrefix(name, "sales"):
+		body, _ := io.ReadAll(r.Body)
+		s.created[name] = string(body)
+		s.existing[name] = true
+		_, _ = w.Write([]byte(`{"acknowledged":true}`))
+	case r.Method == http.MethodGet && strings.HasSuffix(name, "/_mapping"):
+		_, _ = w.Write([]byte(`{"` + strings.TrimSuffix(name, "/_mapping") + `":{"mappings":{"properties":{"country":{"type":"keyword"}}}}}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+		body, _ := io.ReadAll(r.Body)
+		var items []string
+		for line, text := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+			if line%2 != 0 {
+				continue
+			}
+			var action map[string]struct {
+				Index string `json:"_index"`
+			}
+			_ = json.Unmarshal([]byte(text), &action)
+			s.targets = append(s.targets, action["index"].Index)
+			items = append(items, `{"index":{"_index":"`+action["index"].Index+`","status":201}}`)
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[` + strings.Join(items, ",") + `]}`))
+	default:
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+// TestRunPartitionBy verifies behavior for the related scenario.
+func TestRunPartitionBy(t *testing.T) {
+	t.Parallel()
+
+	server := &partitionServer{existing: map[string]bool{"sales-us": true}, created: map[string]string{}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	mappings := writeTestDataFile(t, "mappings.json", `{"mappings":{"properties":{"country":{"type":"keyword"}}}}`)
+	data := writeTestDataFile(t, "sales.csv", "country,amount\nUS,1\nDE,2\n,3\nUS,4\n")
+
+	result, err := Run(context.Background(), Options{
+		URL:          httpServer.URL,
+		Index:        "sales",
+		MappingsFile: mappings,
+		DataFile:     data,
+		AddToIndex:   true,
+		PartitionBy:  "country",
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !slices.Equal(result.PartitionIndices, []string{"sales-us", "sales-de"}) {
+		t.Fatalf("expected partitions sales-us and sales-de, got %v", result.PartitionIndices)
+	}
+	if !slices.Equal(server.targets, []string{"sales-us", "sales-de", "sales", "sales-us"}) {
+		t.Fatalf("expected documents routed by country, got %v", server.targets)
+	}
+	if _, ok := server.created["sales-us"]; ok {
+		t.Fatalf("expected the existing sales-us partition to be reused")
+	}
+	if body := server.created["sales-de"]; body != server.created["sales"] || !strings.Contains(body, `"country"`) {
+		t.Fatalf("expected sales-de to be created with the -index body, got %q and %q", body, server.created["sales"])
+	}
+	if result.DocumentsSucceeded != 4 {
+		t.Fatalf("expected 4 documents indexed, got %d", result.DocumentsSucceeded)
+	}
+
+	limited := &partitionServer{existing: map[string]bool{}, created: map[string]string{}}
+	limitedServer := httptest.NewServer(limited)
+	defer limitedServer.Close()
+	_, err = Run(context.Background(), Options{
+		URL:          limitedServer.URL,
+		Index:        "sales",
+		DataFile:     data,
+		AddToIndex:   true,
+		PartitionBy:  "country",
+		PartitionMax: 1,
+	})
+	if err == nil || !strings.Contains(err.Error(), "more than 1 distinct values") {
+		t.Fatalf("expected the partition limit to fail the load, got %v", err)
+	}
+	if _, ok := limited.created["sales-de"]; ok {
+		t.Fatalf("expected no partition past -partition-max to be created")
+	}
+
+	_, err = Diff(context.Background(), DiffOptions{Load: Options{URL: httpServer.URL, Index: "sales", DataFile: data, IDField: "country", PartitionBy: "country"}})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected diff to reject -partition-by, got %v", err)
+	}
+}
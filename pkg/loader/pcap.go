@@ -0,0 +1,542 @@
+package loader
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── PCAP Input ────────────────────────────────────────────────────────────────
+
+const (
+	// pcapModePacket indexes one document per packet.
+	pcapModePacket = "packet"
+	// pcapModeFlow indexes one document per flow: packets sharing a
+	// transport and both endpoints, in either direction.
+	pcapModeFlow = "flow"
+)
+
+// Link types of the capture interface that are decoded.
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLinuxSLL = 113
+	linkTypeLoop     = 108
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+	linkTypeSLL2     = 276
+)
+
+// pcapngSectionHeader is the block type that starts every pcapng section.
+const pcapngSectionHeader = 0x0a0d0d0a
+
+// ipProtocols names the IP protocol numbers, as network.transport.
+var ipProtocols = map[byte]string{1: "icmp", 2: "igmp", 6: "tcp", 17: "udp", 47: "gre", 50: "esp", 51: "ah", 58: "ipv6-icmp", 132: "sctp"}
+
+// tcpFlagNames are the TCP flags from the lowest bit up.
+var tcpFlagNames = []string{"FIN", "SYN", "RST", "PSH", "ACK", "URG", "ECE", "CWR"}
+
+// pcapPacket is one captured packet and the interface it came from.
+type pcapPacket struct {
+	timestamp time.Time
+	linkType  uint32
+	data      []byte
+	length    int
+}
+
+// pcapSource yields the packets of a classic pcap or a pcapng file.
+type pcapSource interface {
+	nextPacket() (pcapPacket, error)
+}
+
+// newPcapSource detects the capture format from its first bytes.
+func newPcapSource(reader *bufio.Reader) (pcapSource, error) {
+	magic, err := reader.Peek(4)
+	if err != nil {
+		return nil, errors.New("not a capture file: no pcap or pcapng header")
+	}
+	switch {
+	case binary.LittleEndian.Uint32(magic) == pcapngSectionHeader:
+		return &pcapngSource{reader: reader}, nil
+	case isPcapMagic(binary.LittleEndian.Uint32(magic)) || isPcapMagic(binary.BigEndian.Uint32(magic)):
+		return newClassicPcapSource(reader)
+	}
+	return nil, errors.New("not a capture file: no pcap or pcapng header")
+}
+
+// isPcapMagic reports whether magic is a microsecond or nanosecond pcap
+// header in the byte order it was read in.
+func isPcapMagic(magic uint32) bool {
+	return magic == 0xa1b2c3d4 || magic == 0xa1b23c4d
+}
+
+// classicPcapSource reads libpcap's original format.
+type classicPcapSource struct {
+	reader   *bufio.Reader
+	order    binary.ByteOrder
+	nanos    bool
+	linkType uint32
+}
+
+// newClassicPcapSource reads the file header.
+func newClassicPcapSource(reader *bufio.Reader) (*classicPcapSource, error) {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, errors.New("not a capture file: pcap header is cut off")
+	}
+	source := &classicPcapSource{reader: reader, order: binary.LittleEndian}
+	if !isPcapMagic(binary.LittleEndian.Uint32(header)) {
+		source.order = binary.BigEndian
+	}
+	source.nanos = source.order.Uint32(header) == 0xa1b23c4d
+	source.linkType = source.order.Uint32(header[20:]) & 0xffff
+	return source, nil
+}
+
+// nextPacket reads the following record.
+func (s *classicPcapSource) nextPacket() (pcapPacket, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(s.reader, header); err != nil {
+		return pcapPacket{}, err
+	}
+	captured := s.order.Uint32(header[8:])
+	if captured > 1<<24 {
+		return pcapPacket{}, fmt.Errorf("packet record of %d bytes is not plausible", captured)
+	}
+	data := make([]byte, captured)
+	if _, err := io.ReadFull(s.reader, data); err != nil {
+		return pcapPacket{}, io.ErrUnexpectedEOF
+	}
+	fraction := time.Duration(s.order.Uint32(header[4:]))
+	if !s.nanos {
+		fraction *= time.Microsecond
+	}
+	return pcapPacket{
+		timestamp: time.Unix(int64(s.order.Uint32(header)), int64(fraction)).UTC(),
+		linkType:  s.linkType,
+		data:      data,
+		length:    int(s.order.Uint32(header[12:])),
+	}, nil
+}
+
+// pcapngInterface is what a pcapng interface description block says of
+// the packets captured on it.
+type pcapngInterface struct {
+	linkType uint32
+	// units is the timestamp resolution in units per second.
+	units uint64
+}
+
+// pcapngSource reads the pcapng format, section by section.
+type pcapngSource struct {
+	reader     *bufio.Reader
+	order      binary.ByteOrder
+	interfaces []pcapngInterface
+}
+
+// nextPacket reads blocks until the following packet.
+func (s *pcapngSource) nextPacket() (pcapPacket, error) {
+	for {
+		head := make([]byte, 8)
+		if _, err := io.ReadFull(s.reader, head); err != nil {
+			return pcapPacket{}, err
+		}
+		if binary.LittleEndian.Uint32(head) == pcapngSectionHeader {
+			magic, err := s.reader.Peek(4)
+			if err != nil {
+				return pcapPacket{}, io.ErrUnexpectedEOF
+			}
+			s.order = binary.ByteOrder(binary.LittleEndian)
+			if binary.BigEndian.Uint32(magic) == 0x1a2b3c4d {
+				s.order = binary.BigEndian
+			}
+			s.interfaces = nil
+		} else if s.order == nil {
+			return pcapPacket{}, errors.New("pcapng block before the section header")
+		}
+		length := s.order.Uint32(head[4:])
+		if length < 12 || length%4 != 0 || length > 1<<24 {
+			return pcapPacket{}, fmt.Errorf("pcapng block of %d bytes is not plausible", length)
+		}
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(s.reader, body); err != nil {
+			return pcapPacket{}, io.ErrUnexpectedEOF
+		}
+		body = body[:len(body)-4]
+		switch s.order.Uint32(head) {
+		case 1: // interface description
+			if len(body) < 8 {
+				return pcapPacket{}, errors.New("pcapng interface description is cut off")
+			}
+			s.interfaces = append(s.interfaces, pcapngInterface{linkType: uint32(s.order.Uint16(body)), units: s.resolution(body[8:])})
+		case 6: // enhanced packet
+			if len(body) < 20 {
+				return pcapPacket{}, errors.New("pcapng packet block is cut off")
+			}
+			iface := int(s.order.Uint32(body))
+			if iface >= len(s.interfaces) {
+				return pcapPacket{}, fmt.Errorf("pcapng packet names undescribed interface %d", iface)
+			}
+			captured := int(s.order.Uint32(body[12:]))
+			if 20+captured > len(body) {
+				return pcapPacket{}, errors.New("pcapng packet block is cut off")
+			}
+			ticks := uint64(s.order.Uint32(body[4:]))<<32 | uint64(s.order.Uint32(body[8:]))
+			units := s.interfaces[iface].units
+			return pcapPacket{
+				timestamp: time.Unix(int64(ticks/units), int64((ticks%units)*uint64(time.Second)/units)).UTC(),
+				linkType:  s.interfaces[iface].linkType,
+				data:      body[20 : 20+captured],
+				length:    int(s.order.Uint32(body[16:])),
+			}, nil
+		case 3: // simple packet, without a timestamp
+			if len(body) < 4 || len(s.interfaces) == 0 {
+				return pcapPacket{}, errors.New("pcapng simple packet block is cut off")
+			}
+			original := int(s.order.Uint32(body))
+			return pcapPacket{linkType: s.interfaces[0].linkType, data: body[4:min(4+original, len(body))], length: original}, nil
+		}
+	}
+}
+
+// resolution returns the if_tsresol option of an interface, in units per
+// second; microseconds when it is absent.
+func (s *pcapngSource) resolution(options []byte) uint64 {
+	for len(options) >= 4 {
+		code, size := s.order.Uint16(options), int(s.order.Uint16(options[2:]))
+		if code == 0 || 4+size > len(options) {
+			break
+		}
+		if code == 9 && size >= 1 {
+			value := options[4]
+			if value&0x80 != 0 {
+				return 1 << min(value&0x7f, 63)
+			}
+			units := uint64(1)
+			for range min(value, 19) {
+				units *= 10
+			}
+			return units
+		}
+		options = options[4+(size+3)&^3:]
+	}
+	return 1e6
+}
+
+// decodePacket returns the metadata of packet: its link, network, and
+// transport headers, as far as they were captured.
+func decodePacket(packet pcapPacket) map[string]interface{} {
+	doc := map[string]interface{}{}
+	network := map[string]interface{}{"bytes": int64(packet.length)}
+	doc["network"] = network
+	data := packet.data
+	etherType := -1
+	switch packet.linkType {
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return doc
+		}
+		setField(doc, "destination.mac", formatMAC(data[0:6]))
+		setField(doc, "source.mac", formatMAC(data[6:12]))
+		etherType, data = int(binary.BigEndian.Uint16(data[12:])), data[14:]
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(data) >= 4 {
+			network["vlan"] = map[string]interface{}{"id": int64(binary.BigEndian.Uint16(data) & 0x0fff)}
+			etherType, data = int(binary.BigEndian.Uint16(data[2:])), data[4:]
+		}
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return doc
+		}
+		etherType, data = int(binary.BigEndian.Uint16(data[14:])), data[16:]
+	case linkTypeSLL2:
+		if len(data) < 20 {
+			return doc
+		}
+		etherType, data = int(binary.BigEndian.Uint16(data)), data[20:]
+	case linkTypeNull, linkTypeLoop, linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		if packet.linkType == linkTypeNull || packet.linkType == linkTypeLoop {
+			// The address family differs between systems, unlike the IP
+			// version that follows it.
+			if len(data) < 4 {
+				return doc
+			}
+			data = data[4:]
+		}
+		etherType = 0x0800
+		if len(data) > 0 && data[0]>>4 == 6 {
+			etherType = 0x86dd
+		}
+	default:
+		network["type"] = "link-" + strconv.Itoa(int(packet.linkType))
+		return doc
+	}
+	var protocol byte
+	switch etherType {
+	case 0x0800:
+		network["type"] = "ipv4"
+		if len(data) < 20 || int(data[0]&0x0f)*4 < 20 || len(data) < int(data[0]&0x0f)*4 {
+			return doc
+		}
+		setField(doc, "source.ip", net.IP(data[12:16]).String())
+		setField(doc, "destination.ip", net.IP(data[16:20]).String())
+		protocol = data[9]
+		fragmentOffset := binary.BigEndian.Uint16(data[6:]) & 0x1fff
+		network["transport"] = ipProtocolName(protocol)
+		if fragmentOffset != 0 {
+			// Only the first fragment holds the transport header.
+			return doc
+		}
+		data = data[int(data[0]&0x0f)*4:]
+	case 0x86dd:
+		network["type"] = "ipv6"
+		if len(data) < 40 {
+			return doc
+		}
+		setField(doc, "source.ip", net.IP(data[8:24]).String())
+		setField(doc, "destination.ip", net.IP(data[24:40]).String())
+		protocol, data = data[6], data[40:]
+		// Skip hop-by-hop, routing, fragment, and destination options headers.
+		for (protocol == 0 || protocol == 43 || protocol == 44 || protocol == 60) && len(data) >= 8 {
+			size := int(data[1])*8 + 8
+			if protocol == 44 {
+				size = 8
+				if binary.BigEndian.Uint16(data[2:])&0xfff8 != 0 {
+					network["transport"] = ipProtocolName(data[0])
+					return doc
+				}
+			}
+			if size > len(data) {
+				return doc
+			}
+			protocol, data = data[0], data[size:]
+		}
+		network["transport"] = ipProtocolName(protocol)
+	case 0x0806:
+		network["type"] = "arp"
+		return doc
+	default:
+		network["type"] = fmt.Sprintf("0x%04x", etherType)
+		return doc
+	}
+	switch protocol {
+	case 6, 17, 132:
+		if len(data) < 4 {
+			return doc
+		}
+		setField(doc, "source.port", int64(binary.BigEndian.Uint16(data)))
+		setField(doc, "destination.port", int64(binary.BigEndian.Uint16(data[2:])))
+		if protocol == 6 && len(data) >= 14 {
+			var flags []interface{}
+			for bit, name := range tcpFlagNames {
+				if data[13]&(1<<bit) != 0 {
+					flags = append(flags, name)
+				}
+			}
+			setField(doc, "tcp.flags", flags)
+		}
+	case 1, 58:
+		if len(data) >= 2 {
+			setField(doc, "icmp.type", int64(data[0]))
+			setField(doc, "icmp.code", int64(data[1]))
+		}
+	}
+	return doc
+}
+
+// ipProtocolName returns the name of an IP protocol, or its number.
+func ipProtocolName(protocol byte) string {
+	if name, ok := ipProtocols[protocol]; ok {
+		return name
+	}
+	return strconv.Itoa(int(protocol))
+}
+
+// formatMAC formats a hardware address the way ECS writes it.
+func formatMAC(address []byte) string {
+	return fmt.Sprintf("%02X-%02X-%02X-%02X-%02X-%02X", address[0], address[1], address[2], address[3], address[4], address[5])
+}
+
+// pcapFlow accumulates the packets of one flow, from the endpoint that
+// sent its first packet.
+type pcapFlow struct {
+	doc                 map[string]interface{}
+	start, end          time.Time
+	sourcePackets       int64
+	sourceBytes         int64
+	destinationPackets  int64
+	destinationBytes    int64
+	source, destination string
+	flags               map[string]bool
+}
+
+// pcapReader reads a capture file as packet or flow documents.
+type pcapReader struct {
+	file   io.ReadCloser
+	source pcapSource
+	flows  bool
+	count  int
+	// pending holds the flows to emit, in order of their first packet,
+	// once the whole capture is read.
+	pending []map[string]interface{}
+	drained bool
+}
+
+// newPcapReader reads the capture header of file.
+func newPcapReader(file io.ReadCloser, flows bool) (*pcapReader, error) {
+	source, err := newPcapSource(bufio.NewReaderSize(file, 1<<16))
+	if err != nil {
+		return nil, err
+	}
+	return &pcapReader{file: file, source: source, flows: flows}, nil
+}
+
+// packet returns the following packet, or io.EOF after the last one. A
+// capture cut off mid-packet, as one copied while still running is,
+// ends with a warning.
+func (r *pcapReader) packet() (pcapPacket, error) {
+	packet, err := r.source.nextPacket()
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		log.Warn().Int("packets", r.count).Msg("Capture file ends mid-packet; ignoring the rest")
+		return pcapPacket{}, io.EOF
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return pcapPacket{}, fmt.Errorf("packet %d: %w", r.count+1, err)
+	}
+	if err == nil {
+		r.count++
+	}
+	return packet, err
+}
+
+// next returns the following packet document, or in flow mode, the
+// following flow once every packet is read.
+func (r *pcapReader) next() (map[string]interface{}, error) {
+	if !r.flows {
+		packet, err := r.packet()
+		if err != nil {
+			return nil, err
+		}
+		doc := decodePacket(packet)
+		if !packet.timestamp.IsZero() {
+			doc["@timestamp"] = packet.timestamp.Format(time.RFC3339Nano)
+		}
+		doc["packet"] = map[string]interface{}{"number": int64(r.count), "captured_bytes": int64(len(packet.data))}
+		return doc, nil
+	}
+	if !r.drained {
+		if err := r.readFlows(); err != nil {
+			return nil, err
+		}
+		r.drained = true
+	}
+	if len(r.pending) == 0 {
+		return nil, io.EOF
+	}
+	doc := r.pending[0]
+	r.pending = r.pending[1:]
+	return doc, nil
+}
+
+// readFlows reads every packet into flows keyed by transport and both
+// endpoints. Packets without an IP header are not part of any flow.
+func (r *pcapReader) readFlows() error {
+	flows := map[string]*pcapFlow{}
+	var order []*pcapFlow
+	for {
+		packet, err := r.packet()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		doc := decodePacket(packet)
+		source, sourceOK := flowEndpoint(doc, "source")
+		destination, destinationOK := flowEndpoint(doc, "destination")
+		if !sourceOK || !destinationOK {
+			continue
+		}
+		network := doc["network"].(map[string]interface{})
+		transport, _ := network["transport"].(string)
+		key := transport + " " + min(source, destination) + " " + max(source, destination)
+		flow, ok := flows[key]
+		if !ok {
+			flow = &pcapFlow{doc: doc, start: packet.timestamp, source: source, destination: destination, flags: map[string]bool{}}
+			flows[key] = flow
+			order = append(order, flow)
+		}
+		if packet.timestamp.After(flow.end) {
+			flow.end = packet.timestamp
+		}
+		if source == flow.source {
+			flow.sourcePackets++
+			flow.sourceBytes += int64(packet.length)
+		} else {
+			flow.destinationPackets++
+			flow.destinationBytes += int64(packet.length)
+		}
+		if tcp, ok := doc["tcp"].(map[string]interface{}); ok {
+			for _, flag := range tcp["flags"].([]interface{}) {
+				flow.flags[flag.(string)] = true
+			}
+		}
+	}
+	for _, flow := range order {
+		doc := flow.doc
+		delete(doc, "icmp")
+		delete(doc, "tcp")
+		// MACs name hops, not the flow's endpoints.
+		delete(doc["source"].(map[string]interface{}), "mac")
+		delete(doc["destination"].(map[string]interface{}), "mac")
+		if len(flow.flags) > 0 {
+			var flags []interface{}
+			for _, name := range tcpFlagNames {
+				if flow.flags[name] {
+					flags = append(flags, name)
+				}
+			}
+			setField(doc, "tcp.flags", flags)
+		}
+		setField(doc, "source.packets", flow.sourcePackets)
+		setField(doc, "source.bytes", flow.sourceBytes)
+		setField(doc, "destination.packets", flow.destinationPackets)
+		setField(doc, "destination.bytes", flow.destinationBytes)
+		setField(doc, "network.packets", flow.sourcePackets+flow.destinationPackets)
+		setField(doc, "network.bytes", flow.sourceBytes+flow.destinationBytes)
+		if !flow.start.IsZero() {
+			doc["@timestamp"] = flow.start.Format(time.RFC3339Nano)
+			setField(doc, "event.start", flow.start.Format(time.RFC3339Nano))
+			setField(doc, "event.end", flow.end.Format(time.RFC3339Nano))
+			setField(doc, "event.duration", flow.end.Sub(flow.start).Nanoseconds())
+		}
+		r.pending = append(r.pending, doc)
+	}
+	return nil
+}
+
+// flowEndpoint returns the ip and port of the source or destination of a
+// packet document.
+func flowEndpoint(doc map[string]interface{}, side string) (string, bool) {
+	endpoint, _ := doc[side].(map[string]interface{})
+	ip, ok := endpoint["ip"].(string)
+	if !ok {
+		return "", false
+	}
+	if port, ok := endpoint["port"].(int64); ok {
+		return net.JoinHostPort(ip, strconv.FormatInt(port, 10)), true
+	}
+	return ip, true
+}
+
+// close releases the underlying file.
+func (r *pcapReader) close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,221 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+var (
+	pcapTestClient = []byte{0xaa, 0xbb, 0xcc, 0, 0, 1}
+	pcapTestRouter = []byte{0xaa, 0xbb, 0xcc, 0, 0, 2}
+)
+
+// pcapTestEthernet frames payload as Ethernet, tagged with vlan when it
+// is set.
+func pcapTestEthernet(dst, src []byte, vlan uint16, etherType uint16, payload []byte) []byte {
+	frame := append(append([]byte{}, dst...), src...)
+	if vlan != 0 {
+		frame = binary.BigEndian.AppendUint16(frame, 0x8100)
+		frame = binary.BigEndian.AppendUint16(frame, vlan)
+	}
+	frame = binary.BigEndian.AppendUint16(frame, etherType)
+	return append(frame, payload...)
+}
+
+// pcapTestIPv4 wraps payload in an IPv4 header without options.
+func pcapTestIPv4(src, dst string, protocol byte, payload []byte) []byte {
+	header := make([]byte, 20)
+	header[0], header[8], header[9] = 0x45, 64, protocol
+	binary.BigEndian.PutUint16(header[2:], uint16(20+len(payload)))
+	copy(header[12:], net.ParseIP(src).To4())
+	copy(header[16:], net.ParseIP(dst).To4())
+	return append(header, payload...)
+}
+
+// pcapTestIPv6 wraps payload in an IPv6 header and a hop-by-hop options
+// header.
+func pcapTestIPv6(src, dst string, protocol byte, payload []byte) []byte {
+	header := make([]byte, 40)
+	header[0], header[6], header[7] = 0x60, 0, 1
+	binary.BigEndian.PutUint16(header[4:], uint16(8+len(payload)))
+	copy(header[8:], net.ParseIP(src))
+	copy(header[24:], net.ParseIP(dst))
+	header = append(header, protocol, 0, 5, 2, 0, 0, 1, 0)
+	return append(header, payload...)
+}
+
+// pcapTestTCP returns a TCP header with flags.
+func pcapTestTCP(src, dst uint16, flags byte) []byte {
+	header := make([]byte, 20)
+	binary.BigEndian.PutUint16(header, src)
+	binary.BigEndian.PutUint16(header[2:], dst)
+	header[12], header[13] = 0x50, flags
+	return header
+}
+
+// pcapTestUDP returns a UDP header.
+func pcapTestUDP(src, dst uint16) []byte {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint16(header, src)
+	binary.BigEndian.PutUint16(header[2:], dst)
+	return header
+}
+
+// writeTestPcap returns a little-endian microsecond pcap of a TCP
+// handshake over a VLAN, mDNS over IPv6, ARP, and a ping, ending in a
+// record cut off mid-packet.
+func writeTestPcap(t *testing.T) string {
+	t.Helper()
+
+	var file bytes.Buffer
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header, 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], 65535)
+	binary.LittleEndian.PutUint32(header[20:], linkTypeEthernet)
+	file.Write(header)
+	packets := []struct {
+		micros int
+		length int
+		data   []byte
+	}{
+		{1, 1514, pcapTestEthernet(pcapTestRouter, pcapTestClient, 100, 0x0800, pcapTestIPv4("10.0.0.5", "93.184.216.34", 6, pcapTestTCP(51544, 443, 0x02)))},
+		{250001, 60, pcapTestEthernet(pcapTestClient, pcapTestRouter, 100, 0x0800, pcapTestIPv4("93.184.216.34", "10.0.0.5", 6, pcapTestTCP(443, 51544, 0x12)))},
+		{500000, 0, pcapTestEthernet(pcapTestRouter, pcapTestClient, 0, 0x86dd, pcapTestIPv6("2001:db8::1", "ff02::fb", 17, pcapTestUDP(5353, 5353)))},
+		{600000, 0, pcapTestEthernet(pcapTestRouter, pcapTestClient, 0, 0x0806, make([]byte, 28))},
+		{700000, 0, pcapTestEthernet(pcapTestRouter, pcapTestClient, 0, 0x0800, pcapTestIPv4("10.0.0.5", "10.0.0.1", 1, []byte{8, 0, 0, 0}))},
+	}
+	for _, packet := range packets {
+		record := make([]byte, 16)
+		binary.LittleEndian.PutUint32(record, 1714550400)
+		binary.LittleEndian.PutUint32(record[4:], uint32(packet.micros))
+		binary.LittleEndian.PutUint32(record[8:], uint32(len(packet.data)))
+		binary.LittleEndian.PutUint32(record[12:], uint32(max(packet.length, len(packet.data))))
+		file.Write(record)
+		file.Write(packet.data)
+	}
+	cut := make([]byte, 16)
+	binary.LittleEndian.PutUint32(cut[8:], 100)
+	file.Write(cut)
+	file.Write(make([]byte, 10))
+	return writeTestDataFile(t, "capture.pcap", file.String())
+}
+
+// TestPcapReaderDecodesPackets verifies behavior for the related scenario.
+func TestPcapReaderDecodesPackets(t *testing.T) {
+	t.Parallel()
+
+	config, err := newInputConfig(Options{})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	got, err := readTestDocuments(t, config, writeTestPcap(t))
+	if err != nil {
+		t.Fatalf("reading returned error: %v", err)
+	}
+	want := []string{
+		`{"@timestamp":"2024-05-01T08:00:00.000001Z","destination":{"ip":"93.184.216.34","mac":"AA-BB-CC-00-00-02","port":443},"network":{"bytes":1514,"transport":"tcp","type":"ipv4","vlan":{"id":100}},"packet":{"captured_bytes":58,"number":1},"source":{"ip":"10.0.0.5","mac":"AA-BB-CC-00-00-01","port":51544},"tcp":{"flags":["SYN"]}}`,
+		`{"@timestamp":"2024-05-01T08:00:00.250001Z","destination":{"ip":"10.0.0.5","mac":"AA-BB-CC-00-00-01","port":51544},"network":{"bytes":60,"transport":"tcp","type":"ipv4","vlan":{"id":100}},"packet":{"captured_bytes":58,"number":2},"source":{"ip":"93.184.216.34","mac":"AA-BB-CC-00-00-02","port":443},"tcp":{"flags":["SYN","ACK"]}}`,
+		`{"@timestamp":"2024-05-01T08:00:00.5Z","destination":{"ip":"ff02::fb","mac":"AA-BB-CC-00-00-02","port":5353},"network":{"bytes":70,"transport":"udp","type":"ipv6"},"packet":{"captured_bytes":70,"number":3},"source":{"ip":"2001:db8::1","mac":"AA-BB-CC-00-00-01","port":5353}}`,
+		`{"@timestamp":"2024-05-01T08:00:00.6Z","destination":{"mac":"AA-BB-CC-00-00-02"},"network":{"bytes":42,"type":"arp"},"packet":{"captured_bytes":42,"number":4},"source":{"mac":"AA-BB-CC-00-00-01"}}`,
+		`{"@timestamp":"2024-05-01T08:00:00.7Z","destination":{"ip":"10.0.0.1","mac":"AA-BB-CC-00-00-02"},"icmp":{"code":0,"type":8},"network":{"bytes":38,"transport":"icmp","type":"ipv4"},"packet":{"captured_bytes":38,"number":5},"source":{"ip":"10.0.0.5","mac":"AA-BB-CC-00-00-01"}}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("read\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := readTestDocuments(t, config, writeTestDataFile(t, "notes.pcap", "not a capture")); err == nil || !strings.Contains(err.Error(), "no pcap or pcapng header") {
+		t.Fatalf("expected a file without a capture header to fail, got %v", err)
+	}
+	if _, err := newInputConfig(Options{PcapMode: "session"}); err == nil || !strings.Contains(err.Error(), "-pcap-mode must be packet or flow") {
+		t.Fatalf("expected an unknown -pcap-mode to fail, got %v", err)
+	}
+	if _, err := newInputConfig(Options{Format: formatCSV, PcapMode: pcapModeFlow}); err == nil || !strings.Contains(err.Error(), "-pcap-mode needs -format pcap") {
+		t.Fatalf("expected -pcap-mode flow with another format to fail, got %v", err)
+	}
+}
+
+// TestPcapReaderAggregatesFlows verifies behavior for the related scenario.
+func TestPcapReaderAggregatesFlows(t *testing.T) {
+	t.Parallel()
+
+	config, err := newInputConfig(Options{PcapMode: pcapModeFlow})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	got, err := readTestDocuments(t, config, writeTestPcap(t))
+	if err != nil {
+		t.Fatalf("reading returned error: %v", err)
+	}
+	want := []string{
+		`{"@timestamp":"2024-05-01T08:00:00.000001Z","destination":{"bytes":60,"ip":"93.184.216.34","packets":1,"port":443},"event":{"duration":250000000,"end":"2024-05-01T08:00:00.250001Z","start":"2024-05-01T08:00:00.000001Z"},"network":{"bytes":1574,"packets":2,"transport":"tcp","type":"ipv4","vlan":{"id":100}},"source":{"bytes":1514,"ip":"10.0.0.5","packets":1,"port":51544},"tcp":{"flags":["SYN","ACK"]}}`,
+		`{"@timestamp":"2024-05-01T08:00:00.5Z","destination":{"bytes":0,"ip":"ff02::fb","packets":0,"port":5353},"event":{"duration":0,"end":"2024-05-01T08:00:00.5Z","start":"2024-05-01T08:00:00.5Z"},"network":{"bytes":70,"packets":1,"transport":"udp","type":"ipv6"},"source":{"bytes":70,"ip":"2001:db8::1","packets":1,"port":5353}}`,
+		`{"@timestamp":"2024-05-01T08:00:00.7Z","destination":{"bytes":0,"ip":"10.0.0.1","packets":0},"event":{"duration":0,"end":"2024-05-01T08:00:00.7Z","start":"2024-05-01T08:00:00.7Z"},"network":{"bytes":38,"packets":1,"transport":"icmp","type":"ipv4"},"source":{"bytes":38,"ip":"10.0.0.5","packets":1}}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("read\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestPcapngReaderUsesInterfaceResolution verifies behavior for the related scenario.
+func TestPcapngReaderUsesInterfaceResolution(t *testing.T) {
+	t.Parallel()
+
+	block := func(kind uint32, body []byte) []byte {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		out := binary.LittleEndian.AppendUint32(nil, kind)
+		out = binary.LittleEndian.AppendUint32(out, uint32(12+len(body)))
+		out = append(out, body...)
+		return binary.LittleEndian.AppendUint32(out, uint32(12+len(body)))
+	}
+	var file bytes.Buffer
+	file.Write(block(pcapngSectionHeader, []byte{0x4d, 0x3c, 0x2b, 0x1a, 1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}))
+	// A raw IP interface with if_tsresol 9, nanoseconds.
+	file.Write(block(1, []byte{linkTypeRaw, 0, 0, 0, 0, 0, 0, 0, 9, 0, 1, 0, 9, 0, 0, 0, 0, 0, 0, 0}))
+	packet := pcapTestIPv4("192.0.2.10", "198.51.100.53", 17, pcapTestUDP(40000, 53))
+	ticks := uint64(1714550400)*1e9 + 123456789
+	enhanced := binary.LittleEndian.AppendUint32(nil, 0)
+	enhanced = binary.LittleEndian.AppendUint32(enhanced, uint32(ticks>>32))
+	enhanced = binary.LittleEndian.AppendUint32(enhanced, uint32(ticks))
+	enhanced = binary.LittleEndian.AppendUint32(enhanced, uint32(len(packet)))
+	enhanced = binary.LittleEndian.AppendUint32(enhanced, uint32(len(packet)))
+	file.Write(block(6, append(enhanced, packet...)))
+	file.Write(block(5, []byte{0, 0, 0, 0})) // interface statistics, skipped
+
+	config, err := newInputConfig(Options{Format: formatPcap})
+	if err != nil {
+		t.Fatalf("newInputConfig returned error: %v", err)
+	}
+	got, err := readTestDocuments(t, config, writeTestDataFile(t, "capture.bin", file.String()))
+	want := `{"@timestamp":"2024-05-01T08:00:00.123456789Z","destination":{"ip":"198.51.100.53","port":53},"network":{"bytes":28,"transport":"udp","type":"ipv4"},"packet":{"captured_bytes":28,"number":1},"source":{"ip":"192.0.2.10","port":40000}}`
+	if err != nil || len(got) != 1 || got[0] != want {
+		t.Fatalf("read %q and %v, want %s", got, err, want)
+	}
+}
+
+// TestRunPcapDataFile verifies behavior for the related scenario.
+func TestRunPcapDataFile(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newWatchTestServer(t)
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   writeTestPcap(t),
+		AddToIndex: true,
+		PcapMode:   pcapModeFlow,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if sent := strings.Join(bodies(), ""); result.DocumentsProcessed != 3 || !strings.Contains(sent, `"transport":"tcp"`) || !strings.Contains(sent, `"packets":2`) {
+		t.Fatalf("unexpected result %+v and bulk bodies %s", result, sent)
+	}
+}
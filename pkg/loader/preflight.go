@@ -0,0 +1,179 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Connection Pre-Flight ─────────────────────────────────────────────────────
+
+// ClusterInfo describes the cluster a run connected to, as reported by the
+// pre-flight check.
+type ClusterInfo struct {
+	Name         string
+	UUID         string
+	Version      string
+	BuildFlavor  string
+	Distribution string
+	// License is the license type, or "" when the cluster does not report one.
+	License string
+}
+
+// clusterRootResponse is the body of GET /.
+type clusterRootResponse struct {
+	Name    string `json:"cluster_name"`
+	UUID    string `json:"cluster_uuid"`
+	Version struct {
+		Number       string `json:"number"`
+		BuildFlavor  string `json:"build_flavor"`
+		Distribution string `json:"distribution"`
+	} `json:"version"`
+	Tagline string `json:"tagline"`
+}
+
+// preflightCluster checks, before any client is created, that url answers
+// and accepts the configured credentials, and reports what it is. Failures
+// are ErrConnection or ErrAuthentication with a message that says what to
+// fix. It talks HTTP directly, because the client's product check refuses
+// Elasticsearch releases older than 7.14 before their version is known.
+func preflightCluster(ctx context.Context, opts Options) (ClusterInfo, error) {
+	var info ClusterInfo
+	client := &http.Client{Transport: authTransport(opts), Timeout: 30 * time.Second}
+	defer client.CloseIdleConnections()
+	res, err := clusterGet(ctx, client, opts, "/")
+	if errors.Is(err, errKerberos) || errors.Is(err, errBearerToken) {
+		return info, &RunError{Kind: ErrAuthentication, Op: "authenticating to Elasticsearch", Err: err}
+	}
+	if err != nil {
+		return info, &RunError{Kind: ErrConnection, Op: "connecting to Elasticsearch", Err: fmt.Errorf("cannot reach %s; check -url, DNS, firewalls, and TLS settings (-insecureSkipVerify): %w", opts.URL, err)}
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusUnauthorized:
+		return info, &RunError{Kind: ErrAuthentication, Op: "authenticating to Elasticsearch", Err: fmt.Errorf("%s rejected the credentials (401); %s", opts.URL, credentialHint(opts))}
+	case res.StatusCode == http.StatusForbidden:
+		return info, &RunError{Kind: ErrAuthentication, Op: "authenticating to Elasticsearch", Err: fmt.Errorf("%s accepted the credentials but denied cluster info (403); grant the monitor cluster privilege to %s", opts.URL, credentialName(opts))}
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		body, _ := io.ReadAll(res.Body)
+		return info, &RunError{Kind: ErrConnection, Op: "connecting to Elasticsearch", Err: fmt.Errorf("%s answered GET / with status %d: %s", opts.URL, res.StatusCode, strings.TrimSpace(string(body)))}
+	}
+
+	var root clusterRootResponse
+	if err := json.NewDecoder(res.Body).Decode(&root); err != nil {
+		return info, &RunError{Kind: ErrConnection, Op: "connecting to Elasticsearch", Err: fmt.Errorf("parsing cluster info from %s: %w", opts.URL, err)}
+	}
+	info = ClusterInfo{
+		Name:         root.Name,
+		UUID:         root.UUID,
+		Version:      root.Version.Number,
+		BuildFlavor:  root.Version.BuildFlavor,
+		Distribution: root.Version.Distribution,
+	}
+	// Releases before 7.14 predate the product header; a 7.x version and
+	// the Elasticsearch tagline identify them instead.
+	genuine := res.Header.Get("X-Elastic-Product") == "Elasticsearch" ||
+		info.majorVersion() == 7 && root.Tagline == "You Know, for Search" && info.Distribution == ""
+	if !genuine {
+		return info, &RunError{Kind: ErrConnection, Op: "connecting to Elasticsearch", Err: fmt.Errorf("%s did not answer as Elasticsearch; check that -url points at Elasticsearch and not a proxy or another product", opts.URL)}
+	}
+	info.License = clusterLicense(ctx, client, opts)
+	log.Info().
+		Str("cluster_name", info.Name).
+		Str("cluster_uuid", info.UUID).
+		Str("version", info.Version).
+		Str("build_flavor", info.BuildFlavor).
+		Str("distribution", info.Distribution).
+		Str("license", info.License).
+		Msg("Connected to Elasticsearch")
+	return info, nil
+}
+
+// clusterGet sends an authenticated GET for path to the cluster.
+func clusterGet(ctx context.Context, client *http.Client, opts Options, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(endpointURL(opts), "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case opts.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+opts.APIKey)
+	case opts.User != "" && opts.Pass != "":
+		req.SetBasicAuth(opts.User, opts.Pass)
+	}
+	return client.Do(req)
+}
+
+// majorVersion returns the major release, or 0 when it is not known.
+func (c ClusterInfo) majorVersion() int {
+	major, _, _ := strings.Cut(c.Version, ".")
+	n, _ := strconv.Atoi(major)
+	return n
+}
+
+// clusterLicense returns the license type, or "" when the license API is
+// missing (OSS builds, serverless) or not permitted; neither stops a load.
+func clusterLicense(ctx context.Context, client *http.Client, opts Options) string {
+	res, err := clusterGet(ctx, client, opts, "/_license")
+	if err != nil {
+		log.Debug().Err(err).Msg("Reading cluster license")
+		return ""
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		log.Debug().Int("status_code", res.StatusCode).Msg("Cluster license is not available")
+		return ""
+	}
+	var parsed struct {
+		License struct {
+			Type string `json:"type"`
+		} `json:"license"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		log.Debug().Err(err).Msg("Parsing cluster license")
+		return ""
+	}
+	return parsed.License.Type
+}
+
+// credentialHint says which credentials to check after a 401.
+func credentialHint(opts Options) string {
+	switch {
+	case opts.Kerberos.enabled():
+		return "check that the proxy accepts the Kerberos principal and that -kerberos-spn names its service principal"
+	case opts.BearerToken != nil:
+		return "check that a JWT realm trusts the -oidc-issuer and accepts tokens issued to -oidc-client-id"
+	case opts.APIKey == "" && isServerlessURL(opts.URL):
+		return "Elastic serverless projects accept only API keys; set -apiKey"
+	case opts.APIKey != "":
+		return "check that -apiKey is the base64 encoded id:api_key and has not expired or been invalidated"
+	case opts.User != "":
+		return fmt.Sprintf("check the -user %q and -pass", opts.User)
+	}
+	return "the cluster requires authentication; set -user and -pass or -apiKey"
+}
+
+// credentialName names the identity a 403 applies to.
+func credentialName(opts Options) string {
+	switch {
+	case opts.Kerberos.enabled():
+		return "the Kerberos principal"
+	case opts.BearerToken != nil:
+		return "the bearer token's user"
+	case opts.APIKey != "":
+		return "the -apiKey"
+	case opts.User != "":
+		return fmt.Sprintf("user %q", opts.User)
+	}
+	return "the anonymous user"
+}
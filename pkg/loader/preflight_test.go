@@ -0,0 +1,135 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestPreflightCluster verifies behavior for the related scenario.
+func TestPreflightCluster(t *testing.T) {
+	t.Parallel()
+
+	const current = `{"cluster_name":"prod","cluster_uuid":"u1","version":{"number":"9.1.0","build_flavor":"default"}}`
+	const legacy = `{"cluster_name":"old","cluster_uuid":"u7","version":{"number":"7.10.2","build_flavor":"default"},"tagline":"You Know, for Search"}`
+	tests := []struct {
+		name     string
+		root     string
+		opts     Options
+		status   int
+		product  string
+		license  int
+		wantKind error
+		want     string
+		wantInfo ClusterInfo
+	}{
+		{
+			name:     "connected",
+			status:   http.StatusOK,
+			license:  http.StatusOK,
+			wantInfo: ClusterInfo{Name: "prod", UUID: "u1", Version: "9.1.0", BuildFlavor: "default", License: "platinum"},
+		},
+		{
+			name:     "no license API",
+			status:   http.StatusOK,
+			license:  http.StatusNotFound,
+			wantInfo: ClusterInfo{Name: "prod", UUID: "u1", Version: "9.1.0", BuildFlavor: "default"},
+		},
+		{name: "bad password", opts: Options{User: "loader", Pass: "wrong"}, status: http.StatusUnauthorized, wantKind: ErrAuthentication, want: `-user "loader"`},
+		{name: "bad API key", opts: Options{APIKey: "abc"}, status: http.StatusUnauthorized, wantKind: ErrAuthentication, want: "-apiKey"},
+		{name: "no credentials", status: http.StatusUnauthorized, wantKind: ErrAuthentication, want: "set -user and -pass or -apiKey"},
+		{name: "missing privilege", opts: Options{User: "loader", Pass: "x"}, status: http.StatusForbidden, wantKind: ErrAuthentication, want: "monitor cluster privilege"},
+		{
+			name:     "7.x before the product header",
+			root:     legacy,
+			status:   http.StatusOK,
+			product:  "-",
+			license:  http.StatusOK,
+			wantInfo: ClusterInfo{Name: "old", UUID: "u7", Version: "7.10.2", BuildFlavor: "default", License: "platinum"},
+		},
+		{name: "not elasticsearch", status: http.StatusOK, product: "-", wantKind: ErrConnection, want: "did not answer as Elasticsearch"},
+		{name: "8.x without the product header", root: strings.Replace(legacy, "7.10.2", "8.1.0", 1), status: http.StatusOK, product: "-", wantKind: ErrConnection, want: "did not answer as Elasticsearch"},
+		{name: "server error", status: http.StatusBadGateway, wantKind: ErrConnection, want: "status 502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			root := tt.root
+			if root == "" {
+				root = current
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.product != "-" {
+					w.Header().Set("X-Elastic-Product", "Elasticsearch")
+				}
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/":
+					w.WriteHeader(tt.status)
+					_, _ = w.Write([]byte(root))
+				case "/_license":
+					w.WriteHeader(tt.license)
+					_, _ = w.Write([]byte(`{"license":{"type":"platinum","status":"active"}}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			opts := tt.opts
+			opts.URL = server.URL
+			info, err := preflightCluster(context.Background(), opts)
+			if tt.wantKind == nil {
+				if err != nil || info != tt.wantInfo {
+					t.Fatalf("got %+v, %v; want %+v", info, err, tt.wantInfo)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantKind) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %v mentioning %q, got %v", tt.wantKind, tt.want, err)
+			}
+		})
+	}
+}
+
+// TestRunPreflightStopsBeforeIndexOperations verifies behavior for the related scenario.
+func TestRunPreflightStopsBeforeIndexOperations(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Preflight:  true,
+		Index:      "cards",
+		DataFile:   writeTestDataFile(t, "cards.ndjson", "{\"n\":1}\n"),
+		AddToIndex: true,
+	})
+	if !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("expected ErrAuthentication, got %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "/" {
+		t.Fatalf("expected only GET /, got %v", paths)
+	}
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	_, err = Run(context.Background(), Options{URL: unreachable.URL, Preflight: true, Index: "cards", DataFile: "cards.ndjson", AddToIndex: true})
+	if !errors.Is(err, ErrConnection) || !strings.Contains(err.Error(), "cannot reach") {
+		t.Fatalf("expected ErrConnection, got %v", err)
+	}
+}
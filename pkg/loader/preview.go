@@ -0,0 +1,81 @@
+package loader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ─── Document Preview ──────────────────────────────────────────────────────────
+
+// previewTarget is the index the preview's action lines name: an alias run
+// that creates a timestamped index writes there instead of the alias.
+func previewTarget(opts Options, action dataAction, exists bool) string {
+	if opts.AliasMode && (action == dataActionDelete || !exists) {
+		return buildTimestampedIndexName(opts.Index, time.Now().UTC())
+	}
+	return opts.Index
+}
+
+// previewDocuments writes the first n documents of the load to out as the
+// NDJSON bulk lines they would be sent as, each action line followed by
+// its source. Documents go through the same reading, skipping, and
+// transforms as a load, and are encoded by the same code, so only the
+// batch boundaries are missing. It returns how many documents it wrote.
+func previewDocuments(opts Options, input inputConfig, transforms docTransformChain, index string, settings bulkSettings, n int, out io.Writer) (int, error) {
+	reader, err := input.openDataFiles(opts.dataFiles(), opts.ReadConcurrency)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.close()
+	for skipped := 0; skipped < opts.SkipDocuments; skipped++ {
+		if _, err := reader.next(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	body := newBulkBody()
+	defer body.release()
+	written := 0
+	if rawReader, ok := reader.(rawDocumentReader); ok && len(transforms) == 0 && !settings.decodesDocuments() {
+		// Matches the load's raw passthrough, which keeps each line's bytes.
+		for written < n {
+			doc, err := rawReader.nextRaw()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return written, fmt.Errorf("decoding document %d: %w", written+1, err)
+			}
+			encodeRawBulkBody(body, index, []json.RawMessage{doc}, settings)
+			written++
+		}
+	} else {
+		for read := 1; written < n; read++ {
+			doc, err := reader.next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return written, fmt.Errorf("decoding document %d: %w", read, err)
+			}
+			docs, err := transforms.apply(doc)
+			if err != nil {
+				return written, fmt.Errorf("transforming document %d: %w", read, err)
+			}
+			if len(docs) > n-written {
+				docs = docs[:n-written]
+			}
+			encodeBulkBody(body, index, docs, settings)
+			written += len(docs)
+		}
+	}
+	if _, err := out.Write(body.Bytes()); err != nil {
+		return written, err
+	}
+	return written, nil
+}
@@ -0,0 +1,79 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// TestRunPreviewWritesBulkLines verifies behavior for the related scenario.
+func TestRunPreviewWritesBulkLines(t *testing.T) {
+	t.Parallel()
+
+	var writes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writes.Add(1)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	dataFile := filepath.Join(t.TempDir(), "cards.ndjson")
+	data := "{\"z\":1,\"code\":\"a\"}\n{\"z\":2,\"code\":\"b\"}\n{\"z\":3,\"code\":\"c\"}\n"
+	if err := os.WriteFile(dataFile, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "raw lines",
+			opts: Options{Preview: 2, IDField: "code"},
+			want: "{\"index\":{\"_id\":\"a\",\"_index\":\"cards\"}}\n{\"z\":1,\"code\":\"a\"}\n" +
+				"{\"index\":{\"_id\":\"b\",\"_index\":\"cards\"}}\n{\"z\":2,\"code\":\"b\"}\n",
+		},
+		{
+			name: "transformed and skipped",
+			opts: Options{Preview: 5, SkipDocuments: 1, Rename: []string{"z=n"}, Filter: `n != 3`, DocType: "_doc"},
+			want: "{\"index\":{\"_index\":\"cards\",\"_type\":\"_doc\"}}\n{\"code\":\"b\",\"n\":2}\n",
+		},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		opts := tt.opts
+		opts.URL = server.URL
+		opts.Index = "cards"
+		opts.DataFile = dataFile
+		opts.AddToIndex = true
+		opts.PreviewOutput = &out
+		result, err := Run(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		if out.String() != tt.want {
+			t.Fatalf("%s: preview mismatch\n got: %q\nwant: %q", tt.name, out.String(), tt.want)
+		}
+		if result.DocumentsProcessed != 0 {
+			t.Fatalf("%s: expected no documents to be loaded, got %d", tt.name, result.DocumentsProcessed)
+		}
+	}
+	if writes.Load() != 0 {
+		t.Fatalf("expected no writes, got %d", writes.Load())
+	}
+
+	_, err := Run(context.Background(), Options{URL: server.URL, Index: "cards", SyncManaged: true, Preview: 1})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions without a data action, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package loader
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ─── Protobuf Input ────────────────────────────────────────────────────────────
+
+// protobufSchema is the -message type resolved from a -descriptor set.
+type protobufSchema struct {
+	message protoreflect.MessageDescriptor
+	types   *dynamicpb.Types
+}
+
+// loadProtobufSchema reads a FileDescriptorSet, as written by
+// protoc --include_imports --descriptor_set_out, and finds messageName in it.
+func loadProtobufSchema(path, messageName string) (*protobufSchema, error) {
+	if messageName == "" {
+		return nil, fmt.Errorf("-descriptor needs -message naming the record type")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading -descriptor: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(content, &set); err != nil {
+		return nil, fmt.Errorf("parsing -descriptor %s: %w", path, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("parsing -descriptor %s: %w", path, err)
+	}
+	found, err := files.FindDescriptorByName(protoreflect.FullName(messageName))
+	if err != nil {
+		return nil, fmt.Errorf("-descriptor %s has no message %q", path, messageName)
+	}
+	message, ok := found.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("-message %q is not a message type", messageName)
+	}
+	return &protobufSchema{message: message, types: dynamicpb.NewTypes(files)}, nil
+}
+
+// protobufReader decodes a stream of varint length-delimited messages.
+type protobufReader struct {
+	file      io.ReadCloser
+	reader    *bufio.Reader
+	schema    *protobufSchema
+	unmarshal protodelim.UnmarshalOptions
+	marshal   protojson.MarshalOptions
+	count     int
+}
+
+// newProtobufReader reads schema messages from file.
+func newProtobufReader(file io.ReadCloser, schema *protobufSchema) *protobufReader {
+	return &protobufReader{
+		file:   file,
+		reader: bufio.NewReader(file),
+		schema: schema,
+		unmarshal: protodelim.UnmarshalOptions{
+			UnmarshalOptions: proto.UnmarshalOptions{Resolver: schema.types},
+			MaxSize:          -1,
+		},
+		// Field names follow the .proto file, not protojson's lowerCamelCase.
+		marshal: protojson.MarshalOptions{UseProtoNames: true, Resolver: schema.types},
+	}
+}
+
+// next decodes the following message into its canonical JSON document.
+func (r *protobufReader) next() (map[string]interface{}, error) {
+	message := dynamicpb.NewMessage(r.schema.message)
+	err := r.unmarshal.UnmarshalFrom(r.reader, message)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	r.count++
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = fmt.Errorf("truncated message")
+		}
+		return nil, fmt.Errorf("protobuf message %d: %w", r.count, err)
+	}
+	encoded, err := r.marshal.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("protobuf message %d: %w", r.count, err)
+	}
+	var doc map[string]interface{}
+	if err := decodeJSON(encoded, &doc); err != nil {
+		return nil, fmt.Errorf("protobuf message %d: %w", r.count, err)
+	}
+	return doc, nil
+}
+
+// close releases the underlying file.
+func (r *protobufReader) close() error {
+	return r.file.Close()
+}
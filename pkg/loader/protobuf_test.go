@@ -0,0 +1,147 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// writeTestDescriptor writes a FileDescriptorSet declaring acme.v1.Event and
+// returns its path and the loaded schema.
+func writeTestDescriptor(t *testing.T) (string, *protobufSchema) {
+	t.Helper()
+	field := func(name string, number int32, kind descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Type: kind.Enum(), Label: label.Enum(), JsonName: proto.String(name)}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("acme/v1/event.proto"),
+		Package: proto.String("acme.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Location"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("lat", 1, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, optional, ""),
+			}},
+			{Name: proto.String("Event"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("event_id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+				field("level", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional, ""),
+				field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, ""),
+				field("location", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional, ".acme.v1.Location"),
+			}},
+		},
+	}}}
+	content, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "event.pb")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	schema, err := loadProtobufSchema(path, "acme.v1.Event")
+	if err != nil {
+		t.Fatalf("loadProtobufSchema returned error: %v", err)
+	}
+	return path, schema
+}
+
+// writeTestProtobufStream encodes one Event per id, each with level 2 and a
+// location, as a length-delimited stream.
+func writeTestProtobufStream(t *testing.T, schema *protobufSchema, ids ...string) string {
+	t.Helper()
+	fields := schema.message.Fields()
+	location := fields.ByName("location").Message()
+	var stream bytes.Buffer
+	for _, id := range ids {
+		message := dynamicpb.NewMessage(schema.message)
+		message.Set(fields.ByName("event_id"), protoreflect.ValueOfString(id))
+		message.Set(fields.ByName("level"), protoreflect.ValueOfInt32(2))
+		tags := message.Mutable(fields.ByName("tags")).List()
+		tags.Append(protoreflect.ValueOfString("a"))
+		loc := dynamicpb.NewMessage(location)
+		loc.Set(location.Fields().ByName("lat"), protoreflect.ValueOfFloat64(51.5))
+		message.Set(fields.ByName("location"), protoreflect.ValueOfMessage(loc))
+		if _, err := protodelim.MarshalTo(&stream, message); err != nil {
+			t.Fatalf("MarshalTo returned error: %v", err)
+		}
+	}
+	return writeTestDataFile(t, "events.bin", stream.String())
+}
+
+// TestProtobufReader verifies behavior for the related scenario.
+func TestProtobufReader(t *testing.T) {
+	t.Parallel()
+
+	path, schema := writeTestDescriptor(t)
+	got, err := readTestDocuments(t, inputConfig{protobuf: schema}, writeTestProtobufStream(t, schema, "1", "2"))
+	if err != nil {
+		t.Fatalf("reading stream returned error: %v", err)
+	}
+	want := []string{
+		`{"event_id":"1","level":2,"location":{"lat":51.5},"tags":["a"]}`,
+		`{"event_id":"2","level":2,"location":{"lat":51.5},"tags":["a"]}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("read %q, want %q", got, want)
+	}
+
+	stream, _ := os.ReadFile(writeTestProtobufStream(t, schema, "1", "2"))
+	truncated := writeTestDataFile(t, "events.bin", string(stream[:len(stream)-3]))
+	if _, err := readTestDocuments(t, inputConfig{protobuf: schema}, truncated); err == nil || !strings.Contains(err.Error(), "message 2") {
+		t.Fatalf("expected a message 2 error, got %v", err)
+	}
+
+	failures := []struct {
+		opts Options
+		want string
+	}{
+		{opts: Options{Descriptor: path}, want: "needs -message"},
+		{opts: Options{Descriptor: path, Message: "acme.v1.Missing"}, want: `no message "acme.v1.Missing"`},
+		{opts: Options{Descriptor: path, Message: "acme.v1.Event.level"}, want: "not a message type"},
+		{opts: Options{Format: formatProtobuf}, want: "needs a -descriptor"},
+		{opts: Options{Format: formatCSV, Descriptor: path, Message: "acme.v1.Event"}, want: "needs -format protobuf"},
+	}
+	for _, tt := range failures {
+		if _, err := newInputConfig(tt.opts); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("newInputConfig(%+v): expected error mentioning %q, got %v", tt.opts, tt.want, err)
+		}
+	}
+}
+
+// TestRunProtobufDataFile verifies behavior for the related scenario.
+func TestRunProtobufDataFile(t *testing.T) {
+	t.Parallel()
+
+	path, schema := writeTestDescriptor(t)
+	server, bodies := newWatchTestServer(t)
+	result, err := Run(context.Background(), Options{
+		URL:        server.URL,
+		Index:      "cards",
+		DataFile:   writeTestProtobufStream(t, schema, "1", "2", "3"),
+		AddToIndex: true,
+		Descriptor: path,
+		Message:    "acme.v1.Event",
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsProcessed != 3 {
+		t.Fatalf("expected 3 processed documents, got %+v", result)
+	}
+	if sent := strings.Join(bodies(), ""); !strings.Contains(sent, `"event_id":"3"`) {
+		t.Fatalf("unexpected bulk bodies: %s", sent)
+	}
+}
@@ -0,0 +1,135 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pubsub "cloud.google.com/go/pubsub/v2/apiv1"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+)
+
+// ─── Google Cloud Pub/Sub Source ───────────────────────────────────────────────
+
+const (
+	// pubSubMaxMessages is the most messages one pull returns.
+	pubSubMaxMessages = 1000
+	// pubSubMaxAckIDs is the most ack IDs sent in one acknowledge or
+	// modifyAckDeadline call.
+	pubSubMaxAckIDs = 1000
+)
+
+// pubSubSource pulls messages from one Pub/Sub subscription with the
+// Google Cloud client library.
+type pubSubSource struct {
+	client *pubsub.SubscriptionAdminClient
+	// subscription is the subscription's full resource name.
+	subscription string
+	// ackDeadline, when positive, extends the ack deadline of each pulled
+	// message to this many seconds.
+	ackDeadline int32
+}
+
+// newPubSubSource parses pubsub://<project>/<subscription>. The query may
+// set endpoint (a gRPC host:port) and ack_deadline in seconds. Credentials
+// are the application default credentials, unless GOOGLE_OAUTH_ACCESS_TOKEN
+// is set; PUBSUB_EMULATOR_HOST points the client at the emulator.
+func newPubSubSource(u *url.URL) (*pubSubSource, error) {
+	subscription := strings.Trim(u.Path, "/")
+	if u.Host == "" || subscription == "" || strings.Contains(subscription, "/") {
+		return nil, fmt.Errorf("-source %s: expected pubsub://<project>/<subscription>", redactSourceURL(u.String()))
+	}
+	query := u.Query()
+	s := &pubSubSource{subscription: "projects/" + u.Host + "/subscriptions/" + subscription}
+	if raw := query.Get("ack_deadline"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 10 || seconds > 600 {
+			return nil, fmt.Errorf("-source ack_deadline %q: expected 10 to 600 seconds", raw)
+		}
+		s.ackDeadline = int32(seconds)
+	}
+	var options []option.ClientOption
+	if endpoint := query.Get("endpoint"); endpoint != "" {
+		options = append(options, option.WithEndpoint(endpoint))
+	}
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		options = append(options, option.WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+	}
+	client, err := pubsub.NewSubscriptionAdminClient(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("creating Pub/Sub client: %w", err)
+	}
+	s.client = client
+	return s, nil
+}
+
+// receive pulls up to max messages, waiting up to wait for the first.
+func (s *pubSubSource) receive(ctx context.Context, max int, wait time.Duration) ([]sourceMessage, error) {
+	pullCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	answer, err := s.client.Pull(pullCtx, &pubsubpb.PullRequest{Subscription: s.subscription, MaxMessages: int32(min(max, pubSubMaxMessages))})
+	if err != nil && ctx.Err() == nil && pullCtx.Err() != nil {
+		// Nothing arrived within wait.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Pub/Sub pull: %w", err)
+	}
+	msgs := make([]sourceMessage, 0, len(answer.ReceivedMessages))
+	for _, received := range answer.ReceivedMessages {
+		msgs = append(msgs, sourceMessage{body: received.GetMessage().GetData(), handle: received.AckId})
+	}
+	if s.ackDeadline > 0 && len(msgs) > 0 {
+		if err := s.modifyAckDeadline(ctx, msgs, s.ackDeadline); err != nil {
+			return msgs, err
+		}
+	}
+	return msgs, nil
+}
+
+// ack acknowledges msgs.
+func (s *pubSubSource) ack(ctx context.Context, msgs []sourceMessage) error {
+	for chunk := range slices.Chunk(msgs, pubSubMaxAckIDs) {
+		if err := s.client.Acknowledge(ctx, &pubsubpb.AcknowledgeRequest{Subscription: s.subscription, AckIds: pubSubAckIDs(chunk)}); err != nil {
+			return fmt.Errorf("Pub/Sub acknowledge: %w", err)
+		}
+	}
+	return nil
+}
+
+// nack sets the ack deadline of msgs to zero, so they are redelivered at once.
+func (s *pubSubSource) nack(ctx context.Context, msgs []sourceMessage) error {
+	return s.modifyAckDeadline(ctx, msgs, 0)
+}
+
+// close closes the client's gRPC connection.
+func (s *pubSubSource) close() error {
+	return s.client.Close()
+}
+
+// modifyAckDeadline sets the ack deadline of msgs to seconds from now.
+func (s *pubSubSource) modifyAckDeadline(ctx context.Context, msgs []sourceMessage, seconds int32) error {
+	for chunk := range slices.Chunk(msgs, pubSubMaxAckIDs) {
+		request := &pubsubpb.ModifyAckDeadlineRequest{Subscription: s.subscription, AckIds: pubSubAckIDs(chunk), AckDeadlineSeconds: seconds}
+		if err := s.client.ModifyAckDeadline(ctx, request); err != nil {
+			return fmt.Errorf("Pub/Sub modifyAckDeadline: %w", err)
+		}
+	}
+	return nil
+}
+
+// pubSubAckIDs returns the ack IDs of msgs.
+func pubSubAckIDs(msgs []sourceMessage) []string {
+	ids := make([]string, len(msgs))
+	for i, msg := range msgs {
+		ids[i] = msg.handle
+	}
+	return ids
+}
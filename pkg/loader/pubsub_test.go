@@ -0,0 +1,104 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	pubsub "cloud.google.com/go/pubsub/v2/apiv1"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"cloud.google.com/go/pubsub/v2/pstest"
+)
+
+// TestPubSubSourceSettlesMessages verifies behavior for the related scenario.
+func TestPubSubSourceSettlesMessages(t *testing.T) {
+	server := pstest.NewServer()
+	t.Cleanup(func() { _ = server.Close() })
+	t.Setenv("PUBSUB_EMULATOR_HOST", server.Addr)
+
+	ctx := context.Background()
+	topics, err := pubsub.NewTopicAdminClient(ctx)
+	if err != nil {
+		t.Fatalf("NewTopicAdminClient returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = topics.Close() })
+	if _, err := topics.CreateTopic(ctx, &pubsubpb.Topic{Name: "projects/acme/topics/events"}); err != nil {
+		t.Fatalf("CreateTopic returned error: %v", err)
+	}
+
+	u, _ := url.Parse("pubsub://acme/events-loader?ack_deadline=120")
+	source, err := newPubSubSource(u)
+	if err != nil {
+		t.Fatalf("newPubSubSource returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = source.close() })
+	if _, err := source.client.CreateSubscription(ctx, &pubsubpb.Subscription{Name: source.subscription, Topic: "projects/acme/topics/events", AckDeadlineSeconds: 10}); err != nil {
+		t.Fatalf("CreateSubscription returned error: %v", err)
+	}
+
+	if msgs, err := source.receive(ctx, 5000, 100*time.Millisecond); err != nil || len(msgs) != 0 {
+		t.Fatalf("expected an empty receive once wait passes, got %+v, %v", msgs, err)
+	}
+	one := server.Publish("projects/acme/topics/events", []byte(`{"msg":"one"}`), nil)
+	two := server.Publish("projects/acme/topics/events", []byte(`{"msg":"two"}`), nil)
+	msgs, err := source.receive(ctx, 5000, 5*time.Second)
+	if err != nil {
+		t.Fatalf("receive returned error: %v", err)
+	}
+	// pstest does not keep publish order.
+	slices.SortFunc(msgs, func(a, b sourceMessage) int { return bytes.Compare(a.body, b.body) })
+	if len(msgs) != 2 || string(msgs[0].body) != `{"msg":"one"}` || msgs[1].handle == "" {
+		t.Fatalf("unexpected messages %+v", msgs)
+	}
+	if err := source.ack(ctx, msgs[:1]); err != nil {
+		t.Fatalf("ack returned error: %v", err)
+	}
+	if err := source.nack(ctx, msgs[1:]); err != nil {
+		t.Fatalf("nack returned error: %v", err)
+	}
+
+	for _, msg := range server.Messages() {
+		var deadlines []int32
+		for _, modack := range msg.Modacks {
+			deadlines = append(deadlines, modack.AckDeadline)
+		}
+		switch msg.ID {
+		case one:
+			if msg.Acks != 1 || len(deadlines) != 1 || deadlines[0] != 120 {
+				t.Fatalf("expected the first message extended to 120s and acked, got acks %d, deadlines %v", msg.Acks, deadlines)
+			}
+		case two:
+			if msg.Acks != 0 || len(deadlines) != 2 || deadlines[0] != 120 || deadlines[1] != 0 {
+				t.Fatalf("expected the nack to clear the second message's deadline, got acks %d, deadlines %v", msg.Acks, deadlines)
+			}
+		}
+	}
+	redelivered, err := source.receive(ctx, 5000, 5*time.Second)
+	if err != nil || len(redelivered) != 1 || string(redelivered[0].body) != `{"msg":"two"}` {
+		t.Fatalf("expected the nacked message again, got %+v, %v", redelivered, err)
+	}
+}
+
+// TestNewPubSubSourceValidatesURL verifies behavior for the related scenario.
+func TestNewPubSubSourceValidatesURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		raw     string
+		wantErr string
+	}{
+		{raw: "pubsub://acme", wantErr: "expected pubsub://<project>/<subscription>"},
+		{raw: "pubsub://acme/a/b", wantErr: "expected pubsub://<project>/<subscription>"},
+		{raw: "pubsub://acme/events?ack_deadline=5", wantErr: "ack_deadline"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.raw)
+		if _, err := newPubSubSource(u); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.raw, tt.wantErr, err)
+		}
+	}
+}
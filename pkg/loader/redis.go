@@ -0,0 +1,356 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Redis Source ──────────────────────────────────────────────────────────────
+
+const (
+	redisDefaultPort = "6379"
+	// redisTimeout bounds connecting and each command, on top of how long a
+	// blocking read was asked to wait.
+	redisTimeout = 10 * time.Second
+)
+
+// redisSource consumes a stream through a consumer group, or a list through
+// a per-consumer processing list that holds popped values until they are
+// acknowledged, with the go-redis client. The client connects on first use
+// and again after the connection breaks.
+type redisSource struct {
+	client *redis.Client
+	// stream or list names the key consumed.
+	stream string
+	list   string
+	// group, consumer, and start configure stream consumption; start is the
+	// ID a new group starts from.
+	group    string
+	consumer string
+	start    string
+	// field, when set, is the stream entry field holding the JSON document;
+	// otherwise the entry's fields make up the document.
+	field string
+	// processing holds values popped from list until they are settled.
+	processing string
+
+	// ready is set once the key is prepared; see prepare.
+	ready bool
+	// history is the ID after which the pending entries of this consumer
+	// are read next; empty reads new entries.
+	history string
+}
+
+// newRedisSource parses redis[s]://[[user]:password@]<host>[:port][/<db>]
+// with stream=<key> or list=<key>. Streams take group (default
+// es-bulk-loader), consumer (default the host name), start (default 0),
+// and field; lists take processing. rediss takes ca.
+func newRedisSource(u *url.URL) (*redisSource, error) {
+	display := redactSourceURL(u.String())
+	query := u.Query()
+	s := &redisSource{
+		stream:   query.Get("stream"),
+		list:     query.Get("list"),
+		group:    query.Get("group"),
+		consumer: query.Get("consumer"),
+		start:    query.Get("start"),
+		field:    query.Get("field"),
+	}
+	if u.Hostname() == "" || (s.stream == "") == (s.list == "") {
+		return nil, fmt.Errorf("-source %s: expected %s://<host>[/<db>] with stream=<key> or list=<key>", display, u.Scheme)
+	}
+	port := u.Port()
+	if port == "" {
+		port = redisDefaultPort
+	}
+	// Retries are left to the caller: a reply lost with the connection
+	// leaves entries pending, which prepare reads again.
+	options := &redis.Options{
+		Addr:                  net.JoinHostPort(u.Hostname(), port),
+		DialTimeout:           redisTimeout,
+		ReadTimeout:           -1,
+		ContextTimeoutEnabled: true,
+		MaxRetries:            -1,
+		DisableIdentity:       true,
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		number, err := strconv.Atoi(db)
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("-source %s: database %q is not a number", display, db)
+		}
+		options.DB = number
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			options.Username, options.Password = u.User.Username(), password
+		}
+	}
+	if s.group == "" {
+		s.group = "es-bulk-loader"
+	}
+	if s.consumer == "" {
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			host = "es-bulk-loader"
+		}
+		s.consumer = host
+	}
+	if s.start == "" {
+		s.start = "0"
+	}
+	s.processing = query.Get("processing")
+	if s.list != "" && s.processing == "" {
+		s.processing = s.list + ":processing:" + s.consumer
+	}
+	if strings.EqualFold(u.Scheme, "rediss") {
+		config, err := sourceTLSConfig(u.Hostname(), query)
+		if err != nil {
+			return nil, err
+		}
+		options.TLSConfig = config
+	}
+	s.client = redis.NewClient(options)
+	return s, nil
+}
+
+// receive returns up to max messages, waiting up to wait for the first.
+func (s *redisSource) receive(ctx context.Context, max int, wait time.Duration) ([]sourceMessage, error) {
+	callCtx, cancel := context.WithTimeout(ctx, wait+redisTimeout)
+	defer cancel()
+	if err := s.prepare(callCtx); err != nil {
+		return nil, err
+	}
+	var msgs []sourceMessage
+	var err error
+	if s.stream != "" {
+		msgs, err = s.receiveStream(callCtx, max, wait)
+	} else {
+		msgs, err = s.receiveList(callCtx, max, wait)
+	}
+	if ctx.Err() != nil {
+		// The client closes a connection whose blocking read was cancelled.
+		return msgs, nil
+	}
+	if err != nil && !isRedisError(err) {
+		// Nothing read is handed out on an error, so preparing again is safe
+		// and returns what a broken pipeline left on the processing list.
+		s.ready = false
+	}
+	return msgs, err
+}
+
+// receiveStream reads the pending entries of this consumer after history,
+// or else blocks for new entries.
+func (s *redisSource) receiveStream(ctx context.Context, limit int, wait time.Duration) ([]sourceMessage, error) {
+	args := &redis.XReadGroupArgs{Group: s.group, Consumer: s.consumer, Count: int64(limit), Block: -1}
+	id := ">"
+	if s.history != "" {
+		id = s.history
+	} else {
+		args.Block = max(wait, time.Millisecond)
+	}
+	args.Streams = []string{s.stream, id}
+	streams, err := s.client.XReadGroup(ctx, args).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "NOGROUP") {
+			s.ready = false
+		}
+		return nil, fmt.Errorf("Redis XREADGROUP %s: %w", s.stream, err)
+	}
+	var entries []redis.XMessage
+	if len(streams) > 0 {
+		entries = streams[0].Messages
+	}
+	if s.history != "" {
+		s.history = ""
+		if len(entries) > 0 {
+			s.history = entries[len(entries)-1].ID
+		}
+	}
+	var msgs []sourceMessage
+	var deleted []string
+	for _, entry := range entries {
+		if entry.Values == nil {
+			deleted = append(deleted, entry.ID)
+			continue
+		}
+		body, err := s.entryDocument(entry.Values)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, sourceMessage{body: body, handle: entry.ID})
+	}
+	if len(deleted) > 0 {
+		// Entries trimmed from the stream while pending have nothing left
+		// to index.
+		if err := s.client.XAck(ctx, s.stream, s.group, deleted...).Err(); err != nil {
+			return nil, fmt.Errorf("Redis XACK %s: %w", s.stream, err)
+		}
+	}
+	return msgs, nil
+}
+
+// entryDocument returns the JSON document of a stream entry: its field
+// value, or an object of all its fields.
+func (s *redisSource) entryDocument(values map[string]interface{}) ([]byte, error) {
+	if s.field != "" {
+		value, ok := values[s.field].(string)
+		if !ok {
+			return nil, nil
+		}
+		return []byte(value), nil
+	}
+	return json.Marshal(values)
+}
+
+// receiveList blocks for one value and takes up to limit-1 more without
+// blocking, moving each onto the processing list.
+func (s *redisSource) receiveList(ctx context.Context, limit int, wait time.Duration) ([]sourceMessage, error) {
+	// BRPOPLPUSH is sent as is: the client's own method rounds a timeout
+	// under a second up to one.
+	timeout := strconv.FormatFloat(max(wait.Seconds(), 0.001), 'f', 3, 64)
+	first, err := s.client.Do(ctx, "BRPOPLPUSH", s.list, s.processing, timeout).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Redis BRPOPLPUSH %s: %w", s.list, err)
+	}
+	msgs := []sourceMessage{{body: []byte(first), handle: first}}
+	if limit > 1 {
+		pipe := s.client.Pipeline()
+		commands := make([]*redis.StringCmd, limit-1)
+		for i := range commands {
+			commands[i] = pipe.RPopLPush(ctx, s.list, s.processing)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("Redis RPOPLPUSH %s: %w", s.list, err)
+		}
+		for _, command := range commands {
+			value, err := command.Result()
+			if err != nil {
+				break
+			}
+			msgs = append(msgs, sourceMessage{body: []byte(value), handle: value})
+		}
+	}
+	return msgs, nil
+}
+
+// ack acknowledges stream entries, or removes list values from the
+// processing list.
+func (s *redisSource) ack(ctx context.Context, msgs []sourceMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	if s.stream != "" {
+		ids := make([]string, len(msgs))
+		for i, msg := range msgs {
+			ids[i] = msg.handle
+		}
+		if err := s.client.XAck(ctx, s.stream, s.group, ids...).Err(); err != nil {
+			return fmt.Errorf("Redis XACK %s: %w", s.stream, err)
+		}
+		return nil
+	}
+	return s.run(ctx, func(pipe redis.Pipeliner) {
+		for _, msg := range msgs {
+			pipe.LRem(ctx, s.processing, -1, msg.handle)
+		}
+	})
+}
+
+// nack leaves stream entries pending, to be read again with the next
+// batch, or pushes list values back to be popped next.
+func (s *redisSource) nack(ctx context.Context, msgs []sourceMessage) error {
+	if s.stream != "" {
+		s.history = "0"
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	return s.run(ctx, func(pipe redis.Pipeliner) {
+		for _, msg := range msgs {
+			pipe.RPush(ctx, s.list, msg.handle)
+			pipe.LRem(ctx, s.processing, -1, msg.handle)
+		}
+	})
+}
+
+// run sends the commands fn queues in one pipeline and reports the first
+// error.
+func (s *redisSource) run(ctx context.Context, fn func(redis.Pipeliner)) error {
+	commands, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		fn(pipe)
+		return nil
+	})
+	if err != nil {
+		for _, command := range commands {
+			if command.Err() != nil {
+				return fmt.Errorf("Redis %s: %w", strings.ToUpper(command.Name()), command.Err())
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// close disconnects from the server.
+func (s *redisSource) close() error {
+	return s.client.Close()
+}
+
+// prepare readies the key before the first read, and again after the
+// connection broke: it creates the stream's consumer group, or moves values
+// a previous run left on the processing list back onto the list.
+func (s *redisSource) prepare(ctx context.Context) error {
+	if s.ready {
+		return nil
+	}
+	if s.stream != "" {
+		err := s.client.XGroupCreateMkStream(ctx, s.stream, s.group, s.start).Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("Redis XGROUP CREATE %s %s: %w", s.stream, s.group, err)
+		}
+		// Entries delivered to this consumer before a restart are read first.
+		s.history = "0"
+		s.ready = true
+		return nil
+	}
+	restored := 0
+	for {
+		err := s.client.RPopLPush(ctx, s.processing, s.list).Err()
+		if errors.Is(err, redis.Nil) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Redis RPOPLPUSH %s: %w", s.processing, err)
+		}
+		restored++
+	}
+	if restored > 0 {
+		log.Info().Str("list", s.list).Int("values", restored).Msg("Returned unacknowledged values from the processing list")
+	}
+	s.ready = true
+	return nil
+}
+
+// isRedisError reports whether err is an error reply; the connection stays
+// usable after one.
+func isRedisError(err error) bool {
+	var reply redis.Error
+	return errors.As(err, &reply) && !errors.Is(err, redis.Nil)
+}
@@ -0,0 +1,190 @@
+package loader
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// receiveUntil calls receive until it returns messages or a second passes.
+func receiveUntil(t *testing.T, source messageSource, limit int) []sourceMessage {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		msgs, err := source.receive(context.Background(), limit, 10*time.Millisecond)
+		if err != nil {
+			t.Fatalf("receive returned error: %v", err)
+		}
+		if len(msgs) > 0 {
+			return msgs
+		}
+	}
+	return nil
+}
+
+// TestRedisSourceConsumesStream verifies behavior for the related scenario.
+func TestRedisSourceConsumesStream(t *testing.T) {
+	t.Parallel()
+
+	server := miniredis.RunT(t)
+	server.RequireAuth("s3cret")
+	db := server.DB(2)
+	for _, entry := range [][]string{{"data", `{"n":1}`, "source", "web"}, {"data", `{"n":2}`}, {"data", `{"n":3}`}, {"data", `{"n":4}`}} {
+		if _, err := db.XAdd("events", "*", entry); err != nil {
+			t.Fatalf("XAdd returned error: %v", err)
+		}
+	}
+	u, _ := url.Parse("redis://:s3cret@" + server.Addr() + "/2?stream=events&consumer=loader-1&field=data")
+	source, err := newRedisSource(u)
+	if err != nil {
+		t.Fatalf("newRedisSource returned error: %v", err)
+	}
+	ctx := context.Background()
+
+	first := receiveUntil(t, source, 2)
+	if len(first) != 2 || string(first[0].body) != `{"n":1}` || string(first[1].body) != `{"n":2}` {
+		t.Fatalf("expected entries 1 and 2, got %+v", first)
+	}
+	if err := source.ack(ctx, first[:1]); err != nil {
+		t.Fatalf("ack returned error: %v", err)
+	}
+	if err := source.nack(ctx, first[1:]); err != nil {
+		t.Fatalf("nack returned error: %v", err)
+	}
+	again := receiveUntil(t, source, 2)
+	if len(again) != 1 || again[0].handle != first[1].handle {
+		t.Fatalf("expected the returned entry to be read again first, got %+v", again)
+	}
+	if err := source.ack(ctx, again); err != nil {
+		t.Fatalf("ack returned error: %v", err)
+	}
+	third := receiveUntil(t, source, 2)
+	if len(third) != 2 || string(third[1].body) != `{"n":4}` {
+		t.Fatalf("expected entries 3 and 4, got %+v", third)
+	}
+	source.close()
+
+	// A restarted consumer reads its unacknowledged entries first.
+	restarted, err := newRedisSource(u)
+	if err != nil {
+		t.Fatalf("newRedisSource returned error: %v", err)
+	}
+	defer restarted.close()
+	pending := receiveUntil(t, restarted, 10)
+	if len(pending) != 2 || pending[0].handle != third[0].handle || pending[1].handle != third[1].handle {
+		t.Fatalf("expected entries 3 and 4 to be delivered again, got %+v", pending)
+	}
+	if err := restarted.ack(ctx, pending); err != nil {
+		t.Fatalf("ack returned error: %v", err)
+	}
+	if pending, err := restarted.client.XPending(ctx, "events", "es-bulk-loader").Result(); err != nil || pending.Count != 0 {
+		t.Fatalf("expected no entries pending, got %+v and %v", pending, err)
+	}
+}
+
+// TestRedisSourceEntryDocument verifies behavior for the related scenario.
+func TestRedisSourceEntryDocument(t *testing.T) {
+	t.Parallel()
+
+	server := miniredis.RunT(t)
+	if _, err := server.XAdd("events", "*", []string{"source", "web", "level", "info"}); err != nil {
+		t.Fatalf("XAdd returned error: %v", err)
+	}
+	u, _ := url.Parse("redis://" + server.Addr() + "?stream=events&start=0")
+	source, err := newRedisSource(u)
+	if err != nil {
+		t.Fatalf("newRedisSource returned error: %v", err)
+	}
+	defer source.close()
+	msgs := receiveUntil(t, source, 10)
+	if len(msgs) != 1 || string(msgs[0].body) != `{"level":"info","source":"web"}` {
+		t.Fatalf("expected an object of the entry's fields, got %+v", msgs)
+	}
+}
+
+// TestRedisSourceConsumesList verifies behavior for the related scenario.
+func TestRedisSourceConsumesList(t *testing.T) {
+	t.Parallel()
+
+	server := miniredis.RunT(t)
+	if _, err := server.Push("events", `{"n":3}`, `{"n":2}`, `{"n":1}`); err != nil {
+		t.Fatalf("Push returned error: %v", err)
+	}
+	if _, err := server.Push("events:processing:loader-1", `{"n":0}`); err != nil {
+		t.Fatalf("Push returned error: %v", err)
+	}
+	u, _ := url.Parse("redis://" + server.Addr() + "?list=events&consumer=loader-1")
+	source, err := newRedisSource(u)
+	if err != nil {
+		t.Fatalf("newRedisSource returned error: %v", err)
+	}
+	defer source.close()
+	ctx := context.Background()
+
+	msgs := receiveUntil(t, source, 10)
+	var bodies []string
+	for _, msg := range msgs {
+		bodies = append(bodies, string(msg.body))
+	}
+	if !slices.Equal(bodies, []string{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":0}`}) {
+		t.Fatalf("expected the oldest values first and the leftover value last, got %v", bodies)
+	}
+	if err := source.ack(ctx, []sourceMessage{msgs[0], msgs[1], msgs[3]}); err != nil {
+		t.Fatalf("ack returned error: %v", err)
+	}
+	if err := source.nack(ctx, msgs[2:3]); err != nil {
+		t.Fatalf("nack returned error: %v", err)
+	}
+	list, _ := server.List("events")
+	processing, _ := server.List("events:processing:loader-1")
+	if !slices.Equal(list, []string{`{"n":3}`}) || len(processing) != 0 {
+		t.Fatalf("expected the returned value back on the list, got %v and processing %v", list, processing)
+	}
+	if again := receiveUntil(t, source, 10); len(again) != 1 || string(again[0].body) != `{"n":3}` {
+		t.Fatalf("expected the returned value to be popped again, got %+v", again)
+	}
+}
+
+// TestRedisSourceReportsAuthFailure verifies behavior for the related scenario.
+func TestRedisSourceReportsAuthFailure(t *testing.T) {
+	t.Parallel()
+
+	server := miniredis.RunT(t)
+	server.RequireUserAuth("loader", "s3cret")
+	u, _ := url.Parse("redis://loader:wrong@" + server.Addr() + "?list=events")
+	source, err := newRedisSource(u)
+	if err != nil {
+		t.Fatalf("newRedisSource returned error: %v", err)
+	}
+	defer source.close()
+	_, err = source.receive(context.Background(), 10, 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "WRONGPASS") || strings.Contains(err.Error(), "wrong@") {
+		t.Fatalf("expected the AUTH error without the password, got %v", err)
+	}
+}
+
+// TestNewRedisSourceValidatesURL verifies behavior for the related scenario.
+func TestNewRedisSourceValidatesURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		raw     string
+		wantErr string
+	}{
+		{raw: "redis://localhost", wantErr: "with stream=<key> or list=<key>"},
+		{raw: "redis://localhost?stream=a&list=b", wantErr: "with stream=<key> or list=<key>"},
+		{raw: "rediss://localhost/x?list=events", wantErr: `database "x" is not a number`},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.raw)
+		if _, err := newRedisSource(u); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.raw, tt.wantErr, err)
+		}
+	}
+}
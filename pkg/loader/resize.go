@@ -0,0 +1,227 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Shrink and Split ──────────────────────────────────────────────────────────
+
+// resizeWaitTimeout bounds each wait for shards to relocate or start.
+const resizeWaitTimeout = 10 * time.Minute
+
+// resizePlan is the -shrink-to or -split-to step run on a new alias
+// generation after it is loaded.
+type resizePlan struct {
+	// Kind is "shrink", "split", or "" for none.
+	Kind   string
+	Shards int
+}
+
+// resizePlanFor validates Options.ShrinkTo and Options.SplitTo.
+func resizePlanFor(opts Options, action dataAction) (resizePlan, error) {
+	switch {
+	case opts.ShrinkTo < 0 || opts.SplitTo < 0:
+		return resizePlan{}, fmt.Errorf("-shrink-to and -split-to must be >= 0")
+	case opts.ShrinkTo > 0 && opts.SplitTo > 0:
+		return resizePlan{}, fmt.Errorf("-shrink-to and -split-to cannot be combined")
+	case opts.ShrinkTo == 0 && opts.SplitTo == 0:
+		return resizePlan{}, nil
+	case !opts.AliasMode:
+		return resizePlan{}, fmt.Errorf("-shrink-to and -split-to replace the loaded index with a resized copy and need -alias")
+	case !action.requiresDataFile():
+		return resizePlan{}, fmt.Errorf("-shrink-to and -split-to resize a newly loaded index and need -add, -flush, or -delete")
+	case opts.ShrinkTo > 0:
+		return resizePlan{Kind: "shrink", Shards: opts.ShrinkTo}, nil
+	}
+	return resizePlan{Kind: "split", Shards: opts.SplitTo}, nil
+}
+
+// checkShardCount rejects a target shard count the resize API cannot reach
+// from shards primaries.
+func (p resizePlan) checkShardCount(shards int) error {
+	switch {
+	case p.Kind == "shrink" && (p.Shards >= shards || shards%p.Shards != 0):
+		return fmt.Errorf("-shrink-to %d must be a factor of the index's %d primary shards and smaller", p.Shards, shards)
+	case p.Kind == "split" && (p.Shards <= shards || p.Shards%shards != 0):
+		return fmt.Errorf("-split-to %d must be a multiple of the index's %d primary shards and larger", p.Shards, shards)
+	}
+	return nil
+}
+
+// resizeIndex copies source into target with the plan's shard count. The
+// source is write-blocked first and, for a shrink, gathered onto the node
+// holding its first primary without replicas. The target keeps the write
+// block, so it is read-only, and the source is left for the caller to delete.
+func resizeIndex(es *elasticsearch.Client, source, target string, plan resizePlan) error {
+	layout, _, err := getShardLayout(es, source)
+	if err != nil {
+		return fmt.Errorf("reading shard count: %w", err)
+	}
+	if err := plan.checkShardCount(layout.shards); err != nil {
+		return err
+	}
+	prepare := map[string]any{"index.blocks.write": true}
+	targetSettings := map[string]any{"index.number_of_shards": plan.Shards}
+	if plan.Kind == "shrink" {
+		replicas, err := getIndexSetting(es, source, "index.number_of_replicas")
+		if err != nil {
+			return fmt.Errorf("reading replica count: %w", err)
+		}
+		node, err := primaryShardNode(es, source)
+		if err != nil {
+			return err
+		}
+		prepare["index.number_of_replicas"] = 0
+		prepare["index.routing.allocation.require._name"] = node
+		targetSettings["index.routing.allocation.require._name"] = nil
+		if replicas != "" {
+			targetSettings["index.number_of_replicas"] = replicas
+		}
+		log.Info().Str("index", source).Str("node", node).Msg("Gathering shards on one node for shrink")
+	}
+	if err := putIndexSettings(es, source, prepare); err != nil {
+		return fmt.Errorf("preparing %s: %w", source, err)
+	}
+	if plan.Kind == "shrink" {
+		if err := waitForIndexHealth(es, source, "green"); err != nil {
+			return err
+		}
+	}
+	body, err := json.Marshal(map[string]any{"settings": targetSettings})
+	if err != nil {
+		return err
+	}
+	var res *esapi.Response
+	if plan.Kind == "shrink" {
+		res, err = es.Indices.Shrink(source, strings.NewReader(string(body)), target)
+	} else {
+		res, err = es.Indices.Split(source, strings.NewReader(string(body)), target)
+	}
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		responseBody, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s answered with status %d: %s", plan.Kind, res.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	if err := waitForIndexHealth(es, target, "yellow"); err != nil {
+		return err
+	}
+	log.Info().Str("index", source).Str("target", target).Int("shards", plan.Shards).Msgf("Index %s complete", plan.Kind)
+	return nil
+}
+
+// getIndexSetting returns one flat setting of index, or "" when unset.
+func getIndexSetting(es *elasticsearch.Client, index, name string) (string, error) {
+	res, err := es.Indices.GetSettings(
+		es.Indices.GetSettings.WithIndex(index),
+		es.Indices.GetSettings.WithName(name),
+		es.Indices.GetSettings.WithFlatSettings(true),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return "", fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var parsed map[string]struct {
+		Settings map[string]string `json:"settings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("parsing index settings: %w", err)
+	}
+	for _, settings := range parsed {
+		return settings.Settings[name], nil
+	}
+	return "", nil
+}
+
+// putIndexSettings updates the dynamic settings of index.
+func putIndexSettings(es *elasticsearch.Client, index string, settings map[string]any) error {
+	body, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	res, err := es.Indices.PutSettings(strings.NewReader(string(body)), es.Indices.PutSettings.WithIndex(index))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		responseBody, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	return nil
+}
+
+// primaryShardNode names the node holding the first primary shard of index.
+func primaryShardNode(es *elasticsearch.Client, index string) (string, error) {
+	res, err := es.Cat.Shards(
+		es.Cat.Shards.WithIndex(index),
+		es.Cat.Shards.WithH("shard", "prirep", "node"),
+		es.Cat.Shards.WithFormat("json"),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return "", fmt.Errorf("listing shards: status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var shards []struct {
+		Shard  string `json:"shard"`
+		PriRep string `json:"prirep"`
+		Node   string `json:"node"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&shards); err != nil {
+		return "", fmt.Errorf("parsing shard list: %w", err)
+	}
+	for _, shard := range shards {
+		if shard.PriRep == "p" && shard.Node != "" {
+			return shard.Node, nil
+		}
+	}
+	return "", fmt.Errorf("no started primary shard of %s", index)
+}
+
+// waitForIndexHealth waits until index reaches status with no shard still
+// relocating.
+func waitForIndexHealth(es *elasticsearch.Client, index, status string) error {
+	res, err := es.Cluster.Health(
+		es.Cluster.Health.WithIndex(index),
+		es.Cluster.Health.WithWaitForStatus(status),
+		es.Cluster.Health.WithWaitForNoRelocatingShards(true),
+		es.Cluster.Health.WithTimeout(resizeWaitTimeout),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	var health struct {
+		Status   string `json:"status"`
+		TimedOut bool   `json:"timed_out"`
+	}
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("waiting for %s to be %s: status %d: %s", index, status, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		return fmt.Errorf("parsing cluster health: %w", err)
+	}
+	if health.TimedOut {
+		return fmt.Errorf("%s did not reach %s within %s (status %s)", index, status, resizeWaitTimeout, health.Status)
+	}
+	return nil
+}
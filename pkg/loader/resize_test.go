@@ -0,0 +1,225 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestResizePlanFor verifies behavior for the related scenario.
+func TestResizePlanFor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    Options
+		action  dataAction
+		want    resizePlan
+		wantErr string
+	}{
+		{name: "off", opts: Options{}, action: dataActionAdd},
+		{name: "shrink", opts: Options{AliasMode: true, ShrinkTo: 1}, action: dataActionDelete, want: resizePlan{Kind: "shrink", Shards: 1}},
+		{name: "split", opts: Options{AliasMode: true, SplitTo: 6}, action: dataActionAdd, want: resizePlan{Kind: "split", Shards: 6}},
+		{name: "both", opts: Options{AliasMode: true, ShrinkTo: 1, SplitTo: 6}, action: dataActionAdd, wantErr: "cannot be combined"},
+		{name: "negative", opts: Options{AliasMode: true, ShrinkTo: -1}, action: dataActionAdd, wantErr: ">= 0"},
+		{name: "no alias", opts: Options{ShrinkTo: 1}, action: dataActionAdd, wantErr: "need -alias"},
+		{name: "no data", opts: Options{AliasMode: true, ShrinkTo: 1}, action: dataActionNone, wantErr: "newly loaded"},
+	}
+	for _, tt := range tests {
+		got, err := resizePlanFor(tt.opts, tt.action)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Fatalf("%s: expected %+v, got %+v (%v)", tt.name, tt.want, got, err)
+		}
+	}
+}
+
+// TestResizePlanCheckShardCount verifies behavior for the related scenario.
+func TestResizePlanCheckShardCount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		plan   resizePlan
+		shards int
+		ok     bool
+	}{
+		{plan: resizePlan{Kind: "shrink", Shards: 2}, shards: 4, ok: true},
+		{plan: resizePlan{Kind: "shrink", Shards: 3}, shards: 4},
+		{plan: resizePlan{Kind: "shrink", Shards: 4}, shards: 4},
+		{plan: resizePlan{Kind: "split", Shards: 6}, shards: 3, ok: true},
+		{plan: resizePlan{Kind: "split", Shards: 4}, shards: 3},
+		{plan: resizePlan{Kind: "split", Shards: 1}, shards: 1},
+	}
+	for _, tt := range tests {
+		if err := tt.plan.checkShardCount(tt.shards); (err == nil) != tt.ok {
+			t.Fatalf("%s to %d from %d shards: unexpected result %v", tt.plan.Kind, tt.plan.Shards, tt.shards, err)
+		}
+	}
+}
+
+// newResizeTestServer serves a new generation of alias "cards" with four
+// shards and records the operations a load and resize send, with the body
+// of the last one of each kind, or every settings update in turn.
+func newResizeTestServer(t *testing.T) (*httptest.Server, func() ([]string, map[string]string)) {
+	t.Helper()
+
+	var (
+		mu         sync.Mutex
+		existing   = map[string]bool{}
+		operations []string
+		bodies     = map[string]string{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		path := r.URL.Path
+		switch {
+		case r.Method == http.MethodGet && path == "/_alias/cards":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodHead && strings.HasPrefix(path, "/cards-"):
+			if existing[strings.TrimPrefix(path, "/")] {
+				w.WriteHeader(http.StatusOK)
+			} else {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPut && strings.Contains(path, "/_shrink/"):
+			source, target, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/_shrink/")
+			operations = append(operations, "shrink "+source+" "+target)
+			bodies["shrink"] = string(body)
+			existing[target] = true
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == http.MethodPut && strings.HasSuffix(path, "/_settings"):
+			operations = append(operations, "settings")
+			bodies["settings"] += string(body) + "\n"
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == http.MethodPut && strings.HasPrefix(path, "/cards-"):
+			operations = append(operations, "create")
+			existing[strings.TrimPrefix(path, "/")] = true
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == http.MethodGet && strings.Contains(path, "/_settings"):
+			index := strings.Split(strings.TrimPrefix(path, "/"), "/")[0]
+			_ = json.NewEncoder(w).Encode(map[string]any{index: map[string]any{"settings": map[string]string{
+				"index.number_of_shards":   "4",
+				"index.number_of_replicas": "1",
+			}}})
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/_cat/shards/"):
+			_, _ = w.Write([]byte(`[{"shard":"0","prirep":"r","node":"node-b"},{"shard":"0","prirep":"p","node":"node-a"}]`))
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/_cluster/health/"):
+			operations = append(operations, "health "+strings.TrimPrefix(path, "/_cluster/health/")+" "+r.URL.Query().Get("wait_for_status"))
+			_, _ = w.Write([]byte(`{"status":"green","timed_out":false}`))
+		case r.Method == http.MethodDelete && strings.HasPrefix(path, "/cards-"):
+			operations = append(operations, "delete "+strings.TrimPrefix(path, "/"))
+			delete(existing, strings.TrimPrefix(path, "/"))
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == http.MethodPost && path == "/_bulk":
+			operations = append(operations, "bulk")
+			_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+		case r.Method == http.MethodPost && path == "/_aliases":
+			operations = append(operations, "alias")
+			bodies["alias"] = string(body)
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() ([]string, map[string]string) {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), operations...), maps.Clone(bodies)
+	}
+}
+
+// TestRunShrinksNewGenerationBeforeAliasSwap verifies behavior for the related scenario.
+func TestRunShrinksNewGenerationBeforeAliasSwap(t *testing.T) {
+	t.Parallel()
+
+	server, recorded := newResizeTestServer(t)
+
+	result, err := Run(context.Background(), Options{
+		URL:         server.URL,
+		Index:       "cards",
+		DataFile:    writeTestDataFile(t, "cards.ndjson", "{\"name\":\"a\"}\n"),
+		DeleteIndex: true,
+		SyncManaged: true,
+		AliasMode:   true,
+		ShrinkTo:    2,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	operations, bodies := recorded()
+	loaded := ""
+	for _, op := range operations {
+		if strings.HasPrefix(op, "shrink ") {
+			loaded = strings.Fields(op)[1]
+		}
+	}
+	want := []string{
+		"create",
+		"bulk",
+		"settings",
+		"health " + loaded + " green",
+		"shrink " + loaded + " " + result.CreatedIndex,
+		"health " + result.CreatedIndex + " yellow",
+		"delete " + loaded,
+		"alias",
+	}
+	if loaded == "" || loaded == result.CreatedIndex || strings.Join(operations, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected operations\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(operations, "\n"))
+	}
+	if !strings.Contains(bodies["settings"], `"index.routing.allocation.require._name":"node-a"`) || !strings.Contains(bodies["settings"], `"index.blocks.write":true`) {
+		t.Fatalf("expected the loaded index to be gathered on node-a and write-blocked, got %s", bodies["settings"])
+	}
+	if bodies["shrink"] != `{"settings":{"index.number_of_replicas":"1","index.number_of_shards":2,"index.routing.allocation.require._name":null}}` {
+		t.Fatalf("unexpected shrink body %s", bodies["shrink"])
+	}
+	if !strings.Contains(bodies["alias"], `"index":"`+result.CreatedIndex+`"`) {
+		t.Fatalf("expected the alias to move to the shrunk index, got %s", bodies["alias"])
+	}
+}
+
+// TestRunLoadDurabilityRestoresBeforeResize verifies behavior for the related scenario.
+func TestRunLoadDurabilityRestoresBeforeResize(t *testing.T) {
+	t.Parallel()
+
+	server, recorded := newResizeTestServer(t)
+	_, err := Run(context.Background(), Options{
+		URL:            server.URL,
+		Index:          "cards",
+		DataFile:       writeTestDataFile(t, "cards.ndjson", "{\"name\":\"a\"}\n"),
+		DeleteIndex:    true,
+		SyncManaged:    true,
+		AliasMode:      true,
+		ShrinkTo:       2,
+		LoadDurability: loadDurabilityAsync,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	operations, bodies := recorded()
+	shrink := slices.IndexFunc(operations, func(op string) bool { return strings.HasPrefix(op, "shrink ") })
+	if shrink < 0 || !slices.Equal(operations[:shrink], []string{"create", "settings", "bulk", "settings", "settings", operations[shrink-1]}) {
+		t.Fatalf("expected durability to be restored before the shrink, got\n%s", strings.Join(operations, "\n"))
+	}
+	settings := strings.Split(strings.TrimSpace(bodies["settings"]), "\n")
+	if len(settings) != 3 || settings[0] != `{"index.translog.durability":"async"}` || settings[1] != `{"index.translog.durability":null}` {
+		t.Fatalf("expected async durability to be removed before the index is prepared for the shrink, got %q", settings)
+	}
+}
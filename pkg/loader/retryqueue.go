@@ -0,0 +1,246 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Retry Queue ───────────────────────────────────────────────────────────────
+
+// retryQueueReplaySuffix names the copy of the queue a retry is replaying,
+// so documents that fail again can be appended to a fresh queue meanwhile.
+const retryQueueReplaySuffix = ".replaying"
+
+// retryQueue appends the bulk action and source lines of documents whose
+// transient failures outlasted every retry to -retry-queue, ready to be
+// sent as they are once the cluster recovers.
+type retryQueue struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	// documents counts documents queued this run.
+	documents int
+}
+
+// openRetryQueue opens path for appending, so repeated runs add to what an
+// earlier run left. It returns nil for an empty path.
+func openRetryQueue(path string) (*retryQueue, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &retryQueue{path: path, file: file}, nil
+}
+
+// add appends the action and source lines of docs documents. Unlike the
+// error log, a failed write fails the load, since the documents would
+// otherwise be lost.
+func (q *retryQueue) add(lines []byte, docs int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.file.Write(lines); err != nil {
+		fatal().Err(err).Str("path", q.path).Msg("Error writing retry queue")
+	}
+	q.documents += docs
+}
+
+// close closes the file and returns how many documents this run queued. A
+// queue left empty is removed, so no file means nothing to retry.
+func (q *retryQueue) close() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if info, err := q.file.Stat(); err == nil && info.Size() == 0 {
+		_ = os.Remove(q.path)
+	}
+	_ = q.file.Close()
+	return q.documents
+}
+
+// RetryOptions configures Retry.
+type RetryOptions struct {
+	// Queue is the -retry-queue file a load wrote.
+	Queue string
+	// Load names the cluster and bulk settings to replay with, as for Run;
+	// data files, transforms, and index management are ignored because the
+	// queue holds finished bulk lines.
+	Load Options
+}
+
+// Retry replays the documents a load left in a retry queue. The queue is
+// first renamed aside, and documents that fail transiently again are queued
+// afresh, so Retry can simply be run again until the queue stays empty. An
+// interrupted replay is resumed by the next Retry.
+func Retry(ctx context.Context, opts RetryOptions) (result Result, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	load := opts.Load
+	if load.URL == "" {
+		load.URL = "http://localhost:9200"
+	}
+	if load.BatchSize <= 0 {
+		load.BatchSize = 1000
+	}
+	if strings.TrimSpace(opts.Queue) == "" {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating retry options", Err: fmt.Errorf("-retry-queue is required")}
+	}
+	if len(load.Clusters) > 1 {
+		return result, &RunError{Kind: ErrInvalidOptions, Op: "validating retry options", Err: fmt.Errorf("retry replays to one cluster; pass a single -url")}
+	}
+
+	replaying := opts.Queue + retryQueueReplaySuffix
+	if _, statErr := os.Stat(replaying); statErr == nil {
+		log.Warn().Str("path", replaying).Msg("Resuming an interrupted retry")
+	} else if renameErr := os.Rename(opts.Queue, replaying); renameErr != nil {
+		if errors.Is(renameErr, os.ErrNotExist) {
+			log.Info().Str("path", opts.Queue).Msg("Retry queue is empty")
+			return result, nil
+		}
+		return result, &RunError{Kind: ErrLoaderExecution, Op: "claiming retry queue", Err: renameErr}
+	}
+
+	var info ClusterInfo
+	if load.Preflight {
+		if info, err = preflightCluster(ctx, load); err != nil {
+			return result, err
+		}
+	}
+	cfg := elasticsearchConfig(load)
+	if major := legacyMajor(info, load.DocType); major > 0 {
+		cfg.Transport = &compatTransport{base: cfg.Transport, major: major}
+	}
+	es, err := elasticsearch.NewClient(cfg)
+	if err != nil {
+		return result, &RunError{Kind: ErrLoaderExecution, Op: "creating Elasticsearch client", Err: err}
+	}
+
+	file, err := os.Open(replaying)
+	if err != nil {
+		return result, &RunError{Kind: ErrLoaderExecution, Op: "opening retry queue", Err: err}
+	}
+	defer file.Close()
+	queue, err := openRetryQueue(opts.Queue)
+	if err != nil {
+		return result, &RunError{Kind: ErrLoaderExecution, Op: "opening retry queue", Err: err}
+	}
+	defer func() {
+		if queued := queue.close(); queued > 0 {
+			log.Warn().Str("path", opts.Queue).Int("queued", queued).Msg("Documents failed again and were queued for a later retry")
+		}
+	}()
+	errorLog, err := openErrorLog(load.ErrorLog)
+	if err != nil {
+		return result, &RunError{Kind: ErrLoaderExecution, Op: "opening error log", Err: err}
+	}
+	defer errorLog.close()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			switch typed := recovered.(type) {
+			case *RunError:
+				err = typed
+			case error:
+				err = &RunError{Kind: ErrLoaderExecution, Op: "panic", Err: typed}
+			default:
+				panic(recovered)
+			}
+		}
+	}()
+
+	settings := bulkSettings{
+		RetryAttempts:    load.BulkRetryAttempts,
+		RetryBackoffBase: load.BulkRetryBackoffBase,
+		RetryBackoffMax:  load.BulkRetryBackoffMax,
+		SlowThreshold:    load.SlowBatchThreshold,
+		Metadata:         true,
+		PreserveIndex:    true,
+		ErrorLog:         errorLog,
+		RetryQueue:       queue,
+		Worker:           1,
+	}
+	started := time.Now()
+	indices := make(indexTallies)
+	payload := newBulkBody()
+	defer payload.release()
+	docs, batch := 0, 0
+	send := func() {
+		batch++
+		result.DocumentsProcessed += docs
+		sent := sendBulkPayload(ctx, es, load.Index, payload.Bytes(), docs, batch, result.DocumentsProcessed, 0, settings)
+		result.DocumentsSucceeded += sent.Succeeded
+		result.DocumentsFailed += sent.Failed
+		result.DocumentsQueued += sent.Queued
+		result.BytesSent += int64(payload.Len())
+		indices.merge(sent.Indices)
+		payload.Reset()
+		docs = 0
+	}
+	reader := bufio.NewReader(file)
+	for {
+		action, readErr := readRetryQueueLine(reader)
+		if readErr == io.EOF {
+			break
+		}
+		source, sourceErr := readRetryQueueLine(reader)
+		if readErr != nil || sourceErr != nil {
+			return result, &RunError{Kind: ErrLoaderExecution, Op: "reading retry queue", Err: errors.Join(readErr, sourceErr)}
+		}
+		payload.Write(action)
+		payload.Write(source)
+		if docs++; docs >= load.BatchSize {
+			send()
+		}
+	}
+	if docs > 0 {
+		send()
+	}
+	result.LoadDuration = time.Since(started)
+	result.Indices = indices.sorted()
+
+	_ = file.Close()
+	if err := os.Remove(replaying); err != nil {
+		return result, &RunError{Kind: ErrLoaderExecution, Op: "removing replayed retry queue", Err: err}
+	}
+	log.Info().
+		Int("processed", result.DocumentsProcessed).
+		Int("succeeded", result.DocumentsSucceeded).
+		Int("failed", result.DocumentsFailed).
+		Int("queued", result.DocumentsQueued).
+		Float64("total_time", result.LoadDuration.Seconds()).
+		Msg("Retry completed")
+	logIndexResults(result.Indices)
+	return result, nil
+}
+
+// readRetryQueueLine reads one newline-terminated line; a final line cut
+// short by an interrupted write is an error rather than a document.
+func readRetryQueueLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		return nil, fmt.Errorf("retry queue ends in a partial line")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil, fmt.Errorf("retry queue holds an empty line")
+	}
+	return line, nil
+}
@@ -0,0 +1,120 @@
+package loader
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRunQueuesExhaustedRetriesAndRetryReplaysThem verifies behavior for the related scenario.
+func TestRunQueuesExhaustedRetriesAndRetryReplaysThem(t *testing.T) {
+	t.Parallel()
+
+	var recovered atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.Method != http.MethodPost || r.URL.Path != "/_bulk" {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		scanner := bufio.NewScanner(r.Body)
+		var items []string
+		for scanner.Scan() {
+			scanner.Scan()
+			switch source := scanner.Text(); {
+			case recovered.Load():
+				items = append(items, `{"index":{"_index":"cards","status":201}}`)
+			case strings.Contains(source, `"down"`):
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"error":"unavailable","status":503}`))
+				return
+			case strings.Contains(source, `"busy"`):
+				items = append(items, `{"index":{"_index":"cards","status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}}`)
+			case strings.Contains(source, `"bad"`):
+				items = append(items, `{"index":{"_index":"cards","status":400,"error":{"type":"mapper_parsing_exception","reason":"bad"}}}`)
+			default:
+				items = append(items, `{"index":{"_index":"cards","status":201}}`)
+			}
+		}
+		_, _ = w.Write([]byte(`{"errors":true,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "cards.ndjson")
+	data := "{\"code\":\"a\"}\n{\"code\":\"busy\"}\n{\"code\":\"bad\"}\n{\"code\":\"down\"}\n{\"code\":\"b\"}\n"
+	if err := os.WriteFile(dataFile, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	queue := filepath.Join(dir, "retry.ndjson")
+	load := Options{
+		URL:                  server.URL,
+		Index:                "cards",
+		AddToIndex:           true,
+		BatchSize:            3,
+		BulkRetryAttempts:    1,
+		BulkRetryBackoffBase: time.Millisecond,
+		RetryQueue:           queue,
+	}
+
+	opts := load
+	opts.DataFile = dataFile
+	result, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsSucceeded != 1 || result.DocumentsFailed != 1 || result.DocumentsQueued != 3 {
+		t.Fatalf("expected 1 succeeded, 1 failed, and 3 queued, got %+v", result)
+	}
+	content, err := os.ReadFile(queue)
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	want := []string{
+		`{"index":{"_index":"cards"}}`, `{"code":"busy"}`,
+		`{"index":{"_index":"cards"}}`, `{"code":"down"}`,
+		`{"index":{"_index":"cards"}}`, `{"code":"b"}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected queue %q, got %q", want, lines)
+	}
+
+	// Still down: the documents go back into a fresh queue.
+	result, err = Retry(context.Background(), RetryOptions{Queue: queue, Load: load})
+	if err != nil {
+		t.Fatalf("Retry returned error: %v", err)
+	}
+	if result.DocumentsProcessed != 3 || result.DocumentsQueued != 3 {
+		t.Fatalf("expected 3 documents queued again, got %+v", result)
+	}
+	if _, err := os.Stat(queue + retryQueueReplaySuffix); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the replayed queue to be removed, got %v", err)
+	}
+
+	recovered.Store(true)
+	result, err = Retry(context.Background(), RetryOptions{Queue: queue, Load: load})
+	if err != nil {
+		t.Fatalf("Retry returned error: %v", err)
+	}
+	if result.DocumentsSucceeded != 3 || result.DocumentsQueued != 0 {
+		t.Fatalf("expected 3 documents replayed, got %+v", result)
+	}
+	if _, err := os.Stat(queue); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the drained queue to be removed, got %v", err)
+	}
+
+	result, err = Retry(context.Background(), RetryOptions{Queue: queue, Load: load})
+	if err != nil || result.DocumentsProcessed != 0 {
+		t.Fatalf("expected an empty retry, got %+v, %v", result, err)
+	}
+}
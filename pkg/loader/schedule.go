@@ -0,0 +1,264 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Scheduled Runs ────────────────────────────────────────────────────────────
+
+// cronSearchLimit bounds the search for the next matching minute so an
+// impossible expression such as "0 0 30 2 *" fails instead of looping forever.
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// cronDescriptors maps the supported @-shortcuts to five-field expressions.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronMonthNames and cronDayNames allow three-letter names in month and
+// day-of-week fields.
+var (
+	cronMonthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	cronDayNames   = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// cronSchedule is a parsed five-field cron expression. Each field is a bitmask
+// of allowed values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record unrestricted day fields; when both day fields
+	// are restricted cron matches either one.
+	domStar, dowStar bool
+	location         *time.Location
+}
+
+// parseCronSchedule parses "min hour dom month dow", an @-descriptor, and an
+// optional leading "CRON_TZ=<zone>" (or "TZ=<zone>") to evaluate in that zone.
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	location := time.Local
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		zone, rest, _ := strings.Cut(expr, " ")
+		_, name, _ := strings.Cut(zone, "=")
+		loaded, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("loading schedule time zone %q: %w", name, err)
+		}
+		location = loaded
+		expr = strings.TrimSpace(rest)
+	}
+	if expanded, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = expanded
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must have 5 fields (minute hour day-of-month month day-of-week) or be an @descriptor", expr)
+	}
+	schedule := &cronSchedule{location: location}
+	var err error
+	if schedule.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute field: %w", err)
+	}
+	if schedule.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour field: %w", err)
+	}
+	if schedule.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day-of-month field: %w", err)
+	}
+	if schedule.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("month field: %w", err)
+	}
+	if schedule.dow, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return nil, fmt.Errorf("day-of-week field: %w", err)
+	}
+	// 7 is an alias for Sunday.
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow = schedule.dow&^(1<<7) | 1
+	}
+	schedule.domStar = strings.HasPrefix(fields[2], "*")
+	schedule.dowStar = strings.HasPrefix(fields[4], "*")
+	return schedule, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges, and steps.
+func parseCronField(field string, low, high int, names map[string]int) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			parsed, err := strconv.Atoi(stepPart)
+			if err != nil || parsed <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = parsed
+		}
+
+		start, end := low, high
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			first, last, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(first, names); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(last, names); err != nil {
+				return 0, err
+			}
+		default:
+			value, err := parseCronValue(rangePart, names)
+			if err != nil {
+				return 0, err
+			}
+			start = value
+			if !hasStep {
+				end = value
+			}
+		}
+		if start < low || end > high || start > end {
+			return 0, fmt.Errorf("range %q outside %d-%d", part, low, high)
+		}
+		for value := start; value <= end; value += step {
+			mask |= 1 << uint(value)
+		}
+	}
+	return mask, nil
+}
+
+// parseCronValue parses a number or a case-insensitive name.
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if named, ok := names[strings.ToLower(value)]; ok {
+		return named, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return parsed, nil
+}
+
+// matchesDay applies cron's day-of-month/day-of-week OR rule.
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// next returns the first matching minute strictly after after, or the zero
+// time when nothing matches within cronSearchLimit.
+func (s *cronSchedule) next(after time.Time) time.Time {
+	t := after.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// ScheduleOptions configures repeated runs on a cron schedule.
+type ScheduleOptions struct {
+	// Expr is a five-field cron expression or @-descriptor.
+	Expr string
+	// JobsIndex records one job per run in this Elasticsearch index; empty
+	// disables job history.
+	JobsIndex string
+	// Load is the Run configuration executed at every scheduled time.
+	Load Options
+}
+
+// Schedule executes Run each time Expr matches until ctx is cancelled. A
+// failed run is logged and the schedule continues; a run that overlaps later
+// slots skips them rather than queuing catch-up runs.
+func Schedule(ctx context.Context, opts ScheduleOptions) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	schedule, err := parseCronSchedule(opts.Expr)
+	if err != nil {
+		return &RunError{Kind: ErrInvalidOptions, Op: "parsing schedule", Err: err}
+	}
+	if schedule.next(time.Now()).IsZero() {
+		return &RunError{Kind: ErrInvalidOptions, Op: "parsing schedule", Err: fmt.Errorf("schedule %q never matches", opts.Expr)}
+	}
+	if opts.Load.Tail {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating schedule options", Err: fmt.Errorf("-tail cannot be combined with -schedule")}
+	}
+
+	jobs, err := openJobStore(opts.Load, opts.JobsIndex)
+	if err != nil {
+		return err
+	}
+	recoverInterruptedJobs(jobs, JobModeSchedule, jobHost())
+
+	stopWatchdog := notifyReady("running on schedule " + opts.Expr)
+	defer stopWatchdog()
+	for {
+		next := schedule.next(time.Now())
+		log.Info().Str("schedule", opts.Expr).Time("next_run", next).Msg("Waiting for next scheduled run")
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Info().Str("schedule", opts.Expr).Msg("Schedule stopped")
+			return nil
+		case <-timer.C:
+		}
+
+		started := time.Now()
+		job, err := newJob(JobModeSchedule, "schedule:"+opts.Expr, opts.Load)
+		if err != nil {
+			return &RunError{Kind: ErrLoaderExecution, Op: "creating job record", Err: err}
+		}
+		_, result, runErr := runJob(ctx, jobs, job, opts.Load, Run)
+		if errors.Is(runErr, ErrInvalidOptions) {
+			return runErr
+		}
+		if runErr != nil {
+			if ctx.Err() != nil {
+				log.Warn().Err(runErr).Msg("Scheduled run interrupted")
+				return nil
+			}
+			log.Error().Err(runErr).Msg("Scheduled run failed")
+			continue
+		}
+		log.Info().
+			Int("succeeded", result.DocumentsSucceeded).
+			Int("failed", result.DocumentsFailed).
+			Float64("total_time", time.Since(started).Seconds()).
+			Msg("Scheduled run completed")
+	}
+}
@@ -0,0 +1,60 @@
+package loader
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCronScheduleNext verifies behavior for the related scenario.
+func TestCronScheduleNext(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2024, time.January, 31, 22, 17, 30, 0, time.UTC)
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{name: "every minute", expr: "* * * * *", want: time.Date(2024, time.January, 31, 22, 18, 0, 0, time.UTC)},
+		{name: "step minutes", expr: "*/15 * * * *", want: time.Date(2024, time.January, 31, 22, 30, 0, 0, time.UTC)},
+		{name: "nightly", expr: "30 2 * * *", want: time.Date(2024, time.February, 1, 2, 30, 0, 0, time.UTC)},
+		{name: "daily descriptor", expr: "@daily", want: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "weekday names", expr: "0 9 * * mon-fri", want: time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC)},
+		{name: "sunday as seven", expr: "0 0 * * 7", want: time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", expr: "0 0 29 feb *", want: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "day-of-month or day-of-week", expr: "0 0 15 * sat", want: time.Date(2024, time.February, 3, 0, 0, 0, 0, time.UTC)},
+		{name: "list and range", expr: "5,10 23 1-3 * *", want: time.Date(2024, time.February, 1, 23, 5, 0, 0, time.UTC)},
+		{name: "time zone prefix", expr: "CRON_TZ=America/New_York 0 6 * * *", want: time.Date(2024, time.February, 1, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			schedule, err := parseCronSchedule(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCronSchedule returned error: %v", err)
+			}
+			if got := schedule.next(base); !got.Equal(tt.want) {
+				t.Fatalf("next(%s) = %s, want %s", base, got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+// TestParseCronScheduleRejectsInvalidExpressions verifies behavior for the related scenario.
+func TestParseCronScheduleRejectsInvalidExpressions(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *", "CRON_TZ=Nowhere/Land * * * * *"} {
+		if _, err := parseCronSchedule(expr); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+
+	schedule, err := parseCronSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("parseCronSchedule returned error: %v", err)
+	}
+	if next := schedule.next(time.Now()); !next.IsZero() {
+		t.Fatalf("expected impossible schedule to never match, got %s", next)
+	}
+}
@@ -0,0 +1,227 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	starlarkjson "go.starlark.net/lib/json"
+	starlarkmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// ─── Script Transforms ─────────────────────────────────────────────────────────
+
+// scriptTransformFunc is the function every -script-transform file defines.
+const scriptTransformFunc = "transform"
+
+// scriptFileOptions enables the Starlark dialect a Python user expects:
+// while loops, sets, top-level if/for, and reassignable globals.
+var scriptFileOptions = &syntax.FileOptions{
+	Set:             true,
+	While:           true,
+	TopLevelControl: true,
+	GlobalReassign:  true,
+}
+
+// newScriptTransform loads the Starlark file at path and returns a transform
+// calling its transform(doc) function once per document. The file's top
+// level runs once, so it can build lookup tables or compile constants.
+//
+// transform receives the document as a mutable dict and returns a dict to
+// index, a list of dicts to split the document, or None to drop it. The
+// json and math modules are predeclared; print writes to the debug log and
+// fail aborts the load.
+func newScriptTransform(path string) (docTransform, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading -script-transform: %w", err)
+	}
+	thread := &starlark.Thread{
+		Name: path,
+		Print: func(_ *starlark.Thread, msg string) {
+			log.Debug().Str("script", path).Str("output", msg).Msg("Script transform printed")
+		},
+	}
+	predeclared := starlark.StringDict{
+		"json": starlarkjson.Module,
+		"math": starlarkmath.Module,
+	}
+	globals, err := starlark.ExecFileOptions(scriptFileOptions, thread, path, src, predeclared)
+	if err != nil {
+		return nil, fmt.Errorf("loading -script-transform: %w", scriptError(err))
+	}
+	fn, ok := globals[scriptTransformFunc].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("-script-transform %s must define %s(doc)", path, scriptTransformFunc)
+	}
+	return func(doc map[string]interface{}) ([]map[string]interface{}, error) {
+		value, err := toStarlark(doc)
+		if err != nil {
+			return nil, err
+		}
+		result, err := starlark.Call(thread, fn, starlark.Tuple{value}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("-script-transform: %w", scriptError(err))
+		}
+		return scriptResultDocuments(result)
+	}, nil
+}
+
+// scriptError keeps the Starlark backtrace, which names the failing line.
+func scriptError(err error) error {
+	if evalErr, ok := err.(*starlark.EvalError); ok {
+		return fmt.Errorf("%s", evalErr.Backtrace())
+	}
+	return err
+}
+
+// scriptResultDocuments interprets transform's return value.
+func scriptResultDocuments(result starlark.Value) ([]map[string]interface{}, error) {
+	if result == starlark.None {
+		return nil, nil
+	}
+	if _, ok := result.(*starlark.Dict); ok {
+		doc, err := fromStarlarkDocument(result)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]interface{}{doc}, nil
+	}
+	if list, ok := result.(starlark.Indexable); ok && result.Type() != "string" {
+		docs := make([]map[string]interface{}, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			doc, err := fromStarlarkDocument(list.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+		return docs, nil
+	}
+	return nil, fmt.Errorf("-script-transform %s() must return a dict, a list of dicts, or None, not %s", scriptTransformFunc, result.Type())
+}
+
+// fromStarlarkDocument converts one returned document.
+func fromStarlarkDocument(value starlark.Value) (map[string]interface{}, error) {
+	converted, err := fromStarlark(value)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := converted.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("-script-transform returned a %s where a dict was expected", value.Type())
+	}
+	return doc, nil
+}
+
+// toStarlark converts decoded JSON into Starlark values. Whole numbers that
+// a float64 represents exactly become ints, so scripts can index and format
+// them naturally; object keys are inserted in sorted order.
+func toStarlark(value interface{}) (starlark.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(v), nil
+	case string:
+		return starlark.String(v), nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= 1<<53 {
+			return starlark.MakeInt64(int64(v)), nil
+		}
+		return starlark.Float(v), nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return starlark.MakeInt64(n), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Float(f), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case int64:
+		return starlark.MakeInt64(v), nil
+	case uint:
+		return starlark.MakeUint(v), nil
+	case []interface{}:
+		items := make([]starlark.Value, len(v))
+		for i, item := range v {
+			converted, err := toStarlark(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = converted
+		}
+		return starlark.NewList(items), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		dict := starlark.NewDict(len(v))
+		for _, key := range keys {
+			converted, err := toStarlark(v[key])
+			if err != nil {
+				return nil, err
+			}
+			if err := dict.SetKey(starlark.String(key), converted); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("cannot pass %T to -script-transform", value)
+}
+
+// fromStarlark converts a Starlark value back into JSON-compatible Go values.
+func fromStarlark(value starlark.Value) (interface{}, error) {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Int:
+		if n, ok := v.Int64(); ok {
+			return n, nil
+		}
+		return v.Float(), nil
+	case starlark.Float:
+		return float64(v), nil
+	case *starlark.List, starlark.Tuple:
+		indexable := v.(starlark.Indexable)
+		items := make([]interface{}, indexable.Len())
+		for i := range items {
+			converted, err := fromStarlark(indexable.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = converted
+		}
+		return items, nil
+	case *starlark.Dict:
+		doc := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			key, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("-script-transform returned a dict key of type %s; keys must be strings", item[0].Type())
+			}
+			converted, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			doc[string(key)] = converted
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("-script-transform returned a %s, which has no JSON form", value.Type())
+}
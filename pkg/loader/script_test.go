@@ -0,0 +1,110 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestScript writes a -script-transform file for a test.
+func writeTestScript(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "transform.star")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	return path
+}
+
+// TestScriptTransformContract verifies behavior for the related scenario.
+func TestScriptTransformContract(t *testing.T) {
+	t.Parallel()
+
+	step, err := newScriptTransform(writeTestScript(t, `
+TIERS = {"gold": 3, "silver": 2}
+
+def transform(doc):
+    if doc.get("status") == "test":
+        return None
+    doc["tier_rank"] = TIERS.get(doc.get("tier"), 0)
+    doc["count_type"] = type(doc.get("count"))
+    doc["meta"] = json.decode('{"from":"script"}')
+    return [dict(doc, sku=sku) for sku in doc.pop("skus", [])] or doc
+`))
+	if err != nil {
+		t.Fatalf("newScriptTransform returned error: %v", err)
+	}
+
+	tests := []struct {
+		doc  string
+		want []string
+	}{
+		{
+			doc:  `{"tier":"gold","count":3,"ratio":0.5,"tags":["a",null,true]}`,
+			want: []string{`{"count":3,"count_type":"int","meta":{"from":"script"},"ratio":0.5,"tags":["a",null,true],"tier":"gold","tier_rank":3}`},
+		},
+		{
+			doc: `{"tier":"bronze","skus":["x","y"]}`,
+			want: []string{
+				`{"count_type":"NoneType","meta":{"from":"script"},"sku":"x","tier":"bronze","tier_rank":0}`,
+				`{"count_type":"NoneType","meta":{"from":"script"},"sku":"y","tier":"bronze","tier_rank":0}`,
+			},
+		},
+		{doc: `{"status":"test"}`},
+	}
+	for _, tt := range tests {
+		out, err := step(decodeTestDocument(t, tt.doc))
+		if err != nil {
+			t.Fatalf("transform(%s) returned error: %v", tt.doc, err)
+		}
+		got := make([]string, 0, len(out))
+		for _, doc := range out {
+			got = append(got, encodeTestDocument(t, doc))
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Fatalf("transform(%s) = %q, want %q", tt.doc, got, tt.want)
+		}
+	}
+}
+
+// TestScriptTransformErrors verifies behavior for the related scenario.
+func TestScriptTransformErrors(t *testing.T) {
+	t.Parallel()
+
+	loadFailures := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{name: "syntax", script: "def transform(doc)\n    return doc\n", want: "loading -script-transform"},
+		{name: "missing function", script: "x = 1\n", want: "must define transform(doc)"},
+		{name: "top-level error", script: "x = 1 // 0\n", want: "division by zero"},
+	}
+	for _, tt := range loadFailures {
+		if _, err := newScriptTransform(writeTestScript(t, tt.script)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error mentioning %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	callFailures := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{name: "fail", script: "def transform(doc):\n    fail(\"bad record\")\n", want: "bad record"},
+		{name: "wrong return", script: "def transform(doc):\n    return \"doc\"\n", want: "must return a dict"},
+		{name: "list of non-dicts", script: "def transform(doc):\n    return [1]\n", want: "where a dict was expected"},
+		{name: "non-string key", script: "def transform(doc):\n    return {1: \"x\"}\n", want: "keys must be strings"},
+		{name: "unsupported value", script: "def transform(doc):\n    return {\"f\": transform}\n", want: "no JSON form"},
+	}
+	for _, tt := range callFailures {
+		step, err := newScriptTransform(writeTestScript(t, tt.script))
+		if err != nil {
+			t.Fatalf("%s: newScriptTransform returned error: %v", tt.name, err)
+		}
+		if _, err := step(map[string]interface{}{"id": "1"}); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error mentioning %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
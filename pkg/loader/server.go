@@ -0,0 +1,582 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── HTTP Ingestion Server ─────────────────────────────────────────────────────
+
+const (
+	// defaultServeAddr is the listen address used when ServerOptions.Addr is empty.
+	defaultServeAddr = "localhost:8080"
+	// defaultServeMaxUpload caps a single upload body.
+	defaultServeMaxUpload = 1 << 30
+	// defaultServeQueueSize bounds queued jobs before submissions are rejected.
+	defaultServeQueueSize = 64
+	// serveShutdownTimeout bounds graceful HTTP shutdown.
+	serveShutdownTimeout = 10 * time.Second
+)
+
+// ServerOptions configures the `serve` HTTP ingestion gateway.
+type ServerOptions struct {
+	// Addr is the HTTP listen address; defaults to localhost:8080.
+	Addr string
+	// SpoolDir stores uploads until their job runs; defaults to os.TempDir().
+	SpoolDir string
+	// Token, when set, is required as "Authorization: Bearer <token>".
+	Token string
+	// Insecure allows listening beyond loopback without a Token.
+	Insecure bool
+	// MaxUploadBytes caps one upload; defaults to 1 GiB.
+	MaxUploadBytes int64
+	// QueueSize bounds jobs waiting to run; defaults to 64.
+	QueueSize int
+	// JobsIndex persists job records in this Elasticsearch index; empty keeps
+	// them in memory only.
+	JobsIndex string
+	// GRPCAddr, when set, also serves the streaming ingestion gRPC service.
+	GRPCAddr string
+	// GRPCFlushInterval bounds how long a partial batch waits on a gRPC
+	// stream; defaults to 5s.
+	GRPCFlushInterval time.Duration
+	// Load provides connection, auth, and tuning defaults for every job. Index,
+	// DataFile, and the data action come from each request.
+	Load Options
+}
+
+// queuedJob pairs a job record with the load configuration it runs.
+type queuedJob struct {
+	job  Job
+	load Options
+}
+
+// server groups HTTP handlers, the job store, and the run queue.
+type server struct {
+	opts  ServerOptions
+	store jobStore
+	queue chan queuedJob
+	// run executes a load; it is a field so tests can observe queued options.
+	run func(ctx context.Context, opts Options) (Result, error)
+}
+
+// newServer applies option defaults and builds a server around store.
+func newServer(opts ServerOptions, store jobStore) *server {
+	if opts.Addr == "" {
+		opts.Addr = defaultServeAddr
+	}
+	if opts.SpoolDir == "" {
+		opts.SpoolDir = os.TempDir()
+	}
+	if opts.MaxUploadBytes <= 0 {
+		opts.MaxUploadBytes = defaultServeMaxUpload
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultServeQueueSize
+	}
+	return &server{
+		opts:  opts,
+		store: store,
+		queue: make(chan queuedJob, opts.QueueSize),
+		run:   Run,
+	}
+}
+
+// Serve runs the HTTP ingestion gateway until ctx is cancelled. Jobs run one
+// at a time in submission order.
+func Serve(ctx context.Context, opts ServerOptions) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if flag := uploadFormatOption(opts.Load); flag != "" {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating serve options", Err: fmt.Errorf("%s cannot be combined with serve, which spools every upload as a JSON array", flag)}
+	}
+	var store jobStore = newMemoryJobStore()
+	persistent, err := openJobStore(opts.Load, opts.JobsIndex)
+	if err != nil {
+		return err
+	}
+	if persistent != nil {
+		store = persistent
+		recoverInterruptedJobs(store, JobModeServe, jobHost())
+	}
+	srv := newServer(opts, store)
+	if err := os.MkdirAll(srv.opts.SpoolDir, 0o755); err != nil {
+		return &RunError{Kind: ErrLoaderExecution, Op: "creating spool directory", Err: err}
+	}
+	listener, err := listen(systemdSocketHTTP, srv.opts.Addr)
+	if err != nil {
+		return &RunError{Kind: ErrLoaderExecution, Op: "listening for HTTP", Err: err}
+	}
+	if err := srv.checkExposure(listener, "-listen"); err != nil {
+		_ = listener.Close()
+		return err
+	}
+	if srv.opts.GRPCAddr == "" {
+		return srv.serve(ctx, listener)
+	}
+
+	ingest, err := newGRPCIngest(srv.opts)
+	if err != nil {
+		_ = listener.Close()
+		return &RunError{Kind: ErrInvalidOptions, Op: "configuring gRPC ingestion", Err: err}
+	}
+	grpcListener, err := listen(systemdSocketGRPC, srv.opts.GRPCAddr)
+	if err != nil {
+		_ = listener.Close()
+		return &RunError{Kind: ErrLoaderExecution, Op: "listening for gRPC", Err: err}
+	}
+	if err := srv.checkExposure(grpcListener, "-grpc-listen"); err != nil {
+		_ = listener.Close()
+		_ = grpcListener.Close()
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	grpcDone := make(chan error, 1)
+	go func() {
+		grpcDone <- ingest.serveGRPC(ctx, grpcListener)
+		// A failed gRPC listener stops the HTTP side too.
+		cancel()
+	}()
+	httpErr := srv.serve(ctx, listener)
+	cancel()
+	if grpcErr := <-grpcDone; grpcErr != nil && httpErr == nil {
+		return &RunError{Kind: ErrLoaderExecution, Op: "serving gRPC", Err: grpcErr}
+	}
+	return httpErr
+}
+
+// checkExposure refuses a listener other hosts can reach unless jobs need a
+// token, since any client could otherwise delete indices with the loader's
+// Elasticsearch credentials.
+func (s *server) checkExposure(listener net.Listener, flag string) error {
+	if s.opts.Token != "" || s.opts.Insecure {
+		return nil
+	}
+	addr, ok := listener.Addr().(*net.TCPAddr)
+	if !ok || addr.IP.IsLoopback() {
+		return nil
+	}
+	return &RunError{Kind: ErrInvalidOptions, Op: "validating server options", Err: fmt.Errorf("%s %s is reachable from other hosts; set -serve-token, or -serve-insecure to accept unauthenticated jobs", flag, addr)}
+}
+
+// serve runs the worker and HTTP server on listener until ctx is cancelled.
+func (s *server) serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{Handler: s.routes(), ReadHeaderTimeout: 10 * time.Second}
+
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		s.work(ctx)
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- httpServer.Serve(listener) }()
+	log.Info().Str("addr", listener.Addr().String()).Msg("Ingestion server listening")
+	stopWatchdog := notifyReady("serving on " + listener.Addr().String())
+	defer stopWatchdog()
+
+	select {
+	case err := <-serveErr:
+		return &RunError{Kind: ErrLoaderExecution, Op: "serving HTTP", Err: err}
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Warn().Err(err).Msg("Ingestion server shutdown did not complete cleanly")
+	}
+	<-workerDone
+	log.Info().Msg("Ingestion server stopped")
+	return nil
+}
+
+// routes registers the HTTP API.
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("POST /jobs", s.authorize(s.handleSubmit))
+	mux.HandleFunc("GET /jobs", s.authorize(s.handleList))
+	mux.HandleFunc("GET /jobs/{id}", s.authorize(s.handleGet))
+	return mux
+}
+
+// authorize enforces the optional bearer token.
+func (s *server) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.opts.Token != "" {
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(s.opts.Token)) != 1 {
+				writeJSONError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// handleSubmit spools an upload, records a queued job, and enqueues it.
+func (s *server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	index := strings.TrimSpace(query.Get("index"))
+	if index == "" {
+		writeJSONError(w, http.StatusBadRequest, "index query parameter is required")
+		return
+	}
+	action := query.Get("action")
+	if action == "" {
+		action = "add"
+	}
+	load := s.opts.Load
+	load.Index = index
+	load.AddToIndex, load.FlushIndex, load.DeleteIndex = action == "add", action == "flush", action == "delete"
+	if !load.AddToIndex && !load.FlushIndex && !load.DeleteIndex {
+		writeJSONError(w, http.StatusBadRequest, "action must be add, flush, or delete")
+		return
+	}
+	if raw := query.Get("alias"); raw != "" {
+		alias, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "alias must be a boolean")
+			return
+		}
+		load.AliasMode = alias
+	}
+	if raw := query.Get("keep_last"); raw != "" {
+		keepLast, err := strconv.Atoi(raw)
+		if err != nil || keepLast < 0 {
+			writeJSONError(w, http.StatusBadRequest, "keep_last must be a non-negative integer")
+			return
+		}
+		load.KeepLast = keepLast
+	}
+	if raw := query.Get("batch"); raw != "" {
+		batch, err := strconv.Atoi(raw)
+		if err != nil || batch <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "batch must be a positive integer")
+			return
+		}
+		load.BatchSize = batch
+	}
+	if id := query.Get("id"); id != "" {
+		load.IDField = id
+	}
+
+	job, err := newJob(JobModeServe, "", load)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxUploadBytes)
+	upload, source, err := uploadReader(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	path, written, documents, err := spoolUpload(s.opts.SpoolDir, job.ID, upload)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	load.DataFile = path
+	job.Source = source
+	job.UploadBytes = written
+	job.DocumentsUploaded = documents
+	if err := s.store.save(job); err != nil {
+		_ = os.Remove(path)
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	select {
+	case s.queue <- queuedJob{job: job, load: load}:
+	default:
+		_ = os.Remove(path)
+		job.State = JobFailed
+		job.Error = "job queue is full"
+		_ = s.store.save(job)
+		writeJSONError(w, http.StatusServiceUnavailable, job.Error)
+		return
+	}
+	log.Info().Str("job", job.ID).Str("index", index).Str("action", action).Int("documents", documents).Msg("Queued ingestion job")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// handleList returns every known job.
+func (s *server) handleList(w http.ResponseWriter, r *http.Request) {
+	jobs, err := s.store.list()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]Job{"jobs": jobs})
+}
+
+// handleGet returns one job by id.
+func (s *server) handleGet(w http.ResponseWriter, r *http.Request) {
+	job, ok, err := s.store.get(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// work executes queued jobs sequentially until ctx is cancelled.
+func (s *server) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			s.abandonQueued()
+			return
+		case queued := <-s.queue:
+			s.execute(ctx, queued)
+		}
+	}
+}
+
+// abandonQueued fails jobs that never started because the server is stopping.
+func (s *server) abandonQueued() {
+	for {
+		select {
+		case queued := <-s.queue:
+			_ = os.Remove(queued.load.DataFile)
+			job := queued.job
+			job.State = JobFailed
+			job.Error = "server stopped before the job started"
+			saveJobRecord(s.store, job)
+		default:
+			return
+		}
+	}
+}
+
+// execute runs one job and records its outcome.
+func (s *server) execute(ctx context.Context, queued queuedJob) {
+	job := queued.job
+	defer os.Remove(queued.load.DataFile)
+
+	log.Info().Str("job", job.ID).Str("index", job.Index).Msg("Running ingestion job")
+	job, _, err := runJob(ctx, s.store, job, queued.load, s.run)
+	if err != nil {
+		log.Error().Err(err).Str("job", job.ID).Msg("Ingestion job failed")
+		return
+	}
+	log.Info().Str("job", job.ID).Int("succeeded", job.DocumentsSucceeded).Int("failed", job.DocumentsFailed).Msg("Ingestion job finished")
+}
+
+// uploadReader returns the document stream from a multipart "file" field or
+// the raw request body, plus a short description of the source.
+func uploadReader(r *http.Request) (io.Reader, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, "body", nil
+	}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, "", fmt.Errorf("multipart upload must include a \"file\" part")
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() == "file" {
+			source := "multipart"
+			if name := part.FileName(); name != "" {
+				source = "multipart:" + name
+			}
+			return part, source, nil
+		}
+		_ = part.Close()
+	}
+}
+
+// uploadFormatOption names the first input format option set in load, as
+// it would override the format of spooled uploads.
+func uploadFormatOption(load Options) string {
+	for _, option := range []struct{ flag, value string }{
+		{"-format", load.Format},
+		{"-column-map", load.ColumnMap},
+		{"-layout", load.Layout},
+		{"-descriptor", load.Descriptor},
+		{"-log-format", load.LogFormat},
+		{"-grok-pattern", load.GrokPattern},
+	} {
+		if strings.TrimSpace(option.value) != "" {
+			return option.flag
+		}
+	}
+	if strings.EqualFold(strings.TrimSpace(load.PcapMode), pcapModeFlow) {
+		return "-pcap-mode"
+	}
+	return ""
+}
+
+// spoolUpload writes upload to a JSON array file in dir. A body starting with
+// '[' is copied as-is after validation; anything else is read as NDJSON and
+// re-encoded as an array. It returns the path, bytes read, and document count.
+func spoolUpload(dir, jobID string, upload io.Reader) (string, int64, int, error) {
+	file, err := os.CreateTemp(dir, "es-bulk-loader-"+jobID+"-*.json")
+	if err != nil {
+		return "", 0, 0, err
+	}
+	path := file.Name()
+	fail := func(err error) (string, int64, int, error) {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return "", 0, 0, err
+	}
+
+	counter := &countingReader{reader: upload}
+	buffered := bufio.NewReader(counter)
+	writer := bufio.NewWriter(file)
+	documents := 0
+
+	first, err := peekNonSpace(buffered)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fail(err)
+	}
+	if first == '[' {
+		dec := json.NewDecoder(buffered)
+		if _, err := dec.Token(); err != nil {
+			return fail(err)
+		}
+		_, _ = writer.WriteString("[")
+		for dec.More() {
+			var doc json.RawMessage
+			if err := dec.Decode(&doc); err != nil {
+				return fail(fmt.Errorf("decoding document %d: %w", documents+1, err))
+			}
+			if len(doc) == 0 || doc[0] != '{' {
+				return fail(fmt.Errorf("document %d is not a JSON object", documents+1))
+			}
+			if documents > 0 {
+				_, _ = writer.WriteString(",")
+			}
+			_, _ = writer.Write(doc)
+			documents++
+		}
+		if _, err := dec.Token(); err != nil {
+			return fail(err)
+		}
+		_, _ = writer.WriteString("]")
+	} else {
+		_, _ = writer.WriteString("[")
+		line := 0
+		for {
+			raw, readErr := buffered.ReadBytes('\n')
+			if readErr != nil && !errors.Is(readErr, io.EOF) {
+				return fail(readErr)
+			}
+			line++
+			trimmed := bytes.TrimSpace(raw)
+			if len(trimmed) > 0 {
+				if trimmed[0] != '{' || !json.Valid(trimmed) {
+					return fail(fmt.Errorf("line %d is not a JSON object", line))
+				}
+				if documents > 0 {
+					_, _ = writer.WriteString(",")
+				}
+				_, _ = writer.Write(trimmed)
+				documents++
+			}
+			if readErr != nil {
+				break
+			}
+		}
+		_, _ = writer.WriteString("]")
+	}
+	if documents == 0 {
+		return fail(fmt.Errorf("upload contains no documents"))
+	}
+	if err := writer.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(path)
+		return "", 0, 0, err
+	}
+	return path, counter.n, documents, nil
+}
+
+// peekNonSpace skips leading whitespace and returns the next byte unread.
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, reader.UnreadByte()
+		}
+	}
+}
+
+// countingReader counts bytes read through it.
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+// Read forwards to the wrapped reader and tallies bytes.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// newJobID returns a random 16-character hex identifier.
+func newJobID() (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// writeJSON writes value as an indented JSON response.
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(value)
+}
+
+// writeJSONError writes {"error": message}.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,257 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestServer builds a server whose runs are recorded instead of executed.
+func newTestServer(t *testing.T, opts ServerOptions, runErr error) (*server, *httptest.Server, func() []Options) {
+	t.Helper()
+
+	opts.SpoolDir = t.TempDir()
+	srv := newServer(opts, newMemoryJobStore())
+	var (
+		mu   sync.Mutex
+		runs []Options
+	)
+	srv.run = func(ctx context.Context, load Options) (Result, error) {
+		content, err := os.ReadFile(load.DataFile)
+		if err != nil {
+			t.Errorf("spooled data file unreadable: %v", err)
+		}
+		var docs []map[string]interface{}
+		if err := json.Unmarshal(content, &docs); err != nil {
+			t.Errorf("spooled data file is not a JSON array: %v: %s", err, content)
+		}
+		mu.Lock()
+		runs = append(runs, load)
+		mu.Unlock()
+		return Result{DocumentsProcessed: len(docs), DocumentsSucceeded: len(docs), WriteIndex: load.Index}, runErr
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go srv.work(ctx)
+	httpServer := httptest.NewServer(srv.routes())
+	t.Cleanup(func() {
+		httpServer.Close()
+		cancel()
+	})
+	return srv, httpServer, func() []Options {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]Options(nil), runs...)
+	}
+}
+
+// waitForJobState polls the status endpoint until the job reaches a final state.
+func waitForJobState(t *testing.T, baseURL, id string) Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		res, err := http.Get(baseURL + "/jobs/" + id)
+		if err != nil {
+			t.Fatalf("GET job returned error: %v", err)
+		}
+		var job Job
+		_ = json.NewDecoder(res.Body).Decode(&job)
+		_ = res.Body.Close()
+		if job.State == JobSucceeded || job.State == JobFailed {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s stuck in state %q", id, job.State)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestServerRunsNDJSONUploadJob verifies behavior for the related scenario.
+func TestServerRunsNDJSONUploadJob(t *testing.T) {
+	t.Parallel()
+
+	_, httpServer, runs := newTestServer(t, ServerOptions{Load: Options{URL: "http://es:9200", BatchSize: 50}}, nil)
+
+	res, err := http.Post(httpServer.URL+"/jobs?index=cards&action=flush&batch=10", "application/x-ndjson", strings.NewReader("{\"id\":1}\n\n{\"id\":2}"))
+	if err != nil {
+		t.Fatalf("POST returned error: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", res.StatusCode)
+	}
+	var queued Job
+	if err := json.NewDecoder(res.Body).Decode(&queued); err != nil {
+		t.Fatalf("decode returned error: %v", err)
+	}
+	if res.Header.Get("Location") != "/jobs/"+queued.ID || queued.DocumentsUploaded != 2 {
+		t.Fatalf("unexpected queued job: %+v (location %q)", queued, res.Header.Get("Location"))
+	}
+
+	job := waitForJobState(t, httpServer.URL, queued.ID)
+	if job.State != JobSucceeded || job.DocumentsSucceeded != 2 || job.StartedAt == nil || job.FinishedAt == nil {
+		t.Fatalf("unexpected finished job: %+v", job)
+	}
+	recorded := runs()
+	if len(recorded) != 1 {
+		t.Fatalf("expected one run, got %d", len(recorded))
+	}
+	load := recorded[0]
+	if load.Index != "cards" || !load.FlushIndex || load.AddToIndex || load.BatchSize != 10 || load.URL != "http://es:9200" {
+		t.Fatalf("unexpected run options: %+v", load)
+	}
+	if _, err := os.Stat(load.DataFile); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected spooled file to be removed after the job, got %v", err)
+	}
+}
+
+// TestServerAcceptsMultipartArrayUpload verifies behavior for the related scenario.
+func TestServerAcceptsMultipartArrayUpload(t *testing.T) {
+	t.Parallel()
+
+	_, httpServer, _ := newTestServer(t, ServerOptions{}, errors.New("boom"))
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("note", "ignored")
+	part, _ := writer.CreateFormFile("file", "cards.json")
+	_, _ = part.Write([]byte(`[{"id":"1"},{"id":"2"},{"id":"3"}]`))
+	_ = writer.Close()
+
+	res, err := http.Post(httpServer.URL+"/jobs?index=cards", writer.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("POST returned error: %v", err)
+	}
+	defer res.Body.Close()
+	var queued Job
+	_ = json.NewDecoder(res.Body).Decode(&queued)
+	if res.StatusCode != http.StatusAccepted || queued.Source != "multipart:cards.json" || queued.DocumentsUploaded != 3 || queued.Action != "add" {
+		t.Fatalf("unexpected response %d: %+v", res.StatusCode, queued)
+	}
+
+	job := waitForJobState(t, httpServer.URL, queued.ID)
+	if job.State != JobFailed || job.Error != "boom" {
+		t.Fatalf("expected failed job carrying run error, got %+v", job)
+	}
+
+	listRes, err := http.Get(httpServer.URL + "/jobs")
+	if err != nil {
+		t.Fatalf("GET jobs returned error: %v", err)
+	}
+	defer listRes.Body.Close()
+	var listed struct {
+		Jobs []Job `json:"jobs"`
+	}
+	_ = json.NewDecoder(listRes.Body).Decode(&listed)
+	if len(listed.Jobs) != 1 || listed.Jobs[0].ID != queued.ID {
+		t.Fatalf("unexpected job list: %+v", listed)
+	}
+}
+
+// TestServerRejectsInvalidSubmissions verifies behavior for the related scenario.
+func TestServerRejectsInvalidSubmissions(t *testing.T) {
+	t.Parallel()
+
+	_, httpServer, runs := newTestServer(t, ServerOptions{Token: "secret", MaxUploadBytes: 64}, nil)
+
+	tests := []struct {
+		name   string
+		query  string
+		auth   string
+		body   string
+		status int
+	}{
+		{name: "missing token", query: "index=cards", body: `{"id":1}`, status: http.StatusUnauthorized},
+		{name: "wrong token", query: "index=cards", auth: "Bearer nope", body: `{"id":1}`, status: http.StatusUnauthorized},
+		{name: "bare token", query: "index=cards", auth: "secret", body: `{"id":1}`, status: http.StatusUnauthorized},
+		{name: "other scheme", query: "index=cards", auth: "Basic secret", body: `{"id":1}`, status: http.StatusUnauthorized},
+		{name: "missing index", query: "", auth: "Bearer secret", body: `{"id":1}`, status: http.StatusBadRequest},
+		{name: "bad action", query: "index=cards&action=nuke", auth: "Bearer secret", body: `{"id":1}`, status: http.StatusBadRequest},
+		{name: "not objects", query: "index=cards", auth: "Bearer secret", body: "[1,2]", status: http.StatusBadRequest},
+		{name: "bad ndjson", query: "index=cards", auth: "Bearer secret", body: "{\"id\":1}\nnope\n", status: http.StatusBadRequest},
+		{name: "empty", query: "index=cards", auth: "Bearer secret", body: "  ", status: http.StatusBadRequest},
+		{name: "too large", query: "index=cards", auth: "Bearer secret", body: strings.Repeat(`{"id":1}`+"\n", 20), status: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, httpServer.URL+"/jobs?"+tt.query, strings.NewReader(tt.body))
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request returned error: %v", tt.name, err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != tt.status {
+			t.Fatalf("%s: expected status %d, got %d", tt.name, tt.status, res.StatusCode)
+		}
+	}
+	if len(runs()) != 0 {
+		t.Fatalf("expected no runs for rejected submissions")
+	}
+}
+
+// TestServeRejectsInputFormatOptions verifies behavior for the related scenario.
+func TestServeRejectsInputFormatOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		load Options
+		want string
+	}{
+		{load: Options{Format: formatCSV}, want: "-format"},
+		{load: Options{ColumnMap: "name:keyword"}, want: "-column-map"},
+		{load: Options{LogFormat: "combined"}, want: "-log-format"},
+		{load: Options{GrokPattern: "%{COMBINEDAPACHELOG}"}, want: "-grok-pattern"},
+		{load: Options{PcapMode: pcapModeFlow}, want: "-pcap-mode"},
+	}
+	for _, tt := range tests {
+		err := Serve(context.Background(), ServerOptions{Addr: "127.0.0.1:0", SpoolDir: t.TempDir(), Load: tt.load})
+		var runErr *RunError
+		if !errors.As(err, &runErr) || runErr.Kind != ErrInvalidOptions || !strings.Contains(err.Error(), tt.want+" cannot be combined with serve") {
+			t.Fatalf("%+v: expected an invalid options error naming %s, got %v", tt.load, tt.want, err)
+		}
+	}
+}
+
+// TestServeRefusesUnauthenticatedExposure verifies behavior for the related scenario.
+func TestServeRefusesUnauthenticatedExposure(t *testing.T) {
+	t.Parallel()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		opts ServerOptions
+		want string
+	}{
+		{opts: ServerOptions{Addr: "0.0.0.0:0"}, want: "-listen "},
+		{opts: ServerOptions{Addr: "127.0.0.1:0", GRPCAddr: "0.0.0.0:0"}, want: "-grpc-listen "},
+		{opts: ServerOptions{Addr: "127.0.0.1:0"}},
+		{opts: ServerOptions{Addr: "0.0.0.0:0", Token: "secret"}},
+		{opts: ServerOptions{Addr: "0.0.0.0:0", Insecure: true}},
+	}
+	for _, tt := range tests {
+		tt.opts.SpoolDir = t.TempDir()
+		err := Serve(cancelled, tt.opts)
+		if tt.want == "" {
+			if err != nil {
+				t.Fatalf("listen %q grpc %q: Serve returned error: %v", tt.opts.Addr, tt.opts.GRPCAddr, err)
+			}
+			continue
+		}
+		var runErr *RunError
+		if !errors.As(err, &runErr) || runErr.Kind != ErrInvalidOptions || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "-serve-token") {
+			t.Fatalf("listen %q grpc %q: expected an invalid options error naming %q, got %v", tt.opts.Addr, tt.opts.GRPCAddr, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Elastic Serverless Projects ───────────────────────────────────────────────
+
+const (
+	// serverlessBuildFlavor is the version.build_flavor GET / reports on
+	// serverless projects.
+	serverlessBuildFlavor = "serverless"
+	// serverlessHostSuffix ends the Elasticsearch endpoint of every
+	// serverless project; hosted deployments use other domains.
+	serverlessHostSuffix = ".elastic.cloud"
+)
+
+// serverlessManagedSettings are index settings serverless projects manage
+// themselves and reject in create index requests. A key matches when it
+// equals an entry or continues it with a dot.
+var serverlessManagedSettings = []string{
+	"number_of_shards",
+	"number_of_replicas",
+	"number_of_routing_shards",
+	"auto_expand_replicas",
+	"routing_partition_size",
+	"routing",
+	"translog",
+	"shard",
+	"codec",
+	"store",
+	"merge",
+	"unassigned",
+}
+
+// isServerlessURL reports whether rawURL is a serverless project endpoint.
+func isServerlessURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(parsed.Hostname()), serverlessHostSuffix)
+}
+
+// isServerless reports whether the run targets a serverless project: by
+// the pre-flight build flavor when there was one, else by URL.
+func isServerless(opts Options, info ClusterInfo) bool {
+	if info.Version != "" || info.BuildFlavor != "" {
+		return info.BuildFlavor == serverlessBuildFlavor
+	}
+	return isServerlessURL(opts.URL)
+}
+
+// validateServerless rejects options a serverless project cannot honour.
+func validateServerless(opts Options) error {
+	if opts.APIKey == "" && opts.User != "" {
+		return &RunError{Kind: ErrAuthentication, Op: "validating serverless credentials", Err: fmt.Errorf("Elastic serverless projects accept only API keys; set -apiKey instead of -user and -pass")}
+	}
+	if opts.LoadDurability != "" {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating load durability", Err: fmt.Errorf("-load-durability is not available on serverless projects, which manage translog durability")}
+	}
+	if opts.ShardGroup > 0 {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating shard group", Err: fmt.Errorf("-shard-group is not available on serverless projects, which manage shards")}
+	}
+	return nil
+}
+
+// stripServerlessSettings removes the settings serverless projects manage
+// from a create index body and warns about each one dropped.
+func stripServerlessSettings(body string) (string, error) {
+	var parsed map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		return "", fmt.Errorf("parsing index body: %w", err)
+	}
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(parsed["settings"], &settings); err != nil || len(settings) == 0 {
+		return body, nil
+	}
+	var dropped []string
+	for key := range settings {
+		if serverlessManagedSetting(key) {
+			dropped = append(dropped, key)
+			delete(settings, key)
+		}
+	}
+	if len(dropped) == 0 {
+		return body, nil
+	}
+	sort.Strings(dropped)
+	log.Warn().Strs("settings", dropped).Msg("Dropping index settings that serverless projects manage")
+	encodedSettings, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	parsed["settings"] = encodedSettings
+	encoded, err := json.Marshal(parsed)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// serverlessManagedSetting reports whether key is a managed setting.
+func serverlessManagedSetting(key string) bool {
+	for _, managed := range serverlessManagedSettings {
+		if key == managed || strings.HasPrefix(key, managed+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// indexTemplateList is the body of GET /_index_template.
+type indexTemplateList struct {
+	IndexTemplates []struct {
+		Name          string `json:"name"`
+		IndexTemplate struct {
+			IndexPatterns []string        `json:"index_patterns"`
+			Priority      int             `json:"priority"`
+			DataStream    json.RawMessage `json:"data_stream"`
+		} `json:"index_template"`
+	} `json:"index_templates"`
+}
+
+// isDataStreamTarget reports whether name is an existing data stream or
+// would become one on first write, because the highest priority index
+// template matching it declares data_stream.
+func isDataStreamTarget(es *elasticsearch.Client, name string) (bool, error) {
+	res, err := es.Indices.GetDataStream(es.Indices.GetDataStream.WithName(name))
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		return true, nil
+	}
+
+	template, matched, err := matchIndexTemplate(es, name)
+	if err != nil || !matched {
+		return false, err
+	}
+	return template.DataStream, nil
+}
+
+// indexTemplateMatch is the index template an index name would be created
+// from.
+type indexTemplateMatch struct {
+	Name       string
+	DataStream bool
+}
+
+// matchIndexTemplate returns the highest priority composable index template
+// whose patterns match name; matched is false when none does.
+func matchIndexTemplate(es *elasticsearch.Client, name string) (match indexTemplateMatch, matched bool, err error) {
+	res, err := es.Indices.GetIndexTemplate()
+	if err != nil {
+		return match, false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return match, false, nil
+	}
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return match, false, fmt.Errorf("listing index templates: status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var templates indexTemplateList
+	if err := json.NewDecoder(res.Body).Decode(&templates); err != nil {
+		return match, false, fmt.Errorf("parsing index templates: %w", err)
+	}
+	priority := 0
+	for _, template := range templates.IndexTemplates {
+		spec := template.IndexTemplate
+		if matched && spec.Priority <= priority {
+			continue
+		}
+		for _, pattern := range spec.IndexPatterns {
+			if wildcardMatch(pattern, name) {
+				match = indexTemplateMatch{Name: template.Name, DataStream: len(spec.DataStream) > 0 && string(spec.DataStream) != "null"}
+				priority, matched = spec.Priority, true
+				break
+			}
+		}
+	}
+	return match, matched, nil
+}
+
+// wildcardMatch reports whether name matches pattern, where each * stands
+// for any run of characters, as index template patterns are matched.
+func wildcardMatch(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return len(name) >= len(last) && strings.HasSuffix(name, last)
+}
@@ -0,0 +1,155 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestWildcardMatch verifies behavior for the related scenario.
+func TestWildcardMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "logs-*-*", name: "logs-app-default", want: true},
+		{pattern: "logs-*-*", name: "logs-app", want: false},
+		{pattern: "cards", name: "cards", want: true},
+		{pattern: "card*", name: "cards", want: true},
+		{pattern: "*s", name: "cards", want: true},
+		{pattern: "a*a", name: "a", want: false},
+		{pattern: "*", name: "anything", want: true},
+	}
+	for _, tt := range tests {
+		if got := wildcardMatch(tt.pattern, tt.name); got != tt.want {
+			t.Fatalf("wildcardMatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestValidateServerless verifies behavior for the related scenario.
+func TestValidateServerless(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opts     Options
+		wantKind error
+	}{
+		{name: "api key", opts: Options{APIKey: "abc"}},
+		{name: "basic auth", opts: Options{User: "elastic", Pass: "x"}, wantKind: ErrAuthentication},
+		{name: "durability", opts: Options{APIKey: "abc", LoadDurability: loadDurabilityAsync}, wantKind: ErrInvalidOptions},
+		{name: "shard group", opts: Options{APIKey: "abc", ShardGroup: 2}, wantKind: ErrInvalidOptions},
+	}
+	for _, tt := range tests {
+		err := validateServerless(tt.opts)
+		if tt.wantKind == nil && err != nil || tt.wantKind != nil && !errors.Is(err, tt.wantKind) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.wantKind, err)
+		}
+	}
+	if !isServerlessURL("https://my-project-a1b2c3.es.us-east-1.aws.elastic.cloud:443") || isServerlessURL("https://prod.es.us-east-1.aws.found.io") {
+		t.Fatal("serverless endpoint detection by URL is wrong")
+	}
+}
+
+// TestStripServerlessSettings verifies behavior for the related scenario.
+func TestStripServerlessSettings(t *testing.T) {
+	t.Parallel()
+
+	body := `{"settings":{"number_of_shards":3,"number_of_replicas":1,"translog":{"durability":"async"},"refresh_interval":"30s","default_pipeline":"p"},"mappings":{}}`
+	got, err := stripServerlessSettings(body)
+	if err != nil {
+		t.Fatalf("stripServerlessSettings returned error: %v", err)
+	}
+	want := `{"mappings":{},"settings":{"default_pipeline":"p","refresh_interval":"30s"}}`
+	if got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got, _ := stripServerlessSettings(`{"settings":{"refresh_interval":"5s"},"mappings":{}}`); got != `{"settings":{"refresh_interval":"5s"},"mappings":{}}` {
+		t.Fatalf("expected an untouched body, got %s", got)
+	}
+}
+
+// TestRunServerlessProject verifies behavior for the related scenario.
+func TestRunServerlessProject(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		index       string
+		wantCreated bool
+		wantAction  string
+	}{
+		{name: "data stream template", index: "logs-app-default", wantAction: `{"create":{"_index":"logs-app-default"}}`},
+		{name: "plain index", index: "cards", wantCreated: true, wantAction: `{"index":{"_index":"cards"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				mu      sync.Mutex
+				created string
+				bulk    string
+				accept  string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				defer mu.Unlock()
+				w.Header().Set("X-Elastic-Product", "Elasticsearch")
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.URL.Path == "/":
+					_, _ = w.Write([]byte(`{"cluster_name":"project","version":{"number":"8.11.0","build_flavor":"serverless"}}`))
+				case r.Method == http.MethodHead && r.URL.Path == "/"+tt.index:
+					if created == "" {
+						w.WriteHeader(http.StatusNotFound)
+					}
+				case r.URL.Path == "/_index_template":
+					_, _ = w.Write([]byte(`{"index_templates":[` +
+						`{"name":"logs","index_template":{"index_patterns":["logs-*-*"],"priority":100,"data_stream":{}}},` +
+						`{"name":"catch-all","index_template":{"index_patterns":["*"],"priority":1}}]}`))
+				case r.Method == http.MethodPut && r.URL.Path == "/"+tt.index:
+					created = string(body)
+					_, _ = w.Write([]byte(`{"acknowledged":true}`))
+				case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+					bulk, accept = string(body), r.Header.Get("Accept")
+					_, _ = w.Write([]byte(`{"errors":false,"items":[{"create":{"_index":"x","status":201}}]}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			result, err := Run(context.Background(), Options{
+				URL:          server.URL,
+				APIKey:       "abc",
+				Preflight:    true,
+				Index:        tt.index,
+				DataFile:     writeTestDataFile(t, "docs.ndjson", "{\"n\":1}\n"),
+				SettingsFile: writeTestDataFile(t, "settings.json", `{"index":{"number_of_shards":3,"refresh_interval":"10s"}}`),
+				AddToIndex:   true,
+			})
+			if err != nil {
+				t.Fatalf("Run returned error: %v", err)
+			}
+			if result.DocumentsSucceeded != 1 || !strings.HasPrefix(bulk, tt.wantAction+"\n") {
+				t.Fatalf("expected action %s, got %q with %+v", tt.wantAction, bulk, result)
+			}
+			if strings.Contains(accept, "compatible-with") {
+				t.Fatalf("expected no compatibility media type for a serverless project, got %q", accept)
+			}
+			if (created != "") != tt.wantCreated || strings.Contains(created, "number_of_shards") {
+				t.Fatalf("unexpected create index body %q", created)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package loader
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Shard Grouping ────────────────────────────────────────────────────────────
+
+// maxRoutingShardsLog2 is log2 of the shard count an index created without
+// index.number_of_routing_shards can be split to (1024).
+const maxRoutingShardsLog2 = 10
+
+// shardLayout is what routes a document to a shard of one index.
+type shardLayout struct {
+	shards        int
+	routingShards int
+}
+
+// defaultRoutingShards returns the number of routing shards Elasticsearch
+// 7 and later give an index of shards primaries unless
+// index.number_of_routing_shards is set: enough for at least one split,
+// and for as many doublings as stay within 1024 shards.
+func defaultRoutingShards(shards int) int {
+	splits := maxRoutingShardsLog2 - (32 - bits.LeadingZeros32(uint32(shards-1)))
+	if splits < 1 {
+		splits = 1
+	}
+	return shards << splits
+}
+
+// shardFor returns the shard that Elasticsearch's default routing sends
+// routing to.
+func (l shardLayout) shardFor(routing string) int {
+	hash := int(routingHash(routing)) % l.routingShards
+	if hash < 0 {
+		hash += l.routingShards
+	}
+	return hash / (l.routingShards / l.shards)
+}
+
+// routingHash hashes routing the way Elasticsearch does: Murmur3 x86 32-bit
+// with seed 0 over the UTF-16 code units of the string, low byte first.
+func routingHash(routing string) int32 {
+	var data []byte
+	for _, unit := range utf16Units(routing) {
+		data = append(data, byte(unit), byte(unit>>8))
+	}
+	return int32(murmur3x86_32(data, 0))
+}
+
+// utf16Units returns the UTF-16 encoding of s, as Java strings hold it.
+func utf16Units(s string) []uint16 {
+	units := make([]uint16, 0, len(s))
+	for _, r := range s {
+		if r >= 0x10000 {
+			r -= 0x10000
+			units = append(units, uint16(0xD800+(r>>10)), uint16(0xDC00+(r&0x3FF)))
+			continue
+		}
+		units = append(units, uint16(r))
+	}
+	return units
+}
+
+// murmur3x86_32 is MurmurHash3's x86 32-bit variant.
+func murmur3x86_32(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+	h := seed
+	blocks := len(data) / 4
+	for i := 0; i < blocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+	var k uint32
+	tail := data[blocks*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// getShardLayout reads the shard counts of index. ok is false when shard
+// grouping cannot help: a single shard, or a routing partition that spreads
+// each routing value over several shards.
+func getShardLayout(es *elasticsearch.Client, index string) (layout shardLayout, ok bool, err error) {
+	res, err := es.Indices.GetSettings(
+		es.Indices.GetSettings.WithIndex(index),
+		es.Indices.GetSettings.WithName("index.number_of_shards", "index.number_of_routing_shards", "index.routing_partition_size"),
+		es.Indices.GetSettings.WithFlatSettings(true),
+	)
+	if err != nil {
+		return layout, false, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return layout, false, fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var parsed map[string]struct {
+		Settings map[string]string `json:"settings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return layout, false, fmt.Errorf("parsing index settings: %w", err)
+	}
+	for _, settings := range parsed {
+		layout.shards, _ = strconv.Atoi(settings.Settings["index.number_of_shards"])
+		layout.routingShards, _ = strconv.Atoi(settings.Settings["index.number_of_routing_shards"])
+		if partition, _ := strconv.Atoi(settings.Settings["index.routing_partition_size"]); partition > 1 {
+			return layout, false, nil
+		}
+		break
+	}
+	if layout.shards <= 1 {
+		return layout, false, nil
+	}
+	if layout.routingShards < layout.shards || layout.routingShards%layout.shards != 0 {
+		layout.routingShards = defaultRoutingShards(layout.shards)
+	}
+	return layout, true, nil
+}
+
+// configureShardGroups makes b collect group batches at a time and reorder
+// them by destination shard before they are cut into bulk requests, so each
+// request reaches fewer shards. Grouping is left off when the index layout
+// makes it pointless.
+func (b *bulkBatcher) configureShardGroups(group int) {
+	layout, ok, err := getShardLayout(b.es, b.index)
+	if err != nil {
+		fatal().Err(err).Str("index", b.index).Msg("Reading index shard count for -shard-group")
+	}
+	if !ok {
+		log.Info().Str("index", b.index).Int("shards", layout.shards).Msg("Ignoring -shard-group: every routing value of this index maps to more than one shard or the index has one shard")
+		return
+	}
+	b.shards = &layout
+	b.shardGroup = group
+	log.Info().Str("index", b.index).Int("shards", layout.shards).Int("batches", group).Msg("Grouping documents by destination shard")
+}
+
+// routingKey returns the value Elasticsearch routes doc by: its routing
+// field, else its _id, or "" when the cluster will generate the _id.
+func routingKey(doc map[string]interface{}, settings bulkSettings) string {
+	if settings.RoutingField != "" {
+		if routing := routingValue(doc[settings.RoutingField]); routing != "" {
+			return routing
+		}
+	}
+	if settings.Metadata {
+		if routing, ok := doc[metaRouting].(string); ok && routing != "" {
+			return routing
+		}
+	}
+	if settings.IDField != "" {
+		if id, ok := doc[settings.IDField].(string); ok && id != "" {
+			return id
+		}
+	}
+	if settings.Metadata {
+		if id, ok := doc[metaID].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// rawRoutingKey is routingKey for a pre-encoded document.
+func rawRoutingKey(doc json.RawMessage, settings bulkSettings) string {
+	if settings.RoutingField == "" && settings.IDField == "" {
+		return ""
+	}
+	var fields map[string]interface{}
+	if decodeJSON(doc, &fields) != nil {
+		return ""
+	}
+	return routingKey(fields, settings)
+}
+
+// routingValue formats a routing field value; only strings and numbers
+// route a document.
+func routingValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+// shardOrder returns the positions of n documents reordered by destination
+// shard, keeping their relative order within a shard. Documents without a
+// routing key may land anywhere and go last.
+func (l shardLayout) shardOrder(n int, key func(i int) string) []int {
+	shardOf := make([]int, n)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+		if routing := key(i); routing != "" {
+			shardOf[i] = l.shardFor(routing)
+		} else {
+			shardOf[i] = l.shards
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return shardOf[order[i]] < shardOf[order[j]] })
+	return order
+}
+
+// groupByShard reorders the queued documents by destination shard.
+func (b *bulkBatcher) groupByShard() {
+	if len(b.raw) > 0 {
+		order := b.shards.shardOrder(len(b.raw), func(i int) string { return rawRoutingKey(b.raw[i], b.settings) })
+		grouped := make([]json.RawMessage, len(b.raw))
+		for i, from := range order {
+			grouped[i] = b.raw[from]
+		}
+		copy(b.raw, grouped)
+		return
+	}
+	order := b.shards.shardOrder(len(b.batch), func(i int) string { return routingKey(b.batch[i], b.settings) })
+	grouped := make([]map[string]interface{}, len(b.batch))
+	for i, from := range order {
+		grouped[i] = b.batch[from]
+	}
+	copy(b.batch, grouped)
+}
@@ -0,0 +1,148 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestRoutingHash verifies behavior for the related scenario.
+func TestRoutingHash(t *testing.T) {
+	t.Parallel()
+
+	// Expected values are the ones Elasticsearch's Murmur3HashFunction returns.
+	tests := []struct {
+		routing string
+		want    uint32
+	}{
+		{routing: "hell", want: 0x5a0cb7c3},
+		{routing: "hello", want: 0xd7c31989},
+		{routing: "hello w", want: 0x22ab2984},
+		{routing: "hello wo", want: 0xdf0ca123},
+		{routing: "hello wor", want: 0xe7744d61},
+		{routing: "The quick brown fox jumps over the lazy dog", want: 0xe07db09c},
+	}
+	for _, tt := range tests {
+		if got := uint32(routingHash(tt.routing)); got != tt.want {
+			t.Fatalf("routingHash(%q) = %#x, want %#x", tt.routing, got, tt.want)
+		}
+	}
+	if got := murmur3x86_32([]byte("hello"), 0); got != 0x248bfa47 {
+		t.Fatalf("murmur3x86_32(hello) = %#x, want 0x248bfa47", got)
+	}
+}
+
+// TestDefaultRoutingShards verifies behavior for the related scenario.
+func TestDefaultRoutingShards(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		shards int
+		want   int
+	}{
+		{shards: 1, want: 1024},
+		{shards: 2, want: 1024},
+		{shards: 3, want: 768},
+		{shards: 5, want: 640},
+		{shards: 1000, want: 2000},
+	}
+	for _, tt := range tests {
+		if got := defaultRoutingShards(tt.shards); got != tt.want {
+			t.Fatalf("defaultRoutingShards(%d) = %d, want %d", tt.shards, got, tt.want)
+		}
+	}
+}
+
+// TestRunShardGroupOrdersBatchesByShard verifies behavior for the related scenario.
+func TestRunShardGroupOrdersBatchesByShard(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/cards/_settings/"):
+			_, _ = w.Write([]byte(`{"cards":{"settings":{"index.number_of_shards":"4"}}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			bodies = append(bodies, string(body))
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	var data strings.Builder
+	for i := 1; i <= 12; i++ {
+		fmt.Fprintf(&data, "{\"user\":\"u%d\",\"n\":%d}\n", i, i)
+	}
+	var progress []int
+	result, err := Run(context.Background(), Options{
+		URL:          server.URL,
+		Index:        "cards",
+		DataFile:     writeTestDataFile(t, "cards.ndjson", data.String()),
+		AddToIndex:   true,
+		BatchSize:    3,
+		RoutingField: "user",
+		ShardGroup:   4,
+		Prefetch:     -1,
+		OnProgress:   func(offset int) { progress = append(progress, offset) },
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsSucceeded != 12 || len(bodies) != 4 {
+		t.Fatalf("expected 12 documents in 4 requests, got %+v in %d", result, len(bodies))
+	}
+	if fmt.Sprint(progress) != "[0 0 0 12]" {
+		t.Fatalf("expected progress only after the whole window, got %v", progress)
+	}
+
+	layout := shardLayout{shards: 4, routingShards: defaultRoutingShards(4)}
+	last := -1
+	for _, body := range bodies {
+		lines := strings.Split(strings.TrimSpace(body), "\n")
+		for i := 0; i < len(lines); i += 2 {
+			var action bulkAction
+			if err := json.Unmarshal([]byte(lines[i]), &action); err != nil {
+				t.Fatalf("decoding action %q: %v", lines[i], err)
+			}
+			shard := layout.shardFor(action.Index.Routing)
+			if action.Index.Routing == "" || shard < last {
+				t.Fatalf("documents not grouped by shard: routing %q on shard %d after shard %d", action.Index.Routing, shard, last)
+			}
+			last = shard
+		}
+	}
+}
+
+// TestRunShardGroupNeedsRoutingKey verifies behavior for the related scenario.
+func TestRunShardGroupNeedsRoutingKey(t *testing.T) {
+	t.Parallel()
+
+	_, err := Run(context.Background(), Options{
+		URL:        "http://127.0.0.1:0",
+		Index:      "cards",
+		DataFile:   "cards.ndjson",
+		AddToIndex: true,
+		ShardGroup: 4,
+	})
+	if err == nil || !strings.Contains(err.Error(), "-shard-group needs") {
+		t.Fatalf("expected -shard-group validation error, got %v", err)
+	}
+}
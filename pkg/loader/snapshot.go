@@ -0,0 +1,286 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Snapshot and Restore ──────────────────────────────────────────────────────
+
+// SnapshotOptions configures Snapshot.
+type SnapshotOptions struct {
+	// Repository is the registered snapshot repository to write to.
+	Repository string
+	// Name is the snapshot name; defaults to es-bulk-loader-<UTC time>.
+	Name string
+	// NoWait returns once the cluster accepts the snapshot instead of
+	// waiting for it to finish.
+	NoWait bool
+	// Load names the cluster and, as a comma-separated Index, the indices to
+	// snapshot; an empty Index snapshots every index.
+	Load Options
+}
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	// Repository is the registered snapshot repository to read from.
+	Repository string
+	// Name is the snapshot to restore.
+	Name string
+	// RenamePattern is a regular expression matched against each restored
+	// index name; matches are replaced by RenameReplacement, which may
+	// refer to groups as $1.
+	RenamePattern     string
+	RenameReplacement string
+	// NoWait returns once the cluster accepts the restore instead of waiting
+	// for every shard to be recovered.
+	NoWait bool
+	// Load names the cluster and, as a comma-separated Index, the indices to
+	// restore; an empty Index restores every index in the snapshot.
+	Load Options
+}
+
+// SnapshotReport describes a finished snapshot or restore. Only Repository
+// and Snapshot are set when NoWait returned before it finished.
+type SnapshotReport struct {
+	Repository string
+	Snapshot   string
+	// State is the snapshot state, SUCCESS or PARTIAL; empty for a restore.
+	State   string
+	Indices []string
+	// Shards and FailedShards count the shards copied.
+	Shards       int
+	FailedShards int
+}
+
+// snapshotResponse is the body of a snapshot or restore request that
+// waited for completion; one that did not answers {"accepted":true}.
+type snapshotResponse struct {
+	Snapshot *struct {
+		Snapshot string   `json:"snapshot"`
+		State    string   `json:"state"`
+		Indices  []string `json:"indices"`
+		Shards   struct {
+			Total  int `json:"total"`
+			Failed int `json:"failed"`
+		} `json:"shards"`
+	} `json:"snapshot"`
+	Accepted bool `json:"accepted"`
+}
+
+// Snapshot verifies Repository on every node, then snapshots the indices of
+// Load into it with the same cluster address and credentials a load uses.
+// Unless NoWait is set it waits for the snapshot and fails when any shard
+// was not copied.
+func Snapshot(ctx context.Context, opts SnapshotOptions) (SnapshotReport, error) {
+	report := SnapshotReport{Repository: strings.TrimSpace(opts.Repository), Snapshot: opts.Name}
+	if report.Snapshot == "" {
+		report.Snapshot = "es-bulk-loader-" + time.Now().UTC().Format("20060102-150405")
+	}
+	if report.Repository == "" {
+		return report, &RunError{Kind: ErrInvalidOptions, Op: "validating snapshot options", Err: fmt.Errorf("-snapshot-repository is required")}
+	}
+	if report.Snapshot != strings.ToLower(report.Snapshot) {
+		return report, &RunError{Kind: ErrInvalidOptions, Op: "validating snapshot options", Err: fmt.Errorf("-snapshot-name %q must be lowercase", report.Snapshot)}
+	}
+	es, err := snapshotClient(ctx, opts.Load)
+	if err != nil {
+		return report, err
+	}
+	if err := verifySnapshotRepository(ctx, es, report.Repository); err != nil {
+		return report, err
+	}
+
+	body := map[string]any{"include_global_state": false}
+	if indices := snapshotIndices(opts.Load.Index); len(indices) > 0 {
+		body["indices"] = indices
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return report, &RunError{Kind: ErrLoaderExecution, Op: "serializing snapshot request", Err: err}
+	}
+	log.Info().Str("repository", report.Repository).Str("snapshot", report.Snapshot).Str("index", opts.Load.Index).Msg("Creating snapshot")
+	res, err := es.Snapshot.Create(report.Repository, report.Snapshot,
+		es.Snapshot.Create.WithContext(ctx),
+		es.Snapshot.Create.WithBody(strings.NewReader(string(payload))),
+		es.Snapshot.Create.WithWaitForCompletion(!opts.NoWait),
+	)
+	if err := readSnapshotResponse(res, err, &report); err != nil {
+		return report, &RunError{Kind: ErrIndexOperation, Op: "creating snapshot", Err: err}
+	}
+	if opts.NoWait {
+		log.Info().Str("repository", report.Repository).Str("snapshot", report.Snapshot).Msg("Snapshot started")
+		return report, nil
+	}
+	if report.State != "SUCCESS" || report.FailedShards > 0 {
+		return report, &RunError{Kind: ErrIndexOperation, Op: "creating snapshot", Err: fmt.Errorf("snapshot %s finished %s with %d of %d shards failed", report.Snapshot, report.State, report.FailedShards, report.Shards)}
+	}
+	log.Info().
+		Str("repository", report.Repository).
+		Str("snapshot", report.Snapshot).
+		Strs("indices", report.Indices).
+		Int("shards", report.Shards).
+		Msg("Snapshot complete")
+	return report, nil
+}
+
+// Restore verifies Repository on every node, then restores the indices of
+// Load from the Name snapshot, renamed by RenamePattern. Indices are
+// restored without the snapshot's global state. Unless NoWait is set it
+// waits until every restored shard has recovered and fails when any did
+// not.
+func Restore(ctx context.Context, opts RestoreOptions) (SnapshotReport, error) {
+	report := SnapshotReport{Repository: strings.TrimSpace(opts.Repository), Snapshot: strings.TrimSpace(opts.Name)}
+	invalid := func(err error) (SnapshotReport, error) {
+		return report, &RunError{Kind: ErrInvalidOptions, Op: "validating restore options", Err: err}
+	}
+	if report.Repository == "" {
+		return invalid(fmt.Errorf("-snapshot-repository is required"))
+	}
+	if report.Snapshot == "" {
+		return invalid(fmt.Errorf("-snapshot-name is required"))
+	}
+	if opts.RenamePattern == "" && opts.RenameReplacement != "" {
+		return invalid(fmt.Errorf("-restore-rename-replacement needs -restore-rename-pattern"))
+	}
+	if opts.RenamePattern != "" {
+		if _, err := regexp.Compile(opts.RenamePattern); err != nil {
+			return invalid(fmt.Errorf("-restore-rename-pattern: %w", err))
+		}
+	}
+	es, err := snapshotClient(ctx, opts.Load)
+	if err != nil {
+		return report, err
+	}
+	if err := verifySnapshotRepository(ctx, es, report.Repository); err != nil {
+		return report, err
+	}
+
+	body := map[string]any{"include_global_state": false}
+	if indices := snapshotIndices(opts.Load.Index); len(indices) > 0 {
+		body["indices"] = indices
+	}
+	if opts.RenamePattern != "" {
+		body["rename_pattern"] = opts.RenamePattern
+		body["rename_replacement"] = opts.RenameReplacement
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return report, &RunError{Kind: ErrLoaderExecution, Op: "serializing restore request", Err: err}
+	}
+	log.Info().Str("repository", report.Repository).Str("snapshot", report.Snapshot).Str("index", opts.Load.Index).Msg("Restoring snapshot")
+	res, err := es.Snapshot.Restore(report.Repository, report.Snapshot,
+		es.Snapshot.Restore.WithContext(ctx),
+		es.Snapshot.Restore.WithBody(strings.NewReader(string(payload))),
+		es.Snapshot.Restore.WithWaitForCompletion(!opts.NoWait),
+	)
+	if err := readSnapshotResponse(res, err, &report); err != nil {
+		return report, &RunError{Kind: ErrIndexOperation, Op: "restoring snapshot", Err: err}
+	}
+	if opts.NoWait {
+		log.Info().Str("repository", report.Repository).Str("snapshot", report.Snapshot).Msg("Restore started")
+		return report, nil
+	}
+	if report.FailedShards > 0 {
+		return report, &RunError{Kind: ErrIndexOperation, Op: "restoring snapshot", Err: fmt.Errorf("restore of %s finished with %d of %d shards failed", report.Snapshot, report.FailedShards, report.Shards)}
+	}
+	log.Info().
+		Str("repository", report.Repository).
+		Str("snapshot", report.Snapshot).
+		Strs("indices", report.Indices).
+		Int("shards", report.Shards).
+		Msg("Restore complete")
+	return report, nil
+}
+
+// snapshotClient connects to the single cluster of load the way Run does.
+func snapshotClient(ctx context.Context, load Options) (*elasticsearch.Client, error) {
+	if load.URL == "" {
+		load.URL = "http://localhost:9200"
+	}
+	if len(load.Clusters) > 1 {
+		return nil, &RunError{Kind: ErrInvalidOptions, Op: "validating snapshot options", Err: fmt.Errorf("snapshots are taken on one cluster; pass a single -url")}
+	}
+	var info ClusterInfo
+	var err error
+	if load.Preflight {
+		if info, err = preflightCluster(ctx, load); err != nil {
+			return nil, err
+		}
+	}
+	cfg := elasticsearchConfig(load)
+	if major := legacyMajor(info, load.DocType); major > 0 {
+		cfg.Transport = &compatTransport{base: cfg.Transport, major: major}
+	}
+	es, err := elasticsearch.NewClient(cfg)
+	if err != nil {
+		return nil, &RunError{Kind: ErrLoaderExecution, Op: "creating Elasticsearch client", Err: err}
+	}
+	return es, nil
+}
+
+// verifySnapshotRepository checks that every node can write to repository
+// before a snapshot or restore relies on it.
+func verifySnapshotRepository(ctx context.Context, es *elasticsearch.Client, repository string) error {
+	res, err := es.Snapshot.VerifyRepository(repository, es.Snapshot.VerifyRepository.WithContext(ctx))
+	if err != nil {
+		return &RunError{Kind: ErrIndexOperation, Op: "verifying snapshot repository", Err: err}
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return &RunError{Kind: ErrIndexOperation, Op: "verifying snapshot repository", Err: fmt.Errorf("repository %s: status %d: %s", repository, res.StatusCode, strings.TrimSpace(string(body)))}
+	}
+	var verified struct {
+		Nodes map[string]any `json:"nodes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&verified); err != nil {
+		return &RunError{Kind: ErrIndexOperation, Op: "verifying snapshot repository", Err: fmt.Errorf("parsing verification: %w", err)}
+	}
+	log.Info().Str("repository", repository).Int("nodes", len(verified.Nodes)).Msg("Snapshot repository verified")
+	return nil
+}
+
+// readSnapshotResponse fills report from the answer to a snapshot or
+// restore request.
+func readSnapshotResponse(res *esapi.Response, err error, report *SnapshotReport) error {
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.IsError() {
+		return fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var parsed snapshotResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	if parsed.Snapshot != nil {
+		report.State = parsed.Snapshot.State
+		report.Indices = parsed.Snapshot.Indices
+		report.Shards = parsed.Snapshot.Shards.Total
+		report.FailedShards = parsed.Snapshot.Shards.Failed
+	}
+	return nil
+}
+
+// snapshotIndices splits a comma-separated -index value.
+func snapshotIndices(index string) []string {
+	var indices []string
+	for _, name := range strings.Split(index, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			indices = append(indices, name)
+		}
+	}
+	return indices
+}
@@ -0,0 +1,210 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// snapshotServer answers repository verification and records snapshot and
+// restore requests.
+type snapshotServer struct {
+	mu       sync.Mutex
+	verified bool
+	missing  bool
+	answer   string
+	paths    []string
+	queries  []string
+	bodies   []map[string]any
+}
+
+func (s *snapshotServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/_verify"):
+		if s.missing {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"type":"repository_missing_exception"},"status":404}`))
+			return
+		}
+		s.verified = true
+		_, _ = w.Write([]byte(`{"nodes":{"a":{"name":"node-a"},"b":{"name":"node-b"}}}`))
+	case strings.HasPrefix(r.URL.Path, "/_snapshot/backups/"):
+		body, _ := io.ReadAll(r.Body)
+		var parsed map[string]any
+		_ = json.Unmarshal(body, &parsed)
+		s.paths = append(s.paths, r.Method+" "+r.URL.Path)
+		s.queries = append(s.queries, r.URL.RawQuery)
+		s.bodies = append(s.bodies, parsed)
+		_, _ = w.Write([]byte(s.answer))
+	default:
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+// TestSnapshot verifies behavior for the related scenario.
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    SnapshotOptions
+		answer  string
+		missing bool
+		error   error
+		state   string
+		indices []any
+	}{
+		{
+			name:    "waits for success",
+			opts:    SnapshotOptions{Repository: "backups", Name: "nightly", Load: Options{Index: "customers, orders"}},
+			answer:  `{"snapshot":{"snapshot":"nightly","state":"SUCCESS","indices":["customers","orders"],"shards":{"total":4,"failed":0}}}`,
+			state:   "SUCCESS",
+			indices: []any{"customers", "orders"},
+		},
+		{
+			name:   "partial snapshot fails",
+			opts:   SnapshotOptions{Repository: "backups", Name: "nightly"},
+			answer: `{"snapshot":{"snapshot":"nightly","state":"PARTIAL","indices":["customers"],"shards":{"total":4,"failed":1}}}`,
+			error:  ErrIndexOperation,
+			state:  "PARTIAL",
+		},
+		{
+			name:   "no wait",
+			opts:   SnapshotOptions{Repository: "backups", Name: "nightly", NoWait: true},
+			answer: `{"accepted":true}`,
+		},
+		{
+			name:    "missing repository",
+			opts:    SnapshotOptions{Repository: "backups", Name: "nightly"},
+			missing: true,
+			error:   ErrIndexOperation,
+		},
+		{name: "no repository", opts: SnapshotOptions{Name: "nightly"}, error: ErrInvalidOptions},
+		{name: "uppercase name", opts: SnapshotOptions{Repository: "backups", Name: "Nightly"}, error: ErrInvalidOptions},
+	}
+	for _, tt := range tests {
+		server := &snapshotServer{answer: tt.answer, missing: tt.missing}
+		httpServer := httptest.NewServer(server)
+		tt.opts.Load.URL = httpServer.URL
+		report, err := Snapshot(context.Background(), tt.opts)
+		httpServer.Close()
+		if tt.error == nil && err != nil {
+			t.Fatalf("%s: Snapshot: %v", tt.name, err)
+		}
+		if tt.error != nil && !errors.Is(err, tt.error) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.error, err)
+		}
+		if report.State != tt.state {
+			t.Fatalf("%s: expected state %q, got %q", tt.name, tt.state, report.State)
+		}
+		if tt.error == ErrInvalidOptions || tt.missing {
+			if len(server.paths) != 0 {
+				t.Fatalf("%s: expected no snapshot request, got %v", tt.name, server.paths)
+			}
+			continue
+		}
+		if !server.verified {
+			t.Fatalf("%s: expected the repository to be verified", tt.name)
+		}
+		if len(server.paths) != 1 || server.paths[0] != "PUT /_snapshot/backups/nightly" {
+			t.Fatalf("%s: expected one snapshot request, got %v", tt.name, server.paths)
+		}
+		if wait := strings.Contains(server.queries[0], "wait_for_completion=true"); wait == tt.opts.NoWait {
+			t.Fatalf("%s: expected wait_for_completion=%t, got query %q", tt.name, !tt.opts.NoWait, server.queries[0])
+		}
+		body := server.bodies[0]
+		if body["include_global_state"] != false {
+			t.Fatalf("%s: expected include_global_state false, got %v", tt.name, body)
+		}
+		if indices, _ := body["indices"].([]any); !slices.Equal(indices, tt.indices) {
+			t.Fatalf("%s: expected indices %v, got %v", tt.name, tt.indices, body["indices"])
+		}
+	}
+}
+
+// TestSnapshotDefaultName verifies behavior for the related scenario.
+func TestSnapshotDefaultName(t *testing.T) {
+	t.Parallel()
+
+	server := &snapshotServer{answer: `{"accepted":true}`}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	report, err := Snapshot(context.Background(), SnapshotOptions{Repository: "backups", NoWait: true, Load: Options{URL: httpServer.URL}})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if !strings.HasPrefix(report.Snapshot, "es-bulk-loader-") || server.paths[0] != "PUT /_snapshot/backups/"+report.Snapshot {
+		t.Fatalf("expected a generated es-bulk-loader- snapshot name, got %q and %v", report.Snapshot, server.paths)
+	}
+}
+
+// TestRestore verifies behavior for the related scenario.
+func TestRestore(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		opts   RestoreOptions
+		answer string
+		error  error
+		body   map[string]any
+	}{
+		{
+			name:   "renames restored indices",
+			opts:   RestoreOptions{Repository: "backups", Name: "nightly", RenamePattern: "(.+)", RenameReplacement: "restored-$1", Load: Options{Index: "customers"}},
+			answer: `{"snapshot":{"snapshot":"nightly","indices":["restored-customers"],"shards":{"total":2,"failed":0}}}`,
+			body:   map[string]any{"include_global_state": false, "indices": []any{"customers"}, "rename_pattern": "(.+)", "rename_replacement": "restored-$1"},
+		},
+		{
+			name:   "failed shards",
+			opts:   RestoreOptions{Repository: "backups", Name: "nightly"},
+			answer: `{"snapshot":{"snapshot":"nightly","indices":["customers"],"shards":{"total":2,"failed":1}}}`,
+			error:  ErrIndexOperation,
+			body:   map[string]any{"include_global_state": false},
+		},
+		{name: "no snapshot name", opts: RestoreOptions{Repository: "backups"}, error: ErrInvalidOptions},
+		{name: "replacement without pattern", opts: RestoreOptions{Repository: "backups", Name: "nightly", RenameReplacement: "x"}, error: ErrInvalidOptions},
+		{name: "bad pattern", opts: RestoreOptions{Repository: "backups", Name: "nightly", RenamePattern: "("}, error: ErrInvalidOptions},
+	}
+	for _, tt := range tests {
+		server := &snapshotServer{answer: tt.answer}
+		httpServer := httptest.NewServer(server)
+		tt.opts.Load.URL = httpServer.URL
+		report, err := Restore(context.Background(), tt.opts)
+		httpServer.Close()
+		if tt.error == nil && err != nil {
+			t.Fatalf("%s: Restore: %v", tt.name, err)
+		}
+		if tt.error != nil && !errors.Is(err, tt.error) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.error, err)
+		}
+		if tt.body == nil {
+			if len(server.paths) != 0 {
+				t.Fatalf("%s: expected no restore request, got %v", tt.name, server.paths)
+			}
+			continue
+		}
+		if len(server.paths) != 1 || server.paths[0] != "POST /_snapshot/backups/nightly/_restore" {
+			t.Fatalf("%s: expected one restore request, got %v", tt.name, server.paths)
+		}
+		encoded, _ := json.Marshal(server.bodies[0])
+		want, _ := json.Marshal(tt.body)
+		if string(encoded) != string(want) {
+			t.Fatalf("%s: expected restore body %s, got %s", tt.name, want, encoded)
+		}
+		if tt.error == nil && (report.Shards != 2 || report.Indices[0] != "restored-customers") {
+			t.Fatalf("%s: expected the restored indices in the report, got %+v", tt.name, report)
+		}
+	}
+}
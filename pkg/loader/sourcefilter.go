@@ -0,0 +1,100 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// ─── Stored Source Filtering ───────────────────────────────────────────────────
+
+// validateSourceFiltering rejects empty -source-includes and
+// -source-excludes paths.
+func validateSourceFiltering(includes, excludes []string) error {
+	for _, path := range append(slices.Clone(includes), excludes...) {
+		if strings.TrimSpace(path) == "" {
+			return fmt.Errorf("-source-includes and -source-excludes paths must not be empty")
+		}
+	}
+	return nil
+}
+
+// addSourceFiltering adds includes and excludes to the _source mapping of a
+// create index body, after any the mappings file already lists, so the
+// fields are indexed but not kept in the stored _source.
+func addSourceFiltering(body string, includes, excludes []string) (string, error) {
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		return "", err
+	}
+	mappings, _ := parsed["mappings"].(map[string]interface{})
+	if mappings == nil {
+		mappings = make(map[string]interface{})
+		parsed["mappings"] = mappings
+	}
+	source, _ := mappings["_source"].(map[string]interface{})
+	if source == nil {
+		source = make(map[string]interface{})
+		mappings["_source"] = source
+	}
+	for key, paths := range map[string][]string{"includes": includes, "excludes": excludes} {
+		if len(paths) == 0 {
+			continue
+		}
+		merged := sourceFilterPaths(source[key])
+		for _, path := range paths {
+			if path = strings.TrimSpace(path); !slices.Contains(merged, path) {
+				merged = append(merged, path)
+			}
+		}
+		source[key] = merged
+	}
+	encoded, err := json.Marshal(parsed)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// missingSourceFilters returns the includes and excludes the _source mapping
+// of an existing index does not list. _source filtering is fixed when an
+// index is created, so they cannot be added to it.
+func missingSourceFilters(es *elasticsearch.Client, index string, includes, excludes []string) ([]string, error) {
+	mappings, err := fetchIndexMappings(es, index)
+	if err != nil {
+		return nil, err
+	}
+	source, _ := mappings["_source"].(map[string]any)
+	var missing []string
+	for key, paths := range map[string][]string{"includes": includes, "excludes": excludes} {
+		current := sourceFilterPaths(source[key])
+		for _, path := range paths {
+			if path = strings.TrimSpace(path); !slices.Contains(current, path) {
+				missing = append(missing, key+":"+path)
+			}
+		}
+	}
+	slices.Sort(missing)
+	return missing, nil
+}
+
+// sourceFilterPaths returns the paths of an includes or excludes mapping
+// value, a string or an array of strings.
+func sourceFilterPaths(value interface{}) []string {
+	switch typed := value.(type) {
+	case string:
+		return []string{typed}
+	case []interface{}:
+		paths := make([]string, 0, len(typed))
+		for _, element := range typed {
+			if path, ok := element.(string); ok {
+				paths = append(paths, path)
+			}
+		}
+		return paths
+	}
+	return nil
+}
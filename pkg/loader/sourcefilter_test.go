@@ -0,0 +1,116 @@
+package loader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestAddSourceFiltering verifies behavior for the related scenario.
+func TestAddSourceFiltering(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "no mappings",
+			body: `{"settings":{"number_of_shards":1}}`,
+			want: `{"mappings":{"_source":{"excludes":["body.raw"]}},"settings":{"number_of_shards":1}}`,
+		},
+		{
+			name: "merged with the mappings file",
+			body: `{"mappings":{"_source":{"excludes":"blob"},"properties":{"body":{"type":"object"}}}}`,
+			want: `{"mappings":{"_source":{"excludes":["blob","body.raw"]},"properties":{"body":{"type":"object"}}}}`,
+		},
+		{
+			name: "already listed",
+			body: `{"mappings":{"_source":{"excludes":["body.raw"]}}}`,
+			want: `{"mappings":{"_source":{"excludes":["body.raw"]}}}`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := addSourceFiltering(tt.body, nil, []string{" body.raw "})
+		if err != nil {
+			t.Fatalf("%s: addSourceFiltering returned error: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+	if err := validateSourceFiltering([]string{"title"}, []string{" "}); err == nil {
+		t.Fatalf("expected an empty path to be rejected")
+	}
+}
+
+// TestRunSourceFilteringCreatesAndWarns verifies behavior for the related scenario.
+func TestRunSourceFilteringCreatesAndWarns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		exists      bool
+		wantCreate  string
+		wantWarning string
+	}{
+		{name: "created", wantCreate: `"_source":{"excludes":["body.raw"],"includes":["*"]}`},
+		{name: "existing", exists: true, wantWarning: "excludes:body.raw"},
+	}
+	for _, tt := range tests {
+		var mu sync.Mutex
+		var createBody string
+		exists := tt.exists
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Elastic-Product", "Elasticsearch")
+			switch {
+			case r.Method == http.MethodHead && r.URL.Path == "/mail":
+				mu.Lock()
+				defer mu.Unlock()
+				if exists {
+					w.WriteHeader(http.StatusOK)
+				} else {
+					w.WriteHeader(http.StatusNotFound)
+				}
+			case r.Method == http.MethodPut && r.URL.Path == "/mail":
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				createBody = string(body)
+				exists = true
+				mu.Unlock()
+				_, _ = w.Write([]byte(`{"acknowledged":true}`))
+			case r.Method == http.MethodGet && r.URL.Path == "/mail/_mapping":
+				_, _ = w.Write([]byte(`{"mail":{"mappings":{"_source":{"includes":["*"]}}}}`))
+			case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+				_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+			default:
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{}`))
+			}
+		}))
+		result, err := Run(context.Background(), Options{
+			URL:            server.URL,
+			Index:          "mail",
+			DataFile:       writeTestDataFile(t, "mail.ndjson", "{\"body\":{\"raw\":\"...\"}}\n"),
+			AddToIndex:     true,
+			SourceIncludes: []string{"*"},
+			SourceExcludes: []string{"body.raw"},
+		})
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		if !strings.Contains(createBody, tt.wantCreate) || (tt.wantCreate == "" && createBody != "") {
+			t.Fatalf("%s: expected create body with %s, got %q", tt.name, tt.wantCreate, createBody)
+		}
+		warnings := strings.Join(result.Warnings, "\n")
+		if tt.wantWarning != "" && !strings.Contains(warnings, tt.wantWarning) || tt.wantWarning == "" && strings.Contains(warnings, "_source") {
+			t.Fatalf("%s: unexpected warnings %q", tt.name, warnings)
+		}
+	}
+}
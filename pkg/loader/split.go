@@ -0,0 +1,368 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Input Splitting ───────────────────────────────────────────────────────────
+
+// SplitOptions configures Split.
+type SplitOptions struct {
+	// Documents is the most documents per chunk; 0 leaves the count
+	// unbounded.
+	Documents int
+	// MaxBytes is the largest chunk file, header included; 0 leaves the
+	// size unbounded. A single larger document gets a chunk of its own.
+	MaxBytes int64
+	// OutputDir receives the chunks; defaults to the data file's directory.
+	OutputDir string
+	// Load names the data file and its format, as for Run.
+	Load Options
+}
+
+// splitFormats lists the formats Split can cut without re-encoding.
+var splitFormats = []string{formatJSON, formatNDJSON, formatCSV, formatFixed, formatElasticdump}
+
+// Split cuts the data file into chunk files of at most Documents documents
+// and MaxBytes bytes, named <name>-00001<ext> and so on, so the chunks can
+// be loaded from several machines or retried one by one. Documents are
+// copied byte for byte: CSV chunks each repeat the header row, and JSON
+// array chunks are arrays of their own. It returns the chunk paths.
+func Split(ctx context.Context, opts SplitOptions) ([]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	load := opts.Load
+	invalid := func(err error) ([]string, error) {
+		return nil, &RunError{Kind: ErrInvalidOptions, Op: "validating split options", Err: err}
+	}
+	if opts.Documents < 0 || opts.MaxBytes < 0 {
+		return invalid(fmt.Errorf("-split-documents and -split-size must be 0 or greater"))
+	}
+	if opts.Documents == 0 && opts.MaxBytes == 0 {
+		return invalid(fmt.Errorf("split needs -split-documents, -split-size, or both"))
+	}
+	if strings.TrimSpace(load.DataFile) == "" {
+		return invalid(fmt.Errorf("-data is required"))
+	}
+	if len(load.DataFiles) > 0 {
+		return invalid(fmt.Errorf("split reads one -data file"))
+	}
+	if isArchive(load.DataFile) {
+		return invalid(fmt.Errorf("split cannot cut archive %s; extract it first", load.DataFile))
+	}
+	input, err := newInputConfig(load)
+	if err != nil {
+		return invalid(err)
+	}
+	format := input.formatFor(load.DataFile)
+	supported := false
+	for _, known := range splitFormats {
+		supported = supported || format == known
+	}
+	if !supported {
+		return invalid(fmt.Errorf("split supports -format %s, not %s", strings.Join(splitFormats, ", "), format))
+	}
+
+	file, err := os.Open(load.DataFile)
+	if err != nil {
+		return nil, &RunError{Kind: ErrLoaderExecution, Op: "opening data file", Err: err}
+	}
+	defer file.Close()
+	var (
+		records splitRecordReader
+		layout  splitLayout
+	)
+	switch format {
+	case formatJSON:
+		records, err = newSplitJSONArray(file)
+		layout = splitLayout{prefix: []byte("[\n"), separator: []byte(",\n"), suffix: []byte("\n]\n")}
+	case formatCSV:
+		columns := input.columnMap
+		if columns == nil && strings.EqualFold(filepath.Ext(load.DataFile), ".tsv") {
+			columns = &columnMap{Header: true, Delimiter: "\t"}
+		}
+		var csvRecords *splitCSVRecords
+		csvRecords, err = newSplitCSVRecords(file, columns)
+		if err == nil {
+			records, layout.prefix = csvRecords, csvRecords.header
+		}
+	default:
+		records = &splitLines{reader: bufio.NewReader(file)}
+	}
+	if err != nil {
+		return nil, &RunError{Kind: ErrLoaderExecution, Op: "reading data file", Err: err}
+	}
+
+	dir := opts.OutputDir
+	if strings.TrimSpace(dir) == "" {
+		dir = filepath.Dir(load.DataFile)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, &RunError{Kind: ErrLoaderExecution, Op: "creating split directory", Err: err}
+	}
+	ext := filepath.Ext(load.DataFile)
+	name := strings.TrimSuffix(filepath.Base(load.DataFile), ext)
+
+	var (
+		paths []string
+		chunk *splitChunk
+		total int
+	)
+	finish := func() error {
+		if chunk == nil {
+			return nil
+		}
+		err := chunk.close(layout)
+		log.Debug().Str("chunk", chunk.path).Int("documents", chunk.documents).Int64("bytes", chunk.size).Msg("Wrote chunk")
+		chunk = nil
+		return err
+	}
+	for {
+		if total%1000 == 0 && ctx.Err() != nil {
+			return paths, ctx.Err()
+		}
+		record, err := records.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return paths, &RunError{Kind: ErrLoaderExecution, Op: "reading data file", Err: fmt.Errorf("document %d: %w", total+1, err)}
+		}
+		if chunk != nil && chunk.full(opts, layout, record) {
+			if err := finish(); err != nil {
+				return paths, &RunError{Kind: ErrLoaderExecution, Op: "writing chunk", Err: err}
+			}
+		}
+		if chunk == nil {
+			path := filepath.Join(dir, fmt.Sprintf("%s-%05d%s", name, len(paths)+1, ext))
+			if chunk, err = newSplitChunk(path, layout); err != nil {
+				return paths, &RunError{Kind: ErrLoaderExecution, Op: "writing chunk", Err: err}
+			}
+			paths = append(paths, path)
+		}
+		if err := chunk.write(layout, record); err != nil {
+			return paths, &RunError{Kind: ErrLoaderExecution, Op: "writing chunk", Err: err}
+		}
+		total++
+	}
+	if err := finish(); err != nil {
+		return paths, &RunError{Kind: ErrLoaderExecution, Op: "writing chunk", Err: err}
+	}
+
+	log.Info().Str("data_file", load.DataFile).Str("dir", dir).Int("documents", total).Int("chunks", len(paths)).Msg("Split complete")
+	return paths, nil
+}
+
+// splitLayout is the framing around a chunk's documents: the CSV header
+// or JSON array brackets, and the text between documents.
+type splitLayout struct {
+	prefix    []byte
+	separator []byte
+	suffix    []byte
+}
+
+// splitChunk is the chunk file being written.
+type splitChunk struct {
+	path      string
+	file      *os.File
+	writer    *bufio.Writer
+	documents int
+	size      int64
+}
+
+// newSplitChunk creates path and writes the layout prefix.
+func newSplitChunk(path string, layout splitLayout) (*splitChunk, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	chunk := &splitChunk{path: path, file: file, writer: bufio.NewWriter(file)}
+	if _, err := chunk.writer.Write(layout.prefix); err != nil {
+		file.Close()
+		return nil, err
+	}
+	chunk.size = int64(len(layout.prefix) + len(layout.suffix))
+	return chunk, nil
+}
+
+// full reports whether record would take the chunk past either limit.
+func (c *splitChunk) full(opts SplitOptions, layout splitLayout, record []byte) bool {
+	if opts.Documents > 0 && c.documents >= opts.Documents {
+		return true
+	}
+	return opts.MaxBytes > 0 && c.size+int64(len(layout.separator)+len(record)) > opts.MaxBytes
+}
+
+// write appends one document.
+func (c *splitChunk) write(layout splitLayout, record []byte) error {
+	if c.documents > 0 {
+		if _, err := c.writer.Write(layout.separator); err != nil {
+			return err
+		}
+		c.size += int64(len(layout.separator))
+	}
+	if _, err := c.writer.Write(record); err != nil {
+		return err
+	}
+	c.documents++
+	c.size += int64(len(record))
+	return nil
+}
+
+// close writes the layout suffix and closes the file.
+func (c *splitChunk) close(layout splitLayout) error {
+	_, err := c.writer.Write(layout.suffix)
+	if err == nil {
+		err = c.writer.Flush()
+	}
+	if closeErr := c.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// splitRecordReader returns each document's bytes as they appear in the
+// data file.
+type splitRecordReader interface {
+	next() ([]byte, error)
+}
+
+// splitLines reads line-oriented formats, skipping blank lines as their
+// readers do. Each record keeps its line ending.
+type splitLines struct {
+	reader *bufio.Reader
+}
+
+// next returns the following non-blank line.
+func (s *splitLines) next() ([]byte, error) {
+	for {
+		line, err := s.reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+			return line, nil
+		}
+		if err != nil {
+			return nil, io.EOF
+		}
+	}
+}
+
+// splitJSONArray reads the elements of a top-level JSON array.
+type splitJSONArray struct {
+	dec *json.Decoder
+}
+
+// newSplitJSONArray consumes the opening bracket of the array.
+func newSplitJSONArray(file io.Reader) (*splitJSONArray, error) {
+	dec := json.NewDecoder(file)
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("JSON data file must be an array of objects")
+	}
+	return &splitJSONArray{dec: dec}, nil
+}
+
+// next returns the following element as written.
+func (s *splitJSONArray) next() ([]byte, error) {
+	if !s.dec.More() {
+		return nil, io.EOF
+	}
+	var element json.RawMessage
+	if err := s.dec.Decode(&element); err != nil {
+		return nil, err
+	}
+	return element, nil
+}
+
+// splitCSVRecords reads CSV records with the csv package, so quoted cells
+// may span lines, and returns each record's original bytes.
+type splitCSVRecords struct {
+	csv    *csv.Reader
+	tee    *splitRecorder
+	header []byte
+}
+
+// newSplitCSVRecords reads the header row, if columns say there is one.
+func newSplitCSVRecords(file io.Reader, columns *columnMap) (*splitCSVRecords, error) {
+	if columns == nil {
+		columns = &columnMap{Header: true}
+	}
+	tee := &splitRecorder{reader: file}
+	reader := csv.NewReader(tee)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	reader.LazyQuotes = true
+	if columns.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(columns.Delimiter)
+	}
+	s := &splitCSVRecords{csv: reader, tee: tee}
+	if columns.Header {
+		header, err := s.next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("CSV data file is missing its header row")
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.header = header
+	}
+	return s, nil
+}
+
+// next returns the following record with its line ending.
+func (s *splitCSVRecords) next() ([]byte, error) {
+	if _, err := s.csv.Read(); err != nil {
+		return nil, err
+	}
+	record := s.tee.take(s.csv.InputOffset())
+	if record[len(record)-1] != '\n' {
+		record = append(record, '\n')
+	}
+	return record, nil
+}
+
+// splitRecorder keeps the bytes read through it until take hands them out.
+type splitRecorder struct {
+	reader io.Reader
+	buf    []byte
+	// offset is the input position of buf[0].
+	offset int64
+}
+
+// Read records what it reads.
+func (r *splitRecorder) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.buf = append(r.buf, p[:n]...)
+	return n, err
+}
+
+// take returns the recorded bytes up to the input position end.
+func (r *splitRecorder) take(end int64) []byte {
+	n := end - r.offset
+	record := append([]byte(nil), r.buf[:n]...)
+	r.buf = r.buf[n:]
+	r.offset = end
+	return record
+}
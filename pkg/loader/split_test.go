@@ -0,0 +1,88 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSplitWritesBoundedChunks verifies behavior for the related scenario.
+func TestSplitWritesBoundedChunks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		file      string
+		data      string
+		documents int
+		maxBytes  int64
+		want      []string
+	}{
+		{
+			name:      "ndjson by count",
+			file:      "events.ndjson",
+			data:      "{\"n\":1}\n\n{\"n\":2}\n{\"n\":3}",
+			documents: 2,
+			want:      []string{"{\"n\":1}\n{\"n\":2}\n", "{\"n\":3}\n"},
+		},
+		{
+			name:     "csv by size repeats the header",
+			file:     "cards.csv",
+			data:     "code,note\na,\"two\nlines\"\nb,short\nc,x\n",
+			maxBytes: 25,
+			want:     []string{"code,note\na,\"two\nlines\"\n", "code,note\nb,short\nc,x\n"},
+		},
+		{
+			name:      "json array",
+			file:      "cards.json",
+			data:      "[\n  {\"n\": 1},\n  {\"n\": 2},\n  {\"n\": 3}\n]\n",
+			documents: 2,
+			want:      []string{"[\n{\"n\": 1},\n{\"n\": 2}\n]\n", "[\n{\"n\": 3}\n]\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			dataFile := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(dataFile, []byte(tt.data), 0o600); err != nil {
+				t.Fatalf("WriteFile returned error: %v", err)
+			}
+			outDir := filepath.Join(dir, "chunks")
+			paths, err := Split(context.Background(), SplitOptions{Documents: tt.documents, MaxBytes: tt.maxBytes, OutputDir: outDir, Load: Options{DataFile: dataFile}})
+			if err != nil {
+				t.Fatalf("Split returned error: %v", err)
+			}
+			if len(paths) != len(tt.want) {
+				t.Fatalf("expected %d chunks, got %v", len(tt.want), paths)
+			}
+			ext := filepath.Ext(tt.file)
+			for i, path := range paths {
+				if wantName := fmt.Sprintf("%s-%05d%s", strings.TrimSuffix(tt.file, ext), i+1, ext); filepath.Base(path) != wantName || filepath.Dir(path) != outDir {
+					t.Fatalf("chunk %d: expected %s in %s, got %s", i+1, wantName, outDir, path)
+				}
+				got, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("ReadFile returned error: %v", err)
+				}
+				if string(got) != tt.want[i] {
+					t.Fatalf("chunk %d: expected %q, got %q", i+1, tt.want[i], got)
+				}
+			}
+		})
+	}
+
+	_, err := Split(context.Background(), SplitOptions{Load: Options{DataFile: "events.ndjson"}})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions without a limit, got %v", err)
+	}
+	_, err = Split(context.Background(), SplitOptions{Documents: 1, Load: Options{DataFile: "events.cbor"}})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions for CBOR, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// ─── Amazon SQS Source ─────────────────────────────────────────────────────────
+
+const (
+	// sqsMaxMessages is the most messages one ReceiveMessage returns and one
+	// batch call settles.
+	sqsMaxMessages = 10
+	// sqsMaxWaitSeconds is the longest long poll ReceiveMessage allows.
+	sqsMaxWaitSeconds = 20
+)
+
+// sqsSource receives messages from one SQS queue with the AWS SDK.
+type sqsSource struct {
+	client   *sqs.Client
+	queueURL string
+	region   string
+	// visibility, when positive, is the visibility timeout in seconds
+	// requested for each received message.
+	visibility int32
+}
+
+// newSQSSource parses sqs://<host>/<account>/<queue>. The query may set
+// region, endpoint (such as http://localhost:4566 for LocalStack), and
+// visibility_timeout in seconds. Credentials and any region not named in
+// the URL come from the AWS SDK's default chain; loadOptions add to it.
+func newSQSSource(u *url.URL, loadOptions ...func(*config.LoadOptions) error) (*sqsSource, error) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host == "" || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("-source %s: expected sqs://sqs.<region>.amazonaws.com/<account>/<queue>", redactSourceURL(u.String()))
+	}
+	query := u.Query()
+	endpoint := "https://" + u.Host
+	if raw := query.Get("endpoint"); raw != "" {
+		parsed, err := url.Parse(raw)
+		if err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("-source endpoint %q is not a URL", raw)
+		}
+		endpoint = strings.TrimSuffix(raw, "/")
+	}
+	s := &sqsSource{queueURL: endpoint + "/" + parts[0] + "/" + parts[1]}
+	if raw := query.Get("visibility_timeout"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 || seconds > 43200 {
+			return nil, fmt.Errorf("-source visibility_timeout %q: expected 0 to 43200 seconds", raw)
+		}
+		s.visibility = int32(seconds)
+	}
+	region := query.Get("region")
+	if region == "" {
+		region = sqsHostRegion(u.Host)
+	}
+	if region != "" {
+		loadOptions = append(loadOptions, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), loadOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("-source %s: set ?region= or AWS_REGION", redactSourceURL(u.String()))
+	}
+	s.region = cfg.Region
+	s.client = sqs.NewFromConfig(cfg, func(o *sqs.Options) {
+		if query.Get("endpoint") != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+	return s, nil
+}
+
+// sqsHostRegion returns the region in an sqs.<region>.amazonaws.com or
+// legacy <region>.queue.amazonaws.com host name, or "".
+func sqsHostRegion(host string) string {
+	labels := strings.Split(strings.ToLower(host), ".")
+	switch {
+	case len(labels) >= 4 && labels[0] == "sqs" && labels[2] == "amazonaws":
+		return labels[1]
+	case len(labels) >= 4 && labels[1] == "queue" && labels[2] == "amazonaws":
+		return labels[0]
+	}
+	return ""
+}
+
+// receive long-polls the queue for up to ten messages.
+func (s *sqsSource) receive(ctx context.Context, max int, wait time.Duration) ([]sourceMessage, error) {
+	input := &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(s.queueURL),
+		MaxNumberOfMessages: int32(min(max, sqsMaxMessages)),
+		WaitTimeSeconds:     int32(min(int(math.Ceil(wait.Seconds())), sqsMaxWaitSeconds)),
+	}
+	if s.visibility > 0 {
+		input.VisibilityTimeout = s.visibility
+	}
+	answer, err := s.client.ReceiveMessage(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("SQS ReceiveMessage: %w", err)
+	}
+	msgs := make([]sourceMessage, 0, len(answer.Messages))
+	for _, m := range answer.Messages {
+		msgs = append(msgs, sourceMessage{body: []byte(aws.ToString(m.Body)), handle: aws.ToString(m.ReceiptHandle)})
+	}
+	return msgs, nil
+}
+
+// ack deletes msgs from the queue, ten at a time.
+func (s *sqsSource) ack(ctx context.Context, msgs []sourceMessage) error {
+	for chunk := range slices.Chunk(msgs, sqsMaxMessages) {
+		entries := make([]types.DeleteMessageBatchRequestEntry, len(chunk))
+		for i, msg := range chunk {
+			entries[i] = types.DeleteMessageBatchRequestEntry{Id: aws.String(strconv.Itoa(i)), ReceiptHandle: aws.String(msg.handle)}
+		}
+		answer, err := s.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{QueueUrl: aws.String(s.queueURL), Entries: entries})
+		if err != nil {
+			return fmt.Errorf("SQS DeleteMessageBatch: %w", err)
+		}
+		if err := sqsBatchFailure("DeleteMessageBatch", answer.Failed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nack makes msgs visible again at once, ten at a time.
+func (s *sqsSource) nack(ctx context.Context, msgs []sourceMessage) error {
+	for chunk := range slices.Chunk(msgs, sqsMaxMessages) {
+		entries := make([]types.ChangeMessageVisibilityBatchRequestEntry, len(chunk))
+		for i, msg := range chunk {
+			entries[i] = types.ChangeMessageVisibilityBatchRequestEntry{Id: aws.String(strconv.Itoa(i)), ReceiptHandle: aws.String(msg.handle), VisibilityTimeout: 0}
+		}
+		answer, err := s.client.ChangeMessageVisibilityBatch(ctx, &sqs.ChangeMessageVisibilityBatchInput{QueueUrl: aws.String(s.queueURL), Entries: entries})
+		if err != nil {
+			return fmt.Errorf("SQS ChangeMessageVisibilityBatch: %w", err)
+		}
+		if err := sqsBatchFailure("ChangeMessageVisibilityBatch", answer.Failed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close is a no-op; SQS holds no connection open.
+func (s *sqsSource) close() error {
+	return nil
+}
+
+// sqsBatchFailure describes the entries a batch call could not settle.
+func sqsBatchFailure(action string, failed []types.BatchResultErrorEntry) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	first := failed[0]
+	return fmt.Errorf("SQS %s failed for %d messages: %s: %s", action, len(failed), aws.ToString(first.Code), aws.ToString(first.Message))
+}
@@ -0,0 +1,124 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// TestSQSSourceSettlesMessages verifies behavior for the related scenario.
+func TestSQSSourceSettlesMessages(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu    sync.Mutex
+		calls = map[string][]map[string]interface{}{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/sqs/aws4_request") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"__type":"com.amazonaws.sqs#InvalidClientTokenId","message":"bad signature"}`))
+			return
+		}
+		action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSQS.")
+		var request map[string]interface{}
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &request)
+		mu.Lock()
+		calls[action] = append(calls[action], request)
+		mu.Unlock()
+		switch action {
+		case "ReceiveMessage":
+			_, _ = w.Write([]byte(`{"Messages":[{"MessageId":"1","ReceiptHandle":"rh-1","Body":"{\"msg\":\"one\"}"},{"MessageId":"2","ReceiptHandle":"rh-2","Body":"{\"msg\":\"two\"}"}]}`))
+		case "DeleteMessageBatch", "ChangeMessageVisibilityBatch":
+			_, _ = w.Write([]byte(`{"Successful":[{"Id":"0"}]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"com.amazonaws.sqs#QueueDoesNotExist","message":"no such queue"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse("sqs://sqs.eu-west-1.amazonaws.com/123456789012/events?visibility_timeout=90&endpoint=" + url.QueryEscape(server.URL))
+	source, err := newSQSSource(u, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKID", "secret", "")))
+	if err != nil {
+		t.Fatalf("newSQSSource returned error: %v", err)
+	}
+	if source.region != "eu-west-1" || source.queueURL != server.URL+"/123456789012/events" {
+		t.Fatalf("unexpected region %q or queue URL %q", source.region, source.queueURL)
+	}
+
+	ctx := context.Background()
+	msgs, err := source.receive(ctx, 1000, 30*time.Second)
+	if err != nil {
+		t.Fatalf("receive returned error: %v", err)
+	}
+	if len(msgs) != 2 || string(msgs[0].body) != `{"msg":"one"}` || msgs[1].handle != "rh-2" {
+		t.Fatalf("unexpected messages %+v", msgs)
+	}
+	if err := source.ack(ctx, msgs[:1]); err != nil {
+		t.Fatalf("ack returned error: %v", err)
+	}
+	if err := source.nack(ctx, msgs[1:]); err != nil {
+		t.Fatalf("nack returned error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	receive := calls["ReceiveMessage"][0]
+	if receive["MaxNumberOfMessages"] != float64(10) || receive["WaitTimeSeconds"] != float64(20) || receive["VisibilityTimeout"] != float64(90) {
+		t.Fatalf("unexpected ReceiveMessage request %v", receive)
+	}
+	deleted := calls["DeleteMessageBatch"][0]["Entries"].([]interface{})[0].(map[string]interface{})
+	if deleted["ReceiptHandle"] != "rh-1" {
+		t.Fatalf("unexpected DeleteMessageBatch entry %v", deleted)
+	}
+	returned := calls["ChangeMessageVisibilityBatch"][0]["Entries"].([]interface{})[0].(map[string]interface{})
+	if returned["ReceiptHandle"] != "rh-2" || returned["VisibilityTimeout"] != float64(0) {
+		t.Fatalf("unexpected ChangeMessageVisibilityBatch entry %v", returned)
+	}
+}
+
+// TestNewSQSSourceValidatesURL verifies behavior for the related scenario.
+func TestNewSQSSourceValidatesURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		raw        string
+		wantRegion string
+		wantErr    string
+	}{
+		{name: "regional host", raw: "sqs://sqs.ap-south-1.amazonaws.com/1/q", wantRegion: "ap-south-1"},
+		{name: "legacy host", raw: "sqs://us-west-2.queue.amazonaws.com/1/q", wantRegion: "us-west-2"},
+		{name: "region query", raw: "sqs://localhost:4566/000000000000/q?region=us-east-1", wantRegion: "us-east-1"},
+		{name: "no queue", raw: "sqs://sqs.us-east-1.amazonaws.com/1", wantErr: "expected sqs://"},
+		{name: "visibility", raw: "sqs://sqs.us-east-1.amazonaws.com/1/q?visibility_timeout=soon", wantErr: "visibility_timeout"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.raw)
+		source, err := newSQSSource(u, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKID", "secret", "")))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if source.region != tt.wantRegion {
+			t.Fatalf("%s: expected region %q, got %q", tt.name, tt.wantRegion, source.region)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package loader
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ─── Live Load Statistics ──────────────────────────────────────────────────────
+
+const (
+	// throughputSampleInterval controls how often docs/sec samples are recorded.
+	throughputSampleInterval = time.Second
+	// throughputSampleLimit caps the retained docs/sec history.
+	throughputSampleLimit = 60
+)
+
+// workerStatus describes what a single bulk sender is doing right now.
+type workerStatus struct {
+	ID      int
+	State   string
+	Batch   int
+	Docs    int
+	Since   time.Time
+	Retries int
+}
+
+// loadStats accumulates live counters shared by bulk senders and observers.
+// All methods are safe on a nil receiver so callers never need to guard them.
+type loadStats struct {
+	mu sync.Mutex
+
+	started    time.Time
+	total      int
+	processed  int
+	succeeded  int
+	failed     int
+	batches    int
+	retries    int
+	rejections int
+	bytesSent  int64
+	errorTypes map[string]int
+	workers    map[int]*workerStatus
+
+	throughput    []float64
+	lastSample    time.Time
+	lastProcessed int
+
+	batchLatencies []time.Duration
+	tookLatencies  []time.Duration
+}
+
+// newLoadStats creates an empty statistics collector anchored at now.
+func newLoadStats(now time.Time) *loadStats {
+	return &loadStats{
+		started:    now,
+		lastSample: now,
+		errorTypes: make(map[string]int),
+		workers:    make(map[int]*workerStatus),
+	}
+}
+
+// setTotal records the expected number of documents for progress display.
+func (s *loadStats) setTotal(total int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total = total
+}
+
+// setWorkerState records the current activity of a bulk sender.
+func (s *loadStats) setWorkerState(worker int, state string, batch, docs int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.workers[worker]
+	if !ok {
+		status = &workerStatus{ID: worker}
+		s.workers[worker] = status
+	}
+	if status.State != state || status.Batch != batch {
+		status.Since = time.Now()
+	}
+	status.State = state
+	status.Batch = batch
+	status.Docs = docs
+}
+
+// recordRetry counts a retried bulk request for the given sender.
+func (s *loadStats) recordRetry(worker int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retries++
+	if status, ok := s.workers[worker]; ok {
+		status.Retries++
+	}
+}
+
+// recordRejection counts Elasticsearch queue rejections (HTTP 429 or
+// es_rejected_execution_exception items).
+func (s *loadStats) recordRejection(count int) {
+	if s == nil || count <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejections += count
+}
+
+// recordBatch folds a completed batch into the running totals. latency is the
+// client-observed request time and took is the server-reported "took" value.
+func (s *loadStats) recordBatch(docs, succeeded, failed int, bytes int64, errorTypes map[string]int, latency, took time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batchLatencies = append(s.batchLatencies, latency)
+	s.tookLatencies = append(s.tookLatencies, took)
+	s.batches++
+	s.processed += docs
+	s.succeeded += succeeded
+	s.failed += failed
+	s.bytesSent += bytes
+	for errorType, count := range errorTypes {
+		s.errorTypes[errorType] += count
+	}
+}
+
+// sampleThroughput appends a docs/sec sample when the sample interval elapsed.
+func (s *loadStats) sampleThroughput(now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elapsed := now.Sub(s.lastSample)
+	if elapsed < throughputSampleInterval {
+		return
+	}
+	rate := float64(s.processed-s.lastProcessed) / elapsed.Seconds()
+	s.throughput = append(s.throughput, rate)
+	if len(s.throughput) > throughputSampleLimit {
+		s.throughput = s.throughput[len(s.throughput)-throughputSampleLimit:]
+	}
+	s.lastSample = now
+	s.lastProcessed = s.processed
+}
+
+// loadStatsSnapshot is an immutable copy of loadStats for rendering.
+type loadStatsSnapshot struct {
+	Started    time.Time
+	Total      int
+	Processed  int
+	Succeeded  int
+	Failed     int
+	Batches    int
+	Retries    int
+	Rejections int
+	BytesSent  int64
+	ErrorTypes map[string]int
+	Workers    []workerStatus
+	Throughput []float64
+}
+
+// snapshot copies the current counters so observers can read them lock-free.
+func (s *loadStats) snapshot() loadStatsSnapshot {
+	if s == nil {
+		return loadStatsSnapshot{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := loadStatsSnapshot{
+		Started:    s.started,
+		Total:      s.total,
+		Processed:  s.processed,
+		Succeeded:  s.succeeded,
+		Failed:     s.failed,
+		Batches:    s.batches,
+		Retries:    s.retries,
+		Rejections: s.rejections,
+		BytesSent:  s.bytesSent,
+		ErrorTypes: make(map[string]int, len(s.errorTypes)),
+		Workers:    make([]workerStatus, 0, len(s.workers)),
+		Throughput: append([]float64(nil), s.throughput...),
+	}
+	for errorType, count := range s.errorTypes {
+		snap.ErrorTypes[errorType] = count
+	}
+	for _, status := range s.workers {
+		snap.Workers = append(snap.Workers, *status)
+	}
+	slices.SortFunc(snap.Workers, func(a, b workerStatus) int {
+		return a.ID - b.ID
+	})
+	return snap
+}
+
+// ─── Latency Summaries ─────────────────────────────────────────────────────────
+
+// LatencySummary reports the distribution of a set of per-batch latencies.
+type LatencySummary struct {
+	Count int
+	Min   time.Duration
+	Max   time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+}
+
+// summarizeLatencies computes nearest-rank percentiles over samples.
+func summarizeLatencies(samples []time.Duration) LatencySummary {
+	if len(samples) == 0 {
+		return LatencySummary{}
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	slices.Sort(sorted)
+	return LatencySummary{
+		Count: len(sorted),
+		Min:   sorted[0],
+		Max:   sorted[len(sorted)-1],
+		P50:   percentile(sorted, 50),
+		P95:   percentile(sorted, 95),
+		P99:   percentile(sorted, 99),
+	}
+}
+
+// percentile returns the nearest-rank percentile p (0..100) of sorted samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// latencySummaries returns the client latency and server took distributions.
+func (s *loadStats) latencySummaries() (LatencySummary, LatencySummary) {
+	if s == nil {
+		return LatencySummary{}, LatencySummary{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return summarizeLatencies(s.batchLatencies), summarizeLatencies(s.tookLatencies)
+}
+
+// logLatencySummary emits the latency distribution fields on a log event.
+func logLatencySummary(event *zerolog.Event, prefix string, summary LatencySummary) *zerolog.Event {
+	return event.
+		Float64(prefix+"_min_ms", durationMillis(summary.Min)).
+		Float64(prefix+"_p50_ms", durationMillis(summary.P50)).
+		Float64(prefix+"_p95_ms", durationMillis(summary.P95)).
+		Float64(prefix+"_p99_ms", durationMillis(summary.P99)).
+		Float64(prefix+"_max_ms", durationMillis(summary.Max))
+}
+
+// durationMillis converts a duration to fractional milliseconds for logs.
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
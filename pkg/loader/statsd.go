@@ -0,0 +1,98 @@
+package loader
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── StatsD Metric Emission ────────────────────────────────────────────────────
+
+const (
+	// defaultStatsDPrefix namespaces every emitted metric.
+	defaultStatsDPrefix = "es_bulk_loader."
+	// statsDMaxPacket keeps datagrams under common MTU limits.
+	statsDMaxPacket = 1432
+)
+
+// statsdClient emits StatsD/DogStatsD metrics over UDP. Emission is fire and
+// forget: a missing agent must never slow down or fail a load.
+type statsdClient struct {
+	conn   net.Conn
+	prefix string
+}
+
+// newStatsdClient dials addr over UDP. An empty addr disables emission and
+// returns a nil client, which is safe to use.
+func newStatsdClient(addr string) (*statsdClient, error) {
+	trimmed := strings.TrimSpace(addr)
+	if trimmed == "" {
+		return nil, nil
+	}
+	conn, err := net.Dial("udp", trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("dialing statsd address %q: %w", trimmed, err)
+	}
+	return &statsdClient{conn: conn, prefix: defaultStatsDPrefix}, nil
+}
+
+// close releases the UDP socket.
+func (c *statsdClient) close() {
+	if c == nil {
+		return
+	}
+	_ = c.conn.Close()
+}
+
+// count emits a counter increment.
+func (c *statsdClient) count(name string, value int64, tags ...string) {
+	c.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// timing emits a timer in milliseconds.
+func (c *statsdClient) timing(name string, value time.Duration, tags ...string) {
+	c.send(name, strconv.FormatFloat(durationMillis(value), 'f', 3, 64), "ms", tags)
+}
+
+// send formats and writes one metric line. DogStatsD "|#tag" suffixes are
+// ignored by plain StatsD agents.
+func (c *statsdClient) send(name, value, metricType string, tags []string) {
+	if c == nil {
+		return
+	}
+	line := formatStatsdLine(c.prefix+name, value, metricType, tags)
+	if len(line) > statsDMaxPacket {
+		log.Trace().Str("metric", name).Int("bytes", len(line)).Msg("Dropping oversized statsd packet")
+		return
+	}
+	if _, err := c.conn.Write([]byte(line)); err != nil {
+		log.Trace().Err(err).Str("metric", name).Msg("StatsD write failed")
+	}
+}
+
+// formatStatsdLine renders "<name>:<value>|<type>[|#tag,tag]".
+func formatStatsdLine(name, value, metricType string, tags []string) string {
+	line := name + ":" + value + "|" + metricType
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line
+}
+
+// recordBatch emits the per-batch metric set.
+func (c *statsdClient) recordBatch(index string, succeeded, failed int, bytes int64, latency, took time.Duration) {
+	if c == nil {
+		return
+	}
+	indexTag := "index:" + index
+	c.count("docs_indexed", int64(succeeded), indexTag)
+	c.count("docs_failed", int64(failed), indexTag)
+	c.count("bytes_sent", bytes, indexTag)
+	c.count("batches", 1, indexTag)
+	c.timing("batch_latency", latency, indexTag)
+	c.timing("batch_took", took, indexTag)
+}
@@ -0,0 +1,62 @@
+package loader
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStatsdClientRecordBatchEmitsTaggedMetrics verifies behavior for the related scenario.
+func TestStatsdClientRecordBatchEmitsTaggedMetrics(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	client, err := newStatsdClient(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("newStatsdClient returned error: %v", err)
+	}
+	t.Cleanup(client.close)
+
+	client.recordBatch("cards", 9, 1, 2048, 15*time.Millisecond, 7*time.Millisecond)
+
+	var got []string
+	buf := make([]byte, statsDMaxPacket)
+	_ = listener.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for len(got) < 6 {
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("ReadFrom returned error after %d packets: %v", len(got), err)
+		}
+		got = append(got, string(buf[:n]))
+	}
+
+	want := []string{
+		"es_bulk_loader.docs_indexed:9|c|#index:cards",
+		"es_bulk_loader.docs_failed:1|c|#index:cards",
+		"es_bulk_loader.bytes_sent:2048|c|#index:cards",
+		"es_bulk_loader.batches:1|c|#index:cards",
+		"es_bulk_loader.batch_latency:15.000|ms|#index:cards",
+		"es_bulk_loader.batch_took:7.000|ms|#index:cards",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected statsd packets:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+// TestNewStatsdClientEmptyAddrDisables verifies behavior for the related scenario.
+func TestNewStatsdClientEmptyAddrDisables(t *testing.T) {
+	t.Parallel()
+
+	client, err := newStatsdClient("  ")
+	if err != nil || client != nil {
+		t.Fatalf("expected nil client without error, got %v, %v", client, err)
+	}
+	client.recordBatch("cards", 1, 0, 10, time.Millisecond, time.Millisecond)
+	client.close()
+}
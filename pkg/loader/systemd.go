@@ -0,0 +1,210 @@
+package loader
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── systemd Integration ───────────────────────────────────────────────────────
+
+// systemdListenFDsStart is the first file descriptor systemd passes to a
+// socket-activated service.
+const systemdListenFDsStart = 3
+
+// Names a socket unit gives the sockets of Serve with FileDescriptorName=.
+const (
+	systemdSocketHTTP = "http"
+	systemdSocketGRPC = "grpc"
+)
+
+var (
+	// activatedOnce guards activatedFiles and activatedErr.
+	activatedOnce sync.Once
+	// activatedFiles holds the sockets systemd passed, by name. They stay
+	// open for the life of the process, so a re-executed reload inherits
+	// them again.
+	activatedFiles map[string]*os.File
+	// activatedErr is the error reading the passed sockets, if any.
+	activatedErr error
+)
+
+// NotifySystemd sends state, such as "READY=1" or "STOPPING=1", to the
+// service manager named by $NOTIFY_SOCKET. It does nothing when the process
+// does not run under a Type=notify unit.
+func NotifySystemd(state string) error {
+	return notifySocket(os.Getenv("NOTIFY_SOCKET"), state)
+}
+
+// notifySocket sends state to the datagram socket at path; a leading @ names
+// an abstract socket.
+func notifySocket(path, state string) error {
+	if path == "" {
+		return nil
+	}
+	if strings.HasPrefix(path, "@") {
+		path = "\x00" + path[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("connecting to NOTIFY_SOCKET: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("writing to NOTIFY_SOCKET: %w", err)
+	}
+	return nil
+}
+
+// watchdogInterval returns how often to ping the systemd watchdog: half of
+// WATCHDOG_USEC, or zero when the unit sets no WatchdogSec= or the watchdog
+// is meant for another process.
+func watchdogInterval(usec, pid string) time.Duration {
+	if usec == "" {
+		return 0
+	}
+	if pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Microsecond / 2
+}
+
+// notifyReady tells systemd a daemon mode is up, with status as its STATUS=
+// line, and pings the watchdog until the returned function is called.
+func notifyReady(status string) func() {
+	if err := NotifySystemd("READY=1\nSTATUS=" + status); err != nil {
+		log.Warn().Err(err).Msg("Notifying systemd failed")
+	}
+	interval := watchdogInterval(os.Getenv("WATCHDOG_USEC"), os.Getenv("WATCHDOG_PID"))
+	if interval == 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := NotifySystemd("WATCHDOG=1"); err != nil {
+					log.Warn().Err(err).Msg("Pinging the systemd watchdog failed")
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// systemdSockets returns the sockets systemd passed to the process, keyed
+// by FileDescriptorName=. Unnamed sockets are keyed http then grpc in the
+// order the socket unit lists them.
+func systemdSockets() (map[string]*os.File, error) {
+	activatedOnce.Do(func() {
+		activatedFiles, activatedErr = parseListenFDs(os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS"), os.Getenv("LISTEN_FDNAMES"))
+	})
+	return activatedFiles, activatedErr
+}
+
+// parseListenFDs reads the sockets LISTEN_PID, LISTEN_FDS, and
+// LISTEN_FDNAMES describe; there are none unless they were passed to this
+// process.
+func parseListenFDs(pid, fds, names string) (map[string]*os.File, error) {
+	if pid == "" || pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(fds)
+	if err != nil || count < 1 {
+		return nil, nil
+	}
+	socketNames, err := systemdSocketNames(count, names)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*os.File, count)
+	for i, name := range socketNames {
+		files[name] = os.NewFile(uintptr(systemdListenFDsStart+i), "systemd:"+name)
+	}
+	return files, nil
+}
+
+// systemdSocketNames returns the Serve socket each of count passed sockets
+// is, from the colon-separated LISTEN_FDNAMES. systemd names a socket
+// without FileDescriptorName= after its unit, so those are taken as http
+// then grpc in order.
+func systemdSocketNames(count int, names string) ([]string, error) {
+	var fdNames []string
+	if names != "" {
+		fdNames = strings.Split(names, ":")
+	}
+	defaults := []string{systemdSocketHTTP, systemdSocketGRPC}
+	socketNames := make([]string, 0, count)
+	seen := make(map[string]bool, count)
+	for i := 0; i < count; i++ {
+		name := ""
+		if i < len(fdNames) {
+			name = fdNames[i]
+		}
+		if name != systemdSocketHTTP && name != systemdSocketGRPC {
+			if i >= len(defaults) {
+				return nil, fmt.Errorf("systemd passed %d sockets; name them %s and %s with FileDescriptorName=", count, systemdSocketHTTP, systemdSocketGRPC)
+			}
+			name = defaults[i]
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("systemd passed two sockets named %s", name)
+		}
+		seen[name] = true
+		socketNames = append(socketNames, name)
+	}
+	return socketNames, nil
+}
+
+// activatedListener returns a listener on the socket systemd passed as name,
+// or nil when there is none and the caller should listen on its address.
+func activatedListener(name string) (net.Listener, error) {
+	files, err := systemdSockets()
+	if err != nil {
+		return nil, err
+	}
+	file, ok := files[name]
+	if !ok {
+		return nil, nil
+	}
+	// FileListener duplicates the descriptor, so the inherited one stays
+	// open for a later reload.
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("socket %s from systemd: %w", name, err)
+	}
+	return listener, nil
+}
+
+// listen returns the socket systemd passed as name, or a new TCP listener on
+// addr when the process was not socket-activated.
+func listen(name, addr string) (net.Listener, error) {
+	listener, err := activatedListener(name)
+	if err != nil || listener != nil {
+		if listener != nil {
+			log.Info().Str("socket", name).Str("addr", listener.Addr().String()).Msg("Using socket passed by systemd")
+		}
+		return listener, err
+	}
+	return net.Listen("tcp", addr)
+}
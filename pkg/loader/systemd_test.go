@@ -0,0 +1,107 @@
+package loader
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNotifySocket verifies behavior for the related scenario.
+func TestNotifySocket(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram returned error: %v", err)
+	}
+	defer conn.Close()
+
+	if err := notifySocket("", "READY=1"); err != nil {
+		t.Fatalf("expected no NOTIFY_SOCKET to be a no-op, got %v", err)
+	}
+	if err := notifySocket(path, "READY=1\nSTATUS=watching /data"); err != nil {
+		t.Fatalf("notifySocket returned error: %v", err)
+	}
+	buf := make([]byte, 256)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if got := string(buf[:n]); got != "READY=1\nSTATUS=watching /data" {
+		t.Fatalf("unexpected notification %q", got)
+	}
+	if err := notifySocket(filepath.Join(t.TempDir(), "missing.sock"), "READY=1"); err == nil {
+		t.Fatalf("expected a missing socket to fail")
+	}
+}
+
+// TestWatchdogInterval verifies behavior for the related scenario.
+func TestWatchdogInterval(t *testing.T) {
+	t.Parallel()
+
+	self := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name string
+		usec string
+		pid  string
+		want time.Duration
+	}{
+		{name: "unset"},
+		{name: "any pid", usec: "30000000", want: 15 * time.Second},
+		{name: "own pid", usec: "2000000", pid: self, want: time.Second},
+		{name: "other pid", usec: "2000000", pid: "1"},
+		{name: "invalid", usec: "soon"},
+		{name: "zero", usec: "0"},
+	}
+	for _, tt := range tests {
+		if got := watchdogInterval(tt.usec, tt.pid); got != tt.want {
+			t.Fatalf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestSystemdSocketNames verifies behavior for the related scenario.
+func TestSystemdSocketNames(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		count   int
+		names   string
+		want    []string
+		wantErr string
+	}{
+		{name: "unnamed", count: 1, want: []string{"http"}},
+		{name: "unit names", count: 2, names: "es-bulk-loader.socket:es-bulk-loader.socket", want: []string{"http", "grpc"}},
+		{name: "named", count: 2, names: "grpc:http", want: []string{"grpc", "http"}},
+		{name: "grpc only", count: 1, names: "grpc", want: []string{"grpc"}},
+		{name: "too many", count: 3, wantErr: "FileDescriptorName="},
+		{name: "duplicate", count: 2, names: "http:http", wantErr: "two sockets named http"},
+	}
+	for _, tt := range tests {
+		got, err := systemdSocketNames(tt.count, tt.names)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if files, err := parseListenFDs("1", "2", ""); files != nil || err != nil {
+		t.Fatalf("expected sockets passed to another process to be ignored, got %v, %v", files, err)
+	}
+}
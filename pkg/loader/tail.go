@@ -0,0 +1,173 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Tail Mode ─────────────────────────────────────────────────────────────────
+
+const (
+	// defaultTailFlushInterval bounds how long a partial batch waits in tail mode.
+	defaultTailFlushInterval = 5 * time.Second
+)
+
+// tailPollInterval is how often a file at EOF is checked for growth, truncation,
+// or rotation. It is a variable so tests can shorten it.
+var tailPollInterval = 250 * time.Millisecond
+
+// tailReader follows a path across truncation and rename/recreate rotation,
+// yielding complete NDJSON lines.
+type tailReader struct {
+	path    string
+	file    *os.File
+	reader  *bufio.Reader
+	offset  int64
+	partial []byte
+}
+
+// open (re)opens path from the beginning. A missing file is not an error; the
+// reader keeps polling until it appears.
+func (t *tailReader) open() error {
+	file, err := os.Open(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	t.file = file
+	t.reader = bufio.NewReader(file)
+	t.offset = 0
+	t.partial = t.partial[:0]
+	return nil
+}
+
+// close releases the current file handle.
+func (t *tailReader) close() {
+	if t.file != nil {
+		_ = t.file.Close()
+		t.file = nil
+	}
+}
+
+// readLine returns the next complete line, or nil when no full line is
+// available yet. Bytes after the last newline are held until the line is done.
+func (t *tailReader) readLine() ([]byte, error) {
+	if t.file == nil {
+		if err := t.open(); err != nil || t.file == nil {
+			return nil, err
+		}
+	}
+	chunk, err := t.reader.ReadBytes('\n')
+	t.offset += int64(len(chunk))
+	if err == nil {
+		line := append(t.partial, chunk...)
+		t.partial = nil
+		return line, nil
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	t.partial = append(t.partial, chunk...)
+	return nil, t.checkRotation()
+}
+
+// checkRotation handles a file at EOF: truncation restarts from offset zero and
+// a replaced path is reopened once the old file has been fully drained.
+func (t *tailReader) checkRotation() error {
+	current, err := t.file.Stat()
+	if err != nil {
+		return err
+	}
+	if current.Size() < t.offset {
+		log.Info().Str("data_file", t.path).Msg("Tailed file was truncated; reading from the start")
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		t.reader.Reset(t.file)
+		t.offset = 0
+		t.partial = t.partial[:0]
+		return nil
+	}
+	latest, err := os.Stat(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(current, latest) {
+		log.Info().Str("data_file", t.path).Msg("Tailed file was rotated; following the new file")
+		if len(bytes.TrimSpace(t.partial)) > 0 {
+			log.Warn().Str("data_file", t.path).Int("bytes", len(t.partial)).Msg("Discarding unterminated line from rotated file")
+		}
+		t.close()
+		return t.open()
+	}
+	return nil
+}
+
+// tailDataFile follows path like `tail -F`, indexing each appended NDJSON line
+// through batcher. Partial batches are sent every flushInterval so slow streams
+// still reach the index promptly. It returns nil when ctx is cancelled.
+func tailDataFile(ctx context.Context, path string, batcher *bulkBatcher, flushInterval time.Duration) error {
+	if flushInterval <= 0 {
+		flushInterval = defaultTailFlushInterval
+	}
+	reader := &tailReader{path: path}
+	defer reader.close()
+
+	log.Info().Str("data_file", path).Str("flush_interval", flushInterval.String()).Msg("Tailing data file for appended NDJSON lines")
+	lastFlush := time.Now()
+	lineNumber := 0
+	for {
+		line, err := reader.readLine()
+		if err != nil {
+			return err
+		}
+		if line != nil {
+			lineNumber++
+			trimmed := bytes.TrimSpace(line)
+			if len(trimmed) == 0 {
+				continue
+			}
+			if batcher.rawPassthrough() {
+				if err := checkJSONObject(trimmed); err != nil {
+					log.Warn().Err(err).Str("data_file", path).Int("line", lineNumber).Msg("Skipping malformed NDJSON line")
+					continue
+				}
+				batcher.addRaw(trimmed)
+			} else {
+				var doc map[string]interface{}
+				if err := decodeJSON(trimmed, &doc); err != nil || doc == nil {
+					log.Warn().Err(err).Str("data_file", path).Int("line", lineNumber).Msg("Skipping malformed NDJSON line")
+					continue
+				}
+				batcher.add(doc)
+			}
+			if batcher.pending() == 0 {
+				lastFlush = time.Now()
+			}
+			continue
+		}
+
+		if batcher.pending() > 0 && time.Since(lastFlush) >= flushInterval {
+			batcher.flush()
+			lastFlush = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			log.Info().Str("data_file", path).Msg("Tail stopped")
+			return nil
+		case <-time.After(tailPollInterval):
+		}
+	}
+}
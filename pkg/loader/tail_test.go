@@ -0,0 +1,122 @@
+package loader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRunTailFollowsAppendsAndRotation verifies behavior for the related scenario.
+func TestRunTailFollowsAppendsAndRotation(t *testing.T) {
+	previousPoll := tailPollInterval
+	tailPollInterval = 5 * time.Millisecond
+	t.Cleanup(func() { tailPollInterval = previousPoll })
+
+	var (
+		mu     sync.Mutex
+		bodies strings.Builder
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/logs":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			bodies.Write(body)
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.ndjson")
+	if err := os.WriteFile(path, []byte("{\"msg\":\"one\"}\nnot json\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	appendTo := func(content string) {
+		t.Helper()
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+		if err != nil {
+			t.Fatalf("OpenFile returned error: %v", err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(content); err != nil {
+			t.Fatalf("WriteString returned error: %v", err)
+		}
+	}
+	waitFor := func(needle string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			mu.Lock()
+			sent := bodies.String()
+			mu.Unlock()
+			if strings.Contains(sent, needle) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %q; sent so far:\n%s", needle, sent)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	type runOutcome struct {
+		result Result
+		err    error
+	}
+	done := make(chan runOutcome, 1)
+	go func() {
+		result, err := Run(ctx, Options{
+			URL:               server.URL,
+			Index:             "logs",
+			DataFile:          path,
+			AddToIndex:        true,
+			BatchSize:         100,
+			Tail:              true,
+			TailFlushInterval: 10 * time.Millisecond,
+		})
+		done <- runOutcome{result, err}
+	}()
+
+	waitFor(`"one"`)
+	appendTo(`{"msg":`)
+	time.Sleep(20 * time.Millisecond)
+	appendTo("\"two\"}\n")
+	waitFor(`"two"`)
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("Rename returned error: %v", err)
+	}
+	appendTo("{\"msg\":\"three\"}\n")
+	waitFor(`"three"`)
+
+	cancel()
+	var outcome runOutcome
+	select {
+	case outcome = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after cancellation")
+	}
+	if outcome.err != nil {
+		t.Fatalf("Run returned error: %v", outcome.err)
+	}
+	if outcome.result.DocumentsProcessed != 3 {
+		t.Fatalf("expected 3 processed documents, got %d", outcome.result.DocumentsProcessed)
+	}
+}
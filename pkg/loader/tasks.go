@@ -0,0 +1,186 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Task Tagging and Cancellation ─────────────────────────────────────────────
+
+// opaqueIDHeader tags requests so Elasticsearch copies the value onto the
+// tasks they start, slow logs, and deprecation logs.
+const opaqueIDHeader = "X-Opaque-Id"
+
+// opaqueIDPrefix starts every X-Opaque-Id Run sends by default.
+const opaqueIDPrefix = "es-bulk-loader/"
+
+// defaultOpaqueID returns the X-Opaque-Id a run loading index started at
+// now sends: es-bulk-loader/<index>/<UTC time>.
+func defaultOpaqueID(index string, now time.Time) string {
+	return opaqueIDPrefix + index + "/" + now.UTC().Format("20060102T150405.000Z")
+}
+
+// CancelOptions configures Cancel.
+type CancelOptions struct {
+	// OpaqueID selects the tasks whose X-Opaque-Id starts with it. It
+	// defaults to es-bulk-loader/<Load.Index>/, every load of that index,
+	// or es-bulk-loader/ for every load when Load.Index is empty.
+	OpaqueID string
+	// DryRun lists the matching tasks without cancelling them.
+	DryRun bool
+	// Load names the cluster and credentials, as for Run.
+	Load Options
+}
+
+// LoaderTask is one in-flight task Cancel matched.
+type LoaderTask struct {
+	ID          string
+	Action      string
+	OpaqueID    string
+	RunningTime time.Duration
+	// Cancellable is false for tasks Elasticsearch does not let a client
+	// cancel, such as bulk requests.
+	Cancellable bool
+	// Cancelled reports whether the task was cancelled, by this call or
+	// before it.
+	Cancelled bool
+}
+
+// taskListResponse is the body of GET _tasks?detailed, grouped by node.
+type taskListResponse struct {
+	Nodes map[string]struct {
+		Tasks map[string]struct {
+			Action       string            `json:"action"`
+			RunningTime  int64             `json:"running_time_in_nanos"`
+			Cancellable  bool              `json:"cancellable"`
+			Cancelled    bool              `json:"cancelled"`
+			ParentTaskID string            `json:"parent_task_id"`
+			Headers      map[string]string `json:"headers"`
+		} `json:"tasks"`
+	} `json:"nodes"`
+}
+
+// Cancel finds the in-flight tasks whose X-Opaque-Id matches opts and
+// cancels each cancellable one. Child tasks of a matched task are left to
+// Elasticsearch, which cancels them with their parent. Tasks that cannot be
+// cancelled are returned with Cancellable false.
+func Cancel(ctx context.Context, opts CancelOptions) ([]LoaderTask, error) {
+	prefix := opts.OpaqueID
+	if prefix == "" {
+		prefix = opaqueIDPrefix
+		if opts.Load.Index != "" {
+			prefix += opts.Load.Index + "/"
+		}
+	}
+	if len(opts.Load.Clusters) > 1 {
+		return nil, &RunError{Kind: ErrInvalidOptions, Op: "validating cancel options", Err: fmt.Errorf("tasks are cancelled on one cluster; pass a single -url")}
+	}
+	// Untagged, so the listing request cannot match its own prefix.
+	load := opts.Load
+	load.OpaqueID = ""
+	es, err := snapshotClient(ctx, load)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := listTaggedTasks(ctx, es, prefix)
+	if err != nil {
+		return nil, &RunError{Kind: ErrIndexOperation, Op: "listing tasks", Err: err}
+	}
+	for i := range tasks {
+		task := &tasks[i]
+		event := log.Info().Str("task", task.ID).Str("action", task.Action).Str("opaque_id", task.OpaqueID).Dur("running_time", task.RunningTime)
+		switch {
+		case task.Cancelled:
+			event.Msg("Task is already being cancelled")
+		case !task.Cancellable:
+			event.Msg("Task cannot be cancelled; it finishes on its own")
+		case opts.DryRun:
+			event.Msg("Task would be cancelled")
+		default:
+			if err := cancelTask(ctx, es, task.ID); err != nil {
+				return tasks, &RunError{Kind: ErrIndexOperation, Op: "cancelling task " + task.ID, Err: err}
+			}
+			task.Cancelled = true
+			event.Msg("Task cancelled")
+		}
+	}
+	log.Info().Str("opaque_id", prefix).Int("tasks", len(tasks)).Bool("dry_run", opts.DryRun).Msg("Cancel complete")
+	return tasks, nil
+}
+
+// listTaggedTasks returns the top-level tasks whose X-Opaque-Id starts with
+// prefix, longest running first.
+func listTaggedTasks(ctx context.Context, es *elasticsearch.Client, prefix string) ([]LoaderTask, error) {
+	res, err := es.Tasks.List(es.Tasks.List.WithContext(ctx), es.Tasks.List.WithDetailed(true))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var list taskListResponse
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("parsing tasks: %w", err)
+	}
+	matched := make(map[string]bool)
+	for _, node := range list.Nodes {
+		for id, task := range node.Tasks {
+			if strings.HasPrefix(task.Headers[opaqueIDHeader], prefix) {
+				matched[id] = true
+			}
+		}
+	}
+	var tasks []LoaderTask
+	for _, node := range list.Nodes {
+		for id, task := range node.Tasks {
+			if !matched[id] || matched[task.ParentTaskID] {
+				continue
+			}
+			tasks = append(tasks, LoaderTask{
+				ID:          id,
+				Action:      task.Action,
+				OpaqueID:    task.Headers[opaqueIDHeader],
+				RunningTime: time.Duration(task.RunningTime),
+				Cancellable: task.Cancellable,
+				Cancelled:   task.Cancelled,
+			})
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].RunningTime != tasks[j].RunningTime {
+			return tasks[i].RunningTime > tasks[j].RunningTime
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks, nil
+}
+
+// cancelTask cancels one task by its node:id.
+func cancelTask(ctx context.Context, es *elasticsearch.Client, id string) error {
+	res, err := es.Tasks.Cancel(es.Tasks.Cancel.WithContext(ctx), es.Tasks.Cancel.WithTaskID(id))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.IsError() {
+		return fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var cancelled struct {
+		NodeFailures []json.RawMessage `json:"node_failures"`
+	}
+	if json.Unmarshal(body, &cancelled) == nil && len(cancelled.NodeFailures) > 0 {
+		return fmt.Errorf("node failures: %s", strings.TrimSpace(string(body)))
+	}
+	return nil
+}
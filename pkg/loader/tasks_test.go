@@ -0,0 +1,147 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestDefaultOpaqueID verifies behavior for the related scenario.
+func TestDefaultOpaqueID(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 10, 14, 9, 30, 0, 123e6, time.FixedZone("CEST", 2*60*60))
+	if got := defaultOpaqueID("customers", now); got != "es-bulk-loader/customers/20241014T073000.123Z" {
+		t.Fatalf("unexpected opaque id %q", got)
+	}
+}
+
+// TestRunTagsRequestsWithOpaqueID verifies behavior for the related scenario.
+func TestRunTagsRequestsWithOpaqueID(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var bulkIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/_bulk":
+			mu.Lock()
+			bulkIDs = append(bulkIDs, r.Header.Get("X-Opaque-Id"))
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	data := writeTestDataFile(t, "cards.ndjson", "{\"name\":\"a\"}\n")
+
+	tests := []struct {
+		name     string
+		opaqueID string
+		prefix   string
+	}{
+		{name: "default", prefix: "es-bulk-loader/cards/"},
+		{name: "explicit", opaqueID: "nightly-cards", prefix: "nightly-cards"},
+	}
+	for _, tt := range tests {
+		mu.Lock()
+		bulkIDs = nil
+		mu.Unlock()
+		result, err := Run(context.Background(), Options{URL: server.URL, Index: "cards", DataFile: data, AddToIndex: true, OpaqueID: tt.opaqueID})
+		if err != nil {
+			t.Fatalf("%s: Run returned error: %v", tt.name, err)
+		}
+		if !strings.HasPrefix(result.OpaqueID, tt.prefix) {
+			t.Fatalf("%s: expected Result.OpaqueID to start with %q, got %q", tt.name, tt.prefix, result.OpaqueID)
+		}
+		mu.Lock()
+		ids := slices.Clone(bulkIDs)
+		mu.Unlock()
+		if len(ids) != 1 || ids[0] != result.OpaqueID {
+			t.Fatalf("%s: expected the bulk request tagged %q, got %v", tt.name, result.OpaqueID, ids)
+		}
+	}
+}
+
+// taskServer lists a fixed set of tasks and records the ids cancelled.
+type taskServer struct {
+	mu        sync.Mutex
+	cancelled []string
+	listIDs   []string
+}
+
+func (s *taskServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/_tasks":
+		s.listIDs = append(s.listIDs, r.Header.Get("X-Opaque-Id"))
+		_, _ = w.Write([]byte(`{"nodes":{"n1":{"tasks":{
+			"n1:1":{"action":"indices:data/write/bulk","running_time_in_nanos":5000000000,"cancellable":false,"headers":{"X-Opaque-Id":"es-bulk-loader/cards/20241014T073000.000Z"}},
+			"n1:2":{"action":"indices:data/write/bulk[s]","running_time_in_nanos":4000000000,"cancellable":false,"parent_task_id":"n1:1","headers":{"X-Opaque-Id":"es-bulk-loader/cards/20241014T073000.000Z"}},
+			"n1:3":{"action":"indices:data/write/delete/byquery","running_time_in_nanos":9000000000,"cancellable":true,"headers":{"X-Opaque-Id":"es-bulk-loader/cards/20241014T073000.000Z"}},
+			"n1:4":{"action":"indices:data/write/delete/byquery","running_time_in_nanos":9000000000,"cancellable":true,"headers":{"X-Opaque-Id":"es-bulk-loader/orders/20241014T073000.000Z"}},
+			"n1:5":{"action":"indices:data/read/search","running_time_in_nanos":1000000000,"cancellable":true,"headers":{}}
+		}}}}`))
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/_tasks/") && strings.HasSuffix(r.URL.Path, "/_cancel"):
+		s.cancelled = append(s.cancelled, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/_tasks/"), "/_cancel"))
+		_, _ = w.Write([]byte(`{"nodes":{}}`))
+	default:
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+// TestCancel verifies behavior for the related scenario.
+func TestCancel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		opts          CancelOptions
+		wantTasks     []string
+		wantCancelled []string
+	}{
+		{name: "index", opts: CancelOptions{Load: Options{Index: "cards"}}, wantTasks: []string{"n1:3", "n1:1"}, wantCancelled: []string{"n1:3"}},
+		{name: "every load", wantTasks: []string{"n1:3", "n1:4", "n1:1"}, wantCancelled: []string{"n1:3", "n1:4"}},
+		{name: "explicit prefix", opts: CancelOptions{OpaqueID: "es-bulk-loader/orders/", Load: Options{Index: "cards", OpaqueID: "es-bulk-loader/orders/"}}, wantTasks: []string{"n1:4"}, wantCancelled: []string{"n1:4"}},
+		{name: "dry run", opts: CancelOptions{DryRun: true, Load: Options{Index: "cards"}}, wantTasks: []string{"n1:3", "n1:1"}},
+	}
+	for _, tt := range tests {
+		server := &taskServer{}
+		httpServer := httptest.NewServer(server)
+		tt.opts.Load.URL = httpServer.URL
+		tasks, err := Cancel(context.Background(), tt.opts)
+		httpServer.Close()
+		if err != nil {
+			t.Fatalf("%s: Cancel returned error: %v", tt.name, err)
+		}
+		var ids []string
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+			if task.Cancelled != slices.Contains(tt.wantCancelled, task.ID) {
+				t.Fatalf("%s: task %s reported cancelled %t", tt.name, task.ID, task.Cancelled)
+			}
+		}
+		if !slices.Equal(ids, tt.wantTasks) {
+			t.Fatalf("%s: expected tasks %v, got %v", tt.name, tt.wantTasks, ids)
+		}
+		if !slices.Equal(server.cancelled, tt.wantCancelled) {
+			t.Fatalf("%s: expected cancelled %v, got %v", tt.name, tt.wantCancelled, server.cancelled)
+		}
+		if len(server.listIDs) != 1 || server.listIDs[0] != "" {
+			t.Fatalf("%s: expected one untagged task listing, got %q", tt.name, server.listIDs)
+		}
+	}
+}
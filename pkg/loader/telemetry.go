@@ -0,0 +1,210 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── OpenTelemetry Instrumentation ─────────────────────────────────────────────
+
+const (
+	// telemetryScope names the instrumentation scope for loader spans and metrics.
+	telemetryScope = "github.com/jnovack/es-bulk-loader/pkg/loader"
+	// defaultTelemetryServiceName is used when OTEL_SERVICE_NAME is unset.
+	defaultTelemetryServiceName = "es-bulk-loader"
+)
+
+// telemetry bundles the tracer, meter instruments, and exporter shutdown for a run.
+type telemetry struct {
+	tracerProvider trace.TracerProvider
+	tracer         trace.Tracer
+
+	docsIndexed  metric.Int64Counter
+	docsFailed   metric.Int64Counter
+	batchLatency metric.Float64Histogram
+	bytesSent    metric.Int64Counter
+
+	shutdown func(context.Context) error
+}
+
+// newTelemetry builds OTLP trace and metric exporters when enabled. The
+// protocol comes from OTEL_EXPORTER_OTLP_PROTOCOL and its per-signal variants,
+// and the exporters read the other OTEL_EXPORTER_OTLP_* variables for endpoint,
+// headers, and TLS, so no loader-specific flags are needed.
+func newTelemetry(ctx context.Context, enabled bool) (*telemetry, error) {
+	if !enabled {
+		return newTelemetryWithProviders(tracenoop.NewTracerProvider(), metricnoop.NewMeterProvider(), nil)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", defaultTelemetryServiceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// Re-merge OTEL_SERVICE_NAME/OTEL_RESOURCE_ATTRIBUTES so they win over the
+	// loader default service name.
+	envResource, err := resource.New(ctx, resource.WithFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	if res, err = resource.Merge(res, envResource); err != nil {
+		return nil, err
+	}
+
+	traceExporter, err := newTraceExporter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metricExporter, err := newMetricExporter(ctx)
+	if err != nil {
+		_ = traceExporter.Shutdown(ctx)
+		return nil, err
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithResource(res),
+	)
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithInterval(10*time.Second))),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+
+	return newTelemetryWithProviders(tracerProvider, meterProvider, func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	})
+}
+
+// otlpProtocol returns the OTLP protocol for signal ("TRACES" or "METRICS"),
+// letting the per-signal variable override OTEL_EXPORTER_OTLP_PROTOCOL as the
+// OTLP exporter specification requires. http/protobuf is the default.
+func otlpProtocol(signal string) (string, error) {
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	switch protocol {
+	case "", "http/protobuf":
+		return "http/protobuf", nil
+	case "grpc":
+		return "grpc", nil
+	}
+	return "", fmt.Errorf("unsupported OTLP protocol %q for %s; use grpc or http/protobuf", protocol, signal)
+}
+
+// newTraceExporter builds the span exporter for the configured protocol.
+func newTraceExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	protocol, err := otlpProtocol("TRACES")
+	if err != nil {
+		return nil, err
+	}
+	if protocol == "grpc" {
+		return otlptracegrpc.New(ctx)
+	}
+	return otlptracehttp.New(ctx)
+}
+
+// newMetricExporter builds the metric exporter for the configured protocol.
+func newMetricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	protocol, err := otlpProtocol("METRICS")
+	if err != nil {
+		return nil, err
+	}
+	if protocol == "grpc" {
+		return otlpmetricgrpc.New(ctx)
+	}
+	return otlpmetrichttp.New(ctx)
+}
+
+// newTelemetryWithProviders creates instruments from explicit providers so
+// tests can inject in-memory exporters.
+func newTelemetryWithProviders(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider, shutdown func(context.Context) error) (*telemetry, error) {
+	meter := meterProvider.Meter(telemetryScope)
+	t := &telemetry{
+		tracerProvider: tracerProvider,
+		tracer:         tracerProvider.Tracer(telemetryScope),
+		shutdown:       shutdown,
+	}
+
+	var err error
+	if t.docsIndexed, err = meter.Int64Counter("es_bulk_loader.docs.indexed", metric.WithDescription("Documents indexed successfully"), metric.WithUnit("{document}")); err != nil {
+		return nil, err
+	}
+	if t.docsFailed, err = meter.Int64Counter("es_bulk_loader.docs.failed", metric.WithDescription("Documents rejected by bulk item responses"), metric.WithUnit("{document}")); err != nil {
+		return nil, err
+	}
+	if t.bytesSent, err = meter.Int64Counter("es_bulk_loader.bulk.bytes", metric.WithDescription("Bulk request payload bytes"), metric.WithUnit("By")); err != nil {
+		return nil, err
+	}
+	if t.batchLatency, err = meter.Float64Histogram("es_bulk_loader.bulk.duration", metric.WithDescription("Client-observed bulk request latency"), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// close flushes and stops exporters, bounded so shutdown cannot hang a run.
+func (t *telemetry) close() {
+	if t == nil || t.shutdown == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := t.shutdown(ctx); err != nil {
+		log.Warn().Err(err).Msg("OpenTelemetry exporter shutdown failed")
+	}
+}
+
+// startSpan starts a span when telemetry is configured; otherwise it returns a
+// non-recording span so callers need no nil checks.
+func (t *telemetry) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if t == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// recordBatch records per-batch metrics and finishes the batch span.
+func (t *telemetry) recordBatch(ctx context.Context, span trace.Span, index string, succeeded, failed int, bytes int64, latency, took time.Duration) {
+	span.SetAttributes(
+		attribute.Int("es_bulk_loader.docs.succeeded", succeeded),
+		attribute.Int("es_bulk_loader.docs.failed", failed),
+		attribute.Int64("es_bulk_loader.bulk.bytes", bytes),
+		attribute.Int64("es_bulk_loader.bulk.took_ms", took.Milliseconds()),
+	)
+	if failed > 0 {
+		span.SetStatus(codes.Error, "bulk items failed")
+	}
+	span.End()
+	if t == nil {
+		return
+	}
+	indexAttr := metric.WithAttributes(attribute.String("es_bulk_loader.index", index))
+	t.docsIndexed.Add(ctx, int64(succeeded), indexAttr)
+	t.docsFailed.Add(ctx, int64(failed), indexAttr)
+	t.bytesSent.Add(ctx, bytes, indexAttr)
+	t.batchLatency.Record(ctx, latency.Seconds(), indexAttr)
+}
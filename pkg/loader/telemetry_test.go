@@ -0,0 +1,114 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestBulkInsertRecordsBatchSpanAndMetrics verifies behavior for the related scenario.
+func TestBulkInsertRecordsBatchSpanAndMetrics(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"took":7,"errors":true,"items":[{"index":{"_index":"cards","_id":"1","status":201}},{"index":{"_index":"cards","_id":"2","status":400,"error":{"type":"mapper_parsing_exception","reason":"bad"}}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{server.URL}})
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	spans := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+	tel, err := newTelemetryWithProviders(
+		sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)),
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("newTelemetryWithProviders returned error: %v", err)
+	}
+
+	batch := []map[string]interface{}{{"id": "1"}, {"id": "2"}}
+	result := bulkInsert(context.Background(), es, "cards", batch, 1, 2, 2, bulkSettings{RetryAttempts: 1, Telemetry: tel})
+	if result.Succeeded != 1 || result.Failed != 1 {
+		t.Fatalf("unexpected bulk result: %+v", result)
+	}
+
+	ended := spans.GetSpans()
+	if len(ended) != 1 || ended[0].Name != "bulk batch" {
+		t.Fatalf("expected one bulk batch span, got %+v", ended)
+	}
+	attrs := map[string]int64{}
+	for _, attr := range ended[0].Attributes {
+		attrs[string(attr.Key)] = attr.Value.AsInt64()
+	}
+	if attrs["es_bulk_loader.docs"] != 2 || attrs["es_bulk_loader.docs.failed"] != 1 || attrs["es_bulk_loader.bulk.took_ms"] != 7 {
+		t.Fatalf("unexpected span attributes: %v", attrs)
+	}
+
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	totals := map[string]int64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, point := range sum.DataPoints {
+					totals[m.Name] += point.Value
+				}
+			}
+		}
+	}
+	if totals["es_bulk_loader.docs.indexed"] != 1 || totals["es_bulk_loader.docs.failed"] != 1 {
+		t.Fatalf("unexpected metric totals: %v", totals)
+	}
+}
+
+// TestOTLPProtocol verifies behavior for the related scenario.
+func TestOTLPProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		general string
+		signal  string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", want: "http/protobuf"},
+		{name: "general grpc", general: "grpc", want: "grpc"},
+		{name: "signal overrides general", general: "grpc", signal: "http/protobuf", want: "http/protobuf"},
+		{name: "json unsupported", general: "http/json", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", tt.general)
+		t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", tt.signal)
+		got, err := otlpProtocol("TRACES")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Fatalf("%s: otlpProtocol = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "")
+	metricExporter, err := newMetricExporter(context.Background())
+	if err != nil {
+		t.Fatalf("newMetricExporter returned error: %v", err)
+	}
+	defer metricExporter.Shutdown(context.Background())
+	if _, ok := metricExporter.(*otlpmetricgrpc.Exporter); !ok {
+		t.Fatalf("expected a gRPC metric exporter, got %T", metricExporter)
+	}
+}
@@ -0,0 +1,181 @@
+package loader
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── External Transform Command ────────────────────────────────────────────────
+
+const (
+	// defaultTransformExecTimeout bounds how long -transform-exec may take to
+	// answer one document before the load fails.
+	defaultTransformExecTimeout = 30 * time.Second
+	// transformExecCloseTimeout is how long a command may keep running after
+	// its input is closed before it is killed.
+	transformExecCloseTimeout = 5 * time.Second
+)
+
+// execTransform pipes documents through a long-running command, one NDJSON
+// line in and one line out. Keeping a single process for the whole load
+// avoids paying interpreter start-up per document.
+type execTransform struct {
+	command string
+	timeout time.Duration
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	// lines carries stdout lines; it is closed when stdout reaches EOF.
+	lines    chan []byte
+	timedOut atomic.Bool
+	done     chan struct{}
+	waitErr  error
+}
+
+// startExecTransform launches command through the platform shell. It returns
+// nil when command is empty.
+func startExecTransform(command string, timeout time.Duration) (*execTransform, error) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil, nil
+	}
+	if timeout <= 0 {
+		timeout = defaultTransformExecTimeout
+	}
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.Command(shell, flag, command)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting -transform-exec %q: %w", command, err)
+	}
+	e := &execTransform{
+		command: command,
+		timeout: timeout,
+		cmd:     cmd,
+		stdin:   stdin,
+		lines:   make(chan []byte, 1),
+		done:    make(chan struct{}),
+	}
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go func() { defer readers.Done(); e.readLines(stdout) }()
+	go func() { defer readers.Done(); e.logStderr(stderr) }()
+	go func() {
+		// Wait closes the pipes, so it must not run until both are drained.
+		readers.Wait()
+		e.waitErr = cmd.Wait()
+		close(e.done)
+	}()
+	log.Debug().Str("command", command).Int("pid", cmd.Process.Pid).Msg("Started transform command")
+	return e, nil
+}
+
+// readLines forwards stdout line by line until EOF.
+func (e *execTransform) readLines(stdout io.Reader) {
+	defer close(e.lines)
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && (err == nil || errors.Is(err, io.EOF)) {
+			e.lines <- line
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// logStderr surfaces the command's diagnostics in the loader log.
+func (e *execTransform) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Warn().Str("command", e.command).Str("stderr", scanner.Text()).Msg("Transform command wrote to stderr")
+	}
+}
+
+// transform adapts the command to the document transform chain. Each
+// answer line may be an object, an array of objects to split the document,
+// or null or a blank line to drop it.
+func (e *execTransform) transform() docTransform {
+	return func(doc map[string]interface{}) ([]map[string]interface{}, error) {
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		timer := time.AfterFunc(e.timeout, func() {
+			e.timedOut.Store(true)
+			_ = e.cmd.Process.Kill()
+		})
+		defer timer.Stop()
+		if _, err := e.stdin.Write(append(encoded, '\n')); err != nil {
+			return nil, e.failure(err)
+		}
+		line, ok := <-e.lines
+		if !ok {
+			return nil, e.failure(io.ErrUnexpectedEOF)
+		}
+		return decodeRenderedDocuments(line, "-transform-exec")
+	}
+}
+
+// failure explains why the command could not answer a document.
+func (e *execTransform) failure(err error) error {
+	if e.timedOut.Load() {
+		return fmt.Errorf("-transform-exec %q wrote no line within %s; it must answer and flush one line per input line (for example jq --unbuffered or python -u)", e.command, e.timeout)
+	}
+	select {
+	case <-e.done:
+		if e.waitErr != nil {
+			err = e.waitErr
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+	return fmt.Errorf("-transform-exec %q stopped before answering: %w", e.command, err)
+}
+
+// close ends the command's input and waits for it to exit, killing it after
+// transformExecCloseTimeout. It is nil-safe.
+func (e *execTransform) close() {
+	if e == nil {
+		return
+	}
+	_ = e.stdin.Close()
+	go func() {
+		// Discard output beyond the protocol so the command cannot block on it.
+		for range e.lines {
+		}
+	}()
+	select {
+	case <-e.done:
+	case <-time.After(transformExecCloseTimeout):
+		_ = e.cmd.Process.Kill()
+		<-e.done
+	}
+	if e.waitErr != nil && !e.timedOut.Load() {
+		log.Warn().Err(e.waitErr).Str("command", e.command).Msg("Transform command exited with an error")
+	}
+}
@@ -0,0 +1,123 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testExecAnswerScript echoes each line, dropping lines that mention "drop"
+// and doubling lines that mention "split".
+const testExecAnswerScript = `while IFS= read -r line; do case "$line" in *drop*) echo null;; *split*) echo "[$line,$line]";; *) echo "$line";; esac; done`
+
+// requireShell skips tests that need a POSIX shell.
+func requireShell(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+}
+
+// TestExecTransformAnswers verifies behavior for the related scenario.
+func TestExecTransformAnswers(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+
+	step, err := startExecTransform(testExecAnswerScript, time.Second)
+	if err != nil {
+		t.Fatalf("startExecTransform returned error: %v", err)
+	}
+	defer step.close()
+	transform := step.transform()
+
+	tests := []struct {
+		doc  string
+		want int
+	}{
+		{doc: `{"id":"1"}`, want: 1},
+		{doc: `{"id":"2","mode":"drop"}`, want: 0},
+		{doc: `{"id":"3","mode":"split"}`, want: 2},
+		{doc: `{"id":"4"}`, want: 1},
+	}
+	for _, tt := range tests {
+		out, err := transform(decodeTestDocument(t, tt.doc))
+		if err != nil {
+			t.Fatalf("transform(%s) returned error: %v", tt.doc, err)
+		}
+		if len(out) != tt.want {
+			t.Fatalf("transform(%s) returned %d documents, want %d", tt.doc, len(out), tt.want)
+		}
+		for _, doc := range out {
+			if got := encodeTestDocument(t, doc); got != tt.doc {
+				t.Fatalf("transform(%s) answered %s", tt.doc, got)
+			}
+		}
+	}
+	if step, err := startExecTransform("  ", 0); step != nil || err != nil {
+		t.Fatalf("expected no transform for an empty command, got %v, %v", step, err)
+	}
+}
+
+// TestExecTransformFailures verifies behavior for the related scenario.
+func TestExecTransformFailures(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{name: "silent", command: "cat >/dev/null", want: "flush one line per input line"},
+		{name: "exits", command: "exit 3", want: "stopped before answering"},
+		{name: "invalid json", command: "while read -r line; do echo nope; done", want: "not a JSON object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			step, err := startExecTransform(tt.command, 200*time.Millisecond)
+			if err != nil {
+				t.Fatalf("startExecTransform returned error: %v", err)
+			}
+			defer step.close()
+			_, err = step.transform()(map[string]interface{}{"id": "1"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestRunTransformExec verifies behavior for the related scenario.
+func TestRunTransformExec(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+
+	server, bodies := newWatchTestServer(t)
+	dataFile := filepath.Join(t.TempDir(), "cards.json")
+	content := `[{"id":"1"},{"id":"2","mode":"drop"},{"id":"3","mode":"split"}]`
+	if err := os.WriteFile(dataFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	result, err := Run(context.Background(), Options{
+		URL:           server.URL,
+		Index:         "cards",
+		DataFile:      dataFile,
+		AddToIndex:    true,
+		TransformExec: testExecAnswerScript,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.DocumentsProcessed != 3 || result.DocumentsSkipped != 1 {
+		t.Fatalf("expected 3 processed and 1 skipped, got %+v", result)
+	}
+	sent := strings.Join(bodies(), "")
+	if strings.Count(sent, `"id":"3"`) != 2 || strings.Contains(sent, `"id":"2"`) {
+		t.Fatalf("unexpected bulk bodies: %s", sent)
+	}
+}
@@ -0,0 +1,166 @@
+package loader
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ─── HTTP Transport Wrappers ───────────────────────────────────────────────────
+
+const (
+	// defaultMaxIdleConns replaces net/http's two idle connections per host,
+	// which makes concurrent bulk requests reconnect constantly.
+	defaultMaxIdleConns = 16
+	// defaultKeepAlive matches the net/http default dialer.
+	defaultKeepAlive = 30 * time.Second
+	// defaultIdleConnTimeout matches http.DefaultTransport.
+	defaultIdleConnTimeout = 90 * time.Second
+	// unixURLPrefix starts a -url or -dial-target naming a Unix domain socket.
+	unixURLPrefix = "unix://"
+	// unixSocketEndpoint is the HTTP URL requests carry when -url names a
+	// socket; the socket, not its host, decides where they go.
+	unixSocketEndpoint = "http://localhost"
+)
+
+// DialFunc opens the connection for a request to address, as
+// net.Dialer.DialContext does.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// newHTTPTransport builds the connection pool for opts.
+func newHTTPTransport(opts Options) *http.Transport {
+	maxIdle := opts.MaxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+	keepAlive := opts.KeepAlive
+	if keepAlive == 0 {
+		keepAlive = defaultKeepAlive
+	}
+	idleTimeout := opts.IdleConnTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleConnTimeout
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: keepAlive}
+	return &http.Transport{
+		DialContext:         dialContext(opts, dialer),
+		MaxIdleConns:        maxIdle,
+		MaxIdleConnsPerHost: maxIdle,
+		MaxConnsPerHost:     opts.MaxConnsPerHost,
+		IdleConnTimeout:     idleTimeout,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: opts.InsecureSkipVerify,
+		},
+	}
+}
+
+// respondingNodeKey keys the per-request holder that receives the answering node.
+type respondingNodeKey struct{}
+
+// respondingNode records which node answered a request made with its context.
+type respondingNode struct {
+	mu   sync.Mutex
+	host string
+}
+
+// withRespondingNode returns a context whose requests report the answering node.
+func withRespondingNode(ctx context.Context) (context.Context, *respondingNode) {
+	holder := &respondingNode{}
+	return context.WithValue(ctx, respondingNodeKey{}, holder), holder
+}
+
+// Host returns the recorded node address, or "" when no response was seen.
+func (n *respondingNode) Host() string {
+	if n == nil {
+		return ""
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.host
+}
+
+// nodeTrackingTransport records the node address that served each request so
+// slow batches can be attributed to a specific node.
+type nodeTrackingTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip forwards the request and records the responding node on success.
+func (t *nodeTrackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	if holder, ok := req.Context().Value(respondingNodeKey{}).(*respondingNode); ok {
+		host := req.URL.Host
+		// Elastic Cloud proxies name the instance that actually handled the request.
+		if instance := res.Header.Get("X-Found-Handling-Instance"); instance != "" {
+			host = instance
+		}
+		holder.mu.Lock()
+		holder.host = host
+		holder.mu.Unlock()
+	}
+	return res, nil
+}
+
+// authTransport builds the connection pool for opts wrapped in whichever
+// request authentication they configure beyond basic auth and API keys.
+func authTransport(opts Options) http.RoundTripper {
+	return withBearerToken(opts, withKerberos(opts, newHTTPTransport(opts)))
+}
+
+// dialContext returns how connections for opts are opened: opts.DialContext
+// when set, else every connection goes to the -dial-target or -url socket,
+// else to the request's own host.
+func dialContext(opts Options, dialer *net.Dialer) DialFunc {
+	if opts.DialContext != nil {
+		return opts.DialContext
+	}
+	target := opts.DialTarget
+	if target == "" && strings.HasPrefix(opts.URL, unixURLPrefix) {
+		target = opts.URL
+	}
+	if target == "" {
+		return dialer.DialContext
+	}
+	network, address := "tcp", target
+	if socket, ok := strings.CutPrefix(target, unixURLPrefix); ok {
+		network, address = "unix", socket
+	}
+	// The request's host still names the server in the Host header and for
+	// TLS verification; only the connection goes elsewhere.
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+}
+
+// endpointURL returns the HTTP URL requests for opts are addressed to.
+func endpointURL(opts Options) string {
+	if strings.HasPrefix(opts.URL, unixURLPrefix) {
+		return unixSocketEndpoint
+	}
+	return opts.URL
+}
+
+// validateDialTarget rejects a -url socket or -dial-target that cannot be
+// dialled.
+func validateDialTarget(opts Options) error {
+	for _, target := range []string{opts.URL, opts.DialTarget} {
+		if socket, ok := strings.CutPrefix(target, unixURLPrefix); ok && !strings.HasPrefix(socket, "/") {
+			return fmt.Errorf("%q must name an absolute socket path, as in unix:///var/run/elasticsearch.sock", target)
+		}
+	}
+	if opts.DialTarget == "" || strings.HasPrefix(opts.DialTarget, unixURLPrefix) {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(opts.DialTarget); err != nil {
+		return fmt.Errorf("-dial-target %q must be host:port or unix:///path: %w", opts.DialTarget, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package loader
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestNewHTTPTransport verifies behavior for the related scenario.
+func TestNewHTTPTransport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		opts        Options
+		maxIdle     int
+		maxConns    int
+		idleTimeout time.Duration
+	}{
+		{name: "defaults", maxIdle: defaultMaxIdleConns, idleTimeout: defaultIdleConnTimeout},
+		{
+			name:        "tuned",
+			opts:        Options{MaxIdleConns: 64, MaxConnsPerHost: 32, KeepAlive: -1, IdleConnTimeout: time.Minute, InsecureSkipVerify: true},
+			maxIdle:     64,
+			maxConns:    32,
+			idleTimeout: time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		transport := newHTTPTransport(tt.opts)
+		if transport.MaxIdleConns != tt.maxIdle || transport.MaxIdleConnsPerHost != tt.maxIdle {
+			t.Fatalf("%s: idle connections %d/%d, want %d", tt.name, transport.MaxIdleConns, transport.MaxIdleConnsPerHost, tt.maxIdle)
+		}
+		if transport.MaxConnsPerHost != tt.maxConns || transport.IdleConnTimeout != tt.idleTimeout {
+			t.Fatalf("%s: max conns %d idle timeout %s, want %d %s", tt.name, transport.MaxConnsPerHost, transport.IdleConnTimeout, tt.maxConns, tt.idleTimeout)
+		}
+		if transport.TLSClientConfig.InsecureSkipVerify != tt.opts.InsecureSkipVerify || transport.DialContext == nil {
+			t.Fatalf("%s: unexpected TLS or dialer configuration", tt.name)
+		}
+	}
+}
+
+// TestValidateDialTarget verifies behavior for the related scenario.
+func TestValidateDialTarget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{name: "unset", opts: Options{URL: "http://localhost:9200"}},
+		{name: "socket url", opts: Options{URL: "unix:///var/run/es.sock"}},
+		{name: "tcp target", opts: Options{URL: "https://es.internal:9200", DialTarget: "127.0.0.1:19200"}},
+		{name: "socket target", opts: Options{URL: "https://es.internal:9200", DialTarget: "unix:///tmp/tunnel.sock"}},
+		{name: "relative socket", opts: Options{URL: "unix://es.sock"}, want: "absolute socket path"},
+		{name: "missing port", opts: Options{DialTarget: "127.0.0.1"}, want: "host:port"},
+	}
+	for _, tt := range tests {
+		err := validateDialTarget(tt.opts)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+// TestRunDialsCustomTargets verifies behavior for the related scenario.
+func TestRunDialsCustomTargets(t *testing.T) {
+	t.Parallel()
+
+	dir, err := os.MkdirTemp("", "esbl")
+	if err != nil {
+		t.Fatalf("MkdirTemp returned error: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "es.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Listen returned error: %v", err)
+	}
+	tcp, _ := newWatchTestServer(t)
+	unix := httptest.NewUnstartedServer(tcp.Config.Handler)
+	unix.Listener.Close()
+	unix.Listener = listener
+	unix.Start()
+	t.Cleanup(unix.Close)
+
+	var dialed atomic.Int32
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "socket url", opts: Options{URL: "unix://" + socket}},
+		{name: "tcp dial target", opts: Options{URL: "http://es.invalid:9200", DialTarget: tcp.Listener.Addr().String()}},
+		{name: "socket dial target", opts: Options{URL: "http://es.invalid:9200", DialTarget: "unix://" + socket}},
+		{name: "dial func", opts: Options{URL: "http://es.invalid:9200", DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialed.Add(1)
+			return (&net.Dialer{}).DialContext(ctx, network, tcp.Listener.Addr().String())
+		}}},
+	}
+	for _, tt := range tests {
+		opts := tt.opts
+		opts.Index = "cards"
+		opts.DataFile = writeTestDataFile(t, "cards.ndjson", "{\"n\":1}\n")
+		opts.AddToIndex = true
+		result, err := Run(context.Background(), opts)
+		if err != nil || result.DocumentsSucceeded != 1 {
+			t.Fatalf("%s: got %+v, %v", tt.name, result, err)
+		}
+	}
+	if dialed.Load() == 0 {
+		t.Fatal("expected the DialContext hook to open connections")
+	}
+}
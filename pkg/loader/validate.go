@@ -0,0 +1,297 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// ─── Validate Mode ─────────────────────────────────────────────────────────────
+
+// ValidationProblem is one thing Validate found wrong.
+type ValidationProblem struct {
+	// Check names what failed: options, connectivity, credentials, index,
+	// settings, mappings, pipelines, policies, transforms, or input.
+	Check string
+	// Cluster is the redacted -url the problem concerns, or "" when it
+	// applies to every cluster.
+	Cluster string
+	Err     error
+}
+
+// Error renders the problem with its check and cluster.
+func (p ValidationProblem) Error() string {
+	if p.Cluster != "" {
+		return p.Check + " (" + p.Cluster + "): " + p.Err.Error()
+	}
+	return p.Check + ": " + p.Err.Error()
+}
+
+// ClusterValidation is what Validate learned about one cluster.
+type ClusterValidation struct {
+	URL  string
+	Info ClusterInfo
+	// Reachable reports that the cluster answered and accepted the
+	// credentials.
+	Reachable   bool
+	IndexExists bool
+}
+
+// ValidationReport is the outcome of Validate.
+type ValidationReport struct {
+	Clusters []ClusterValidation
+	// Documents counts the source records read from the data files.
+	Documents int
+	Problems  []ValidationProblem
+}
+
+// Validate checks everything a Run with opts depends on without writing
+// anything: the options, each cluster's connectivity and credentials, the
+// target index and its mappings, the settings, mappings, and managed
+// resource files, and that every data file parses and passes the document
+// transforms. It keeps going after a failure so one pass reports every
+// problem, and returns ErrValidation when it found any. -transform-exec
+// commands are not started.
+func Validate(ctx context.Context, opts Options) (ValidationReport, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.URL == "" {
+		opts.URL = "http://localhost:9200"
+	}
+	var report ValidationReport
+	problem := func(check, cluster string, err error) {
+		report.Problems = append(report.Problems, ValidationProblem{Check: check, Cluster: cluster, Err: err})
+	}
+
+	if (opts.User != "" || opts.Pass != "") && opts.APIKey != "" {
+		problem("options", "", fmt.Errorf("cannot use both basic auth and API key"))
+	}
+	if opts.BearerToken != nil && (opts.User != "" || opts.APIKey != "" || opts.Kerberos.enabled()) {
+		problem("options", "", fmt.Errorf("a bearer token cannot be combined with basic auth, an API key, or Kerberos"))
+	}
+	if err := validateKerberos(opts); err != nil {
+		problem("options", "", err)
+	}
+	if err := validateDialTarget(opts); err != nil {
+		problem("options", "", err)
+	}
+	if strings.TrimSpace(opts.Index) == "" {
+		problem("options", "", fmt.Errorf("-index is required"))
+	}
+	action, err := selectedDataAction(opts.AddToIndex, opts.FlushIndex, opts.DeleteIndex)
+	if err != nil {
+		problem("options", "", err)
+	}
+	if action.requiresDataFile() && len(opts.dataFiles()) == 0 {
+		problem("options", "", fmt.Errorf("-data is required for -add, -flush, and -delete"))
+	}
+	if err := validateLoadDurability(opts.LoadDurability); err != nil {
+		problem("options", "", err)
+	}
+	transforms, err := buildDocTransforms(opts)
+	if err != nil {
+		problem("transforms", "", err)
+	}
+	if _, err := newDeduper(opts.DedupField, opts.DedupPolicy); err != nil {
+		problem("transforms", "", err)
+	}
+	input, inputErr := newInputConfig(opts)
+	if inputErr != nil {
+		problem("input", "", inputErr)
+	} else if err := validateDataFiles(opts, input, false); err != nil {
+		problem("input", "", err)
+	}
+
+	variables := buildTemplateVariables(opts.Index, opts.TemplateVariables)
+	if opts.SettingsFile != "" {
+		if _, err := readJSONObjectFile(opts.SettingsFile, "settings", variables); err != nil {
+			problem("settings", "", err)
+		}
+	}
+	var plan mappingPreflightPlan
+	if opts.MappingsFile != "" {
+		if _, err := readJSONObjectFile(opts.MappingsFile, "mappings", variables); err != nil {
+			problem("mappings", "", err)
+		} else if plan, err = buildMappingPreflightPlan(opts.MappingsFile, variables); err != nil {
+			problem("mappings", "", err)
+		}
+	}
+	if opts.PipelinesFile != "" {
+		if _, err := readJSONObjectFile(opts.PipelinesFile, "", variables); err != nil {
+			problem("pipelines", "", err)
+		}
+	}
+	if opts.PoliciesFile != "" {
+		if _, err := readJSONObjectFile(opts.PoliciesFile, "", variables); err != nil {
+			problem("policies", "", err)
+		}
+	}
+	if opts.TransformsFile != "" {
+		definitions, err := readJSONObjectFile(opts.TransformsFile, "", variables)
+		if err == nil {
+			names := make([]string, 0, len(definitions))
+			for name := range definitions {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			_, _, err = resolveTransformsForSource(namedDefinitions(definitions), names, opts.Index)
+		}
+		if err != nil {
+			problem("transforms", "", err)
+		}
+	}
+
+	if inputErr == nil {
+		for _, path := range opts.dataFiles() {
+			count, readErr, transformErr := checkDataFile(input, path, transforms)
+			report.Documents += count
+			if readErr != nil {
+				problem("input", "", readErr)
+			}
+			if transformErr != nil {
+				problem("transforms", "", transformErr)
+			}
+		}
+	}
+
+	targets := opts.Clusters
+	if len(targets) == 0 {
+		targets = []string{opts.URL}
+	}
+	for _, url := range targets {
+		clusterOpts := opts
+		clusterOpts.URL = url
+		clusterOpts.Clusters = nil
+		check := validateCluster(ctx, clusterOpts, action, plan)
+		report.Clusters = append(report.Clusters, check.ClusterValidation)
+		for _, p := range check.problems {
+			problem(p.Check, redactedURL(url), p.Err)
+		}
+	}
+
+	if len(report.Problems) > 0 {
+		errs := make([]error, 0, len(report.Problems))
+		for _, p := range report.Problems {
+			errs = append(errs, p)
+		}
+		return report, &RunError{Kind: ErrValidation, Op: fmt.Sprintf("validating load (%d problems)", len(report.Problems)), Err: errors.Join(errs...)}
+	}
+	return report, nil
+}
+
+// clusterCheck collects the findings for one cluster.
+type clusterCheck struct {
+	ClusterValidation
+	problems []ValidationProblem
+}
+
+// validateCluster checks that opts.URL answers, accepts the credentials,
+// and holds an index the run can use. It only reads from the cluster.
+func validateCluster(ctx context.Context, opts Options, action dataAction, plan mappingPreflightPlan) clusterCheck {
+	check := clusterCheck{ClusterValidation: ClusterValidation{URL: opts.URL}}
+	problem := func(name string, err error) {
+		check.problems = append(check.problems, ValidationProblem{Check: name, Err: err})
+	}
+
+	info, err := preflightCluster(ctx, opts)
+	if err != nil {
+		if errors.Is(err, ErrAuthentication) {
+			problem("credentials", err)
+		} else {
+			problem("connectivity", err)
+		}
+		return check
+	}
+	check.Info = info
+	check.Reachable = true
+	if err := validateDocType(opts.DocType, info); err != nil {
+		problem("options", err)
+	}
+	if isServerless(opts, info) {
+		if err := validateServerless(opts); err != nil {
+			problem("options", err)
+		}
+	}
+	if strings.TrimSpace(opts.Index) == "" {
+		return check
+	}
+
+	cfg := elasticsearchConfig(opts)
+	if major := legacyMajor(info, opts.DocType); major > 0 {
+		cfg.Transport = &compatTransport{base: cfg.Transport, major: major}
+	}
+	es, err := elasticsearch.NewClient(cfg)
+	if err != nil {
+		problem("connectivity", err)
+		return check
+	}
+	check.IndexExists, err = indexExists(es, opts.Index)
+	if err != nil {
+		problem("index", fmt.Errorf("checking if index %q exists: %w", opts.Index, err))
+		return check
+	}
+	createsIndex := action.requiresDataFile() || opts.SyncManaged && (opts.SettingsFile != "" || opts.MappingsFile != "")
+	switch {
+	case !check.IndexExists && !createsIndex && !opts.Nuke:
+		problem("index", fmt.Errorf("index %q does not exist and this run would not create it", opts.Index))
+	case check.IndexExists && action != dataActionDelete && plan.hasExpectations():
+		if err := verifyMappingPreflight(es, opts.Index, plan); err != nil {
+			problem("mappings", fmt.Errorf("index %q: %w", opts.Index, err))
+		}
+	}
+	return check
+}
+
+// readJSONObjectFile reads path with template variables expanded and
+// parses it as a JSON object. With section set, an object nested under that
+// key must be an object too, as settings and mappings files allow.
+func readJSONObjectFile(path, section string, variables templateVariables) (map[string]json.RawMessage, error) {
+	content, err := readTemplatedFile(path, variables)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	var parsed map[string]json.RawMessage
+	if err := json.Unmarshal(content, &parsed); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if nested, ok := parsed[section]; ok && section != "" {
+		var inner map[string]json.RawMessage
+		if err := json.Unmarshal(nested, &inner); err != nil {
+			return nil, fmt.Errorf("parsing %s in %s: %w", section, path, err)
+		}
+	}
+	return parsed, nil
+}
+
+// checkDataFile reads every document of path and runs it through
+// transforms. It reports how many documents it read, the error that
+// stopped reading, and the first transform failure.
+func checkDataFile(input inputConfig, path string, transforms docTransformChain) (count int, readErr, transformErr error) {
+	reader, err := input.open(path)
+	if err != nil {
+		return 0, fmt.Errorf("opening %s: %w", path, err), nil
+	}
+	defer reader.close()
+	for {
+		doc, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			return count, nil, transformErr
+		}
+		if err != nil {
+			return count, fmt.Errorf("%s: document %d: %w", path, count+1, err), transformErr
+		}
+		count++
+		if transformErr == nil {
+			if _, err := transforms.apply(doc); err != nil {
+				transformErr = fmt.Errorf("%s: document %d: %w", path, count, err)
+			}
+		}
+	}
+}
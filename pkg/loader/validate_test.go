@@ -0,0 +1,131 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+// TestValidateReportsEveryProblem verifies behavior for the related scenario.
+func TestValidateReportsEveryProblem(t *testing.T) {
+	t.Parallel()
+
+	var writes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method != http.MethodGet && r.Method != http.MethodHead:
+			writes.Add(1)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/":
+			_, _ = w.Write([]byte(`{"cluster_name":"c","version":{"number":"9.1.0"}}`))
+		case r.URL.Path == "/cards":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/cards/_mapping":
+			_, _ = w.Write([]byte(`{"cards":{"mappings":{"properties":{"name":{"type":"text"}}}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+		return path
+	}
+	good := Options{
+		URL:          server.URL,
+		Index:        "cards",
+		AddToIndex:   true,
+		DataFile:     write("good.ndjson", "{\"name\":\"a\"}\n{\"name\":\"b\"}\n"),
+		MappingsFile: write("mappings.json", `{"mappings":{"properties":{"name":{"type":"text"}}}}`),
+		SettingsFile: write("settings.json", `{"number_of_replicas":0}`),
+	}
+	report, err := Validate(context.Background(), good)
+	if err != nil || len(report.Problems) != 0 {
+		t.Fatalf("expected a clean report, got %v (%v)", report.Problems, err)
+	}
+	if report.Documents != 2 || len(report.Clusters) != 1 || !report.Clusters[0].Reachable || !report.Clusters[0].IndexExists {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	bad := good
+	bad.SettingsFile = write("bad-settings.json", `{"number_of_replicas":`)
+	bad.MappingsFile = write("keyword.json", `{"properties":{"name":{"type":"keyword"}}}`)
+	bad.DataFile = write("bad.ndjson", "{\"name\":\"a\"}\nnot json\n")
+	bad.Normalize = []string{"name=shout"}
+	bad.TransformsFile = write("transforms.json", `{"rollup":{"source_index":"cards"}}`)
+	report, err = Validate(context.Background(), bad)
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+	var checks []string
+	for _, problem := range report.Problems {
+		checks = append(checks, problem.Check)
+	}
+	for _, want := range []string{"settings", "mappings", "input", "transforms"} {
+		if !slices.Contains(checks, want) {
+			t.Fatalf("expected a %s problem, got %v", want, report.Problems)
+		}
+	}
+	if writes.Load() != 0 {
+		t.Fatalf("expected no writes, got %d", writes.Load())
+	}
+}
+
+// TestValidateClusterProblems verifies behavior for the related scenario.
+func TestValidateClusterProblems(t *testing.T) {
+	t.Parallel()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen returned error: %v", err)
+	}
+	closedURL := "http://" + closed.Addr().String()
+	closed.Close()
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(unauthorized.Close)
+	missing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"cluster_name":"c","version":{"number":"9.1.0"}}`))
+	}))
+	t.Cleanup(missing.Close)
+
+	report, err := Validate(context.Background(), Options{
+		Clusters: []string{closedURL, unauthorized.URL, missing.URL},
+		Index:    "cards",
+		Enrich:   EnrichOptions{Enabled: true, All: true},
+	})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+	want := []string{"connectivity", "credentials", "index"}
+	if len(report.Problems) != len(want) {
+		t.Fatalf("expected problems %v, got %v", want, report.Problems)
+	}
+	for i, problem := range report.Problems {
+		if problem.Check != want[i] || problem.Cluster == "" {
+			t.Fatalf("problem %d: expected a %s problem for a cluster, got %v", i, want[i], problem)
+		}
+	}
+	if !report.Clusters[2].Reachable || report.Clusters[2].IndexExists {
+		t.Fatalf("unexpected cluster report %+v", report.Clusters[2])
+	}
+}
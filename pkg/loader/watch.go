@@ -0,0 +1,301 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ─── Directory Watch Mode ──────────────────────────────────────────────────────
+
+const (
+	// defaultWatchPattern selects files picked up from the watch directory.
+	defaultWatchPattern = "*.json"
+	// defaultWatchInterval is the delay between directory scans.
+	defaultWatchInterval = 5 * time.Second
+	// watchDoneDir receives files that loaded successfully.
+	watchDoneDir = "done"
+	// watchFailedDir receives files whose load returned an error.
+	watchFailedDir = "failed"
+	// watchStateFile records per-file offsets so a restart resumes mid-file.
+	watchStateFile = ".es-bulk-loader-watch.json"
+)
+
+// WatchOptions configures continuous ingestion from a drop directory.
+type WatchOptions struct {
+	// Dir is scanned for new data files.
+	Dir string
+	// Pattern is a filepath.Match glob applied to file names; defaults to *.json.
+	Pattern string
+	// Interval is the delay between scans; defaults to 5s.
+	Interval time.Duration
+	// JobsIndex records one job per file in this Elasticsearch index; empty
+	// disables job history.
+	JobsIndex string
+	// Load is the per-file Run configuration; DataFile is set for each file.
+	Load Options
+}
+
+// watchFileState records how far a file has been loaded. Size and ModTime
+// guard against resuming into a different file that reused the same name.
+type watchFileState struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Offset  int       `json:"offset"`
+}
+
+// watchState is the persisted offset journal for a watch directory.
+type watchState struct {
+	Files map[string]watchFileState `json:"files"`
+}
+
+// watcher groups state shared across scans of one watch directory.
+type watcher struct {
+	opts      WatchOptions
+	statePath string
+	state     watchState
+	jobs      jobStore
+	// pending holds the last observed size/mtime so a file is only loaded once
+	// it has stopped changing between two scans.
+	pending map[string]os.FileInfo
+}
+
+// Watch loads files dropped into opts.Dir until ctx is cancelled. Each file is
+// loaded with Run, then moved to done/ or failed/ beneath Dir.
+func Watch(ctx context.Context, opts WatchOptions) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if strings.TrimSpace(opts.Dir) == "" {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating watch directory", Err: fmt.Errorf("-watch requires a directory")}
+	}
+	if opts.Pattern == "" {
+		opts.Pattern = defaultWatchPattern
+	}
+	if _, err := filepath.Match(opts.Pattern, ""); err != nil {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating watch pattern", Err: err}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultWatchInterval
+	}
+	action, err := selectedDataAction(opts.Load.AddToIndex, opts.Load.FlushIndex, opts.Load.DeleteIndex)
+	if err != nil {
+		return &RunError{Kind: ErrInvalidOptions, Op: "selecting data action", Err: err}
+	}
+	if !action.requiresDataFile() {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating watch action", Err: fmt.Errorf("-watch requires one of -add, -flush, or -delete")}
+	}
+	if len(opts.Load.dataFiles()) > 0 {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating watch options", Err: fmt.Errorf("-data cannot be combined with -watch")}
+	}
+	if opts.Load.Tail {
+		return &RunError{Kind: ErrInvalidOptions, Op: "validating watch options", Err: fmt.Errorf("-tail cannot be combined with -watch")}
+	}
+	for _, sub := range []string{watchDoneDir, watchFailedDir} {
+		if err := os.MkdirAll(filepath.Join(opts.Dir, sub), 0o755); err != nil {
+			return &RunError{Kind: ErrLoaderExecution, Op: "creating watch subdirectory", Err: err}
+		}
+	}
+
+	w := &watcher{
+		opts:      opts,
+		statePath: filepath.Join(opts.Dir, watchStateFile),
+		pending:   make(map[string]os.FileInfo),
+	}
+	if err := w.loadState(); err != nil {
+		return &RunError{Kind: ErrLoaderExecution, Op: "reading watch state", Err: err}
+	}
+	if w.jobs, err = openJobStore(opts.Load, opts.JobsIndex); err != nil {
+		return err
+	}
+	recoverInterruptedJobs(w.jobs, JobModeWatch, jobHost())
+
+	log.Info().Str("dir", opts.Dir).Str("pattern", opts.Pattern).Str("interval", opts.Interval.String()).Msg("Watching directory for data files")
+	stopWatchdog := notifyReady("watching " + opts.Dir)
+	defer stopWatchdog()
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := w.scan(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			log.Info().Str("dir", opts.Dir).Msg("Watch stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan loads every settled file currently matching the pattern.
+func (w *watcher) scan(ctx context.Context) error {
+	entries, err := os.ReadDir(w.opts.Dir)
+	if err != nil {
+		return &RunError{Kind: ErrLoaderExecution, Op: "listing watch directory", Err: err}
+	}
+	names := make([]string, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if matched, _ := filepath.Match(w.opts.Pattern, entry.Name()); matched {
+			names = append(names, entry.Name())
+			seen[entry.Name()] = true
+		}
+	}
+	for name := range w.pending {
+		if !seen[name] {
+			delete(w.pending, name)
+		}
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return nil
+		}
+		info, err := os.Stat(filepath.Join(w.opts.Dir, name))
+		if err != nil {
+			continue
+		}
+		if !w.settled(name, info) {
+			continue
+		}
+		if err := w.process(ctx, name, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settled reports whether a file is unchanged since the previous scan. Files
+// with a journaled offset are treated as settled so a restart resumes at once.
+func (w *watcher) settled(name string, info os.FileInfo) bool {
+	if recorded, ok := w.state.Files[name]; ok && recorded.Size == info.Size() && recorded.ModTime.Equal(info.ModTime()) {
+		return true
+	}
+	previous, ok := w.pending[name]
+	w.pending[name] = info
+	return ok && previous.Size() == info.Size() && previous.ModTime().Equal(info.ModTime())
+}
+
+// process loads one file and moves it to done/ or failed/.
+func (w *watcher) process(ctx context.Context, name string, info os.FileInfo) error {
+	path := filepath.Join(w.opts.Dir, name)
+	entry, resumed := w.state.Files[name]
+	if !resumed || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+		entry = watchFileState{Size: info.Size(), ModTime: info.ModTime()}
+	}
+	if err := w.record(name, entry); err != nil {
+		return &RunError{Kind: ErrLoaderExecution, Op: "writing watch state", Err: err}
+	}
+
+	load := w.opts.Load
+	load.DataFile = path
+	load.SkipDocuments = entry.Offset
+	load.OnProgress = func(offset int) {
+		entry.Offset = offset
+		if err := w.record(name, entry); err != nil {
+			log.Warn().Err(err).Str("file", name).Msg("Could not persist watch offset")
+		}
+	}
+
+	log.Info().Str("file", name).Int("offset", entry.Offset).Msg("Loading watched file")
+	job, err := newJob(JobModeWatch, "watch:"+name, load)
+	if err != nil {
+		return &RunError{Kind: ErrLoaderExecution, Op: "creating job record", Err: err}
+	}
+	_, result, runErr := runJob(ctx, w.jobs, job, load, Run)
+	if runErr != nil && ctx.Err() != nil {
+		// Interrupted: keep the file and its offset so the next start resumes.
+		log.Warn().Err(runErr).Str("file", name).Int("offset", entry.Offset).Msg("Watched file load interrupted")
+		return nil
+	}
+	if errors.Is(runErr, ErrInvalidOptions) {
+		return runErr
+	}
+
+	target := watchDoneDir
+	if runErr != nil {
+		target = watchFailedDir
+		log.Error().Err(runErr).Str("file", name).Msg("Watched file load failed")
+	} else {
+		log.Info().Str("file", name).Int("succeeded", result.DocumentsSucceeded).Int("failed", result.DocumentsFailed).Msg("Watched file loaded")
+	}
+	destination, err := moveWatchedFile(path, filepath.Join(w.opts.Dir, target))
+	if err != nil {
+		return &RunError{Kind: ErrLoaderExecution, Op: "moving watched file", Err: err}
+	}
+	log.Debug().Str("file", name).Str("destination", destination).Msg("Moved watched file")
+	delete(w.pending, name)
+	if err := w.forget(name); err != nil {
+		return &RunError{Kind: ErrLoaderExecution, Op: "writing watch state", Err: err}
+	}
+	return nil
+}
+
+// moveWatchedFile renames path into dir, adding a timestamp suffix when a file
+// with the same name was already processed.
+func moveWatchedFile(path, dir string) (string, error) {
+	destination := filepath.Join(dir, filepath.Base(path))
+	if _, err := os.Stat(destination); err == nil {
+		ext := filepath.Ext(path)
+		stem := strings.TrimSuffix(filepath.Base(path), ext)
+		destination = filepath.Join(dir, fmt.Sprintf("%s-%s%s", stem, time.Now().UTC().Format("20060102150405.000000000"), ext))
+	}
+	return destination, os.Rename(path, destination)
+}
+
+// loadState reads the offset journal, treating a missing file as empty.
+func (w *watcher) loadState() error {
+	w.state = watchState{Files: make(map[string]watchFileState)}
+	content, err := os.ReadFile(w.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, &w.state); err != nil {
+		return fmt.Errorf("parsing %s: %w", w.statePath, err)
+	}
+	if w.state.Files == nil {
+		w.state.Files = make(map[string]watchFileState)
+	}
+	return nil
+}
+
+// record stores an offset for name and persists the journal.
+func (w *watcher) record(name string, entry watchFileState) error {
+	w.state.Files[name] = entry
+	return w.saveState()
+}
+
+// forget drops name from the journal once the file has been moved.
+func (w *watcher) forget(name string) error {
+	delete(w.state.Files, name)
+	return w.saveState()
+}
+
+// saveState writes the journal atomically via a temp file and rename.
+func (w *watcher) saveState() error {
+	content, err := json.MarshalIndent(w.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := w.statePath + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.statePath)
+}
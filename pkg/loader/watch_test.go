@@ -0,0 +1,158 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newWatchTestServer fakes an existing index that accepts every bulk request
+// and records the request bodies.
+func newWatchTestServer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/cards":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			bodies = append(bodies, string(body))
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"_index":"cards","_id":"1","status":201}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), bodies...)
+	}
+}
+
+// runWatchUntil runs Watch in the background until done reports true.
+func runWatchUntil(t *testing.T, opts WatchOptions, done func() bool) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() { errs <- Watch(ctx, opts) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatalf("watch did not reach expected state; Watch returned %v", <-errs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-errs; err != nil {
+		t.Fatalf("Watch returned error: %v", err)
+	}
+}
+
+// TestWatchMovesFilesToDoneAndFailed verifies behavior for the related scenario.
+func TestWatchMovesFilesToDoneAndFailed(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newWatchTestServer(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "good.json"), []byte(`[{"id":"1"}]`), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"not":"an array"}`), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ignored.txt"), []byte(`[]`), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	exists := func(parts ...string) bool {
+		_, err := os.Stat(filepath.Join(append([]string{dir}, parts...)...))
+		return err == nil
+	}
+	runWatchUntil(t, WatchOptions{
+		Dir:      dir,
+		Interval: 10 * time.Millisecond,
+		Load:     Options{URL: server.URL, Index: "cards", AddToIndex: true},
+	}, func() bool {
+		return exists(watchDoneDir, "good.json") && exists(watchFailedDir, "bad.json")
+	})
+
+	if !exists("ignored.txt") {
+		t.Fatal("expected non-matching file to stay in the watch directory")
+	}
+	content, err := os.ReadFile(filepath.Join(dir, watchStateFile))
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	var state watchState
+	if err := json.Unmarshal(content, &state); err != nil || len(state.Files) != 0 {
+		t.Fatalf("expected empty offset journal after moving files, got %s (%v)", content, err)
+	}
+}
+
+// TestWatchResumesFromJournaledOffset verifies behavior for the related scenario.
+func TestWatchResumesFromJournaledOffset(t *testing.T) {
+	t.Parallel()
+
+	server, bulkBodies := newWatchTestServer(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "partial.json")
+	if err := os.WriteFile(path, []byte(`[{"id":"first"},{"id":"second"}]`), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat returned error: %v", err)
+	}
+	journal, _ := json.Marshal(watchState{Files: map[string]watchFileState{
+		"partial.json": {Size: info.Size(), ModTime: info.ModTime(), Offset: 1},
+	}})
+	if err := os.WriteFile(filepath.Join(dir, watchStateFile), journal, 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	runWatchUntil(t, WatchOptions{
+		Dir:      dir,
+		Interval: 10 * time.Millisecond,
+		Load:     Options{URL: server.URL, Index: "cards", AddToIndex: true},
+	}, func() bool {
+		_, err := os.Stat(filepath.Join(dir, watchDoneDir, "partial.json"))
+		return err == nil
+	})
+
+	bodies := bulkBodies()
+	if len(bodies) != 1 || strings.Contains(bodies[0], "first") || !strings.Contains(bodies[0], "second") {
+		t.Fatalf("expected a single bulk request with only the second document, got %q", bodies)
+	}
+}
+
+// TestWatchRejectsMissingDataAction verifies behavior for the related scenario.
+func TestWatchRejectsMissingDataAction(t *testing.T) {
+	t.Parallel()
+
+	err := Watch(context.Background(), WatchOptions{Dir: t.TempDir(), Load: Options{Index: "cards"}})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected invalid options error, got %v", err)
+	}
+}